@@ -19,6 +19,8 @@ package agent
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
@@ -47,19 +49,27 @@ import (
 	"github.com/bleemeo/glouton/debouncer"
 	"github.com/bleemeo/glouton/delay"
 	"github.com/bleemeo/glouton/discovery"
+	"github.com/bleemeo/glouton/discovery/consul"
 	"github.com/bleemeo/glouton/discovery/promexporter"
 	"github.com/bleemeo/glouton/facts"
 	"github.com/bleemeo/glouton/facts/container-runtime/containerd"
+	"github.com/bleemeo/glouton/facts/container-runtime/crio"
 	"github.com/bleemeo/glouton/facts/container-runtime/kubernetes"
 	"github.com/bleemeo/glouton/facts/container-runtime/merge"
+	"github.com/bleemeo/glouton/facts/container-runtime/podman"
 	"github.com/bleemeo/glouton/facts/container-runtime/veth"
+	"github.com/bleemeo/glouton/fileoutput"
 	"github.com/bleemeo/glouton/fluentbit"
+	"github.com/bleemeo/glouton/graphite"
 	"github.com/bleemeo/glouton/influxdb"
 	"github.com/bleemeo/glouton/inputs"
 	"github.com/bleemeo/glouton/inputs/docker"
 	"github.com/bleemeo/glouton/inputs/mdstat"
+	"github.com/bleemeo/glouton/inputs/netns"
 	nvidia "github.com/bleemeo/glouton/inputs/nvidia_smi"
+	"github.com/bleemeo/glouton/inputs/pressure"
 	"github.com/bleemeo/glouton/inputs/smart"
+	"github.com/bleemeo/glouton/inputs/sqlquery"
 	"github.com/bleemeo/glouton/inputs/statsd"
 	"github.com/bleemeo/glouton/inputs/temp"
 	"github.com/bleemeo/glouton/inputs/vsphere"
@@ -68,6 +78,7 @@ import (
 	"github.com/bleemeo/glouton/mqtt"
 	"github.com/bleemeo/glouton/mqtt/client"
 	"github.com/bleemeo/glouton/nrpe"
+	"github.com/bleemeo/glouton/otlp"
 	"github.com/bleemeo/glouton/prometheus/exporter/blackbox"
 	"github.com/bleemeo/glouton/prometheus/exporter/ipmi"
 	"github.com/bleemeo/glouton/prometheus/exporter/snmp"
@@ -75,11 +86,13 @@ import (
 	"github.com/bleemeo/glouton/prometheus/registry"
 	"github.com/bleemeo/glouton/prometheus/rules"
 	"github.com/bleemeo/glouton/prometheus/scrapper"
+	"github.com/bleemeo/glouton/remotewrite"
 	"github.com/bleemeo/glouton/store"
 	"github.com/bleemeo/glouton/task"
 	"github.com/bleemeo/glouton/telemetry"
 	"github.com/bleemeo/glouton/threshold"
 	"github.com/bleemeo/glouton/types"
+	"github.com/bleemeo/glouton/utils/objectstore"
 	"github.com/bleemeo/glouton/version"
 	"github.com/bleemeo/glouton/zabbix"
 
@@ -110,6 +123,20 @@ const (
 	defaultInterval = 0
 )
 
+// diagnosticUploadTimeout is the maximum time spent to write and upload the diagnostic archive
+// when Glouton is unhealthy.
+const diagnosticUploadTimeout = time.Minute
+
+// diagnosticWriteTimeout is the time after which the diagnostic archive written so far
+// is uploaded, because writing it is probably stuck.
+const diagnosticWriteTimeout = 40 * time.Second
+
+// minTelemetryInterval is the minimal delay between two telemetry posts.
+const minTelemetryInterval = time.Hour
+
+// mandatoryTasks are the tasks required by Glouton, the agent stops when one of them crashes.
+var mandatoryTasks = []string{"Bleemeo SAAS connector", "Metric collector", "Metric store"} //nolint:gochecknoglobals
+
 var (
 	// We want to reply with capitalized U to match output from a Zabbix agent.
 	errUnsupportedKey     = errors.New("Unsupported item key") //nolint:stylecheck
@@ -120,6 +147,7 @@ type agent struct {
 	taskRegistry *task.Registry
 	config       config.Config
 	configItems  []config.Item
+	configFiles  []string
 	state        *state.State
 	stateDir     string
 	cancel       context.CancelFunc
@@ -129,7 +157,9 @@ type agent struct {
 	discovery              *discovery.Discovery
 	dockerRuntime          *dockerRuntime.Docker
 	containerFilter        facts.ContainerFilter
+	containerMetricsFilter facts.ContainerMetricsFilter
 	containerdRuntime      *containerd.Containerd
+	podmanRuntime          *podman.Podman
 	containerRuntime       crTypes.RuntimeInterface
 	collector              *collector.Collector
 	factProvider           *facts.FactProvider
@@ -140,7 +170,9 @@ type agent struct {
 	snmpManager            *snmp.Manager
 	snmpUpdatePending      bool
 	snmpRegistration       []int
+	snmpResolution         time.Duration
 	store                  *store.Store
+	batchPusher            *store.BatchPusher
 	gathererRegistry       *registry.Registry
 	metricFormat           types.MetricFormat
 	dynamicScrapper        *promexporter.DynamicScrapper
@@ -157,15 +189,20 @@ type agent struct {
 	fluentbitManager       *fluentbit.Manager
 	vSphereManager         *vsphere.Manager
 
-	triggerHandler            *debouncer.Debouncer
-	triggerLock               sync.Mutex
-	triggerDiscAt             time.Time
-	triggerDiscImmediate      bool
+	triggerHandler       *debouncer.Debouncer
+	triggerLock          sync.Mutex
+	triggerDiscAt        time.Time
+	triggerDiscImmediate bool
+	// firstDiscoveryAt is the end of the initial discovery delay, the
+	// discoveries triggered before are postponed until then.
+	firstDiscoveryAt          time.Time
 	triggerFact               bool
 	triggerSystemUpdateMetric bool
 
 	dockerInputPresent bool
 	dockerInputID      int
+	podmanInputPresent bool
+	podmanInputID      int
 
 	l                sync.Mutex
 	cond             *sync.Cond
@@ -174,23 +211,29 @@ type agent struct {
 	configWarnings   prometheus.MultiError
 }
 
-func zabbixResponse(key string, args []string) (string, error) {
-	_ = args
+// zabbixResponse returns the callback answering the Zabbix agent keys,
+// hostname is the name of the host in Zabbix.
+func zabbixResponse(hostname string) func(key string, args []string) (string, error) {
+	return func(key string, args []string) (string, error) {
+		_ = args
 
-	if key == "agent.ping" {
-		return "1", nil
-	}
+		switch key {
+		case "agent.ping":
+			return "1", nil
+		case "agent.version":
+			return fmt.Sprintf("4 (Glouton %s)", version.Version), nil
+		case "agent.hostname":
+			return hostname, nil
+		}
 
-	if key == "agent.version" {
-		return fmt.Sprintf("4 (Glouton %s)", version.Version), nil
+		return "", errUnsupportedKey
 	}
-
-	return "", errUnsupportedKey
 }
 
 type taskInfo struct {
 	function task.Runner
 	name     string
+	priority task.Priority
 }
 
 func (a *agent) init(ctx context.Context, configFiles []string, firstRun bool) (ok bool) {
@@ -209,6 +252,7 @@ func (a *agent) init(ctx context.Context, configFiles []string, firstRun bool) (
 
 	a.config = cfg
 	a.configItems = configItems
+	a.configFiles = configFiles
 
 	a.setupLogger()
 
@@ -259,6 +303,11 @@ func (a *agent) init(ctx context.Context, configFiles []string, firstRun bool) (
 		DenyList:          a.config.Container.Filter.DenyList,
 	}
 
+	a.containerMetricsFilter, err = facts.NewContainerMetricsFilter(a.config.Container.MetricsAllowlist)
+	if err != nil {
+		logger.Printf("Warning: invalid container.metrics_allowlist: %v", err)
+	}
+
 	statePath := a.config.Agent.StateFile
 	cachePath := a.config.Agent.StateCacheFile
 	oldStatePath := a.config.Agent.DeprecatedStateFile
@@ -519,6 +568,7 @@ func (a *agent) updateSNMPResolution(resolution time.Duration) {
 	}
 
 	a.snmpUpdatePending = true
+	a.snmpResolution = resolution
 	previousRegistration := a.snmpRegistration
 	a.snmpRegistration = nil
 
@@ -570,6 +620,60 @@ func (a *agent) updateSNMPResolution(resolution time.Duration) {
 	a.snmpRegistration = append(a.snmpRegistration, newRegistration...)
 }
 
+// reloadSNMPTargets reads the SNMP targets from the configuration files and
+// registers the gatherers again when the targets changed. The metrics of the
+// removed targets are dropped from the store.
+func (a *agent) reloadSNMPTargets() {
+	cfg, _, _, err := config.Load(true, true, a.configFiles...)
+	if err != nil {
+		logger.Printf("Unable to reload the SNMP targets: %v", err)
+
+		return
+	}
+
+	hadTargets := len(a.snmpManager.Targets()) > 0
+
+	removed, changed, warnings := a.snmpManager.UpdateTargets(cfg.Metric.SNMP.Targets)
+	for _, warning := range warnings {
+		logger.Printf("SNMP targets reload: %v", warning)
+	}
+
+	if !changed {
+		return
+	}
+
+	logger.V(1).Printf("SNMP targets changed, %d targets configured", len(a.snmpManager.Targets()))
+
+	if !hadTargets {
+		logger.Printf("The first SNMP target was added, the default SNMP metrics are only allowed after a restart of Glouton")
+	}
+
+	a.l.Lock()
+	resolution := a.snmpResolution
+	a.l.Unlock()
+
+	a.updateSNMPResolution(resolution)
+
+	var toDrop []map[string]string
+
+	for _, target := range removed {
+		metrics, err := a.store.Metrics(map[string]string{types.LabelSNMPTarget: target.Address()})
+		if err != nil {
+			logger.V(1).Printf("Unable to list the metrics of the SNMP target %s: %v", target.Address(), err)
+
+			continue
+		}
+
+		for _, m := range metrics {
+			toDrop = append(toDrop, m.Labels())
+		}
+	}
+
+	if len(toDrop) > 0 {
+		a.store.DropMetrics(toDrop)
+	}
+}
+
 func (a *agent) updateMetricResolution(ctx context.Context, defaultResolution time.Duration, snmpResolution time.Duration) {
 	a.l.Lock()
 	a.metricResolution = defaultResolution
@@ -598,8 +702,15 @@ func (a *agent) getConfigThreshold() map[string]threshold.Threshold {
 		softPeriods[metric] = time.Duration(period) * time.Second
 	}
 
+	defaultRecoveryPeriod := time.Duration(a.config.Metric.SoftStatusRecoveryDefault) * time.Second
+
+	recoveryPeriods := make(map[string]time.Duration, len(a.config.Metric.SoftStatusRecovery))
+	for metric, period := range a.config.Metric.SoftStatusRecovery {
+		recoveryPeriods[metric] = time.Duration(period) * time.Second
+	}
+
 	for metric, configThreshold := range a.config.Thresholds {
-		configThresholds[metric] = threshold.FromConfig(configThreshold, metric, softPeriods, defaultSoftPeriod)
+		configThresholds[metric] = threshold.FromConfig(configThreshold, metric, softPeriods, defaultSoftPeriod, recoveryPeriods, defaultRecoveryPeriod)
 	}
 
 	return configThresholds
@@ -703,6 +814,12 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 		a.config.Agent.PublicIPIndicator,
 	)
 
+	if err := a.factProvider.DisableSources(a.config.Facts.DisabledSources); err != nil {
+		logger.Printf("Warning: invalid facts.disabled_sources: %v", err)
+	}
+
+	a.factProvider.SetFactsScript(a.config.Agent.FactsScript)
+
 	factsMap, err := a.factProvider.FastFacts(ctx)
 	if err != nil {
 		logger.Printf("Warning: get facts failed, some information (e.g. name of this server) may be wrong. %v", err)
@@ -775,6 +892,7 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 
 	a.snmpManager, warnings = snmp.NewManager(
 		a.config.Metric.SNMP.ExporterAddress,
+		a.config.Metric.SNMP.AuthsFile,
 		a.factProvider,
 		a.config.Metric.SNMP.Targets,
 	)
@@ -798,6 +916,14 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 		a.store = store.New(2*time.Minute, 2*time.Hour)
 	}
 
+	if a.config.Store.SnapshotFile != "" {
+		a.store.SetSnapshotFile(a.config.Store.SnapshotFile)
+
+		if err := a.store.Load(); err != nil {
+			logger.Printf("Unable to load the metric store snapshot, it will be ignored: %v", err)
+		}
+	}
+
 	filteredStore := store.NewFilteredStore(
 		a.store,
 		func(m []types.MetricPoint) []types.MetricPoint {
@@ -806,12 +932,35 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 		mFilter.filterMetrics,
 	)
 	a.threshold = threshold.New(a.state)
+	a.threshold.SetStore(a.store)
 
 	secretInputsGate := gate.New(inputs.MaxParallelSecrets())
 
+	exporterDenyMetrics, warnings := buildMatchersList(a.config.Metric.ExporterDenyMetrics)
+	if warnings != nil {
+		a.addWarnings(warnings...)
+	}
+
+	staleMetrics, warnings := buildMatchersList(a.config.Metric.StaleMetrics)
+	if warnings != nil {
+		a.addWarnings(warnings...)
+	}
+
+	resolutionOverrides := make(map[string]time.Duration, len(a.config.Metric.ResolutionOverrides))
+	for name, seconds := range a.config.Metric.ResolutionOverrides {
+		resolutionOverrides[name] = time.Duration(seconds) * time.Second
+	}
+
+	var pointPusher types.PointPusher = a.store
+
+	if a.config.Store.BatchWindowMS > 0 {
+		a.batchPusher = store.NewBatchPusher(a.store, time.Duration(a.config.Store.BatchWindowMS)*time.Millisecond)
+		pointPusher = a.batchPusher
+	}
+
 	a.gathererRegistry, err = registry.New(
 		registry.Option{
-			PushPoint:             a.store,
+			PushPoint:             pointPusher,
 			ThresholdHandler:      a.threshold,
 			FQDN:                  fqdn,
 			GloutonPort:           strconv.Itoa(a.config.Web.Listener.Port),
@@ -821,6 +970,12 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 			Queryable:             a.store,
 			SecretInputsGate:      secretInputsGate,
 			ShutdownDeadline:      15 * time.Second,
+			AlignTimestamps:       a.config.Metric.AlignTimestamps,
+			ResolutionOverrides:   resolutionOverrides,
+			MetricRenames:         a.config.Metric.Rename,
+			EmitRawCounters:       a.config.Metric.EmitRawCounters,
+			ExporterDenyMetrics:   exporterDenyMetrics,
+			StaleMetrics:          staleMetrics,
 		})
 	if err != nil {
 		logger.Printf("Unable to create the metrics registry: %v", err)
@@ -837,17 +992,40 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 		a.deletedContainersCallback,
 		a.containerFilter.ContainerIgnored,
 	)
+	a.dockerRuntime.CollectDiskUsage = a.config.Container.CollectDiskUsage
 	a.containerdRuntime = containerd.New(
 		a.config.Container.Runtime.ContainerD,
 		a.hostRootPath,
 		a.deletedContainersCallback,
 		a.containerFilter.ContainerIgnored,
 	)
+	a.containerdRuntime.IsMetricsAllowed = a.containerMetricsAllowed()
+	runtimes := []crTypes.RuntimeInterface{
+		a.dockerRuntime,
+		a.containerdRuntime,
+		crio.New(
+			a.config.Container.Runtime.CRIO,
+			a.hostRootPath,
+			a.deletedContainersCallback,
+			a.containerFilter.ContainerIgnored,
+		),
+	}
+
+	// Podman is only added when its socket exists, including the sockets of rootless Podman.
+	if a.config.Container.Runtime.Podman.Enable && podman.SocketFound(a.config.Container.Runtime.Podman, a.hostRootPath) {
+		a.podmanRuntime = podman.New(
+			a.config.Container.Runtime.Podman,
+			a.hostRootPath,
+			a.deletedContainersCallback,
+			a.containerFilter.ContainerIgnored,
+		)
+		a.podmanRuntime.CollectDiskUsage = a.config.Container.CollectDiskUsage
+
+		runtimes = append(runtimes, a.podmanRuntime)
+	}
+
 	a.containerRuntime = &merge.Runtime{
-		Runtimes: []crTypes.RuntimeInterface{
-			a.dockerRuntime,
-			a.containerdRuntime,
-		},
+		Runtimes:         runtimes,
 		ContainerIgnored: a.containerFilter.ContainerIgnored,
 	}
 
@@ -924,9 +1102,19 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 		psLister,
 		a.containerRuntime,
 	)
-	netstat := &facts.NetstatProvider{FilePath: a.config.Agent.NetstatFile}
+	netstat := &facts.NetstatProvider{
+		FilePath: a.config.Agent.NetstatFile,
+		Families: a.config.Discovery.NetstatFamilies,
+	}
+
+	if !a.factProvider.IsSourceDisabled(facts.SourceContainerRuntime) {
+		a.factProvider.AddCallback(a.containerRuntime.RuntimeFact)
+	}
+
+	if a.config.Agent.CloudMetadata.Enable && !a.factProvider.IsSourceDisabled(facts.SourceCloudProvider) {
+		a.factProvider.AddCallback(facts.NewCloudMetadata().Facts)
+	}
 
-	a.factProvider.AddCallback(a.containerRuntime.RuntimeFact)
 	a.factProvider.SetFact("installation_format", a.config.Agent.InstallationFormat)
 
 	acc := &inputs.Accumulator{
@@ -943,6 +1131,7 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 		ContainerInfo:      a.containerRuntime,
 		IsContainerIgnored: a.containerFilter.ContainerIgnored,
 		FileReader:         discovery.SudoFileReader{HostRootPath: a.hostRootPath},
+		HostRootPath:       a.hostRootPath,
 	})
 
 	a.discovery, warnings = discovery.New(
@@ -956,15 +1145,17 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 		a.containerFilter.ContainerIgnored,
 		a.metricFormat,
 		psFact,
+		time.Duration(a.config.ServiceConnectTimeout)*time.Second,
 	)
 	if warnings != nil {
 		a.addWarnings(warnings...)
 	}
 
 	a.dynamicScrapper = &promexporter.DynamicScrapper{
-		Registry:        a.gathererRegistry,
-		DynamicJobName:  "discovered-exporters",
-		FluentBitInputs: a.config.Log.Inputs,
+		Registry:                a.gathererRegistry,
+		DynamicJobName:          "discovered-exporters",
+		FluentBitInputs:         a.config.Log.Inputs,
+		EnvoyMaxSeriesPerFamily: a.config.Metric.EnvoyMaxSeriesPerFamily,
 	}
 
 	if a.config.Blackbox.Enable {
@@ -980,10 +1171,27 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 
 	promExporter := a.gathererRegistry.Exporter()
 
+	var pushGateway types.PointPusher
+
+	if a.config.Web.Endpoints.PushGatewayEnable {
+		ttl := time.Duration(a.config.Web.Endpoints.PushGatewayTTL) * time.Second
+		if ttl <= 0 {
+			a.addWarnings(fmt.Errorf(
+				"%w: web.endpoints.push_gateway_ttl must be positive, using the default 3600",
+				config.ErrInvalidValue,
+			))
+
+			ttl = time.Hour
+		}
+
+		pushGateway = a.gathererRegistry.WithTTL(ttl)
+	}
+
 	api := &api.API{
 		DB:                 api.NewQueryable(a.store, a.BleemeoAgentID),
 		ContainerRuntime:   a.containerRuntime,
 		Endpoints:          a.config.Web.Endpoints,
+		PushGateway:        pushGateway,
 		PsFact:             psFact,
 		FactProvider:       a.factProvider,
 		BindAddress:        apiBindAddress,
@@ -996,24 +1204,37 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 		DiagnosticArchive:  a.writeDiagnosticArchive,
 		MetricFormat:       a.metricFormat,
 		LocalUIDisabled:    !a.config.Web.LocalUI.Enable,
+		MetricsAPIEnabled:  a.config.Web.API.Enable,
+		AuthToken:          a.config.Web.AuthToken,
+		FireTrigger: func(runDiscovery bool, sendFacts bool, systemUpdateMetric bool) {
+			a.FireTrigger(runDiscovery, sendFacts, systemUpdateMetric, false)
+		},
+	}
+
+	if a.monitorManager != nil {
+		api.MonitorManager = a.monitorManager
 	}
 
 	tasks := []taskInfo{
-		{a.watchdog, "Agent Watchdog"},
-		{a.store.Run, "Metric store"},
-		{a.containerRuntime.Run, "Docker connector"},
-		{a.healthCheck, "Agent healthcheck"},
-		{a.hourlyDiscovery, "Service Discovery"},
-		{a.dailyFact, "Facts gatherer"},
-		{a.dockerWatcher, "Docker event watcher"},
-		{a.netstatWatcher, "Netstat file watcher"},
-		{a.miscTasks, "Miscelanous tasks"},
-		{a.sendToTelemetry, "Send Facts information to our telemetry tool"},
-		{a.threshold.Run, "Threshold state"},
+		{a.watchdog, "Agent Watchdog", task.PriorityHigh},
+		{a.store.Run, "Metric store", task.PriorityHigh},
+		{a.containerRuntime.Run, "Docker connector", task.PriorityNormal},
+		{a.healthCheck, "Agent healthcheck", task.PriorityNormal},
+		{a.hourlyDiscovery, "Service Discovery", task.PriorityNormal},
+		{a.dailyFact, "Facts gatherer", task.PriorityNormal},
+		{a.dockerWatcher, "Docker event watcher", task.PriorityNormal},
+		{a.netstatWatcher, "Netstat file watcher", task.PriorityNormal},
+		{a.miscTasks, "Miscelanous tasks", task.PriorityLow},
+		{a.sendToTelemetry, "Send Facts information to our telemetry tool", task.PriorityLow},
+		{a.threshold.Run, "Threshold state", task.PriorityNormal},
+	}
+
+	if a.batchPusher != nil {
+		tasks = append(tasks, taskInfo{a.batchPusher.Run, "Metric store batching", task.PriorityNormal})
 	}
 
 	if a.config.Agent.EnableCrashReporting {
-		tasks = append(tasks, taskInfo{a.crashReportManagement, "Crash report management"})
+		tasks = append(tasks, taskInfo{a.crashReportManagement, "Crash report management", task.PriorityLow})
 	}
 
 	if a.config.JMX.Enable {
@@ -1034,7 +1255,7 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 			Pusher:                        a.gathererRegistry.WithTTL(5 * time.Minute),
 		}
 
-		tasks = append(tasks, taskInfo{a.jmx.Run, "jmxtrans"})
+		tasks = append(tasks, taskInfo{a.jmx.Run, "jmxtrans", task.PriorityNormal})
 	}
 
 	baseRules := fluentbit.PromQLRulesFromInputs(a.config.Log.Inputs)
@@ -1058,7 +1279,7 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 			Process:                        psFact,
 			Docker:                         a.containerRuntime,
 			Store:                          filteredStore,
-			SNMP:                           a.snmpManager.Targets(),
+			SNMP:                           a.snmpManager.Targets,
 			SNMPOnlineTarget:               a.snmpManager.OnlineCount,
 			Discovery:                      a.discovery,
 			MonitorManager:                 a.monitorManager,
@@ -1092,7 +1313,7 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 		a.l.Unlock()
 
 		a.gathererRegistry.UpdateRelabelHook(a.bleemeoConnector.RelabelHook)
-		tasks = append(tasks, taskInfo{a.bleemeoConnector.Run, "Bleemeo SAAS connector"})
+		tasks = append(tasks, taskInfo{a.bleemeoConnector.Run, "Bleemeo SAAS connector", task.PriorityHigh})
 
 		_, err = a.gathererRegistry.RegisterAppenderCallback(
 			registry.RegistrationOption{
@@ -1108,6 +1329,16 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 		}
 	}
 
+	if a.config.Discovery.InitialDelay > 0 {
+		initialDelay := time.Duration(a.config.Discovery.InitialDelay) * time.Second
+
+		a.discovery.DelayFirstDiscovery(initialDelay)
+
+		a.triggerLock.Lock()
+		a.firstDiscoveryAt = time.Now().Add(initialDelay)
+		a.triggerLock.Unlock()
+	}
+
 	a.FireTrigger(true, true, false, false)
 
 	// Only start gatherers after the relabel hook is set to avoid sending metrics without
@@ -1132,7 +1363,7 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 				Description: "process status metrics",
 				JitterSeed:  baseJitter,
 			},
-			processSource.NewStatusSource(psFact),
+			processSource.NewStatusSource(psFact, a.config.Process.CountZombies),
 		)
 		if err != nil {
 			logger.Printf("unable to add processes metrics: %v", err)
@@ -1166,11 +1397,17 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 			ApplyDynamicRelabel: true,
 		},
 		miscAppenderMinute{
-			containerRuntime:  a.containerRuntime,
-			discovery:         a.discovery,
-			store:             a.store,
-			hostRootPath:      a.hostRootPath,
-			getConfigWarnings: a.getWarnings,
+			containerRuntime:   a.containerRuntime,
+			discovery:          a.discovery,
+			store:              a.store,
+			hostRootPath:       a.hostRootPath,
+			getConfigWarnings:  a.getWarnings,
+			configHash:         configHash(a.config),
+			checkStateWritable: a.state.CheckWritable,
+			facts:              a.factProvider.Facts,
+			mandatoryTasksUp:   a.mandatoryTasksUp,
+			dnsCanary:          a.config.Agent.DNSCanary,
+			lookupHost:         net.DefaultResolver.LookupHost,
 		},
 	)
 	if err != nil {
@@ -1205,6 +1442,7 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 				JitterSeed:               labels.FromMap(target.ExtraLabels).Hash(),
 				Interval:                 defaultInterval,
 				ExtraLabels:              target.ExtraLabels,
+				HonorLabels:              target.HonorLabels,
 				AcceptAllowedMetricsOnly: true,
 				HonorTimestamp:           true,
 			},
@@ -1215,6 +1453,31 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 		}
 	}
 
+	if a.config.Discovery.Consul.Enable {
+		consulCfg := a.config.Discovery.Consul
+
+		consulDiscovery, err := consul.New(
+			consul.Options{
+				Address:         consulCfg.Address,
+				Token:           consulCfg.Token,
+				Datacenter:      consulCfg.Datacenter,
+				Tag:             consulCfg.Tag,
+				RefreshInterval: time.Duration(consulCfg.RefreshInterval) * time.Second,
+				ScrapeTargets:   consulCfg.ScrapeTargets,
+				MetricsPath:     consulCfg.MetricsPath,
+				ScrapeInterval:  defaultInterval,
+				Checks:          consulCfg.Checks,
+				ConnectTimeout:  time.Duration(a.config.ServiceConnectTimeout) * time.Second,
+			},
+			a.gathererRegistry,
+		)
+		if err != nil {
+			logger.Printf("Unable to start the Consul discovery: %v", err)
+		} else {
+			tasks = append(tasks, taskInfo{consulDiscovery.Run, "Consul discovery", task.PriorityNormal})
+		}
+	}
+
 	a.gathererRegistry.AddDefaultCollector()
 
 	sentry.ConfigureScope(func(scope *sentry.Scope) {
@@ -1226,36 +1489,233 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 
 	if a.config.NRPE.Enable {
 		nrpeConfFile := a.config.NRPE.ConfPaths
-		nrperesponse := nrpe.NewResponse(a.config.Services, a.discovery, nrpeConfFile)
+		nrperesponse := nrpe.NewResponse(a.config.Services, a.discovery, nrpeConfFile, a.config.NRPE.AllowArguments)
 		server := nrpe.New(
 			fmt.Sprintf("%s:%d", a.config.NRPE.Address, a.config.NRPE.Port),
 			a.config.NRPE.SSL,
 			nrperesponse.Response,
 		)
-		tasks = append(tasks, taskInfo{server.Run, "NRPE server"})
+		tasks = append(tasks, taskInfo{server.Run, "NRPE server", task.PriorityNormal})
+	}
+
+	// The Zabbix items are keyed on the host name, it defaults to the FQDN.
+	zabbixHost := a.config.Zabbix.Host
+
+	if zabbixHost == "" && (a.config.Zabbix.Enable || a.config.Zabbix.Active.Enable) {
+		facts, err := a.factProvider.Facts(ctx, time.Hour)
+		if err != nil {
+			logger.V(1).Printf("Unable to get the facts for the Zabbix hostname: %v", err)
+		} else {
+			zabbixHost = facts["fqdn"]
+		}
 	}
 
 	if a.config.Zabbix.Enable {
 		server := zabbix.New(
 			net.JoinHostPort(a.config.Zabbix.Address, strconv.Itoa(a.config.Zabbix.Port)),
-			zabbixResponse,
+			zabbixResponse(zabbixHost),
+			a.store,
+		)
+		tasks = append(tasks, taskInfo{server.Run, "Zabbix server", task.PriorityNormal})
+	}
+
+	if a.config.Zabbix.Active.Enable {
+		client := zabbix.NewActive(
+			zabbix.ActiveOptions{
+				ServerAddress:   a.config.Zabbix.Active.ServerAddress,
+				Hostname:        zabbixHost,
+				RefreshInterval: time.Duration(a.config.Zabbix.Active.RefreshInterval) * time.Second,
+			},
+			zabbixResponse(zabbixHost),
+			a.store,
 		)
-		tasks = append(tasks, taskInfo{server.Run, "Zabbix server"})
+		tasks = append(tasks, taskInfo{client.Run, "Zabbix active checks", task.PriorityNormal})
 	}
 
 	if a.config.InfluxDB.Enable {
+		bucket := a.config.InfluxDB.Bucket
+		if bucket == "" {
+			bucket = a.config.InfluxDB.DBName
+		}
+
 		server := influxdb.New(
 			"http://"+net.JoinHostPort(a.config.InfluxDB.Host, strconv.Itoa(a.config.InfluxDB.Port)),
 			a.config.InfluxDB.DBName,
 			a.store,
 			a.config.InfluxDB.Tags,
+			influxdb.Options{
+				Version:          a.config.InfluxDB.Version,
+				Token:            a.config.InfluxDB.Token,
+				Org:              a.config.InfluxDB.Org,
+				Bucket:           bucket,
+				Precision:        a.config.InfluxDB.Precision,
+				MaxPendingPoints: a.config.InfluxDB.MaxPendingPoints,
+			},
 		)
 		a.influxdbConnector = server
-		tasks = append(tasks, taskInfo{server.Run, "influxdb"})
+
+		influxdbRegistry := prometheus.NewRegistry()
+		influxdbRegistry.MustRegister(server)
+
+		_, err = a.gathererRegistry.RegisterGatherer(
+			registry.RegistrationOption{
+				Description: "InfluxDB connector",
+				JitterSeed:  baseJitter,
+				Interval:    defaultInterval,
+			},
+			influxdbRegistry,
+		)
+		if err != nil {
+			logger.Printf("Unable to add InfluxDB connector metrics: %v", err)
+		}
+		tasks = append(tasks, taskInfo{server.Run, "influxdb", task.PriorityNormal})
 
 		logger.V(2).Printf("Influxdb is activated !")
 	}
 
+	if remoteWriteCfg := a.config.Metric.Prometheus.RemoteWrite; remoteWriteCfg.Enable {
+		client := remotewrite.New(
+			remotewrite.Options{
+				URL:              remoteWriteCfg.URL,
+				Username:         remoteWriteCfg.Username,
+				Password:         remoteWriteCfg.Password,
+				Headers:          remoteWriteCfg.Headers,
+				CAFile:           remoteWriteCfg.CAFile,
+				SSLInsecure:      remoteWriteCfg.SSLInsecure,
+				MaxPendingPoints: remoteWriteCfg.MaxPendingPoints,
+			},
+			filteredStore,
+		)
+
+		remoteWriteRegistry := prometheus.NewRegistry()
+		remoteWriteRegistry.MustRegister(client)
+
+		_, err = a.gathererRegistry.RegisterGatherer(
+			registry.RegistrationOption{
+				Description: "Prometheus remote_write connector",
+				JitterSeed:  baseJitter,
+				Interval:    defaultInterval,
+			},
+			remoteWriteRegistry,
+		)
+		if err != nil {
+			logger.Printf("Unable to add Prometheus remote_write connector metrics: %v", err)
+		}
+
+		tasks = append(tasks, taskInfo{client.Run, "Prometheus remote_write", task.PriorityNormal})
+	}
+
+	if a.config.Graphite.Enable {
+		client, err := graphite.New(
+			graphite.Options{
+				Address:          net.JoinHostPort(a.config.Graphite.Host, strconv.Itoa(a.config.Graphite.Port)),
+				PathTemplate:     a.config.Graphite.PathTemplate,
+				MaxPendingPoints: a.config.Graphite.MaxPendingPoints,
+			},
+			filteredStore,
+		)
+		if err != nil {
+			logger.Printf("Unable to start the Graphite connector: %v", err)
+		} else {
+			graphiteRegistry := prometheus.NewRegistry()
+			graphiteRegistry.MustRegister(client)
+
+			_, err = a.gathererRegistry.RegisterGatherer(
+				registry.RegistrationOption{
+					Description: "Graphite connector",
+					JitterSeed:  baseJitter,
+					Interval:    defaultInterval,
+				},
+				graphiteRegistry,
+			)
+			if err != nil {
+				logger.Printf("Unable to add Graphite connector metrics: %v", err)
+			}
+
+			tasks = append(tasks, taskInfo{client.Run, "Graphite", task.PriorityNormal})
+		}
+	}
+
+	if a.config.FileOutput.Enable {
+		client, err := fileoutput.New(
+			fileoutput.Options{
+				Path:             a.config.FileOutput.Path,
+				MaxFileSize:      int64(a.config.FileOutput.MaxSizeMB) * 1024 * 1024,
+				MaxFiles:         a.config.FileOutput.MaxFiles,
+				MaxPendingPoints: a.config.FileOutput.MaxPendingPoints,
+			},
+			filteredStore,
+		)
+		if err != nil {
+			logger.Printf("Unable to start the file output: %v", err)
+		} else {
+			fileOutputRegistry := prometheus.NewRegistry()
+			fileOutputRegistry.MustRegister(client)
+
+			_, err = a.gathererRegistry.RegisterGatherer(
+				registry.RegistrationOption{
+					Description: "File output",
+					JitterSeed:  baseJitter,
+					Interval:    defaultInterval,
+				},
+				fileOutputRegistry,
+			)
+			if err != nil {
+				logger.Printf("Unable to add file output metrics: %v", err)
+			}
+
+			tasks = append(tasks, taskInfo{client.Run, "File output", task.PriorityNormal})
+		}
+	}
+
+	if a.config.OTLP.Enable {
+		client, err := otlp.New(
+			otlp.Options{
+				Protocol:         a.config.OTLP.Protocol,
+				Endpoint:         a.config.OTLP.Endpoint,
+				Insecure:         a.config.OTLP.Insecure,
+				CAFile:           a.config.OTLP.CAFile,
+				SSLInsecure:      a.config.OTLP.SSLInsecure,
+				Headers:          a.config.OTLP.Headers,
+				MaxPendingPoints: a.config.OTLP.MaxPendingPoints,
+				Resource: func() otlp.Resource {
+					attributes := map[string]string{
+						"host.name":       fqdn,
+						"service.name":    "glouton",
+						"service.version": version.Version,
+					}
+
+					if agentID := a.BleemeoAgentID(); agentID != "" {
+						attributes["glouton.agent_id"] = agentID
+					}
+
+					return otlp.Resource{Attributes: attributes}
+				},
+			},
+			filteredStore,
+		)
+		if err != nil {
+			logger.Printf("Unable to start the OTLP connector: %v", err)
+		} else {
+			otlpRegistry := prometheus.NewRegistry()
+			otlpRegistry.MustRegister(client)
+
+			_, err = a.gathererRegistry.RegisterGatherer(
+				registry.RegistrationOption{
+					Description: "OTLP connector",
+					JitterSeed:  baseJitter,
+					Interval:    defaultInterval,
+				},
+				otlpRegistry,
+			)
+			if err != nil {
+				logger.Printf("Unable to add OTLP connector metrics: %v", err)
+			}
+
+			tasks = append(tasks, taskInfo{client.Run, "OTLP", task.PriorityNormal})
+		}
+	}
+
 	if a.bleemeoConnector == nil {
 		a.updateThresholds(ctx, nil, true)
 	} else {
@@ -1280,6 +1740,7 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 			tasks = append(tasks, taskInfo{
 				a.fluentbitManager.Run,
 				"Fluent Bit manager",
+				task.PriorityNormal,
 			})
 		}
 	}
@@ -1311,24 +1772,58 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 	tasks = append(tasks, taskInfo{
 		a.gathererRegistry.Run,
 		"Metric collector",
+		task.PriorityHigh,
 	})
 
-	if a.config.Telegraf.StatsD.Enable {
-		input, err := statsd.New(fmt.Sprintf("%s:%d", a.config.Telegraf.StatsD.Address, a.config.Telegraf.StatsD.Port))
-		if err != nil {
-			logger.Printf("Unable to create StatsD input: %v", err)
-
-			a.config.Telegraf.StatsD.Enable = false
-		} else if _, err = a.collector.AddInput(input, "statsd"); err != nil {
-			if strings.Contains(err.Error(), "address already in use") {
-				logger.Printf("Unable to listen on StatsD port because another program already use it")
-				logger.Printf("The StatsD integration is now disabled. Restart the agent to try re-enabling it.")
-				logger.Printf("See https://go.bleemeo.com/l/agent-configuration-statsd to permanently disable StatsD integration or using an alternate port")
-			} else {
-				logger.Printf("Unable to create StatsD input: %v", err)
+	globalLabels := &globalLabelsMerger{update: a.gathererRegistry.UpdateGlobalLabels}
+
+	if a.config.Agent.LabelsFile != "" {
+		watcher := &labelsFileWatcher{
+			path:         a.config.Agent.LabelsFile,
+			updateLabels: globalLabels.updater("labels_file"),
+		}
+
+		tasks = append(tasks, taskInfo{watcher.Run, "Labels file watcher", task.PriorityNormal})
+	}
+
+	if a.config.Facts.VersionFile != "" {
+		updateVersionLabel := globalLabels.updater("version_file")
+
+		watcher := facts.NewVersionFileWatcher(a.config.Facts.VersionFile, func(version string, deployed bool) {
+			a.factProvider.SetFact(facts.FactAppVersion, version)
+
+			if a.config.Facts.VersionLabel && version != "" {
+				updateVersionLabel(map[string]string{facts.FactAppVersion: version})
+			} else if a.config.Facts.VersionLabel {
+				updateVersionLabel(nil)
 			}
 
-			a.config.Telegraf.StatsD.Enable = false
+			if deployed {
+				a.gathererRegistry.WithTTL(5*time.Minute).PushPoints(ctx, []types.MetricPoint{
+					{
+						Labels: map[string]string{
+							types.LabelName:      "app_deployment",
+							facts.FactAppVersion: version,
+						},
+						Point: types.Point{Time: time.Now(), Value: 1},
+					},
+				})
+			}
+		})
+
+		tasks = append(tasks, taskInfo{watcher.Run, "Version file watcher", task.PriorityNormal})
+	}
+
+	if a.config.Telegraf.StatsD.Enable {
+		datadogExtensions := a.config.Telegraf.StatsD.DatadogExtensions
+
+		a.config.Telegraf.StatsD.Enable = a.addStatsDInput(config.StatsDListener{
+			Address: a.config.Telegraf.StatsD.Address,
+			Port:    a.config.Telegraf.StatsD.Port,
+		}, datadogExtensions)
+
+		for _, listener := range a.config.Telegraf.StatsD.Listeners {
+			a.addStatsDInput(listener, datadogExtensions)
 		}
 	}
 
@@ -1347,6 +1842,7 @@ func (a *agent) run(ctx context.Context, sighupChan chan os.Signal) { //nolint:m
 		tasks = append(tasks, taskInfo{
 			a.mqtt.Run,
 			"MQTT connector",
+			task.PriorityHigh,
 		})
 	}
 
@@ -1423,16 +1919,29 @@ func (a *agent) registerInputs(ctx context.Context) {
 		}
 	}
 
+	for _, query := range a.config.SQL.Queries {
+		input, opts, err := sqlquery.New(query)
+		a.registerInput("SQL query "+query.Name, input, opts, err)
+	}
+
+	if a.config.NetworkNamespaces.Enable {
+		input, opts, err := netns.New(a.hostRootPath, a.config.NetworkNamespaces.AllowList)
+		a.registerInput("network namespaces", input, opts, err)
+	}
+
 	input, opts, err := temp.New()
 	a.registerInput("Temp", input, opts, err)
 
+	input, opts, err = pressure.New(a.hostRootPath)
+	a.registerInput("PSI", input, opts, err)
+
 	a.vSphereManager.RegisterGatherers(ctx, a.config.VSphere, a.gathererRegistry.RegisterGatherer, a.state, a.factProvider)
 }
 
 // Register a single input.
 func (a *agent) registerInput(name string, input telegraf.Input, opts registry.RegistrationOption, err error) {
 	if err != nil {
-		if errors.Is(err, inputs.ErrMissingCommand) {
+		if errors.Is(err, inputs.ErrMissingCommand) || errors.Is(err, inputs.ErrUnavailable) {
 			logger.V(1).Printf("input %s: %v", name, err)
 		} else {
 			logger.Printf("Failed to create input %s: %v", name, err)
@@ -1471,6 +1980,8 @@ func (a *agent) handleSighup(ctx context.Context, sighupChan chan os.Signal) {
 				connector.UpdateMonitors()
 			}
 
+			a.reloadSNMPTargets()
+
 			l.Lock()
 			if !systemUpdateMetricPending {
 				systemUpdateMetricPending = true
@@ -1534,8 +2045,23 @@ func (a *agent) buildCollectorsConfig() (conf inputs.CollectorConfig, err error)
 	}, nil
 }
 
+// telemetryInterval returns the delay between two telemetry posts, which is at least minTelemetryInterval.
+func telemetryInterval(seconds int) time.Duration {
+	interval := time.Duration(seconds) * time.Second
+	if interval < minTelemetryInterval {
+		return minTelemetryInterval
+	}
+
+	return interval
+}
+
 func (a *agent) sendToTelemetry(ctx context.Context) error {
 	if a.config.Agent.Telemetry.Enable {
+		interval := telemetryInterval(a.config.Agent.Telemetry.Interval)
+		if interval != time.Duration(a.config.Agent.Telemetry.Interval)*time.Second {
+			logger.V(1).Printf("The telemetry interval is too low, using %v", interval)
+		}
+
 		select {
 		case <-time.After(delay.JitterDelay(5*time.Minute, 0.2)):
 		case <-ctx.Done():
@@ -1555,7 +2081,7 @@ func (a *agent) sendToTelemetry(ctx context.Context) error {
 			telemetry.PostInformation(ctx, telemetryID, a.config.Agent.Telemetry.Address, a.BleemeoAgentID(), facts)
 
 			select {
-			case <-time.After(delay.JitterDelay(24*time.Hour, 0.05)):
+			case <-time.After(delay.JitterDelay(interval, 0.05)):
 			case <-ctx.Done():
 				return nil
 			}
@@ -1611,8 +2137,13 @@ func (a *agent) startTasks(tasks []taskInfo) {
 	a.l.Lock()
 	defer a.l.Unlock()
 
+	// Start the critical tasks first.
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].priority > tasks[j].priority
+	})
+
 	for _, t := range tasks {
-		id, err := a.taskRegistry.AddTask(t.function, t.name)
+		id, err := a.taskRegistry.AddTaskWithPriority(t.function, t.name, t.priority)
 		if err != nil {
 			logger.V(1).Printf("Unable to start %s: %v", t.name, err)
 		}
@@ -1661,6 +2192,7 @@ func (a *agent) watchdog(ctx context.Context) error {
 
 			n := runtime.Stack(buffer, true)
 			logger.Printf("%s", string(buffer[:n]))
+			a.uploadDiagnosticArchive()
 			logger.Printf("Glouton seems unhealthy, killing myself")
 			panic("Glouton seems unhealthy (health check is no longer running), killing myself")
 		default:
@@ -1680,11 +2212,11 @@ func (a *agent) healthCheck(ctx context.Context) error {
 			return nil
 		}
 
-		mandatoryTasks := []string{"Bleemeo SAAS connector", "Metric collector", "Metric store"}
 		for _, name := range mandatoryTasks {
 			if crashed, err := a.doesTaskCrashed(ctx, name); crashed && err != nil {
 				logger.Printf("Task %#v crashed: %v", name, err)
 				logger.Printf("Stopping the agent as task %#v is critical", name)
+				a.uploadDiagnosticArchive()
 				a.cancel()
 			}
 		}
@@ -1707,6 +2239,87 @@ func (a *agent) healthCheck(ctx context.Context) error {
 	}
 }
 
+// uploadDiagnosticArchive writes the diagnostic archive and uploads it to the configured
+// object store. Since Glouton is unhealthy, writing the diagnostic may hang: the files
+// written so far are uploaded after a timeout.
+func (a *agent) uploadDiagnosticArchive() {
+	cfg := a.config.Agent.DiagnosticUpload
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), diagnosticUploadTimeout)
+	defer cancel()
+
+	client := &objectstore.Client{
+		Endpoint:  cfg.Endpoint,
+		Bucket:    cfg.Bucket,
+		Region:    cfg.Region,
+		AccessKey: cfg.AccessKey,
+		SecretKey: cfg.SecretKey,
+	}
+
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "unknown"
+	}
+
+	key := fmt.Sprintf("glouton-diagnostic-%s-%s.tar", hostname, time.Now().UTC().Format("20060102T150405Z"))
+	archive := objectstore.NewArchiveWriter(client)
+	done := make(chan struct{})
+
+	writeCtx, cancelWrite := context.WithTimeout(ctx, diagnosticWriteTimeout)
+	defer cancelWrite()
+
+	go func() {
+		defer crashreport.ProcessPanic()
+		defer close(done)
+
+		if err := a.writeDiagnosticArchive(writeCtx, archive); err != nil {
+			logger.V(1).Printf("Failed to write the diagnostic archive: %v", err)
+		}
+	}()
+
+	var err error
+
+	select {
+	case <-done:
+		err = archive.Upload(ctx, key)
+	case <-writeCtx.Done():
+		logger.Printf("Writing the diagnostic archive didn't finish on time, uploading the partial archive")
+
+		err = archive.UploadPartial(ctx, key)
+	}
+
+	if err != nil {
+		logger.Printf("Failed to upload the diagnostic archive: %v", err)
+
+		return
+	}
+
+	logger.Printf("Diagnostic archive uploaded to %s/%s", cfg.Bucket, key)
+}
+
+// mandatoryTasksUp returns whether each started mandatory task is still running.
+func (a *agent) mandatoryTasksUp(ctx context.Context) map[string]bool {
+	result := make(map[string]bool, len(mandatoryTasks))
+
+	for _, name := range mandatoryTasks {
+		a.l.Lock()
+		_, started := a.taskIDs[name]
+		a.l.Unlock()
+
+		if !started {
+			continue
+		}
+
+		crashed, _ := a.doesTaskCrashed(ctx, name)
+		result[name] = !crashed
+	}
+
+	return result
+}
+
 // Return true if the given task exited before ctx was terminated
 // Also return the error the tasks returned.
 func (a *agent) doesTaskCrashed(ctx context.Context, name string) (bool, error) {
@@ -1905,8 +2518,17 @@ func (a *agent) FireTrigger(discovery bool, sendFacts bool, systemUpdateMetric b
 	a.triggerLock.Lock()
 	defer a.triggerLock.Unlock()
 
+	now := time.Now()
+
 	if discovery {
-		a.triggerDiscImmediate = true
+		if now.Before(a.firstDiscoveryAt) {
+			// The discovery is postponed until the end of the initial delay.
+			if a.triggerDiscAt.Before(a.firstDiscoveryAt) {
+				a.triggerDiscAt = a.firstDiscoveryAt
+			}
+		} else {
+			a.triggerDiscImmediate = true
+		}
 	}
 
 	if sendFacts {
@@ -1920,7 +2542,11 @@ func (a *agent) FireTrigger(discovery bool, sendFacts bool, systemUpdateMetric b
 	// Some discovery requests ask for a second discovery in 1 minutes.
 	// The second discovery allows to discover services that are slow to start
 	if secondDiscovery {
-		deadline := time.Now().Add(time.Minute)
+		deadline := now.Add(time.Minute)
+		if deadline.Before(a.firstDiscoveryAt) {
+			deadline = a.firstDiscoveryAt
+		}
+
 		a.triggerDiscAt = deadline
 	}
 
@@ -1941,6 +2567,42 @@ func (a *agent) cleanTrigger() (discovery bool, sendFacts bool, systemUpdateMetr
 	return
 }
 
+// containerMetricsAllowed returns the function restricting the containers
+// for which metrics are gathered, or nil when all containers are allowed.
+func (a *agent) containerMetricsAllowed() func(facts.Container) bool {
+	if a.containerMetricsFilter.IsEmpty() {
+		return nil
+	}
+
+	return a.containerMetricsFilter.MetricsAllowed
+}
+
+// updatePodmanInput adds or removes the input gathering Podman containers metrics.
+// Podman exposes a Docker compatible API, so the Docker input is used.
+func (a *agent) updatePodmanInput(ctx context.Context) {
+	hasConnection := a.podmanRuntime.IsRuntimeRunning(ctx)
+	if hasConnection && !a.podmanInputPresent && a.config.Telegraf.DockerMetricsEnable {
+		i, err := docker.New(
+			a.podmanRuntime.ServerAddress(),
+			a.podmanRuntime,
+			a.containerFilter.ContainerIgnored,
+			a.containerMetricsAllowed(),
+		)
+		if err != nil {
+			logger.V(1).Printf("error when creating Podman input: %v", err)
+		} else {
+			logger.V(2).Printf("Enable Podman metrics")
+
+			a.podmanInputID, _ = a.collector.AddInput(i, "podman")
+			a.podmanInputPresent = true
+		}
+	} else if !hasConnection && a.podmanInputPresent {
+		logger.V(2).Printf("Disable Podman metrics")
+		a.collector.RemoveInput(a.podmanInputID)
+		a.podmanInputPresent = false
+	}
+}
+
 func (a *agent) handleTrigger(ctx context.Context) {
 	runDiscovery, runFact, runSystemUpdateMetric := a.cleanTrigger()
 	if runDiscovery {
@@ -1959,8 +2621,10 @@ func (a *agent) handleTrigger(ctx context.Context) {
 			}
 
 			if a.dynamicScrapper != nil {
-				if containers, err := a.containerRuntime.Containers(ctx, time.Hour, false); err == nil {
-					a.dynamicScrapper.Update(containers)
+				// Without container runtime, the services could still be scraped.
+				containers, err := a.containerRuntime.Containers(ctx, time.Hour, false)
+				if err == nil || errors.As(err, &facts.NoRuntimeError{}) {
+					a.dynamicScrapper.Update(containers, services)
 				}
 			}
 
@@ -1972,7 +2636,12 @@ func (a *agent) handleTrigger(ctx context.Context) {
 
 		hasConnection := a.dockerRuntime.IsRuntimeRunning(ctx)
 		if hasConnection && !a.dockerInputPresent && a.config.Telegraf.DockerMetricsEnable {
-			i, err := docker.New(a.dockerRuntime.ServerAddress(), a.dockerRuntime, a.containerFilter.ContainerIgnored)
+			i, err := docker.New(
+				a.dockerRuntime.ServerAddress(),
+				a.dockerRuntime,
+				a.containerFilter.ContainerIgnored,
+				a.containerMetricsAllowed(),
+			)
 			if err != nil {
 				logger.V(1).Printf("error when creating Docker input: %v", err)
 			} else {
@@ -1986,6 +2655,10 @@ func (a *agent) handleTrigger(ctx context.Context) {
 			a.collector.RemoveInput(a.dockerInputID)
 			a.dockerInputPresent = false
 		}
+
+		if a.podmanRuntime != nil {
+			a.updatePodmanInput(ctx)
+		}
 	}
 
 	if runFact {
@@ -2154,6 +2827,7 @@ func (a *agent) writeDiagnosticArchive(ctx context.Context, archive types.Archiv
 		a.taskRegistry.DiagnosticArchive,
 		a.store.DiagnosticArchive,
 		a.diagnosticConfig,
+		a.factProvider.DiagnosticArchive,
 		a.discovery.DiagnosticArchive,
 		a.diagnosticContainers,
 		a.diagnosticSNMP,
@@ -2359,6 +3033,8 @@ func (a *agent) diagnosticGloutonState(_ context.Context, archive types.ArchiveW
 		TriggerSystemUpdateMetric bool
 		DockerInputPresent        bool
 		DockerInputID             int
+		PodmanInputPresent        bool
+		PodmanInputID             int
 		MetricResolutionSeconds   float64
 		PahoLastPingCheckAt       time.Time
 		PathToStateDir            string
@@ -2374,6 +3050,8 @@ func (a *agent) diagnosticGloutonState(_ context.Context, archive types.ArchiveW
 		TriggerSystemUpdateMetric: a.triggerSystemUpdateMetric,
 		DockerInputPresent:        a.dockerInputPresent,
 		DockerInputID:             a.dockerInputID,
+		PodmanInputPresent:        a.podmanInputPresent,
+		PodmanInputID:             a.podmanInputID,
 		MetricResolutionSeconds:   a.metricResolution.Seconds(),
 		PahoLastPingCheckAt:       a.pahoLogWrapper.LastPingAt(),
 		PathToStateDir:            a.stateDir,
@@ -2525,6 +3203,52 @@ func (a *agent) diagnosticSNMP(ctx context.Context, archive types.ArchiveWriter)
 	return nil
 }
 
+// addStatsDInput adds a StatsD listener to the collector and returns whether it succeeded.
+func (a *agent) addStatsDInput(listener config.StatsDListener, datadogExtensions bool) bool {
+	address := fmt.Sprintf("%s:%d", listener.Address, listener.Port)
+
+	input, err := statsd.New(statsd.Options{
+		ServiceAddress:    address,
+		Protocol:          listener.Protocol,
+		Templates:         listener.Templates,
+		DatadogExtensions: datadogExtensions,
+	})
+	if err != nil {
+		logger.Printf("Unable to create StatsD input on %s: %v", address, err)
+
+		return false
+	}
+
+	if _, err = a.collector.AddInput(input, "statsd"); err != nil {
+		if strings.Contains(err.Error(), "address already in use") {
+			logger.Printf("Unable to listen on StatsD port %s because another program already use it", address)
+			logger.Printf("This StatsD listener is now disabled. Restart the agent to try re-enabling it.")
+			logger.Printf("See https://go.bleemeo.com/l/agent-configuration-statsd to permanently disable StatsD integration or using an alternate port")
+		} else {
+			logger.Printf("Unable to create StatsD input on %s: %v", address, err)
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// configHash returns the hex encoded SHA-256 of the configuration dump.
+// Secrets are censored in the dump, so changing a password doesn't change the hash.
+func configHash(cfg config.Config) string {
+	content, err := yaml.Marshal(config.Dump(cfg))
+	if err != nil {
+		logger.V(1).Printf("Unable to compute the configuration hash: %v", err)
+
+		return ""
+	}
+
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:])
+}
+
 func (a *agent) diagnosticConfig(_ context.Context, archive types.ArchiveWriter) error {
 	file, err := archive.Create("config.yaml")
 	if err != nil {
@@ -2738,6 +3462,31 @@ func setupContainer(hostRootPath string) {
 	}
 }
 
+// ValidateConfig runs the validations done when the agent starts
+// and returns the invalid values found in the configuration.
+func ValidateConfig(cfg config.Config) prometheus.MultiError {
+	var errs prometheus.MultiError
+
+	errs = append(errs, discovery.ValidateServices(cfg.Services)...)
+
+	if _, err := facts.NewContainerMetricsFilter(cfg.Container.MetricsAllowlist); err != nil {
+		errs.Append(fmt.Errorf("%w: container.metrics_allowlist: %w", config.ErrInvalidValue, err))
+	}
+
+	if types.StringToMetricFormat(cfg.Agent.MetricsFormat) == types.MetricFormatUnknown {
+		errs.Append(fmt.Errorf("%w: unsupported agent.metrics_format %q", config.ErrInvalidValue, cfg.Agent.MetricsFormat))
+	}
+
+	_, warnings := prometheusConfigToURLs(cfg.Metric.Prometheus.Targets)
+	errs = append(errs, warnings...)
+
+	if _, err := newMetricFilter(cfg, false, true, types.MetricFormatBleemeo); err != nil {
+		errs.Append(fmt.Errorf("%w: metric filter: %w", config.ErrInvalidValue, err))
+	}
+
+	return errs
+}
+
 // prometheusConfigToURLs convert metric.prometheus.targets config to a list of targets.
 // It returns the targets and some warnings.
 //
@@ -2762,9 +3511,15 @@ func prometheusConfigToURLs(configTargets []config.PrometheusTarget) ([]*scrappe
 				// correctly handles empty values (drop the label).
 				types.LabelMetaScrapeInstance: scrapper.HostPort(targetURL),
 			},
-			URL:       targetURL,
-			AllowList: configTarget.AllowMetrics,
-			DenyList:  configTarget.DenyMetrics,
+			URL:          targetURL,
+			AllowList:    configTarget.AllowMetrics,
+			DenyList:     configTarget.DenyMetrics,
+			HonorLabels:  configTarget.HonorLabels,
+			MetricPrefix: configTarget.MetricPrefix,
+		}
+
+		if configTarget.Source != "" {
+			target.ExtraLabels[types.LabelMetaScrapeSource] = configTarget.Source
 		}
 
 		targets = append(targets, target)
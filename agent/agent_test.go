@@ -19,11 +19,16 @@ package agent
 
 import (
 	"context"
+	"fmt"
+	"net"
 	"net/url"
 	"testing"
 	"time"
 
 	"github.com/bleemeo/glouton/config"
+	"github.com/bleemeo/glouton/debouncer"
+	"github.com/bleemeo/glouton/discovery"
+	"github.com/bleemeo/glouton/facts"
 	"github.com/bleemeo/glouton/prometheus/scrapper"
 	"github.com/bleemeo/glouton/store"
 	"github.com/bleemeo/glouton/types"
@@ -251,6 +256,21 @@ func Test_prometheusConfigToURLs(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:        "source-and-prefix",
+			cfgFilename: "testdata/source-prometheus-targets.conf",
+			want: []*scrapper.Target{
+				{
+					ExtraLabels: map[string]string{
+						types.LabelMetaScrapeJob:      "leaf1",
+						types.LabelMetaScrapeInstance: "leaf1:9090",
+						types.LabelMetaScrapeSource:   "leaf1",
+					},
+					URL:          mustParse("http://leaf1:9090/metrics"),
+					MetricPrefix: "leaf1_",
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -825,3 +845,332 @@ func TestUPSDBatteryStatus(t *testing.T) { //nolint:maintidx
 		})
 	}
 }
+
+func TestConfigHash(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.DefaultConfig()
+	cfg.Bleemeo.RegistrationKey = "secret"
+
+	hash := configHash(cfg)
+	if len(hash) != 64 {
+		t.Fatalf("configHash() = %q, want a SHA-256 hex digest", hash)
+	}
+
+	if hash2 := configHash(cfg); hash2 != hash {
+		t.Errorf("configHash() isn't stable: %q != %q", hash2, hash)
+	}
+
+	cfg.Bleemeo.RegistrationKey = "another-secret"
+
+	if hash2 := configHash(cfg); hash2 != hash {
+		t.Errorf("configHash() changed when a secret changed")
+	}
+
+	cfg.Web.Listener.Port++
+
+	if hash2 := configHash(cfg); hash2 == hash {
+		t.Errorf("configHash() didn't change when the config changed")
+	}
+
+	points := configHashPoints(time.Now(), hash)
+	if len(points) != 2 || points[1].Labels["hash"] != hash {
+		t.Errorf("configHashPoints() = %v", points)
+	}
+}
+
+func TestDiscoveredServicesPoints(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	services := []discovery.Service{
+		{Name: "mysql", ServiceType: discovery.MySQLService, Active: true},
+		{Name: "mysql", Instance: "db2", ServiceType: discovery.MySQLService, Active: true},
+		{Name: "nginx", ServiceType: discovery.NginxService, Active: true},
+		{Name: "redis", ServiceType: discovery.RedisService, Active: false},
+	}
+
+	want := []types.MetricPoint{
+		{
+			Point:  types.Point{Time: now, Value: 2},
+			Labels: map[string]string{types.LabelName: "agent_discovered_services", "type": "mysql"},
+		},
+		{
+			Point:  types.Point{Time: now, Value: 1},
+			Labels: map[string]string{types.LabelName: "agent_discovered_services", "type": "nginx"},
+		},
+	}
+
+	got := discoveredServicesPoints(now, services)
+
+	sortOpt := cmpopts.SortSlices(func(x, y types.MetricPoint) bool {
+		return x.Labels["type"] < y.Labels["type"]
+	})
+
+	if diff := cmp.Diff(want, got, sortOpt); diff != "" {
+		t.Errorf("discoveredServicesPoints() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestIsolatedNamespacesPoints(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	services := []discovery.Service{
+		{Name: "nginx", Active: true},
+		{Name: "sshd", Active: true, IsolatedNamespaces: []string{"mnt"}},
+		{Name: "redis", Active: true, IsolatedNamespaces: []string{"mnt", "pid"}, ContainerID: "1234", Instance: "redis-1"},
+		{Name: "postfix", Active: false, IsolatedNamespaces: []string{"mnt", "pid"}},
+	}
+
+	want := []types.MetricPoint{
+		{
+			Point: types.Point{Time: now, Value: 1},
+			Labels: map[string]string{
+				types.LabelName:    "service_isolated_namespaces",
+				types.LabelItem:    "",
+				types.LabelService: "sshd",
+				"namespaces":       "mnt",
+			},
+			Annotations: types.MetricAnnotations{
+				ServiceName: "sshd",
+			},
+		},
+	}
+
+	got := isolatedNamespacesPoints(now, services)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("isolatedNamespacesPoints() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLastDiscoveryPoints(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	if got := lastDiscoveryPoints(now, time.Time{}); got != nil {
+		t.Errorf("lastDiscoveryPoints() = %v, want nil when the discovery never ran", got)
+	}
+
+	want := []types.MetricPoint{
+		{
+			Point:  types.Point{Time: now, Value: 90},
+			Labels: map[string]string{types.LabelName: "agent_last_discovery_seconds"},
+		},
+	}
+
+	got := lastDiscoveryPoints(now, now.Add(-90*time.Second))
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("lastDiscoveryPoints() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDNSResolvePoints(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	lookupOK := func(context.Context, string) ([]string, error) {
+		return []string{"192.0.2.1"}, nil
+	}
+
+	lookupFailed := func(context.Context, string) ([]string, error) {
+		return nil, &net.DNSError{Err: "no such host", Name: "bleemeo.com", IsNotFound: true}
+	}
+
+	for _, test := range []struct {
+		name       string
+		lookupHost func(context.Context, string) ([]string, error)
+		wantOK     float64
+	}{
+		{name: "ok", lookupHost: lookupOK, wantOK: 1},
+		{name: "failed", lookupHost: lookupFailed, wantOK: 0},
+	} {
+		points := dnsResolvePoints(context.Background(), now, "bleemeo.com", test.lookupHost)
+
+		values := make(map[string]float64, len(points))
+
+		for _, point := range points {
+			values[point.Labels[types.LabelName]] = point.Value
+		}
+
+		if got := values["agent_dns_resolve_ok"]; got != test.wantOK {
+			t.Errorf("%s: agent_dns_resolve_ok = %v, want %v", test.name, got, test.wantOK)
+		}
+
+		if _, ok := values["agent_dns_resolve_seconds"]; !ok {
+			t.Errorf("%s: agent_dns_resolve_seconds is missing", test.name)
+		}
+	}
+}
+
+func TestTelemetryInterval(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		seconds int
+		want    time.Duration
+	}{
+		{seconds: 86400, want: 24 * time.Hour},
+		{seconds: 604800, want: 7 * 24 * time.Hour},
+		{seconds: 60, want: minTelemetryInterval},
+		{seconds: 0, want: minTelemetryInterval},
+	}
+
+	for _, c := range cases {
+		if got := telemetryInterval(c.seconds); got != c.want {
+			t.Errorf("telemetryInterval(%d) = %v, want %v", c.seconds, got, c.want)
+		}
+	}
+}
+
+func TestMandatoryTasksPoints(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	want := []types.MetricPoint{
+		{
+			Point:  types.Point{Time: now, Value: 1},
+			Labels: map[string]string{types.LabelName: "agent_mandatory_task_up", "task": "Metric collector"},
+		},
+		{
+			Point:  types.Point{Time: now, Value: 0},
+			Labels: map[string]string{types.LabelName: "agent_mandatory_task_up", "task": "Metric store"},
+		},
+	}
+
+	got := mandatoryTasksPoints(now, map[string]bool{"Metric collector": true, "Metric store": false})
+
+	sortOpt := cmpopts.SortSlices(func(x, y types.MetricPoint) bool {
+		return x.Labels["task"] < y.Labels["task"]
+	})
+
+	if diff := cmp.Diff(want, got, sortOpt); diff != "" {
+		t.Errorf("mandatoryTasksPoints() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestContainersCountPoints(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	containers := make([]facts.Container, 0, containerImagesTopN+3)
+	containers = append(containers,
+		facts.FakeContainer{FakeImageName: "nginx", FakeState: facts.ContainerRunning},
+		facts.FakeContainer{FakeImageName: "nginx", FakeState: facts.ContainerStopped},
+		facts.FakeContainer{FakeImageName: "redis", FakeState: facts.ContainerRunning},
+	)
+
+	for i := range containerImagesTopN {
+		containers = append(containers, facts.FakeContainer{
+			FakeImageName: fmt.Sprintf("image-%02d", i),
+			FakeState:     facts.ContainerCreated,
+		})
+	}
+
+	got := make(map[string]float64)
+
+	for _, p := range containersCountPoints(now, containers) {
+		got[types.LabelsToText(p.Labels)] = p.Value
+	}
+
+	want := map[string]float64{
+		`__name__="containers_count"`:                       2,
+		`__name__="containers_by_state",state="running"`:    2,
+		`__name__="containers_by_state",state="stopped"`:    1,
+		`__name__="containers_by_state",state="created"`:    float64(containerImagesTopN),
+		`__name__="containers_by_state",state="restarting"`: 0,
+		`__name__="containers_by_state",state="unknown"`:    0,
+		`__name__="containers_by_image",image="nginx"`:      2,
+		// The last images in alphabetical order are aggregated.
+		`__name__="containers_by_image",image="other"`: 2,
+	}
+
+	for i := range containerImagesTopN - 1 {
+		want[fmt.Sprintf(`__name__="containers_by_image",image="image-%02d"`, i)] = 1
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("containersCountPoints() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFactsInfoPoints(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	facts := map[string]string{
+		"fqdn":           "server.example.com",
+		"hostname":       "server",
+		"os_name":        "Ubuntu",
+		"kernel_release": "6.8.0-40-generic",
+		"virtual":        "",
+		"aws_ami_id":     "ami-1234",
+	}
+
+	points := factsInfoPoints(now, facts)
+	if len(points) != 2 {
+		t.Fatalf("factsInfoPoints() returned %d points, want 2", len(points))
+	}
+
+	if points[0].Labels[types.LabelName] != "agent_info" || points[0].Labels["fqdn"] != "server.example.com" {
+		t.Errorf("unexpected agent_info labels: %v", points[0].Labels)
+	}
+
+	wantHostLabels := map[string]string{
+		types.LabelName:  "host_info",
+		"fqdn":           "server.example.com",
+		"hostname":       "server",
+		"os_name":        "Ubuntu",
+		"kernel_release": "6.8.0-40-generic",
+	}
+
+	if diff := cmp.Diff(wantHostLabels, points[1].Labels); diff != "" {
+		t.Errorf("host_info labels mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, p := range points {
+		if p.Value != 1 {
+			t.Errorf("%s value = %v, want 1", p.Labels[types.LabelName], p.Value)
+		}
+	}
+}
+
+// TestFireTriggerInitialDelay checks that a discovery triggered during the
+// initial delay, e.g. by a container event, is postponed until its end.
+func TestFireTriggerInitialDelay(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := &agent{
+		triggerHandler:   debouncer.New(ctx, func(context.Context) {}, time.Hour, time.Hour),
+		firstDiscoveryAt: time.Now().Add(10 * time.Minute),
+	}
+
+	// A container start asks for an immediate and a second discovery.
+	a.FireTrigger(true, false, false, true)
+
+	if discovery, _, _ := a.cleanTrigger(); discovery {
+		t.Error("discovery was triggered during the initial delay")
+	}
+
+	if a.triggerDiscAt.Before(a.firstDiscoveryAt) {
+		t.Errorf("triggerDiscAt = %v, want after the initial delay (%v)", a.triggerDiscAt, a.firstDiscoveryAt)
+	}
+
+	// Once the delay elapsed, the discovery runs immediately.
+	a.firstDiscoveryAt = time.Now().Add(-time.Second)
+	a.triggerDiscAt = time.Time{}
+
+	a.FireTrigger(true, false, false, false)
+
+	if discovery, _, _ := a.cleanTrigger(); !discovery {
+		t.Error("discovery wasn't triggered after the initial delay")
+	}
+}
@@ -24,9 +24,12 @@ import (
 	"github.com/bleemeo/glouton/config"
 	"github.com/bleemeo/glouton/facts/container-runtime/veth"
 	"github.com/bleemeo/glouton/logger"
+	"github.com/bleemeo/glouton/prometheus/exporter/cgroup"
 	"github.com/bleemeo/glouton/prometheus/exporter/node"
+	"github.com/bleemeo/glouton/prometheus/registry"
 
 	"github.com/prometheus/procfs"
+	"golang.org/x/sys/unix"
 )
 
 func initOSSpecificParts(chan<- os.Signal) {
@@ -55,6 +58,30 @@ func (a *agent) registerOSSpecificComponents(vethProvider *veth.Provider) {
 			logger.Printf("Unable to start node_exporter, system metrics will be missing: %v", err)
 		}
 	}
+
+	if len(a.config.Agent.Cgroup.Slices) > 0 {
+		a.registerCgroupGatherer()
+	}
+}
+
+func (a *agent) registerCgroupGatherer() {
+	gatherer, err := cgroup.New(a.hostRootPath, a.config.Agent.Cgroup.Slices)
+	if err != nil {
+		logger.Printf("Unable to monitor cgroups: %v", err)
+
+		return
+	}
+
+	_, err = a.gathererRegistry.RegisterGatherer(
+		registry.RegistrationOption{
+			Description: "cgroup metrics",
+			JitterSeed:  0,
+		},
+		gatherer,
+	)
+	if err != nil {
+		logger.Printf("Unable to add cgroup gatherer: %v", err)
+	}
 }
 
 func getResidentMemoryOfSelf() uint64 {
@@ -70,3 +97,27 @@ func getResidentMemoryOfSelf() uint64 {
 
 	return uint64(s.ResidentMemory())
 }
+
+// getOpenFDsOfSelf returns the number of file descriptors opened by Glouton.
+func getOpenFDsOfSelf() (int, error) {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		// /dev/fd lists the file descriptors on systems without procfs (e.g. FreeBSD or macOS).
+		entries, err = os.ReadDir("/dev/fd")
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(entries), nil
+}
+
+// getMaxFDsOfSelf returns the maximum number of file descriptors Glouton could open.
+func getMaxFDsOfSelf() uint64 {
+	var limit unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_NOFILE, &limit); err != nil {
+		return 0
+	}
+
+	return uint64(limit.Cur) //nolint:unconvert, nolintlint // required for e.g., FreeBSD that has the field as int64
+}
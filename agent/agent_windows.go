@@ -25,6 +25,7 @@ import (
 	"github.com/bleemeo/glouton/facts/container-runtime/veth"
 	"github.com/bleemeo/glouton/logger"
 
+	"github.com/shirou/gopsutil/v3/process"
 	"github.com/yusufpapurcu/wmi"
 	"golang.org/x/sys/windows/svc"
 )
@@ -116,3 +117,20 @@ func (a *agent) registerOSSpecificComponents(*veth.Provider) {
 func getResidentMemoryOfSelf() uint64 {
 	return 0
 }
+
+// getOpenFDsOfSelf returns the number of handles opened by Glouton.
+func getOpenFDsOfSelf() (int, error) {
+	p, err := process.NewProcess(int32(os.Getpid()))
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := p.NumFDs()
+
+	return int(n), err
+}
+
+// getMaxFDsOfSelf returns 0 as Windows doesn't have a per-process limit on handles.
+func getMaxFDsOfSelf() uint64 {
+	return 0
+}
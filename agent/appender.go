@@ -19,21 +19,29 @@ package agent
 import (
 	"context"
 	"fmt"
+	"runtime"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bleemeo/glouton/discovery"
+	"github.com/bleemeo/glouton/facts"
 	crTypes "github.com/bleemeo/glouton/facts/container-runtime/types"
 	"github.com/bleemeo/glouton/logger"
 	"github.com/bleemeo/glouton/prometheus/model"
 	"github.com/bleemeo/glouton/prometheus/registry"
 	"github.com/bleemeo/glouton/store"
 	"github.com/bleemeo/glouton/types"
+	"github.com/bleemeo/glouton/version"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/prometheus/storage"
 )
 
+// containerImagesTopN is the number of images for which containers_by_image is emitted.
+const containerImagesTopN = 10
+
 // miscAppender collects container metrics.
 type miscAppender struct {
 	containerRuntime crTypes.RuntimeInterface
@@ -52,20 +60,7 @@ func (ma miscAppender) CollectWithState(ctx context.Context, state registry.Gath
 		return fmt.Errorf("gather on DockerProvider failed: %w", err)
 	}
 
-	countRunning := 0
-
-	for _, c := range containers {
-		if c.State().IsRunning() {
-			countRunning++
-		}
-	}
-
-	points = append(points, types.MetricPoint{
-		Point: types.Point{Time: state.T0, Value: float64(countRunning)},
-		Labels: map[string]string{
-			types.LabelName: "containers_count",
-		},
-	})
+	points = append(points, containersCountPoints(state.T0, containers)...)
 
 	err = model.SendPointsToAppender(points, app)
 	if err != nil {
@@ -77,14 +72,24 @@ func (ma miscAppender) CollectWithState(ctx context.Context, state registry.Gath
 
 // miscAppenderMinute collects various metrics every minutes.
 type miscAppenderMinute struct {
-	containerRuntime  crTypes.RuntimeInterface
-	discovery         *discovery.Discovery
-	store             *store.Store
-	hostRootPath      string
-	getConfigWarnings func() prometheus.MultiError
+	containerRuntime   crTypes.RuntimeInterface
+	discovery          *discovery.Discovery
+	store              *store.Store
+	hostRootPath       string
+	getConfigWarnings  func() prometheus.MultiError
+	configHash         string
+	checkStateWritable func() error
+	facts              func(ctx context.Context, maxAge time.Duration) (map[string]string, error)
+	mandatoryTasksUp   func(ctx context.Context) map[string]bool
+	dnsCanary          string
+	lookupHost         func(ctx context.Context, host string) ([]string, error)
 }
 
 func (ma miscAppenderMinute) CollectWithState(ctx context.Context, state registry.GatherState, app storage.Appender) error {
+	// Read the last discovery time before calling Discovery below, which may run
+	// a discovery and would hide how old the previous one was.
+	lastDiscovery := ma.discovery.LastUpdate()
+
 	points, err := ma.containerRuntime.MetricsMinute(ctx, state.T0)
 	if err != nil {
 		logger.V(2).Printf("container Runtime metrics gather failed: %v", err)
@@ -104,7 +109,7 @@ func (ma miscAppenderMinute) CollectWithState(ctx context.Context, state registr
 
 		switch srv.ServiceType { //nolint:exhaustive,nolintlint
 		case discovery.PostfixService:
-			n, err := postfixQueueSize(ctx, srv, ma.hostRootPath, ma.containerRuntime)
+			n, queues, err := postfixQueueSize(ctx, srv, ma.hostRootPath, ma.containerRuntime)
 			if err != nil {
 				logger.V(1).Printf("Unabled to gather postfix queue size on %s: %v", srv, err)
 
@@ -131,6 +136,21 @@ func (ma miscAppenderMinute) CollectWithState(ctx context.Context, state registr
 					Value: n,
 				},
 			})
+
+			for queue, count := range queues {
+				points = append(points, serviceMetricPoint(srv, "postfix_queue_messages", count, map[string]string{"queue": queue}))
+			}
+		case discovery.DovecotService:
+			connections, err := dovecotConnections(ctx, srv, ma.hostRootPath, ma.containerRuntime)
+			if err != nil {
+				logger.V(1).Printf("Unabled to gather dovecot connections on %s: %v", srv, err)
+
+				continue
+			}
+
+			for protocol, count := range connections {
+				points = append(points, serviceMetricPoint(srv, "dovecot_connections", count, map[string]string{"protocol": protocol}))
+			}
 		case discovery.EximService:
 			n, err := eximQueueSize(ctx, srv, ma.hostRootPath, ma.containerRuntime)
 			if err != nil {
@@ -186,6 +206,38 @@ func (ma miscAppenderMinute) CollectWithState(ctx context.Context, state registr
 		},
 	})
 
+	points = append(points, agentFDsPoints(state.T0)...)
+	points = append(points, discoveredServicesPoints(state.T0, service)...)
+	points = append(points, lastDiscoveryPoints(state.T0, lastDiscovery)...)
+	points = append(points, listeningPortsPoints(state.T0, ma.discovery.ListeningPorts())...)
+	points = append(points, isolatedNamespacesPoints(state.T0, service)...)
+	points = append(points, mandatoryTasksPoints(state.T0, ma.mandatoryTasksUp(ctx))...)
+
+	if ma.dnsCanary != "" {
+		points = append(points, dnsResolvePoints(ctx, state.T0, ma.dnsCanary, ma.lookupHost)...)
+	}
+
+	facts, err := ma.facts(ctx, 24*time.Hour)
+	if err != nil {
+		logger.V(1).Printf("Unable to get facts for the info metrics: %v", err)
+	} else {
+		points = append(points, factsInfoPoints(state.T0, facts)...)
+	}
+	points = append(points, configHashPoints(state.T0, ma.configHash)...)
+
+	stateWritable := 1.0
+
+	if err := ma.checkStateWritable(); err != nil {
+		logger.V(1).Printf("The state file isn't writable: %v", err)
+
+		stateWritable = 0
+	}
+
+	points = append(points, types.MetricPoint{
+		Point:  types.Point{Time: state.T0, Value: stateWritable},
+		Labels: map[string]string{types.LabelName: "agent_state_writable"},
+	})
+
 	// Add SMART status and UPSD battery status metrics.
 	points = append(
 		points,
@@ -204,6 +256,369 @@ func (ma miscAppenderMinute) CollectWithState(ctx context.Context, state registr
 	return app.Commit()
 }
 
+// serviceMetricPoint returns a point of a metric associated with the service.
+func serviceMetricPoint(srv discovery.Service, name string, value float64, extraLabels map[string]string) types.MetricPoint {
+	labels := map[string]string{
+		types.LabelName: name,
+		types.LabelItem: srv.Instance,
+	}
+
+	for k, v := range extraLabels {
+		labels[k] = v
+	}
+
+	return types.MetricPoint{
+		Labels: labels,
+		Annotations: types.MetricAnnotations{
+			BleemeoItem:     srv.Instance,
+			ContainerID:     srv.ContainerID,
+			ServiceName:     srv.Name,
+			ServiceInstance: srv.Instance,
+		},
+		Point: types.Point{
+			Time:  time.Now(),
+			Value: value,
+		},
+	}
+}
+
+// agentFDsPoints returns the number of file descriptors used by Glouton and its limit.
+// This allows to detect file descriptor leaks in Glouton itself.
+func agentFDsPoints(now time.Time) []types.MetricPoint {
+	openFDs, err := getOpenFDsOfSelf()
+	if err != nil {
+		logger.V(2).Printf("Unable to get the number of open file descriptors: %v", err)
+
+		return nil
+	}
+
+	points := []types.MetricPoint{
+		{
+			Point:  types.Point{Time: now, Value: float64(openFDs)},
+			Labels: map[string]string{types.LabelName: "agent_open_fds"},
+		},
+	}
+
+	if maxFDs := getMaxFDsOfSelf(); maxFDs > 0 {
+		points = append(points, types.MetricPoint{
+			Point:  types.Point{Time: now, Value: float64(maxFDs)},
+			Labels: map[string]string{types.LabelName: "agent_max_fds"},
+		})
+	}
+
+	return points
+}
+
+// dnsResolvePoints resolves the canary name and returns whether it succeeded and
+// how long it took. It detects host-level DNS failures, which break the scrapers and checks.
+func dnsResolvePoints(
+	ctx context.Context,
+	now time.Time,
+	canary string,
+	lookupHost func(ctx context.Context, host string) ([]string, error),
+) []types.MetricPoint {
+	const dnsResolveTimeout = 10 * time.Second
+
+	ctx, cancel := context.WithTimeout(ctx, dnsResolveTimeout)
+	defer cancel()
+
+	start := time.Now()
+	addrs, err := lookupHost(ctx, canary)
+	duration := time.Since(start)
+
+	resolveOK := 1.0
+
+	if err != nil || len(addrs) == 0 {
+		logger.V(1).Printf("Unable to resolve the DNS canary %s: %v", canary, err)
+
+		resolveOK = 0
+	}
+
+	return []types.MetricPoint{
+		{
+			Point:  types.Point{Time: now, Value: duration.Seconds()},
+			Labels: map[string]string{types.LabelName: "agent_dns_resolve_seconds"},
+		},
+		{
+			Point:  types.Point{Time: now, Value: resolveOK},
+			Labels: map[string]string{types.LabelName: "agent_dns_resolve_ok"},
+		},
+	}
+}
+
+// discoveredServicesPoints returns the number of active services discovered for each service type.
+func discoveredServicesPoints(now time.Time, services []discovery.Service) []types.MetricPoint {
+	countByType := make(map[discovery.ServiceName]int)
+
+	for _, srv := range services {
+		if !srv.Active {
+			continue
+		}
+
+		countByType[srv.ServiceType]++
+	}
+
+	points := make([]types.MetricPoint, 0, len(countByType))
+
+	for serviceType, count := range countByType {
+		points = append(points, types.MetricPoint{
+			Point: types.Point{Time: now, Value: float64(count)},
+			Labels: map[string]string{
+				types.LabelName: "agent_discovered_services",
+				"type":          string(serviceType),
+			},
+		})
+	}
+
+	return points
+}
+
+// lastDiscoveryPoints returns the age in seconds of the last discovery run.
+// It allows to alert when the discovery silently stops.
+func lastDiscoveryPoints(now time.Time, lastUpdate time.Time) []types.MetricPoint {
+	if lastUpdate.IsZero() {
+		return nil
+	}
+
+	return []types.MetricPoint{
+		{
+			Point:  types.Point{Time: now, Value: now.Sub(lastUpdate).Seconds()},
+			Labels: map[string]string{types.LabelName: "agent_last_discovery_seconds"},
+		},
+	}
+}
+
+// containersCountPoints returns the number of running containers, the number of containers
+// per state and the number of containers for the most used images. Images outside
+// the top containerImagesTopN are counted with the image "other" to bound the cardinality.
+func containersCountPoints(now time.Time, containers []facts.Container) []types.MetricPoint {
+	countRunning := 0
+	countByState := map[facts.ContainerState]int{
+		facts.ContainerUnknown:    0,
+		facts.ContainerCreated:    0,
+		facts.ContainerRunning:    0,
+		facts.ContainerRestarting: 0,
+		facts.ContainerStopped:    0,
+	}
+	countByImage := make(map[string]int)
+
+	for _, c := range containers {
+		if c.State().IsRunning() {
+			countRunning++
+		}
+
+		countByState[c.State()]++
+		countByImage[c.ImageName()]++
+	}
+
+	points := make([]types.MetricPoint, 0, 1+len(countByState)+containerImagesTopN+1)
+
+	points = append(points, types.MetricPoint{
+		Point: types.Point{Time: now, Value: float64(countRunning)},
+		Labels: map[string]string{
+			types.LabelName: "containers_count",
+		},
+	})
+
+	for st, count := range countByState {
+		points = append(points, types.MetricPoint{
+			Point: types.Point{Time: now, Value: float64(count)},
+			Labels: map[string]string{
+				types.LabelName: "containers_by_state",
+				"state":         st.String(),
+			},
+		})
+	}
+
+	images := make([]string, 0, len(countByImage))
+	for image := range countByImage {
+		images = append(images, image)
+	}
+
+	sort.Slice(images, func(i, j int) bool {
+		if countByImage[images[i]] != countByImage[images[j]] {
+			return countByImage[images[i]] > countByImage[images[j]]
+		}
+
+		return images[i] < images[j]
+	})
+
+	countOther := 0
+
+	for i, image := range images {
+		if i >= containerImagesTopN {
+			countOther += countByImage[image]
+
+			continue
+		}
+
+		points = append(points, types.MetricPoint{
+			Point: types.Point{Time: now, Value: float64(countByImage[image])},
+			Labels: map[string]string{
+				types.LabelName: "containers_by_image",
+				"image":         image,
+			},
+		})
+	}
+
+	if countOther > 0 {
+		points = append(points, types.MetricPoint{
+			Point: types.Point{Time: now, Value: float64(countOther)},
+			Labels: map[string]string{
+				types.LabelName: "containers_by_image",
+				"image":         "other",
+			},
+		})
+	}
+
+	return points
+}
+
+// mandatoryTasksPoints returns the agent_mandatory_task_up metrics. The agent stops when
+// a mandatory task crashed, this metric allows to observe a task which stopped before that.
+func mandatoryTasksPoints(now time.Time, tasksUp map[string]bool) []types.MetricPoint {
+	points := make([]types.MetricPoint, 0, len(tasksUp))
+
+	for name, up := range tasksUp {
+		value := 0.0
+		if up {
+			value = 1
+		}
+
+		points = append(points, types.MetricPoint{
+			Point: types.Point{Time: now, Value: value},
+			Labels: map[string]string{
+				types.LabelName: "agent_mandatory_task_up",
+				"task":          name,
+			},
+		})
+	}
+
+	return points
+}
+
+// listeningPortsPoints returns the host_listening_port info metrics, one per port on which
+// a process listens. This allows to detect unexpected open ports.
+func listeningPortsPoints(now time.Time, ports []discovery.ListeningPort) []types.MetricPoint {
+	points := make([]types.MetricPoint, 0, len(ports))
+
+	for _, port := range ports {
+		points = append(points, types.MetricPoint{
+			Point: types.Point{Time: now, Value: 1},
+			Labels: map[string]string{
+				types.LabelName: "host_listening_port",
+				"port":          strconv.Itoa(port.Port),
+				"protocol":      port.Protocol,
+				"process":       port.Process,
+			},
+		})
+	}
+
+	return points
+}
+
+// isolatedNamespacesPoints returns the service_isolated_namespaces info metrics, one per service
+// running outside a container in a mount or PID namespace which differs from the host ones.
+// It's a separate metric, so the namespaces don't change the identity of the service status.
+func isolatedNamespacesPoints(now time.Time, services []discovery.Service) []types.MetricPoint {
+	var points []types.MetricPoint
+
+	for _, srv := range services {
+		if !srv.Active || srv.ContainerID != "" || len(srv.IsolatedNamespaces) == 0 {
+			continue
+		}
+
+		point := serviceMetricPoint(srv, "service_isolated_namespaces", 1, map[string]string{
+			types.LabelService: srv.Name,
+			"namespaces":       strings.Join(srv.IsolatedNamespaces, ","),
+		})
+		point.Time = now
+
+		points = append(points, point)
+	}
+
+	return points
+}
+
+// hostInfoFacts are the facts used as labels of the host_info metric.
+//
+//nolint:gochecknoglobals
+var hostInfoFacts = []string{
+	"fqdn",
+	"hostname",
+	"domain",
+	"os_name",
+	"os_version",
+	"os_codename",
+	"kernel_release",
+	"architecture",
+	"virtual",
+	"system_vendor",
+	"product_name",
+}
+
+// factsInfoPoints returns the agent_info and host_info metrics. These metrics follow
+// the Prometheus info pattern: the metadata are in the labels and the value is always 1.
+func factsInfoPoints(now time.Time, facts map[string]string) []types.MetricPoint {
+	agentLabels := map[string]string{
+		types.LabelName: "agent_info",
+		"version":       version.Version,
+		"commit":        version.BuildHash,
+		"os":            runtime.GOOS,
+		"arch":          runtime.GOARCH,
+		"fqdn":          facts["fqdn"],
+	}
+
+	hostLabels := map[string]string{
+		types.LabelName: "host_info",
+	}
+
+	for _, name := range hostInfoFacts {
+		if value := facts[name]; value != "" {
+			hostLabels[name] = value
+		}
+	}
+
+	return []types.MetricPoint{
+		{
+			Point:  types.Point{Time: now, Value: 1},
+			Labels: agentLabels,
+		},
+		{
+			Point:  types.Point{Time: now, Value: 1},
+			Labels: hostLabels,
+		},
+	}
+}
+
+// configHashPoints returns the digest of the running configuration. The numeric value
+// allows to compare hosts easily, the full hash is available in the info metric.
+func configHashPoints(now time.Time, configHash string) []types.MetricPoint {
+	if len(configHash) < 12 {
+		return nil
+	}
+
+	// Only keep the first 48 bits of the hash so the value is exactly representable by a float64.
+	digest, err := strconv.ParseUint(configHash[:12], 16, 64)
+	if err != nil {
+		return nil
+	}
+
+	return []types.MetricPoint{
+		{
+			Point:  types.Point{Time: now, Value: float64(digest)},
+			Labels: map[string]string{types.LabelName: "agent_config_hash"},
+		},
+		{
+			Point: types.Point{Time: now, Value: 1},
+			Labels: map[string]string{
+				types.LabelName: "agent_config_info",
+				"hash":          configHash,
+			},
+		},
+	}
+}
+
 // statusFromLastPoint returns points for the targetMetric based on the last point from baseMetricName.
 // statusDescription must return the status description based on the last point and labels of baseMetricName.
 // If statusDescription returns an unset status, the point is ignored.
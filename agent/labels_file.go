@@ -0,0 +1,206 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bleemeo/glouton/logger"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	labelsFileDebounceDelay = 2 * time.Second
+	// labelsFileRetryDelay is the delay between two attempts to watch the
+	// directory of the labels file when it doesn't exist.
+	labelsFileRetryDelay = time.Minute
+)
+
+var errInvalidLabelName = errors.New("invalid label name")
+
+// globalLabelsMerger merges the global labels of several sources, like the labels
+// file and the application version, and applies them.
+type globalLabelsMerger struct {
+	l        sync.Mutex
+	bySource map[string]map[string]string
+	update   func(map[string]string)
+}
+
+// updater returns a function which replaces the labels of the given source.
+func (m *globalLabelsMerger) updater(source string) func(map[string]string) {
+	return func(labels map[string]string) {
+		m.l.Lock()
+		defer m.l.Unlock()
+
+		if m.bySource == nil {
+			m.bySource = make(map[string]map[string]string)
+		}
+
+		m.bySource[source] = labels
+
+		merged := make(map[string]string)
+
+		for _, sourceLabels := range m.bySource {
+			for name, value := range sourceLabels {
+				merged[name] = value
+			}
+		}
+
+		m.update(merged)
+	}
+}
+
+// labelsFileWatcher applies the labels from agent.labels_file as global labels
+// and updates them when the file changes.
+type labelsFileWatcher struct {
+	path          string
+	updateLabels  func(map[string]string)
+	currentLabels map[string]string
+	// retryDelay overrides labelsFileRetryDelay, it's used in tests.
+	retryDelay time.Duration
+}
+
+// Run watches the labels file until the context is canceled.
+func (w *labelsFileWatcher) Run(ctx context.Context) error {
+	w.reload()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	defer watcher.Close()
+
+	var debounce, retry <-chan time.Time
+
+	if !w.watchDir(watcher) {
+		retry = time.After(w.getRetryDelay())
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-retry:
+			retry = nil
+
+			if !w.watchDir(watcher) {
+				retry = time.After(w.getRetryDelay())
+
+				continue
+			}
+
+			// The file may have been created with its directory.
+			w.reload()
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if filepath.Clean(event.Name) == filepath.Clean(w.path) {
+				debounce = time.After(labelsFileDebounceDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			logger.V(1).Printf("Error while watching labels file %s: %v", w.path, err)
+		case <-debounce:
+			debounce = nil
+
+			w.reload()
+		}
+	}
+}
+
+// watchDir watches the parent directory of the labels file, to keep receiving
+// events when the file is replaced. It returns false when the directory can't be
+// watched, e.g. because it doesn't exist yet.
+func (w *labelsFileWatcher) watchDir(watcher *fsnotify.Watcher) bool {
+	if err := watcher.Add(filepath.Dir(w.path)); err != nil {
+		logger.V(1).Printf("Unable to watch the labels file %s, will retry later: %v", w.path, err)
+
+		return false
+	}
+
+	return true
+}
+
+func (w *labelsFileWatcher) getRetryDelay() time.Duration {
+	if w.retryDelay > 0 {
+		return w.retryDelay
+	}
+
+	return labelsFileRetryDelay
+}
+
+func (w *labelsFileWatcher) reload() {
+	labels, err := readLabelsFile(w.path)
+	if err != nil {
+		logger.Printf("Unable to read labels file, keeping previous labels: %v", err)
+
+		return
+	}
+
+	if reflect.DeepEqual(labels, w.currentLabels) {
+		return
+	}
+
+	logger.V(1).Printf("Global labels from %s updated: %v", w.path, labels)
+
+	w.currentLabels = labels
+	w.updateLabels(labels)
+}
+
+// readLabelsFile reads a YAML map of label names to values.
+// A missing file is the same as a file without labels.
+func readLabelsFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	labels := make(map[string]string)
+
+	if err := yaml.Unmarshal(content, &labels); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	for name := range labels {
+		if !model.LabelName(name).IsValid() || strings.HasPrefix(name, model.ReservedLabelPrefix) {
+			return nil, fmt.Errorf("%w %q in %s", errInvalidLabelName, name, path)
+		}
+	}
+
+	return labels, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadLabelsFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr error
+	}{
+		{
+			name:    "labels",
+			content: "owner: alice\nteam: infra\n",
+			want:    map[string]string{"owner": "alice", "team": "infra"},
+		},
+		{
+			name:    "empty",
+			content: "",
+			want:    map[string]string{},
+		},
+		{
+			name:    "invalid-name",
+			content: "my-owner: alice\n",
+			wantErr: errInvalidLabelName,
+		},
+		{
+			name:    "reserved-name",
+			content: "__name__: alice\n",
+			wantErr: errInvalidLabelName,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(dir, tt.name+".yml")
+
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := readLabelsFile(path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("readLabelsFile() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("readLabelsFile() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	got, err := readLabelsFile(filepath.Join(dir, "missing.yml"))
+	if err != nil || len(got) != 0 {
+		t.Errorf("readLabelsFile() on missing file = %v, %v, want empty map", got, err)
+	}
+}
+
+// TestLabelsFileWatcherMissingDir checks that the watcher keeps retrying when
+// the directory of the labels file doesn't exist yet.
+func TestLabelsFileWatcherMissingDir(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "glouton")
+	updates := make(chan map[string]string, 10)
+
+	watcher := &labelsFileWatcher{
+		path:         filepath.Join(dir, "labels.yml"),
+		updateLabels: func(labels map[string]string) { updates <- labels },
+		retryDelay:   10 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+
+	go func() {
+		runErr <- watcher.Run(ctx)
+	}()
+
+	// The missing file is read as an empty list of labels.
+	select {
+	case labels := <-updates:
+		if len(labels) != 0 {
+			t.Errorf("labels = %v, want no labels", labels)
+		}
+	case err := <-runErr:
+		t.Fatalf("Run() returned %v before the directory was created", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the labels were never updated")
+	}
+
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(watcher.path, []byte("owner: alice\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case labels := <-updates:
+		if diff := cmp.Diff(map[string]string{"owner": "alice"}, labels); diff != "" {
+			t.Errorf("labels mismatch (-want +got):\n%s", diff)
+		}
+	case err := <-runErr:
+		t.Fatalf("Run() returned %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the labels were not updated after the directory was created")
+	}
+
+	cancel()
+
+	if err := <-runErr; err != nil {
+		t.Errorf("Run() = %v, want nil", err)
+	}
+}
@@ -43,11 +43,39 @@ import (
 var (
 	commonDefaultSystemMetrics = []string{
 		"agent_status",
+		"app_deployment",
 		types.MetricServiceStatus,
 		"system_pending_updates",
 		"system_pending_security_updates",
 		"time_drift",
 		"agent_config_warning",
+		"agent_open_fds",
+		"agent_max_fds",
+		"agent_config_hash",
+		"agent_config_info",
+		"agent_state_writable",
+		"agent_discovered_services",
+		"agent_last_discovery_seconds",
+		"agent_gather_duration_seconds",
+		"agent_mandatory_task_up",
+		"agent_dns_resolve_seconds",
+		"agent_dns_resolve_ok",
+		"agent_info",
+		"agent_bleemeo_metric_resolution_seconds",
+		"agent_bleemeo_metrics_allowlist_enabled",
+		"agent_bleemeo_metrics_dropped",
+		"host_info",
+		"host_listening_port",
+		"service_isolated_namespaces",
+		"cgroup_cpu_seconds",
+		"cgroup_memory_bytes",
+
+		// Pressure stall information
+		"pressure_cpu_some_avg10",
+		"pressure_memory_some_avg10",
+		"pressure_memory_full_avg10",
+		"pressure_io_some_avg10",
+		"pressure_io_full_avg10",
 
 		// Services metrics that are not classified as a service in common.serviceType
 
@@ -80,6 +108,8 @@ var (
 
 		// Docker
 		"containers_count",
+		"containers_by_state",
+		"containers_by_image",
 		"container_cpu_used",
 		"container_health_status",
 		"container_io_read_bytes",
@@ -89,6 +119,7 @@ var (
 		"container_mem_used_perc_status",
 		"container_net_bits_recv",
 		"container_net_bits_sent",
+		"container_disk_used_bytes",
 
 		// Prometheus scrapper
 		"process_cpu_seconds_total{scrape_job!=\"\"}",
@@ -104,6 +135,10 @@ var (
 		"probe_ssl_validation_success",
 		"probe_http_duration_seconds",
 		"probe_failed_due_to_tls_error",
+		"probe_icmp_duration_seconds",
+		"probe_icmp_packet_loss_ratio",
+		"probe_transaction_step_duration_seconds",
+		"probe_transaction_step_success",
 	}
 
 	promLinuxDefaultSystemMetrics = []string{
@@ -129,6 +164,8 @@ var (
 		"node_network_transmit_packets_total",
 		"node_network_receive_errs_total",
 		"node_network_transmit_errs_total",
+		"node_network_receive_drop_total",
+		"node_network_transmit_drop_total",
 	}
 
 	promLinuxSwapMetrics = []string{
@@ -220,6 +257,7 @@ var (
 		"process_status_sleeping",
 		"process_status_stopped",
 		"process_status_zombies",
+		"system_zombie_processes",
 		"process_total",
 		"process_total_threads",
 		"system_load1",
@@ -296,12 +334,26 @@ var (
 		"nvidia_smi_clocks_current_memory",
 		"nvidia_smi_clocks_current_video",
 
+		// Network namespaces
+		"netns_bits_recv",
+		"netns_bits_sent",
+		"netns_packets_recv",
+		"netns_packets_sent",
+		"netns_err_in",
+		"netns_err_out",
+		"netns_drop_in",
+		"netns_drop_out",
+
 		// Temperature
 		`{__name__="sensor_temperature", sensor=~"coretemp_package_id_.*"}`,
 		`{__name__="sensor_temperature", sensor="k10temp_tctl"}`,
 
 		// vSphere
 		"vsphere_vm_cpu_latency_perc",
+		"vsphere_vm_power_state",
+		"vsphere_connection_up",
+		"resource_pool_cpu_usage",
+		"resource_pool_mem_usage",
 
 		"vms_running_count",
 		"vms_stopped_count",
@@ -332,6 +384,13 @@ var (
 			"apache_scoreboard_open",
 		},
 
+		discovery.BindService: {
+			"bind_queries",
+			"bind_requests",
+			"bind_servfail",
+			"bind_cache_hit_perc",
+		},
+
 		discovery.BitBucketService: {
 			"bitbucket_events",
 			"bitbucket_io_tasks",
@@ -369,6 +428,16 @@ var (
 			"cassandra_write_time_average",
 		},
 
+		discovery.ClickHouseService: {
+			"clickhouse_queries_running",
+			"clickhouse_tcp_connections",
+			"clickhouse_http_connections",
+			"clickhouse_queries",
+			"clickhouse_failed_queries",
+			"clickhouse_inserted_rows",
+			"clickhouse_uptime_seconds",
+		},
+
 		discovery.ConfluenceService: {
 			"confluence_db_query_time",
 			"confluence_jvm_gc",
@@ -383,6 +452,10 @@ var (
 			"confluence_requests",
 		},
 
+		discovery.DovecotService: {
+			"dovecot_connections",
+		},
+
 		discovery.ElasticSearchService: {
 			"elasticsearch_docs_count",
 			"elasticsearch_jvm_gc",
@@ -616,6 +689,12 @@ var (
 			"openldap_operations_search_completed",
 		},
 
+		discovery.OpenVPNService: {
+			"openvpn_connected_clients",
+			"openvpn_client_bytes_received",
+			"openvpn_client_bytes_sent",
+		},
+
 		discovery.PHPFPMService: {
 			"phpfpm_accepted_conn",
 			"phpfpm_active_processes",
@@ -632,6 +711,7 @@ var (
 
 		discovery.PostfixService: {
 			"postfix_queue_size",
+			"postfix_queue_messages",
 		},
 
 		discovery.PostgreSQLService: {
@@ -694,6 +774,24 @@ var (
 			"redis_volatile_changes",
 		},
 
+		discovery.SquidService: {
+			"squid_requests",
+			"squid_server_requests",
+			"squid_hit_ratio",
+			"squid_clients",
+		},
+
+		discovery.TraefikService: {
+			"traefik_config_reloads_total",
+			"traefik_entrypoint_requests_total",
+			"traefik_entrypoint_request_duration_seconds_sum",
+			"traefik_entrypoint_request_duration_seconds_count",
+			"traefik_entrypoint_open_connections",
+			"traefik_service_requests_total",
+			"traefik_service_open_connections",
+			"traefik_service_server_up",
+		},
+
 		discovery.UPSDService: {
 			"upsd_battery_status",
 			"upsd_status_flags",
@@ -940,6 +1038,13 @@ func newMetricFilter(config config.Config, hasSNMP, hasSwap bool, format types.M
 		rawAllowList = append(rawAllowList, getDefaultMetrics(format, hasSwap)...)
 	}
 
+	// The metrics of the SQL queries are explicitly configured, so they are always allowed.
+	for _, query := range config.SQL.Queries {
+		for _, metricName := range query.Metrics {
+			rawAllowList = append(rawAllowList, metricName)
+		}
+	}
+
 	var warnings prometheus.MultiError
 
 	staticAllowList, warn := buildMatchersList(rawAllowList)
@@ -1506,7 +1506,7 @@ func Test_RebuildDefaultMetrics(t *testing.T) {
 		},
 		{
 			Active:      true,
-			ServiceType: discovery.BindService,
+			ServiceType: discovery.AsteriskService,
 		},
 	}
 
@@ -1519,9 +1519,9 @@ func Test_RebuildDefaultMetrics(t *testing.T) {
 		metricsNames = append(metricsNames, k)
 	}
 
-	want := []string{"postfix_queue_size"}
+	want := []string{"postfix_queue_messages", "postfix_queue_size"}
 
-	res := cmp.Diff(metricsNames, want, cmpopts.IgnoreUnexported(labels.Matcher{}))
+	res := cmp.Diff(metricsNames, want, cmpopts.IgnoreUnexported(labels.Matcher{}), cmpopts.SortSlices(func(x, y string) bool { return x < y }))
 
 	if res != "" {
 		t.Errorf("rebuildDefaultMetrics():\n%s", res)
@@ -28,7 +28,7 @@ import (
 )
 
 var (
-	errRunInContainer   = errors.New("can't gather the postfix running on host because Glouton run in a container")
+	errRunInContainer   = errors.New("can't gather the service running on host because Glouton run in a container")
 	errUnexpectedOutput = errors.New("postqueue output don't contains expected output")
 )
 
@@ -39,30 +39,41 @@ var (
 	postfixREEmpty = regexp.MustCompile(
 		`Mail queue is empty`,
 	)
+	postfixREQueueID = regexp.MustCompile(
+		`(?m)^[0-9A-Za-z]+([*!]?)\s+\d+\s+[A-Z][a-z]{2}\s`,
+	)
 )
 
 type dockerExecuter interface {
 	Exec(ctx context.Context, containerID string, cmd []string) ([]byte, error)
 }
 
-func postfixQueueSize(ctx context.Context, srv discovery.Service, hostRootPath string, docker dockerExecuter) (float64, error) {
-	if srv.ContainerID != "" {
-		out, err := docker.Exec(ctx, srv.ContainerID, []string{"postqueue", "-p"})
-		if err != nil {
-			return 0, err
-		}
+// postfixQueueSize returns the number of messages in the Postfix queue, in total
+// and by queue (active, deferred and hold).
+func postfixQueueSize(ctx context.Context, srv discovery.Service, hostRootPath string, docker dockerExecuter) (float64, map[string]float64, error) {
+	out, err := runServiceCommand(ctx, srv, hostRootPath, docker, []string{"postqueue", "-p"})
+	if err != nil {
+		return 0, nil, err
+	}
 
-		return parsePostfix(out)
-	} else if hostRootPath == "/" {
-		out, err := exec.Command("postqueue", "-p").Output()
-		if err != nil {
-			return 0, err
-		}
+	n, err := parsePostfix(out)
+	if err != nil {
+		return 0, nil, err
+	}
 
-		return parsePostfix(out)
+	return n, parsePostfixQueues(out), nil
+}
+
+// runServiceCommand runs the command in the container of the service, or on the host
+// when the service isn't in a container.
+func runServiceCommand(ctx context.Context, srv discovery.Service, hostRootPath string, docker dockerExecuter, cmd []string) ([]byte, error) {
+	if srv.ContainerID != "" {
+		return docker.Exec(ctx, srv.ContainerID, cmd)
+	} else if hostRootPath == "/" {
+		return exec.CommandContext(ctx, cmd[0], cmd[1:]...).Output()
 	}
 
-	return 0, errRunInContainer
+	return nil, errRunInContainer
 }
 
 func parsePostfix(output []byte) (n float64, err error) {
@@ -78,22 +89,62 @@ func parsePostfix(output []byte) (n float64, err error) {
 	return strconv.ParseFloat(string(result[1]), 64)
 }
 
-func eximQueueSize(ctx context.Context, srv discovery.Service, hostRootPath string, docker dockerExecuter) (float64, error) {
-	if srv.ContainerID != "" {
-		out, err := docker.Exec(ctx, srv.ContainerID, []string{"exim4", "-bpc"})
-		if err != nil {
-			return 0, err
+// parsePostfixQueues counts the messages of the postqueue output by queue. In this output,
+// the queue ID is followed by "*" for messages in the active queue and by "!" for
+// messages in the hold queue. Other messages are in the deferred queue.
+func parsePostfixQueues(output []byte) map[string]float64 {
+	queues := map[string]float64{
+		"active":   0,
+		"deferred": 0,
+		"hold":     0,
+	}
+
+	for _, match := range postfixREQueueID.FindAllSubmatch(output, -1) {
+		switch string(match[1]) {
+		case "*":
+			queues["active"]++
+		case "!":
+			queues["hold"]++
+		default:
+			queues["deferred"]++
 		}
+	}
 
-		return strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
-	} else if hostRootPath == "/" {
-		out, err := exec.Command("exim4", "-bpc").Output()
-		if err != nil {
-			return 0, err
+	return queues
+}
+
+// dovecotConnections returns the number of connections to Dovecot by protocol (imap, pop3...).
+func dovecotConnections(ctx context.Context, srv discovery.Service, hostRootPath string, docker dockerExecuter) (map[string]float64, error) {
+	out, err := runServiceCommand(ctx, srv, hostRootPath, docker, []string{"doveadm", "who", "-1"})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDoveadmWho(out), nil
+}
+
+// parseDoveadmWho counts the connections by protocol from the output of "doveadm who -1",
+// which contains one line per connection: username, protocol, PID and IP address.
+func parseDoveadmWho(output []byte) map[string]float64 {
+	connections := make(map[string]float64)
+
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] == "username" {
+			continue
 		}
 
-		return strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+		connections[fields[1]]++
+	}
+
+	return connections
+}
+
+func eximQueueSize(ctx context.Context, srv discovery.Service, hostRootPath string, docker dockerExecuter) (float64, error) {
+	out, err := runServiceCommand(ctx, srv, hostRootPath, docker, []string{"exim4", "-bpc"})
+	if err != nil {
+		return 0, err
 	}
 
-	return 0, errRunInContainer
+	return strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
 }
@@ -17,7 +17,11 @@
 //nolint:scopelint
 package agent
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
 
 func Test_parsePostfix(t *testing.T) {
 	tests := []struct {
@@ -66,3 +70,46 @@ func Test_parsePostfix(t *testing.T) {
 		})
 	}
 }
+
+func Test_parsePostfixQueues(t *testing.T) {
+	output := []byte(`-Queue ID-  --Size-- ----Arrival Time---- -Sender/Recipient-------
+1C92E7D564*    4357 Tue Jan 28 06:58:20  root
+                                         ubuntu-upgrades@example.com
+
+36BF87D65A     1363 Wed Feb 12 06:10:02  root
+(connect to mx.example.com[192.0.2.1]:25: Connection refused)
+                                         ubuntu-upgrades@example.com
+
+4Bt5Wr1sM6z9vQ3!   1024 Wed Feb 12 07:10:02  root
+                                         ubuntu-upgrades@example.com
+
+-- 6 Kbytes in 3 Requests.
+`)
+
+	want := map[string]float64{
+		"active":   1,
+		"deferred": 1,
+		"hold":     1,
+	}
+
+	if diff := cmp.Diff(want, parsePostfixQueues(output)); diff != "" {
+		t.Errorf("parsePostfixQueues() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_parseDoveadmWho(t *testing.T) {
+	output := []byte(`username                 proto pid   ip
+alice@example.com        imap  1234  192.0.2.1
+alice@example.com        imap  1235  192.0.2.2
+bob@example.com          pop3  1236  192.0.2.3
+`)
+
+	want := map[string]float64{
+		"imap": 2,
+		"pop3": 1,
+	}
+
+	if diff := cmp.Diff(want, parseDoveadmWho(output)); diff != "" {
+		t.Errorf("parseDoveadmWho() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -194,6 +194,40 @@ func (s *State) FileSizes() (int, int) {
 	return sizePersistent, sizeCache
 }
 
+// CheckWritable returns an error if the directory of the state file isn't writable,
+// for example because the disk is full or the filesystem was remounted read-only.
+func (s *State) CheckWritable() error {
+	s.l.RLock()
+	isInMemory := s.isInMemory
+	persistentPath := s.persistentPath
+	s.l.RUnlock()
+
+	if isInMemory || persistentPath == "" {
+		return nil
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(persistentPath), ".write-check-*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString("ok"); err != nil {
+		f.Close()
+
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
+
 // Close wait for any background write.
 func (s *State) Close() {
 	// To avoid dead-lock, it's important to acquire the read-lock before.
@@ -643,3 +643,34 @@ func TestSkipCacheWriteIfRemoved(t *testing.T) {
 		t.Fatalf("cache file IS created")
 	}
 }
+
+func TestCheckWritable(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := Load(filepath.Join(dir, "state.json"), filepath.Join(dir, "state.cache.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.CheckWritable(); err != nil {
+		t.Errorf("CheckWritable() = %v, want nil", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 0 {
+		t.Errorf("CheckWritable() left %d files in the state directory", len(entries))
+	}
+
+	s, err = Load(filepath.Join(dir, "missing-dir", "state.json"), filepath.Join(dir, "missing-dir", "state.cache.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.CheckWritable(); err == nil {
+		t.Errorf("CheckWritable() = nil, want an error for a missing directory")
+	}
+}
@@ -17,8 +17,11 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"crypto/subtle"
 	"embed"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
@@ -34,6 +37,8 @@ import (
 	"github.com/bleemeo/glouton/discovery"
 	"github.com/bleemeo/glouton/facts"
 	"github.com/bleemeo/glouton/logger"
+	"github.com/bleemeo/glouton/prometheus/exporter/blackbox"
+	"github.com/bleemeo/glouton/prometheus/model"
 	"github.com/bleemeo/glouton/prometheus/promql"
 	"github.com/bleemeo/glouton/threshold"
 	"github.com/bleemeo/glouton/types"
@@ -42,10 +47,18 @@ import (
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/go-chi/chi/v5"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	prometheusModel "github.com/prometheus/common/model"
 	"github.com/rs/cors"
 	_ "github.com/urfave/cli/v2" // Prevent go mod tidy from removing gqlgen dependencies
 )
 
+// maxPushGatewayBodySize is the maximum size of the metrics sent to the Pushgateway endpoint.
+const maxPushGatewayBodySize = 10 << 20
+
+var errInvalidGroupingPath = errors.New("invalid grouping path")
+
 //go:embed static
 var staticFolder embed.FS
 
@@ -53,6 +66,11 @@ type containerInterface interface {
 	Containers(ctx context.Context, maxAge time.Duration, includeIgnored bool) (containers []facts.Container, err error)
 }
 
+type monitorManagerInterface interface {
+	PauseMonitor(id string) error
+	ResumeMonitor(id string) error
+}
+
 type agentInterface interface {
 	BleemeoRegistrationAt() time.Time
 	BleemeoLastReport() time.Time
@@ -75,8 +93,19 @@ type API struct {
 	AgentInfo          agentInterface
 	PrometheurExporter http.Handler
 	Threshold          *threshold.Registry
+	MonitorManager     monitorManagerInterface
 	DiagnosticPage     func(ctx context.Context) string
 	DiagnosticArchive  func(ctx context.Context, w types.ArchiveWriter) error
+	// FireTrigger queues a discovery, a facts refresh and/or a system updates refresh.
+	FireTrigger func(runDiscovery bool, sendFacts bool, systemUpdateMetric bool)
+	// AuthToken protects the endpoints that alter the agent state when set.
+	AuthToken string
+	// PushGateway receives the points sent to the Pushgateway compatible endpoint.
+	// The endpoint is disabled when nil.
+	PushGateway types.PointPusher
+	// MetricsAPIEnabled enables the read-only JSON API on /api/v1/metrics and /api/v1/query.
+	// It's only served when the local UI is enabled.
+	MetricsAPIEnabled bool
 
 	router http.Handler
 }
@@ -156,6 +185,24 @@ func (api *API) init() {
 	}
 
 	promql := promql.PromQL{}
+	router.Post("/api/v1/monitors/{id}/pause", api.monitorHandler(func(id string) error {
+		return api.MonitorManager.PauseMonitor(id)
+	}))
+	router.Post("/api/v1/monitors/{id}/resume", api.monitorHandler(func(id string) error {
+		return api.MonitorManager.ResumeMonitor(id)
+	}))
+	router.Post("/api/v1/refresh", api.refreshHandler)
+
+	if api.PushGateway != nil {
+		router.Put("/metrics/job/*", api.pushGatewayHandler)
+		router.Post("/metrics/job/*", api.pushGatewayHandler)
+	}
+
+	if api.MetricsAPIEnabled && !api.LocalUIDisabled {
+		router.Get("/api/v1/metrics", api.metricsListHandler)
+		router.Get("/api/v1/query", api.metricsQueryHandler)
+	}
+
 	router.Mount("/api/v1", promql.Register(api.DB))
 	router.Handle("/metrics", api.PrometheurExporter)
 	router.Handle("/playground", playground.Handler("GraphQL playground", "/graphql"))
@@ -267,6 +314,165 @@ func (api *API) init() {
 	api.router = router
 }
 
+// monitorHandler returns a handler applying the action on the monitor given in the URL.
+func (api *API) monitorHandler(action func(id string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !api.isAuthorized(r) {
+			http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+
+			return
+		}
+
+		if api.MonitorManager == nil {
+			http.Error(w, "monitors are not enabled on this agent", http.StatusServiceUnavailable)
+
+			return
+		}
+
+		err := action(chi.URLParam(r, "id"))
+		if errors.Is(err, blackbox.ErrUnknownMonitor) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// refreshRequest is the body of a refresh request. An empty body refreshes the discovery and the facts.
+type refreshRequest struct {
+	Discovery     bool `json:"discovery"`
+	Facts         bool `json:"facts"`
+	SystemUpdates bool `json:"system_updates"`
+}
+
+// refreshHandler queues an immediate discovery and/or facts refresh.
+func (api *API) refreshHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.isAuthorized(r) {
+		http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+
+		return
+	}
+
+	if api.FireTrigger == nil {
+		http.Error(w, "refresh is not available", http.StatusServiceUnavailable)
+
+		return
+	}
+
+	request := refreshRequest{Discovery: true, Facts: true}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 4096))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if len(bytes.TrimSpace(body)) > 0 {
+		request = refreshRequest{}
+
+		if err := json.Unmarshal(body, &request); err != nil {
+			http.Error(w, fmt.Sprintf("invalid body: %v", err), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	api.FireTrigger(request.Discovery, request.Facts, request.SystemUpdates)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// pushGatewayHandler accepts metrics in the Prometheus text format, like the
+// Pushgateway does on "/metrics/job/<job>{/<label>/<value>}". The job and the
+// grouping labels are added to all pushed points.
+func (api *API) pushGatewayHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.isAuthorized(r) {
+		http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+
+		return
+	}
+
+	groupingLabels, err := parsePushGatewayPath(strings.TrimPrefix(r.URL.Path, "/metrics/job/"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	var parser expfmt.TextParser
+
+	families, err := parser.TextToMetricFamilies(io.LimitReader(r.Body, maxPushGatewayBodySize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid body: %v", err), http.StatusBadRequest)
+
+		return
+	}
+
+	familyList := make([]*dto.MetricFamily, 0, len(families))
+	for _, family := range families {
+		familyList = append(familyList, family)
+	}
+
+	points := model.FamiliesToMetricPoints(time.Now(), familyList, true)
+
+	for _, point := range points {
+		for name, value := range groupingLabels {
+			point.Labels[name] = value
+		}
+	}
+
+	api.PushGateway.PushPoints(r.Context(), points)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// parsePushGatewayPath returns the job and the grouping labels from
+// the path following "/metrics/job/", like "my_job/instance/host1".
+func parsePushGatewayPath(groupingPath string) (map[string]string, error) {
+	parts := strings.Split(strings.Trim(groupingPath, "/"), "/")
+
+	if parts[0] == "" {
+		return nil, fmt.Errorf("%w: the job name is required", errInvalidGroupingPath)
+	}
+
+	if len(parts)%2 != 1 {
+		return nil, fmt.Errorf("%w: a label has no value", errInvalidGroupingPath)
+	}
+
+	groupingLabels := map[string]string{"job": parts[0]}
+
+	for i := 1; i < len(parts); i += 2 {
+		if !prometheusModel.LabelName(parts[i]).IsValid() {
+			return nil, fmt.Errorf("%w: invalid label name %q", errInvalidGroupingPath, parts[i])
+		}
+
+		groupingLabels[parts[i]] = parts[i+1]
+	}
+
+	return groupingLabels, nil
+}
+
+// isAuthorized returns whether the request has the bearer token required by the API.
+// All requests are authorized when no token is configured.
+func (api *API) isAuthorized(r *http.Request) bool {
+	if api.AuthToken == "" {
+		return true
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(api.AuthToken)) == 1
+}
+
 func (api *API) diagnosticArchive(ctx context.Context, archive types.ArchiveWriter) error {
 	if err := api.DiagnosticArchive(ctx, archive); err != nil {
 		currentFile := archive.CurrentFileName()
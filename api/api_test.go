@@ -0,0 +1,280 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bleemeo/glouton/types"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestRefreshHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		token      string
+		body       string
+		wantStatus int
+		wantCall   []bool
+	}{
+		{
+			name:       "empty-body",
+			wantStatus: http.StatusAccepted,
+			wantCall:   []bool{true, true, false},
+		},
+		{
+			name:       "selected",
+			body:       `{"system_updates": true}`,
+			wantStatus: http.StatusAccepted,
+			wantCall:   []bool{false, false, true},
+		},
+		{
+			name:       "valid-token",
+			token:      "secret",
+			body:       `{"discovery": true}`,
+			wantStatus: http.StatusAccepted,
+			wantCall:   []bool{true, false, false},
+		},
+		{
+			name:       "invalid-body",
+			body:       `{"discovery": "yes"`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotCall []bool
+
+			api := &API{
+				AuthToken: tt.token,
+				FireTrigger: func(runDiscovery bool, sendFacts bool, systemUpdateMetric bool) {
+					gotCall = []bool{runDiscovery, sendFacts, systemUpdateMetric}
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/refresh", strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			rec := httptest.NewRecorder()
+			api.refreshHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+
+			if len(gotCall) != len(tt.wantCall) {
+				t.Fatalf("FireTrigger called with %v, want %v", gotCall, tt.wantCall)
+			}
+
+			for i := range gotCall {
+				if gotCall[i] != tt.wantCall[i] {
+					t.Errorf("FireTrigger called with %v, want %v", gotCall, tt.wantCall)
+				}
+			}
+		})
+	}
+}
+
+func TestRefreshHandlerUnauthorized(t *testing.T) {
+	t.Parallel()
+
+	called := false
+
+	api := &API{
+		AuthToken: "secret",
+		FireTrigger: func(bool, bool, bool) {
+			called = true
+		},
+	}
+
+	for _, header := range []string{"", "Bearer wrong", "secret"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/refresh", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+
+		rec := httptest.NewRecorder()
+		api.refreshHandler(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("status with Authorization %q = %d, want %d", header, rec.Code, http.StatusUnauthorized)
+		}
+	}
+
+	if called {
+		t.Error("FireTrigger was called without a valid token")
+	}
+}
+
+type fakeMonitorManager struct {
+	paused []string
+}
+
+func (m *fakeMonitorManager) PauseMonitor(id string) error {
+	m.paused = append(m.paused, id)
+
+	return nil
+}
+
+func (m *fakeMonitorManager) ResumeMonitor(string) error {
+	return nil
+}
+
+func TestMonitorHandlerUnauthorized(t *testing.T) {
+	t.Parallel()
+
+	manager := &fakeMonitorManager{}
+	api := &API{
+		AuthToken:      "secret",
+		MonitorManager: manager,
+	}
+
+	handler := api.monitorHandler(manager.PauseMonitor)
+
+	for _, header := range []string{"", "Bearer wrong", "secret"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/monitors/1234/pause", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("status with Authorization %q = %d, want %d", header, rec.Code, http.StatusUnauthorized)
+		}
+	}
+
+	if len(manager.paused) > 0 {
+		t.Errorf("monitors %v were paused without a valid token", manager.paused)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/monitors/1234/pause", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("status with a valid token = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
+
+type pointsRecorder struct {
+	points []types.MetricPoint
+}
+
+func (p *pointsRecorder) PushPoints(_ context.Context, points []types.MetricPoint) {
+	p.points = append(p.points, points...)
+}
+
+func TestPushGatewayHandler(t *testing.T) {
+	t.Parallel()
+
+	const body = `# TYPE backup_duration_seconds gauge
+backup_duration_seconds{database="main"} 42.5
+# TYPE backup_last_success_timestamp_seconds gauge
+backup_last_success_timestamp_seconds 1.7095464e+09
+`
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+		wantLabels []map[string]string
+	}{
+		{
+			name:       "job",
+			path:       "/metrics/job/backup",
+			body:       body,
+			wantStatus: http.StatusOK,
+			wantLabels: []map[string]string{
+				{types.LabelName: "backup_duration_seconds", "database": "main", "job": "backup"},
+				{types.LabelName: "backup_last_success_timestamp_seconds", "job": "backup"},
+			},
+		},
+		{
+			name:       "grouping-labels",
+			path:       "/metrics/job/backup/instance/db1/database/replica",
+			body:       body,
+			wantStatus: http.StatusOK,
+			wantLabels: []map[string]string{
+				{types.LabelName: "backup_duration_seconds", "database": "replica", "instance": "db1", "job": "backup"},
+				{types.LabelName: "backup_last_success_timestamp_seconds", "database": "replica", "instance": "db1", "job": "backup"},
+			},
+		},
+		{
+			name:       "missing-label-value",
+			path:       "/metrics/job/backup/instance",
+			body:       body,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing-job",
+			path:       "/metrics/job/",
+			body:       body,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid-body",
+			path:       "/metrics/job/backup",
+			body:       "backup_duration_seconds{ 42\n",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			recorder := &pointsRecorder{}
+			api := &API{PushGateway: recorder}
+
+			req := httptest.NewRequest(http.MethodPut, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			api.pushGatewayHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			gotLabels := make([]map[string]string, 0, len(recorder.points))
+			for _, point := range recorder.points {
+				gotLabels = append(gotLabels, point.Labels)
+			}
+
+			sortLabels := func(x, y map[string]string) bool { return x[types.LabelName] < y[types.LabelName] }
+			if diff := cmp.Diff(tt.wantLabels, gotLabels, cmpopts.SortSlices(sortLabels), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("labels mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
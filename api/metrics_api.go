@@ -0,0 +1,279 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bleemeo/glouton/logger"
+	"github.com/bleemeo/glouton/types"
+)
+
+const (
+	defaultMetricsLimit = 100
+	maxMetricsLimit     = 1000
+	// defaultQueryRange is the range returned by the query endpoint when no start is given.
+	// It's also how far back the last value of a metric is looked for.
+	defaultQueryRange = time.Hour
+)
+
+var errInvalidParameter = errors.New("invalid parameter")
+
+type metricsListResponse struct {
+	Total   int                `json:"total"`
+	Offset  int                `json:"offset"`
+	Limit   int                `json:"limit"`
+	Metrics []metricsListEntry `json:"metrics"`
+}
+
+type metricsListEntry struct {
+	Labels        map[string]string `json:"labels"`
+	LastValue     *float64          `json:"last_value"`
+	LastTimestamp *time.Time        `json:"last_timestamp"`
+}
+
+type metricsQueryResponse struct {
+	Series []metricsQuerySeries `json:"series"`
+}
+
+type metricsQuerySeries struct {
+	Labels map[string]string   `json:"labels"`
+	Points []metricsQueryPoint `json:"points"`
+}
+
+type metricsQueryPoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// metricsListHandler lists the metrics with their last value. The metrics are sorted
+// by labels and paginated with the "limit" and "offset" parameters.
+func (api *API) metricsListHandler(w http.ResponseWriter, r *http.Request) {
+	limit, err := intParameter(r, "limit", defaultMetricsLimit)
+	if err == nil && (limit <= 0 || limit > maxMetricsLimit) {
+		err = fmt.Errorf("%w: limit must be between 1 and %d", errInvalidParameter, maxMetricsLimit)
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	offset, err := intParameter(r, "offset", 0)
+	if err == nil && offset < 0 {
+		err = fmt.Errorf("%w: offset must be positive", errInvalidParameter)
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	metrics, err := api.DB.Metrics(map[string]string{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	sortMetrics(metrics)
+
+	response := metricsListResponse{
+		Total:   len(metrics),
+		Offset:  offset,
+		Limit:   limit,
+		Metrics: make([]metricsListEntry, 0, limit),
+	}
+
+	// Only read the points of the metrics on the requested page.
+	pageStart := min(offset, len(metrics))
+	page := metrics[pageStart:min(pageStart+limit, len(metrics))]
+	now := time.Now()
+
+	for _, metric := range page {
+		entry := metricsListEntry{Labels: metric.Labels()}
+
+		points, err := metric.Points(now.Add(-defaultQueryRange), now)
+		if err == nil && len(points) > 0 {
+			last := points[len(points)-1]
+			entry.LastValue = jsonFloat(last.Value)
+			entry.LastTimestamp = &last.Time
+		}
+
+		response.Metrics = append(response.Metrics, entry)
+	}
+
+	writeJSON(w, response)
+}
+
+// metricsQueryHandler returns the points of the metrics with the name given in the
+// "metric" parameter between "start" and "end". The last hour is returned by default.
+func (api *API) metricsQueryHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("metric")
+	if name == "" {
+		http.Error(w, "the metric parameter is required", http.StatusBadRequest)
+
+		return
+	}
+
+	end, err := timeParameter(r, "end", time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	start, err := timeParameter(r, "start", end.Add(-defaultQueryRange))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if start.After(end) {
+		http.Error(w, "start must be before end", http.StatusBadRequest)
+
+		return
+	}
+
+	metrics, err := api.DB.Metrics(map[string]string{types.LabelName: name})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	sortMetrics(metrics)
+
+	response := metricsQueryResponse{
+		Series: make([]metricsQuerySeries, 0, len(metrics)),
+	}
+
+	for _, metric := range metrics {
+		points, err := metric.Points(start, end)
+		if err != nil {
+			// The metric may have been deleted since it was listed.
+			logger.V(2).Printf("Failed to read the points of %s: %v", types.LabelsToText(metric.Labels()), err)
+
+			continue
+		}
+
+		series := metricsQuerySeries{
+			Labels: metric.Labels(),
+			Points: make([]metricsQueryPoint, 0, len(points)),
+		}
+
+		for _, point := range points {
+			// NaN and infinite values can't be encoded in JSON.
+			if math.IsNaN(point.Value) || math.IsInf(point.Value, 0) {
+				continue
+			}
+
+			series.Points = append(series.Points, metricsQueryPoint{Time: point.Time, Value: point.Value})
+		}
+
+		response.Series = append(response.Series, series)
+	}
+
+	writeJSON(w, response)
+}
+
+// sortMetrics sorts the metrics by labels, so the pagination is stable.
+func sortMetrics(metrics []types.Metric) {
+	keys := make([]string, len(metrics))
+
+	for i, metric := range metrics {
+		keys[i] = types.LabelsToText(metric.Labels())
+	}
+
+	sort.Sort(metricsByLabels{metrics: metrics, keys: keys})
+}
+
+type metricsByLabels struct {
+	metrics []types.Metric
+	keys    []string
+}
+
+func (m metricsByLabels) Len() int           { return len(m.metrics) }
+func (m metricsByLabels) Less(i, j int) bool { return m.keys[i] < m.keys[j] }
+func (m metricsByLabels) Swap(i, j int) {
+	m.metrics[i], m.metrics[j] = m.metrics[j], m.metrics[i]
+	m.keys[i], m.keys[j] = m.keys[j], m.keys[i]
+}
+
+// intParameter returns the integer value of a query parameter, or defaultValue when it's not set.
+func intParameter(r *http.Request, name string, defaultValue int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s must be an integer", errInvalidParameter, name)
+	}
+
+	return result, nil
+}
+
+// timeParameter returns the time of a query parameter given as a RFC 3339 date or
+// a Unix timestamp, or defaultValue when it's not set.
+func timeParameter(r *http.Request, name string, defaultValue time.Time) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	if timestamp, err := strconv.ParseFloat(value, 64); err == nil {
+		seconds, fraction := math.Modf(timestamp)
+
+		return time.Unix(int64(seconds), int64(fraction*1e9)), nil
+	}
+
+	result, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s must be a RFC 3339 date or a Unix timestamp", errInvalidParameter, name)
+	}
+
+	return result, nil
+}
+
+// jsonFloat returns a pointer to the value, or nil when the value can't be encoded in JSON.
+func jsonFloat(value float64) *float64 {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil
+	}
+
+	return &value
+}
+
+func writeJSON(w http.ResponseWriter, response any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.V(2).Printf("Failed to write the API response: %v", err)
+	}
+}
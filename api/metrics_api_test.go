@@ -0,0 +1,264 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bleemeo/glouton/store"
+	"github.com/bleemeo/glouton/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func newMetricsAPITest(t *testing.T, now time.Time) *API {
+	t.Helper()
+
+	metricStore := store.New(time.Hour, time.Hour)
+
+	var points []types.MetricPoint
+
+	for i := range 3 {
+		for _, item := range []string{"/", "/home"} {
+			points = append(points, types.MetricPoint{
+				Labels: map[string]string{types.LabelName: "disk_used_perc", types.LabelItem: item},
+				Point:  types.Point{Time: now.Add(time.Duration(i-2) * 10 * time.Second), Value: float64(10 * (i + 1))},
+			})
+		}
+
+		points = append(points, types.MetricPoint{
+			Labels: map[string]string{types.LabelName: "cpu_used"},
+			Point:  types.Point{Time: now.Add(time.Duration(i-2) * 10 * time.Second), Value: float64(i)},
+		})
+	}
+
+	metricStore.PushPoints(context.Background(), points)
+
+	return &API{
+		DB:                NewQueryable(metricStore, func() string { return "" }),
+		MetricsAPIEnabled: true,
+	}
+}
+
+func TestMetricsListHandler(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().Truncate(time.Second)
+	api := newMetricsAPITest(t, now)
+
+	tests := []struct {
+		query      string
+		wantStatus int
+		wantNames  []string
+		wantTotal  int
+	}{
+		{
+			query:      "",
+			wantStatus: http.StatusOK,
+			wantNames:  []string{"cpu_used", "disk_used_perc,item=/", "disk_used_perc,item=/home"},
+			wantTotal:  3,
+		},
+		{
+			query:      "?limit=1&offset=1",
+			wantStatus: http.StatusOK,
+			wantNames:  []string{"disk_used_perc,item=/"},
+			wantTotal:  3,
+		},
+		{
+			query:      "?offset=10",
+			wantStatus: http.StatusOK,
+			wantNames:  []string{},
+			wantTotal:  3,
+		},
+		{
+			query:      "?limit=0",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			query:      fmt.Sprintf("?limit=%d", maxMetricsLimit+1),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			query:      "?offset=abc",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/metrics"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			api.metricsListHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response metricsListResponse
+
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+
+			if response.Total != tt.wantTotal {
+				t.Errorf("total = %d, want %d", response.Total, tt.wantTotal)
+			}
+
+			gotNames := make([]string, 0, len(response.Metrics))
+
+			for _, metric := range response.Metrics {
+				name := metric.Labels[types.LabelName]
+				if item := metric.Labels[types.LabelItem]; item != "" {
+					name += ",item=" + item
+				}
+
+				gotNames = append(gotNames, name)
+
+				if metric.LastValue == nil || metric.LastTimestamp == nil || !metric.LastTimestamp.Equal(now) {
+					t.Errorf("metric %s has no last value at %v", name, now)
+				}
+			}
+
+			if diff := cmp.Diff(tt.wantNames, gotNames); diff != "" {
+				t.Errorf("metrics mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMetricsQueryHandler(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().Truncate(time.Second)
+	api := newMetricsAPITest(t, now)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		want       metricsQueryResponse
+	}{
+		{
+			name:       "default-range",
+			query:      "?metric=cpu_used",
+			wantStatus: http.StatusOK,
+			want: metricsQueryResponse{
+				Series: []metricsQuerySeries{
+					{
+						Labels: map[string]string{types.LabelName: "cpu_used"},
+						Points: []metricsQueryPoint{
+							{Time: now.Add(-20 * time.Second), Value: 0},
+							{Time: now.Add(-10 * time.Second), Value: 1},
+							{Time: now, Value: 2},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "unix-range",
+			query:      fmt.Sprintf("?metric=disk_used_perc&start=%d&end=%d", now.Add(-15*time.Second).Unix(), now.Add(-5*time.Second).Unix()),
+			wantStatus: http.StatusOK,
+			want: metricsQueryResponse{
+				Series: []metricsQuerySeries{
+					{
+						Labels: map[string]string{types.LabelName: "disk_used_perc", types.LabelItem: "/"},
+						Points: []metricsQueryPoint{{Time: now.Add(-10 * time.Second), Value: 20}},
+					},
+					{
+						Labels: map[string]string{types.LabelName: "disk_used_perc", types.LabelItem: "/home"},
+						Points: []metricsQueryPoint{{Time: now.Add(-10 * time.Second), Value: 20}},
+					},
+				},
+			},
+		},
+		{
+			name:       "rfc3339-range",
+			query:      "?metric=cpu_used&start=" + now.Add(-5*time.Second).Format(time.RFC3339),
+			wantStatus: http.StatusOK,
+			want: metricsQueryResponse{
+				Series: []metricsQuerySeries{
+					{
+						Labels: map[string]string{types.LabelName: "cpu_used"},
+						Points: []metricsQueryPoint{{Time: now, Value: 2}},
+					},
+				},
+			},
+		},
+		{
+			name:       "unknown-metric",
+			query:      "?metric=mem_used",
+			wantStatus: http.StatusOK,
+			want:       metricsQueryResponse{Series: []metricsQuerySeries{}},
+		},
+		{
+			name:       "missing-metric",
+			query:      "",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid-start",
+			query:      "?metric=cpu_used&start=yesterday",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "start-after-end",
+			query:      fmt.Sprintf("?metric=cpu_used&start=%d&end=%d", now.Unix(), now.Add(-time.Minute).Unix()),
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/query"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			api.metricsQueryHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got metricsQueryResponse
+
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("response mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
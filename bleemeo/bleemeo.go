@@ -468,7 +468,7 @@ func (c *Connector) RelabelHook(ctx context.Context, labels map[string]string) (
 	if labels[gloutonTypes.LabelMetaSNMPTarget] != "" {
 		var target *snmp.Target
 
-		for _, t := range c.option.SNMP {
+		for _, t := range c.option.SNMP() {
 			if t.Address() == labels[gloutonTypes.LabelMetaSNMPTarget] {
 				target = t
 
@@ -714,7 +714,7 @@ func (c *Connector) DiagnosticSNMPAssociation(ctx context.Context, file io.Write
 		}
 	}
 
-	for _, t := range c.option.SNMP {
+	for _, t := range c.option.SNMP() {
 		agent, err := c.sync.FindSNMPAgent(ctx, t, snmpTypeID, c.cache.AgentsByUUID())
 		if err != nil {
 			fmt.Fprintf(file, " * %s => %v\n", t.String(ctx), err)
@@ -988,9 +988,62 @@ func (c *Connector) EmitInternalMetric(_ context.Context, state registry.GatherS
 		}
 	}
 
+	if currentConfig, ok := c.cache.CurrentAccountConfig(); ok {
+		if err := appendAccountConfigPoints(app, currentConfig.AgentConfigByName[bleemeo.AgentType_Agent]); err != nil {
+			return err
+		}
+	}
+
+	if c.option.Config.Bleemeo.MaxMetrics > 0 && c.sync != nil {
+		_, err := app.Append(
+			0,
+			labels.FromMap(map[string]string{
+				gloutonTypes.LabelName: "agent_bleemeo_metrics_dropped",
+			}),
+			0, // Use time from Registry
+			float64(c.sync.DroppedMetricCount()),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
 	return app.Commit()
 }
 
+// appendAccountConfigPoints appends the metrics describing the limits of the account
+// configuration: the metric resolution and whether a metrics allowlist is applied.
+func appendAccountConfigPoints(app storage.Appender, agentConfig types.GloutonAgentConfig) error {
+	allowlistEnabled := 0.0
+	if len(agentConfig.MetricsAllowlist) > 0 {
+		allowlistEnabled = 1
+	}
+
+	points := []struct {
+		name  string
+		value float64
+	}{
+		{name: "agent_bleemeo_metric_resolution_seconds", value: agentConfig.MetricResolution.Seconds()},
+		{name: "agent_bleemeo_metrics_allowlist_enabled", value: allowlistEnabled},
+	}
+
+	for _, p := range points {
+		_, err := app.Append(
+			0,
+			labels.FromMap(map[string]string{
+				gloutonTypes.LabelName: p.name,
+			}),
+			0, // Use time from Registry
+			p.value,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (c *Connector) IsMetricAllowed(metric gloutonTypes.LabelsAndAnnotation) (bool, types.DenyReason, error) {
 	f := filter.NewFilter(c.cache)
 
@@ -27,6 +27,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bleemeo/bleemeo-go"
@@ -89,6 +90,8 @@ type Client struct {
 	// Whether we should log that all failed points have
 	// been processed during the next health check.
 	shouldLogRecovery bool
+	// Number of points dropped because their payload alone exceeds the maximum payload size.
+	droppedLargePoints atomic.Int64
 
 	l             sync.Mutex
 	startedAt     time.Time
@@ -153,6 +156,9 @@ func New(opts Option) *Client {
 		TooManyErrorsHandler: checkDuplicate,
 		ID:                   "Bleemeo",
 		PahoLastPingCheckAt:  opts.PahoLastPingCheckAt,
+		KeepAlive:            time.Duration(opts.Config.Bleemeo.MQTT.KeepAlive) * time.Second,
+		MinReconnectDelay:    time.Duration(opts.Config.Bleemeo.MQTT.ReconnectMin) * time.Second,
+		MaxReconnectDelay:    time.Duration(opts.Config.Bleemeo.MQTT.ReconnectMax) * time.Second,
 	})
 
 	return c
@@ -329,6 +335,7 @@ func (c *Client) DiagnosticArchive(ctx context.Context, archive types.ArchiveWri
 		PendingPointsCount         int
 		SendingSuspended           bool
 		DisableReason              bleemeoTypes.DisableReason
+		DroppedLargePointsCount    int64
 	}{
 		StartedAt:                  c.startedAt,
 		LastRegisteredMetricsCount: c.lastRegisteredMetricsCount,
@@ -336,6 +343,7 @@ func (c *Client) DiagnosticArchive(ctx context.Context, archive types.ArchiveWri
 		PendingPointsCount:         len(c.pendingPoints),
 		SendingSuspended:           c.sendingSuspended,
 		DisableReason:              c.disableReason,
+		DroppedLargePointsCount:    c.droppedLargePoints.Load(),
 	}
 
 	enc := json.NewEncoder(file)
@@ -556,13 +564,45 @@ func (c *Client) sendPoints() {
 				end = len(agentPayload)
 			}
 
-			if err := c.mqtt.Publish(fmt.Sprintf("v1/agent/%s/data", agentID), agentPayload[i:end], true); err != nil {
-				logger.V(1).Printf("Unable to publish points: %v", err)
+			for _, batch := range c.splitPayload(agentPayload[i:end]) {
+				if err := c.mqtt.Publish(fmt.Sprintf("v1/agent/%s/data", agentID), batch, true); err != nil {
+					logger.V(1).Printf("Unable to publish points: %v", err)
+				}
 			}
 		}
 	}
 }
 
+// splitPayload splits the points in batches whose JSON encoding doesn't exceed
+// the configured maximum payload size. Points which exceed the limit alone are dropped.
+// The size is computed before compression, so the published messages are smaller.
+func (c *Client) splitPayload(points []metricPayload) [][]metricPayload {
+	maxBytes := c.opts.Config.Bleemeo.MQTT.MaxPayloadBytes
+	if maxBytes <= 0 || len(points) == 0 {
+		return [][]metricPayload{points}
+	}
+
+	encoded, err := json.Marshal(points)
+	if err != nil || len(encoded) <= maxBytes {
+		return [][]metricPayload{points}
+	}
+
+	if len(points) == 1 {
+		c.droppedLargePoints.Add(1)
+
+		logger.V(1).Printf(
+			"Dropping point of metric %s: its payload is %d bytes which is more than the maximum of %d bytes",
+			points[0].LabelsText, len(encoded), maxBytes,
+		)
+
+		return nil
+	}
+
+	middle := len(points) / 2
+
+	return append(c.splitPayload(points[:middle]), c.splitPayload(points[middle:])...)
+}
+
 func (c *Client) sendPointsMakePayload(points []types.MetricPoint) map[bleemeoTypes.AgentID][]metricPayload {
 	c.l.Lock()
 	defer c.l.Unlock()
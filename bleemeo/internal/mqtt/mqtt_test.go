@@ -18,9 +18,11 @@ package mqtt
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -438,3 +440,56 @@ func BenchmarkFailedPointsDropping(b *testing.B) {
 		}
 	}
 }
+
+func TestSplitPayload(t *testing.T) {
+	t.Parallel()
+
+	c := mockClient(t)
+
+	points := make([]metricPayload, 0, 10)
+	for i := range 10 {
+		points = append(points, metricPayload{
+			LabelsText:  fmt.Sprintf(`__name__="metric_%d"`, i),
+			TimestampMS: 1700000000000,
+			Value:       float64(i),
+		})
+	}
+
+	points = append(points, metricPayload{
+		LabelsText:        `__name__="large_metric"`,
+		StatusDescription: strings.Repeat("x", 1000),
+	})
+
+	c.opts.Config.Bleemeo.MQTT.MaxPayloadBytes = 300
+
+	batches := c.splitPayload(points)
+
+	count := 0
+
+	for _, batch := range batches {
+		encoded, err := json.Marshal(batch)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(encoded) > 300 {
+			t.Errorf("batch of %d bytes exceeds the limit", len(encoded))
+		}
+
+		count += len(batch)
+	}
+
+	if count != 10 {
+		t.Errorf("got %d points in batches, want 10", count)
+	}
+
+	if dropped := c.droppedLargePoints.Load(); dropped != 1 {
+		t.Errorf("droppedLargePoints = %d, want 1", dropped)
+	}
+
+	c.opts.Config.Bleemeo.MQTT.MaxPayloadBytes = 0
+
+	if batches := c.splitPayload(points); len(batches) != 1 || len(batches[0]) != len(points) {
+		t.Errorf("splitPayload() without limit returned %d batches", len(batches))
+	}
+}
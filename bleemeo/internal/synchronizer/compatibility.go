@@ -35,6 +35,7 @@ type synchronizerState struct {
 	lastVSphereUpdate     time.Time
 	metricRetryAt         time.Time
 	lastMetricCount       int
+	droppedMetricCount    int
 	lastMetricActivation  time.Time
 
 	onDemandDiagnostic synchronizerOnDemandDiagnostic
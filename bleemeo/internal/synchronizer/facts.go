@@ -67,7 +67,7 @@ func (s *Synchronizer) syncFacts(ctx context.Context, syncType types.SyncType, e
 
 	previousFacts := s.option.Cache.FactsByKey()
 
-	allAgentFacts := make(map[string]map[string]string, 1+len(s.option.SNMP))
+	allAgentFacts := make(map[string]map[string]string, 1+len(s.option.SNMP()))
 	allAgentFacts[s.agentID] = localFacts
 
 	if !execution.IsOnlyEssential() {
@@ -78,7 +78,7 @@ func (s *Synchronizer) syncFacts(ctx context.Context, syncType types.SyncType, e
 
 		remoteAgentList := s.option.Cache.AgentsByUUID()
 
-		for _, t := range s.option.SNMP {
+		for _, t := range s.option.SNMP() {
 			if agent, err := s.FindSNMPAgent(ctx, t, agentTypeID, remoteAgentList); err == nil {
 				facts, err := t.Facts(ctx, 24*time.Hour)
 				if err != nil {
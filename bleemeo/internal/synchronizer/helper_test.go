@@ -171,7 +171,7 @@ func (helper *syncTestHelper) initSynchronizer(t *testing.T) {
 			NotifyFirstRegistration:    func() {},
 			MetricFormat:               helper.MetricFormat,
 			Process:                    mockProcessLister{},
-			SNMP:                       helper.SNMP,
+			SNMP:                       func() []*snmp.Target { return helper.SNMP },
 			SNMPOnlineTarget:           func() int { return len(helper.SNMP) },
 			NotifyLabelsUpdate:         helper.NotifyLabelsUpdate,
 			VSphereDevices:             func(context.Context, time.Duration) []bleemeoTypes.VSphereDevice { return helper.devices },
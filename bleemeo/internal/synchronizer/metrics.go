@@ -40,7 +40,10 @@ import (
 )
 
 // agentStatusName is the name of the special metrics used to store the agent connection status.
-const agentStatusName = "agent_status"
+const (
+	agentStatusName    = "agent_status"
+	metricsDroppedName = "agent_bleemeo_metrics_dropped"
+)
 
 // Those constant are here to make linter happy. We should likely drop them and use boolean type,
 // they are only used in API call and I'm pretty sure the API accept boolean.
@@ -161,7 +164,9 @@ type metricComparator struct {
 	isGoodItem        *regexp.Regexp
 }
 
-func newComparator(format gloutonTypes.MetricFormat) *metricComparator {
+// newComparator returns a metricComparator for the given format.
+// extraEssentials are metric names added to the essential metrics.
+func newComparator(format gloutonTypes.MetricFormat, extraEssentials []string) *metricComparator {
 	essentials := []string{
 		"node_cpu_seconds_global",
 		"node_cpu_seconds_total",
@@ -186,6 +191,7 @@ func newComparator(format gloutonTypes.MetricFormat) *metricComparator {
 		"node_network_receive_errs_total",
 		"node_network_transmit_errs_total",
 		"agent_config_warning",
+		metricsDroppedName,
 		agentStatusName,
 	}
 
@@ -256,7 +262,7 @@ func newComparator(format gloutonTypes.MetricFormat) *metricComparator {
 		"system_pending_security_updates", "system_pending_security_updates_status",
 	}
 
-	isEssentials := make(map[string]bool, len(essentials))
+	isEssentials := make(map[string]bool, len(essentials)+len(extraEssentials))
 	isHighCard := make(map[string]bool, len(highCard))
 	isImportant := make(map[string]bool, len(important))
 
@@ -264,6 +270,10 @@ func newComparator(format gloutonTypes.MetricFormat) *metricComparator {
 		isEssentials[v] = true
 	}
 
+	for _, v := range extraEssentials {
+		isEssentials[v] = true
+	}
+
 	for _, v := range highCard {
 		isHighCard[v] = true
 	}
@@ -359,8 +369,13 @@ func (m *metricComparator) isLess(metricA map[string]string, metricB map[string]
 	}
 }
 
-func prioritizeAndFilterMetrics(format gloutonTypes.MetricFormat, metrics []gloutonTypes.Metric, onlyEssential bool) []gloutonTypes.Metric {
-	cmp := newComparator(format)
+func prioritizeAndFilterMetrics(
+	format gloutonTypes.MetricFormat,
+	metrics []gloutonTypes.Metric,
+	onlyEssential bool,
+	extraEssentials []string,
+) []gloutonTypes.Metric {
+	cmp := newComparator(format, extraEssentials)
 
 	if onlyEssential {
 		i := 0
@@ -384,6 +399,34 @@ func prioritizeAndFilterMetrics(format gloutonTypes.MetricFormat, metrics []glou
 	return metrics
 }
 
+// capMetrics keeps at most bleemeo.max_metrics metrics. The metrics must be sorted by
+// priority, the lowest priority metrics are dropped. The dropped metrics are not
+// registered and are deactivated if they were already registered.
+func (s *Synchronizer) capMetrics(metrics []gloutonTypes.Metric) []gloutonTypes.Metric {
+	maxMetrics := s.option.Config.Bleemeo.MaxMetrics
+	dropped := 0
+
+	if maxMetrics > 0 && len(metrics) > maxMetrics {
+		dropped = len(metrics) - maxMetrics
+		metrics = metrics[:maxMetrics]
+	}
+
+	s.state.l.Lock()
+	defer s.state.l.Unlock()
+
+	if dropped > 0 && dropped != s.state.droppedMetricCount {
+		logger.Printf(
+			"Too many metrics, %d metrics won't be sent to Bleemeo because bleemeo.max_metrics is set to %d",
+			dropped,
+			maxMetrics,
+		)
+	}
+
+	s.state.droppedMetricCount = dropped
+
+	return metrics
+}
+
 func httpResponseToMetricFailureKind(content string) bleemeoTypes.FailureKind {
 	switch {
 	case strings.Contains(content, "metric is not whitelisted"):
@@ -558,7 +601,13 @@ func (s *Synchronizer) syncMetrics(ctx context.Context, syncType types.SyncType,
 	}
 
 	filteredMetrics = s.filterMetrics(localMetrics)
-	filteredMetrics = prioritizeAndFilterMetrics(s.option.MetricFormat, filteredMetrics, execution.IsOnlyEssential())
+	filteredMetrics = prioritizeAndFilterMetrics(
+		s.option.MetricFormat,
+		filteredMetrics,
+		execution.IsOnlyEssential(),
+		s.option.Config.Metric.EssentialMetrics,
+	)
+	filteredMetrics = s.capMetrics(filteredMetrics)
 
 	if err = newMetricRegisterer(s, apiClient).registerMetrics(ctx, filteredMetrics); err != nil {
 		return updateThresholds, err
@@ -618,6 +667,13 @@ func (s *Synchronizer) UpdateUnitsAndThresholds(ctx context.Context, firstUpdate
 		softPeriods[metric] = time.Duration(period) * time.Second
 	}
 
+	defaultRecoveryPeriod := time.Duration(s.option.Config.Metric.SoftStatusRecoveryDefault) * time.Second
+
+	recoveryPeriods := make(map[string]time.Duration, len(s.option.Config.Metric.SoftStatusRecovery))
+	for metric, period := range s.option.Config.Metric.SoftStatusRecovery {
+		recoveryPeriods[metric] = time.Duration(period) * time.Second
+	}
+
 	s.l.Lock()
 	thresholdOverrides := s.thresholdOverrides
 	s.l.Unlock()
@@ -655,6 +711,12 @@ func (s *Synchronizer) UpdateUnitsAndThresholds(ctx context.Context, firstUpdate
 			thresh.CriticalDelay = softPeriod
 		}
 
+		thresh.RecoveryDelay = defaultRecoveryPeriod
+
+		if recoveryPeriod, ok := recoveryPeriods[metricName]; ok {
+			thresh.RecoveryDelay = recoveryPeriod
+		}
+
 		if !thresh.IsZero() {
 			thresholds[m.LabelsText] = thresh
 		}
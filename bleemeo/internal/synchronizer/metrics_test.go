@@ -95,8 +95,8 @@ func TestPrioritizeAndFilterMetrics(t *testing.T) {
 		}
 	}
 
-	metrics = prioritizeAndFilterMetrics(gloutonTypes.MetricFormatBleemeo, metrics, false)
-	metrics2 = prioritizeAndFilterMetrics(gloutonTypes.MetricFormatBleemeo, metrics2, true)
+	metrics = prioritizeAndFilterMetrics(gloutonTypes.MetricFormatBleemeo, metrics, false, nil)
+	metrics2 = prioritizeAndFilterMetrics(gloutonTypes.MetricFormatBleemeo, metrics2, true, nil)
 
 	for i, m := range metrics {
 		if !isHighPriority[m.Labels()[gloutonTypes.LabelName]] && i < countHighPriority {
@@ -277,7 +277,7 @@ func TestPrioritizeAndFilterMetrics2(t *testing.T) {
 				metrics = append(metrics, mockMetric{labels: gloutonTypes.TextToLabels(lbls)})
 			}
 
-			result := prioritizeAndFilterMetrics(tt.format, metrics, false)
+			result := prioritizeAndFilterMetrics(tt.format, metrics, false, nil)
 
 			for _, ord := range tt.order {
 				firstIdx := -1
@@ -389,7 +389,7 @@ func Test_metricComparator_IsSignificantItem(t *testing.T) {
 		t.Run(tt.item, func(t *testing.T) {
 			t.Parallel()
 
-			m := newComparator(gloutonTypes.MetricFormatBleemeo)
+			m := newComparator(gloutonTypes.MetricFormatBleemeo, nil)
 			if got := m.IsSignificantItem(tt.item); got != tt.want {
 				t.Errorf("metricComparator.IsSignificantItem() = %v, want %v", got, tt.want)
 			}
@@ -401,6 +401,7 @@ func Test_metricComparator_SkipInOnlyEssential(t *testing.T) {
 	tests := []struct {
 		name                string
 		format              gloutonTypes.MetricFormat
+		extraEssentials     []string
 		metric              string
 		keepInOnlyEssential bool
 	}{
@@ -434,12 +435,32 @@ func Test_metricComparator_SkipInOnlyEssential(t *testing.T) {
 			metric:              `__name__="net_bits_recv",item="br-2a4d1a465acd"`,
 			keepInOnlyEssential: false,
 		},
+		{
+			name:                "custom metrics aren't essential",
+			format:              gloutonTypes.MetricFormatBleemeo,
+			metric:              `__name__="business_orders_total"`,
+			keepInOnlyEssential: false,
+		},
+		{
+			name:                "configured essential metrics are essential",
+			format:              gloutonTypes.MetricFormatBleemeo,
+			extraEssentials:     []string{"business_orders_total"},
+			metric:              `__name__="business_orders_total"`,
+			keepInOnlyEssential: true,
+		},
+		{
+			name:                "configured essential metrics are essential in Prometheus format",
+			format:              gloutonTypes.MetricFormatPrometheus,
+			extraEssentials:     []string{"business_orders_total"},
+			metric:              `__name__="business_orders_total",item="shop"`,
+			keepInOnlyEssential: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			m := newComparator(tt.format)
+			m := newComparator(tt.format, tt.extraEssentials)
 			metric := gloutonTypes.TextToLabels(tt.metric)
 
 			if got := m.KeepInOnlyEssential(metric); got != tt.keepInOnlyEssential {
@@ -533,7 +554,7 @@ func Test_metricComparator_importanceWeight(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			m := newComparator(tt.format)
+			m := newComparator(tt.format, nil)
 
 			metricA := gloutonTypes.TextToLabels(tt.metricBefore)
 			metricB := gloutonTypes.TextToLabels(tt.metricAfter)
@@ -1404,6 +1425,52 @@ func TestMetricTooMany(t *testing.T) { //nolint:maintidx
 
 // TestMetricLongItem test that metric with very long item works.
 // Long item happen with long container name, test this scenario.
+// TestMetricMaxMetrics checks that the lowest priority metrics are dropped when
+// the number of metrics exceeds bleemeo.max_metrics.
+func TestMetricMaxMetrics(t *testing.T) {
+	helper := newHelper(t)
+	defer helper.Close()
+
+	helper.cfg.Bleemeo.MaxMetrics = 5
+
+	helper.preregisterAgent(t)
+	helper.initSynchronizer(t)
+	helper.AddTime(time.Minute)
+
+	idAgentMain, _ := helper.state.BleemeoCredentials()
+
+	for n := range 10 {
+		helper.pushPoints(t, []labels.Labels{
+			labels.New(
+				labels.Label{Name: gloutonTypes.LabelName, Value: "metric"},
+				labels.Label{Name: gloutonTypes.LabelItem, Value: strconv.FormatInt(int64(n), 10)},
+				labels.Label{Name: gloutonTypes.LabelMetaBleemeoItem, Value: strconv.FormatInt(int64(n), 10)},
+				labels.Label{Name: gloutonTypes.LabelInstanceUUID, Value: idAgentMain},
+			),
+		})
+	}
+
+	if err := helper.runOnceWithResult(t).CheckMethodWithFull(types.EntityMetric); err != nil {
+		t.Error(err)
+	}
+
+	// agent_status isn't in the store of the test, it's registered in addition to the 5 metrics kept.
+	metrics := helper.MetricsFromAPI()
+	if len(metrics) != 6 {
+		t.Errorf("len(metrics) = %d, want 6", len(metrics))
+	}
+
+	for _, m := range metrics {
+		if m.Name != agentStatusName && m.Item > "4" {
+			t.Errorf("metric %s with item %s should have been dropped", m.Name, m.Item)
+		}
+	}
+
+	if got := helper.s.DroppedMetricCount(); got != 5 {
+		t.Errorf("DroppedMetricCount() = %d, want 5", got)
+	}
+}
+
 func TestMetricLongItem(t *testing.T) {
 	helper := newHelper(t)
 	defer helper.Close()
@@ -51,7 +51,7 @@ func (s *Synchronizer) syncSNMP(ctx context.Context, syncType types.SyncType, ex
 		return false, nil
 	}
 
-	return false, s.snmpRegisterAndUpdate(ctx, execution, s.option.SNMP)
+	return false, s.snmpRegisterAndUpdate(ctx, execution, s.option.SNMP())
 }
 
 type snmpAssociation struct {
@@ -78,9 +78,9 @@ func (s *Synchronizer) FindSNMPAgent(ctx context.Context, target *snmp.Target, s
 		return bleemeoTypes.Agent{}, err
 	}
 
-	associatedID := make(map[string]bool, len(s.option.SNMP))
+	associatedID := make(map[string]bool, len(s.option.SNMP()))
 
-	for _, v := range s.option.SNMP {
+	for _, v := range s.option.SNMP() {
 		err := s.option.State.Get(snmpCachePrefix+v.Address(), &association)
 		if err != nil {
 			return bleemeoTypes.Agent{}, err
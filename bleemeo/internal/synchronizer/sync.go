@@ -555,6 +555,14 @@ func (s *Synchronizer) LastMetricActivation() time.Time {
 	return s.state.lastMetricActivation
 }
 
+// DroppedMetricCount returns the number of metrics not sent to Bleemeo because of bleemeo.max_metrics.
+func (s *Synchronizer) DroppedMetricCount() int {
+	s.state.l.Lock()
+	defer s.state.l.Unlock()
+
+	return s.state.droppedMetricCount
+}
+
 // UpdateMetrics request to update a specific metrics.
 func (s *Synchronizer) UpdateMetrics(metricUUID ...string) {
 	s.l.Lock()
@@ -40,7 +40,7 @@ type GlobalOption struct {
 	Facts                   FactProvider
 	Process                 ProcessProvider
 	Docker                  DockerProvider
-	SNMP                    []*snmp.Target
+	SNMP                    func() []*snmp.Target
 	SNMPOnlineTarget        func() int
 	Store                   Store
 	Discovery               discovery.PersistentDiscoverer
@@ -52,6 +52,8 @@ type baseCheck struct {
 
 	dialer *net.Dialer
 	wg     sync.WaitGroup
+	// connectTimeout is the timeout of TCP connections, the default timeout is used when zero.
+	connectTimeout time.Duration
 
 	persistentConnection bool
 	// Map of addresses with disabled persistent connection.
@@ -226,7 +228,7 @@ func (bc *baseCheck) doCheck(ctx context.Context) types.StatusDescription {
 			continue
 		}
 
-		if status = checkTCP(ctx, addr, nil, nil, nil); status.CurrentStatus != types.StatusOk {
+		if status = checkTCP(ctx, addr, nil, nil, nil, bc.connectTimeout); status.CurrentStatus != types.StatusOk {
 			return status
 		}
 	}
@@ -305,7 +307,12 @@ func (bc *baseCheck) openSocket(ctx context.Context, addr string, scheduleUpdate
 }
 
 func (bc *baseCheck) openSocketOnce(ctx context.Context, addr string, scheduleUpdate func(runAt time.Time)) (longSleep bool) {
-	ctx2, cancel := context.WithTimeout(ctx, 10*time.Second)
+	timeout := bc.connectTimeout
+	if timeout <= 0 {
+		timeout = defaultTCPTimeout
+	}
+
+	ctx2, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	conn, err := bc.dialer.DialContext(ctx2, "tcp", addr)
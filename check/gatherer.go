@@ -37,9 +37,10 @@ type Gatherer struct {
 	scheduleUpdate func(runAt time.Time)
 
 	l sync.Mutex
-	// The last metric point produced by the check is kept to be
+	// The last metric points produced by the check are kept to be
 	// returned when the gatherer is called from /metrics.
 	lastMetricPoint types.MetricPoint
+	lastExtraPoints []types.MetricPoint
 }
 
 // checker is an interface which specifies a check.
@@ -49,6 +50,12 @@ type checker interface {
 	Close()
 }
 
+// extraPointsChecker is implemented by checks which produce
+// metrics in addition to the status, e.g. Nagios performance data.
+type extraPointsChecker interface {
+	ExtraPoints() []types.MetricPoint
+}
+
 // NewCheckGatherer returns a new check gatherer.
 func NewCheckGatherer(check checker) *Gatherer {
 	return &Gatherer{check: check}
@@ -58,24 +65,32 @@ func NewCheckGatherer(check checker) *Gatherer {
 func (cg *Gatherer) GatherWithState(ctx context.Context, state registry.GatherState) ([]*dto.MetricFamily, error) {
 	cg.l.Lock()
 	lastMetricPoint := cg.lastMetricPoint
+	lastExtraPoints := cg.lastExtraPoints
 	cg.l.Unlock()
 
 	// Return the metrics from the last check on /metrics (unless we don't have one yet).
 	if !state.FromScrapeLoop && lastMetricPoint.Labels != nil {
-		mfs := model.MetricPointsToFamilies([]types.MetricPoint{lastMetricPoint})
+		mfs := model.MetricPointsToFamilies(append([]types.MetricPoint{lastMetricPoint}, lastExtraPoints...))
 
 		return mfs, nil
 	}
 
 	point := cg.check.Check(ctx, cg.scheduleUpdate)
 
-	// Keep the last point. We don't keep the metric families because
+	var extraPoints []types.MetricPoint
+
+	if c, ok := cg.check.(extraPointsChecker); ok {
+		extraPoints = c.ExtraPoints()
+	}
+
+	// Keep the last points. We don't keep the metric families because
 	// they might be mutated later and cause data races.
 	cg.l.Lock()
 	cg.lastMetricPoint = point
+	cg.lastExtraPoints = extraPoints
 	cg.l.Unlock()
 
-	mfs := model.MetricPointsToFamilies([]types.MetricPoint{point})
+	mfs := model.MetricPointsToFamilies(append([]types.MetricPoint{point}, extraPoints...))
 
 	return mfs, nil
 }
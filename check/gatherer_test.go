@@ -0,0 +1,86 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bleemeo/glouton/prometheus/model"
+	"github.com/bleemeo/glouton/prometheus/registry"
+	"github.com/bleemeo/glouton/types"
+)
+
+// TestNagiosGatherer checks the performance data points returned by the gatherer of a Nagios check.
+func TestNagiosGatherer(t *testing.T) {
+	t.Parallel()
+
+	labels := map[string]string{
+		types.LabelName:    "service_status",
+		types.LabelService: "custom-check",
+	}
+
+	nagiosCheck := NewNagios(
+		`echo "WARNING - slow response | time=1500ms;1000;2000;0 size=2KB"`,
+		nil,
+		false,
+		labels,
+		types.MetricAnnotations{ServiceName: "custom-check"},
+	)
+	gatherer := NewCheckGatherer(nagiosCheck)
+
+	t.Cleanup(gatherer.Close)
+
+	mfs, err := gatherer.GatherWithState(context.Background(), registry.GatherState{T0: time.Now(), FromScrapeLoop: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]struct {
+		value  float64
+		status types.Status
+	}{
+		"custom_check_time": {value: 1.5, status: types.StatusWarning},
+		"custom_check_size": {value: 2048, status: types.StatusUnset},
+	}
+
+	got := 0
+
+	for _, point := range model.FamiliesToMetricPoints(time.Now(), mfs, true) {
+		name := point.Labels[types.LabelName]
+
+		wantPoint, ok := want[name]
+		if !ok {
+			continue
+		}
+
+		got++
+
+		if point.Value != wantPoint.value {
+			t.Errorf("%s = %f, want %f", name, point.Value, wantPoint.value)
+		}
+
+		if point.Annotations.Status.CurrentStatus != wantPoint.status {
+			t.Errorf("%s status = %v, want %v", name, point.Annotations.Status.CurrentStatus, wantPoint.status)
+		}
+	}
+
+	if got != len(want) {
+		t.Errorf("got %d performance data points, want %d", got, len(want))
+	}
+}
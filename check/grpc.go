@@ -0,0 +1,157 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bleemeo/glouton/types"
+	"github.com/bleemeo/glouton/version"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCCheck perform a check using the gRPC Health Checking protocol.
+type GRPCCheck struct {
+	*baseCheck
+
+	mainAddress string
+	serviceName string
+	tlsConfig   *tls.Config
+}
+
+// NewGRPC create a new gRPC health check.
+//
+// The main address uses the format "IP:port", it's the address on which grpc.health.v1.Health/Check is called
+// for the given service name. An empty service name asks for the overall health of the server.
+//
+// If tlsConfig is nil, the connection is made without TLS.
+//
+// timeout limits the time spent connecting and waiting for the response, it defaults to 10 seconds when zero.
+func NewGRPC(
+	address string,
+	tcpAddresses []string,
+	persistentConnection bool,
+	serviceName string,
+	tlsConfig *tls.Config,
+	timeout time.Duration,
+	labels map[string]string,
+	annotations types.MetricAnnotations,
+) *GRPCCheck {
+	gc := &GRPCCheck{
+		mainAddress: address,
+		serviceName: serviceName,
+		tlsConfig:   tlsConfig,
+	}
+
+	gc.baseCheck = newBase(address, tcpAddresses, persistentConnection, gc.grpcMainCheck, labels, annotations)
+	gc.baseCheck.connectTimeout = timeout
+
+	return gc
+}
+
+func (gc *GRPCCheck) DiagnosticArchive(ctx context.Context, archive types.ArchiveWriter) error {
+	if err := gc.baseCheck.DiagnosticArchive(ctx, archive); err != nil {
+		return err
+	}
+
+	file, err := archive.Create("check-grpc.json")
+	if err != nil {
+		return err
+	}
+
+	obj := struct {
+		Address     string
+		ServiceName string
+		TLS         bool
+	}{
+		Address:     gc.mainAddress,
+		ServiceName: gc.serviceName,
+		TLS:         gc.tlsConfig != nil,
+	}
+
+	enc := json.NewEncoder(file)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(obj)
+}
+
+func (gc *GRPCCheck) grpcMainCheck(ctx context.Context) types.StatusDescription {
+	creds := insecure.NewCredentials()
+	if gc.tlsConfig != nil {
+		creds = credentials.NewTLS(gc.tlsConfig)
+	}
+
+	conn, err := grpc.NewClient(gc.mainAddress, grpc.WithTransportCredentials(creds), grpc.WithUserAgent(version.UserAgent()))
+	if err != nil {
+		return types.StatusDescription{
+			CurrentStatus:     types.StatusUnknown,
+			StatusDescription: fmt.Sprintf("Invalid gRPC address %#v", gc.mainAddress),
+		}
+	}
+
+	defer conn.Close()
+
+	timeout := gc.connectTimeout
+	if timeout <= 0 {
+		timeout = defaultTCPTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: gc.serviceName})
+
+	switch {
+	case status.Code(err) == codes.DeadlineExceeded:
+		return types.StatusDescription{
+			CurrentStatus:     types.StatusCritical,
+			StatusDescription: fmt.Sprintf("Connection timed out after %v", timeout),
+		}
+	case status.Code(err) == codes.NotFound:
+		return types.StatusDescription{
+			CurrentStatus:     types.StatusCritical,
+			StatusDescription: fmt.Sprintf("gRPC service %#v is unknown", gc.serviceName),
+		}
+	case err != nil:
+		return types.StatusDescription{
+			CurrentStatus:     types.StatusCritical,
+			StatusDescription: "gRPC health check failed: " + status.Convert(err).Message(),
+		}
+	}
+
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return types.StatusDescription{
+			CurrentStatus:     types.StatusCritical,
+			StatusDescription: "gRPC CRITICAL - status=" + resp.GetStatus().String(),
+		}
+	}
+
+	return types.StatusDescription{
+		CurrentStatus:     types.StatusOk,
+		StatusDescription: "gRPC OK - status=" + resp.GetStatus().String(),
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bleemeo/glouton/types"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestCheckGRPC(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("serving.Service", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("down.Service", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	go server.Serve(listener) //nolint:errcheck
+
+	t.Cleanup(server.Stop)
+
+	// This address refuses connections.
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	closedAddress := closedListener.Addr().String()
+	closedListener.Close()
+
+	tests := []struct {
+		name              string
+		address           string
+		serviceName       string
+		wantStatus        types.Status
+		wantDescriptionIn string
+	}{
+		{
+			name:              "server",
+			address:           listener.Addr().String(),
+			wantStatus:        types.StatusOk,
+			wantDescriptionIn: "SERVING",
+		},
+		{
+			name:              "serving",
+			address:           listener.Addr().String(),
+			serviceName:       "serving.Service",
+			wantStatus:        types.StatusOk,
+			wantDescriptionIn: "SERVING",
+		},
+		{
+			name:              "not-serving",
+			address:           listener.Addr().String(),
+			serviceName:       "down.Service",
+			wantStatus:        types.StatusCritical,
+			wantDescriptionIn: "NOT_SERVING",
+		},
+		{
+			name:              "unknown-service",
+			address:           listener.Addr().String(),
+			serviceName:       "unknown.Service",
+			wantStatus:        types.StatusCritical,
+			wantDescriptionIn: "unknown",
+		},
+		{
+			name:              "refused",
+			address:           closedAddress,
+			wantStatus:        types.StatusCritical,
+			wantDescriptionIn: "gRPC health check failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			gc := NewGRPC(tt.address, nil, false, tt.serviceName, nil, 5*time.Second, nil, types.MetricAnnotations{})
+
+			got := gc.grpcMainCheck(context.Background())
+
+			if got.CurrentStatus != tt.wantStatus {
+				t.Errorf("CurrentStatus = %v, want %v (%s)", got.CurrentStatus, tt.wantStatus, got.StatusDescription)
+			}
+
+			if !strings.Contains(got.StatusDescription, tt.wantDescriptionIn) {
+				t.Errorf("StatusDescription = %q, want it to contain %q", got.StatusDescription, tt.wantDescriptionIn)
+			}
+		})
+	}
+}
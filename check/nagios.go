@@ -20,6 +20,8 @@ import (
 	"context"
 	"fmt"
 	"os/exec"
+	"sync"
+	"time"
 
 	"github.com/bleemeo/glouton/types"
 
@@ -31,6 +33,10 @@ type NagiosCheck struct {
 	*baseCheck
 
 	nagiosCommand string
+
+	perfDataLock sync.Mutex
+	// Points built from the performance data of the last check.
+	perfDataPoints []types.MetricPoint
 }
 
 // NewNagios create a new Nagios check.
@@ -62,6 +68,8 @@ func NewNagios(
 func (nc *NagiosCheck) nagiosMainCheck(context.Context) types.StatusDescription {
 	part, err := shlex.Split(nc.nagiosCommand)
 	if err != nil {
+		nc.setPerfData(nil)
+
 		return types.StatusDescription{
 			CurrentStatus:     types.StatusUnknown,
 			StatusDescription: fmt.Sprintf("UNKNOWN - failed to parse command line: %v", err),
@@ -69,6 +77,8 @@ func (nc *NagiosCheck) nagiosMainCheck(context.Context) types.StatusDescription
 	}
 
 	if len(part) == 0 {
+		nc.setPerfData(nil)
+
 		return types.StatusDescription{
 			CurrentStatus:     types.StatusUnknown,
 			StatusDescription: fmt.Sprintf("UNKNOWN - command %#v looks empty", nc.nagiosCommand),
@@ -82,6 +92,11 @@ func (nc *NagiosCheck) nagiosMainCheck(context.Context) types.StatusDescription
 		StatusDescription: string(output),
 	}
 
+	// The description is kept unchanged with the performance data, as NRPE
+	// must reply with the full output of the check.
+	_, perfData := splitNagiosOutput(string(output))
+	nc.setPerfData(perfData)
+
 	if exitError, ok := err.(*exec.ExitError); ok {
 		result.CurrentStatus = types.FromNagios(exitError.ExitCode())
 	} else if err != nil {
@@ -91,3 +106,46 @@ func (nc *NagiosCheck) nagiosMainCheck(context.Context) types.StatusDescription
 
 	return result
 }
+
+// ExtraPoints returns the points built from the performance data of the last check.
+func (nc *NagiosCheck) ExtraPoints() []types.MetricPoint {
+	nc.perfDataLock.Lock()
+	defer nc.perfDataLock.Unlock()
+
+	return nc.perfDataPoints
+}
+
+func (nc *NagiosCheck) setPerfData(perfData []perfData) {
+	serviceName := nc.labels[types.LabelService]
+	now := time.Now().Truncate(time.Second)
+	points := make([]types.MetricPoint, 0, len(perfData))
+
+	for _, data := range perfData {
+		labels := make(map[string]string, len(nc.labels))
+
+		for k, v := range nc.labels {
+			labels[k] = v
+		}
+
+		labels[types.LabelName] = perfDataMetricName(serviceName, data.Label)
+
+		points = append(points, types.MetricPoint{
+			Point: types.Point{
+				Time:  now,
+				Value: data.Value,
+			},
+			Labels: labels,
+			Annotations: types.MetricAnnotations{
+				ServiceName:     nc.annotations.ServiceName,
+				ServiceInstance: nc.annotations.ServiceInstance,
+				ContainerID:     nc.annotations.ContainerID,
+				// The thresholds from the performance data give the status of the point.
+				Status: data.status(),
+			},
+		})
+	}
+
+	nc.perfDataLock.Lock()
+	nc.perfDataPoints = points
+	nc.perfDataLock.Unlock()
+}
@@ -0,0 +1,328 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bleemeo/glouton/types"
+)
+
+//nolint:gochecknoglobals
+var invalidMetricNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// perfData is a single value from the performance data of a Nagios check.
+// The format is 'label'=value[UOM];[warn];[crit];[min];[max].
+type perfData struct {
+	Label string
+	Value float64
+	// Unit is the unit after conversion, values in time and bytes are
+	// converted to seconds and bytes. It's empty when no unit is given.
+	Unit string
+	// Warning, Critical, Min and Max are converted to the unit of the value.
+	Warning  *perfDataRange
+	Critical *perfDataRange
+	Min      *float64
+	Max      *float64
+}
+
+// perfDataRange is a threshold in the Nagios range format, e.g. "10", "10:", "~:10" or "@10:20".
+type perfDataRange struct {
+	Raw   string
+	Start float64
+	End   float64
+	// Inside is true when the alert is raised for values inside the range instead of outside.
+	Inside bool
+}
+
+// alert returns whether the value raises an alert for this range.
+func (r perfDataRange) alert(value float64) bool {
+	outside := value < r.Start || value > r.End
+
+	return outside != r.Inside
+}
+
+// status returns the status of the value from the warning and critical thresholds.
+// The status isn't set when the performance data have no threshold.
+func (d perfData) status() types.StatusDescription {
+	if d.Warning == nil && d.Critical == nil {
+		return types.StatusDescription{}
+	}
+
+	description := "Current value: " + strings.TrimSpace(strconv.FormatFloat(d.Value, 'f', -1, 64)+" "+d.Unit)
+
+	switch {
+	case d.Critical != nil && d.Critical.alert(d.Value):
+		return types.StatusDescription{
+			CurrentStatus:     types.StatusCritical,
+			StatusDescription: fmt.Sprintf("%s critical threshold (%s) exceeded", description, d.Critical.Raw),
+		}
+	case d.Warning != nil && d.Warning.alert(d.Value):
+		return types.StatusDescription{
+			CurrentStatus:     types.StatusWarning,
+			StatusDescription: fmt.Sprintf("%s warning threshold (%s) exceeded", description, d.Warning.Raw),
+		}
+	default:
+		return types.StatusDescription{
+			CurrentStatus:     types.StatusOk,
+			StatusDescription: description,
+		}
+	}
+}
+
+//nolint:gochecknoglobals
+var perfDataUnits = map[string]struct {
+	unit   string
+	factor float64
+}{
+	"us": {unit: "s", factor: 1e-6},
+	"ms": {unit: "s", factor: 1e-3},
+	"s":  {unit: "s", factor: 1},
+	"B":  {unit: "B", factor: 1},
+	"KB": {unit: "B", factor: 1024},
+	"MB": {unit: "B", factor: 1024 * 1024},
+	"GB": {unit: "B", factor: 1024 * 1024 * 1024},
+	"TB": {unit: "B", factor: 1024 * 1024 * 1024 * 1024},
+	"%":  {unit: "%", factor: 1},
+	"c":  {unit: "c", factor: 1},
+}
+
+// splitNagiosOutput splits the output of a Nagios check into its text and its performance data.
+//
+// The output format is:
+//
+//	TEXT OUTPUT | OPTIONAL PERFDATA
+//	LONG TEXT LINE 1
+//	LONG TEXT LINE 2 | PERFDATA LINE 2
+//	PERFDATA LINE 3
+func splitNagiosOutput(output string) (string, []perfData) {
+	lines := strings.Split(output, "\n")
+	textLines := make([]string, 0, len(lines))
+	perfLines := make([]string, 0)
+
+	for i, line := range lines {
+		text, perf, found := strings.Cut(line, "|")
+
+		textLines = append(textLines, text)
+
+		if !found {
+			continue
+		}
+
+		perfLines = append(perfLines, perf)
+
+		// After a "|" in the long text, all the following lines are performance data.
+		if i > 0 {
+			perfLines = append(perfLines, lines[i+1:]...)
+
+			break
+		}
+	}
+
+	for i, line := range textLines {
+		textLines[i] = strings.TrimRight(line, " ")
+	}
+
+	text := strings.TrimRight(strings.Join(textLines, "\n"), "\n")
+
+	var result []perfData
+
+	for _, line := range perfLines {
+		result = append(result, parsePerfData(line)...)
+	}
+
+	return text, result
+}
+
+// parsePerfData parses a space separated list of performance data.
+// Invalid values are ignored.
+func parsePerfData(line string) []perfData {
+	var result []perfData
+
+	for _, item := range splitPerfDataItems(line) {
+		label, values, found := strings.Cut(item, "=")
+		if !found || label == "" {
+			continue
+		}
+
+		label = strings.ReplaceAll(strings.Trim(label, "'"), "''", "'")
+
+		fields := strings.Split(values, ";")
+
+		value, unit, factor, ok := parsePerfDataValue(fields[0])
+		if !ok {
+			continue
+		}
+
+		data := perfData{
+			Label: label,
+			Value: value,
+			Unit:  unit,
+		}
+
+		if len(fields) > 1 {
+			data.Warning = parsePerfDataRange(fields[1], factor)
+		}
+
+		if len(fields) > 2 {
+			data.Critical = parsePerfDataRange(fields[2], factor)
+		}
+
+		if len(fields) > 3 {
+			data.Min = parseOptionalFloat(fields[3], factor)
+		}
+
+		if len(fields) > 4 {
+			data.Max = parseOptionalFloat(fields[4], factor)
+		}
+
+		result = append(result, data)
+	}
+
+	return result
+}
+
+// splitPerfDataItems splits performance data on spaces, labels may contain spaces when quoted.
+func splitPerfDataItems(line string) []string {
+	var (
+		items   []string
+		current strings.Builder
+		quoted  bool
+	)
+
+	for _, r := range line {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+
+			current.WriteRune(r)
+		case (r == ' ' || r == '\t') && !quoted:
+			if current.Len() > 0 {
+				items = append(items, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	if current.Len() > 0 {
+		items = append(items, current.String())
+	}
+
+	return items
+}
+
+// parsePerfDataValue parses a value with its optional unit and converts it to the base unit.
+// It also returns the factor used for the conversion.
+// The value "U" means the value couldn't be determined, it's reported as not ok.
+func parsePerfDataValue(raw string) (float64, string, float64, bool) {
+	idx := strings.IndexFunc(raw, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-' && r != '+' && r != 'e' && r != 'E'
+	})
+
+	number, unit := raw, ""
+	if idx >= 0 {
+		number, unit = raw[:idx], raw[idx:]
+	}
+
+	value, err := parseFloat(number)
+	if err != nil {
+		return 0, "", 0, false
+	}
+
+	if conv, ok := perfDataUnits[unit]; ok {
+		return value * conv.factor, conv.unit, conv.factor, true
+	}
+
+	return value, unit, 1, true
+}
+
+// parseOptionalFloat parses the min or max of a performance data. They use the
+// unit of the value, so they are converted with the same factor unless they
+// have their own unit.
+func parseOptionalFloat(raw string, factor float64) *float64 {
+	value, unit, _, ok := parsePerfDataValue(raw)
+	if !ok {
+		return nil
+	}
+
+	if unit == "" {
+		value *= factor
+	}
+
+	return &value
+}
+
+// parsePerfDataRange parses a threshold range and converts it with the factor of the value.
+// It returns nil when the range is empty or invalid.
+func parsePerfDataRange(raw string, factor float64) *perfDataRange {
+	if raw == "" {
+		return nil
+	}
+
+	rangeText, inside := strings.CutPrefix(raw, "@")
+	result := &perfDataRange{Raw: raw, Inside: inside}
+
+	startText, endText, found := strings.Cut(rangeText, ":")
+	if !found {
+		startText, endText = "0", rangeText
+	}
+
+	switch startText {
+	case "~":
+		result.Start = math.Inf(-1)
+	case "":
+		result.Start = 0
+	default:
+		start, err := parseFloat(startText)
+		if err != nil {
+			return nil
+		}
+
+		result.Start = start * factor
+	}
+
+	if endText == "" {
+		result.End = math.Inf(1)
+	} else {
+		end, err := parseFloat(endText)
+		if err != nil {
+			return nil
+		}
+
+		result.End = end * factor
+	}
+
+	return result
+}
+
+// parseFloat parses a number which may use a comma as decimal separator.
+func parseFloat(raw string) (float64, error) {
+	return strconv.ParseFloat(strings.Replace(raw, ",", ".", 1), 64)
+}
+
+// perfDataMetricName returns the metric name for a performance data label of a service.
+func perfDataMetricName(serviceName string, label string) string {
+	name := invalidMetricNameChars.ReplaceAllString(serviceName+"_"+label, "_")
+
+	return strings.Trim(name, "_")
+}
@@ -0,0 +1,171 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bleemeo/glouton/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func TestSplitNagiosOutput(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		output       string
+		wantText     string
+		wantPerfData []perfData
+	}{
+		{
+			name:     "no-perfdata",
+			output:   "OK - everything is fine\n",
+			wantText: "OK - everything is fine",
+		},
+		{
+			name:     "check_load",
+			output:   "WARNING - load average: 0.02, 0.07, 0.06|load1=0.022;0.150;0.300;0; load5=0.068;0.100;0.250;0; load15=0.060;0.050;0.200;0; ",
+			wantText: "WARNING - load average: 0.02, 0.07, 0.06",
+			wantPerfData: []perfData{
+				{
+					Label:    "load1",
+					Value:    0.022,
+					Warning:  &perfDataRange{Raw: "0.150", End: 0.150},
+					Critical: &perfDataRange{Raw: "0.300", End: 0.300},
+					Min:      floatPtr(0),
+				},
+				{
+					Label:    "load5",
+					Value:    0.068,
+					Warning:  &perfDataRange{Raw: "0.100", End: 0.100},
+					Critical: &perfDataRange{Raw: "0.250", End: 0.250},
+					Min:      floatPtr(0),
+				},
+				{
+					Label:    "load15",
+					Value:    0.060,
+					Warning:  &perfDataRange{Raw: "0.050", End: 0.050},
+					Critical: &perfDataRange{Raw: "0.200", End: 0.200},
+					Min:      floatPtr(0),
+				},
+			},
+		},
+		{
+			name:     "units-and-quoted-label",
+			output:   "HTTP OK | time=12ms;100;200 'disk used'=2KB;;;0;10KB size=U swap=1MB;@1:2;~:3;;4",
+			wantText: "HTTP OK",
+			wantPerfData: []perfData{
+				{
+					Label:    "time",
+					Value:    0.012,
+					Unit:     "s",
+					Warning:  &perfDataRange{Raw: "100", End: 0.1},
+					Critical: &perfDataRange{Raw: "200", End: 0.2},
+				},
+				{Label: "disk used", Value: 2048, Unit: "B", Min: floatPtr(0), Max: floatPtr(10240)},
+				{
+					Label:    "swap",
+					Value:    1048576,
+					Unit:     "B",
+					Warning:  &perfDataRange{Raw: "@1:2", Start: 1048576, End: 2097152, Inside: true},
+					Critical: &perfDataRange{Raw: "~:3", Start: math.Inf(-1), End: 3145728},
+					Max:      floatPtr(4194304),
+				},
+			},
+		},
+		{
+			name:     "long-output",
+			output:   "DISK OK | root=40%\n/ is fine\n/home is fine | home=12%;80;90\nvar=3%",
+			wantText: "DISK OK\n/ is fine\n/home is fine",
+			wantPerfData: []perfData{
+				{Label: "root", Value: 40, Unit: "%"},
+				{
+					Label:    "home",
+					Value:    12,
+					Unit:     "%",
+					Warning:  &perfDataRange{Raw: "80", End: 80},
+					Critical: &perfDataRange{Raw: "90", End: 90},
+				},
+				{Label: "var", Value: 3, Unit: "%"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			gotText, gotPerfData := splitNagiosOutput(tt.output)
+			if gotText != tt.wantText {
+				t.Errorf("text = %q, want %q", gotText, tt.wantText)
+			}
+
+			if diff := cmp.Diff(tt.wantPerfData, gotPerfData); diff != "" {
+				t.Errorf("perfdata mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPerfDataMetricName(t *testing.T) {
+	t.Parallel()
+
+	if got := perfDataMetricName("custom-check", "disk used"); got != "custom_check_disk_used" {
+		t.Errorf("perfDataMetricName() = %s, want custom_check_disk_used", got)
+	}
+}
+
+func TestPerfDataStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		perfData   string
+		wantStatus types.Status
+	}{
+		{perfData: "time=12ms", wantStatus: types.StatusUnset},
+		{perfData: "time=12ms;100;200", wantStatus: types.StatusOk},
+		{perfData: "time=150ms;100;200", wantStatus: types.StatusWarning},
+		{perfData: "time=250ms;100;200", wantStatus: types.StatusCritical},
+		{perfData: "free=5%;10:;5:", wantStatus: types.StatusWarning},
+		{perfData: "free=4%;10:;5:", wantStatus: types.StatusCritical},
+		{perfData: "temp=15;@10:20", wantStatus: types.StatusWarning},
+		{perfData: "temp=25;@10:20", wantStatus: types.StatusOk},
+		{perfData: "temp=-25;~:20", wantStatus: types.StatusOk},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.perfData, func(t *testing.T) {
+			t.Parallel()
+
+			data := parsePerfData(tt.perfData)
+			if len(data) != 1 {
+				t.Fatalf("parsePerfData() returned %d values, want 1", len(data))
+			}
+
+			if got := data[0].status().CurrentStatus; got != tt.wantStatus {
+				t.Errorf("status = %v, want %v", got, tt.wantStatus)
+			}
+		})
+	}
+}
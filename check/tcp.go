@@ -29,6 +29,9 @@ import (
 	"github.com/bleemeo/glouton/types"
 )
 
+// defaultTCPTimeout is the connect and read timeout used when none is configured.
+const defaultTCPTimeout = 10 * time.Second
+
 // TCPCheck perform a TCP check.
 type TCPCheck struct {
 	*baseCheck
@@ -48,6 +51,8 @@ type TCPCheck struct {
 // On tcpAddresses (which are supposed to contains addresse) a TCP connection is openned and closed on each check.
 //
 // If persistentConnection is set, a persistent TCP connection will be openned to detect service incident quickyl.
+//
+// connectTimeout limits the time spent connecting and waiting for the response, it defaults to 10 seconds when zero.
 func NewTCP(
 	address string,
 	tcpAddresses []string,
@@ -55,6 +60,7 @@ func NewTCP(
 	send []byte,
 	expect []byte,
 	closeMsg []byte,
+	connectTimeout time.Duration,
 	labels map[string]string,
 	annotations types.MetricAnnotations,
 ) *TCPCheck {
@@ -71,6 +77,7 @@ func NewTCP(
 	}
 
 	tc.baseCheck = newBase(address, tcpAddresses, persistentConnection, mainCheck, labels, annotations)
+	tc.baseCheck.connectTimeout = connectTimeout
 
 	return tc
 }
@@ -80,10 +87,10 @@ func (tc *TCPCheck) tcpMainCheck(ctx context.Context) types.StatusDescription {
 		return types.StatusDescription{}
 	}
 
-	return checkTCP(ctx, tc.mainAddress, tc.send, tc.expect, tc.closeMsg)
+	return checkTCP(ctx, tc.mainAddress, tc.send, tc.expect, tc.closeMsg, tc.connectTimeout)
 }
 
-func checkTCP(ctx context.Context, address string, send []byte, expect []byte, closeMsg []byte) types.StatusDescription {
+func checkTCP(ctx context.Context, address string, send []byte, expect []byte, closeMsg []byte, timeout time.Duration) types.StatusDescription {
 	_, portStr, err := net.SplitHostPort(address)
 	if err != nil {
 		return types.StatusDescription{
@@ -100,9 +107,18 @@ func checkTCP(ctx context.Context, address string, send []byte, expect []byte, c
 		}
 	}
 
+	if timeout <= 0 {
+		timeout = defaultTCPTimeout
+	}
+
+	timedOutStatus := types.StatusDescription{
+		CurrentStatus:     types.StatusCritical,
+		StatusDescription: fmt.Sprintf("TCP port %d, connection timed out after %s", port, formatTimeout(timeout)),
+	}
+
 	start := time.Now()
 
-	ctx2, cancel := context.WithTimeout(ctx, 10*time.Second)
+	ctx2, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	var dialer net.Dialer
@@ -110,10 +126,7 @@ func checkTCP(ctx context.Context, address string, send []byte, expect []byte, c
 	conn, err := dialer.DialContext(ctx2, "tcp", address)
 	if err != nil {
 		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-			return types.StatusDescription{
-				CurrentStatus:     types.StatusCritical,
-				StatusDescription: fmt.Sprintf("TCP port %d, connection timed out after 10 seconds", port),
-			}
+			return timedOutStatus
 		}
 
 		return types.StatusDescription{
@@ -124,7 +137,7 @@ func checkTCP(ctx context.Context, address string, send []byte, expect []byte, c
 
 	defer conn.Close()
 
-	err = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	err = conn.SetDeadline(time.Now().Add(timeout))
 	if err != nil {
 		logger.V(1).Printf("Unable to set Deadline: %v", err)
 
@@ -137,10 +150,7 @@ func checkTCP(ctx context.Context, address string, send []byte, expect []byte, c
 	if len(send) > 0 {
 		n, err := conn.Write(send)
 		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-			return types.StatusDescription{
-				CurrentStatus:     types.StatusCritical,
-				StatusDescription: fmt.Sprintf("TCP port %d, connection timed out after 10 seconds", port),
-			}
+			return timedOutStatus
 		}
 
 		if err != nil || n != len(send) {
@@ -155,10 +165,7 @@ func checkTCP(ctx context.Context, address string, send []byte, expect []byte, c
 		firstBytes, found, err := readUntilPatternFound(conn, expect)
 
 		if netErr, ok := err.(net.Error); ok && netErr.Timeout() && len(firstBytes) == 0 {
-			return types.StatusDescription{
-				CurrentStatus:     types.StatusCritical,
-				StatusDescription: fmt.Sprintf("TCP port %d, connection timed out after 10 seconds", port),
-			}
+			return timedOutStatus
 		} else if err != nil && (!ok || !netErr.Timeout()) {
 			return types.StatusDescription{
 				CurrentStatus:     types.StatusCritical,
@@ -205,6 +212,15 @@ func checkTCP(ctx context.Context, address string, send []byte, expect []byte, c
 	}
 }
 
+// formatTimeout returns the timeout in seconds when it's a whole number of seconds.
+func formatTimeout(timeout time.Duration) string {
+	if timeout%time.Second == 0 {
+		return fmt.Sprintf("%d seconds", timeout/time.Second)
+	}
+
+	return timeout.String()
+}
+
 func readUntilPatternFound(conn io.Reader, expect []byte) (firstBytes []byte, found bool, err error) {
 	// The following assume expect is less that 4096 bytes
 	readBuffer := make([]byte, 4096)
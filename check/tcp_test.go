@@ -0,0 +1,91 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bleemeo/glouton/types"
+)
+
+func TestCheckTCPTimeout(t *testing.T) {
+	t.Parallel()
+
+	// This listener accepts connections but never answers.
+	silentListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { silentListener.Close() })
+
+	// This address refuses connections.
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	closedAddress := closedListener.Addr().String()
+	closedListener.Close()
+
+	silentPort := silentListener.Addr().(*net.TCPAddr).Port //nolint:forcetypeassert
+	closedPort := closedListener.Addr().(*net.TCPAddr).Port //nolint:forcetypeassert
+
+	tests := []struct {
+		name    string
+		address string
+		want    types.StatusDescription
+	}{
+		{
+			name:    "timeout",
+			address: silentListener.Addr().String(),
+			want: types.StatusDescription{
+				CurrentStatus:     types.StatusCritical,
+				StatusDescription: fmt.Sprintf("TCP port %d, connection timed out after 100ms", silentPort),
+			},
+		},
+		{
+			name:    "refused",
+			address: closedAddress,
+			want: types.StatusDescription{
+				CurrentStatus:     types.StatusCritical,
+				StatusDescription: fmt.Sprintf("TCP port %d, Connection refused", closedPort),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			start := time.Now()
+
+			got := checkTCP(context.Background(), tt.address, []byte("PING\n"), []byte("PONG"), nil, 100*time.Millisecond)
+			if got != tt.want {
+				t.Errorf("checkTCP() = %v, want %v", got, tt.want)
+			}
+
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("checkTCP() took %v, the timeout wasn't applied", elapsed)
+			}
+		})
+	}
+}
@@ -452,6 +452,11 @@ func migrateMetricsPrometheus(k *koanf.Koanf, config map[string]interface{}) pro
 	}
 
 	for key, dict := range vMap {
+		// remote_write also has an url but it isn't a target.
+		if key == "remote_write" {
+			continue
+		}
+
 		tmp, ok := dict.(map[string]interface{})
 		if !ok {
 			continue
@@ -597,7 +602,7 @@ func migrateServices(config map[string]interface{}) prometheus.MultiError {
 }
 
 // Dump return a copy of the whole configuration, with secrets retracted.
-// secret is any key containing "key", "secret", "password" or "passwd".
+// secret is any key containing "key", "secret", "password", "passwd", "passphrase", "token" or "dsn".
 func Dump(config Config) map[string]interface{} {
 	k := koanf.New(delimiter)
 	_ = k.Load(structs.Provider(config, Tag), nil)
@@ -639,7 +644,7 @@ func CensorSecretItem(key string, value interface{}) interface{} {
 
 // isSecret returns whether the given config key corresponds to a secret.
 func isSecret(key string) bool {
-	for _, name := range []string{"key", "secret", "password", "passwd"} {
+	for _, name := range []string{"key", "secret", "password", "passwd", "passphrase", "token", "dsn"} {
 		if strings.Contains(key, name) {
 			return true
 		}
@@ -40,6 +40,8 @@ func mapKeys() []string {
 	return []string{
 		"thresholds",
 		"metric.softstatus_period",
+		"metric.resolution_overrides",
+		"metric.rename",
 		"influxdb.tags",
 	}
 }
@@ -58,11 +60,13 @@ func DefaultConfig() Config { //nolint:maintidx
 		Agent: Agent{
 			CloudImageCreationFile: "cloudimage_creation",
 			FactsFile:              "facts.yaml",
+			FactsScript:            "",
 			InstallationFormat:     "manual",
 			ProcessExporter: ProcessExporter{
 				Enable: true,
 			},
 			PublicIPIndicator:    "https://myip.bleemeo.com",
+			DNSCanary:            "",
 			NetstatFile:          "netstat.out",
 			StateDirectory:       "",
 			StateFile:            "state.json",
@@ -83,8 +87,12 @@ func DefaultConfig() Config { //nolint:maintidx
 				Collectors: []string{"cpu", "cs", "logical_disk", "logon", "memory", "net", "os", "system", "tcp"},
 			},
 			Telemetry: Telemetry{
-				Enable:  true,
-				Address: "https://telemetry.bleemeo.com/v1/telemetry/",
+				Enable:   true,
+				Address:  "https://telemetry.bleemeo.com/v1/telemetry/",
+				Interval: 86400,
+			},
+			CloudMetadata: CloudMetadata{
+				Enable: true,
 			},
 		},
 		Blackbox: Blackbox{
@@ -96,6 +104,7 @@ func DefaultConfig() Config { //nolint:maintidx
 			Modules: map[string]bbConf.Module{
 				"http": defaultBlackboxModule,
 			},
+			Transactions: []BlackboxTransaction{},
 		},
 		Bleemeo: Bleemeo{
 			Enable:         true,
@@ -110,12 +119,17 @@ func DefaultConfig() Config { //nolint:maintidx
 			InitialServerGroupName:            "",
 			InitialServerGroupNameForSNMP:     "",
 			InitialServerGroupNameForVSphere:  "",
+			MaxMetrics:                        0,
 			MQTT: BleemeoMQTT{
-				CAFile:      "",
-				Host:        "mqtt.bleemeo.com",
-				Port:        8883,
-				SSLInsecure: false,
-				SSL:         true,
+				CAFile:          "",
+				Host:            "mqtt.bleemeo.com",
+				Port:            8883,
+				SSLInsecure:     false,
+				SSL:             true,
+				MaxPayloadBytes: 0,
+				KeepAlive:       45,
+				ReconnectMin:    5,
+				ReconnectMax:    600,
 			},
 			RegistrationKey: "",
 			Sentry: Sentry{
@@ -124,6 +138,8 @@ func DefaultConfig() Config { //nolint:maintidx
 		},
 		Container: Container{
 			PIDNamespaceHost: false,
+			CollectDiskUsage: false,
+			MetricsAllowlist: []string{},
 			Type:             "",
 			Filter: ContainerFilter{
 				AllowByDefault: true,
@@ -146,6 +162,20 @@ func DefaultConfig() Config { //nolint:maintidx
 					},
 					PrefixHostRoot: true,
 				},
+				Podman: ContainerRuntimePodman{
+					Enable: true,
+					Addresses: []string{
+						"unix:///run/podman/podman.sock",
+						"unix:///run/user/*/podman/podman.sock",
+					},
+					PrefixHostRoot: true,
+				},
+				CRIO: ContainerRuntimeAddresses{
+					Addresses: []string{
+						"/var/run/crio/crio.sock",
+					},
+					PrefixHostRoot: true,
+				},
 			},
 		},
 		DF: DF{
@@ -203,6 +233,21 @@ func DefaultConfig() Config { //nolint:maintidx
 				"/dev",
 			},
 		},
+		Discovery: Discovery{
+			InitialDelay:    0,
+			NetstatFamilies: []string{},
+			Consul: Consul{
+				Enable:          false,
+				Address:         "http://localhost:8500",
+				Token:           "",
+				Datacenter:      "",
+				Tag:             "glouton",
+				RefreshInterval: 60,
+				ScrapeTargets:   true,
+				MetricsPath:     "/metrics",
+				Checks:          false,
+			},
+		},
 		DiskIgnore: []string{
 			// Ignore some devices
 			"^(bcache|cd|dm-|fd|loop|pass|ram|sr|zd|zram)\\d+$",
@@ -223,12 +268,34 @@ func DefaultConfig() Config { //nolint:maintidx
 			"^rsxx[0-9]$",
 			"^[A-Z]:$",
 		},
+		Facts: Facts{
+			DisabledSources: []string{},
+			VersionFile:     "",
+			VersionLabel:    false,
+		},
+		FileOutput: FileOutput{
+			Enable:           false,
+			Path:             "",
+			MaxSizeMB:        100,
+			MaxFiles:         5,
+			MaxPendingPoints: 100000,
+		},
+		Graphite: Graphite{
+			Enable:           false,
+			Host:             "localhost",
+			Port:             2003,
+			PathTemplate:     "{instance}.{__name__}.{*}",
+			MaxPendingPoints: 100000,
+		},
 		InfluxDB: InfluxDB{
-			Enable: false,
-			DBName: "glouton",
-			Host:   "localhost",
-			Port:   8086,
-			Tags:   map[string]string{},
+			Enable:           false,
+			DBName:           "glouton",
+			Host:             "localhost",
+			Port:             8086,
+			Tags:             map[string]string{},
+			Version:          1,
+			Precision:        "ns",
+			MaxPendingPoints: 100000,
 		},
 		IPMI: IPMI{
 			Enable:           true,
@@ -277,21 +344,42 @@ func DefaultConfig() Config { //nolint:maintidx
 		Metric: Metric{
 			Prometheus: Prometheus{
 				Targets: []PrometheusTarget{},
+				RemoteWrite: PrometheusRemoteWrite{
+					Enable:           false,
+					URL:              "",
+					Username:         "",
+					Password:         "",
+					Headers:          map[string]string{},
+					CAFile:           "",
+					SSLInsecure:      false,
+					MaxPendingPoints: 100000,
+				},
 			},
 			SNMP: SNMP{
 				ExporterAddress: "http://localhost:9116",
+				AuthsFile:       "",
 				Targets:         []SNMPTarget{},
 			},
 			IncludeDefaultMetrics:   true,
 			AllowMetrics:            []string{},
 			DenyMetrics:             []string{},
 			SoftStatusPeriodDefault: 5 * 60,
+			AlignTimestamps:         false,
 			SoftStatusPeriod: map[string]int{
 				"system_pending_updates":          86400,
 				"system_pending_security_updates": 86400,
 				"time_elapsed_since_last_data":    0,
 				"time_drift":                      0,
 			},
+			SoftStatusRecoveryDefault: 0,
+			SoftStatusRecovery:        map[string]int{},
+			ResolutionOverrides:       map[string]int{},
+			Rename:                    map[string]string{},
+			EmitRawCounters:           false,
+			ExporterDenyMetrics:       []string{},
+			StaleMetrics:              []string{},
+			EssentialMetrics:          []string{},
+			EnvoyMaxSeriesPerFamily:   100,
 		},
 		MQTT: OpenSourceMQTT{
 			Enable:      false,
@@ -314,21 +402,40 @@ func DefaultConfig() Config { //nolint:maintidx
 			"fwpr",
 			"fwln",
 		},
-		NRPE: NRPE{
+		NetworkNamespaces: NetworkNamespaces{
 			Enable:    false,
-			Address:   "0.0.0.0",
-			Port:      5666,
-			SSL:       true,
-			ConfPaths: []string{"/etc/nagios/nrpe.cfg"},
+			AllowList: []string{},
+		},
+		NRPE: NRPE{
+			Enable:         false,
+			Address:        "0.0.0.0",
+			Port:           5666,
+			SSL:            true,
+			ConfPaths:      []string{"/etc/nagios/nrpe.cfg"},
+			AllowArguments: false,
 		},
 		NvidiaSMI: NvidiaSMI{
 			Enable:  false,
 			BinPath: "/usr/bin/nvidia-smi",
 			Timeout: 5,
 		},
-		ServiceIgnoreCheck:   []NameInstance{},
-		ServiceIgnoreMetrics: []NameInstance{},
-		Services:             []Service{},
+		OTLP: OTLP{
+			Enable:           false,
+			Protocol:         "grpc",
+			Endpoint:         "localhost:4317",
+			Insecure:         false,
+			CAFile:           "",
+			SSLInsecure:      false,
+			Headers:          map[string]string{},
+			MaxPendingPoints: 100000,
+		},
+		Process: Process{
+			CountZombies: true,
+		},
+		ServiceConnectTimeout: 10,
+		ServiceIgnoreCheck:    []NameInstance{},
+		ServiceIgnoreMetrics:  []NameInstance{},
+		Services:              []Service{},
 		Smart: Smart{
 			Enable:       true,
 			PathSmartctl: "smartctl",
@@ -338,13 +445,22 @@ func DefaultConfig() Config { //nolint:maintidx
 			},
 			MaxConcurrency: 4,
 		},
+		SQL: SQL{
+			Queries: []SQLQuery{},
+		},
+		Store: Store{
+			SnapshotFile:  "",
+			BatchWindowMS: 0,
+		},
 		Tags: []string{},
 		Telegraf: Telegraf{
 			DockerMetricsEnable: true,
 			StatsD: StatsD{
-				Enable:  true,
-				Address: "127.0.0.1",
-				Port:    8125,
+				Enable:            true,
+				Address:           "127.0.0.1",
+				Port:              8125,
+				DatadogExtensions: false,
+				Listeners:         []StatsDListener{},
 			},
 		},
 		Thresholds: map[string]Threshold{},
@@ -352,7 +468,9 @@ func DefaultConfig() Config { //nolint:maintidx
 		Web: Web{
 			Enable: true,
 			Endpoints: WebEndpoints{
-				DebugEnable: false,
+				DebugEnable:       false,
+				PushGatewayEnable: false,
+				PushGatewayTTL:    3600,
 			},
 			Listener: Listener{
 				Address: "127.0.0.1",
@@ -361,12 +479,21 @@ func DefaultConfig() Config { //nolint:maintidx
 			LocalUI: LocalUI{
 				Enable: true,
 			},
+			API: WebAPI{
+				Enable: false,
+			},
 			StaticCDNURL: "/static/",
 		},
 		Zabbix: Zabbix{
 			Enable:  false,
 			Address: "127.0.0.1",
 			Port:    10050,
+			Host:    "",
+			Active: ZabbixActive{
+				Enable:          false,
+				ServerAddress:   "127.0.0.1:10051",
+				RefreshInterval: 120,
+			},
 		},
 	}
 }
@@ -0,0 +1,164 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/knadh/koanf/providers/confmap"
+	"github.com/knadh/koanf/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var errUnresolvedVariable = errors.New("environment variable is not set")
+
+// expandEnv replaces the references to environment variables in the string values
+// of the config, including the values nested in maps and lists. It returns
+// a warning for each variable which isn't set and has no default value.
+func expandEnv(k *koanf.Koanf) (*koanf.Koanf, prometheus.MultiError) {
+	var warnings prometheus.MultiError
+
+	config := k.All()
+	unresolved := make(map[string]struct{})
+
+	for key, value := range config {
+		config[key] = expandValue(value, os.LookupEnv, unresolved)
+	}
+
+	names := make([]string, 0, len(unresolved))
+
+	for name := range unresolved {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		warnings.Append(fmt.Errorf("%w: %s", errUnresolvedVariable, name))
+	}
+
+	// We can't reuse the previous Koanf because it doesn't allow replacing nested values.
+	newConfig := koanf.New(delimiter)
+
+	warning := newConfig.Load(confmap.Provider(config, delimiter), nil)
+	warnings.Append(warning)
+
+	return newConfig, warnings
+}
+
+// expandValue expands the strings contained in value.
+func expandValue(value interface{}, lookup func(string) (string, bool), unresolved map[string]struct{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return expandString(v, lookup, unresolved)
+	case []interface{}:
+		for i, item := range v {
+			v[i] = expandValue(item, lookup, unresolved)
+		}
+
+		return v
+	case []string:
+		for i, item := range v {
+			v[i] = expandString(item, lookup, unresolved)
+		}
+
+		return v
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = expandValue(item, lookup, unresolved)
+		}
+
+		return v
+	default:
+		return value
+	}
+}
+
+// expandString replaces $VAR, ${VAR} and ${VAR:-default} by the value of the
+// variable. "$$" is replaced by a single "$". A "$" which isn't followed by a
+// variable name is kept as is, so regular expressions like "^foo$" are unchanged.
+// The names of the variables not set and without default are added to unresolved.
+func expandString(value string, lookup func(string) (string, bool), unresolved map[string]struct{}) string {
+	if !strings.Contains(value, "$") {
+		return value
+	}
+
+	var result strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i+1 == len(value) {
+			result.WriteByte(value[i])
+
+			continue
+		}
+
+		next := value[i+1]
+
+		switch {
+		case next == '$':
+			result.WriteByte('$')
+
+			i++
+		case next == '{':
+			end := strings.IndexByte(value[i+2:], '}')
+			if end == -1 {
+				result.WriteByte(value[i])
+
+				continue
+			}
+
+			name, defaultValue, hasDefault := strings.Cut(value[i+2:i+2+end], ":-")
+
+			if envValue, ok := lookup(name); ok && (envValue != "" || !hasDefault) {
+				result.WriteString(envValue)
+			} else if hasDefault {
+				result.WriteString(defaultValue)
+			} else {
+				unresolved[name] = struct{}{}
+			}
+
+			i += end + 2
+		case isVariableStart(next):
+			end := i + 2
+			for end < len(value) && (isVariableStart(value[end]) || value[end] >= '0' && value[end] <= '9') {
+				end++
+			}
+
+			name := value[i+1 : end]
+
+			if envValue, ok := lookup(name); ok {
+				result.WriteString(envValue)
+			} else {
+				unresolved[name] = struct{}{}
+			}
+
+			i = end - 1
+		default:
+			result.WriteByte(value[i])
+		}
+	}
+
+	return result.String()
+}
+
+func isVariableStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
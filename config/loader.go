@@ -109,6 +109,14 @@ func (c *configLoader) Load(path string, provider koanf.Provider, parser koanf.P
 	err := k.Load(provider, parser)
 	warnings.Append(err)
 
+	// Expand the environment variables referenced in the config files.
+	if providerType == SourceFile {
+		var moreWarnings prometheus.MultiError
+
+		k, moreWarnings = expandEnv(k)
+		warnings = append(warnings, moreWarnings...)
+	}
+
 	// Migrate old configuration keys.
 	k, moreWarnings := migrate(k)
 	warnings = append(warnings, moreWarnings...)
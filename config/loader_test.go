@@ -77,9 +77,11 @@ func TestLoader(t *testing.T) {
 			Key: "blackbox.targets",
 			Value: []any{
 				map[string]any{
-					"module": "mymodule",
-					"name":   "myname",
-					"url":    "https://bleemeo.com",
+					"interval":  float64(0),
+					"module":    "mymodule",
+					"name":      "myname",
+					"source_ip": "",
+					"url":       "https://bleemeo.com",
 				},
 			},
 			Type:     TypeBlackboxTargets,
@@ -129,7 +131,10 @@ func TestLoader(t *testing.T) {
 				map[string]any{
 					"allow_metrics": nil,
 					"deny_metrics":  nil,
+					"honor_labels":  false,
+					"metric_prefix": "",
 					"name":          "my_app",
+					"source":        "",
 					"url":           "http://localhost:8080/metrics",
 				},
 			},
@@ -150,8 +155,14 @@ func TestLoader(t *testing.T) {
 			Key: "metric.snmp.targets",
 			Value: []any{
 				map[string]any{
-					"initial_name": "AP Wifi",
-					"target":       "127.0.0.1",
+					"initial_name":    "AP Wifi",
+					"target":          "127.0.0.1",
+					"security_name":   "",
+					"auth_protocol":   "",
+					"auth_passphrase": "",
+					"priv_protocol":   "",
+					"priv_passphrase": "",
+					"context_name":    "",
 				},
 			},
 			Type:     TypeSNMPTargets,
@@ -190,6 +201,9 @@ func TestLoader(t *testing.T) {
 					"port":                0.0,
 					"stats_port":          0.0,
 					"check_command":       "",
+					"connect_timeout":     0.0,
+					"enabled":             nil,
+					"status_file":         "",
 					"jmx_password":        "",
 					"excluded_items":      nil,
 					"http_path":           "",
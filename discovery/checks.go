@@ -18,14 +18,17 @@ package discovery
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/bleemeo/glouton/check"
 	"github.com/bleemeo/glouton/facts"
 	"github.com/bleemeo/glouton/logger"
+	"github.com/bleemeo/glouton/mqtt"
 	"github.com/bleemeo/glouton/prometheus/registry"
 	"github.com/bleemeo/glouton/types"
 
@@ -37,6 +40,10 @@ const (
 	customCheckHTTP    = "http"
 	customCheckNagios  = "nagios"
 	customCheckProcess = "process"
+	customCheckGRPC    = "grpc"
+
+	// clickHouseHTTPPort is the default port of the ClickHouse HTTP interface.
+	clickHouseHTTPPort = 8123
 )
 
 // CheckDetails is used to save a check and his id.
@@ -140,6 +147,14 @@ func (d *Discovery) createCheck(service Service) {
 		d.createTCPCheck(service, di, primaryAddress, tcpAddresses, labels, annotations)
 	case ApacheService, InfluxDBService, NginxService, SquidService:
 		d.createHTTPCheck(service, di, primaryAddress, tcpAddresses, labels, annotations)
+	case ClickHouseService:
+		// Prefer the /ping endpoint of the HTTP interface, the native protocol
+		// is only checked with a TCP connection.
+		if httpAddress := clickHouseHTTPAddress(service); httpAddress != "" {
+			d.createHTTPCheck(service, di, httpAddress, tcpAddresses, labels, annotations)
+		} else {
+			d.createTCPCheck(service, di, primaryAddress, tcpAddresses, labels, annotations)
+		}
 	case NTPService:
 		if primaryAddress != "" {
 			check := check.NewNTP(
@@ -178,6 +193,25 @@ func (d *Discovery) createCheck(service Service) {
 	}
 }
 
+// clickHouseHTTPAddress returns the address of the HTTP interface of a ClickHouse
+// server, or an empty string if the server doesn't listen on it.
+func clickHouseHTTPAddress(service Service) string {
+	port := clickHouseHTTPPort
+	force := false
+
+	if service.Config.StatsPort != 0 {
+		port = service.Config.StatsPort
+		force = true
+	}
+
+	ip := service.AddressForPort(port, "tcp", force)
+	if ip == "" {
+		return ""
+	}
+
+	return net.JoinHostPort(ip, strconv.Itoa(port))
+}
+
 func createCheckType(service Service, d *Discovery, di discoveryInfo, primaryAddress string, tcpAddresses []string, labels map[string]string, annotations types.MetricAnnotations) {
 	switch service.Config.CheckType {
 	case customCheckTCP:
@@ -188,6 +222,8 @@ func createCheckType(service Service, d *Discovery, di discoveryInfo, primaryAdd
 		d.createNagiosCheck(service, primaryAddress, labels, annotations)
 	case customCheckProcess:
 		d.createProcessCheck(service, labels, annotations)
+	case customCheckGRPC:
+		d.createGRPCCheck(service, di, primaryAddress, tcpAddresses, labels, annotations)
 	default:
 		logger.V(1).Printf("Unknown check type %#v on custom service %#v", service.Config.CheckType, service.Name)
 	}
@@ -210,7 +246,10 @@ func (d *Discovery) createTCPCheck(service Service, di discoveryInfo, primaryAdd
 	case RedisService:
 		tcpSend = []byte("PING\n")
 
-		if service.Config.Password != "" {
+		switch {
+		case service.Config.Username != "" && service.Config.Password != "":
+			tcpSend = []byte(fmt.Sprintf("AUTH %s %s\nPING\n", service.Config.Username, service.Config.Password))
+		case service.Config.Password != "":
 			tcpSend = []byte(fmt.Sprintf("AUTH %s\nPING\n", service.Config.Password))
 		}
 
@@ -220,6 +259,11 @@ func (d *Discovery) createTCPCheck(service Service, di discoveryInfo, primaryAdd
 		tcpExpect = []byte("imok")
 	}
 
+	connectTimeout := d.connectTimeout
+	if service.Config.ConnectTimeout != 0 {
+		connectTimeout = time.Duration(service.Config.ConnectTimeout) * time.Second
+	}
+
 	tcpCheck := check.NewTCP(
 		primaryAddress,
 		tcpAddresses,
@@ -227,6 +271,7 @@ func (d *Discovery) createTCPCheck(service Service, di discoveryInfo, primaryAdd
 		tcpSend,
 		tcpExpect,
 		tcpClose,
+		connectTimeout,
 		labels,
 		annotations,
 	)
@@ -263,7 +308,7 @@ func (d *Discovery) createHTTPCheck(
 		expectedStatusCode = 400
 	}
 
-	if service.ServiceType == InfluxDBService {
+	if service.ServiceType == InfluxDBService || service.ServiceType == ClickHouseService {
 		u.Path = "/ping"
 	}
 
@@ -293,6 +338,45 @@ func (d *Discovery) createHTTPCheck(
 	d.addCheck(httpCheck, service)
 }
 
+func (d *Discovery) createGRPCCheck(
+	service Service,
+	di discoveryInfo,
+	primaryAddress string,
+	tcpAddresses []string,
+	labels map[string]string,
+	annotations types.MetricAnnotations,
+) {
+	if primaryAddress == "" {
+		d.createTCPCheck(service, di, primaryAddress, tcpAddresses, labels, annotations)
+
+		return
+	}
+
+	var tlsConfig *tls.Config
+
+	if service.Config.SSL {
+		tlsConfig = mqtt.TLSConfig(service.Config.SSLInsecure, service.Config.CAFile)
+	}
+
+	connectTimeout := d.connectTimeout
+	if service.Config.ConnectTimeout != 0 {
+		connectTimeout = time.Duration(service.Config.ConnectTimeout) * time.Second
+	}
+
+	grpcCheck := check.NewGRPC(
+		primaryAddress,
+		tcpAddresses,
+		!di.DisablePersistentConnection,
+		service.Config.GRPCService,
+		tlsConfig,
+		connectTimeout,
+		labels,
+		annotations,
+	)
+
+	d.addCheck(grpcCheck, service)
+}
+
 func (d *Discovery) createContainerStoppedCheck(
 	service Service,
 	primaryAddress string,
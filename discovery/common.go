@@ -69,6 +69,7 @@ const (
 	BindService          ServiceName = "bind"
 	BitBucketService     ServiceName = "bitbucket"
 	CassandraService     ServiceName = "cassandra"
+	ClickHouseService    ServiceName = "clickhouse"
 	ConfluenceService    ServiceName = "confluence"
 	DovecotService       ServiceName = "dovecot"
 	EjabberService       ServiceName = "ejabberd"
@@ -99,6 +100,7 @@ const (
 	RedisService         ServiceName = "redis"
 	SaltMasterService    ServiceName = "salt_master"
 	SquidService         ServiceName = "squid"
+	TraefikService       ServiceName = "traefik"
 	UWSGIService         ServiceName = "uwsgi"
 	VarnishService       ServiceName = "varnish"
 	UPSDService          ServiceName = "upsd"
@@ -138,10 +140,15 @@ type Service struct {
 	MetricsIgnored  bool
 	// The interval of the check, used only for custom checks.
 	Interval time.Duration
+	// IsolatedNamespaces are the namespaces (mnt, pid) of the process which differ from the host ones.
+	// It's only set for services running outside a container.
+	IsolatedNamespaces []string
 
 	HasNetstatInfo  bool
 	LastNetstatInfo time.Time
 	container       facts.Container
+	// phpfpmStatusURLs are the status pages of the PHP-FPM pools found in the configuration.
+	phpfpmStatusURLs []string
 }
 
 func (s Service) String() string {
@@ -274,6 +281,10 @@ func (s Service) merge(update Service) Service {
 		}
 	}
 
+	if len(s.phpfpmStatusURLs) == 0 {
+		s.phpfpmStatusURLs = update.phpfpmStatusURLs
+	}
+
 	// Merge configs, existing values are preferred.
 	if err := mergo.Merge(&s.Config, update.Config); err != nil {
 		logger.V(1).Printf("Failed to merge service configs: %s", err)
@@ -306,6 +317,10 @@ var (
 			ServiceProtocol: "tcp",
 			IgnoreHighPort:  true,
 		},
+		ClickHouseService: {
+			ServicePort:     9000,
+			ServiceProtocol: "tcp",
+		},
 		ConfluenceService: {
 			ServicePort:     8090,
 			ServiceProtocol: "tcp",
@@ -417,6 +432,10 @@ var (
 			ServicePort:     3128,
 			ServiceProtocol: "tcp",
 		},
+		TraefikService: {
+			ServicePort:     80,
+			ServiceProtocol: "tcp",
+		},
 		UPSDService: {
 			ServicePort:     3493,
 			ServiceProtocol: "tcp",
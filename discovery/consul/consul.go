@@ -0,0 +1,381 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package consul discovers the services registered in the Consul catalog and
+// registers them as scrape targets and/or checks.
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bleemeo/glouton/check"
+	"github.com/bleemeo/glouton/logger"
+	"github.com/bleemeo/glouton/prometheus/registry"
+	"github.com/bleemeo/glouton/prometheus/scrapper"
+	"github.com/bleemeo/glouton/types"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+const tokenHeader = "X-Consul-Token"
+
+var (
+	errUnexpectedStatus = errors.New("unexpected status code")
+	errMissingTag       = errors.New("a tag is required")
+)
+
+// GathererRegistry allow to register/unregister prometheus Gatherer.
+type GathererRegistry interface {
+	RegisterGatherer(opt registry.RegistrationOption, gatherer prometheus.Gatherer) (int, error)
+	Unregister(id int) bool
+}
+
+// Options are the options of the Consul discovery.
+type Options struct {
+	// Address is the URL of the Consul HTTP API.
+	Address    string
+	Token      string
+	Datacenter string
+	// Tag is the tag the services must have to be discovered.
+	Tag             string
+	RefreshInterval time.Duration
+	// ScrapeTargets registers a scrape target on MetricsPath for each service.
+	ScrapeTargets  bool
+	MetricsPath    string
+	ScrapeInterval time.Duration
+	// Checks registers a TCP check for each service.
+	Checks         bool
+	ConnectTimeout time.Duration
+}
+
+// Service is a service instance registered in the Consul catalog.
+type Service struct {
+	ID      string
+	Name    string
+	Node    string
+	Address string
+	Port    int
+}
+
+// HostPort returns the address used to reach the service.
+func (s Service) HostPort() string {
+	return net.JoinHostPort(s.Address, strconv.Itoa(s.Port))
+}
+
+// key identifies a service instance. A change of the address creates a new instance.
+func (s Service) key() string {
+	return s.Node + "/" + s.ID + "/" + s.HostPort()
+}
+
+// Discovery periodically queries the Consul catalog and keeps the registry in sync.
+type Discovery struct {
+	opts     Options
+	registry GathererRegistry
+	baseURL  *url.URL
+	client   *http.Client
+
+	l             sync.Mutex
+	registrations map[string][]int
+}
+
+// New returns a Consul discovery.
+func New(opts Options, registry GathererRegistry) (*Discovery, error) {
+	baseURL, err := url.Parse(opts.Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Consul address: %w", err)
+	}
+
+	if opts.Tag == "" {
+		return nil, errMissingTag
+	}
+
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = time.Minute
+	}
+
+	if opts.MetricsPath == "" {
+		opts.MetricsPath = "/metrics"
+	}
+
+	return &Discovery{
+		opts:          opts,
+		registry:      registry,
+		baseURL:       baseURL,
+		client:        &http.Client{Timeout: 10 * time.Second},
+		registrations: make(map[string][]int),
+	}, nil
+}
+
+// Run queries the Consul catalog every refresh interval until the context is cancelled.
+// All registrations are removed when it returns.
+func (d *Discovery) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.opts.RefreshInterval)
+	defer ticker.Stop()
+
+	defer d.unregisterAll()
+
+	for {
+		if err := d.refresh(ctx); err != nil && ctx.Err() == nil {
+			logger.V(1).Printf("Consul discovery failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh registers the services newly found in the catalog and unregisters
+// the ones which disappeared.
+func (d *Discovery) refresh(ctx context.Context) error {
+	services, err := d.Services(ctx)
+	if err != nil {
+		return err
+	}
+
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	seen := make(map[string]bool, len(services))
+
+	for _, service := range services {
+		key := service.key()
+		seen[key] = true
+
+		if _, ok := d.registrations[key]; ok {
+			continue
+		}
+
+		d.registrations[key] = d.register(service)
+	}
+
+	for key, ids := range d.registrations {
+		if seen[key] {
+			continue
+		}
+
+		for _, id := range ids {
+			d.registry.Unregister(id)
+		}
+
+		delete(d.registrations, key)
+	}
+
+	return nil
+}
+
+func (d *Discovery) unregisterAll() {
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	for key, ids := range d.registrations {
+		for _, id := range ids {
+			d.registry.Unregister(id)
+		}
+
+		delete(d.registrations, key)
+	}
+}
+
+func (d *Discovery) register(service Service) []int {
+	var ids []int
+
+	if d.opts.ScrapeTargets {
+		u := &url.URL{
+			Scheme: "http",
+			Host:   service.HostPort(),
+			Path:   d.opts.MetricsPath,
+		}
+
+		target := scrapper.New(u, map[string]string{
+			types.LabelMetaScrapeJob:      service.Name,
+			types.LabelMetaScrapeInstance: service.HostPort(),
+		})
+
+		id, err := d.registry.RegisterGatherer(
+			registry.RegistrationOption{
+				Description:              "Consul service " + service.Name + " " + u.String(),
+				JitterSeed:               labels.FromMap(target.ExtraLabels).Hash(),
+				Interval:                 d.opts.ScrapeInterval,
+				ExtraLabels:              target.ExtraLabels,
+				AcceptAllowedMetricsOnly: true,
+				HonorTimestamp:           true,
+			},
+			target,
+		)
+		if err != nil {
+			logger.Printf("Unable to add Prometheus scrapper for Consul service %s: %v", service.Name, err)
+		} else {
+			ids = append(ids, id)
+		}
+	}
+
+	if d.opts.Checks {
+		lbls := map[string]string{
+			types.LabelName:            types.MetricServiceStatus,
+			types.LabelService:         service.Name,
+			types.LabelServiceInstance: service.ID,
+		}
+		annotations := types.MetricAnnotations{
+			ServiceName:     service.Name,
+			ServiceInstance: service.ID,
+		}
+
+		tcpCheck := check.NewTCP(service.HostPort(), nil, false, nil, nil, nil, d.opts.ConnectTimeout, lbls, annotations)
+		checkGatherer := check.NewCheckGatherer(tcpCheck)
+
+		id, err := d.registry.RegisterGatherer(
+			registry.RegistrationOption{
+				Description:  "check for Consul service " + service.Name + " " + service.HostPort(),
+				JitterSeed:   labels.FromMap(lbls).Hash(),
+				StopCallback: checkGatherer.Close,
+				MinInterval:  time.Minute,
+			},
+			checkGatherer,
+		)
+		if err != nil {
+			logger.Printf("Unable to add check for Consul service %s: %v", service.Name, err)
+		} else {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+type catalogService struct {
+	Node           string
+	Address        string
+	ServiceID      string
+	ServiceName    string
+	ServiceAddress string
+	ServicePort    int
+}
+
+// Services returns the services of the catalog which have the configured tag.
+func (d *Discovery) Services(ctx context.Context) ([]Service, error) {
+	var names map[string][]string
+
+	if err := d.get(ctx, "/v1/catalog/services", nil, &names); err != nil {
+		return nil, err
+	}
+
+	var result []Service
+
+	for name, tags := range names {
+		if !hasTag(tags, d.opts.Tag) {
+			continue
+		}
+
+		var entries []catalogService
+
+		query := url.Values{"tag": {d.opts.Tag}}
+
+		if err := d.get(ctx, "/v1/catalog/service/"+url.PathEscape(name), query, &entries); err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			address := entry.ServiceAddress
+			if address == "" {
+				address = entry.Address
+			}
+
+			if address == "" || entry.ServicePort == 0 {
+				continue
+			}
+
+			result = append(result, Service{
+				ID:      entry.ServiceID,
+				Name:    entry.ServiceName,
+				Node:    entry.Node,
+				Address: address,
+				Port:    entry.ServicePort,
+			})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].key() < result[j].key()
+	})
+
+	return result, nil
+}
+
+func (d *Discovery) get(ctx context.Context, path string, query url.Values, v interface{}) error {
+	u := *d.baseURL
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+
+	if d.opts.Datacenter != "" {
+		if query == nil {
+			query = url.Values{}
+		}
+
+		query.Set("dc", d.opts.Datacenter)
+	}
+
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	if d.opts.Token != "" {
+		req.Header.Set(tokenHeader, d.opts.Token)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Drain the body to allow reusing the connection.
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		return fmt.Errorf("%w %d for %s", errUnexpectedStatus, resp.StatusCode, path)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,199 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/bleemeo/glouton/prometheus/registry"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type fakeRegistry struct {
+	l          sync.Mutex
+	nextID     int
+	registered map[int]string
+}
+
+func (r *fakeRegistry) RegisterGatherer(opt registry.RegistrationOption, _ prometheus.Gatherer) (int, error) {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	r.nextID++
+	r.registered[r.nextID] = opt.Description
+
+	return r.nextID, nil
+}
+
+func (r *fakeRegistry) Unregister(id int) bool {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	_, ok := r.registered[id]
+	delete(r.registered, id)
+
+	return ok
+}
+
+func (r *fakeRegistry) descriptions() []string {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	result := make([]string, 0, len(r.registered))
+
+	for _, d := range r.registered {
+		result = append(result, d)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// fakeConsul serves the catalog endpoints from a list of services per name.
+type fakeConsul struct {
+	l        sync.Mutex
+	token    string
+	tags     map[string][]string
+	services map[string][]map[string]interface{}
+}
+
+func (c *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if r.Header.Get(tokenHeader) != c.token {
+		w.WriteHeader(http.StatusForbidden)
+
+		return
+	}
+
+	var body interface{}
+
+	switch r.URL.Path {
+	case "/v1/catalog/services":
+		body = c.tags
+	default:
+		name := r.URL.Path[len("/v1/catalog/service/"):]
+		body = c.services[name]
+	}
+
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func TestRefresh(t *testing.T) {
+	t.Parallel()
+
+	consul := &fakeConsul{
+		token: "secret",
+		tags: map[string][]string{
+			"web":   {"glouton", "http"},
+			"db":    {"glouton"},
+			"cache": {"other"},
+		},
+		services: map[string][]map[string]interface{}{
+			"web": {
+				{"Node": "n1", "Address": "10.0.0.1", "ServiceID": "web-1", "ServiceName": "web", "ServicePort": 8080},
+				{"Node": "n2", "Address": "10.0.0.2", "ServiceID": "web-2", "ServiceName": "web", "ServiceAddress": "192.168.0.2", "ServicePort": 8080},
+			},
+			"db": {
+				{"Node": "n1", "Address": "10.0.0.1", "ServiceID": "db", "ServiceName": "db", "ServicePort": 5432},
+			},
+		},
+	}
+
+	srv := httptest.NewServer(consul)
+	t.Cleanup(srv.Close)
+
+	reg := &fakeRegistry{registered: make(map[int]string)}
+
+	d, err := New(Options{
+		Address:       srv.URL,
+		Token:         "secret",
+		Tag:           "glouton",
+		ScrapeTargets: true,
+		Checks:        true,
+	}, reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"Consul service db http://10.0.0.1:5432/metrics",
+		"Consul service web http://10.0.0.1:8080/metrics",
+		"Consul service web http://192.168.0.2:8080/metrics",
+		"check for Consul service db 10.0.0.1:5432",
+		"check for Consul service web 10.0.0.1:8080",
+		"check for Consul service web 192.168.0.2:8080",
+	}
+
+	if diff := cmp.Diff(want, reg.descriptions()); diff != "" {
+		t.Errorf("registrations mismatch (-want +got):\n%s", diff)
+	}
+
+	consul.l.Lock()
+	consul.tags["db"] = []string{"other"}
+	consul.services["web"] = consul.services["web"][:1]
+	consul.l.Unlock()
+
+	if err := d.refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want = []string{
+		"Consul service web http://10.0.0.1:8080/metrics",
+		"check for Consul service web 10.0.0.1:8080",
+	}
+
+	if diff := cmp.Diff(want, reg.descriptions()); diff != "" {
+		t.Errorf("registrations after update mismatch (-want +got):\n%s", diff)
+	}
+
+	d.unregisterAll()
+
+	if got := reg.descriptions(); len(got) != 0 {
+		t.Errorf("registrations = %v, want none", got)
+	}
+}
+
+func TestServicesToken(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(&fakeConsul{token: "secret"})
+	t.Cleanup(srv.Close)
+
+	d, err := New(Options{Address: srv.URL, Token: "wrong", Tag: "glouton"}, &fakeRegistry{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.Services(context.Background()); err == nil {
+		t.Error("Services() succeeded with an invalid token")
+	}
+}
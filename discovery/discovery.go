@@ -24,6 +24,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bleemeo/glouton/config"
@@ -59,6 +60,11 @@ type Discovery struct {
 	discoveredServicesMap map[NameInstance]Service
 	servicesMap           map[NameInstance]Service
 	lastDiscoveryUpdate   time.Time
+	// lastUpdateUnixNano mirrors lastDiscoveryUpdate so it could be read
+	// without taking the lock, which is held during a (possibly stuck) discovery.
+	lastUpdateUnixNano atomic.Int64
+	// firstDiscoveryAt is the time before which no discovery is done.
+	firstDiscoveryAt time.Time
 
 	lastConfigservicesMap map[NameInstance]Service
 	activeCollector       map[NameInstance]collectorDetails
@@ -72,6 +78,7 @@ type Discovery struct {
 	isContainerIgnored    func(facts.Container) bool
 	metricFormat          types.MetricFormat
 	processFact           processFact
+	connectTimeout        time.Duration
 	pendingUpdateCond     *sync.Cond
 	pendingUpdate         bool
 }
@@ -107,6 +114,7 @@ func New(
 	isContainerIgnored func(c facts.Container) bool,
 	metricFormat types.MetricFormat,
 	processFact processFact,
+	connectTimeout time.Duration,
 ) (*Discovery, prometheus.MultiError) {
 	initialServices := servicesFromState(state)
 	discoveredServicesMap := make(map[NameInstance]Service, len(initialServices))
@@ -135,6 +143,7 @@ func New(
 		isContainerIgnored:    isContainerIgnored,
 		metricFormat:          metricFormat,
 		processFact:           processFact,
+		connectTimeout:        connectTimeout,
 	}
 
 	discovery.pendingUpdateCond = sync.NewCond(&discovery.l)
@@ -142,6 +151,13 @@ func New(
 	return discovery, warnings
 }
 
+// ValidateServices validates the service overrides and returns the warnings.
+func ValidateServices(services []config.Service) prometheus.MultiError {
+	_, warnings := validateServices(services)
+
+	return warnings
+}
+
 // validateServices validates the service config.
 // It returns the services as a map and some warnings.
 func validateServices(services []config.Service) (map[NameInstance]config.Service, prometheus.MultiError) {
@@ -205,6 +221,21 @@ func validateServices(services []config.Service) (map[NameInstance]config.Servic
 			srv.StatsProtocol = ""
 		}
 
+		// The gRPC check needs a port to dial, the address defaults to localhost.
+		if srv.CheckType == customCheckGRPC && srv.Port == 0 {
+			warning := fmt.Errorf(
+				"%w: service '%s' uses a grpc check but its port is not set",
+				config.ErrInvalidValue, srv.Type,
+			)
+			warnings.Append(warning)
+
+			continue
+		}
+
+		if srv.Enabled != nil && !*srv.Enabled {
+			logger.V(1).Printf("Service override %s (instance %q) is disabled, it won't be checked nor monitored", srv.Type, srv.Instance)
+		}
+
 		// Check for duplicated overrides.
 		key := NameInstance{
 			Name:     srv.Type,
@@ -249,12 +280,33 @@ func (d *Discovery) Discovery(ctx context.Context, maxAge time.Duration) (servic
 	return d.discovery(ctx, maxAge)
 }
 
-// LastUpdate return when the last update occurred.
-func (d *Discovery) LastUpdate() time.Time {
+// DelayFirstDiscovery prevents any discovery from running before the delay elapsed.
+// This allows services on slow-booting hosts to bind their ports before the first discovery.
+func (d *Discovery) DelayFirstDiscovery(delay time.Duration) {
 	d.l.Lock()
 	defer d.l.Unlock()
 
-	return d.lastDiscoveryUpdate
+	d.firstDiscoveryAt = time.Now().Add(delay)
+}
+
+// ListeningPorts returns the TCP and UDP ports on which processes listened during the last dynamic discovery.
+func (d *Discovery) ListeningPorts() []ListeningPort {
+	if dd, ok := d.dynamicDiscovery.(*DynamicDiscovery); ok {
+		return dd.ListeningPorts()
+	}
+
+	return nil
+}
+
+// LastUpdate return when the last update occurred.
+// It doesn't take the discovery lock.
+func (d *Discovery) LastUpdate() time.Time {
+	unixNano := d.lastUpdateUnixNano.Load()
+	if unixNano == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, unixNano)
 }
 
 // DiagnosticArchive add to a zipfile useful diagnostic information.
@@ -364,7 +416,7 @@ func (d *Discovery) discovery(ctx context.Context, maxAge time.Duration) (servic
 	ctx, cancel := context.WithTimeout(ctx, discoveryTimeout)
 	defer cancel()
 
-	if time.Since(d.lastDiscoveryUpdate) >= maxAge {
+	if time.Since(d.lastDiscoveryUpdate) >= maxAge && !time.Now().Before(d.firstDiscoveryAt) {
 		for d.pendingUpdate {
 			d.pendingUpdateCond.Wait()
 		}
@@ -389,6 +441,7 @@ func (d *Discovery) discovery(ctx context.Context, maxAge time.Duration) (servic
 				d.reconfigure()
 
 				d.lastDiscoveryUpdate = time.Now()
+				d.lastUpdateUnixNano.Store(d.lastDiscoveryUpdate.UnixNano())
 			}
 		}
 	}
@@ -665,7 +718,8 @@ func applyOverrideInPlace(
 			}
 		}
 
-		if service.ServiceType == CustomService {
+		// A disabled custom service is kept without being validated, it won't be checked anyway.
+		if service.ServiceType == CustomService && !isServiceDisabled(service) {
 			// If the port is not set, use the JMX port.
 			if service.Config.Port == 0 {
 				service.Config.Port = service.Config.JMXPort
@@ -724,6 +778,11 @@ func applyOverrideInPlace(
 	}
 }
 
+// isServiceDisabled returns whether the service override disables the service.
+func isServiceDisabled(service Service) bool {
+	return service.Config.Enabled != nil && !*service.Config.Enabled
+}
+
 func filterListenAddress(list []facts.ListenAddress, drop facts.ListenAddress) []facts.ListenAddress {
 	i := 0
 
@@ -748,6 +807,11 @@ func (d *Discovery) ignoreServicesAndPorts() {
 			service.MetricsIgnored = d.isInputIgnored(service)
 		}
 
+		if isServiceDisabled(service) {
+			service.CheckIgnored = true
+			service.MetricsIgnored = true
+		}
+
 		if len(service.IgnoredPorts) > 0 {
 			n := 0
 
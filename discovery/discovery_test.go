@@ -274,7 +274,7 @@ func TestDiscoverySingle(t *testing.T) {
 		state := mockState{
 			DiscoveredService: previousService,
 		}
-		disc, _ := New(&MockDiscoverer{result: []Service{c.dynamicResult}}, nil, state, mockContainerInfo{}, nil, nil, nil, facts.ContainerFilter{}.ContainerIgnored, types.MetricFormatBleemeo, nil)
+		disc, _ := New(&MockDiscoverer{result: []Service{c.dynamicResult}}, nil, state, mockContainerInfo{}, nil, nil, nil, facts.ContainerFilter{}.ContainerIgnored, types.MetricFormatBleemeo, nil, 0)
 
 		srv, err := disc.Discovery(ctx, 0)
 		if err != nil {
@@ -318,6 +318,7 @@ func Test_applyOverride(t *testing.T) { //nolint:maintidx
 	}
 
 	t0 := time.Now()
+	disabled := false
 
 	tests := []struct {
 		name string
@@ -589,6 +590,29 @@ func Test_applyOverride(t *testing.T) { //nolint:maintidx
 				},
 			},
 		},
+		{
+			name: "disabled custom service",
+			args: args{
+				discoveredServicesMap: map[NameInstance]Service{},
+				servicesOverride: []config.Service{
+					{
+						Type:    "myapplication",
+						Enabled: &disabled,
+					},
+				},
+			},
+			want: map[NameInstance]Service{
+				{Name: "myapplication"}: {
+					Name:        "myapplication",
+					ServiceType: CustomService,
+					Config: config.Service{
+						Type:    "myapplication",
+						Enabled: &disabled,
+					},
+					Active: true,
+				},
+			},
+		},
 		{
 			name: "override port from jmx port",
 			args: args{
@@ -864,7 +888,7 @@ func TestUpdateMetricsAndCheck(t *testing.T) {
 	}
 	state := mockState{}
 
-	disc, _ := New(mockDynamic, reg, state, nil, nil, nil, nil, facts.ContainerFilter{}.ContainerIgnored, types.MetricFormatBleemeo, nil)
+	disc, _ := New(mockDynamic, reg, state, nil, nil, nil, nil, facts.ContainerFilter{}.ContainerIgnored, types.MetricFormatBleemeo, nil, 0)
 	disc.containerInfo = docker
 
 	mockDynamic.result = []Service{
@@ -1101,6 +1125,17 @@ func TestValidateServices(t *testing.T) {
 			Type:          "bad_stats_protocol",
 			StatsProtocol: "bad",
 		},
+		{
+			Type:        "custom_grpc",
+			Port:        50051,
+			CheckType:   "grpc",
+			GRPCService: "my.Service",
+		},
+		{
+			Type:      "custom_grpc_no_port",
+			Address:   "10.0.0.2",
+			CheckType: "grpc",
+		},
 	}
 
 	wantWarnings := []string{
@@ -1111,6 +1146,7 @@ func TestValidateServices(t *testing.T) {
 		"invalid config value: service type \"custom-bad.name\" can not contains dot (.) or dash (-). Changed to \"custom_bad_name\"",
 		"invalid config value: service 'ssl_and_starttls' can't set both SSL and StartTLS, StartTLS will be used",
 		"invalid config value: service 'bad_stats_protocol' has an unsupported stats protocol: 'bad'",
+		"invalid config value: service 'custom_grpc_no_port' uses a grpc check but its port is not set",
 	}
 
 	wantServices := map[NameInstance]config.Service{
@@ -1180,6 +1216,14 @@ func TestValidateServices(t *testing.T) {
 			Type:          "bad_stats_protocol",
 			StatsProtocol: "",
 		},
+		{
+			Name: "custom_grpc",
+		}: {
+			Type:        "custom_grpc",
+			Port:        50051,
+			CheckType:   "grpc",
+			GRPCService: "my.Service",
+		},
 	}
 
 	gotServices, gotWarnings := validateServices(services)
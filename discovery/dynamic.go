@@ -51,6 +51,8 @@ type Option struct {
 	IsContainerIgnored func(facts.Container) bool
 	FileReader         fileReader
 	DefaultStack       string
+	// HostRootPath is the path where the host filesystem is mounted, used to reach Unix sockets.
+	HostRootPath string
 }
 
 // DynamicDiscovery implement the dynamic discovery. It will only return
@@ -66,6 +68,16 @@ type DynamicDiscovery struct {
 
 	lastDiscoveryUpdate time.Time
 	services            []Service
+	listeningPorts      []ListeningPort
+}
+
+// ListeningPort is a TCP or UDP port on which a process listens.
+type ListeningPort struct {
+	Port int
+	// Protocol is "tcp" or "udp", IPv4 and IPv6 sockets aren't distinguished.
+	Protocol string
+	// Process is the name of the listening process.
+	Process string
 }
 
 type containerInfoProvider interface {
@@ -143,6 +155,57 @@ func (dd *DynamicDiscovery) LastUpdate() time.Time {
 	return dd.lastDiscoveryUpdate
 }
 
+// ListeningPorts returns the TCP and UDP ports on which processes listened during the last discovery.
+func (dd *DynamicDiscovery) ListeningPorts() []ListeningPort {
+	dd.l.Lock()
+	defer dd.l.Unlock()
+
+	return dd.listeningPorts
+}
+
+// listeningPorts returns the deduplicated TCP and UDP listening ports from the netstat data.
+func listeningPorts(netstat map[int][]facts.ListenAddress, processes map[int]facts.Process) []ListeningPort {
+	seen := make(map[ListeningPort]bool)
+	ports := make([]ListeningPort, 0)
+
+	for pid, addresses := range netstat {
+		for _, address := range addresses {
+			protocol := strings.TrimSuffix(address.NetworkFamily, "6")
+			if protocol != "tcp" && protocol != "udp" {
+				continue
+			}
+
+			port := ListeningPort{
+				Port:     address.Port,
+				Protocol: protocol,
+				Process:  processes[pid].Name,
+			}
+
+			if seen[port] {
+				continue
+			}
+
+			seen[port] = true
+
+			ports = append(ports, port)
+		}
+	}
+
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].Port != ports[j].Port {
+			return ports[i].Port < ports[j].Port
+		}
+
+		if ports[i].Protocol != ports[j].Protocol {
+			return ports[i].Protocol < ports[j].Protocol
+		}
+
+		return ports[i].Process < ports[j].Process
+	})
+
+	return ports
+}
+
 // ProcessServiceInfo return the service & container a process belong based on its command line + pid & start time.
 func (dd *DynamicDiscovery) ProcessServiceInfo(cmdLine []string, pid int, createTime time.Time) (serviceName ServiceName, containerName string) {
 	serviceType, ok := serviceByCommand(cmdLine)
@@ -182,37 +245,39 @@ func (dd *DynamicDiscovery) ProcessServiceInfo(cmdLine []string, pid int, create
 //nolint:gochecknoglobals
 var (
 	knownProcesses = map[string]ServiceName{
-		"apache2":      ApacheService,
-		"asterisk":     AsteriskService,
-		"dovecot":      DovecotService,
-		"exim4":        EximService,
-		"exim":         EximService,
-		"freeradius":   FreeradiusService,
-		"haproxy":      HAProxyService,
-		"httpd":        ApacheService,
-		"influxd":      InfluxDBService,
-		"libvirtd":     LibvirtService,
-		"master":       PostfixService,
-		"memcached":    MemcachedService,
-		"mongod":       MongoDBService,
-		"mosquitto":    MosquittoService, //nolint:misspell
-		"mysqld":       MySQLService,
-		"named":        BindService,
-		"nats-server":  NatsService,
-		"nfsiod":       NfsService,
-		"nginx":        NginxService,
-		"ntpd":         NTPService,
-		"openvpn":      OpenVPNService,
-		"php-fpm":      PHPFPMService,
-		"postgres":     PostgreSQLService,
-		"redis-server": RedisService,
-		"slapd":        OpenLDAPService,
-		"squid3":       SquidService,
-		"squid":        SquidService,
-		"upsd":         UPSDService,
-		"uwsgi":        UWSGIService,
-		"uWSGI":        UWSGIService,
-		"varnishd":     VarnishService,
+		"apache2":           ApacheService,
+		"asterisk":          AsteriskService,
+		"clickhouse-server": ClickHouseService,
+		"dovecot":           DovecotService,
+		"exim4":             EximService,
+		"exim":              EximService,
+		"freeradius":        FreeradiusService,
+		"haproxy":           HAProxyService,
+		"httpd":             ApacheService,
+		"influxd":           InfluxDBService,
+		"libvirtd":          LibvirtService,
+		"master":            PostfixService,
+		"memcached":         MemcachedService,
+		"mongod":            MongoDBService,
+		"mosquitto":         MosquittoService, //nolint:misspell
+		"mysqld":            MySQLService,
+		"named":             BindService,
+		"nats-server":       NatsService,
+		"nfsiod":            NfsService,
+		"nginx":             NginxService,
+		"ntpd":              NTPService,
+		"openvpn":           OpenVPNService,
+		"php-fpm":           PHPFPMService,
+		"postgres":          PostgreSQLService,
+		"redis-server":      RedisService,
+		"slapd":             OpenLDAPService,
+		"squid3":            SquidService,
+		"squid":             SquidService,
+		"traefik":           TraefikService,
+		"upsd":              UPSDService,
+		"uwsgi":             UWSGIService,
+		"uWSGI":             UWSGIService,
+		"varnishd":          VarnishService,
 	}
 	knownInterpretedProcess = []struct {
 		CmdLineMustContains []string
@@ -392,12 +457,14 @@ func (dd *DynamicDiscovery) updateDiscovery(ctx context.Context, maxAge time.Dur
 	// possible for two different service to have the same listening address... which is unlikely.
 	// When a conflict occur, only kept port that are associated with the standard port of the service.
 	services := fixListenAddressConflict(servicesMap)
+	ports := listeningPorts(netstat, processes)
 
 	dd.l.Lock()
 	defer dd.l.Unlock()
 
 	dd.lastDiscoveryUpdate = time.Now()
 	dd.services = services
+	dd.listeningPorts = ports
 
 	return nil
 }
@@ -422,6 +489,11 @@ func (dd *DynamicDiscovery) serviceFromProcess(process facts.Process, netstat ma
 		Active:        true,
 	}
 
+	// Containers always have their own namespaces, only the other processes are checked.
+	if service.ContainerID == "" {
+		service.IsolatedNamespaces = isolatedNamespaces(dd.option.FileReader, process.PID)
+	}
+
 	if service.ContainerID != "" {
 		service.container, ok = dd.option.ContainerInfo.CachedContainer(service.ContainerID)
 		if !ok || dd.option.IsContainerIgnored(service.container) {
@@ -434,6 +506,11 @@ func (dd *DynamicDiscovery) serviceFromProcess(process facts.Process, netstat ma
 	dd.updateListenAddresses(&service, di)
 
 	dd.fillConfig(&service)
+
+	if service.ServiceType == PHPFPMService && service.container == nil {
+		service.phpfpmStatusURLs = dd.phpfpmStatusURLs(process.CmdLineList)
+	}
+
 	dd.fillConfigFromLabels(&service)
 	dd.discoveryFromLabels(&service)
 	dd.guessJMX(&service, process.CmdLineList)
@@ -488,6 +565,13 @@ func (dd *DynamicDiscovery) updateListenAddresses(service *Service, di discovery
 
 	newListenAddresses := service.ListenAddresses[:0]
 
+	// Services like DNS servers may only listen on UDP for their main port even if
+	// they also support TCP. The UDP address is used when no TCP address matched.
+	var (
+		protocolMatched bool
+		udpAddress      string
+	)
+
 	for _, a := range service.ListenAddresses {
 		if a.Network() == "unix" {
 			newListenAddresses = append(newListenAddresses, a)
@@ -512,8 +596,16 @@ func (dd *DynamicDiscovery) updateListenAddresses(service *Service, di discovery
 			continue
 		}
 
-		if int(port) == di.ServicePort && a.Network() == di.ServiceProtocol && address != net.IPv4zero.String() {
-			defaultAddress = address
+		if int(port) == di.ServicePort && a.Network() == di.ServiceProtocol {
+			protocolMatched = true
+
+			if address != net.IPv4zero.String() {
+				defaultAddress = address
+			}
+		}
+
+		if int(port) == di.ServicePort && a.Network() == "udp" && address != net.IPv4zero.String() {
+			udpAddress = address
 		}
 
 		if !di.IgnoreHighPort || port <= 32000 {
@@ -521,6 +613,10 @@ func (dd *DynamicDiscovery) updateListenAddresses(service *Service, di discovery
 		}
 	}
 
+	if !protocolMatched && udpAddress != "" {
+		defaultAddress = udpAddress
+	}
+
 	service.ListenAddresses = newListenAddresses
 	service.IPAddress = defaultAddress
 
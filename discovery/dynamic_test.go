@@ -104,6 +104,14 @@ func TestServiceByCommand(t *testing.T) {
 			in:   []string{"/usr/bin/memcached", "-m", "64", "-p", "11211", "-u", "memcache", "-l", "127.0.0.1", "-P", "/var/run/memcached/memcached.pid"},
 			want: MemcachedService,
 		},
+		{
+			in:   []string{"/usr/bin/clickhouse-server", "--config-file=/etc/clickhouse-server/config.xml"},
+			want: ClickHouseService,
+		},
+		{
+			in:   []string{"traefik", "--entrypoints.web.address=:80", "--metrics.prometheus=true"},
+			want: TraefikService,
+		},
 	}
 
 	for i, c := range cases {
@@ -255,6 +263,54 @@ func TestDynamicDiscoverySingle(t *testing.T) { //nolint:maintidx
 				LastNetstatInfo: t0,
 			},
 		},
+		{
+			testName:         "ntp-bind-specific-udp",
+			cmdLine:          []string{"/usr/sbin/ntpd", "-p", "/var/run/ntpd.pid", "-g", "-u", "107:114"},
+			containerID:      "",
+			netstatAddresses: []facts.ListenAddress{{NetworkFamily: "udp", Address: "192.168.1.1", Port: 123}},
+			want: Service{
+				Name:            "ntp",
+				ServiceType:     NTPService,
+				ContainerID:     "",
+				ListenAddresses: []facts.ListenAddress{{NetworkFamily: "udp", Address: "192.168.1.1", Port: 123}},
+				IPAddress:       "192.168.1.1",
+				Active:          true,
+				HasNetstatInfo:  true,
+				LastNetstatInfo: t0,
+			},
+		},
+		{
+			testName:         "bind-udp-only",
+			cmdLine:          []string{"/usr/sbin/named", "-u", "bind"},
+			containerID:      "",
+			netstatAddresses: []facts.ListenAddress{{NetworkFamily: "udp", Address: "192.168.1.1", Port: 53}},
+			want: Service{
+				Name:            "bind",
+				ServiceType:     BindService,
+				ContainerID:     "",
+				ListenAddresses: []facts.ListenAddress{{NetworkFamily: "udp", Address: "192.168.1.1", Port: 53}},
+				IPAddress:       "192.168.1.1",
+				Active:          true,
+				HasNetstatInfo:  true,
+				LastNetstatInfo: t0,
+			},
+		},
+		{
+			testName:         "bind-tcp-and-udp",
+			cmdLine:          []string{"/usr/sbin/named", "-u", "bind"},
+			containerID:      "",
+			netstatAddresses: []facts.ListenAddress{{NetworkFamily: "tcp", Address: "0.0.0.0", Port: 53}, {NetworkFamily: "udp", Address: "192.168.1.1", Port: 53}},
+			want: Service{
+				Name:            "bind",
+				ServiceType:     BindService,
+				ContainerID:     "",
+				ListenAddresses: []facts.ListenAddress{{NetworkFamily: "tcp", Address: "0.0.0.0", Port: 53}, {NetworkFamily: "udp", Address: "192.168.1.1", Port: 53}},
+				IPAddress:       "127.0.0.1",
+				Active:          true,
+				HasNetstatInfo:  true,
+				LastNetstatInfo: t0,
+			},
+		},
 		{
 			testName:           "redis-container",
 			cmdLine:            []string{"redis-server *:6379"},
@@ -903,6 +959,26 @@ func TestDynamicDiscoverySingle(t *testing.T) { //nolint:maintidx
 				LastNetstatInfo: t0,
 			},
 		},
+		{
+			testName: "clickhouse",
+			cmdLine:  []string{"/usr/bin/clickhouse-server", "--config-file=/etc/clickhouse-server/config.xml", "--pid-file=/run/clickhouse-server/clickhouse-server.pid"},
+			netstatAddresses: []facts.ListenAddress{
+				{NetworkFamily: "tcp", Address: "0.0.0.0", Port: 8123},
+				{NetworkFamily: "tcp", Address: "0.0.0.0", Port: 9000},
+			},
+			want: Service{
+				Name:        "clickhouse",
+				ServiceType: ClickHouseService,
+				ListenAddresses: []facts.ListenAddress{
+					{NetworkFamily: "tcp", Address: "0.0.0.0", Port: 8123},
+					{NetworkFamily: "tcp", Address: "0.0.0.0", Port: 9000},
+				},
+				IPAddress:       "127.0.0.1",
+				Active:          true,
+				HasNetstatInfo:  true,
+				LastNetstatInfo: t0,
+			},
+		},
 		{
 			testName: "fail2ban",
 			cmdLine:  []string{"/usr/bin/python3", "/usr/bin/fail2ban-server", "-xf", "start"},
@@ -1928,6 +2004,42 @@ func TestDynamicDiscovery(t *testing.T) { //nolint:maintidx
 	}
 }
 
+func TestListeningPorts(t *testing.T) {
+	t.Parallel()
+
+	processes := map[int]facts.Process{
+		1:   {PID: 1, Name: "sshd"},
+		42:  {PID: 42, Name: "nginx"},
+		100: {PID: 100, Name: "named"},
+	}
+
+	netstat := map[int][]facts.ListenAddress{
+		1: {
+			{NetworkFamily: "tcp", Address: "0.0.0.0", Port: 22},
+			{NetworkFamily: "tcp6", Address: "::", Port: 22},
+		},
+		42: {
+			{NetworkFamily: "tcp", Address: "0.0.0.0", Port: 80},
+			{NetworkFamily: "unix", Address: "/run/nginx.sock"},
+		},
+		100: {
+			{NetworkFamily: "udp", Address: "127.0.0.1", Port: 53},
+			{NetworkFamily: "tcp", Address: "127.0.0.1", Port: 53},
+		},
+	}
+
+	want := []ListeningPort{
+		{Port: 22, Protocol: "tcp", Process: "sshd"},
+		{Port: 53, Protocol: "tcp", Process: "named"},
+		{Port: 53, Protocol: "udp", Process: "named"},
+		{Port: 80, Protocol: "tcp", Process: "nginx"},
+	}
+
+	if diff := cmp.Diff(want, listeningPorts(netstat, processes)); diff != "" {
+		t.Errorf("listeningPorts() mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func Test_fillGenericExtraAttributes(t *testing.T) {
 	cases := []struct {
 		name                        string
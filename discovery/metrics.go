@@ -29,6 +29,8 @@ import (
 	"github.com/bleemeo/glouton/facts/container-runtime/veth"
 	"github.com/bleemeo/glouton/inputs"
 	"github.com/bleemeo/glouton/inputs/apache"
+	"github.com/bleemeo/glouton/inputs/bind"
+	"github.com/bleemeo/glouton/inputs/clickhouse"
 	"github.com/bleemeo/glouton/inputs/cpu"
 	"github.com/bleemeo/glouton/inputs/disk"
 	"github.com/bleemeo/glouton/inputs/diskio"
@@ -45,10 +47,12 @@ import (
 	"github.com/bleemeo/glouton/inputs/nfs"
 	"github.com/bleemeo/glouton/inputs/nginx"
 	"github.com/bleemeo/glouton/inputs/openldap"
+	"github.com/bleemeo/glouton/inputs/openvpn"
 	"github.com/bleemeo/glouton/inputs/phpfpm"
 	"github.com/bleemeo/glouton/inputs/postgresql"
 	"github.com/bleemeo/glouton/inputs/rabbitmq"
 	"github.com/bleemeo/glouton/inputs/redis"
+	"github.com/bleemeo/glouton/inputs/squid"
 	"github.com/bleemeo/glouton/inputs/swap"
 	"github.com/bleemeo/glouton/inputs/system"
 	"github.com/bleemeo/glouton/inputs/upsd"
@@ -322,6 +326,25 @@ func (d *Discovery) createInput(service Service) error { //nolint:maintidx
 
 			input, err = apache.New(statusURL)
 		}
+	case BindService:
+		if service.Config.StatsURL != "" {
+			input, gathererOptions, err = bind.New(service.Config.StatsURL)
+		} else {
+			// The default port of the statistics channel is 8053.
+			port := 8053
+
+			if service.Config.StatsPort != 0 {
+				port = service.Config.StatsPort
+			}
+
+			if ip := service.AddressForPort(port, "tcp", true); ip != "" {
+				input, gathererOptions, err = bind.New("http://" + net.JoinHostPort(ip, strconv.Itoa(port)))
+			}
+		}
+	case ClickHouseService:
+		if address := clickHouseHTTPAddress(service); address != "" {
+			input, gathererOptions, err = clickhouse.New(address, service.Config)
+		}
 	case ElasticSearchService:
 		if ip, port := service.AddressPort(); ip != "" {
 			input, err = elasticsearch.New("http://" + net.JoinHostPort(ip, strconv.Itoa(port)))
@@ -368,10 +391,20 @@ func (d *Discovery) createInput(service Service) error { //nolint:maintidx
 		if ip, port := service.AddressPort(); ip != "" {
 			input, gathererOptions, err = openldap.New(ip, port, service.Config)
 		}
+	case OpenVPNService:
+		// The status file or the management interface must be configured.
+		if service.Config.StatusFile != "" || service.Config.StatsURL != "" {
+			input, gathererOptions, err = openvpn.New(
+				service.Config.StatusFile,
+				service.Config.StatsURL,
+				service.Config.Password,
+				service.Config.IncludedItems,
+			)
+		}
 	case PHPFPMService:
-		statsURL := urlForPHPFPM(service)
-		if statsURL != "" {
-			input, err = phpfpm.New(statsURL)
+		statsURLs := urlsForPHPFPM(service)
+		if len(statsURLs) > 0 {
+			input, err = phpfpm.New(statsURLs)
 		}
 	case PostgreSQLService:
 		if ip, port := service.AddressPort(); ip != "" && service.Config.Password != "" {
@@ -410,12 +443,18 @@ func (d *Discovery) createInput(service Service) error { //nolint:maintidx
 			input, err = rabbitmq.New(url, username, password)
 		}
 	case RedisService:
+		if socket := service.Config.MetricsUnixSocket; socket != "" {
+			input, err = redis.New("unix://"+socket, service.Config.Username, service.Config.Password)
+		} else if ip, port := service.AddressPort(); ip != "" {
+			input, err = redis.New("tcp://"+net.JoinHostPort(ip, strconv.Itoa(port)), service.Config.Username, service.Config.Password)
+		}
+	case SquidService:
 		if ip, port := service.AddressPort(); ip != "" {
-			input, err = redis.New("tcp://"+net.JoinHostPort(ip, strconv.Itoa(port)), service.Config.Password)
+			input, gathererOptions, err = squid.New(net.JoinHostPort(ip, strconv.Itoa(port)), service.Config)
 		}
 	case UPSDService:
 		if ip, port := service.AddressPort(); ip != "" {
-			input, gathererOptions, err = upsd.New(ip, port, service.Config.Username, service.Config.Password)
+			input, gathererOptions, err = upsd.New(ip, port, service.Config.Username, service.Config.Password, service.Config.IncludedItems)
 		}
 	case UWSGIService:
 		// The port used in the stats server documentation is 1717.
@@ -520,14 +559,20 @@ func (d *Discovery) registerInput(input telegraf.Input, opts registry.Registrati
 	return err
 }
 
-func urlForPHPFPM(service Service) string {
+// urlsForPHPFPM returns the status URLs of the PHP-FPM pools. The stats_url from the
+// configuration is preferred, then the pools found in the PHP-FPM configuration.
+func urlsForPHPFPM(service Service) []string {
 	url := service.Config.StatsURL
 	if url != "" {
-		return url
+		return []string{url}
 	}
 
 	if service.Config.Port != 0 && service.IPAddress != "" {
-		return fmt.Sprintf("fcgi://%s/status", net.JoinHostPort(service.IPAddress, strconv.Itoa(service.Config.Port)))
+		return []string{fmt.Sprintf("fcgi://%s/status", net.JoinHostPort(service.IPAddress, strconv.Itoa(service.Config.Port)))}
+	}
+
+	if len(service.phpfpmStatusURLs) > 0 {
+		return service.phpfpmStatusURLs
 	}
 
 	for _, v := range service.ListenAddresses {
@@ -535,10 +580,10 @@ func urlForPHPFPM(service Service) string {
 			continue
 		}
 
-		return fmt.Sprintf("fcgi://%s/status", v.String())
+		return []string{fmt.Sprintf("fcgi://%s/status", v.String())}
 	}
 
-	return ""
+	return nil
 }
 
 func getMySQLSocket(service Service) string {
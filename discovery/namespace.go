@@ -0,0 +1,70 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"path/filepath"
+	"strconv"
+)
+
+// hostNamespacePID is the process whose namespaces are the host namespaces.
+const hostNamespacePID = 1
+
+// checkedNamespaces are the namespaces compared with the host ones. A process
+// in a different mount or PID namespace runs in a chroot-like sandbox.
+//
+//nolint:gochecknoglobals
+var checkedNamespaces = []string{"mnt", "pid"}
+
+// linkReader is implemented by file readers able to read symbolic links.
+type linkReader interface {
+	Readlink(path string) (string, error)
+}
+
+// isolatedNamespaces returns the namespaces of the process which differ from the host
+// namespaces, as read from /proc/<pid>/ns/* inside the host root.
+// It returns nil when the namespaces can't be read, e.g. because of missing permission.
+func isolatedNamespaces(reader fileReader, pid int) []string {
+	linker, ok := reader.(linkReader)
+	if !ok || pid == hostNamespacePID {
+		return nil
+	}
+
+	var namespaces []string
+
+	for _, ns := range checkedNamespaces {
+		hostNS, err := linker.Readlink(namespacePath(hostNamespacePID, ns))
+		if err != nil {
+			return nil
+		}
+
+		processNS, err := linker.Readlink(namespacePath(pid, ns))
+		if err != nil {
+			return nil
+		}
+
+		if processNS != hostNS {
+			namespaces = append(namespaces, ns)
+		}
+	}
+
+	return namespaces
+}
+
+func namespacePath(pid int, ns string) string {
+	return filepath.Join("/proc", strconv.Itoa(pid), "ns", ns)
+}
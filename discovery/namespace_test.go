@@ -0,0 +1,106 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type mockLinkReader struct {
+	mockFileReader
+
+	links map[string]string
+}
+
+func (m mockLinkReader) Readlink(path string) (string, error) {
+	link, ok := m.links[path]
+	if !ok {
+		return "", os.ErrNotExist
+	}
+
+	return link, nil
+}
+
+func TestIsolatedNamespaces(t *testing.T) {
+	t.Parallel()
+
+	reader := mockLinkReader{
+		links: map[string]string{
+			"/proc/1/ns/mnt":  "mnt:[4026531841]",
+			"/proc/1/ns/pid":  "pid:[4026531836]",
+			"/proc/42/ns/mnt": "mnt:[4026531841]",
+			"/proc/42/ns/pid": "pid:[4026531836]",
+			"/proc/43/ns/mnt": "mnt:[4026532600]",
+			"/proc/43/ns/pid": "pid:[4026531836]",
+			"/proc/44/ns/mnt": "mnt:[4026532700]",
+			"/proc/44/ns/pid": "pid:[4026532701]",
+			"/proc/45/ns/mnt": "mnt:[4026532800]",
+		},
+	}
+
+	tests := []struct {
+		name   string
+		reader fileReader
+		pid    int
+		want   []string
+	}{
+		{
+			name:   "host",
+			reader: reader,
+			pid:    42,
+			want:   nil,
+		},
+		{
+			name:   "mount-namespace",
+			reader: reader,
+			pid:    43,
+			want:   []string{"mnt"},
+		},
+		{
+			name:   "mount-and-pid-namespace",
+			reader: reader,
+			pid:    44,
+			want:   []string{"mnt", "pid"},
+		},
+		{
+			name:   "unreadable",
+			reader: reader,
+			pid:    45,
+			want:   nil,
+		},
+		{
+			name:   "no-link-reader",
+			reader: mockFileReader{},
+			pid:    43,
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := isolatedNamespaces(tt.reader, tt.pid)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("isolatedNamespaces() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bleemeo/glouton/logger"
+
+	"gopkg.in/ini.v1"
+)
+
+// phpfpmDefaultConfigPaths are the usual locations of the PHP-FPM main configuration,
+// used when the configuration isn't visible in the master process command line.
+//
+//nolint:gochecknoglobals
+var phpfpmDefaultConfigPaths = []string{
+	"/etc/php-fpm.conf",
+	"/etc/php/*/fpm/php-fpm.conf",
+	"/usr/local/etc/php-fpm.conf",
+}
+
+// phpfpmMasterRegexp matches the process title of the PHP-FPM master, which contains the configuration path.
+var phpfpmMasterRegexp = regexp.MustCompile(`master process \(([^)]+)\)`)
+
+// fileGlobber is implemented by file readers able to list files matching a pattern.
+type fileGlobber interface {
+	Glob(pattern string) ([]string, error)
+}
+
+// phpfpmPool is a PHP-FPM pool read from the configuration.
+type phpfpmPool struct {
+	name       string
+	listen     string
+	statusPath string
+}
+
+// phpfpmStatusURLs returns the status URL of each pool which has the status page enabled.
+// The URLs use the format expected by the Telegraf PHP-FPM input.
+func (dd *DynamicDiscovery) phpfpmStatusURLs(cmdLine []string) []string {
+	reader := dd.option.FileReader
+	if reader == nil {
+		return nil
+	}
+
+	var configPaths []string
+
+	if match := phpfpmMasterRegexp.FindStringSubmatch(strings.Join(cmdLine, " ")); match != nil {
+		configPaths = []string{match[1]}
+	} else {
+		configPaths = expandGlobs(reader, phpfpmDefaultConfigPaths)
+	}
+
+	var urls []string
+
+	for _, path := range configPaths {
+		pools, err := readPHPFPMConfig(reader, path)
+		if err != nil {
+			logger.V(2).Printf("Unable to read PHP-FPM config %s: %v", path, err)
+
+			continue
+		}
+
+		for _, pool := range pools {
+			if url := pool.statusURL(dd.option.HostRootPath); url != "" {
+				urls = append(urls, url)
+			}
+		}
+
+		if len(pools) > 0 {
+			break
+		}
+	}
+
+	return urls
+}
+
+// readPHPFPMConfig returns the pools defined in the main configuration and its included files.
+func readPHPFPMConfig(reader fileReader, path string) ([]phpfpmPool, error) {
+	content, err := reader.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := ini.Load(content)
+	if err != nil {
+		return nil, err
+	}
+
+	pools := phpfpmPoolsFromConfig(cfg)
+
+	include := iniSafeString(cfg.Section("global").Key("include"))
+	if include == "" {
+		return pools, nil
+	}
+
+	// Relative includes are relative to the PHP-FPM prefix, which is usually
+	// the directory of the main configuration.
+	if !filepath.IsAbs(include) {
+		include = filepath.Join(filepath.Dir(path), include)
+	}
+
+	for _, includedPath := range expandGlobs(reader, []string{include}) {
+		content, err := reader.ReadFile(includedPath)
+		if err != nil {
+			logger.V(2).Printf("Unable to read PHP-FPM config %s: %v", includedPath, err)
+
+			continue
+		}
+
+		cfg, err := ini.Load(content)
+		if err != nil {
+			logger.V(2).Printf("Unable to parse PHP-FPM config %s: %v", includedPath, err)
+
+			continue
+		}
+
+		pools = append(pools, phpfpmPoolsFromConfig(cfg)...)
+	}
+
+	return pools, nil
+}
+
+func phpfpmPoolsFromConfig(cfg *ini.File) []phpfpmPool {
+	var pools []phpfpmPool
+
+	for _, section := range cfg.Sections() {
+		name := section.Name()
+		if name == ini.DefaultSection || name == "global" {
+			continue
+		}
+
+		pools = append(pools, phpfpmPool{
+			name:       name,
+			listen:     strings.ReplaceAll(iniSafeString(section.Key("listen")), "$pool", name),
+			statusPath: iniSafeString(section.Key("pm.status_path")),
+		})
+	}
+
+	return pools
+}
+
+// statusURL returns the URL of the status page of the pool, or an empty string
+// if the status page isn't enabled.
+func (p phpfpmPool) statusURL(hostRootPath string) string {
+	if p.statusPath == "" || p.listen == "" {
+		return ""
+	}
+
+	if strings.HasPrefix(p.listen, "/") {
+		// The Telegraf input expects "socket:path" for Unix sockets.
+		return fmt.Sprintf("%s:%s", filepath.Join(hostRootPath, p.listen), strings.TrimPrefix(p.statusPath, "/"))
+	}
+
+	host, port, err := net.SplitHostPort(p.listen)
+	if err != nil {
+		// Only a port is given, PHP-FPM listens on all addresses.
+		host, port = "", p.listen
+	}
+
+	if host == "" || host == "*" || host == net.IPv4zero.String() || host == net.IPv6zero.String() {
+		host = localhostIP
+	}
+
+	return fmt.Sprintf("fcgi://%s/%s", net.JoinHostPort(host, port), strings.TrimPrefix(p.statusPath, "/"))
+}
+
+// expandGlobs returns the files matching the patterns. Patterns are returned
+// unchanged when the reader can't list files.
+func expandGlobs(reader fileReader, patterns []string) []string {
+	globber, ok := reader.(fileGlobber)
+	if !ok {
+		return patterns
+	}
+
+	var paths []string
+
+	for _, pattern := range patterns {
+		matches, err := globber.Glob(pattern)
+		if err != nil {
+			continue
+		}
+
+		sort.Strings(matches)
+
+		paths = append(paths, matches...)
+	}
+
+	return paths
+}
@@ -0,0 +1,87 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPHPFPMStatusURLs(t *testing.T) {
+	t.Parallel()
+
+	dd := NewDynamic(Option{
+		HostRootPath: "/hostroot",
+		FileReader: mockFileReader{
+			contents: map[string]string{
+				"/etc/php/8.1/fpm/php-fpm.conf": `
+[global]
+pid = /run/php/php8.1-fpm.pid
+include=/etc/php/8.1/fpm/pool.d/www.conf
+
+[admin]
+listen = 127.0.0.1:9001
+pm.status_path = /fpm-status
+`,
+				"/etc/php/8.1/fpm/pool.d/www.conf": `
+[www]
+user = www-data
+listen = /run/php/$pool.sock
+pm.status_path = /status
+
+[nostatus]
+listen = 9002
+`,
+			},
+		},
+	})
+
+	got := dd.phpfpmStatusURLs([]string{"php-fpm: master process (/etc/php/8.1/fpm/php-fpm.conf)"})
+	want := []string{
+		"fcgi://127.0.0.1:9001/fpm-status",
+		"/hostroot/run/php/www.sock:status",
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("phpfpmStatusURLs() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPHPFPMPoolStatusURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		listen string
+		want   string
+	}{
+		{listen: "9000", want: "fcgi://127.0.0.1:9000/status"},
+		{listen: "0.0.0.0:9000", want: "fcgi://127.0.0.1:9000/status"},
+		{listen: "[::]:9000", want: "fcgi://127.0.0.1:9000/status"},
+		{listen: "192.168.1.2:9000", want: "fcgi://192.168.1.2:9000/status"},
+		{listen: "/run/php-fpm.sock", want: "/run/php-fpm.sock:status"},
+		{listen: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		pool := phpfpmPool{name: "www", listen: tt.listen, statusPath: "/status"}
+
+		if got := pool.statusURL(""); got != tt.want {
+			t.Errorf("statusURL() with listen %q = %q, want %q", tt.listen, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promexporter
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bleemeo/glouton/facts"
+	"github.com/bleemeo/glouton/logger"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	// envoyAdminPortLabel is the container label (or annotation) used to
+	// explicitly enable the scrape of the Envoy admin interface.
+	envoyAdminPortLabel   = "glouton.envoy_admin_port"
+	envoyDefaultAdminPort = 9901
+	envoyStatsPath        = "/stats/prometheus"
+)
+
+// envoyImagePrefixes are the image names for which the Envoy admin interface
+// is scraped without any explicit configuration.
+var envoyImagePrefixes = []string{ //nolint:gochecknoglobals
+	"envoyproxy/envoy",
+	"docker.io/envoyproxy/envoy",
+}
+
+// envoyAllowedPrefixes are the metric families kept from Envoy. Envoy exposes
+// thousands of stats, only the most useful ones are kept.
+var envoyAllowedPrefixes = []string{ //nolint:gochecknoglobals
+	"envoy_server_",
+	"envoy_cluster_upstream_cx_",
+	"envoy_cluster_upstream_rq_",
+	"envoy_cluster_membership_",
+	"envoy_http_downstream_cx_",
+	"envoy_http_downstream_rq_",
+	"envoy_listener_downstream_cx_",
+}
+
+// envoyURLFromContainer returns the URL of the Envoy admin stats endpoint of
+// the container, or an empty string if the container doesn't run Envoy.
+func envoyURLFromContainer(c facts.Container) string {
+	portStr := c.Labels()[envoyAdminPortLabel]
+	if portStr == "" {
+		portStr = c.Annotations()[envoyAdminPortLabel]
+	}
+
+	if portStr == "" && isEnvoyImage(c.ImageName()) {
+		portStr = strconv.FormatInt(envoyDefaultAdminPort, 10)
+	}
+
+	if portStr == "" {
+		return ""
+	}
+
+	port, err := strconv.ParseInt(portStr, 10, 0)
+	if err != nil {
+		return ""
+	}
+
+	// usedonly avoids sending stats that were never updated, which are most of Envoy stats.
+	return fmt.Sprintf("http://%s%s?usedonly", net.JoinHostPort(c.PrimaryAddress(), strconv.FormatInt(port, 10)), envoyStatsPath)
+}
+
+func isEnvoyImage(imageName string) bool {
+	for _, prefix := range envoyImagePrefixes {
+		if imageName == prefix || strings.HasPrefix(imageName, prefix+":") || strings.HasPrefix(imageName, prefix+"@") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// envoyGatherModifier returns a gather modifier which bounds the cardinality of
+// Envoy metrics. Only a subset of the families are kept, histograms are dropped
+// and the number of series per family is limited to maxSeriesPerFamily.
+// The series aren't limited when maxSeriesPerFamily is zero or negative.
+func envoyGatherModifier(maxSeriesPerFamily int) func(mfs []*dto.MetricFamily, _ error) []*dto.MetricFamily {
+	return func(mfs []*dto.MetricFamily, _ error) []*dto.MetricFamily {
+		result := mfs[:0]
+
+		for _, mf := range mfs {
+			if mf.GetType() == dto.MetricType_HISTOGRAM || !isEnvoyMetricAllowed(mf.GetName()) {
+				continue
+			}
+
+			if maxSeriesPerFamily > 0 && len(mf.GetMetric()) > maxSeriesPerFamily {
+				logger.V(1).Printf(
+					"Envoy metric %s has %d series, only the first %d are kept",
+					mf.GetName(), len(mf.GetMetric()), maxSeriesPerFamily,
+				)
+
+				// Sort the series so the same ones are kept on each scrape.
+				sortMetricsByLabels(mf.GetMetric())
+
+				mf.Metric = mf.GetMetric()[:maxSeriesPerFamily]
+			}
+
+			result = append(result, mf)
+		}
+
+		return result
+	}
+}
+
+// sortMetricsByLabels sorts the metrics of a family by their label values.
+func sortMetricsByLabels(metrics []*dto.Metric) {
+	keys := make(map[*dto.Metric]string, len(metrics))
+
+	for _, m := range metrics {
+		var key strings.Builder
+
+		for _, lbl := range m.GetLabel() {
+			key.WriteString(lbl.GetName())
+			key.WriteByte('=')
+			key.WriteString(lbl.GetValue())
+			key.WriteByte(',')
+		}
+
+		keys[m] = key.String()
+	}
+
+	sort.SliceStable(metrics, func(i, j int) bool {
+		return keys[metrics[i]] < keys[metrics[j]]
+	})
+}
+
+func isEnvoyMetricAllowed(name string) bool {
+	for _, prefix := range envoyAllowedPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
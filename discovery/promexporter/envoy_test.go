@@ -0,0 +1,228 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promexporter
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bleemeo/glouton/facts"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestEnvoyURLFromContainer(t *testing.T) {
+	tests := []struct {
+		name      string
+		container facts.Container
+		want      string
+	}{
+		{
+			name: "not-envoy",
+			container: facts.FakeContainer{
+				FakePrimaryAddress: "sample",
+				FakeImageName:      "nginx:latest",
+			},
+			want: "",
+		},
+		{
+			name: "envoy-image",
+			container: facts.FakeContainer{
+				FakePrimaryAddress: "sample",
+				FakeImageName:      "envoyproxy/envoy:v1.30-latest",
+			},
+			want: "http://sample:9901/stats/prometheus?usedonly",
+		},
+		{
+			name: "envoy-like-image",
+			container: facts.FakeContainer{
+				FakePrimaryAddress: "sample",
+				FakeImageName:      "envoyproxy/envoy-tools:latest",
+			},
+			want: "",
+		},
+		{
+			name: "label",
+			container: facts.FakeContainer{
+				FakePrimaryAddress: "sample",
+				FakeImageName:      "my-sidecar:latest",
+				FakeLabels: map[string]string{
+					envoyAdminPortLabel: "15000",
+				},
+			},
+			want: "http://sample:15000/stats/prometheus?usedonly",
+		},
+		{
+			name: "annotation",
+			container: facts.FakeContainer{
+				FakePrimaryAddress: "10.0.0.1",
+				FakeAnnotations: map[string]string{
+					envoyAdminPortLabel: "15000",
+				},
+			},
+			want: "http://10.0.0.1:15000/stats/prometheus?usedonly",
+		},
+		{
+			name: "invalid-port",
+			container: facts.FakeContainer{
+				FakePrimaryAddress: "sample",
+				FakeLabels: map[string]string{
+					envoyAdminPortLabel: "admin",
+				},
+			},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := envoyURLFromContainer(tt.container); got != tt.want {
+				t.Errorf("envoyURLFromContainer() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvoyGatherModifier(t *testing.T) {
+	t.Parallel()
+
+	const maxSeries = 100
+
+	manySeries := make([]*dto.Metric, 0, maxSeries+10)
+
+	// The series are added in the reverse order to check that they are sorted before being truncated.
+	for i := maxSeries + 9; i >= 0; i-- {
+		manySeries = append(manySeries, &dto.Metric{
+			Label: []*dto.LabelPair{
+				{Name: proto.String("envoy_cluster_name"), Value: proto.String(fmt.Sprintf("cluster-%03d", i))},
+			},
+			Counter: &dto.Counter{Value: proto.Float64(1)},
+		})
+	}
+
+	mfs := []*dto.MetricFamily{
+		{
+			Name:   proto.String("envoy_server_uptime"),
+			Type:   dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{{Gauge: &dto.Gauge{Value: proto.Float64(42)}}},
+		},
+		{
+			Name:   proto.String("envoy_cluster_upstream_rq_total"),
+			Type:   dto.MetricType_COUNTER.Enum(),
+			Metric: manySeries,
+		},
+		{
+			Name:   proto.String("envoy_cluster_upstream_rq_time"),
+			Type:   dto.MetricType_HISTOGRAM.Enum(),
+			Metric: []*dto.Metric{{Histogram: &dto.Histogram{SampleCount: proto.Uint64(1)}}},
+		},
+		{
+			Name:   proto.String("envoy_runtime_load_success"),
+			Type:   dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{{Counter: &dto.Counter{Value: proto.Float64(1)}}},
+		},
+	}
+
+	got := envoyGatherModifier(maxSeries)(mfs, nil)
+
+	if len(got) != 2 {
+		t.Fatalf("len(got) = %d, want 2", len(got))
+	}
+
+	if got[0].GetName() != "envoy_server_uptime" || got[1].GetName() != "envoy_cluster_upstream_rq_total" {
+		t.Errorf("got families %s and %s", got[0].GetName(), got[1].GetName())
+	}
+
+	if len(got[1].GetMetric()) != maxSeries {
+		t.Fatalf("len(metrics) = %d, want %d", len(got[1].GetMetric()), maxSeries)
+	}
+
+	for i, m := range got[1].GetMetric() {
+		want := fmt.Sprintf("cluster-%03d", i)
+		if value := m.GetLabel()[0].GetValue(); value != want {
+			t.Errorf("metrics[%d] has cluster %s, want %s", i, value, want)
+		}
+	}
+}
+
+func TestEnvoyGatherModifierUnlimited(t *testing.T) {
+	t.Parallel()
+
+	metrics := make([]*dto.Metric, 0, 150)
+
+	for range 150 {
+		metrics = append(metrics, &dto.Metric{Counter: &dto.Counter{Value: proto.Float64(1)}})
+	}
+
+	mfs := []*dto.MetricFamily{
+		{
+			Name:   proto.String("envoy_cluster_upstream_rq_total"),
+			Type:   dto.MetricType_COUNTER.Enum(),
+			Metric: metrics,
+		},
+	}
+
+	got := envoyGatherModifier(0)(mfs, nil)
+
+	if len(got) != 1 || len(got[0].GetMetric()) != 150 {
+		t.Errorf("series were dropped without limit: %v", got)
+	}
+}
+
+// TestListExportersEnvoy checks that only the targets discovered as Envoy
+// are handled as Envoy, even if another exporter uses the same path.
+func TestListExportersEnvoy(t *testing.T) {
+	t.Parallel()
+
+	containers := []facts.Container{
+		facts.FakeContainer{
+			FakeContainerName:  "envoy",
+			FakePrimaryAddress: "10.0.0.1",
+			FakeImageName:      "envoyproxy/envoy:v1.30-latest",
+		},
+		facts.FakeContainer{
+			FakeContainerName:  "app",
+			FakePrimaryAddress: "10.0.0.2",
+			FakeLabels: map[string]string{
+				"prometheus.io/scrape": "true",
+				"prometheus.io/path":   envoyStatsPath,
+				"prometheus.io/port":   "8080",
+			},
+		},
+	}
+
+	d := DynamicScrapper{DynamicJobName: "jobname"}
+
+	targets, envoyURLs := d.listExporters(containers)
+	if len(targets) != 2 {
+		t.Fatalf("len(targets) = %d, want 2", len(targets))
+	}
+
+	want := map[string]bool{"http://10.0.0.1:9901/stats/prometheus?usedonly": true}
+	if len(envoyURLs) != len(want) {
+		t.Errorf("envoyURLs = %v, want %v", envoyURLs, want)
+	}
+
+	for u := range want {
+		if !envoyURLs[u] {
+			t.Errorf("envoyURLs = %v, want %v", envoyURLs, want)
+		}
+	}
+}
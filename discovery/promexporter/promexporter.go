@@ -27,6 +27,7 @@ import (
 	"sync"
 
 	"github.com/bleemeo/glouton/config"
+	"github.com/bleemeo/glouton/discovery"
 	"github.com/bleemeo/glouton/facts"
 	"github.com/bleemeo/glouton/logger"
 	"github.com/bleemeo/glouton/prometheus/registry"
@@ -39,16 +40,24 @@ import (
 const defaultInterval = 0
 
 // listExporters return list of exporters based on containers labels/annotations.
-func (d *DynamicScrapper) listExporters(containers []facts.Container) []*scrapper.Target {
+// It also returns the URLs of the targets discovered as Envoy admin interfaces.
+func (d *DynamicScrapper) listExporters(containers []facts.Container) ([]*scrapper.Target, map[string]bool) {
 	result := make([]*scrapper.Target, 0)
+	envoyURLs := make(map[string]bool)
 
 	for _, c := range containers {
+		isEnvoy := false
 		u := urlFromLabels(c.Labels(), c.PrimaryAddress())
 
 		if u == "" {
 			u = urlFromLabels(c.Annotations(), c.PrimaryAddress())
 		}
 
+		if u == "" {
+			u = envoyURLFromContainer(c)
+			isEnvoy = u != ""
+		}
+
 		if u == "" {
 			continue
 		}
@@ -83,9 +92,13 @@ func (d *DynamicScrapper) listExporters(containers []facts.Container) []*scrappe
 			ContainerLabels: cLabelsAnnotations,
 		}
 		result = append(result, target)
+
+		if isEnvoy {
+			envoyURLs[tmp.String()] = true
+		}
 	}
 
-	return result
+	return result, envoyURLs
 }
 
 func urlFromLabels(labels map[string]string, address string) string {
@@ -124,18 +137,40 @@ type DynamicScrapper struct {
 	DynamicJobName   string
 	Registry         *registry.Registry
 	FluentBitInputs  []config.LogInput
+	// EnvoyMaxSeriesPerFamily limits the number of series kept per metric family from Envoy.
+	EnvoyMaxSeriesPerFamily int
+
+	probeL     sync.Mutex
+	probeCache map[string]probeResult
+	// probeURL checks whether a service metrics endpoint responds, it's replaced in tests.
+	probeURL func(u *url.URL) bool
 }
 
-// Update updates the scrappers targets using new containers informations.
-func (d *DynamicScrapper) Update(containers []facts.Container) {
+// Update updates the scrappers targets using new containers and services informations.
+func (d *DynamicScrapper) Update(containers []facts.Container, services []discovery.Service) {
+	// The services endpoints are probed before taking the lock, as probing may be slow.
+	serviceTargets := d.listServiceExporters(services)
+
 	d.l.Lock()
 	defer d.l.Unlock()
 
-	d.update(containers)
+	d.update(containers, serviceTargets)
 }
 
-func (d *DynamicScrapper) update(containers []facts.Container) {
-	dynamicTargets := d.listExporters(containers)
+func (d *DynamicScrapper) update(containers []facts.Container, serviceTargets []*scrapper.Target) {
+	dynamicTargets, envoyURLs := d.listExporters(containers)
+
+	// A service could already be scraped using the labels of its container.
+	containerURLs := make(map[string]bool, len(dynamicTargets))
+	for _, target := range dynamicTargets {
+		containerURLs[target.URL.String()] = true
+	}
+
+	for _, target := range serviceTargets {
+		if !containerURLs[target.URL.String()] {
+			dynamicTargets = append(dynamicTargets, target)
+		}
+	}
 
 	if len(dynamicTargets) > 0 {
 		dynamicTargetsStr := make([]string, 0, len(dynamicTargets))
@@ -163,17 +198,20 @@ func (d *DynamicScrapper) update(containers []facts.Container) {
 
 		hash := labels.FromMap(t.ExtraLabels).Hash()
 
-		id, err := d.Registry.RegisterGatherer(
-			registry.RegistrationOption{
-				Description:              "Prometheus exporter " + t.URL.String(),
-				JitterSeed:               hash,
-				Interval:                 defaultInterval,
-				Rules:                    t.Rules,
-				ExtraLabels:              t.ExtraLabels,
-				AcceptAllowedMetricsOnly: true,
-			},
-			t,
-		)
+		opt := registry.RegistrationOption{
+			Description:              "Prometheus exporter " + t.URL.String(),
+			JitterSeed:               hash,
+			Interval:                 defaultInterval,
+			Rules:                    t.Rules,
+			ExtraLabels:              t.ExtraLabels,
+			AcceptAllowedMetricsOnly: true,
+		}
+
+		if envoyURLs[t.URL.String()] {
+			opt.GatherModifier = envoyGatherModifier(d.EnvoyMaxSeriesPerFamily)
+		}
+
+		id, err := d.Registry.RegisterGatherer(opt, t)
 		if err != nil {
 			logger.Printf("Failed to register scrapper for %v: %v", t.URL, err)
 
@@ -354,7 +354,7 @@ func TestListExporters(t *testing.T) { //nolint:maintidx
 			d := DynamicScrapper{
 				DynamicJobName: "jobname",
 			}
-			got := d.listExporters(tt.containers)
+			got, _ := d.listExporters(tt.containers)
 
 			if diff := cmp.Diff(tt.want, got, cmpopts.IgnoreUnexported(scrapper.Target{})); diff != "" {
 				t.Errorf("ListExporters() != want: %v", diff)
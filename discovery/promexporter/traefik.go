@@ -0,0 +1,181 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promexporter
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/bleemeo/glouton/discovery"
+	"github.com/bleemeo/glouton/logger"
+	"github.com/bleemeo/glouton/prometheus/scrapper"
+	"github.com/bleemeo/glouton/types"
+)
+
+const (
+	// traefikDefaultMetricsPort is the port of the "traefik" entrypoint,
+	// which serves the Prometheus metrics by default.
+	traefikDefaultMetricsPort = 8080
+	traefikMetricsPath        = "/metrics"
+	traefikProbeTimeout       = 5 * time.Second
+	// The result of a probe is kept for this duration, so the endpoints
+	// aren't probed on every discovery update.
+	probeCacheDuration = 5 * time.Minute
+)
+
+type probeResult struct {
+	available bool
+	probedAt  time.Time
+}
+
+// listServiceExporters returns the Prometheus endpoints of the discovered services.
+// Only the endpoints which respond are returned. It must be called without d.l held.
+func (d *DynamicScrapper) listServiceExporters(services []discovery.Service) []*scrapper.Target {
+	result := make([]*scrapper.Target, 0)
+
+	for _, service := range services {
+		if service.ServiceType != discovery.TraefikService || !service.Active || service.MetricsIgnored {
+			continue
+		}
+
+		u := traefikURLFromService(service)
+		if u == nil {
+			continue
+		}
+
+		if !d.isAvailable(u) {
+			logger.V(2).Printf("The metrics endpoint %s of Traefik isn't available, it will not be scraped", u)
+
+			continue
+		}
+
+		labels := map[string]string{
+			types.LabelMetaScrapeJob:       d.DynamicJobName,
+			types.LabelMetaScrapeInstance:  scrapper.HostPort(u),
+			types.LabelMetaServiceName:     service.Name,
+			types.LabelMetaServiceInstance: service.Instance,
+		}
+
+		if service.ContainerName != "" {
+			labels[types.LabelContainerName] = service.ContainerName
+		}
+
+		result = append(result, &scrapper.Target{
+			URL:         u,
+			ExtraLabels: labels,
+		})
+	}
+
+	return result
+}
+
+// traefikURLFromService returns the URL of the Traefik metrics, from the stats_url
+// of the service or from its address and stats_port.
+func traefikURLFromService(service discovery.Service) *url.URL {
+	if service.Config.StatsURL != "" {
+		u, err := url.Parse(service.Config.StatsURL)
+		if err != nil {
+			logger.Printf("ignoring invalid Traefik stats_url %v: %v", service.Config.StatsURL, err)
+
+			return nil
+		}
+
+		return u
+	}
+
+	port := traefikDefaultMetricsPort
+	if service.Config.StatsPort != 0 {
+		port = service.Config.StatsPort
+	}
+
+	ip := service.AddressForPort(port, "tcp", true)
+	if ip == "" {
+		return nil
+	}
+
+	return &url.URL{
+		Scheme: "http",
+		Host:   net.JoinHostPort(ip, strconv.Itoa(port)),
+		Path:   traefikMetricsPath,
+	}
+}
+
+// isAvailable returns whether the URL responds with a 200 status code.
+// The result is cached for probeCacheDuration.
+func (d *DynamicScrapper) isAvailable(u *url.URL) bool {
+	key := u.String()
+
+	d.probeL.Lock()
+	result, ok := d.probeCache[key]
+	d.probeL.Unlock()
+
+	if ok && time.Since(result.probedAt) < probeCacheDuration {
+		return result.available
+	}
+
+	probe := d.probeURL
+	if probe == nil {
+		probe = probeURL
+	}
+
+	result = probeResult{
+		available: probe(u),
+		probedAt:  time.Now(),
+	}
+
+	d.probeL.Lock()
+	defer d.probeL.Unlock()
+
+	if d.probeCache == nil {
+		d.probeCache = make(map[string]probeResult)
+	}
+
+	// Drop the expired results, so the cache doesn't grow with the removed services.
+	for k, r := range d.probeCache {
+		if time.Since(r.probedAt) >= probeCacheDuration {
+			delete(d.probeCache, k)
+		}
+	}
+
+	d.probeCache[key] = result
+
+	return result.available
+}
+
+// probeURL returns whether the URL responds with a 200 status code.
+func probeURL(u *url.URL) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), traefikProbeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false
+	}
+
+	resp.Body.Close()
+
+	return resp.StatusCode == http.StatusOK
+}
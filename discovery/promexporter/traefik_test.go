@@ -0,0 +1,165 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promexporter
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/bleemeo/glouton/config"
+	"github.com/bleemeo/glouton/discovery"
+	"github.com/bleemeo/glouton/facts"
+	"github.com/bleemeo/glouton/prometheus/scrapper"
+	"github.com/bleemeo/glouton/types"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestListServiceExporters(t *testing.T) {
+	traefik := discovery.Service{
+		Name:        "traefik",
+		ServiceType: discovery.TraefikService,
+		IPAddress:   "127.0.0.1",
+		ListenAddresses: []facts.ListenAddress{
+			{NetworkFamily: "tcp", Address: "0.0.0.0", Port: 80},
+			{NetworkFamily: "tcp", Address: "0.0.0.0", Port: 8080},
+		},
+		Active: true,
+	}
+
+	customPort := traefik
+	customPort.Name = "traefik-custom"
+	customPort.Instance = "proxy"
+	customPort.ContainerName = "proxy"
+	customPort.IPAddress = "172.17.0.2"
+	customPort.Config = config.Service{StatsPort: 8082}
+
+	ignored := traefik
+	ignored.MetricsIgnored = true
+
+	inactive := traefik
+	inactive.Active = false
+
+	down := traefik
+	down.IPAddress = "10.0.0.1"
+	down.ListenAddresses = nil
+
+	other := traefik
+	other.ServiceType = discovery.NginxService
+
+	d := DynamicScrapper{
+		DynamicJobName: fakeJobName,
+		probeURL: func(u *url.URL) bool {
+			return u.Hostname() != "10.0.0.1"
+		},
+	}
+
+	got := d.listServiceExporters([]discovery.Service{traefik, customPort, ignored, inactive, down, other})
+
+	want := []*scrapper.Target{
+		{
+			URL: &url.URL{Scheme: "http", Host: "127.0.0.1:8080", Path: "/metrics"},
+			ExtraLabels: map[string]string{
+				types.LabelMetaScrapeJob:       fakeJobName,
+				types.LabelMetaScrapeInstance:  "127.0.0.1:8080",
+				types.LabelMetaServiceName:     "traefik",
+				types.LabelMetaServiceInstance: "",
+			},
+		},
+		{
+			URL: &url.URL{Scheme: "http", Host: "172.17.0.2:8082", Path: "/metrics"},
+			ExtraLabels: map[string]string{
+				types.LabelMetaScrapeJob:       fakeJobName,
+				types.LabelMetaScrapeInstance:  "172.17.0.2:8082",
+				types.LabelMetaServiceName:     "traefik-custom",
+				types.LabelMetaServiceInstance: "proxy",
+				types.LabelContainerName:       "proxy",
+			},
+		},
+	}
+
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreUnexported(scrapper.Target{})); diff != "" {
+		t.Errorf("listServiceExporters() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestListServiceExportersProbeCache(t *testing.T) {
+	traefik := discovery.Service{
+		Name:        "traefik",
+		ServiceType: discovery.TraefikService,
+		IPAddress:   "127.0.0.1",
+		ListenAddresses: []facts.ListenAddress{
+			{NetworkFamily: "tcp", Address: "0.0.0.0", Port: 8080},
+		},
+		Active: true,
+	}
+
+	probeCount := 0
+
+	d := DynamicScrapper{
+		DynamicJobName: fakeJobName,
+		probeURL: func(*url.URL) bool {
+			probeCount++
+
+			return false
+		},
+	}
+
+	for range 3 {
+		if got := d.listServiceExporters([]discovery.Service{traefik}); len(got) != 0 {
+			t.Errorf("listServiceExporters() = %v, want no target", got)
+		}
+	}
+
+	if probeCount != 1 {
+		t.Errorf("the endpoint was probed %d times, want 1", probeCount)
+	}
+
+	// Once the result expired, the endpoint is probed again.
+	d.probeL.Lock()
+	d.probeCache["http://127.0.0.1:8080/metrics"] = probeResult{available: false, probedAt: time.Now().Add(-probeCacheDuration)}
+	d.probeL.Unlock()
+
+	d.probeURL = func(*url.URL) bool {
+		probeCount++
+
+		return true
+	}
+
+	if got := d.listServiceExporters([]discovery.Service{traefik}); len(got) != 1 {
+		t.Errorf("listServiceExporters() returned %d targets, want 1", len(got))
+	}
+
+	if probeCount != 2 {
+		t.Errorf("the endpoint was probed %d times, want 2", probeCount)
+	}
+}
+
+func TestTraefikURLFromService(t *testing.T) {
+	service := discovery.Service{
+		ServiceType: discovery.TraefikService,
+		IPAddress:   "127.0.0.1",
+		Config:      config.Service{StatsURL: "http://traefik.local:9100/custom-metrics"},
+	}
+
+	u := traefikURLFromService(service)
+	if u == nil || u.String() != "http://traefik.local:9100/custom-metrics" {
+		t.Errorf("traefikURLFromService() = %v, want the stats_url", u)
+	}
+}
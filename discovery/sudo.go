@@ -49,3 +49,39 @@ func (s SudoFileReader) ReadFile(path string) ([]byte, error) {
 
 	return cmd.Output()
 }
+
+// Readlink returns the destination of the symbolic link inside the host root.
+// Unlike ReadFile, it doesn't fallback to sudo.
+func (s SudoFileReader) Readlink(path string) (string, error) {
+	if s.HostRootPath == "" {
+		return "", os.ErrNotExist
+	}
+
+	return os.Readlink(filepath.Join(s.HostRootPath, path))
+}
+
+// Glob returns the files matching the pattern inside the host root.
+// The returned paths are relative to the host root.
+func (s SudoFileReader) Glob(pattern string) ([]string, error) {
+	if s.HostRootPath == "" {
+		return nil, os.ErrNotExist
+	}
+
+	matches, err := filepath.Glob(filepath.Join(s.HostRootPath, pattern))
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(matches))
+
+	for _, match := range matches {
+		relPath, err := filepath.Rel(s.HostRootPath, match)
+		if err != nil {
+			continue
+		}
+
+		paths = append(paths, "/"+relPath)
+	}
+
+	return paths, nil
+}
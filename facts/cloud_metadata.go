@@ -0,0 +1,181 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package facts
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bleemeo/glouton/crashreport"
+	"github.com/bleemeo/glouton/logger"
+)
+
+const (
+	// cloudMetadataTimeout is the maximum duration of a probe of the metadata endpoints.
+	// Off-cloud, the endpoints are unreachable and the probe must not delay the facts.
+	cloudMetadataTimeout = 500 * time.Millisecond
+	// cloudMetadataProbeInterval is the minimum delay between two probes when no provider was found.
+	cloudMetadataProbeInterval = time.Hour
+
+	cloudProviderAWS   = "aws"
+	cloudProviderGCP   = "gcp"
+	cloudProviderAzure = "azure"
+)
+
+// CloudMetadata provides the facts cloud_provider, cloud_instance_id, cloud_instance_type
+// and cloud_region from the metadata endpoint of the cloud providers.
+// Its Facts method is a FactCallback.
+type CloudMetadata struct {
+	l sync.Mutex
+
+	awsURL   string
+	gceURL   string
+	azureURL string
+
+	facts     map[string]string
+	lastProbe time.Time
+}
+
+// NewCloudMetadata returns a CloudMetadata using the well-known metadata endpoints.
+func NewCloudMetadata() *CloudMetadata {
+	return &CloudMetadata{
+		awsURL:   "http://169.254.169.254/latest/meta-data/",
+		gceURL:   "http://metadata.google.internal/computeMetadata/v1/instance/",
+		azureURL: "http://169.254.169.254/metadata/instance/compute?api-version=2019-11-01",
+	}
+}
+
+// Facts returns the cloud facts. The instance metadata doesn't change, so once a
+// provider is found it's no longer probed. Off-cloud, the probe is retried every hour.
+func (c *CloudMetadata) Facts(ctx context.Context, _ map[string]string) map[string]string {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if c.facts != nil || time.Since(c.lastProbe) < cloudMetadataProbeInterval {
+		return c.facts
+	}
+
+	c.lastProbe = time.Now()
+	c.facts = c.probe(ctx)
+
+	if c.facts != nil {
+		logger.V(2).Printf("facts: running on the cloud provider %s", c.facts["cloud_provider"])
+	}
+
+	return c.facts
+}
+
+// probe queries the metadata endpoints of all providers concurrently and
+// returns the facts of the first one which answered, or nil.
+func (c *CloudMetadata) probe(ctx context.Context) map[string]string {
+	ctx, cancel := context.WithTimeout(ctx, cloudMetadataTimeout)
+	defer cancel()
+
+	probes := []func(context.Context) map[string]string{
+		c.awsMetadata,
+		c.gceMetadata,
+		c.azureMetadata,
+	}
+
+	results := make([]map[string]string, len(probes))
+
+	var wg sync.WaitGroup
+
+	for i, probe := range probes {
+		wg.Add(1)
+
+		go func() {
+			defer crashreport.ProcessPanic()
+			defer wg.Done()
+
+			results[i] = probe(ctx)
+		}()
+	}
+
+	wg.Wait()
+
+	for _, result := range results {
+		if result != nil {
+			return result
+		}
+	}
+
+	return nil
+}
+
+func (c *CloudMetadata) awsMetadata(ctx context.Context) map[string]string {
+	instanceID := urlContent(ctx, c.awsURL+"instance-id")
+	if instanceID == "" {
+		return nil
+	}
+
+	return map[string]string{
+		"cloud_provider":      cloudProviderAWS,
+		"cloud_instance_id":   instanceID,
+		"cloud_instance_type": urlContent(ctx, c.awsURL+"instance-type"),
+		"cloud_region":        urlContent(ctx, c.awsURL+"placement/region"),
+	}
+}
+
+func (c *CloudMetadata) gceMetadata(ctx context.Context) map[string]string {
+	headers := []string{"Metadata-Flavor:Google"}
+
+	instanceID := httpQuery(ctx, c.gceURL+"id", headers)
+	if instanceID == "" {
+		return nil
+	}
+
+	// The machine type and the zone are returned as "projects/<id>/zones/europe-west1-b".
+	machineType := path.Base(httpQuery(ctx, c.gceURL+"machine-type", headers))
+	zone := path.Base(httpQuery(ctx, c.gceURL+"zone", headers))
+
+	region := zone
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		region = zone[:i]
+	}
+
+	return map[string]string{
+		"cloud_provider":      cloudProviderGCP,
+		"cloud_instance_id":   instanceID,
+		"cloud_instance_type": machineType,
+		"cloud_region":        region,
+	}
+}
+
+func (c *CloudMetadata) azureMetadata(ctx context.Context) map[string]string {
+	data := httpQuery(ctx, c.azureURL, []string{"Metadata:true"})
+	if data == "" {
+		return nil
+	}
+
+	var compute azureCompute
+
+	if err := json.Unmarshal([]byte(data), &compute); err != nil || compute.ID == "" {
+		return nil
+	}
+
+	return map[string]string{
+		"cloud_provider":      cloudProviderAzure,
+		"cloud_instance_id":   compute.ID,
+		"cloud_instance_type": compute.VMSize,
+		"cloud_region":        compute.Location,
+	}
+}
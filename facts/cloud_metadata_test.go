@@ -0,0 +1,111 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package facts
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCloudMetadata(t *testing.T) {
+	t.Parallel()
+
+	responses := map[string]string{
+		"/aws/instance-id":      "i-0123456789abcdef0",
+		"/aws/instance-type":    "t3.micro",
+		"/aws/placement/region": "eu-west-3",
+		"/gce/id":               "4567890123456789",
+		"/gce/machine-type":     "projects/123456/machineTypes/e2-medium",
+		"/gce/zone":             "projects/123456/zones/europe-west1-b",
+		"/azure":                `{"vmId": "02aab8a4-74ef-476e-8182-f6d2ba4166a6", "vmSize": "Standard_B1s", "location": "francecentral"}`,
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	unreachable := srv.URL + "/unreachable/"
+
+	cases := []struct {
+		name     string
+		cloud    *CloudMetadata
+		expected map[string]string
+	}{
+		{
+			name:  "aws",
+			cloud: &CloudMetadata{awsURL: srv.URL + "/aws/", gceURL: unreachable, azureURL: unreachable},
+			expected: map[string]string{
+				"cloud_provider":      "aws",
+				"cloud_instance_id":   "i-0123456789abcdef0",
+				"cloud_instance_type": "t3.micro",
+				"cloud_region":        "eu-west-3",
+			},
+		},
+		{
+			name:  "gcp",
+			cloud: &CloudMetadata{awsURL: unreachable, gceURL: srv.URL + "/gce/", azureURL: unreachable},
+			expected: map[string]string{
+				"cloud_provider":      "gcp",
+				"cloud_instance_id":   "4567890123456789",
+				"cloud_instance_type": "e2-medium",
+				"cloud_region":        "europe-west1",
+			},
+		},
+		{
+			name:  "azure",
+			cloud: &CloudMetadata{awsURL: unreachable, gceURL: unreachable, azureURL: srv.URL + "/azure"},
+			expected: map[string]string{
+				"cloud_provider":      "azure",
+				"cloud_instance_id":   "02aab8a4-74ef-476e-8182-f6d2ba4166a6",
+				"cloud_instance_type": "Standard_B1s",
+				"cloud_region":        "francecentral",
+			},
+		},
+		{
+			name:     "off-cloud",
+			cloud:    &CloudMetadata{awsURL: unreachable, gceURL: unreachable, azureURL: unreachable},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := tc.cloud.Facts(context.Background(), nil)
+			if diff := cmp.Diff(tc.expected, got); diff != "" {
+				t.Errorf("Facts() mismatch (-want +got):\n%s", diff)
+			}
+
+			if tc.cloud.lastProbe.IsZero() {
+				t.Error("lastProbe should be set after a probe")
+			}
+		})
+	}
+}
@@ -63,6 +63,9 @@ type Containerd struct {
 	Addresses                 []string
 	DeletedContainersCallback func(containersID []string)
 	IsContainerIgnored        func(facts.Container) bool
+	// IsMetricsAllowed restricts the containers for which metrics are gathered.
+	// All containers are allowed when it's nil.
+	IsMetricsAllowed func(facts.Container) bool
 
 	l                 sync.Mutex
 	workedOnce        bool
@@ -231,6 +234,10 @@ func (c *Containerd) Metrics(ctx context.Context, now time.Time) ([]types.Metric
 	c.l.Lock()
 
 	for _, cont := range c.containers {
+		if c.IsMetricsAllowed != nil && !c.IsMetricsAllowed(cont) {
+			continue
+		}
+
 		if !c.IsContainerIgnored(cont) {
 			idPerNamespace[cont.namespace] = append(idPerNamespace[cont.namespace], "id=="+cont.info.ID)
 
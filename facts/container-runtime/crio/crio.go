@@ -0,0 +1,847 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package crio implements the CRI-O container runtime.
+//
+// CRI-O is queried with the CRI gRPC API on its socket. This API has no events,
+// so the containers are listed periodically.
+package crio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bleemeo/glouton/config"
+	"github.com/bleemeo/glouton/facts"
+	containerTypes "github.com/bleemeo/glouton/facts/container-runtime/types"
+	"github.com/bleemeo/glouton/logger"
+	"github.com/bleemeo/glouton/types"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+const (
+	// pollInterval is the delay between two listings of the containers, the CRI API has no events.
+	pollInterval = 10 * time.Second
+	// requestTimeout is the maximum duration of a request to CRI-O.
+	requestTimeout = 10 * time.Second
+)
+
+var (
+	errNoAddresses  = errors.New("not addressed given")
+	errNotSupported = errors.New("not supported by CRI-O runtime")
+)
+
+// CRIO implements a connector to CRI-O.
+type CRIO struct {
+	Addresses                 []string
+	DeletedContainersCallback func(containersID []string)
+	IsContainerIgnored        func(facts.Container) bool
+
+	l                 sync.Mutex
+	workedOnce        bool
+	openConnection    func(ctx context.Context, address string) (cl crioClient, err error)
+	client            crioClient
+	lastUpdate        time.Time
+	lastDestroyedName map[string]time.Time
+	containers        map[string]containerObject
+	notifyC           chan facts.ContainerEvent
+	// pendingEvents are the events found by the listings of the containers, not yet sent.
+	pendingEvents []facts.ContainerEvent
+}
+
+// New returns a new CRI-O runtime.
+func New(
+	runtime config.ContainerRuntimeAddresses,
+	hostRoot string,
+	deletedContainersCallback func(containersID []string),
+	isContainerIgnored func(facts.Container) bool,
+) *CRIO {
+	return newWithOpenner(
+		containerTypes.ExpandRuntimeAddresses(runtime, hostRoot),
+		deletedContainersCallback,
+		isContainerIgnored,
+		openConnection,
+	)
+}
+
+func newWithOpenner(
+	addresses []string,
+	deletedContainersCallback func(containersID []string),
+	isContainerIgnored func(facts.Container) bool,
+	openConnection func(ctx context.Context, address string) (cl crioClient, err error),
+) *CRIO {
+	return &CRIO{
+		Addresses:                 addresses,
+		DeletedContainersCallback: deletedContainersCallback,
+		IsContainerIgnored:        isContainerIgnored,
+		openConnection:            openConnection,
+		lastDestroyedName:         make(map[string]time.Time),
+		containers:                make(map[string]containerObject),
+	}
+}
+
+func (c *CRIO) DiagnosticArchive(_ context.Context, archive types.ArchiveWriter) error {
+	file, err := archive.Create("crio.json")
+	if err != nil {
+		return err
+	}
+
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	type containerInfo struct {
+		ID        string
+		Name      string
+		IsIgnored bool
+	}
+
+	containers := make([]containerInfo, 0, len(c.containers))
+
+	for _, row := range c.containers {
+		containers = append(containers, containerInfo{
+			ID:        row.ID(),
+			Name:      row.ContainerName(),
+			IsIgnored: c.IsContainerIgnored(row),
+		})
+	}
+
+	obj := struct {
+		ClientIsNil bool
+		WorkedOnce  bool
+		LastUpdate  time.Time
+		Containers  []containerInfo
+	}{
+		ClientIsNil: c.client == nil,
+		WorkedOnce:  c.workedOnce,
+		LastUpdate:  c.lastUpdate,
+		Containers:  containers,
+	}
+
+	enc := json.NewEncoder(file)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(obj)
+}
+
+// LastUpdate return the last time containers list was updated.
+func (c *CRIO) LastUpdate() time.Time {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	return c.lastUpdate
+}
+
+// RuntimeFact will return facts from the CRI-O runtime, like crio_version.
+func (c *CRIO) RuntimeFact(ctx context.Context, currentFact map[string]string) map[string]string {
+	_ = currentFact
+
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	cl, err := c.getClient(ctx)
+	if err != nil {
+		return nil
+	}
+
+	version, err := cl.Version(ctx)
+	if err != nil {
+		c.closeClient()
+
+		return nil
+	}
+
+	return map[string]string{
+		"crio_version":      version.GetRuntimeVersion(),
+		"container_runtime": "CRI-O",
+	}
+}
+
+// Metrics returns no points, the containers metrics are gathered from their cgroups.
+func (c *CRIO) Metrics(_ context.Context, now time.Time) ([]types.MetricPoint, error) {
+	_ = now
+
+	return nil, nil
+}
+
+// MetricsMinute returns no points.
+func (c *CRIO) MetricsMinute(_ context.Context, now time.Time) ([]types.MetricPoint, error) {
+	_ = now
+
+	return nil, nil
+}
+
+// CachedContainer return a container without querying CRI-O, it use in-memory cache which must have been filled by a call to Containers().
+func (c *CRIO) CachedContainer(containerID string) (cont facts.Container, found bool) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	cont, found = c.containers[containerID]
+
+	return cont, found
+}
+
+// Containers return CRI-O containers.
+func (c *CRIO) Containers(ctx context.Context, maxAge time.Duration, includeIgnored bool) (containers []facts.Container, err error) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if time.Since(c.lastUpdate) >= maxAge {
+		err = c.updateContainers(ctx)
+		if err != nil {
+			if !c.workedOnce {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+	}
+
+	containers = make([]facts.Container, 0, len(c.containers))
+	for _, cont := range c.containers {
+		if includeIgnored || !c.IsContainerIgnored(cont) {
+			containers = append(containers, cont)
+		}
+	}
+
+	return
+}
+
+// IsRuntimeRunning returns whether or not CRI-O is available.
+func (c *CRIO) IsRuntimeRunning(ctx context.Context) bool {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	cl, err := c.getClient(ctx)
+	if err != nil {
+		return false
+	}
+
+	if _, err := cl.Version(ctx); err != nil {
+		c.closeClient()
+
+		return false
+	}
+
+	return true
+}
+
+func (c *CRIO) IsContainerNameRecentlyDeleted(name string) bool {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	_, ok := c.lastDestroyedName[name]
+
+	return ok
+}
+
+// Exec isn't supported, the CRI API only runs commands through a streaming server.
+func (c *CRIO) Exec(_ context.Context, containerID string, cmd []string) ([]byte, error) {
+	_ = containerID
+	_ = cmd
+
+	return nil, fmt.Errorf("exec is %w", errNotSupported)
+}
+
+// Events return the channel used to send events. There is only one shared channel (so
+// multiple consumer should be implemented by caller).
+func (c *CRIO) Events() <-chan facts.ContainerEvent {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if c.notifyC == nil {
+		c.notifyC = make(chan facts.ContainerEvent)
+	}
+
+	return c.notifyC
+}
+
+// ProcessWithCache facts.containerRuntime.
+func (c *CRIO) ProcessWithCache() facts.ContainerRuntimeProcessQuerier {
+	return &crioProcessQuerier{c: c}
+}
+
+// ContainerLastKill return the last time a container was killed or zero-time if unknown.
+// CRI-O does not provide this information.
+func (c *CRIO) ContainerLastKill(containerID string) time.Time {
+	_ = containerID
+
+	return time.Time{}
+}
+
+// Run lists the containers periodically and sends the events for the containers
+// which started, stopped or were deleted, until context is cancelled.
+func (c *CRIO) Run(ctx context.Context) error {
+	var (
+		lastErrorNotify  time.Time
+		sleepDelay       float64
+		reconnectAttempt int
+	)
+
+	// This will initialize c.notifyC
+	c.Events()
+
+	for {
+		err := c.run(ctx)
+
+		c.l.Lock()
+
+		reconnectAttempt++
+
+		if err != nil && ctx.Err() == nil && (time.Since(lastErrorNotify) >= time.Hour || reconnectAttempt <= 1) {
+			if c.workedOnce {
+				logger.Printf("Unable to contact CRI-O: %v", err)
+			} else {
+				logger.V(2).Printf("Unable to contact CRI-O: %v", err)
+			}
+
+			lastErrorNotify = time.Now()
+		}
+
+		sleepDelay = 5 * math.Pow(2, float64(reconnectAttempt))
+		if sleepDelay > 60 {
+			sleepDelay = 60
+		}
+
+		c.l.Unlock()
+
+		select {
+		case <-time.After(time.Duration(sleepDelay) * time.Second):
+		case <-ctx.Done():
+			close(c.notifyC)
+
+			c.l.Lock()
+			c.closeClient()
+			c.l.Unlock()
+
+			return nil
+		}
+	}
+}
+
+func (c *CRIO) run(ctx context.Context) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		c.l.Lock()
+
+		for k, v := range c.lastDestroyedName {
+			if time.Since(v) > 10*time.Minute {
+				delete(c.lastDestroyedName, k)
+			}
+		}
+
+		err := c.updateContainers(ctx)
+		events := c.pendingEvents
+		c.pendingEvents = nil
+
+		c.l.Unlock()
+
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			select {
+			case c.notifyC <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// updateContainers refreshes the containers and queues the events of the containers
+// which started, stopped or were deleted since the last update. It must be called with the lock held.
+func (c *CRIO) updateContainers(ctx context.Context) error {
+	cl, err := c.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	list, err := cl.ListContainers(ctx)
+	if err != nil {
+		c.closeClient()
+
+		return fmt.Errorf("listing containers failed: %w", err)
+	}
+
+	containers := make(map[string]containerObject, len(list))
+
+	for _, listed := range list {
+		if cont, ok := c.containers[listed.GetId()]; ok && cont.status.GetState() == listed.GetState() {
+			// The status only changes with the state of the container, no need to query it again.
+			containers[listed.GetId()] = cont
+
+			continue
+		}
+
+		cont, err := c.containerStatus(ctx, cl, listed.GetId())
+		if status.Code(err) == codes.NotFound {
+			// The container was deleted since the listing.
+			continue
+		}
+
+		if err != nil {
+			c.closeClient()
+
+			return err
+		}
+
+		containers[listed.GetId()] = cont
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	var deletedContainerID []string
+
+	for id, cont := range c.containers {
+		if _, ok := containers[id]; !ok {
+			deletedContainerID = append(deletedContainerID, id)
+			c.lastDestroyedName[cont.ContainerName()] = time.Now()
+			c.queueEvent(facts.ContainerEvent{
+				Type:        facts.EventTypeDelete,
+				ContainerID: id,
+				Container:   cont,
+			})
+		}
+	}
+
+	// No event is sent for the containers which already exist on the first listing.
+	if !c.lastUpdate.IsZero() {
+		for id, cont := range containers {
+			wasRunning := c.containers[id].State().IsRunning()
+
+			switch {
+			case cont.State().IsRunning() && !wasRunning:
+				c.queueEvent(facts.ContainerEvent{
+					Type:        facts.EventTypeStart,
+					ContainerID: id,
+					Container:   cont,
+				})
+			case !cont.State().IsRunning() && wasRunning:
+				c.queueEvent(facts.ContainerEvent{
+					Type:        facts.EventTypeStop,
+					ContainerID: id,
+					Container:   cont,
+				})
+			}
+		}
+	}
+
+	if len(deletedContainerID) > 0 && c.DeletedContainersCallback != nil {
+		c.DeletedContainersCallback(deletedContainerID)
+	}
+
+	c.lastUpdate = time.Now()
+	c.containers = containers
+
+	return nil
+}
+
+// containerStatus returns the container with its status and the address of its pod.
+func (c *CRIO) containerStatus(ctx context.Context, cl crioClient, id string) (containerObject, error) {
+	resp, err := cl.ContainerStatus(ctx, id)
+	if err != nil {
+		return containerObject{}, err
+	}
+
+	cont := containerObject{status: resp.GetStatus()}
+
+	// The verbose information contains the PID and the sandbox of the container.
+	if raw, ok := resp.GetInfo()["info"]; ok {
+		if err := json.Unmarshal([]byte(raw), &cont.info); err != nil {
+			logger.V(2).Printf("unable to decode the information of the CRI-O container %s: %v", id, err)
+		}
+	}
+
+	if cont.info.SandboxID != "" {
+		sandbox, err := cl.PodSandboxStatus(ctx, cont.info.SandboxID)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return containerObject{}, err
+		}
+
+		cont.primaryAddress = sandbox.GetNetwork().GetIp()
+	}
+
+	return cont, nil
+}
+
+// queueEvent adds an event which will be sent by Run. The events are dropped when Run isn't used.
+func (c *CRIO) queueEvent(event facts.ContainerEvent) {
+	if c.notifyC == nil {
+		return
+	}
+
+	c.pendingEvents = append(c.pendingEvents, event)
+}
+
+func (c *CRIO) getClient(ctx context.Context) (crioClient, error) {
+	if c.client == nil {
+		var firstErr error
+
+		if len(c.Addresses) == 0 {
+			firstErr = errNoAddresses
+		}
+
+		for _, addr := range c.Addresses {
+			cl, err := c.openConnection(ctx, addr)
+			if err != nil {
+				logger.V(2).Printf("CRI-O openConnection on %s failed: %v", addr, err)
+
+				if firstErr == nil {
+					firstErr = err
+				}
+
+				continue
+			}
+
+			if _, err = cl.Version(ctx); err != nil {
+				logger.V(2).Printf("CRI-O openConnection on %s failed: %v", addr, err)
+
+				if firstErr == nil {
+					firstErr = err
+				}
+
+				_ = cl.Close()
+
+				continue
+			}
+
+			c.client = cl
+
+			break
+		}
+
+		if c.client == nil {
+			return nil, firstErr
+		}
+	}
+
+	c.workedOnce = true
+
+	return c.client, nil
+}
+
+func (c *CRIO) closeClient() {
+	if c.client != nil {
+		_ = c.client.Close()
+	}
+
+	c.client = nil
+}
+
+// containerInfo is the verbose information of a container status.
+type containerInfo struct {
+	SandboxID string `json:"sandboxID"`
+	Pid       int    `json:"pid"`
+}
+
+type crioClient interface {
+	Version(ctx context.Context) (*runtimeapi.VersionResponse, error)
+	ListContainers(ctx context.Context) ([]*runtimeapi.Container, error)
+	ContainerStatus(ctx context.Context, id string) (*runtimeapi.ContainerStatusResponse, error)
+	PodSandboxStatus(ctx context.Context, id string) (*runtimeapi.PodSandboxStatus, error)
+	Close() error
+}
+
+func openConnection(_ context.Context, address string) (crioClient, error) {
+	path := strings.TrimPrefix(address, "unix://")
+
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("unable to access socket %s: %w", address, err)
+	}
+
+	conn, err := grpc.NewClient("unix://"+path, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+
+	return realClient{
+		conn:   conn,
+		client: runtimeapi.NewRuntimeServiceClient(conn),
+	}, nil
+}
+
+type realClient struct {
+	conn   *grpc.ClientConn
+	client runtimeapi.RuntimeServiceClient
+}
+
+func (cl realClient) Version(ctx context.Context) (*runtimeapi.VersionResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	return cl.client.Version(ctx, &runtimeapi.VersionRequest{})
+}
+
+func (cl realClient) ListContainers(ctx context.Context) ([]*runtimeapi.Container, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	resp, err := cl.client.ListContainers(ctx, &runtimeapi.ListContainersRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.GetContainers(), nil
+}
+
+func (cl realClient) ContainerStatus(ctx context.Context, id string) (*runtimeapi.ContainerStatusResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	return cl.client.ContainerStatus(ctx, &runtimeapi.ContainerStatusRequest{ContainerId: id, Verbose: true})
+}
+
+func (cl realClient) PodSandboxStatus(ctx context.Context, id string) (*runtimeapi.PodSandboxStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	resp, err := cl.client.PodSandboxStatus(ctx, &runtimeapi.PodSandboxStatusRequest{PodSandboxId: id})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.GetStatus(), nil
+}
+
+func (cl realClient) Close() error {
+	return cl.conn.Close()
+}
+
+type containerObject struct {
+	status         *runtimeapi.ContainerStatus
+	info           containerInfo
+	primaryAddress string
+}
+
+func (c containerObject) RuntimeName() string {
+	return containerTypes.CRIORuntime
+}
+
+func (c containerObject) Annotations() map[string]string {
+	return c.status.GetAnnotations()
+}
+
+func (c containerObject) Command() []string {
+	return nil
+}
+
+func (c containerObject) ContainerJSON() string {
+	obj := struct {
+		Status *runtimeapi.ContainerStatus
+		Info   containerInfo
+	}{
+		Status: c.status,
+		Info:   c.info,
+	}
+
+	buffer, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		logger.V(2).Printf("unable to marshal container info: %v", err)
+	}
+
+	return string(buffer)
+}
+
+func (c containerObject) ContainerName() string {
+	podName := c.PodName()
+	podNamespace := c.PodNamespace()
+	containerName := c.status.GetLabels()["io.kubernetes.container.name"]
+
+	if podName != "" && podNamespace != "" && containerName != "" {
+		return fmt.Sprintf("k8s_%s_%s_%s", containerName, podName, podNamespace)
+	}
+
+	if name := c.status.GetMetadata().GetName(); name != "" {
+		return name
+	}
+
+	return c.ID()
+}
+
+func (c containerObject) CreatedAt() time.Time {
+	return nanoTime(c.status.GetCreatedAt())
+}
+
+func (c containerObject) Environment() map[string]string {
+	return nil
+}
+
+func (c containerObject) FinishedAt() time.Time {
+	return nanoTime(c.status.GetFinishedAt())
+}
+
+func (c containerObject) Health() (facts.ContainerHealth, string) {
+	return facts.ContainerNoHealthCheck, ""
+}
+
+func (c containerObject) ID() string {
+	return c.status.GetId()
+}
+
+func (c containerObject) ImageID() string {
+	return c.status.GetImageRef()
+}
+
+func (c containerObject) ImageName() string {
+	return c.status.GetImage().GetImage()
+}
+
+func (c containerObject) Labels() map[string]string {
+	return c.status.GetLabels()
+}
+
+func (c containerObject) ListenAddresses() []facts.ListenAddress {
+	return nil
+}
+
+func (c containerObject) LogPath() string {
+	return c.status.GetLogPath()
+}
+
+func (c containerObject) PodName() string {
+	return c.status.GetLabels()["io.kubernetes.pod.name"]
+}
+
+func (c containerObject) PodNamespace() string {
+	return c.status.GetLabels()["io.kubernetes.pod.namespace"]
+}
+
+func (c containerObject) PrimaryAddress() string {
+	return c.primaryAddress
+}
+
+func (c containerObject) StartedAt() time.Time {
+	return nanoTime(c.status.GetStartedAt())
+}
+
+func (c containerObject) State() facts.ContainerState {
+	if c.status == nil {
+		return facts.ContainerUnknown
+	}
+
+	switch c.status.GetState() {
+	case runtimeapi.ContainerState_CONTAINER_CREATED:
+		return facts.ContainerCreated
+	case runtimeapi.ContainerState_CONTAINER_RUNNING:
+		return facts.ContainerRunning
+	case runtimeapi.ContainerState_CONTAINER_EXITED:
+		return facts.ContainerStopped
+	default:
+		return facts.ContainerUnknown
+	}
+}
+
+func (c containerObject) StoppedAndReplaced() bool {
+	return false
+}
+
+func (c containerObject) PID() int {
+	return c.info.Pid
+}
+
+// nanoTime converts a CRI timestamp in nanoseconds, zero means unset.
+func nanoTime(ts int64) time.Time {
+	if ts == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, ts)
+}
+
+// cgroupRE matches the cgroup of a container: "crio-<id>.scope" with the systemd cgroup
+// driver, "crio-<id>" with the cgroupfs driver.
+var cgroupRE = regexp.MustCompile(`crio-([0-9a-f]{64})(?:\.scope)?`)
+
+type crioProcessQuerier struct {
+	c                 *CRIO
+	containersUpdated bool
+}
+
+func (q *crioProcessQuerier) Processes(context.Context) ([]facts.Process, error) {
+	// The CRI API doesn't list the processes of a container.
+	return nil, nil
+}
+
+func (q *crioProcessQuerier) ContainerFromCGroup(ctx context.Context, cgroupData string) (facts.Container, error) {
+	match := cgroupRE.FindStringSubmatch(cgroupData)
+	if match == nil {
+		return nil, nil //nolint: nilnil
+	}
+
+	q.c.l.Lock()
+	defer q.c.l.Unlock()
+
+	if cont, ok := q.c.containers[match[1]]; ok {
+		return cont, nil
+	}
+
+	if !q.containersUpdated {
+		q.containersUpdated = true
+
+		if err := q.c.updateContainers(ctx); err != nil {
+			if !q.c.workedOnce {
+				return nil, nil //nolint: nilnil
+			}
+
+			return nil, err
+		}
+
+		if cont, ok := q.c.containers[match[1]]; ok {
+			return cont, nil
+		}
+	}
+
+	return nil, nil //nolint: nilnil
+}
+
+func (q *crioProcessQuerier) ContainerFromPID(_ context.Context, parentContainerID string, pid int) (facts.Container, error) {
+	_ = parentContainerID
+
+	q.c.l.Lock()
+	defer q.c.l.Unlock()
+
+	for _, c := range q.c.containers {
+		if c.info.Pid == pid {
+			return c, nil
+		}
+	}
+
+	return nil, nil //nolint: nilnil
+}
@@ -0,0 +1,332 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crio
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bleemeo/glouton/facts"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+const (
+	sandboxID   = "1111111111111111111111111111111111111111111111111111111111111111"
+	containerID = "2222222222222222222222222222222222222222222222222222222222222222"
+	stoppedID   = "3333333333333333333333333333333333333333333333333333333333333333"
+)
+
+type mockContainer struct {
+	status *runtimeapi.ContainerStatus
+	pid    int
+}
+
+type mockClient struct {
+	containers       map[string]mockContainer
+	statusCallsCount int
+}
+
+func (cl *mockClient) Version(context.Context) (*runtimeapi.VersionResponse, error) {
+	return &runtimeapi.VersionResponse{RuntimeName: "cri-o", RuntimeVersion: "1.30.0"}, nil
+}
+
+func (cl *mockClient) ListContainers(context.Context) ([]*runtimeapi.Container, error) {
+	list := make([]*runtimeapi.Container, 0, len(cl.containers))
+
+	for id, cont := range cl.containers {
+		list = append(list, &runtimeapi.Container{
+			Id:           id,
+			PodSandboxId: sandboxID,
+			State:        cont.status.GetState(),
+		})
+	}
+
+	return list, nil
+}
+
+func (cl *mockClient) ContainerStatus(_ context.Context, id string) (*runtimeapi.ContainerStatusResponse, error) {
+	cl.statusCallsCount++
+
+	cont, ok := cl.containers[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "container %s not found", id)
+	}
+
+	return &runtimeapi.ContainerStatusResponse{
+		Status: cont.status,
+		Info: map[string]string{
+			"info": fmt.Sprintf(`{"sandboxID": %q, "pid": %d}`, sandboxID, cont.pid),
+		},
+	}, nil
+}
+
+func (cl *mockClient) PodSandboxStatus(_ context.Context, id string) (*runtimeapi.PodSandboxStatus, error) {
+	if id != sandboxID {
+		return nil, status.Errorf(codes.NotFound, "pod sandbox %s not found", id)
+	}
+
+	return &runtimeapi.PodSandboxStatus{
+		Id:      id,
+		Network: &runtimeapi.PodSandboxNetworkStatus{Ip: "10.85.0.4"},
+	}, nil
+}
+
+func (cl *mockClient) Close() error {
+	return nil
+}
+
+func podLabels(containerName string) map[string]string {
+	return map[string]string{
+		"io.kubernetes.container.name": containerName,
+		"io.kubernetes.pod.name":       "nginx-7d9f8",
+		"io.kubernetes.pod.namespace":  "default",
+	}
+}
+
+func newTestCRIO(cl *mockClient, deleted *[]string) *CRIO {
+	return newWithOpenner(
+		[]string{"/run/crio/crio.sock"},
+		func(containersID []string) {
+			*deleted = append(*deleted, containersID...)
+		},
+		facts.ContainerFilter{}.ContainerIgnored,
+		func(context.Context, string) (crioClient, error) {
+			return cl, nil
+		},
+	)
+}
+
+func TestCRIO_Containers(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	finishedAt := time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC)
+
+	cl := &mockClient{
+		containers: map[string]mockContainer{
+			containerID: {
+				status: &runtimeapi.ContainerStatus{
+					Id:        containerID,
+					Metadata:  &runtimeapi.ContainerMetadata{Name: "nginx"},
+					State:     runtimeapi.ContainerState_CONTAINER_RUNNING,
+					CreatedAt: createdAt.UnixNano(),
+					StartedAt: createdAt.UnixNano(),
+					Image:     &runtimeapi.ImageSpec{Image: "docker.io/library/nginx:latest"},
+					ImageRef:  "docker.io/library/nginx@sha256:abcd",
+					Labels:    podLabels("nginx"),
+					LogPath:   "/var/log/pods/default_nginx-7d9f8_1234/nginx/0.log",
+				},
+				pid: 200,
+			},
+			stoppedID: {
+				status: &runtimeapi.ContainerStatus{
+					Id:         stoppedID,
+					Metadata:   &runtimeapi.ContainerMetadata{Name: "init"},
+					State:      runtimeapi.ContainerState_CONTAINER_EXITED,
+					CreatedAt:  createdAt.UnixNano(),
+					StartedAt:  createdAt.UnixNano(),
+					FinishedAt: finishedAt.UnixNano(),
+					Image:      &runtimeapi.ImageSpec{Image: "docker.io/library/busybox:latest"},
+					Labels:     podLabels("init"),
+				},
+			},
+		},
+	}
+
+	var deleted []string
+
+	c := newTestCRIO(cl, &deleted)
+
+	containers, err := c.Containers(context.Background(), 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]facts.FakeContainer, len(containers))
+
+	for _, cont := range containers {
+		got[cont.ID()] = facts.FakeContainer{
+			FakeContainerName:  cont.ContainerName(),
+			FakeImageName:      cont.ImageName(),
+			FakeImageID:        cont.ImageID(),
+			FakeState:          cont.State(),
+			FakePrimaryAddress: cont.PrimaryAddress(),
+			FakePodName:        cont.PodName(),
+			FakePodNamespace:   cont.PodNamespace(),
+			FakeCreatedAt:      cont.CreatedAt(),
+			FakeStartedAt:      cont.StartedAt(),
+			FakeFinishedAt:     cont.FinishedAt(),
+			FakePID:            cont.PID(),
+		}
+	}
+
+	// The pause container of the pod isn't listed by the CRI API, the stopped containers are.
+	want := map[string]facts.FakeContainer{
+		containerID: {
+			FakeContainerName:  "k8s_nginx_nginx-7d9f8_default",
+			FakeImageName:      "docker.io/library/nginx:latest",
+			FakeImageID:        "docker.io/library/nginx@sha256:abcd",
+			FakeState:          facts.ContainerRunning,
+			FakePrimaryAddress: "10.85.0.4",
+			FakePodName:        "nginx-7d9f8",
+			FakePodNamespace:   "default",
+			FakeCreatedAt:      createdAt,
+			FakeStartedAt:      createdAt,
+			FakePID:            200,
+		},
+		stoppedID: {
+			FakeContainerName:  "k8s_init_nginx-7d9f8_default",
+			FakeImageName:      "docker.io/library/busybox:latest",
+			FakeState:          facts.ContainerStopped,
+			FakePrimaryAddress: "10.85.0.4",
+			FakePodName:        "nginx-7d9f8",
+			FakePodNamespace:   "default",
+			FakeCreatedAt:      createdAt,
+			FakeStartedAt:      createdAt,
+			FakeFinishedAt:     finishedAt,
+		},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Containers() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The status of the containers whose state didn't change isn't queried again.
+	cl.statusCallsCount = 0
+
+	if _, err := c.Containers(context.Background(), 0, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if cl.statusCallsCount != 0 {
+		t.Errorf("ContainerStatus was called %d times, want 0", cl.statusCallsCount)
+	}
+
+	querier := c.ProcessWithCache()
+
+	for _, cgroup := range []string{
+		"0::/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod1234.slice/crio-" + containerID + ".scope\n",
+		"0::/kubepods/besteffort/pod1234/crio-" + containerID + "\n",
+	} {
+		cont, err := querier.ContainerFromCGroup(context.Background(), cgroup)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if cont == nil || cont.ID() != containerID {
+			t.Errorf("ContainerFromCGroup(%q) = %v, want container %s", cgroup, cont, containerID)
+		}
+	}
+
+	delete(cl.containers, containerID)
+
+	if _, err := c.Containers(context.Background(), 0, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff([]string{containerID}, deleted); diff != "" {
+		t.Errorf("deleted containers mismatch (-want +got):\n%s", diff)
+	}
+
+	if !c.IsContainerNameRecentlyDeleted("k8s_nginx_nginx-7d9f8_default") {
+		t.Error("IsContainerNameRecentlyDeleted() = false, want true")
+	}
+}
+
+// popEvents returns the type of the pending events by container ID and clears them.
+func popEvents(c *CRIO) map[string]facts.EventType {
+	events := make(map[string]facts.EventType, len(c.pendingEvents))
+
+	for _, event := range c.pendingEvents {
+		events[event.ContainerID] = event.Type
+	}
+
+	c.pendingEvents = nil
+
+	return events
+}
+
+func TestCRIO_Events(t *testing.T) {
+	newStatus := func(id string, state runtimeapi.ContainerState) *runtimeapi.ContainerStatus {
+		return &runtimeapi.ContainerStatus{Id: id, State: state, Labels: podLabels("nginx")}
+	}
+
+	cl := &mockClient{
+		containers: map[string]mockContainer{
+			containerID: {status: newStatus(containerID, runtimeapi.ContainerState_CONTAINER_RUNNING), pid: 200},
+		},
+	}
+
+	var deleted []string
+
+	c := newTestCRIO(cl, &deleted)
+
+	// Initialize the events channel like Run does.
+	c.Events()
+
+	steps := []struct {
+		name   string
+		update func()
+		want   map[string]facts.EventType
+	}{
+		{
+			name:   "first listing",
+			update: func() {},
+			want:   map[string]facts.EventType{},
+		},
+		{
+			name: "container stopped and another started",
+			update: func() {
+				cl.containers[containerID] = mockContainer{status: newStatus(containerID, runtimeapi.ContainerState_CONTAINER_EXITED)}
+				cl.containers[stoppedID] = mockContainer{status: newStatus(stoppedID, runtimeapi.ContainerState_CONTAINER_RUNNING), pid: 300}
+			},
+			want: map[string]facts.EventType{
+				containerID: facts.EventTypeStop,
+				stoppedID:   facts.EventTypeStart,
+			},
+		},
+		{
+			name: "stopped container deleted",
+			update: func() {
+				delete(cl.containers, containerID)
+			},
+			want: map[string]facts.EventType{
+				containerID: facts.EventTypeDelete,
+			},
+		},
+	}
+
+	for _, step := range steps {
+		step.update()
+
+		if _, err := c.Containers(context.Background(), 0, false); err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(step.want, popEvents(c)); diff != "" {
+			t.Errorf("%s: events mismatch (-want +got):\n%s", step.name, diff)
+		}
+	}
+
+	if diff := cmp.Diff([]string{containerID}, deleted); diff != "" {
+		t.Errorf("deleted containers mismatch (-want +got):\n%s", diff)
+	}
+}
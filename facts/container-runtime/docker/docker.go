@@ -60,6 +60,12 @@ var (
 // dockerTimeout is the time limit for requests made by the docker client.
 const dockerTimeout = 10 * time.Second
 
+const (
+	// Computing the size of containers is expensive for Docker, it's done on a slow interval.
+	diskUsageInterval = 10 * time.Minute
+	diskUsageTimeout  = time.Minute
+)
+
 // Docker implement a method to query Docker runtime.
 // It try to connect to the first valid DockerSockets. Empty string is a special
 // value: it means use default.
@@ -67,6 +73,8 @@ type Docker struct {
 	DockerSockets             []string
 	DeletedContainersCallback func(containersID []string)
 	IsContainerIgnored        func(facts.Container) bool
+	// CollectDiskUsage enables the container_disk_used_bytes metric.
+	CollectDiskUsage bool
 
 	l                sync.Mutex
 	workedOnce       bool
@@ -86,6 +94,10 @@ type Docker struct {
 	lastUpdate                     time.Time
 	bridgeNetworks                 map[string]interface{}
 	containerAddressOnDockerBridge map[string]string
+
+	diskUsageLock   sync.Mutex
+	diskUsageAt     time.Time
+	diskUsageValues map[string]int64
 }
 
 // New returns a new Docker runtime.
@@ -282,10 +294,84 @@ func (d *Docker) Metrics(_ context.Context, now time.Time) ([]types.MetricPoint,
 	return []types.MetricPoint{}, nil
 }
 
-func (d *Docker) MetricsMinute(_ context.Context, now time.Time) ([]types.MetricPoint, error) {
-	_ = now
+func (d *Docker) MetricsMinute(ctx context.Context, now time.Time) ([]types.MetricPoint, error) {
+	if !d.CollectDiskUsage {
+		return []types.MetricPoint{}, nil
+	}
 
-	return []types.MetricPoint{}, nil
+	return d.diskUsagePoints(ctx, now)
+}
+
+// diskUsagePoints returns the size of the writable layer of containers.
+// The size is refreshed every diskUsageInterval, the last known values are used in between.
+func (d *Docker) diskUsagePoints(ctx context.Context, now time.Time) ([]types.MetricPoint, error) {
+	d.diskUsageLock.Lock()
+	defer d.diskUsageLock.Unlock()
+
+	if d.diskUsageValues == nil || time.Since(d.diskUsageAt) >= diskUsageInterval {
+		values, err := d.containersDiskUsage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		d.diskUsageValues = values
+		d.diskUsageAt = time.Now()
+	}
+
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	points := make([]types.MetricPoint, 0, len(d.diskUsageValues))
+
+	for id, size := range d.diskUsageValues {
+		c, ok := d.containers[id]
+		if !ok || d.ignoredID[id] {
+			continue
+		}
+
+		name := c.ContainerName()
+
+		points = append(points, types.MetricPoint{
+			Point: types.Point{Time: now, Value: float64(size)},
+			Labels: map[string]string{
+				types.LabelName:            "container_disk_used_bytes",
+				types.LabelItem:            name,
+				types.LabelMetaContainerID: id,
+			},
+			Annotations: types.MetricAnnotations{
+				BleemeoItem: name,
+				ContainerID: id,
+			},
+		})
+	}
+
+	return points, nil
+}
+
+func (d *Docker) containersDiskUsage(ctx context.Context) (map[string]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, diskUsageTimeout)
+	defer cancel()
+
+	d.l.Lock()
+	cl, err := d.getClient(ctx)
+	d.l.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	dockerContainers, err := cl.ContainerList(ctx, container.ListOptions{All: true, Size: true})
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]int64, len(dockerContainers))
+
+	for _, c := range dockerContainers {
+		values[c.ID] = c.SizeRw
+	}
+
+	return values, nil
 }
 
 // Run will run connect and listen to Docker event until context is cancelled
@@ -87,7 +87,7 @@ func (cl *MockDockerClient) ContainerList(_ context.Context, options containerTy
 		return nil, cl.ReturnError
 	}
 
-	if !reflect.DeepEqual(options, containerTypes.ListOptions{All: true}) {
+	if !reflect.DeepEqual(options, containerTypes.ListOptions{All: true}) && !reflect.DeepEqual(options, containerTypes.ListOptions{All: true, Size: true}) {
 		return nil, fmt.Errorf("ContainerList %w with options other than all=True", errNotImplemented)
 	}
 
@@ -100,6 +100,10 @@ func (cl *MockDockerClient) ContainerList(_ context.Context, options containerTy
 		result[i] = dockerTypes.Container{
 			ID: c.ID,
 		}
+
+		if options.Size && c.SizeRw != nil {
+			result[i].SizeRw = *c.SizeRw
+		}
 	}
 
 	return result, nil
@@ -29,6 +29,7 @@ import (
 
 	"github.com/bleemeo/glouton/facts"
 	"github.com/bleemeo/glouton/facts/container-runtime/internal/testutil"
+	"github.com/bleemeo/glouton/types"
 
 	containerTypes "github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/events"
@@ -246,6 +247,58 @@ func TestDocker_Containers(t *testing.T) {
 }
 
 // TestDocker_Run do not test much, but at least it execute code to ensure it don't crash.
+func TestDocker_DiskUsage(t *testing.T) {
+	cl, err := NewDockerMock("testdata/docker-20.10.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sizes := make(map[string]int64, len(cl.Containers))
+
+	for i := range cl.Containers {
+		size := int64(1000 * (i + 1))
+		cl.Containers[i].SizeRw = &size
+		sizes[cl.Containers[i].ID] = size
+	}
+
+	d := FakeDocker(cl, facts.ContainerFilter{}.ContainerIgnored)
+
+	if _, err := d.Containers(context.Background(), 0, false); err != nil {
+		t.Fatal(err)
+	}
+
+	points, err := d.MetricsMinute(context.Background(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(points) != 0 {
+		t.Errorf("got %d points while disk usage collection is disabled", len(points))
+	}
+
+	d.CollectDiskUsage = true
+
+	points, err = d.MetricsMinute(context.Background(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The container with glouton.enable=off is ignored.
+	if len(points) != len(cl.Containers)-1 {
+		t.Errorf("got %d points, want %d", len(points), len(cl.Containers)-1)
+	}
+
+	for _, p := range points {
+		if p.Labels[types.LabelName] != "container_disk_used_bytes" {
+			t.Errorf("unexpected metric %s", p.Labels[types.LabelName])
+		}
+
+		if want := float64(sizes[p.Annotations.ContainerID]); p.Value != want {
+			t.Errorf("%s = %f, want %f", p.Labels[types.LabelItem], p.Value, want)
+		}
+	}
+}
+
 func TestDocker_Run(t *testing.T) {
 	start := time.Now()
 
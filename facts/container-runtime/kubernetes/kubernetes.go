@@ -34,6 +34,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/bleemeo/glouton/crashreport"
 	"github.com/bleemeo/glouton/facts"
 	crTypes "github.com/bleemeo/glouton/facts/container-runtime/types"
 	"github.com/bleemeo/glouton/logger"
@@ -44,6 +45,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -75,11 +77,15 @@ type Kubernetes struct {
 	version        *version.Info
 	id2Pod         map[string]corev1.Pod
 	podID2Pod      map[string]corev1.Pod
+	notifyC        chan facts.ContainerEvent
 }
 
 const (
 	caExpLabel   = "kubernetes_ca_day_left"
 	certExpLabel = "kubernetes_certificate_day_left"
+
+	// podWatchRetryDelay is the delay before re-opening the PODs watch after a failure.
+	podWatchRetryDelay = 10 * time.Second
 )
 
 var (
@@ -173,9 +179,25 @@ func (k *Kubernetes) Containers(ctx context.Context, maxAge time.Duration, inclu
 	return response, nil
 }
 
-// Events return container events.
+// Events return container events. It contains the events of the wrapped runtime
+// and the events generated from the PODs lifecycle seen by the Kubernetes watch API.
 func (k *Kubernetes) Events() <-chan facts.ContainerEvent {
-	return k.Runtime.Events()
+	k.l.Lock()
+	defer k.l.Unlock()
+
+	if k.notifyC == nil {
+		k.notifyC = make(chan facts.ContainerEvent)
+
+		go func() {
+			defer crashreport.ProcessPanic()
+
+			for event := range k.Runtime.Events() {
+				k.notifyC <- event
+			}
+		}()
+	}
+
+	return k.notifyC
 }
 
 // IsRuntimeRunning tells if Glouton is connected to the container runtime.
@@ -254,9 +276,145 @@ func (k *Kubernetes) ProcessWithCache() facts.ContainerRuntimeProcessQuerier {
 
 // Run the connector.
 func (k *Kubernetes) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+
+	defer wg.Wait()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if k.NodeName != "" {
+		wg.Add(1)
+
+		go func() {
+			defer crashreport.ProcessPanic()
+			defer wg.Done()
+
+			k.watchPods(ctx)
+		}()
+	}
+
 	return k.Runtime.Run(ctx)
 }
 
+// watchPods sends container events from the PODs lifecycle until the context is cancelled.
+// This allows discovery to react to PODs changes even when the runtime events are unavailable.
+func (k *Kubernetes) watchPods(ctx context.Context) {
+	// ensure k.notifyC is created
+	_ = k.Events()
+
+	k.l.Lock()
+	notifyC := k.notifyC
+	k.l.Unlock()
+
+	state := podWatchState{running: make(map[string]bool)}
+
+	for ctx.Err() == nil {
+		err := k.watchPodsOnce(ctx, &state, notifyC)
+		if err != nil {
+			logger.V(2).Printf("Kubernetes PODs watch failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(podWatchRetryDelay):
+		}
+	}
+}
+
+func (k *Kubernetes) watchPodsOnce(ctx context.Context, state *podWatchState, notifyC chan<- facts.ContainerEvent) error {
+	k.l.Lock()
+	cl, err := k.getClient(ctx)
+	k.l.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	watcher, err := cl.WatchPODs(ctx, k.NodeName)
+	if err != nil {
+		return err
+	}
+
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case watchEvent, ok := <-watcher.ResultChan():
+			if !ok {
+				return nil
+			}
+
+			events := state.events(watchEvent)
+			if len(events) == 0 {
+				continue
+			}
+
+			// Force a refresh of the PODs on the next containers listing.
+			k.l.Lock()
+			k.lastPodsUpdate = time.Time{}
+			k.l.Unlock()
+
+			for _, event := range events {
+				select {
+				case notifyC <- event:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// podWatchState keeps the containers state seen in PODs watch events, to only send events on changes.
+type podWatchState struct {
+	// running maps a container ID (as used by the runtime) to whether it's running.
+	running map[string]bool
+}
+
+// events returns the container events corresponding to a POD watch event.
+func (s *podWatchState) events(watchEvent watch.Event) []facts.ContainerEvent {
+	pod, ok := watchEvent.Object.(*corev1.Pod)
+	if !ok {
+		return nil
+	}
+
+	var events []facts.ContainerEvent
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.ContainerID == "" {
+			continue
+		}
+
+		containerID := kuberIDtoRuntimeID(status.ContainerID)
+		wasRunning, known := s.running[containerID]
+
+		if watchEvent.Type == watch.Deleted {
+			if known {
+				delete(s.running, containerID)
+
+				events = append(events, facts.ContainerEvent{Type: facts.EventTypeDelete, ContainerID: containerID})
+			}
+
+			continue
+		}
+
+		isRunning := status.State.Running != nil
+		s.running[containerID] = isRunning
+
+		switch {
+		case isRunning && (!known || !wasRunning):
+			events = append(events, facts.ContainerEvent{Type: facts.EventTypeStart, ContainerID: containerID})
+		case !isRunning && known && wasRunning:
+			events = append(events, facts.ContainerEvent{Type: facts.EventTypeStop, ContainerID: containerID})
+		}
+	}
+
+	return events
+}
+
 // RuntimeFact return facts about the container runtime & Kubernetes.
 func (k *Kubernetes) RuntimeFact(ctx context.Context, currentFact map[string]string) map[string]string {
 	facts := k.Runtime.RuntimeFact(ctx, currentFact)
@@ -668,6 +826,7 @@ func (k *Kubernetes) updatePods(ctx context.Context) error {
 
 func kuberIDtoRuntimeID(containerID string) string {
 	containerID = strings.TrimPrefix(containerID, "docker://")
+	containerID = strings.TrimPrefix(containerID, "cri-o://")
 
 	if strings.HasPrefix(containerID, "containerd://") {
 		containerID = strings.TrimPrefix(containerID, "containerd://")
@@ -690,6 +849,8 @@ type kubeClient interface {
 	// GetReplicasets return all replicasets in the cluster.
 	GetReplicasets(ctx context.Context) ([]appsv1.ReplicaSet, error)
 	GetServerVersion(ctx context.Context) (*version.Info, error)
+	// WatchPODs watches changes of the PODs on given nodeName.
+	WatchPODs(ctx context.Context, nodeName string) (watch.Interface, error)
 	IsUsingLocalAPI() bool
 	Config() *rest.Config
 }
@@ -731,6 +892,10 @@ func (cl realClient) GetPODs(ctx context.Context, nodeName string) ([]corev1.Pod
 	return list.Items, nil
 }
 
+func (cl realClient) WatchPODs(ctx context.Context, nodeName string) (watch.Interface, error) {
+	return cl.client.CoreV1().Pods("").Watch(ctx, metav1.ListOptions{FieldSelector: "spec.nodeName=" + nodeName})
+}
+
 func (cl realClient) GetNamespaces(ctx context.Context) ([]corev1.Namespace, error) {
 	ns, err := cl.client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
 	if err != nil {
@@ -39,6 +39,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/yaml"
 )
@@ -139,6 +140,12 @@ func (k *mockKubernetesClient) GetServerVersion(_ context.Context) (*version.Inf
 	return k.versions.ServerVersion, nil
 }
 
+func (k *mockKubernetesClient) WatchPODs(_ context.Context, nodeName string) (watch.Interface, error) {
+	_ = nodeName
+
+	return nil, errNotImplemented
+}
+
 func (k *mockKubernetesClient) IsUsingLocalAPI() bool {
 	return true
 }
@@ -984,3 +991,64 @@ func TestClusterMetrics(t *testing.T) {
 		t.Fatalf("Didn't get expected points:\n%s", diff)
 	}
 }
+
+func TestPodWatchStateEvents(t *testing.T) {
+	t.Parallel()
+
+	newPod := func(running bool) *corev1.Pod {
+		state := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}
+		if running {
+			state = corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+		}
+
+		return &corev1.Pod{
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{
+					{ContainerID: "containerd://1234", State: state},
+					{ContainerID: ""},
+				},
+			},
+		}
+	}
+
+	state := podWatchState{running: make(map[string]bool)}
+
+	steps := []struct {
+		name  string
+		event watch.Event
+		want  []facts.ContainerEvent
+	}{
+		{
+			name:  "added",
+			event: watch.Event{Type: watch.Added, Object: newPod(true)},
+			want:  []facts.ContainerEvent{{Type: facts.EventTypeStart, ContainerID: "k8s.io/1234"}},
+		},
+		{
+			name:  "unchanged",
+			event: watch.Event{Type: watch.Modified, Object: newPod(true)},
+			want:  nil,
+		},
+		{
+			name:  "stopped",
+			event: watch.Event{Type: watch.Modified, Object: newPod(false)},
+			want:  []facts.ContainerEvent{{Type: facts.EventTypeStop, ContainerID: "k8s.io/1234"}},
+		},
+		{
+			name:  "deleted",
+			event: watch.Event{Type: watch.Deleted, Object: newPod(false)},
+			want:  []facts.ContainerEvent{{Type: facts.EventTypeDelete, ContainerID: "k8s.io/1234"}},
+		},
+		{
+			name:  "deleted-unknown",
+			event: watch.Event{Type: watch.Deleted, Object: newPod(false)},
+			want:  nil,
+		},
+	}
+
+	for _, step := range steps {
+		got := state.events(step.event)
+		if diff := cmp.Diff(step.want, got); diff != "" {
+			t.Errorf("%s: events mismatch (-want +got):\n%s", step.name, diff)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podman
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bleemeo/glouton/config"
+	"github.com/bleemeo/glouton/crashreport"
+	"github.com/bleemeo/glouton/facts"
+	"github.com/bleemeo/glouton/facts/container-runtime/docker"
+	containerTypes "github.com/bleemeo/glouton/facts/container-runtime/types"
+	"github.com/bleemeo/glouton/logger"
+	"github.com/bleemeo/glouton/types"
+	"github.com/bleemeo/glouton/utils/archivewriter"
+)
+
+// Podman implements a method to query the Podman runtime.
+// It uses the Docker compatible API exposed on the Podman socket,
+// containers are reported with the Podman runtime name.
+type Podman struct {
+	*docker.Docker
+
+	l       sync.Mutex
+	notifyC chan facts.ContainerEvent
+}
+
+// New returns a new Podman runtime.
+func New(
+	runtime config.ContainerRuntimePodman,
+	hostRoot string,
+	deletedContainersCallback func(containersID []string),
+	isContainerIgnored func(facts.Container) bool,
+) *Podman {
+	// The addresses are already prefixed by the host root.
+	addresses := config.ContainerRuntimeAddresses{
+		Addresses:      expandAddresses(runtime, hostRoot),
+		PrefixHostRoot: false,
+	}
+
+	return &Podman{
+		Docker: docker.New(
+			addresses,
+			hostRoot,
+			deletedContainersCallback,
+			func(c facts.Container) bool {
+				return isContainerIgnored(podmanContainer{Container: c})
+			},
+		),
+	}
+}
+
+// SocketFound returns whether one of the Podman sockets exists.
+func SocketFound(runtime config.ContainerRuntimePodman, hostRoot string) bool {
+	for _, address := range expandAddresses(runtime, hostRoot) {
+		if !strings.HasPrefix(address, "unix://") {
+			continue
+		}
+
+		info, err := os.Stat(strings.TrimPrefix(address, "unix://"))
+		if err == nil && info.Mode()&os.ModeSocket != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expandAddresses adds the host root to the socket addresses and expands the
+// patterns, like "unix:///run/user/*/podman/podman.sock" for the sockets of
+// rootless Podman.
+func expandAddresses(runtime config.ContainerRuntimePodman, hostRoot string) []string {
+	addresses := containerTypes.ExpandRuntimeAddresses(
+		config.ContainerRuntimeAddresses{
+			Addresses:      runtime.Addresses,
+			PrefixHostRoot: runtime.PrefixHostRoot,
+		},
+		hostRoot,
+	)
+
+	result := make([]string, 0, len(addresses))
+
+	for _, address := range addresses {
+		path := strings.TrimPrefix(address, "unix://")
+		if path == address || !strings.ContainsAny(path, "*?[") {
+			result = append(result, address)
+
+			continue
+		}
+
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			logger.V(1).Printf("Invalid Podman socket pattern %s: %v", address, err)
+
+			continue
+		}
+
+		for _, match := range matches {
+			result = append(result, "unix://"+match)
+		}
+	}
+
+	return result
+}
+
+// RuntimeFact will return facts from the Podman runtime, like podman_version.
+func (p *Podman) RuntimeFact(ctx context.Context, currentFact map[string]string) map[string]string {
+	dockerFacts := p.Docker.RuntimeFact(ctx, currentFact)
+	if dockerFacts == nil {
+		return nil
+	}
+
+	return map[string]string{
+		"podman_version":     dockerFacts["docker_version"],
+		"podman_api_version": dockerFacts["docker_api_version"],
+		"container_runtime":  "Podman",
+	}
+}
+
+// CachedContainer return a container without querying Podman.
+func (p *Podman) CachedContainer(containerID string) (c facts.Container, found bool) {
+	c, found = p.Docker.CachedContainer(containerID)
+	if !found {
+		return c, found
+	}
+
+	return podmanContainer{Container: c}, found
+}
+
+// Containers return Podman containers.
+func (p *Podman) Containers(ctx context.Context, maxAge time.Duration, includeIgnored bool) (containers []facts.Container, err error) {
+	containers, err = p.Docker.Containers(ctx, maxAge, includeIgnored)
+
+	for i, c := range containers {
+		containers[i] = podmanContainer{Container: c}
+	}
+
+	return containers, err
+}
+
+// Events return the channel used to send events.
+func (p *Podman) Events() <-chan facts.ContainerEvent {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	if p.notifyC == nil {
+		p.notifyC = make(chan facts.ContainerEvent)
+
+		go func() {
+			defer crashreport.ProcessPanic()
+
+			for event := range p.Docker.Events() {
+				if event.Container != nil {
+					event.Container = podmanContainer{Container: event.Container}
+				}
+
+				p.notifyC <- event
+			}
+		}()
+	}
+
+	return p.notifyC
+}
+
+// DiagnosticArchive add diagnostic information.
+func (p *Podman) DiagnosticArchive(ctx context.Context, archive types.ArchiveWriter) error {
+	return p.Docker.DiagnosticArchive(ctx, archivewriter.NewPrefixWriter("podman-", archive))
+}
+
+// podmanContainer is a container from the Docker compatible API of Podman.
+type podmanContainer struct {
+	facts.Container
+}
+
+func (c podmanContainer) RuntimeName() string {
+	return containerTypes.PodmanRuntime
+}
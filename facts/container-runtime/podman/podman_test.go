@@ -0,0 +1,243 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podman
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bleemeo/glouton/config"
+	"github.com/bleemeo/glouton/facts"
+	"github.com/bleemeo/glouton/facts/container-runtime/docker"
+	containerTypes "github.com/bleemeo/glouton/facts/container-runtime/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// The Podman runtime uses the Docker compatible API, so the Docker test data are used.
+const dockerTestdata = "../docker/testdata/docker-20.10.0"
+
+func fakePodman(t *testing.T, isContainerIgnored func(facts.Container) bool) *Podman {
+	t.Helper()
+
+	cl, err := docker.NewDockerMock(dockerTestdata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Podman{Docker: docker.FakeDocker(cl, isContainerIgnored)}
+}
+
+func TestPodman_RuntimeFact(t *testing.T) {
+	p := fakePodman(t, facts.ContainerFilter{}.ContainerIgnored)
+
+	want := map[string]string{
+		"podman_version":     "20.10.0",
+		"podman_api_version": "1.41",
+		"container_runtime":  "Podman",
+	}
+
+	if got := p.RuntimeFact(context.Background(), nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("Podman.RuntimeFact() = %v, want %v", got, want)
+	}
+}
+
+func TestPodman_ContainersRuntimeName(t *testing.T) {
+	p := fakePodman(t, facts.ContainerFilter{}.ContainerIgnored)
+
+	containers, err := p.Containers(context.Background(), 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(containers) == 0 {
+		t.Fatal("no container found")
+	}
+
+	for _, c := range containers {
+		if c.RuntimeName() != containerTypes.PodmanRuntime {
+			t.Errorf("container %s has runtime %s, want %s", c.ContainerName(), c.RuntimeName(), containerTypes.PodmanRuntime)
+		}
+
+		cached, found := p.CachedContainer(c.ID())
+		if !found {
+			t.Errorf("container %s isn't in the cache", c.ContainerName())
+
+			continue
+		}
+
+		if cached.RuntimeName() != containerTypes.PodmanRuntime {
+			t.Errorf("cached container %s has runtime %s, want %s", c.ContainerName(), cached.RuntimeName(), containerTypes.PodmanRuntime)
+		}
+	}
+
+	if _, found := p.CachedContainer("unknown"); found {
+		t.Error("CachedContainer() found an unknown container")
+	}
+}
+
+func TestPodman_ContainersMaxAge(t *testing.T) {
+	p := fakePodman(t, facts.ContainerFilter{}.ContainerIgnored)
+
+	first, err := p.Containers(context.Background(), 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The cached containers are also reported with the Podman runtime.
+	cached, err := p.Containers(context.Background(), time.Hour, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cached) != len(first) {
+		t.Fatalf("got %d cached containers, want %d", len(cached), len(first))
+	}
+
+	for _, c := range cached {
+		if c.RuntimeName() != containerTypes.PodmanRuntime {
+			t.Errorf("container %s has runtime %s, want %s", c.ContainerName(), c.RuntimeName(), containerTypes.PodmanRuntime)
+		}
+	}
+}
+
+func TestExpandAddresses(t *testing.T) {
+	hostRoot := t.TempDir()
+
+	for _, uid := range []string{"1000", "1001"} {
+		dir := filepath.Join(hostRoot, "run", "user", uid, "podman")
+
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, "podman.sock"), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runtime := config.ContainerRuntimePodman{
+		Enable: true,
+		Addresses: []string{
+			"unix:///run/podman/podman.sock",
+			"unix:///run/user/*/podman/podman.sock",
+		},
+		PrefixHostRoot: true,
+	}
+
+	want := []string{
+		"unix:///run/podman/podman.sock",
+		"unix://" + filepath.Join(hostRoot, "run/podman/podman.sock"),
+		"unix://" + filepath.Join(hostRoot, "run/user/1000/podman/podman.sock"),
+		"unix://" + filepath.Join(hostRoot, "run/user/1001/podman/podman.sock"),
+	}
+
+	if diff := cmp.Diff(want, expandAddresses(runtime, hostRoot)); diff != "" {
+		t.Errorf("expandAddresses() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestExpandAddressesSelection(t *testing.T) {
+	hostRoot := t.TempDir()
+
+	tests := []struct {
+		name    string
+		runtime config.ContainerRuntimePodman
+		want    []string
+	}{
+		{
+			name: "without-host-root",
+			runtime: config.ContainerRuntimePodman{
+				Addresses:      []string{"unix:///run/podman/podman.sock"},
+				PrefixHostRoot: false,
+			},
+			want: []string{"unix:///run/podman/podman.sock"},
+		},
+		{
+			name: "tcp-address",
+			runtime: config.ContainerRuntimePodman{
+				Addresses:      []string{"tcp://127.0.0.1:8888"},
+				PrefixHostRoot: true,
+			},
+			want: []string{"tcp://127.0.0.1:8888"},
+		},
+		{
+			name: "pattern-without-match",
+			runtime: config.ContainerRuntimePodman{
+				Addresses:      []string{"unix:///run/user/*/podman/podman.sock"},
+				PrefixHostRoot: false,
+			},
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, expandAddresses(tt.runtime, hostRoot)); diff != "" {
+				t.Errorf("expandAddresses() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSocketFound(t *testing.T) {
+	hostRoot := t.TempDir()
+	runtime := config.ContainerRuntimePodman{
+		Enable:         true,
+		Addresses:      []string{"unix:///run/user/*/podman/podman.sock"},
+		PrefixHostRoot: true,
+	}
+
+	if SocketFound(runtime, hostRoot) {
+		t.Error("SocketFound() = true without a socket")
+	}
+
+	dir := filepath.Join(hostRoot, "run", "user", "1000", "podman")
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// A regular file isn't a socket.
+	if err := os.WriteFile(filepath.Join(dir, "podman.sock"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if SocketFound(runtime, hostRoot) {
+		t.Error("SocketFound() = true with a regular file")
+	}
+
+	if err := os.Remove(filepath.Join(dir, "podman.sock")); err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("unix", filepath.Join(dir, "podman.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer listener.Close()
+
+	if !SocketFound(runtime, hostRoot) {
+		t.Error("SocketFound() = false with a rootless socket")
+	}
+}
@@ -30,6 +30,8 @@ import (
 const (
 	DockerRuntime     = "docker"
 	ContainerDRuntime = "containerd"
+	PodmanRuntime     = "podman"
+	CRIORuntime       = "cri-o"
 )
 
 // RuntimeInterface is the interface that container runtime provide.
@@ -19,7 +19,9 @@ package facts
 import (
 	"context"
 	"errors"
+	"fmt"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -221,6 +223,91 @@ func (cf ContainerFilter) ContainerEnabled(c Container) (enabled bool, explicit
 	return containerEnabledFromLabels(LabelsAndAnnotations(c), cf.DisabledByDefault)
 }
 
+// ContainerMetricsFilter tells whether metrics are gathered for a container.
+// It only restricts the metrics, the discovery still uses all the containers.
+type ContainerMetricsFilter struct {
+	names  []*regexp.Regexp
+	labels map[string][]*regexp.Regexp
+}
+
+// NewContainerMetricsFilter returns a filter from an allowlist. Each entry is
+// either a regular expression matched against the container name, or a
+// "label=regex" which matches the value of a container label.
+// An empty allowlist allows all containers.
+func NewContainerMetricsFilter(allowlist []string) (ContainerMetricsFilter, error) {
+	var (
+		filter ContainerMetricsFilter
+		errs   []error
+	)
+
+	for _, entry := range allowlist {
+		label, expr, isLabel := strings.Cut(entry, "=")
+		if !isLabel {
+			expr = entry
+		}
+
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid entry %q: %w", entry, err))
+
+			continue
+		}
+
+		if !isLabel {
+			filter.names = append(filter.names, re)
+
+			continue
+		}
+
+		if filter.labels == nil {
+			filter.labels = make(map[string][]*regexp.Regexp)
+		}
+
+		filter.labels[label] = append(filter.labels[label], re)
+	}
+
+	return filter, errors.Join(errs...)
+}
+
+// IsEmpty returns whether the filter allows all containers.
+func (f ContainerMetricsFilter) IsEmpty() bool {
+	return len(f.names) == 0 && len(f.labels) == 0
+}
+
+// MetricsAllowed returns whether metrics should be gathered for the container.
+func (f ContainerMetricsFilter) MetricsAllowed(c Container) bool {
+	if f.IsEmpty() {
+		return true
+	}
+
+	for _, re := range f.names {
+		if re.MatchString(c.ContainerName()) {
+			return true
+		}
+	}
+
+	if len(f.labels) == 0 {
+		return false
+	}
+
+	labels := LabelsAndAnnotations(c)
+
+	for label, expressions := range f.labels {
+		value, ok := labels[label]
+		if !ok {
+			continue
+		}
+
+		for _, re := range expressions {
+			if re.MatchString(value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func containerEnabledFromLabels(labels map[string]string, disabledByDefault bool) (enabled bool, explicit bool) {
 	label := labels[containerEnableLabel]
 	if label == "" {
@@ -271,3 +271,60 @@ func TestContainerIgnoredPorts(t *testing.T) {
 		})
 	}
 }
+
+func TestContainerMetricsFilter(t *testing.T) {
+	filter, err := NewContainerMetricsFilter([]string{"^web-", "com.example.team=^payments$", "[invalid"})
+	if err == nil {
+		t.Error("NewContainerMetricsFilter() should fail on an invalid regular expression")
+	}
+
+	tests := []struct {
+		name      string
+		container FakeContainer
+		want      bool
+	}{
+		{
+			name:      "name-match",
+			container: FakeContainer{FakeContainerName: "web-frontend"},
+			want:      true,
+		},
+		{
+			name: "label-match",
+			container: FakeContainer{
+				FakeContainerName: "api",
+				FakeLabels:        map[string]string{"com.example.team": "payments"},
+			},
+			want: true,
+		},
+		{
+			name: "label-mismatch",
+			container: FakeContainer{
+				FakeContainerName: "api",
+				FakeLabels:        map[string]string{"com.example.team": "payments-old"},
+			},
+			want: false,
+		},
+		{
+			name:      "no-match",
+			container: FakeContainer{FakeContainerName: "frontend-web"},
+			want:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filter.MetricsAllowed(tt.container); got != tt.want {
+				t.Errorf("MetricsAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	emptyFilter, err := NewContainerMetricsFilter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !emptyFilter.IsEmpty() || !emptyFilter.MetricsAllowed(FakeContainer{FakeContainerName: "any"}) {
+		t.Error("an empty allowlist should allow all containers")
+	}
+}
@@ -50,6 +50,16 @@ const (
 	FactUpdatedAt         = "fact_updated_at"
 )
 
+// Fact sources that could be disabled with DisableSources.
+const (
+	SourcePublicIP         = "public_ip"
+	SourceCloudProvider    = "cloud_provider"
+	SourceContainerRuntime = "container_runtime"
+	SourceAutoUpgrade      = "auto_upgrade"
+)
+
+var errUnknownSource = errors.New("unknown fact source")
+
 var (
 	errAutoUpgradeNotSupported = errors.New("auto upgrade is not supported on this operating system")
 	errUnsupportedOS           = errors.New("unsupported OS")
@@ -73,8 +83,13 @@ type FactProvider struct {
 	hostRootPath   string
 	ipIndicatorURL string
 
-	manualFact map[string]string
-	callbacks  []FactCallback
+	manualFact      map[string]string
+	callbacks       []FactCallback
+	disabledSources map[string]bool
+
+	factsScript   string
+	scriptFacts   map[string]string
+	lastScriptRun scriptRun
 
 	facts           map[string]string
 	lastFactsUpdate time.Time
@@ -112,6 +127,39 @@ func (f *FactProvider) AddCallback(cb FactCallback) {
 	f.callbacks = append(f.callbacks, cb)
 }
 
+// DisableSources disables the collection of some facts. Disabled sources
+// are not gathered at all, which avoid slow or undesired work.
+// Unknown sources are ignored and returned as an error.
+func (f *FactProvider) DisableSources(sources []string) error {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	var errs []error
+
+	for _, source := range sources {
+		switch source {
+		case SourcePublicIP, SourceCloudProvider, SourceContainerRuntime, SourceAutoUpgrade:
+			if f.disabledSources == nil {
+				f.disabledSources = make(map[string]bool)
+			}
+
+			f.disabledSources[source] = true
+		default:
+			errs = append(errs, fmt.Errorf("%w %q", errUnknownSource, source))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// IsSourceDisabled returns whether the given fact source is disabled.
+func (f *FactProvider) IsSourceDisabled(source string) bool {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	return f.disabledSources[source]
+}
+
 // Facts returns the list of facts for this system.
 func (f *FactProvider) Facts(ctx context.Context, maxAge time.Duration) (facts map[string]string, err error) {
 	f.l.Lock()
@@ -163,9 +211,13 @@ func (f *FactProvider) SetFact(key string, value string) {
 }
 
 func (f *FactProvider) updateFacts(ctx context.Context) {
+	f.runFactsScript(ctx)
+
 	newFacts := f.fastUpdateFacts(ctx)
 
-	collectCloudProvidersFacts(ctx, newFacts)
+	if !f.disabledSources[SourceCloudProvider] {
+		collectCloudProvidersFacts(ctx, newFacts)
+	}
 
 	CleanFacts(newFacts)
 
@@ -200,6 +252,11 @@ func (f *FactProvider) fastUpdateFacts(ctx context.Context) map[string]string {
 		}
 	}
 
+	// The facts of the script are the ones known from its last successful run.
+	for k, v := range f.scriptFacts {
+		newFacts[k] = v
+	}
+
 	for k, v := range f.platformFacts() {
 		newFacts[k] = v
 	}
@@ -208,7 +265,7 @@ func (f *FactProvider) fastUpdateFacts(ctx context.Context) map[string]string {
 	newFacts["primary_address"] = primaryAddress
 	newFacts["primary_mac_address"] = primaryMacAddress
 
-	if f.ipIndicatorURL != "" {
+	if f.ipIndicatorURL != "" && !f.disabledSources[SourcePublicIP] {
 		subctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 		defer cancel()
 
@@ -264,13 +321,15 @@ func (f *FactProvider) fastUpdateFacts(ctx context.Context) map[string]string {
 	newFacts[FactUpdatedAt] = time.Now().UTC().Format(time.RFC3339)
 	newFacts["glouton_pid"] = strconv.FormatInt(int64(os.Getpid()), 10)
 
-	autoUpgradeEnabled, err := autoUpgradeIsEnabled(ctx)
-	if !errors.Is(err, errAutoUpgradeNotSupported) {
-		if err != nil {
-			logger.V(1).Printf("Failed to check auto-upgrade status: %v", err)
-		}
+	if !f.disabledSources[SourceAutoUpgrade] {
+		autoUpgradeEnabled, err := autoUpgradeIsEnabled(ctx)
+		if !errors.Is(err, errAutoUpgradeNotSupported) {
+			if err != nil {
+				logger.V(1).Printf("Failed to check auto-upgrade status: %v", err)
+			}
 
-		newFacts["auto_upgrade_enabled"] = strconv.FormatBool(autoUpgradeEnabled)
+			newFacts["auto_upgrade_enabled"] = strconv.FormatBool(autoUpgradeEnabled)
+		}
 	}
 
 	cpu, err := cpu.Info()
@@ -17,6 +17,8 @@
 package facts
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -313,3 +315,35 @@ func Test_tzFromSymlink(t *testing.T) {
 		})
 	}
 }
+
+func TestDisableSources(t *testing.T) {
+	t.Parallel()
+
+	f := NewFacter("", "", "http://127.0.0.1:1/")
+
+	err := f.DisableSources([]string{SourcePublicIP, "does_not_exist", SourceAutoUpgrade})
+	if !errors.Is(err, errUnknownSource) {
+		t.Errorf("DisableSources() error = %v, want %v", err, errUnknownSource)
+	}
+
+	for _, source := range []string{SourcePublicIP, SourceAutoUpgrade} {
+		if !f.IsSourceDisabled(source) {
+			t.Errorf("source %s should be disabled", source)
+		}
+	}
+
+	if f.IsSourceDisabled(SourceCloudProvider) {
+		t.Errorf("source %s should be enabled", SourceCloudProvider)
+	}
+
+	facts, err := f.FastFacts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"public_ip", "auto_upgrade_enabled"} {
+		if _, ok := facts[name]; ok {
+			t.Errorf("fact %s is present but its source is disabled", name)
+		}
+	}
+}
@@ -38,6 +38,9 @@ import (
 // The file should be the output of netstat run as root.
 type NetstatProvider struct {
 	FilePath string
+	// Families restricts the socket families returned: "tcp4", "tcp6", "udp4", "udp6" or "unix".
+	// "tcp" and "udp" match both IPv4 and IPv6. All families are returned when empty.
+	Families []string
 }
 
 // Netstat return a mapping from PID to listening addresses
@@ -52,7 +55,7 @@ func (np NetstatProvider) Netstat(_ context.Context, processes map[int]Process)
 
 		netstatData, errFile = os.ReadFile(np.FilePath)
 		if errFile == nil {
-			netstat = decodeNetstatFile(string(netstatData))
+			netstat = np.decodeNetstatFile(string(netstatData))
 
 			np.cleanRecycledPIDs(netstat, processes, netstatInfo.ModTime())
 		}
@@ -74,7 +77,11 @@ func (np NetstatProvider) mergeNetstats(netstat map[int][]ListenAddress, dynamic
 			continue
 		}
 
-		if c.Status != "LISTEN" {
+		// UDP sockets have no state, the sockets without a remote address are the
+		// ones waiting for datagrams, the others are connected client sockets.
+		isUDPListen := c.Type == syscall.SOCK_DGRAM && c.Raddr.Port == 0
+
+		if c.Status != "LISTEN" && !isUDPListen {
 			continue
 		}
 
@@ -95,6 +102,10 @@ func (np NetstatProvider) mergeNetstats(netstat map[int][]ListenAddress, dynamic
 			protocol += "6"
 		}
 
+		if !np.isFamilyAllowed(protocol) {
+			continue
+		}
+
 		netstat[int(c.Pid)] = addAddress(netstat[int(c.Pid)], ListenAddress{
 			NetworkFamily: protocol,
 			Address:       address,
@@ -146,7 +157,28 @@ func (l ListenAddress) String() string {
 	return fmt.Sprintf("%s:%d", l.Address, l.Port)
 }
 
-func decodeNetstatFile(data string) map[int][]ListenAddress {
+// isFamilyAllowed returns whether addresses of the network family ("tcp", "tcp6", "udp", "udp6"
+// or "unix") should be returned.
+func (np NetstatProvider) isFamilyAllowed(family string) bool {
+	if len(np.Families) == 0 {
+		return true
+	}
+
+	for _, allowed := range np.Families {
+		switch allowed {
+		case family, family + "4":
+			return true
+		case "tcp", "udp":
+			if family == allowed+"6" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (np NetstatProvider) decodeNetstatFile(data string) map[int][]ListenAddress {
 	result := make(map[int][]ListenAddress)
 	lines := strings.Split(data, "\n")
 
@@ -188,6 +220,10 @@ func decodeNetstatFile(data string) map[int][]ListenAddress {
 			port = 0
 		}
 
+		if !np.isFamilyAllowed(protocol) {
+			continue
+		}
+
 		addresses := result[int(pid)]
 		if addresses == nil {
 			addresses = make([]ListenAddress, 0)
@@ -67,6 +67,7 @@ func getMockNetstat() []psutilNet.ConnectionStat {
 		{Fd: 76, Family: 10, Type: 2, Laddr: psutilNet.Addr{IP: "::", Port: 46429}, Raddr: psutilNet.Addr{IP: "::", Port: 0}, Status: "NONE", Uids: []int32{1000, 1000, 1000, 1000}, Pid: 4587},
 		{Fd: 0, Family: 10, Type: 2, Laddr: psutilNet.Addr{IP: "fe80::92d0:93a3:f56:b588", Port: 546}, Raddr: psutilNet.Addr{IP: "FE80:0000:0000:5EFE:0192.0168.0001.0123", Port: 0}, Status: "NONE", Uids: []int32{}, Pid: 0},
 		{Fd: 38, Family: 10, Type: 2, Laddr: psutilNet.Addr{IP: "::", Port: 60918}, Raddr: psutilNet.Addr{IP: "::", Port: 0}, Status: "NONE", Uids: []int32{1000, 1000, 1000, 1000}, Pid: 4587},
+		{Fd: 21, Family: 2, Type: 2, Laddr: psutilNet.Addr{IP: "192.168.1.40", Port: 41234}, Raddr: psutilNet.Addr{IP: "9.9.9.9", Port: 53}, Status: "NONE", Uids: []int32{1000, 1000, 1000, 1000}, Pid: 3191},
 	}
 }
 
@@ -144,7 +145,7 @@ func TestDecodeNetstatFile(t *testing.T) {
 		},
 	}
 
-	got := decodeNetstatFile(fileContent)
+	got := NetstatProvider{}.decodeNetstatFile(fileContent)
 	if len(got) != len(want) {
 		t.Errorf("decodeNetstatFile(...) == %v, want %v", got, want)
 	} else {
@@ -155,12 +156,45 @@ func TestDecodeNetstatFile(t *testing.T) {
 	}
 }
 
+func TestNetstatFamilies(t *testing.T) {
+	np := NetstatProvider{Families: []string{"tcp4", "unix"}}
+
+	for pid, addresses := range np.decodeNetstatFile(fileContent) {
+		for _, addr := range addresses {
+			if addr.NetworkFamily != "tcp" && addr.NetworkFamily != "unix" {
+				t.Errorf("PID %d has address %v of family %s, want only tcp and unix", pid, addr, addr.NetworkFamily)
+			}
+		}
+	}
+
+	tests := []struct {
+		families []string
+		family   string
+		want     bool
+	}{
+		{families: nil, family: "udp6", want: true},
+		{families: []string{"tcp4"}, family: "tcp", want: true},
+		{families: []string{"tcp4"}, family: "tcp6", want: false},
+		{families: []string{"tcp6"}, family: "tcp6", want: true},
+		{families: []string{"tcp"}, family: "tcp6", want: true},
+		{families: []string{"tcp"}, family: "udp", want: false},
+		{families: []string{"unix"}, family: "unix", want: true},
+	}
+
+	for _, tt := range tests {
+		np := NetstatProvider{Families: tt.families}
+		if got := np.isFamilyAllowed(tt.family); got != tt.want {
+			t.Errorf("isFamilyAllowed(%s) with families %v = %v, want %v", tt.family, tt.families, got, tt.want)
+		}
+	}
+}
+
 func TestMergeNetstats(t *testing.T) {
-	netstat := decodeNetstatFile(fileContent)
+	netstat := NetstatProvider{}.decodeNetstatFile(fileContent)
 	mockNetstat := getMockNetstat()
 
 	np := &NetstatProvider{
-		"null",
+		FilePath: "null",
 	}
 
 	np.mergeNetstats(netstat, mockNetstat)
@@ -190,12 +224,21 @@ func TestMergeNetstats(t *testing.T) {
 	if data[0].Port != 4242 || data[0].Address != "0.0.0.0" {
 		t.Errorf("Unexpected created data in netstat results for port 32668. Got %s:%d, want 0.0.0.0:4242", data[0].Address, data[0].Port)
 	}
+
+	cmpAddresses(t, "mergeNetstats(...)[4587]", netstat[4587], []ListenAddress{
+		{NetworkFamily: "udp", Address: "0.0.0.0", Port: 46429},
+		{NetworkFamily: "udp", Address: "0.0.0.0", Port: 60918},
+	})
+
+	if _, ok := netstat[3191]; ok {
+		t.Errorf("PID 3191 only has connected sockets, it shouldn't be in merged Netstat")
+	}
 }
 
 func TestCleanRecycledPIDs(t *testing.T) {
-	netstat := decodeNetstatFile(fileContent)
+	netstat := NetstatProvider{}.decodeNetstatFile(fileContent)
 	np := &NetstatProvider{
-		"null",
+		FilePath: "null",
 	}
 	mockProcesses := make(map[int]Process)
 	modTime, _ := time.Parse(time.RFC3339, "2020-11-01T22:08:41+00:00")
@@ -0,0 +1,150 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package facts
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/bleemeo/glouton/logger"
+	"github.com/bleemeo/glouton/types"
+)
+
+// factsScriptTimeout is the maximum duration of one run of the facts script.
+const factsScriptTimeout = 30 * time.Second
+
+// scriptRun is the result of the last run of the facts script.
+type scriptRun struct {
+	RunAt    time.Time
+	Duration time.Duration
+	Stdout   string
+	Stderr   string
+	Error    string
+}
+
+// SetFactsScript sets a script executed on each refresh of the facts.
+// The script prints "key=value" lines on its standard output,
+// these facts take precedence over the ones of the facts file.
+func (f *FactProvider) SetFactsScript(script string) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	f.factsScript = script
+}
+
+// runFactsScript executes the facts script and updates the script facts.
+// On failure, the previously known script facts are kept.
+func (f *FactProvider) runFactsScript(ctx context.Context) {
+	if f.factsScript == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, factsScriptTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, f.factsScript) //nolint:gosec
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+
+	f.lastScriptRun = scriptRun{
+		RunAt:    start,
+		Duration: time.Since(start),
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+	}
+
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		f.lastScriptRun.Error = err.Error()
+
+		logger.V(1).Printf("Failed to run the facts script %s: %v", f.factsScript, err)
+
+		return
+	}
+
+	f.scriptFacts = parseFactsScriptOutput(stdout.Bytes())
+}
+
+// parseFactsScriptOutput returns the facts from the "key=value" lines of the output.
+// Empty lines and lines starting with "#" are ignored.
+func parseFactsScriptOutput(output []byte) map[string]string {
+	result := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+
+		if !found || key == "" {
+			logger.V(2).Printf("Ignoring invalid line %q in the facts script output", line)
+
+			continue
+		}
+
+		result[key] = strings.TrimSpace(value)
+	}
+
+	return result
+}
+
+// DiagnosticArchive adds the last run of the facts script to the archive.
+func (f *FactProvider) DiagnosticArchive(_ context.Context, archive types.ArchiveWriter) error {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	if f.factsScript == "" {
+		return nil
+	}
+
+	file, err := archive.Create("facts-script.json")
+	if err != nil {
+		return err
+	}
+
+	obj := struct {
+		Script  string
+		LastRun scriptRun
+		Facts   map[string]string
+	}{
+		Script:  f.factsScript,
+		LastRun: f.lastScriptRun,
+		Facts:   f.scriptFacts,
+	}
+
+	enc := json.NewEncoder(file)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(obj)
+}
@@ -0,0 +1,105 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package facts
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseFactsScriptOutput(t *testing.T) {
+	t.Parallel()
+
+	output := `
+# Generated by the deployment tool
+deployment_id=1234
+ environment = production
+url=https://example.com/?a=b
+invalid line
+=no key
+`
+
+	want := map[string]string{
+		"deployment_id": "1234",
+		"environment":   "production",
+		"url":           "https://example.com/?a=b",
+	}
+
+	if diff := cmp.Diff(want, parseFactsScriptOutput([]byte(output))); diff != "" {
+		t.Errorf("parseFactsScriptOutput() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFactsScript(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the test script is a shell script")
+	}
+
+	dir := t.TempDir()
+	factsFile := filepath.Join(dir, "facts.yaml")
+	script := filepath.Join(dir, "facts.sh")
+
+	writeFile := func(path string, content string) {
+		t.Helper()
+
+		if err := os.WriteFile(path, []byte(content), 0o700); err != nil { //nolint:gosec
+			t.Fatal(err)
+		}
+	}
+
+	writeFile(factsFile, "deployment_id: from-file\nteam: ops\n")
+	writeFile(script, "#!/bin/sh\necho deployment_id=42\n")
+
+	f := NewFacter(factsFile, "", "")
+	f.SetFactsScript(script)
+
+	if err := f.DisableSources([]string{SourceCloudProvider, SourceAutoUpgrade}); err != nil {
+		t.Fatal(err)
+	}
+
+	facts, err := f.Facts(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if facts["deployment_id"] != "42" || facts["team"] != "ops" {
+		t.Errorf("deployment_id = %q, team = %q, want 42 and ops", facts["deployment_id"], facts["team"])
+	}
+
+	// A failing script keeps the previously known facts.
+	writeFile(script, "#!/bin/sh\necho deployment_id=43\nexit 1\n")
+
+	facts, err = f.Facts(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if facts["deployment_id"] != "42" {
+		t.Errorf("deployment_id = %q, want 42", facts["deployment_id"])
+	}
+
+	if f.lastScriptRun.Error == "" {
+		t.Error("the error of the last script run should be kept")
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package facts
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bleemeo/glouton/logger"
+)
+
+// FactAppVersion is the fact containing the version read from the version file.
+const FactAppVersion = "app_version"
+
+const versionFilePollInterval = 30 * time.Second
+
+// VersionFileWatcher reads the version of an application from a file, like
+// /etc/app-version, and reports when it changes. The modification time of the
+// file is watched, the file is only read when it changes.
+type VersionFileWatcher struct {
+	path string
+	// onChange is called with the new version. deployed is false for the first
+	// read, when the version wasn't changed by a deployment.
+	onChange func(version string, deployed bool)
+
+	lastModTime time.Time
+	version     string
+	initialized bool
+}
+
+// NewVersionFileWatcher returns a watcher of the version file.
+func NewVersionFileWatcher(path string, onChange func(version string, deployed bool)) *VersionFileWatcher {
+	return &VersionFileWatcher{
+		path:     path,
+		onChange: onChange,
+	}
+}
+
+// Run checks the version file until the context is canceled.
+func (w *VersionFileWatcher) Run(ctx context.Context) error {
+	for {
+		w.check()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(versionFilePollInterval):
+		}
+	}
+}
+
+// check reads the version file if its modification time changed and calls onChange
+// when the version is different. A missing file is the same as an empty version.
+func (w *VersionFileWatcher) check() {
+	var (
+		modTime time.Time
+		version string
+	)
+
+	stat, err := os.Stat(w.path)
+
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		logger.V(1).Printf("Unable to read the version file %s: %v", w.path, err)
+
+		return
+	default:
+		modTime = stat.ModTime()
+
+		if w.initialized && modTime.Equal(w.lastModTime) {
+			return
+		}
+
+		content, err := os.ReadFile(w.path)
+		if err != nil {
+			logger.V(1).Printf("Unable to read the version file %s: %v", w.path, err)
+
+			return
+		}
+
+		// Only the first line is used, the file could contain other information.
+		version, _, _ = strings.Cut(string(content), "\n")
+		version = strings.TrimSpace(version)
+	}
+
+	w.lastModTime = modTime
+
+	if w.initialized && version == w.version {
+		return
+	}
+
+	deployed := w.initialized
+
+	w.initialized = true
+	w.version = version
+
+	if deployed {
+		logger.V(1).Printf("The version in %s changed to %q", w.path, version)
+	}
+
+	w.onChange(version, deployed)
+}
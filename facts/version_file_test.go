@@ -0,0 +1,87 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package facts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type versionChange struct {
+	Version  string
+	Deployed bool
+}
+
+func TestVersionFileWatcher(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "app-version")
+
+	var changes []versionChange
+
+	watcher := NewVersionFileWatcher(path, func(version string, deployed bool) {
+		changes = append(changes, versionChange{Version: version, Deployed: deployed})
+	})
+
+	writeVersion := func(content string, modTime time.Time) {
+		t.Helper()
+
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t0 := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	writeVersion("1.0.0\n", t0)
+	watcher.check()
+
+	// The modification time didn't change, the file isn't read again.
+	writeVersion("ignored\n", t0)
+	watcher.check()
+
+	writeVersion("1.1.0\nbuilt by CI\n", t0.Add(time.Minute))
+	watcher.check()
+
+	// Same version with a new modification time isn't a deployment.
+	writeVersion("1.1.0\n", t0.Add(2*time.Minute))
+	watcher.check()
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	watcher.check()
+
+	want := []versionChange{
+		{Version: "1.0.0", Deployed: false},
+		{Version: "1.1.0", Deployed: true},
+		{Version: "", Deployed: true},
+	}
+
+	if diff := cmp.Diff(want, changes); diff != "" {
+		t.Errorf("changes mismatch (-want +got):\n%s", diff)
+	}
+}
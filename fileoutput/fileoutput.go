@@ -0,0 +1,312 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fileoutput writes the metrics to a local file in JSON lines, with a size-based rotation.
+package fileoutput
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/bleemeo/glouton/logger"
+	"github.com/bleemeo/glouton/types"
+	"github.com/bleemeo/glouton/utils/pointsbuffer"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultMaxPendingPoints = 100000
+	defaultMaxFileSize      = 100 * 1024 * 1024
+	defaultMaxFiles         = 5
+	defaultBatchSize        = 1000
+
+	// writeInterval is the delay between two writes of the pending points.
+	writeInterval = 10 * time.Second
+	// Delays between two attempts to write the points when the file isn't writable.
+	minRetryDelay = 10 * time.Second
+	maxRetryDelay = 5 * time.Minute
+)
+
+//nolint:gochecknoglobals
+var pendingPointsDesc = prometheus.NewDesc(
+	"glouton_file_output_pending_points",
+	"Number of points waiting to be written to the file",
+	nil,
+	nil,
+)
+
+var errMissingPath = errors.New("the path of the file is missing")
+
+// Store is the interface used by the client to access the Metric Store.
+type Store interface {
+	AddNotifiee(cb func([]types.MetricPoint)) int
+	RemoveNotifiee(id int)
+}
+
+// Options are the options of the file output.
+type Options struct {
+	// Path is the file the points are appended to.
+	Path string
+	// MaxFileSize is the size in bytes after which the file is rotated.
+	MaxFileSize int64
+	// MaxFiles is the number of rotated files kept, named Path.1 to Path.MaxFiles.
+	MaxFiles int
+	// MaxPendingPoints is the number of points kept while the file isn't writable.
+	// The oldest points are dropped when the buffer is full.
+	MaxPendingPoints int
+}
+
+// line is a point as written in the file.
+type line struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+	Value  float64           `json:"value"`
+	Time   time.Time         `json:"time"`
+}
+
+// Client writes the points of the store to a file.
+type Client struct {
+	opts         Options
+	store        Store
+	buffer       *pointsbuffer.Buffer
+	maxBatchSize int
+
+	// file and fileSize are only used by the Run goroutine.
+	file     *os.File
+	fileSize int64
+	lastErr  error
+}
+
+// New returns a file output.
+func New(opts Options, store Store) (*Client, error) {
+	if opts.Path == "" {
+		return nil, errMissingPath
+	}
+
+	if opts.MaxFileSize <= 0 {
+		opts.MaxFileSize = defaultMaxFileSize
+	}
+
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = defaultMaxFiles
+	}
+
+	maxPendingPoints := opts.MaxPendingPoints
+	if maxPendingPoints <= 0 {
+		maxPendingPoints = defaultMaxPendingPoints
+	}
+
+	return &Client{
+		opts:         opts,
+		store:        store,
+		buffer:       pointsbuffer.New("file output", maxPendingPoints),
+		maxBatchSize: defaultBatchSize,
+	}, nil
+}
+
+// formatLine returns the JSON line of the point. It returns false
+// for points that can't be encoded, like NaN.
+func formatLine(point types.MetricPoint) ([]byte, bool) {
+	if math.IsNaN(point.Value) || math.IsInf(point.Value, 0) {
+		return nil, false
+	}
+
+	lbls := make(map[string]string, len(point.Labels))
+
+	for name, value := range point.Labels {
+		if name != types.LabelName {
+			lbls[name] = value
+		}
+	}
+
+	data, err := json.Marshal(line{
+		Name:   point.Labels[types.LabelName],
+		Labels: lbls,
+		Value:  point.Value,
+		Time:   point.Time,
+	})
+	if err != nil {
+		return nil, false
+	}
+
+	return append(data, '\n'), true
+}
+
+// openFile opens the file in append mode if it isn't already open.
+func (c *Client) openFile() error {
+	if c.file != nil {
+		return nil
+	}
+
+	file, err := os.OpenFile(c.opts.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+
+		return err
+	}
+
+	c.file = file
+	c.fileSize = info.Size()
+
+	return nil
+}
+
+func (c *Client) closeFile() {
+	if c.file == nil {
+		return
+	}
+
+	_ = c.file.Close()
+	c.file = nil
+}
+
+// rotate closes the file and shifts the rotated files: Path becomes Path.1,
+// Path.1 becomes Path.2 and so on. The oldest file is removed.
+func (c *Client) rotate() error {
+	c.closeFile()
+
+	for i := c.opts.MaxFiles - 1; i >= 1; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", c.opts.Path, i), fmt.Sprintf("%s.%d", c.opts.Path, i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return os.Rename(c.opts.Path, c.opts.Path+".1")
+}
+
+// write appends a batch of points to the file, rotating it when it's too large.
+func (c *Client) write(points []types.MetricPoint) error {
+	if c.fileSize >= c.opts.MaxFileSize {
+		if err := c.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.openFile(); err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(c.file)
+
+	for _, point := range points {
+		data, ok := formatLine(point)
+		if !ok {
+			continue
+		}
+
+		n, err := writer.Write(data)
+		c.fileSize += int64(n)
+
+		if err != nil {
+			c.closeFile()
+
+			return err
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		c.closeFile()
+
+		return err
+	}
+
+	return nil
+}
+
+// writePending writes the pending points by batches. It returns false when
+// a batch failed to be written and must be retried later.
+func (c *Client) writePending(ctx context.Context) bool {
+	for ctx.Err() == nil && c.buffer.Len() > 0 {
+		batch := c.buffer.Batch(c.maxBatchSize)
+
+		if err := c.write(batch.Points); err != nil {
+			if c.lastErr == nil {
+				logger.Printf("Fail to write the metrics to %s: %v", c.opts.Path, err)
+			} else {
+				logger.V(2).Printf("Fail to write the metrics to %s: %v", c.opts.Path, err)
+			}
+
+			c.lastErr = err
+
+			return false
+		}
+
+		if c.lastErr != nil {
+			logger.Printf("The metrics are written to %s again", c.opts.Path)
+
+			c.lastErr = nil
+		}
+
+		c.buffer.Remove(batch)
+	}
+
+	return true
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *Client) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pendingPointsDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *Client) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(pendingPointsDesc, prometheus.GaugeValue, float64(c.buffer.Len()))
+}
+
+// Run writes the points of the store until the context is cancelled.
+func (c *Client) Run(ctx context.Context) error {
+	notifieeID := c.store.AddNotifiee(c.buffer.Add)
+	defer c.store.RemoveNotifiee(notifieeID)
+
+	defer c.closeFile()
+
+	retryDelay := minRetryDelay
+
+	for ctx.Err() == nil {
+		delay := writeInterval
+
+		if c.writePending(ctx) {
+			retryDelay = minRetryDelay
+		} else {
+			// Exponential backoff while the file isn't writable.
+			delay = retryDelay
+			retryDelay = min(2*retryDelay, maxRetryDelay)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+	}
+
+	// Write the remaining points before stopping.
+	c.writePending(context.Background())
+
+	return nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fileoutput
+
+import (
+	"bufio"
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bleemeo/glouton/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	var lines []string
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return lines
+}
+
+func TestWritePending(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "metrics.jsonl")
+
+	client, err := New(Options{Path: path}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(client.closeFile)
+
+	t0 := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	client.buffer.Add([]types.MetricPoint{
+		{
+			Point:  types.Point{Time: t0, Value: 42.5},
+			Labels: map[string]string{types.LabelName: "cpu_used"},
+		},
+		{
+			Point:  types.Point{Time: t0, Value: math.NaN()},
+			Labels: map[string]string{types.LabelName: "cpu_idle"},
+		},
+		{
+			Point:  types.Point{Time: t0, Value: 1024},
+			Labels: map[string]string{types.LabelName: "net_bits_recv", types.LabelItem: "eth0"},
+		},
+	})
+
+	if !client.writePending(context.Background()) {
+		t.Fatal("writePending failed")
+	}
+
+	want := []string{
+		`{"name":"cpu_used","labels":{},"value":42.5,"time":"2024-03-01T10:00:00Z"}`,
+		`{"name":"net_bits_recv","labels":{"item":"eth0"},"value":1024,"time":"2024-03-01T10:00:00Z"}`,
+	}
+
+	if diff := cmp.Diff(want, readLines(t, path)); diff != "" {
+		t.Errorf("lines mismatch (-want +got):\n%s", diff)
+	}
+
+	if n := client.buffer.Len(); n != 0 {
+		t.Errorf("buffer.Len() = %d, want 0", n)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "metrics.jsonl")
+
+	// Each line is larger than the maximum size, so the file is rotated before each write.
+	client, err := New(Options{Path: path, MaxFileSize: 10, MaxFiles: 2}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(client.closeFile)
+
+	for i := range 4 {
+		client.buffer.Add([]types.MetricPoint{
+			{
+				Point:  types.Point{Time: time.Unix(int64(i), 0).UTC(), Value: float64(i)},
+				Labels: map[string]string{types.LabelName: "counter"},
+			},
+		})
+
+		if !client.writePending(context.Background()) {
+			t.Fatal("writePending failed")
+		}
+	}
+
+	wantByFile := map[string]string{
+		path:        `{"name":"counter","labels":{},"value":3,"time":"1970-01-01T00:00:03Z"}`,
+		path + ".1": `{"name":"counter","labels":{},"value":2,"time":"1970-01-01T00:00:02Z"}`,
+		path + ".2": `{"name":"counter","labels":{},"value":1,"time":"1970-01-01T00:00:01Z"}`,
+	}
+
+	for file, want := range wantByFile {
+		if diff := cmp.Diff([]string{want}, readLines(t, file)); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", file, diff)
+		}
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("%s.3 should not exist, got err=%v", path, err)
+	}
+}
+
+func TestAddPointsDropOldest(t *testing.T) {
+	t.Parallel()
+
+	client, err := New(Options{Path: "unused", MaxPendingPoints: 3}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 5 {
+		client.buffer.Add([]types.MetricPoint{{Point: types.Point{Value: float64(i)}}})
+	}
+
+	got := make([]float64, 0, 3)
+
+	for _, point := range client.buffer.Batch(client.maxBatchSize).Points {
+		got = append(got, point.Value)
+	}
+
+	if diff := cmp.Diff([]float64{2, 3, 4}, got); diff != "" {
+		t.Errorf("pending points mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMissingPath(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New(Options{}, nil); err == nil {
+		t.Error("New() succeeded without a path")
+	}
+}
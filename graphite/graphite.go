@@ -0,0 +1,348 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package graphite sends the metrics to a Graphite server using the plaintext protocol.
+package graphite
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bleemeo/glouton/logger"
+	"github.com/bleemeo/glouton/types"
+	"github.com/bleemeo/glouton/utils/pointsbuffer"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultMaxPendingPoints = 100000
+	defaultBatchSize        = 1000
+	// DefaultPathTemplate is the template used when none is configured.
+	DefaultPathTemplate = "{instance}.{__name__}.{*}"
+
+	connectTimeout = 10 * time.Second
+	writeTimeout   = 30 * time.Second
+
+	// Delays between two attempts to send the points when the server isn't reachable.
+	minRetryDelay = 10 * time.Second
+	maxRetryDelay = 5 * time.Minute
+)
+
+//nolint:gochecknoglobals
+var (
+	pendingPointsDesc = prometheus.NewDesc(
+		"glouton_graphite_pending_points",
+		"Number of points waiting to be sent to Graphite",
+		nil,
+		nil,
+	)
+	// invalidPathCharacters matches the characters replaced in the path segments.
+	invalidPathCharacters = regexp.MustCompile(`[^a-zA-Z0-9_\-]`)
+)
+
+var errInvalidTemplate = errors.New("invalid path template")
+
+// Store is the interface used by the client to access the Metric Store.
+type Store interface {
+	AddNotifiee(cb func([]types.MetricPoint)) int
+	RemoveNotifiee(id int)
+}
+
+// Options are the options of the Graphite client.
+type Options struct {
+	// Address is the host:port of the carbon plaintext receiver.
+	Address string
+	// PathTemplate describes how the labels are translated into the metric path.
+	// It's a list of segments separated by dots. A segment "{label}" is replaced by
+	// the value of the label and is skipped when the label is missing. The segment
+	// "{*}" is replaced by the values of the labels not used by other segments, sorted
+	// by label name. Other segments are kept as is.
+	PathTemplate string
+	// MaxPendingPoints is the number of points kept while Graphite is unreachable.
+	// The oldest points are dropped when the buffer is full.
+	MaxPendingPoints int
+}
+
+// templateSegment is a segment of the path template.
+type templateSegment struct {
+	// label is the name of the label for "{label}" segments.
+	label string
+	// literal is the value of segments which aren't a label.
+	literal string
+	// others is true for the "{*}" segment.
+	others bool
+}
+
+// Client sends the points of the store to Graphite.
+type Client struct {
+	opts         Options
+	store        Store
+	template     []templateSegment
+	buffer       *pointsbuffer.Buffer
+	maxBatchSize int
+
+	// conn is only used by the Run goroutine.
+	conn    net.Conn
+	lastErr error
+}
+
+// New returns a Graphite client.
+func New(opts Options, store Store) (*Client, error) {
+	if opts.PathTemplate == "" {
+		opts.PathTemplate = DefaultPathTemplate
+	}
+
+	template, err := parseTemplate(opts.PathTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	maxPendingPoints := opts.MaxPendingPoints
+	if maxPendingPoints <= 0 {
+		maxPendingPoints = defaultMaxPendingPoints
+	}
+
+	return &Client{
+		opts:         opts,
+		store:        store,
+		template:     template,
+		buffer:       pointsbuffer.New("Graphite", maxPendingPoints),
+		maxBatchSize: defaultBatchSize,
+	}, nil
+}
+
+// parseTemplate splits the path template in segments.
+func parseTemplate(template string) ([]templateSegment, error) {
+	parts := strings.Split(template, ".")
+	segments := make([]templateSegment, 0, len(parts))
+
+	for _, part := range parts {
+		switch {
+		case part == "":
+			return nil, fmt.Errorf("%w: empty segment in %q", errInvalidTemplate, template)
+		case part == "{*}":
+			segments = append(segments, templateSegment{others: true})
+		case strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}"):
+			segments = append(segments, templateSegment{label: part[1 : len(part)-1]})
+		case strings.ContainsAny(part, "{}"):
+			return nil, fmt.Errorf("%w: invalid segment %q", errInvalidTemplate, part)
+		default:
+			segments = append(segments, templateSegment{literal: part})
+		}
+	}
+
+	return segments, nil
+}
+
+// path returns the Graphite path of the metric with the given labels.
+func (c *Client) path(lbls map[string]string) string {
+	used := make(map[string]bool, len(c.template))
+
+	for _, segment := range c.template {
+		if segment.label != "" {
+			used[segment.label] = true
+		}
+	}
+
+	parts := make([]string, 0, len(c.template)+len(lbls))
+
+	for _, segment := range c.template {
+		switch {
+		case segment.others:
+			names := make([]string, 0, len(lbls))
+
+			for name := range lbls {
+				// Meta labels, like __name__, are only used when explicitly referenced.
+				if !used[name] && !strings.HasPrefix(name, "__") {
+					names = append(names, name)
+				}
+			}
+
+			sort.Strings(names)
+
+			for _, name := range names {
+				if value := lbls[name]; value != "" {
+					parts = append(parts, sanitize(value))
+				}
+			}
+		case segment.label != "":
+			if value := lbls[segment.label]; value != "" {
+				parts = append(parts, sanitize(value))
+			}
+		default:
+			parts = append(parts, segment.literal)
+		}
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// sanitize replaces the characters that aren't allowed in a path segment.
+func sanitize(value string) string {
+	return invalidPathCharacters.ReplaceAllString(value, "_")
+}
+
+// formatLine returns the plaintext line of the point. It returns false
+// for points that can't be sent, like NaN.
+func (c *Client) formatLine(point types.MetricPoint) (string, bool) {
+	if math.IsNaN(point.Value) || math.IsInf(point.Value, 0) {
+		return "", false
+	}
+
+	path := c.path(point.Labels)
+	if path == "" {
+		return "", false
+	}
+
+	return fmt.Sprintf(
+		"%s %s %d\n",
+		path,
+		strconv.FormatFloat(point.Value, 'f', -1, 64),
+		point.Time.Unix(),
+	), true
+}
+
+// send writes a batch of points on the connection, connecting first if needed.
+// The connection is closed on error, it will be re-opened on the next send.
+func (c *Client) send(ctx context.Context, points []types.MetricPoint) error {
+	if c.conn == nil {
+		dialer := net.Dialer{Timeout: connectTimeout}
+
+		conn, err := dialer.DialContext(ctx, "tcp", c.opts.Address)
+		if err != nil {
+			return err
+		}
+
+		c.conn = conn
+	}
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		c.closeConn()
+
+		return err
+	}
+
+	writer := bufio.NewWriter(c.conn)
+
+	for _, point := range points {
+		line, ok := c.formatLine(point)
+		if !ok {
+			continue
+		}
+
+		if _, err := writer.WriteString(line); err != nil {
+			c.closeConn()
+
+			return err
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		c.closeConn()
+
+		return err
+	}
+
+	return nil
+}
+
+func (c *Client) closeConn() {
+	if c.conn == nil {
+		return
+	}
+
+	_ = c.conn.Close()
+	c.conn = nil
+}
+
+// sendPending sends the pending points by batches. It returns false when
+// a batch failed to be sent and must be retried later.
+func (c *Client) sendPending(ctx context.Context) bool {
+	for ctx.Err() == nil && c.buffer.Len() > 0 {
+		batch := c.buffer.Batch(c.maxBatchSize)
+
+		if err := c.send(ctx, batch.Points); err != nil {
+			if c.lastErr == nil {
+				logger.Printf("Fail to send the metrics to Graphite: %v", err)
+			} else {
+				logger.V(2).Printf("Fail to send the metrics to Graphite: %v", err)
+			}
+
+			c.lastErr = err
+
+			return false
+		}
+
+		if c.lastErr != nil {
+			logger.Printf("Graphite is reachable again")
+
+			c.lastErr = nil
+		}
+
+		c.buffer.Remove(batch)
+	}
+
+	return true
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *Client) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pendingPointsDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *Client) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(pendingPointsDesc, prometheus.GaugeValue, float64(c.buffer.Len()))
+}
+
+// Run sends the points of the store until the context is cancelled.
+func (c *Client) Run(ctx context.Context) error {
+	notifieeID := c.store.AddNotifiee(c.buffer.Add)
+	defer c.store.RemoveNotifiee(notifieeID)
+
+	defer c.closeConn()
+
+	retryDelay := minRetryDelay
+
+	for ctx.Err() == nil {
+		delay := minRetryDelay
+
+		if c.sendPending(ctx) {
+			retryDelay = minRetryDelay
+		} else {
+			// Exponential backoff while Graphite is unreachable.
+			delay = retryDelay
+			retryDelay = min(2*retryDelay, maxRetryDelay)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,297 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphite
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"math"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bleemeo/glouton/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeCarbon is a carbon plaintext receiver which records the lines received.
+type fakeCarbon struct {
+	listener net.Listener
+
+	l     sync.Mutex
+	lines []string
+	conns []net.Conn
+}
+
+func newFakeCarbon(t *testing.T) *fakeCarbon {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	carbon := &fakeCarbon{listener: listener}
+
+	go carbon.serve()
+
+	t.Cleanup(carbon.Close)
+
+	return carbon
+}
+
+func (f *fakeCarbon) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		f.l.Lock()
+		f.conns = append(f.conns, conn)
+		f.l.Unlock()
+
+		go func() {
+			scanner := bufio.NewScanner(conn)
+
+			for scanner.Scan() {
+				f.l.Lock()
+				f.lines = append(f.lines, scanner.Text())
+				f.l.Unlock()
+			}
+		}()
+	}
+}
+
+// waitLines waits until count lines were received and returns them.
+func (f *fakeCarbon) waitLines(t *testing.T, count int) []string {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		f.l.Lock()
+		lines := append([]string(nil), f.lines...)
+		f.l.Unlock()
+
+		if len(lines) >= count {
+			return lines
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("timeout while waiting for %d lines", count)
+
+	return nil
+}
+
+// closeConns closes the connections accepted so far.
+func (f *fakeCarbon) closeConns() {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	for _, conn := range f.conns {
+		conn.Close()
+	}
+
+	f.conns = nil
+}
+
+func (f *fakeCarbon) Close() {
+	f.listener.Close()
+	f.closeConns()
+}
+
+func TestPath(t *testing.T) {
+	t.Parallel()
+
+	lbls := map[string]string{
+		types.LabelName:     "disk_used_perc",
+		types.LabelInstance: "server.example.com:8015",
+		types.LabelItem:     "/home",
+		"device":            "sda1",
+		"__meta_something":  "ignored",
+	}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{
+			template: DefaultPathTemplate,
+			want:     "server_example_com_8015.disk_used_perc.sda1._home",
+		},
+		{
+			template: "glouton.{__name__}.{item}",
+			want:     "glouton.disk_used_perc._home",
+		},
+		{
+			template: "{missing}.{__name__}",
+			want:     "disk_used_perc",
+		},
+		{
+			template: "servers.{instance}.{device}.{__name__}.{*}",
+			want:     "servers.server_example_com_8015.sda1.disk_used_perc._home",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			t.Parallel()
+
+			client, err := New(Options{PathTemplate: tt.template}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := client.path(lbls); got != tt.want {
+				t.Errorf("path() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidTemplate(t *testing.T) {
+	t.Parallel()
+
+	for _, template := range []string{"a..b", "{__name__", "a.{b}c"} {
+		if _, err := New(Options{PathTemplate: template}, nil); !errors.Is(err, errInvalidTemplate) {
+			t.Errorf("New(%q) error = %v, want %v", template, err, errInvalidTemplate)
+		}
+	}
+}
+
+func TestSendPending(t *testing.T) {
+	t.Parallel()
+
+	carbon := newFakeCarbon(t)
+
+	client, err := New(Options{Address: carbon.listener.Addr().String(), PathTemplate: "{__name__}.{*}"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(client.closeConn)
+
+	t0 := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	client.buffer.Add([]types.MetricPoint{
+		{
+			Point:  types.Point{Time: t0, Value: 42.5},
+			Labels: map[string]string{types.LabelName: "cpu_used"},
+		},
+		{
+			Point:  types.Point{Time: t0, Value: math.NaN()},
+			Labels: map[string]string{types.LabelName: "cpu_idle"},
+		},
+		{
+			Point:  types.Point{Time: t0, Value: 1024},
+			Labels: map[string]string{types.LabelName: "net_bits_recv", types.LabelItem: "eth0"},
+		},
+	})
+
+	if !client.sendPending(context.Background()) {
+		t.Fatal("sendPending failed")
+	}
+
+	want := []string{
+		"cpu_used 42.5 1709287200",
+		"net_bits_recv.eth0 1024 1709287200",
+	}
+
+	if diff := cmp.Diff(want, carbon.waitLines(t, 2)); diff != "" {
+		t.Errorf("lines mismatch (-want +got):\n%s", diff)
+	}
+
+	if n := client.buffer.Len(); n != 0 {
+		t.Errorf("buffer.Len() = %d, want 0", n)
+	}
+}
+
+func TestSendPendingReconnect(t *testing.T) {
+	t.Parallel()
+
+	carbon := newFakeCarbon(t)
+
+	// Reserve an address with nothing listening on it.
+	unusedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unusedAddress := unusedListener.Addr().String()
+	unusedListener.Close()
+
+	client, err := New(Options{Address: unusedAddress}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(client.closeConn)
+
+	client.buffer.Add([]types.MetricPoint{
+		{
+			Point:  types.Point{Time: time.Unix(1709287200, 0), Value: 1},
+			Labels: map[string]string{types.LabelName: "agent_status"},
+		},
+	})
+
+	if client.sendPending(context.Background()) {
+		t.Fatal("sendPending succeeded without server")
+	}
+
+	if n := client.buffer.Len(); n != 1 {
+		t.Fatalf("buffer.Len() = %d, want 1", n)
+	}
+
+	client.opts.Address = carbon.listener.Addr().String()
+
+	if !client.sendPending(context.Background()) {
+		t.Fatal("sendPending failed")
+	}
+
+	if diff := cmp.Diff([]string{"agent_status 1 1709287200"}, carbon.waitLines(t, 1)); diff != "" {
+		t.Errorf("lines mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAddPointsDropOldest(t *testing.T) {
+	t.Parallel()
+
+	client, err := New(Options{MaxPendingPoints: 3}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 5 {
+		client.buffer.Add([]types.MetricPoint{{Point: types.Point{Value: float64(i)}}})
+	}
+
+	got := make([]float64, 0, 3)
+
+	for _, point := range client.buffer.Batch(client.maxBatchSize).Points {
+		got = append(got, point.Value)
+	}
+
+	if diff := cmp.Diff([]float64{2, 3, 4}, got); diff != "" {
+		t.Errorf("pending points mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -18,6 +18,7 @@ package influxdb
 
 import (
 	"context"
+	"errors"
 	"math"
 	"sync"
 	"time"
@@ -25,49 +26,113 @@ import (
 	"github.com/bleemeo/glouton/logger"
 	"github.com/bleemeo/glouton/store"
 	"github.com/bleemeo/glouton/types"
+	"github.com/bleemeo/glouton/utils/pointsbuffer"
 
 	influxDBClient "github.com/influxdata/influxdb1-client/v2"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 const (
 	defaultMaxPendingPoints = 100000
 	defaultBatchSize        = 1000
+
+	// Delays between two attempts to send the points when the server isn't reachable.
+	minRetryDelay = 10 * time.Second
+	maxRetryDelay = 5 * time.Minute
+)
+
+//nolint:gochecknoglobals
+var pendingPointsDesc = prometheus.NewDesc(
+	"glouton_influxdb_pending_points",
+	"Number of points waiting to be sent to the InfluxDB server",
+	nil,
+	nil,
 )
 
+var errUnexpectedClient = errors.New("the InfluxDB client doesn't support queries")
+
+// Options are the options specific to InfluxDB 2.x.
+type Options struct {
+	// Version is the major version of the InfluxDB server, 1 or 2.
+	Version int
+	// Token, Org and Bucket are used by InfluxDB 2.x.
+	Token  string
+	Org    string
+	Bucket string
+	// Precision of the timestamps sent: "s", "ms", "us" or "ns" (the default).
+	Precision string
+	// MaxPendingPoints is the number of points kept while the server is unreachable.
+	// The oldest points are dropped when the buffer is full.
+	MaxPendingPoints int
+}
+
+// writeClient is the part of the InfluxDB client used to send the points.
+type writeClient interface {
+	Ping(timeout time.Duration) (time.Duration, string, error)
+	Write(bp influxDBClient.BatchPoints) error
+}
+
 // Client is an influxdb client for Bleemeo Cloud platform.
 type Client struct {
 	serverAddress       string
 	dataBaseName        string
+	options             Options
+	precision           string
 	store               *store.Store
 	influxDBBatchPoints influxDBClient.BatchPoints
 	additionalTags      map[string]string
-	maxPendingPoints    int
+	buffer              *pointsbuffer.Buffer
 	maxBatchSize        int
 	sendPointsState     struct {
 		err       error
 		hasChange bool
 	}
 
-	lock                 sync.Mutex
-	gloutonPendingPoints []types.MetricPoint
-	influxClient         influxDBClient.Client
+	lock         sync.Mutex
+	influxClient writeClient
 }
 
 // New create a new influxDB client.
-func New(serverAddress, dataBaseName string, storeAgent *store.Store, additionalTags map[string]string) *Client {
+// With InfluxDB 2.x (options.Version is 2), the points are written to options.Bucket
+// of options.Org using the token authentication, dataBaseName is unused.
+func New(serverAddress, dataBaseName string, storeAgent *store.Store, additionalTags map[string]string, options Options) *Client {
+	maxPendingPoints := options.MaxPendingPoints
+	if maxPendingPoints <= 0 {
+		maxPendingPoints = defaultMaxPendingPoints
+	}
+
 	return &Client{
-		serverAddress:    serverAddress,
-		dataBaseName:     dataBaseName,
-		influxClient:     nil,
-		store:            storeAgent,
-		additionalTags:   additionalTags,
-		maxPendingPoints: defaultMaxPendingPoints,
-		maxBatchSize:     defaultBatchSize,
+		serverAddress:  serverAddress,
+		dataBaseName:   dataBaseName,
+		options:        options,
+		precision:      batchPrecision(options.Precision),
+		influxClient:   nil,
+		store:          storeAgent,
+		additionalTags: additionalTags,
+		buffer:         pointsbuffer.New("InfluxDB", maxPendingPoints),
+		maxBatchSize:   defaultBatchSize,
+	}
+}
+
+// batchPrecision returns the precision used by the batch points. The client
+// uses "u" for microseconds and defaults to nanoseconds.
+func batchPrecision(precision string) string {
+	switch precision {
+	case "s", "ms":
+		return precision
+	case "us", "u":
+		return "u"
+	default:
+		return "ns"
 	}
 }
 
 // doConnect connects an influxDB client to the server and returns true if the connection is established.
 func (c *Client) doConnect() error {
+	if c.options.Version == 2 {
+		return c.doConnectV2()
+	}
+
 	// Create the influxBD client
 	if c.influxClient == nil {
 		influxClient, err := influxDBClient.NewHTTPClient(influxDBClient.HTTPConfig{
@@ -88,11 +153,16 @@ func (c *Client) doConnect() error {
 		return pingErr
 	}
 
+	queryClient, ok := c.influxClient.(influxDBClient.Client)
+	if !ok {
+		return errUnexpectedClient
+	}
+
 	// Create the database
 	query := influxDBClient.Query{
 		Command: "CREATE DATABASE " + c.dataBaseName,
 	}
-	answer, err := c.influxClient.Query(query)
+	answer, err := queryClient.Query(query)
 	// If the query creation failed
 	if err != nil {
 		return err
@@ -106,7 +176,7 @@ func (c *Client) doConnect() error {
 	// If the query and the answer succed the database is created and we create a BatchPoints
 	bp, _ := influxDBClient.NewBatchPoints(influxDBClient.BatchPointsConfig{
 		Database:  c.dataBaseName,
-		Precision: "s",
+		Precision: c.precision,
 	})
 	c.influxDBBatchPoints = bp
 
@@ -115,6 +185,28 @@ func (c *Client) doConnect() error {
 	return nil
 }
 
+// doConnectV2 creates the InfluxDB 2.x client and checks the health of the server.
+// With InfluxDB 2.x, the bucket must already exist.
+func (c *Client) doConnectV2() error {
+	if c.influxClient == nil {
+		c.influxClient = newV2Client(c.serverAddress, c.options.Token, c.options.Org, c.options.Bucket)
+
+		logger.V(2).Printf("InfluxDB 2.x client created")
+	}
+
+	_, _, pingErr := c.influxClient.Ping(5 * time.Second)
+	if pingErr != nil {
+		return pingErr
+	}
+
+	bp, _ := influxDBClient.NewBatchPoints(influxDBClient.BatchPointsConfig{
+		Precision: c.precision,
+	})
+	c.influxDBBatchPoints = bp
+
+	return nil
+}
+
 // connect tries to connect the influxDB client to the server and create the database.
 // connect retries this operation after a delay if it fails.
 func (c *Client) connect(ctx context.Context) {
@@ -142,23 +234,6 @@ func (c *Client) connect(ctx context.Context) {
 	}
 }
 
-// addPoints adds metrics points to the client attribute BleemeopendingPoints.
-func (c *Client) addPoints(points []types.MetricPoint) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-
-	switch {
-	case len(points) >= c.maxPendingPoints:
-		c.gloutonPendingPoints = make([]types.MetricPoint, c.maxPendingPoints)
-		copy(c.gloutonPendingPoints, points[len(points)-c.maxPendingPoints:])
-	case len(c.gloutonPendingPoints)+len(points) > c.maxPendingPoints:
-		c.gloutonPendingPoints = append(c.gloutonPendingPoints[:0], c.gloutonPendingPoints[len(points):]...)
-		c.gloutonPendingPoints = append(c.gloutonPendingPoints, points...)
-	default:
-		c.gloutonPendingPoints = append(c.gloutonPendingPoints, points...)
-	}
-}
-
 // convertMetricPoint convert a gloutonMetricPoint in influxDBClient.Point.
 func convertMetricPoint(metricPoint types.MetricPoint, additionalTags map[string]string) (*influxDBClient.Point, error) {
 	measurement := metricPoint.Labels[types.LabelName]
@@ -186,38 +261,28 @@ func (c *Client) convertPendingPoints() {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	nbFailConversion := 0
-	points := c.influxDBBatchPoints.Points()
-
-	if len(points) >= c.maxBatchSize {
+	available := c.maxBatchSize - len(c.influxDBBatchPoints.Points())
+	if available <= 0 {
 		logger.V(2).Printf("The influxDBBatchPoint is already full")
 
 		return
 	}
 
-	for i, metricPoint := range c.gloutonPendingPoints {
+	batch := c.buffer.Batch(available)
+
+	for _, metricPoint := range batch.Points {
 		pt, err := convertMetricPoint(metricPoint, c.additionalTags)
 		if err != nil {
 			logger.V(2).Printf("Error: impossible to create an influxMetricPoint, the %s metric won't be sent to the influxdb server", metricPoint.Labels[types.LabelName])
 
-			nbFailConversion++
-
 			continue
 		}
 
-		nbConvertPoints := i - nbFailConversion
-		if nbConvertPoints >= c.maxBatchSize {
-			logger.V(2).Printf("The influxDBBatchPoint is full: stop converting points")
-
-			c.gloutonPendingPoints = append(c.gloutonPendingPoints[:0], c.gloutonPendingPoints[i:]...)
-
-			return
-		}
-
 		c.influxDBBatchPoints.AddPoint(pt)
 	}
 
-	c.gloutonPendingPoints = c.gloutonPendingPoints[:0]
+	// The points are kept in the influxDBBatchPoint until they are sent.
+	c.buffer.Remove(batch)
 }
 
 // sendPoints sends points cointain in the influxDBBatchPoint.
@@ -248,10 +313,12 @@ func (c *Client) sendPoints() {
 	// to receive the new points
 	newBp, _ := influxDBClient.NewBatchPoints(influxDBClient.BatchPointsConfig{
 		Database:  c.dataBaseName,
-		Precision: "s",
+		Precision: c.precision,
 	})
 
+	c.lock.Lock()
 	c.influxDBBatchPoints = newBp
+	c.lock.Unlock()
 
 	if c.sendPointsState.err != nil {
 		c.sendPointsState.err = nil
@@ -303,23 +370,38 @@ func (c *Client) HealthCheck() bool {
 		logger.Printf("influxClient is not initialized, impossible to contact the influxdb server")
 	}
 
-	if len(c.gloutonPendingPoints) > defaultBatchSize {
-		logger.Printf("%d points are waiting to be sent to the influxdb server", len(c.gloutonPendingPoints))
-	}
+	pendingPoints := c.buffer.Len()
 
-	if len(c.gloutonPendingPoints) >= defaultMaxPendingPoints {
-		logger.Printf("%d points are waiting to be sent to the influxdb server. Older points are being dropped", len(c.gloutonPendingPoints))
+	if pendingPoints > defaultBatchSize {
+		logger.Printf("%d points are waiting to be sent to the influxdb server", pendingPoints)
 	}
 
 	return ok
 }
 
-// lenGloutonPendingPoints return the len of the slice c.gloutonPendingPoints.
-func (c *Client) lenGloutonPendingPoints() int {
+// lenPendingPoints returns the number of points waiting to be sent, including
+// the points of the batch which failed to be sent.
+func (c *Client) lenPendingPoints() int {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	return len(c.gloutonPendingPoints)
+	count := c.buffer.Len()
+
+	if c.influxDBBatchPoints != nil {
+		count += len(c.influxDBBatchPoints.Points())
+	}
+
+	return count
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *Client) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pendingPointsDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *Client) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(pendingPointsDesc, prometheus.GaugeValue, float64(c.lenPendingPoints()))
 }
 
 // Run runs the influxDB service.
@@ -328,30 +410,39 @@ func (c *Client) Run(ctx context.Context) error {
 	c.connect(ctx)
 
 	// Suscribe to the Store to receive the metrics
-	c.store.AddNotifiee(c.addPoints)
+	c.store.AddNotifiee(c.buffer.Add)
 
-	// Initialize a ticker of 10 seconds
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
+	retryDelay := minRetryDelay
 
 	for ctx.Err() == nil {
-		for c.lenGloutonPendingPoints() > 0 {
+		failed := false
+
+		for c.lenPendingPoints() > 0 {
 			// Convert the BleemeoPendingPoints in InfluxDBPendingPoints
 			c.convertPendingPoints()
 
 			// Send the point to the server
-			// If sendPoints fail we retry after a tick
+			// If sendPoints fail the batch is kept and we retry after a delay
 			c.sendPoints()
 
-			breakstate := c.sendCheck()
-			if breakstate {
+			failed = c.sendCheck()
+			if failed {
 				break
 			}
 		}
 
-		// Wait the ticker or the and of the programm
+		delay := minRetryDelay
+
+		if failed {
+			// Exponential backoff while the server is unreachable.
+			delay = retryDelay
+			retryDelay = min(2*retryDelay, maxRetryDelay)
+		} else {
+			retryDelay = minRetryDelay
+		}
+
 		select {
-		case <-ticker.C:
+		case <-time.After(delay):
 		case <-ctx.Done():
 		}
 	}
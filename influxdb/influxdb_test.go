@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/bleemeo/glouton/types"
+	"github.com/bleemeo/glouton/utils/pointsbuffer"
 
 	influxDBClient "github.com/influxdata/influxdb1-client/v2"
 )
@@ -126,10 +127,13 @@ func TestConvertMetricPoint(t *testing.T) {
 	}
 }
 
-func TestAddPoints(t *testing.T) {
-	var client Client
+// pendingPoints returns the points waiting to be converted.
+func pendingPoints(client *Client) []types.MetricPoint {
+	return client.buffer.Batch(client.buffer.Len()).Points
+}
 
-	client.maxPendingPoints = 3
+func TestAddPoints(t *testing.T) {
+	client := Client{buffer: pointsbuffer.New("InfluxDB", 3)}
 	metricPoints := make([]types.MetricPoint, 6)
 
 	for i := range metricPoints {
@@ -144,79 +148,77 @@ func TestAddPoints(t *testing.T) {
 		}
 	}
 
-	client.addPoints(metricPoints[0:2])
+	client.buffer.Add(metricPoints[0:2])
 
-	if len(client.gloutonPendingPoints) != 2 {
-		t.Errorf("len(client.gloutonPendingPoints) = %v want 2", len(client.gloutonPendingPoints))
+	if len(pendingPoints(&client)) != 2 {
+		t.Errorf("len(pendingPoints(&client)) = %v want 2", len(pendingPoints(&client)))
 	}
 
-	if client.gloutonPendingPoints[0].Labels[types.LabelName] != metricName0 {
-		t.Errorf("client.gloutonPendingPoints[0] = %s want MetricPoint0", client.gloutonPendingPoints[0].Labels[types.LabelName])
+	if pendingPoints(&client)[0].Labels[types.LabelName] != metricName0 {
+		t.Errorf("pendingPoints(&client)[0] = %s want MetricPoint0", pendingPoints(&client)[0].Labels[types.LabelName])
 	}
 
-	if client.gloutonPendingPoints[1].Labels[types.LabelName] != metricName1 {
-		t.Errorf("client.gloutonPendingPoints[1] = %s want MetricPoint1", client.gloutonPendingPoints[0].Labels[types.LabelName])
+	if pendingPoints(&client)[1].Labels[types.LabelName] != metricName1 {
+		t.Errorf("pendingPoints(&client)[1] = %s want MetricPoint1", pendingPoints(&client)[0].Labels[types.LabelName])
 	}
 
-	client.addPoints(metricPoints[2:3])
+	client.buffer.Add(metricPoints[2:3])
 
-	if len(client.gloutonPendingPoints) != 3 {
-		t.Errorf("len(client.gloutonPendingPoints) = %v want 3", len(client.gloutonPendingPoints))
+	if len(pendingPoints(&client)) != 3 {
+		t.Errorf("len(pendingPoints(&client)) = %v want 3", len(pendingPoints(&client)))
 	}
 
-	if client.gloutonPendingPoints[0].Labels[types.LabelName] != metricName0 {
-		t.Errorf("client.gloutonPendingPoints[0].Labels[%s] = %s want MetricPoint0", types.LabelName, client.gloutonPendingPoints[0].Labels[types.LabelName])
+	if pendingPoints(&client)[0].Labels[types.LabelName] != metricName0 {
+		t.Errorf("pendingPoints(&client)[0].Labels[%s] = %s want MetricPoint0", types.LabelName, pendingPoints(&client)[0].Labels[types.LabelName])
 	}
 
-	if client.gloutonPendingPoints[1].Labels[types.LabelName] != metricName1 {
-		t.Errorf("client.gloutonPendingPoints[1].Labels[%s] = %s want MetricPoint1", types.LabelName, client.gloutonPendingPoints[1].Labels[types.LabelName])
+	if pendingPoints(&client)[1].Labels[types.LabelName] != metricName1 {
+		t.Errorf("pendingPoints(&client)[1].Labels[%s] = %s want MetricPoint1", types.LabelName, pendingPoints(&client)[1].Labels[types.LabelName])
 	}
 
-	if client.gloutonPendingPoints[2].Labels[types.LabelName] != metricName2 {
-		t.Errorf("client.gloutonPendingPoints[2].Labels[%s]: %s want MetricPoint2", types.LabelName, client.gloutonPendingPoints[2].Labels[types.LabelName])
+	if pendingPoints(&client)[2].Labels[types.LabelName] != metricName2 {
+		t.Errorf("pendingPoints(&client)[2].Labels[%s]: %s want MetricPoint2", types.LabelName, pendingPoints(&client)[2].Labels[types.LabelName])
 	}
 
-	client.addPoints(metricPoints[3:4])
+	client.buffer.Add(metricPoints[3:4])
 
-	if len(client.gloutonPendingPoints) != 3 {
-		t.Errorf("len(client.gloutonPendingPoints) = %v want 3", len(client.gloutonPendingPoints))
+	if len(pendingPoints(&client)) != 3 {
+		t.Errorf("len(pendingPoints(&client)) = %v want 3", len(pendingPoints(&client)))
 	}
 
-	if client.gloutonPendingPoints[0].Labels[types.LabelName] != metricName1 {
-		t.Errorf("client.gloutonPendingPoints[0].Labels[%s]: %s want MetricPoint1", types.LabelName, client.gloutonPendingPoints[0].Labels[types.LabelName])
+	if pendingPoints(&client)[0].Labels[types.LabelName] != metricName1 {
+		t.Errorf("pendingPoints(&client)[0].Labels[%s]: %s want MetricPoint1", types.LabelName, pendingPoints(&client)[0].Labels[types.LabelName])
 	}
 
-	if client.gloutonPendingPoints[1].Labels[types.LabelName] != metricName2 {
-		t.Errorf("client.gloutonPendingPoints[1].Labels[%s]: %s want MetricPoint2", types.LabelName, client.gloutonPendingPoints[1].Labels[types.LabelName])
+	if pendingPoints(&client)[1].Labels[types.LabelName] != metricName2 {
+		t.Errorf("pendingPoints(&client)[1].Labels[%s]: %s want MetricPoint2", types.LabelName, pendingPoints(&client)[1].Labels[types.LabelName])
 	}
 
-	if client.gloutonPendingPoints[2].Labels[types.LabelName] != metricName3 {
-		t.Errorf("client.gloutonPendingPoints[2].Labels[%s]: %s want MetricPoint3", types.LabelName, client.gloutonPendingPoints[2].Labels[types.LabelName])
+	if pendingPoints(&client)[2].Labels[types.LabelName] != metricName3 {
+		t.Errorf("pendingPoints(&client)[2].Labels[%s]: %s want MetricPoint3", types.LabelName, pendingPoints(&client)[2].Labels[types.LabelName])
 	}
 
-	client.addPoints(metricPoints)
+	client.buffer.Add(metricPoints)
 
-	if len(client.gloutonPendingPoints) != 3 {
-		t.Errorf("len(client.gloutonPendingPoints) = %v want 3", len(client.gloutonPendingPoints))
+	if len(pendingPoints(&client)) != 3 {
+		t.Errorf("len(pendingPoints(&client)) = %v want 3", len(pendingPoints(&client)))
 	}
 
-	if client.gloutonPendingPoints[0].Labels[types.LabelName] != metricName3 {
-		t.Errorf("client.gloutonPendingPoints[0].Labels[%s]: %s want MetricPoint3", types.LabelName, client.gloutonPendingPoints[0].Labels[types.LabelName])
+	if pendingPoints(&client)[0].Labels[types.LabelName] != metricName3 {
+		t.Errorf("pendingPoints(&client)[0].Labels[%s]: %s want MetricPoint3", types.LabelName, pendingPoints(&client)[0].Labels[types.LabelName])
 	}
 
-	if client.gloutonPendingPoints[1].Labels[types.LabelName] != metricName4 {
-		t.Errorf("client.gloutonPendingPoints[1].Labels[%s]: %s want MetricPoint4", types.LabelName, client.gloutonPendingPoints[1].Labels[types.LabelName])
+	if pendingPoints(&client)[1].Labels[types.LabelName] != metricName4 {
+		t.Errorf("pendingPoints(&client)[1].Labels[%s]: %s want MetricPoint4", types.LabelName, pendingPoints(&client)[1].Labels[types.LabelName])
 	}
 
-	if client.gloutonPendingPoints[2].Labels[types.LabelName] != metricName5 {
-		t.Errorf("client.gloutonPendingPoints[2].Labels[%s]: %s want MetricPoint5", types.LabelName, client.gloutonPendingPoints[2].Labels[types.LabelName])
+	if pendingPoints(&client)[2].Labels[types.LabelName] != metricName5 {
+		t.Errorf("pendingPoints(&client)[2].Labels[%s]: %s want MetricPoint5", types.LabelName, pendingPoints(&client)[2].Labels[types.LabelName])
 	}
 }
 
 func TestConvertPendingPoints(t *testing.T) {
-	var client Client
-
-	client.maxPendingPoints = 50
+	client := Client{buffer: pointsbuffer.New("InfluxDB", 50)}
 	client.maxBatchSize = 5
 	bp, _ := influxDBClient.NewBatchPoints(influxDBClient.BatchPointsConfig{
 		Database:  client.dataBaseName,
@@ -237,32 +239,32 @@ func TestConvertPendingPoints(t *testing.T) {
 		}
 	}
 
-	client.addPoints(metricPoints)
+	client.buffer.Add(metricPoints)
 
-	if len(client.gloutonPendingPoints) != 50 {
-		t.Errorf("len(client.gloutonPendingPoints) = %v want 50", len(client.gloutonPendingPoints))
+	if len(pendingPoints(&client)) != 50 {
+		t.Errorf("len(pendingPoints(&client)) = %v want 50", len(pendingPoints(&client)))
 	}
 
-	if client.gloutonPendingPoints[0].Labels[types.LabelName] != metricName0 {
-		t.Errorf("client.gloutonPendingPoints[0].Labels[%s] = %s want MetricPoint0", types.LabelName, client.gloutonPendingPoints[1].Labels[types.LabelName])
+	if pendingPoints(&client)[0].Labels[types.LabelName] != metricName0 {
+		t.Errorf("pendingPoints(&client)[0].Labels[%s] = %s want MetricPoint0", types.LabelName, pendingPoints(&client)[1].Labels[types.LabelName])
 	}
 
-	if client.gloutonPendingPoints[49].Labels[types.LabelName] != metricName49 {
-		t.Errorf("client.gloutonPendingPoints[49].Labels[%s] = %s want MetricPoint49", types.LabelName, client.gloutonPendingPoints[1].Labels[types.LabelName])
+	if pendingPoints(&client)[49].Labels[types.LabelName] != metricName49 {
+		t.Errorf("pendingPoints(&client)[49].Labels[%s] = %s want MetricPoint49", types.LabelName, pendingPoints(&client)[1].Labels[types.LabelName])
 	}
 
 	client.convertPendingPoints()
 
-	if len(client.gloutonPendingPoints) != 45 {
-		t.Errorf("len(client.gloutonPendingPoints) = %v want 45", len(client.gloutonPendingPoints))
+	if len(pendingPoints(&client)) != 45 {
+		t.Errorf("len(pendingPoints(&client)) = %v want 45", len(pendingPoints(&client)))
 	}
 
-	if client.gloutonPendingPoints[0].Labels[types.LabelName] != metricName5 {
-		t.Errorf("client.gloutonPendingPoints[0].Labels[%s] = %s want MetricPoint5", types.LabelName, client.gloutonPendingPoints[1].Labels[types.LabelName])
+	if pendingPoints(&client)[0].Labels[types.LabelName] != metricName5 {
+		t.Errorf("pendingPoints(&client)[0].Labels[%s] = %s want MetricPoint5", types.LabelName, pendingPoints(&client)[1].Labels[types.LabelName])
 	}
 
-	if client.gloutonPendingPoints[44].Labels[types.LabelName] != metricName49 {
-		t.Errorf("client.gloutonPendingPoints[44].Labels[%s] = %s want MetricPoint49", types.LabelName, client.gloutonPendingPoints[1].Labels[types.LabelName])
+	if pendingPoints(&client)[44].Labels[types.LabelName] != metricName49 {
+		t.Errorf("pendingPoints(&client)[44].Labels[%s] = %s want MetricPoint49", types.LabelName, pendingPoints(&client)[1].Labels[types.LabelName])
 	}
 
 	points := client.influxDBBatchPoints.Points()
@@ -276,3 +278,30 @@ func TestConvertPendingPoints(t *testing.T) {
 		}
 	}
 }
+
+func TestAddPointsDropOldest(t *testing.T) {
+	client := Client{buffer: pointsbuffer.New("InfluxDB", 3)}
+	metricPoints := make([]types.MetricPoint, 4)
+
+	for i := range metricPoints {
+		metricPoints[i] = types.MetricPoint{
+			Point:  types.Point{Time: time.Now(), Value: float64(i)},
+			Labels: map[string]string{types.LabelName: fmt.Sprintf("MetricPoint%v", i)},
+		}
+	}
+
+	client.buffer.Add(metricPoints[0:2])
+	client.buffer.Add(metricPoints[2:4])
+
+	if len(pendingPoints(&client)) != 3 {
+		t.Fatalf("len(pendingPoints(&client)) = %v want 3", len(pendingPoints(&client)))
+	}
+
+	if pendingPoints(&client)[0].Labels[types.LabelName] != metricName1 {
+		t.Errorf("pendingPoints(&client)[0].Labels[%s] = %s want MetricPoint1", types.LabelName, pendingPoints(&client)[0].Labels[types.LabelName])
+	}
+
+	if got := client.lenPendingPoints(); got != 3 {
+		t.Errorf("lenPendingPoints() = %d, want 3", got)
+	}
+}
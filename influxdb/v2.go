@@ -0,0 +1,150 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package influxdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	influxDBClient "github.com/influxdata/influxdb1-client/v2"
+)
+
+const v2WriteTimeout = 30 * time.Second
+
+var (
+	errUnhealthy   = errors.New("InfluxDB server is unhealthy")
+	errWriteFailed = errors.New("write failed")
+)
+
+// v2Client writes points to InfluxDB 2.x using the /api/v2/write endpoint.
+type v2Client struct {
+	serverAddress string
+	token         string
+	org           string
+	bucket        string
+	httpClient    *http.Client
+}
+
+func newV2Client(serverAddress, token, org, bucket string) *v2Client {
+	return &v2Client{
+		serverAddress: strings.TrimSuffix(serverAddress, "/"),
+		token:         token,
+		org:           org,
+		bucket:        bucket,
+		httpClient:    &http.Client{},
+	}
+}
+
+// Ping checks the /health endpoint and returns the response time and the server version.
+func (c *v2Client) Ping(timeout time.Duration) (time.Duration, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.serverAddress+"/health", nil)
+	if err != nil {
+		return 0, "", err
+	}
+
+	start := time.Now()
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+
+	defer resp.Body.Close()
+
+	var health struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Version string `json:"version"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return 0, "", fmt.Errorf("%w: %s", errUnhealthy, resp.Status)
+	}
+
+	if resp.StatusCode != http.StatusOK || health.Status != "pass" {
+		return 0, "", fmt.Errorf("%w: status %q: %s", errUnhealthy, health.Status, health.Message)
+	}
+
+	return time.Since(start), health.Version, nil
+}
+
+// v2Precision converts the precision of the batch points to the precision of the v2 API.
+func v2Precision(precision string) string {
+	switch precision {
+	case "u":
+		return "us"
+	case "n", "":
+		return "ns"
+	default:
+		return precision
+	}
+}
+
+// Write sends the points using the line protocol.
+func (c *v2Client) Write(bp influxDBClient.BatchPoints) error {
+	var body bytes.Buffer
+
+	for _, pt := range bp.Points() {
+		body.WriteString(pt.PrecisionString(bp.Precision()))
+		body.WriteByte('\n')
+	}
+
+	params := url.Values{}
+	params.Set("org", c.org)
+	params.Set("bucket", c.bucket)
+	params.Set("precision", v2Precision(bp.Precision()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), v2WriteTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.serverAddress+"/api/v2/write?"+params.Encode(), &body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "Token "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+		return fmt.Errorf("%w: %s: %s", errWriteFailed, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package influxdb
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	influxDBClient "github.com/influxdata/influxdb1-client/v2"
+)
+
+func TestV2Client(t *testing.T) {
+	t.Parallel()
+
+	var (
+		gotQuery string
+		gotAuth  string
+		gotBody  string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			_, _ = w.Write([]byte(`{"name":"influxdb","message":"ready for queries and writes","status":"pass","version":"v2.7.6"}`))
+		case "/api/v2/write":
+			gotQuery = r.URL.RawQuery
+			gotAuth = r.Header.Get("Authorization")
+			body, _ := io.ReadAll(r.Body)
+			gotBody = string(body)
+
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := newV2Client(srv.URL, "secret-token", "my-org", "my-bucket")
+
+	_, version, err := client.Ping(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if version != "v2.7.6" {
+		t.Errorf("Ping() version = %q, want %q", version, "v2.7.6")
+	}
+
+	bp, _ := influxDBClient.NewBatchPoints(influxDBClient.BatchPointsConfig{Precision: batchPrecision("ms")})
+	pt, _ := influxDBClient.NewPoint(
+		"cpu_used",
+		map[string]string{"instance": "server"},
+		map[string]interface{}{"value": 4.2},
+		time.Date(2024, 1, 2, 3, 4, 5, 250*int(time.Millisecond), time.UTC),
+	)
+	bp.AddPoint(pt)
+
+	if err := client.Write(bp); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "bucket=my-bucket&org=my-org&precision=ms"; gotQuery != want {
+		t.Errorf("query = %q, want %q", gotQuery, want)
+	}
+
+	if want := "Token secret-token"; gotAuth != want {
+		t.Errorf("Authorization = %q, want %q", gotAuth, want)
+	}
+
+	if want := "cpu_used,instance=server value=4.2 1704164645250\n"; gotBody != want {
+		t.Errorf("body = %q, want %q", gotBody, want)
+	}
+}
+
+func TestV2ClientUnhealthy(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"name":"influxdb","message":"not ready","status":"fail"}`))
+	}))
+	defer srv.Close()
+
+	client := newV2Client(srv.URL, "", "org", "bucket")
+
+	if _, _, err := client.Ping(time.Second); err == nil {
+		t.Error("Ping() succeeded, want an error")
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bind gathers the statistics of a BIND (named) server from its statistics channel.
+package bind
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bleemeo/glouton/inputs/internal"
+	"github.com/bleemeo/glouton/prometheus/registry"
+
+	"github.com/influxdata/telegraf"
+)
+
+const requestTimeout = 10 * time.Second
+
+var errUnexpectedStatus = errors.New("unexpected HTTP status")
+
+type format int
+
+const (
+	// formatAuto tries the JSON format then falls back to the XML format.
+	formatAuto format = iota
+	formatJSON
+	formatXML
+)
+
+// stats are the counters read from the statistics channel.
+type stats struct {
+	// qtypes is the number of queries received by type (A, AAAA, MX...).
+	qtypes map[string]float64
+	// nsstats are the name server statistics (Requestv4, QryServFail...).
+	nsstats map[string]float64
+	// cacheStats are the cache statistics summed over all views (QueryHits, QueryMisses...).
+	cacheStats map[string]float64
+}
+
+type bindInput struct {
+	baseURL string
+	format  format
+	client  *http.Client
+
+	// Previous cache hits and misses, used to compute the hit ratio over the last interval.
+	hasPrevious    bool
+	previousHits   float64
+	previousMisses float64
+}
+
+// New returns a BIND input reading the statistics channel at statsURL.
+// statsURL could point to the JSON ("/json/v1") or XML ("/xml/v3") statistics.
+// When it's only the address of the statistics channel, the JSON format is tried first,
+// then the XML format for versions built without JSON support.
+func New(statsURL string) (telegraf.Input, registry.RegistrationOption, error) {
+	input := &bindInput{
+		client: &http.Client{},
+	}
+
+	statsURL = strings.TrimSuffix(statsURL, "/")
+
+	switch {
+	case strings.HasSuffix(statsURL, "/json/v1"):
+		input.baseURL, input.format = statsURL, formatJSON
+	case strings.HasSuffix(statsURL, "/xml/v3"):
+		input.baseURL, input.format = statsURL, formatXML
+	default:
+		input.baseURL, input.format = statsURL, formatAuto
+	}
+
+	internalInput := &internal.Input{
+		Input: input,
+		Accumulator: internal.Accumulator{
+			DerivatedMetrics: []string{"queries", "requests", "servfail"},
+		},
+		Name: "bind",
+	}
+
+	return internalInput, registry.RegistrationOption{}, nil
+}
+
+// SampleConfig returns the default configuration of the input.
+func (i *bindInput) SampleConfig() string {
+	return ""
+}
+
+// Gather reads the statistics channel and adds the queries, SERVFAIL and cache metrics.
+func (i *bindInput) Gather(acc telegraf.Accumulator) error {
+	s, err := i.stats()
+	if err != nil {
+		return err
+	}
+
+	for qtype, value := range s.qtypes {
+		acc.AddFields("bind", map[string]interface{}{"queries": value}, map[string]string{"qtype": qtype})
+	}
+
+	fields := map[string]interface{}{
+		"requests": s.nsstats["Requestv4"] + s.nsstats["Requestv6"],
+		"servfail": s.nsstats["QryServFail"],
+	}
+
+	hits, misses := s.cacheStats["QueryHits"], s.cacheStats["QueryMisses"]
+
+	if i.hasPrevious && hits >= i.previousHits && misses >= i.previousMisses {
+		deltaHits := hits - i.previousHits
+		deltaMisses := misses - i.previousMisses
+
+		if deltaHits+deltaMisses > 0 {
+			fields["cache_hit_perc"] = deltaHits * 100 / (deltaHits + deltaMisses)
+		}
+	}
+
+	i.hasPrevious = true
+	i.previousHits = hits
+	i.previousMisses = misses
+
+	acc.AddFields("bind", fields, nil)
+
+	return nil
+}
+
+func (i *bindInput) stats() (stats, error) {
+	switch i.format {
+	case formatJSON:
+		return i.readStats(i.baseURL+"/server", parseJSON)
+	case formatXML:
+		return i.readStats(i.baseURL+"/server", parseXML)
+	case formatAuto:
+		s, err := i.readStats(i.baseURL+"/json/v1/server", parseJSON)
+		if err == nil {
+			i.baseURL, i.format = i.baseURL+"/json/v1", formatJSON
+
+			return s, nil
+		}
+
+		s, xmlErr := i.readStats(i.baseURL+"/xml/v3/server", parseXML)
+		if xmlErr == nil {
+			i.baseURL, i.format = i.baseURL+"/xml/v3", formatXML
+
+			return s, nil
+		}
+
+		return stats{}, fmt.Errorf("JSON statistics: %w, XML statistics: %w", err, xmlErr)
+	}
+
+	return stats{}, nil
+}
+
+func (i *bindInput) readStats(url string, parse func(io.Reader) (stats, error)) (stats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return stats{}, err
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return stats{}, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return stats{}, fmt.Errorf("%w: %s returned %s", errUnexpectedStatus, url, resp.Status)
+	}
+
+	return parse(resp.Body)
+}
+
+// parseJSON parses the server statistics of the JSON format (BIND 9.10+).
+func parseJSON(r io.Reader) (stats, error) {
+	var content struct {
+		QTypes  map[string]float64 `json:"qtypes"`
+		NSStats map[string]float64 `json:"nsstats"`
+		Views   map[string]struct {
+			Resolver struct {
+				CacheStats map[string]float64 `json:"cachestats"`
+			} `json:"resolver"`
+		} `json:"views"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&content); err != nil {
+		return stats{}, err
+	}
+
+	s := stats{
+		qtypes:     content.QTypes,
+		nsstats:    content.NSStats,
+		cacheStats: make(map[string]float64),
+	}
+
+	for _, view := range content.Views {
+		for name, value := range view.Resolver.CacheStats {
+			s.cacheStats[name] += value
+		}
+	}
+
+	return s, nil
+}
+
+type xmlCounters struct {
+	Type     string `xml:"type,attr"`
+	Counters []struct {
+		Name  string  `xml:"name,attr"`
+		Value float64 `xml:",chardata"`
+	} `xml:"counter"`
+}
+
+// parseXML parses the server statistics of the XML v3 format (BIND 9.9+).
+func parseXML(r io.Reader) (stats, error) {
+	var content struct {
+		Server struct {
+			Counters []xmlCounters `xml:"counters"`
+		} `xml:"server"`
+		Views []struct {
+			Counters []xmlCounters `xml:"counters"`
+		} `xml:"views>view"`
+	}
+
+	if err := xml.NewDecoder(r).Decode(&content); err != nil {
+		return stats{}, err
+	}
+
+	s := stats{
+		qtypes:     make(map[string]float64),
+		nsstats:    make(map[string]float64),
+		cacheStats: make(map[string]float64),
+	}
+
+	for _, counters := range content.Server.Counters {
+		var target map[string]float64
+
+		switch counters.Type {
+		case "qtype":
+			target = s.qtypes
+		case "nsstat":
+			target = s.nsstats
+		default:
+			continue
+		}
+
+		for _, c := range counters.Counters {
+			target[c.Name] = c.Value
+		}
+	}
+
+	for _, view := range content.Views {
+		for _, counters := range view.Counters {
+			if counters.Type != "cachestats" {
+				continue
+			}
+
+			for _, c := range counters.Counters {
+				s.cacheStats[c.Name] += c.Value
+			}
+		}
+	}
+
+	return s, nil
+}
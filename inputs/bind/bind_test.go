@@ -0,0 +1,113 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bind
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const jsonServerStats = `{
+  "json-stats-version": "1.2",
+  "opcodes": {"QUERY": 30},
+  "qtypes": {"A": 20, "AAAA": 10},
+  "nsstats": {"Requestv4": 25, "Requestv6": 5, "QryServFail": 2},
+  "views": {
+    "_default": {"resolver": {"cachestats": {"QueryHits": 15, "QueryMisses": 5}}},
+    "internal": {"resolver": {"cachestats": {"QueryHits": 5, "QueryMisses": 0}}}
+  }
+}`
+
+const xmlServerStats = `<?xml version="1.0" encoding="UTF-8"?>
+<statistics version="3.11">
+  <server>
+    <counters type="opcode"><counter name="QUERY">30</counter></counters>
+    <counters type="qtype"><counter name="A">20</counter><counter name="AAAA">10</counter></counters>
+    <counters type="nsstat">
+      <counter name="Requestv4">25</counter>
+      <counter name="Requestv6">5</counter>
+      <counter name="QryServFail">2</counter>
+    </counters>
+  </server>
+  <views>
+    <view name="_default">
+      <counters type="resstats"><counter name="Queryv4">12</counter></counters>
+      <counters type="cachestats"><counter name="QueryHits">15</counter><counter name="QueryMisses">5</counter></counters>
+    </view>
+    <view name="internal">
+      <counters type="cachestats"><counter name="QueryHits">5</counter><counter name="QueryMisses">0</counter></counters>
+    </view>
+  </views>
+</statistics>`
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	want := stats{
+		qtypes:     map[string]float64{"A": 20, "AAAA": 10},
+		nsstats:    map[string]float64{"Requestv4": 25, "Requestv6": 5, "QryServFail": 2},
+		cacheStats: map[string]float64{"QueryHits": 20, "QueryMisses": 5},
+	}
+
+	gotJSON, err := parseJSON(strings.NewReader(jsonServerStats))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(want, gotJSON, cmp.AllowUnexported(stats{})); diff != "" {
+		t.Errorf("parseJSON() mismatch (-want +got):\n%s", diff)
+	}
+
+	gotXML, err := parseXML(strings.NewReader(xmlServerStats))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(want, gotXML, cmp.AllowUnexported(stats{})); diff != "" {
+		t.Errorf("parseXML() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestAutoDetectXML checks that the XML format is used when JSON statistics aren't available.
+func TestAutoDetectXML(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/xml/v3/server" {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(xmlServerStats))
+	}))
+	defer srv.Close()
+
+	input := &bindInput{baseURL: srv.URL, format: formatAuto, client: srv.Client()}
+
+	if _, err := input.stats(); err != nil {
+		t.Fatal(err)
+	}
+
+	if input.format != formatXML || input.baseURL != srv.URL+"/xml/v3" {
+		t.Errorf("format = %v with URL %s, want XML with URL %s/xml/v3", input.format, input.baseURL, srv.URL)
+	}
+}
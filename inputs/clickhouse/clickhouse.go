@@ -0,0 +1,178 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package clickhouse gathers the statistics of a ClickHouse server
+// from its system tables with the HTTP interface.
+package clickhouse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bleemeo/glouton/config"
+	"github.com/bleemeo/glouton/inputs/internal"
+	"github.com/bleemeo/glouton/prometheus/registry"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	queryTimeout = 5 * time.Second
+	// defaultUsername is the user created by default on a ClickHouse server.
+	defaultUsername = "default"
+)
+
+// statsQuery returns the table, the name and the value of the statistics
+// used by the input as tab separated values.
+const statsQuery = `SELECT 'metrics', metric, toFloat64(value) FROM system.metrics
+WHERE metric IN ('Query', 'TCPConnection', 'HTTPConnection')
+UNION ALL SELECT 'events', event, toFloat64(value) FROM system.events
+WHERE event IN ('Query', 'FailedQuery', 'InsertedRows')
+UNION ALL SELECT 'asynchronous_metrics', metric, toFloat64(value) FROM system.asynchronous_metrics
+WHERE metric = 'Uptime'
+FORMAT TabSeparated`
+
+var (
+	errUnexpectedStatus = errors.New("unexpected status code")
+	errAccessDenied     = errors.New("access to ClickHouse denied, check the username and password")
+)
+
+//nolint:gochecknoglobals
+var fieldNames = map[string]string{
+	"metrics.Query":               "queries_running",
+	"metrics.TCPConnection":       "tcp_connections",
+	"metrics.HTTPConnection":      "http_connections",
+	"events.Query":                "queries",
+	"events.FailedQuery":          "failed_queries",
+	"events.InsertedRows":         "inserted_rows",
+	"asynchronous_metrics.Uptime": "uptime_seconds",
+}
+
+type clickHouseInput struct {
+	// url is the URL of the HTTP interface, like "http://127.0.0.1:8123/".
+	url        string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+// New returns a ClickHouse input. The address is the host and port of the
+// HTTP interface, the username defaults to the "default" user.
+func New(address string, config config.Service) (telegraf.Input, registry.RegistrationOption, error) {
+	input := &clickHouseInput{
+		url:        "http://" + address + "/",
+		username:   config.Username,
+		password:   config.Password,
+		httpClient: &http.Client{Timeout: queryTimeout},
+	}
+
+	if input.username == "" {
+		input.username = defaultUsername
+	}
+
+	internalInput := &internal.Input{
+		Input: input,
+		Accumulator: internal.Accumulator{
+			DerivatedMetrics: []string{"queries", "failed_queries", "inserted_rows"},
+		},
+		Name: "clickhouse",
+	}
+
+	return internalInput, registry.RegistrationOption{}, nil
+}
+
+// SampleConfig returns the default configuration of the input.
+func (i *clickHouseInput) SampleConfig() string {
+	return ""
+}
+
+// Gather adds the running queries, the connections, the query rates and the uptime.
+func (i *clickHouseInput) Gather(acc telegraf.Accumulator) error {
+	data, err := i.query(statsQuery)
+	if err != nil {
+		return err
+	}
+
+	acc.AddFields("clickhouse", parseStats(data), nil)
+
+	return nil
+}
+
+// query returns the result of a query sent to the HTTP interface.
+func (i *clickHouseInput) query(query string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, i.url+"?query="+url.QueryEscape(query), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("X-ClickHouse-User", i.username)
+
+	if i.password != "" {
+		req.Header.Set("X-ClickHouse-Key", i.password)
+	}
+
+	resp, err := i.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, errAccessDenied
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnexpectedStatus, resp.Status)
+	}
+}
+
+// parseStats returns the fields from the tab separated output of statsQuery.
+func parseStats(data []byte) map[string]interface{} {
+	fields := make(map[string]interface{})
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		columns := strings.Split(scanner.Text(), "\t")
+		if len(columns) != 3 {
+			continue
+		}
+
+		field, ok := fieldNames[columns[0]+"."+columns[1]]
+		if !ok {
+			continue
+		}
+
+		if value, err := strconv.ParseFloat(columns[2], 64); err == nil {
+			fields[field] = value
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,99 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bleemeo/glouton/config"
+	"github.com/bleemeo/glouton/inputs/internal"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const statsOutput = "metrics\tQuery\t2\n" +
+	"metrics\tTCPConnection\t5\n" +
+	"metrics\tHTTPConnection\t1\n" +
+	"events\tQuery\t1234\n" +
+	"events\tFailedQuery\t3\n" +
+	"events\tInsertedRows\t98765\n" +
+	"asynchronous_metrics\tUptime\t3600\n"
+
+func TestGatherHTTP(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-ClickHouse-User") != "monitoring" || r.Header.Get("X-ClickHouse-Key") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+
+			return
+		}
+
+		if !strings.Contains(r.URL.Query().Get("query"), "system.metrics") {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(statsOutput))
+	}))
+	t.Cleanup(srv.Close)
+
+	address := strings.TrimPrefix(srv.URL, "http://")
+
+	input, _, err := New(address, config.Service{Username: "monitoring", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acc := &internal.StoreAccumulator{}
+
+	if err := input.Gather(acc); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(acc.Measurement) != 1 {
+		t.Fatalf("got %d measurements, want 1", len(acc.Measurement))
+	}
+
+	// The events counters are derivated, so they are only sent from the second gather.
+	want := map[string]interface{}{
+		"queries_running":  2.0,
+		"tcp_connections":  5.0,
+		"http_connections": 1.0,
+		"uptime_seconds":   3600.0,
+	}
+
+	if diff := cmp.Diff(want, acc.Measurement[0].Fields); diff != "" {
+		t.Errorf("fields mismatch (-want +got):\n%s", diff)
+	}
+
+	input, _, err = New(address, config.Service{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clickhouse, _ := input.(*internal.Input).Input.(*clickHouseInput)
+
+	if err := clickhouse.Gather(&internal.StoreAccumulator{}); !errors.Is(err, errAccessDenied) {
+		t.Errorf("Gather() error = %v, want %v", err, errAccessDenied)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bleemeo/glouton/facts"
+	crTypes "github.com/bleemeo/glouton/facts/container-runtime/types"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs/docker"
+)
+
+// allowlistInput gathers the Docker metrics only for the containers allowed by
+// isMetricsAllowed, so the stats of other containers are never requested.
+// The Telegraf input compiles its container filter on the first gather, so a new
+// input is used when the names of the allowed containers change. The previous input
+// is dropped, Telegraf closes the idle connections of its client at the end of each gather.
+type allowlistInput struct {
+	newInput         func(containerInclude []string) *docker.Docker
+	dockerRuntime    crTypes.RuntimeInterface
+	isMetricsAllowed func(facts.Container) bool
+
+	l       sync.Mutex
+	input   *docker.Docker
+	include []string
+}
+
+func (a *allowlistInput) SampleConfig() string {
+	return ""
+}
+
+func (a *allowlistInput) Gather(acc telegraf.Accumulator) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The discovery keeps the list of containers up-to-date, a cached list is fine.
+	containers, err := a.dockerRuntime.Containers(ctx, time.Hour, false)
+	if err != nil {
+		return err
+	}
+
+	include := allowedContainerNames(containers, a.isMetricsAllowed)
+	if len(include) == 0 {
+		return nil
+	}
+
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	if a.input == nil || !slices.Equal(a.include, include) {
+		patterns := make([]string, len(include))
+		for i, name := range include {
+			patterns[i] = escapeGlob(name)
+		}
+
+		input := a.newInput(patterns)
+		if err := input.Init(); err != nil {
+			return err
+		}
+
+		a.input = input
+		a.include = include
+	}
+
+	return a.input.Gather(acc)
+}
+
+// escapeGlob returns a pattern matching only the given name with the Telegraf filters.
+// A filter without any of the "*?[" characters is an exact match, other filters are
+// globs, joined in a "{a,b}" alternation, where all the special characters must be escaped.
+func escapeGlob(name string) string {
+	if !strings.ContainsAny(name, "*?[") {
+		return name
+	}
+
+	var sb strings.Builder
+
+	for _, r := range name {
+		if strings.ContainsRune(`*?[]{},\`, r) {
+			sb.WriteByte('\\')
+		}
+
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+// allowedContainerNames returns the sorted names of the containers allowed by isMetricsAllowed.
+func allowedContainerNames(containers []facts.Container, isMetricsAllowed func(facts.Container) bool) []string {
+	names := make([]string, 0, len(containers))
+
+	for _, c := range containers {
+		if c.ContainerName() == "" || !isMetricsAllowed(c) {
+			continue
+		}
+
+		names = append(names, c.ContainerName())
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,40 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import "testing"
+
+func TestEscapeGlob(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "web-1", want: "web-1"},
+		{name: "web{1}", want: "web{1}"},
+		{name: "web*", want: `web\*`},
+		{name: "a?b", want: `a\?b`},
+		{name: "x[1],{y}", want: `x\[1\]\,\{y\}`},
+	}
+
+	for _, tt := range tests {
+		if got := escapeGlob(tt.name); got != tt.want {
+			t.Errorf("escapeGlob(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
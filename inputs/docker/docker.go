@@ -31,22 +31,46 @@ import (
 )
 
 // New initialise docker.Input.
-func New(dockerAddress string, dockerRuntime crTypes.RuntimeInterface, isContainerIgnored func(facts.Container) bool) (i telegraf.Input, err error) {
+// When isMetricsAllowed is not nil, the containers stats are only requested for
+// the containers it allows.
+func New(
+	dockerAddress string,
+	dockerRuntime crTypes.RuntimeInterface,
+	isContainerIgnored func(facts.Container) bool,
+	isMetricsAllowed func(facts.Container) bool,
+) (i telegraf.Input, err error) {
 	input, ok := telegraf_inputs.Inputs["docker"]
 	if ok {
-		dockerInput, ok := input().(*docker.Docker)
-		if ok {
-			if dockerAddress != "" {
-				dockerInput.Endpoint = dockerAddress
+		if _, ok := input().(*docker.Docker); ok {
+			newInput := func(containerInclude []string) *docker.Docker {
+				dockerInput, _ := input().(*docker.Docker)
+
+				if dockerAddress != "" {
+					dockerInput.Endpoint = dockerAddress
+				}
+
+				dockerInput.PerDevice = false
+				dockerInput.Total = true
+				dockerInput.ContainerInclude = containerInclude
+				dockerInput.Log = internal.Logger{}
+
+				return dockerInput
 			}
 
 			r := renamer{dockerRuntime: dockerRuntime, isContainerIgnored: isContainerIgnored}
 
-			dockerInput.PerDevice = false
-			dockerInput.Total = true
-			dockerInput.Log = internal.Logger{}
+			var gatherInput telegraf.Input = newInput(nil)
+
+			if isMetricsAllowed != nil {
+				gatherInput = &allowlistInput{
+					newInput:         newInput,
+					dockerRuntime:    dockerRuntime,
+					isMetricsAllowed: isMetricsAllowed,
+				}
+			}
+
 			i = &internal.Input{
-				Input: dockerInput,
+				Input: gatherInput,
 				Accumulator: internal.Accumulator{
 					RenameGlobal:     r.renameGlobal,
 					DerivatedMetrics: []string{"usage_total", "rx_bytes", "tx_bytes", "io_service_bytes_recursive_read", "io_service_bytes_recursive_write"},
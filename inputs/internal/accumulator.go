@@ -63,6 +63,8 @@ type RenameCallback func(labels map[string]string, annotations types.MetricAnnot
 //   - Any metrics matching DerivatedMetrics are derivated. Metric seen for the first time are dropped.
 //     Derivation is only applied to Counter values, that is something that only go upward. If value does downward, it's skipped.
 //   - Then TransformMetrics is called on a float64 version of fields. It may apply per-metric transformation.
+//   - If EmitRawCounters is set, the raw value of derivated metrics is also emitted with a "_total" suffix.
+//     TransformMetrics and RenameMetrics aren't applied on those raw values.
 type Accumulator struct {
 	Accumulator telegraf.Accumulator
 
@@ -90,11 +92,15 @@ type Accumulator struct {
 
 	RenameCallbacks []RenameCallback
 
+	// EmitRawCounters enables emitting the raw counter of derivated metrics alongside the rate.
+	EmitRawCounters bool
+
 	// map a flattened tags to a map[fieldName]value
 	currentValues    map[string]map[string]metricPoint
 	pastValues       map[string]map[string]metricPoint
 	workStringBuffer []string
 	workResult       map[string]float64
+	workRaw          map[string]float64
 	now              time.Time
 	l                sync.Mutex
 }
@@ -193,6 +199,14 @@ func (a *Accumulator) convertToFloatFields(currentContext GatherContext, fields
 		delete(a.workResult, k)
 	}
 
+	if a.workRaw == nil {
+		a.workRaw = make(map[string]float64)
+	}
+
+	for k := range a.workRaw {
+		delete(a.workRaw, k)
+	}
+
 	for _, m := range a.DerivatedMetrics {
 		if searchMetrics == nil {
 			searchMetrics = make(map[string]bool, len(a.DerivatedMetrics))
@@ -238,6 +252,12 @@ func (a *Accumulator) convertToFloatFields(currentContext GatherContext, fields
 		}
 
 		a.doDerivated(a.workResult, flatTag, len(fields), metricName, value, metricTime)
+
+		if a.EmitRawCounters {
+			if valueFloat, err := inputs.ConvertToFloat(value); err == nil {
+				a.workRaw[metricName] = valueFloat
+			}
+		}
 	}
 
 	return a.workResult
@@ -331,6 +351,14 @@ func (a *Accumulator) processMetrics(
 		fieldsPerMeasurements[currentContext.Measurement] = currentMap
 	}
 
+	for metricName, value := range a.workRaw {
+		if _, ok := fieldsPerMeasurements[currentContext.Measurement]; !ok {
+			fieldsPerMeasurements[currentContext.Measurement] = make(map[string]interface{}, len(a.workRaw))
+		}
+
+		fieldsPerMeasurements[currentContext.Measurement][metricName+"_total"] = value
+	}
+
 	a.l.Unlock()
 
 	for _, f := range a.RenameCallbacks {
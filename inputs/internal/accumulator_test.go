@@ -247,6 +247,35 @@ func TestDerive(t *testing.T) {
 	}
 }
 
+func TestEmitRawCounters(t *testing.T) {
+	var got []map[string]interface{}
+
+	finalFunc := func(_ string, fields map[string]interface{}, _ map[string]string, _ types.MetricAnnotations, _ ...time.Time) {
+		got = append(got, fields)
+	}
+
+	t0 := time.Now()
+	t1 := t0.Add(10 * time.Second)
+	acc := Accumulator{
+		DerivatedMetrics: []string{"bytes_sent"},
+		EmitRawCounters:  true,
+	}
+
+	acc.PrepareGather()
+	acc.processMetrics(finalFunc, "net", map[string]interface{}{"bytes_sent": uint64(1000), "speed": 42.0}, nil, t0)
+	acc.PrepareGather()
+	acc.processMetrics(finalFunc, "net", map[string]interface{}{"bytes_sent": uint64(1500), "speed": 42.0}, nil, t1)
+
+	want := []map[string]interface{}{
+		{"bytes_sent_total": 1000.0, "speed": 42.0},
+		{"bytes_sent": 50.0, "bytes_sent_total": 1500.0, "speed": 42.0},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+}
+
 func TestDeriveFunc(t *testing.T) {
 	called1 := false
 	called2 := false
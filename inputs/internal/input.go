@@ -93,6 +93,11 @@ func (i *Input) fixTelegrafInput() {
 	models.SetLoggerOnPlugin(i.Input, i.logger)
 }
 
+// SetEmitRawCounters enables emitting the raw value of derivated counters alongside the rate.
+func (i *Input) SetEmitRawCounters(enable bool) {
+	i.Accumulator.EmitRawCounters = enable
+}
+
 // SecretCount allows getting the secret count of the underlying input.
 func (i *Input) SecretCount() int {
 	if si, ok := i.Input.(inputs.SecretfulInput); ok {
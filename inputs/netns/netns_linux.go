@@ -0,0 +1,317 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+// Package netns gathers the interface statistics of the network namespaces
+// other than the host one, like the namespaces created by containers or VPNs.
+package netns
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/bleemeo/glouton/inputs/internal"
+	"github.com/bleemeo/glouton/logger"
+	"github.com/bleemeo/glouton/prometheus/registry"
+	"github.com/bleemeo/glouton/types"
+
+	"github.com/influxdata/telegraf"
+)
+
+// labelNetns is the label containing the name of the network namespace.
+const labelNetns = "netns"
+
+// namedNamespaceDirs are the directories where "ip netns" creates the named namespaces.
+var namedNamespaceDirs = []string{"run/netns", "var/run/netns"} //nolint:gochecknoglobals
+
+type netnsInput struct {
+	hostRootPath string
+	allowList    []string
+}
+
+// namespace is a network namespace with a process running in it.
+type namespace struct {
+	name string
+	pid  int
+}
+
+// New returns an input gathering the interfaces of the network namespaces
+// whose name matches one of the patterns of the allow list.
+func New(hostRootPath string, allowList []string) (telegraf.Input, registry.RegistrationOption, error) {
+	for _, pattern := range allowList {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, registry.RegistrationOption{}, fmt.Errorf("invalid network namespace pattern %q: %w", pattern, err)
+		}
+	}
+
+	internalInput := &internal.Input{
+		Input: &netnsInput{
+			hostRootPath: hostRootPath,
+			allowList:    allowList,
+		},
+		Accumulator: internal.Accumulator{
+			RenameGlobal:     renameGlobal,
+			DerivatedMetrics: []string{"bytes_sent", "bytes_recv", "drop_in", "drop_out", "packets_recv", "packets_sent", "err_out", "err_in"},
+			TransformMetrics: transformMetrics,
+		},
+		Name: "netns",
+	}
+
+	return internalInput, registry.RegistrationOption{}, nil
+}
+
+// SampleConfig returns the default configuration of the input.
+func (i *netnsInput) SampleConfig() string {
+	return ""
+}
+
+// Gather adds the statistics of each interface of the allowed namespaces.
+func (i *netnsInput) Gather(acc telegraf.Accumulator) error {
+	namespaces, err := i.listNamespaces()
+	if err != nil {
+		return err
+	}
+
+	for _, ns := range namespaces {
+		if !i.isAllowed(ns.name) {
+			continue
+		}
+
+		devPath := filepath.Join(i.hostRootPath, "proc", strconv.Itoa(ns.pid), "net", "dev")
+
+		stats, err := readNetDev(devPath)
+		if err != nil {
+			// The process may have exited since the namespaces were listed.
+			logger.V(2).Printf("Failed to read the interfaces of the network namespace %s: %v", ns.name, err)
+
+			continue
+		}
+
+		for iface, fields := range stats {
+			acc.AddFields("netns", fields, map[string]string{
+				labelNetns:      ns.name,
+				types.LabelItem: iface,
+			})
+		}
+	}
+
+	return nil
+}
+
+func (i *netnsInput) isAllowed(name string) bool {
+	for _, pattern := range i.allowList {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listNamespaces returns the network namespaces, except the host one, which
+// have at least one process. A namespace is named from /var/run/netns, else
+// from the command of its process with the lowest PID.
+func (i *netnsInput) listNamespaces() ([]namespace, error) {
+	procDir := filepath.Join(i.hostRootPath, "proc")
+
+	hostInode, err := namespaceInode(filepath.Join(procDir, "1", "ns", "net"))
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil, err
+	}
+
+	pids := make([]int, 0, len(entries))
+
+	for _, entry := range entries {
+		if pid, err := strconv.Atoi(entry.Name()); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+
+	sort.Ints(pids)
+
+	// The process with the lowest PID of each namespace, keyed by the namespace inode.
+	processes := make(map[uint64]int)
+	inodes := make([]uint64, 0)
+
+	for _, pid := range pids {
+		inode, err := namespaceInode(filepath.Join(procDir, strconv.Itoa(pid), "ns", "net"))
+		if err != nil || inode == hostInode {
+			continue
+		}
+
+		if _, ok := processes[inode]; !ok {
+			processes[inode] = pid
+			inodes = append(inodes, inode)
+		}
+	}
+
+	names := i.namedNamespaces()
+	namespaces := make([]namespace, 0, len(inodes))
+	usedNames := make(map[string]bool, len(inodes))
+
+	for _, inode := range inodes {
+		pid := processes[inode]
+
+		name, ok := names[inode]
+		if !ok {
+			comm, err := os.ReadFile(filepath.Join(procDir, strconv.Itoa(pid), "comm"))
+			if err != nil {
+				continue
+			}
+
+			name = strings.TrimSpace(string(comm))
+		}
+
+		// Only the first namespace of a given name is kept to avoid mixing their interfaces.
+		if name == "" || usedNames[name] {
+			continue
+		}
+
+		usedNames[name] = true
+
+		namespaces = append(namespaces, namespace{name: name, pid: pid})
+	}
+
+	return namespaces, nil
+}
+
+// namedNamespaces returns the names of the namespaces created by "ip netns", keyed by inode.
+func (i *netnsInput) namedNamespaces() map[uint64]string {
+	names := make(map[uint64]string)
+
+	for _, dir := range namedNamespaceDirs {
+		entries, err := os.ReadDir(filepath.Join(i.hostRootPath, dir))
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			var stat syscall.Stat_t
+
+			if err := syscall.Stat(filepath.Join(i.hostRootPath, dir, entry.Name()), &stat); err != nil {
+				continue
+			}
+
+			names[stat.Ino] = entry.Name()
+		}
+	}
+
+	return names
+}
+
+// namespaceInode returns the inode of the namespace of a /proc/<pid>/ns/net link.
+func namespaceInode(linkPath string) (uint64, error) {
+	var stat syscall.Stat_t
+
+	if err := syscall.Stat(linkPath, &stat); err != nil {
+		return 0, err
+	}
+
+	return stat.Ino, nil
+}
+
+func readNetDev(devPath string) (map[string]map[string]interface{}, error) {
+	f, err := os.Open(devPath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return parseNetDev(f)
+}
+
+// parseNetDev parses the content of /proc/net/dev. The loopback interface is ignored.
+func parseNetDev(r io.Reader) (map[string]map[string]interface{}, error) {
+	stats := make(map[string]map[string]interface{})
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		iface, counters, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			// The two header lines don't contain a colon.
+			continue
+		}
+
+		iface = strings.TrimSpace(iface)
+		values := strings.Fields(counters)
+
+		if iface == "lo" || len(values) < 12 {
+			continue
+		}
+
+		fields := make(map[string]interface{}, 8)
+
+		for index, name := range map[int]string{
+			0:  "bytes_recv",
+			1:  "packets_recv",
+			2:  "err_in",
+			3:  "drop_in",
+			8:  "bytes_sent",
+			9:  "packets_sent",
+			10: "err_out",
+			11: "drop_out",
+		} {
+			value, err := strconv.ParseUint(values[index], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid counter %s of interface %s: %w", name, iface, err)
+			}
+
+			fields[name] = value
+		}
+
+		stats[iface] = fields
+	}
+
+	return stats, scanner.Err()
+}
+
+// renameGlobal uses the namespace and the interface as Bleemeo item, the
+// interface name alone isn't unique across namespaces.
+func renameGlobal(gatherContext internal.GatherContext) (internal.GatherContext, bool) {
+	gatherContext.Annotations.BleemeoItem = gatherContext.Tags[labelNetns] + "_" + gatherContext.Tags[types.LabelItem]
+
+	return gatherContext, false
+}
+
+func transformMetrics(_ internal.GatherContext, fields map[string]float64, _ map[string]interface{}) map[string]float64 {
+	for metricName, value := range fields {
+		switch metricName {
+		case "bytes_sent":
+			delete(fields, "bytes_sent")
+			fields["bits_sent"] = value * 8
+		case "bytes_recv":
+			delete(fields, "bytes_recv")
+			fields["bits_recv"] = value * 8
+		}
+	}
+
+	return fields
+}
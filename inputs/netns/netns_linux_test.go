@@ -0,0 +1,151 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package netns
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bleemeo/glouton/inputs/internal"
+	"github.com/bleemeo/glouton/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const netDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:    1000      10    0    0    0     0          0         0     1000      10    0    0    0     0       0          0
+  eth0: 1234567    2345    1    2    0     0          0         0   765432    1234    3    4    0     0       0          0
+`
+
+func TestParseNetDev(t *testing.T) {
+	t.Parallel()
+
+	got, err := parseNetDev(strings.NewReader(netDev))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]map[string]interface{}{
+		"eth0": {
+			"bytes_recv":   uint64(1234567),
+			"packets_recv": uint64(2345),
+			"err_in":       uint64(1),
+			"drop_in":      uint64(2),
+			"bytes_sent":   uint64(765432),
+			"packets_sent": uint64(1234),
+			"err_out":      uint64(3),
+			"drop_out":     uint64(4),
+		},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("parseNetDev() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// writeFile creates a file and its parent directories.
+func writeFile(t *testing.T, name string, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// addProcess creates a fake /proc/<pid> whose network namespace is the file nsFile.
+func addProcess(t *testing.T, root string, pid string, comm string, nsFile string) {
+	t.Helper()
+
+	writeFile(t, filepath.Join(root, "proc", pid, "comm"), comm+"\n")
+	writeFile(t, filepath.Join(root, "proc", pid, "net", "dev"), netDev)
+
+	if err := os.MkdirAll(filepath.Join(root, "proc", pid, "ns"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(nsFile, filepath.Join(root, "proc", pid, "ns", "net")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGather(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	nsDir := filepath.Join(root, "namespaces")
+
+	for _, ns := range []string{"host", "vpn", "container", "other"} {
+		writeFile(t, filepath.Join(nsDir, ns), "")
+	}
+
+	addProcess(t, root, "1", "systemd", filepath.Join(nsDir, "host"))
+	addProcess(t, root, "42", "openvpn", filepath.Join(nsDir, "vpn"))
+	addProcess(t, root, "43", "bash", filepath.Join(nsDir, "vpn"))
+	addProcess(t, root, "100", "nginx", filepath.Join(nsDir, "container"))
+	addProcess(t, root, "200", "postgres", filepath.Join(nsDir, "other"))
+
+	if err := os.MkdirAll(filepath.Join(root, "var", "run", "netns"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(filepath.Join(nsDir, "vpn"), filepath.Join(root, "var", "run", "netns", "vpn-office")); err != nil {
+		t.Fatal(err)
+	}
+
+	input, _, err := New(root, []string{"vpn-*", "nginx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acc := &internal.StoreAccumulator{}
+
+	if err := input.Gather(acc); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]bool)
+
+	for _, measurement := range acc.Measurement {
+		got[measurement.Tags[labelNetns]+"/"+measurement.Tags[types.LabelItem]] = true
+	}
+
+	want := map[string]bool{
+		"vpn-office/eth0": true,
+		"nginx/eth0":      true,
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("gathered interfaces mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewInvalidPattern(t *testing.T) {
+	t.Parallel()
+
+	if _, _, err := New("/", []string{"vpn-["}); err == nil {
+		t.Error("New() with an invalid pattern didn't return an error")
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+
+package netns
+
+import (
+	"github.com/bleemeo/glouton/inputs"
+	"github.com/bleemeo/glouton/prometheus/registry"
+
+	"github.com/influxdata/telegraf"
+)
+
+// New returns an error wrapping inputs.ErrUnavailable, network namespaces are Linux-only.
+func New(_ string, _ []string) (telegraf.Input, registry.RegistrationOption, error) {
+	return nil, registry.RegistrationOption{}, inputs.ErrUnavailable
+}
@@ -0,0 +1,274 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package openvpn gathers the connected clients of an OpenVPN server, either from
+// its status file or from its management interface.
+package openvpn
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bleemeo/glouton/inputs/internal"
+	"github.com/bleemeo/glouton/prometheus/registry"
+
+	"github.com/influxdata/telegraf"
+)
+
+const managementTimeout = 5 * time.Second
+
+var (
+	errNoSource           = errors.New("a status file or a management address is required")
+	errUnsupportedScheme  = errors.New("unsupported management address scheme")
+	errManagementPassword = errors.New("management interface requires a password")
+	errNoClientList       = errors.New("no client list found in the status")
+)
+
+// client is a client connected to the OpenVPN server.
+type client struct {
+	commonName    string
+	bytesReceived float64
+	bytesSent     float64
+}
+
+type openvpnInput struct {
+	statusFile        string
+	managementNetwork string
+	managementAddress string
+	password          string
+	allowedClients    map[string]bool
+}
+
+// New returns an OpenVPN input. The clients are read from statusFile if it's set,
+// else from the management interface at managementURL ("tcp://host:port" or "unix:///path").
+// Per client metrics are only sent for the clients in allowedClients.
+func New(statusFile string, managementURL string, password string, allowedClients []string) (telegraf.Input, registry.RegistrationOption, error) {
+	input := &openvpnInput{
+		statusFile:     statusFile,
+		password:       password,
+		allowedClients: make(map[string]bool, len(allowedClients)),
+	}
+
+	for _, name := range allowedClients {
+		input.allowedClients[name] = true
+	}
+
+	if statusFile == "" {
+		if managementURL == "" {
+			return nil, registry.RegistrationOption{}, errNoSource
+		}
+
+		u, err := url.Parse(managementURL)
+		if err != nil {
+			return nil, registry.RegistrationOption{}, err
+		}
+
+		switch u.Scheme {
+		case "tcp":
+			input.managementNetwork, input.managementAddress = "tcp", u.Host
+		case "unix":
+			input.managementNetwork, input.managementAddress = "unix", u.Path
+		default:
+			return nil, registry.RegistrationOption{}, fmt.Errorf("%w: %s", errUnsupportedScheme, u.Scheme)
+		}
+	}
+
+	internalInput := &internal.Input{
+		Input: input,
+		Accumulator: internal.Accumulator{
+			DerivatedMetrics: []string{"client_bytes_received", "client_bytes_sent"},
+		},
+		Name: "openvpn",
+	}
+
+	return internalInput, registry.RegistrationOption{}, nil
+}
+
+// SampleConfig returns the default configuration of the input.
+func (i *openvpnInput) SampleConfig() string {
+	return ""
+}
+
+// Gather adds the number of connected clients and the traffic of the allowed clients.
+func (i *openvpnInput) Gather(acc telegraf.Accumulator) error {
+	clients, err := i.clients()
+	if err != nil {
+		return err
+	}
+
+	acc.AddFields("openvpn", map[string]interface{}{"connected_clients": len(clients)}, nil)
+
+	for _, c := range clients {
+		if !i.allowedClients[c.commonName] {
+			continue
+		}
+
+		acc.AddFields(
+			"openvpn",
+			map[string]interface{}{
+				"client_bytes_received": c.bytesReceived,
+				"client_bytes_sent":     c.bytesSent,
+			},
+			map[string]string{"client": c.commonName},
+		)
+	}
+
+	return nil
+}
+
+func (i *openvpnInput) clients() ([]client, error) {
+	if i.statusFile != "" {
+		file, err := os.Open(i.statusFile)
+		if err != nil {
+			return nil, err
+		}
+
+		defer file.Close()
+
+		return parseStatus(file)
+	}
+
+	conn, err := net.DialTimeout(i.managementNetwork, i.managementAddress, managementTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(managementTimeout)); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+
+	// The management interface may ask for a password before anything else.
+	// Otherwise it sends a greeting line starting with ">INFO:".
+	greeting, err := reader.ReadString(':')
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(greeting, "ENTER PASSWORD") {
+		if i.password == "" {
+			return nil, errManagementPassword
+		}
+
+		if _, err := fmt.Fprintf(conn, "%s\n", i.password); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := io.WriteString(conn, "status 2\n"); err != nil {
+		return nil, err
+	}
+
+	return parseStatus(reader)
+}
+
+// parseStatus parses the status output of OpenVPN, as written in the status file or
+// returned by the "status" command of the management interface. The versions 1, 2 and 3
+// of the format are supported.
+func parseStatus(r io.Reader) ([]client, error) {
+	var (
+		clients []client
+		// columns maps the column name to its index in the client lines.
+		columns      map[string]int
+		inClientList bool
+	)
+
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		separator := ","
+		if strings.Contains(line, "\t") {
+			separator = "\t"
+		}
+
+		fields := strings.Split(line, separator)
+
+		switch {
+		case line == "END":
+			return clients, nil
+		case strings.HasPrefix(line, ">"), strings.HasPrefix(line, "SUCCESS:"):
+			// Notifications and command results from the management interface.
+			continue
+		case fields[0] == "HEADER" && len(fields) > 2 && fields[1] == "CLIENT_LIST":
+			columns = columnIndexes(fields[2:])
+		case fields[0] == "CLIENT_LIST" && columns != nil:
+			clients = appendClient(clients, fields[1:], columns)
+		case fields[0] == "Common Name":
+			// Version 1: the client list starts with its header line.
+			columns = columnIndexes(fields)
+			inClientList = true
+		case line == "ROUTING TABLE":
+			inClientList = false
+		case inClientList:
+			clients = appendClient(clients, fields, columns)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// The status file may not contain the final END line if it was written
+	// while being read. Keep what we could parse in this case.
+	if columns == nil {
+		return nil, errNoClientList
+	}
+
+	return clients, nil
+}
+
+func columnIndexes(names []string) map[string]int {
+	columns := make(map[string]int, len(names))
+
+	for i, name := range names {
+		columns[name] = i
+	}
+
+	return columns
+}
+
+func appendClient(clients []client, fields []string, columns map[string]int) []client {
+	value := func(name string) string {
+		idx, ok := columns[name]
+		if !ok || idx >= len(fields) {
+			return ""
+		}
+
+		return fields[idx]
+	}
+
+	received, _ := strconv.ParseFloat(value("Bytes Received"), 64)
+	sent, _ := strconv.ParseFloat(value("Bytes Sent"), 64)
+
+	return append(clients, client{
+		commonName:    value("Common Name"),
+		bytesReceived: received,
+		bytesSent:     sent,
+	})
+}
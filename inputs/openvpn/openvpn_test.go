@@ -0,0 +1,100 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openvpn
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		status string
+		want   []client
+	}{
+		{
+			name: "version-1",
+			status: `OpenVPN CLIENT LIST
+Updated,Thu Jun 18 08:12:15 2015
+Common Name,Real Address,Bytes Received,Bytes Sent,Connected Since
+alice,1.2.3.4:1234,4577,4549,Thu Jun 18 08:12:09 2015
+bob,1.2.3.5:1234,100,200,Thu Jun 18 08:12:09 2015
+ROUTING TABLE
+Virtual Address,Common Name,Real Address,Last Ref
+10.8.0.6,alice,1.2.3.4:1234,Thu Jun 18 08:12:09 2015
+GLOBAL STATS
+Max bcast/mcast queue length,0
+END
+`,
+			want: []client{
+				{commonName: "alice", bytesReceived: 4577, bytesSent: 4549},
+				{commonName: "bob", bytesReceived: 100, bytesSent: 200},
+			},
+		},
+		{
+			name: "version-2-management",
+			status: `>INFO:OpenVPN Management Interface Version 3 -- type 'help' for more info
+TITLE,OpenVPN 2.5.1 x86_64-pc-linux-gnu
+TIME,Thu Jun 18 08:12:15 2015,1434615135
+HEADER,CLIENT_LIST,Common Name,Real Address,Virtual Address,Virtual IPv6 Address,Bytes Received,Bytes Sent,Connected Since
+CLIENT_LIST,alice,1.2.3.4:1234,10.8.0.6,,4577,4549,Thu Jun 18 08:12:09 2015
+HEADER,ROUTING_TABLE,Virtual Address,Common Name,Real Address,Last Ref
+ROUTING_TABLE,10.8.0.6,alice,1.2.3.4:1234,Thu Jun 18 08:12:09 2015
+END
+`,
+			want: []client{
+				{commonName: "alice", bytesReceived: 4577, bytesSent: 4549},
+			},
+		},
+		{
+			name: "version-3",
+			status: "HEADER\tCLIENT_LIST\tCommon Name\tReal Address\tBytes Received\tBytes Sent\n" +
+				"CLIENT_LIST\talice\t1.2.3.4:1234\t10\t20\n" +
+				"END\n",
+			want: []client{
+				{commonName: "alice", bytesReceived: 10, bytesSent: 20},
+			},
+		},
+		{
+			name: "no-client",
+			status: `HEADER,CLIENT_LIST,Common Name,Real Address,Bytes Received,Bytes Sent
+END
+`,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseStatus(strings.NewReader(tt.status))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(client{})); diff != "" {
+				t.Errorf("parseStatus() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
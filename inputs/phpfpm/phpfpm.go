@@ -34,17 +34,19 @@ import (
 var errInputCreation = errors.New("error during creation of PHP-FPM input")
 
 // We use a dedicated function to be able to recover from a panic.
-func reflectSet(url string, input telegraf.Input) {
+func reflectSet(urls []string, input telegraf.Input) {
 	inputValue := reflect.Indirect(reflect.ValueOf(input))
 	serverValue := inputValue.FieldByName("Urls")
-	serverValue.Set(reflect.ValueOf(append(make([]string, 0), url)))
+	serverValue.Set(reflect.ValueOf(append(make([]string, 0, len(urls)), urls...)))
 
 	timeoutValue := inputValue.FieldByName("Timeout")
 	timeoutValue.Set(reflect.ValueOf(config.Duration(10 * time.Second)))
 }
 
 // New initialise phpfpm.Input.
-func New(url string) (i telegraf.Input, err error) {
+//
+// Each URL is the status page of a pool, the metrics have a "pool" label.
+func New(urls []string) (i telegraf.Input, err error) {
 	input, ok := telegraf_inputs.Inputs["phpfpm"]
 	if ok {
 		phpfpmInput := input()
@@ -55,7 +57,7 @@ func New(url string) (i telegraf.Input, err error) {
 					err = fmt.Errorf("%w: %v", errInputCreation, r)
 				}
 			}()
-			reflectSet(url, phpfpmInput)
+			reflectSet(urls, phpfpmInput)
 		}()
 
 		if err != nil {
@@ -0,0 +1,158 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+// Package pressure gathers the Linux pressure stall information (PSI).
+package pressure
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bleemeo/glouton/inputs"
+	"github.com/bleemeo/glouton/inputs/internal"
+	"github.com/bleemeo/glouton/prometheus/registry"
+
+	"github.com/influxdata/telegraf"
+)
+
+// resources are the files read in /proc/pressure.
+var resources = []string{"cpu", "memory", "io"} //nolint:gochecknoglobals
+
+var errInvalidLine = errors.New("invalid line")
+
+type pressureInput struct {
+	pressureDir string
+}
+
+// New returns an input gathering the pressure stall information from /proc/pressure
+// in the host root. An error wrapping inputs.ErrUnavailable is returned when the kernel
+// doesn't expose PSI (kernel older than 4.20 or booted with psi=0).
+func New(hostRootPath string) (telegraf.Input, registry.RegistrationOption, error) {
+	pressureDir := filepath.Join(hostRootPath, "proc", "pressure")
+
+	if _, err := os.Stat(filepath.Join(pressureDir, "cpu")); err != nil {
+		return nil, registry.RegistrationOption{}, fmt.Errorf("%w: %w", inputs.ErrUnavailable, err)
+	}
+
+	internalInput := &internal.Input{
+		Input: &pressureInput{pressureDir: pressureDir},
+		Name:  "pressure",
+	}
+
+	return internalInput, registry.RegistrationOption{}, nil
+}
+
+// SampleConfig returns the default configuration of the input.
+func (i *pressureInput) SampleConfig() string {
+	return ""
+}
+
+// Gather reads the PSI files and adds the average pressures, e.g. pressure_cpu_some_avg10.
+func (i *pressureInput) Gather(acc telegraf.Accumulator) error {
+	fields := make(map[string]interface{})
+
+	for _, resource := range resources {
+		values, err := readPressureFile(filepath.Join(i.pressureDir, resource))
+		if err != nil {
+			// The memory and io files could be missing when the cgroup controller is disabled.
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			acc.AddError(err)
+
+			continue
+		}
+
+		for name, value := range values {
+			fields[resource+"_"+name] = value
+		}
+	}
+
+	if len(fields) > 0 {
+		acc.AddFields("pressure", fields, nil)
+	}
+
+	return nil
+}
+
+func readPressureFile(path string) (map[string]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	values, err := parsePressure(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return values, nil
+}
+
+// parsePressure parses a PSI file, which contains lines such as:
+//
+//	some avg10=0.12 avg60=0.05 avg300=0.01 total=123456
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//
+// It returns the averages keyed by "some_avg10", "full_avg60", etc.
+// The total stall time is ignored.
+func parsePressure(r io.Reader) (map[string]float64, error) {
+	values := make(map[string]float64)
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 0 {
+			continue
+		}
+
+		kind := parts[0]
+		if kind != "some" && kind != "full" {
+			return nil, fmt.Errorf("%w: %q", errInvalidLine, scanner.Text())
+		}
+
+		for _, part := range parts[1:] {
+			name, rawValue, ok := strings.Cut(part, "=")
+			if !ok {
+				return nil, fmt.Errorf("%w: %q", errInvalidLine, scanner.Text())
+			}
+
+			if !strings.HasPrefix(name, "avg") {
+				continue
+			}
+
+			value, err := strconv.ParseFloat(rawValue, 64)
+			if err != nil {
+				return nil, err
+			}
+
+			values[kind+"_"+name] = value
+		}
+	}
+
+	return values, scanner.Err()
+}
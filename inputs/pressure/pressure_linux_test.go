@@ -0,0 +1,68 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package pressure
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bleemeo/glouton/inputs"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParsePressure(t *testing.T) {
+	t.Parallel()
+
+	content := `some avg10=1.53 avg60=0.87 avg300=0.29 total=37158442
+full avg10=0.25 avg60=0.10 avg300=0.03 total=10238490
+`
+
+	want := map[string]float64{
+		"some_avg10":  1.53,
+		"some_avg60":  0.87,
+		"some_avg300": 0.29,
+		"full_avg10":  0.25,
+		"full_avg60":  0.10,
+		"full_avg300": 0.03,
+	}
+
+	got, err := parsePressure(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("parsePressure() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := parsePressure(strings.NewReader("invalid avg10=1\n")); !errors.Is(err, errInvalidLine) {
+		t.Errorf("parsePressure() error = %v, want %v", err, errInvalidLine)
+	}
+}
+
+func TestNewWithoutPSI(t *testing.T) {
+	t.Parallel()
+
+	_, _, err := New(t.TempDir())
+	if !errors.Is(err, inputs.ErrUnavailable) {
+		t.Errorf("New() error = %v, want %v", err, inputs.ErrUnavailable)
+	}
+}
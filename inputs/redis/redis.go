@@ -107,7 +107,10 @@ func getUnexportedField(object interface{}, fieldName string) (field interface{}
 }
 
 // New initialise redis.Input.
-func New(url string, password string) (i telegraf.Input, err error) {
+// url is either "tcp://host:port" or "unix:///path/to/redis.sock".
+// When username is empty, the legacy AUTH with only the password (requirepass) is used,
+// else the ACL user is used.
+func New(url string, username string, password string) (i telegraf.Input, err error) {
 	input, ok := telegraf_inputs.Inputs["redis"]
 	if ok {
 		redisInput, ok := input().(*redis.Redis)
@@ -115,6 +118,7 @@ func New(url string, password string) (i telegraf.Input, err error) {
 			slice := append(make([]string, 0), url)
 			redisInput.Servers = slice
 			redisInput.Log = internal.Logger{}
+			redisInput.Username = username
 			redisInput.Password = password
 			i = &internal.Input{
 				Input: redisServiceInput{redisInput},
@@ -0,0 +1,247 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sqlquery converts the result of an SQL query to metrics.
+package sqlquery
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bleemeo/glouton/config"
+	"github.com/bleemeo/glouton/inputs/internal"
+	"github.com/bleemeo/glouton/logger"
+	"github.com/bleemeo/glouton/prometheus/registry"
+
+	_ "github.com/go-sql-driver/mysql" // Register the mysql driver.
+	"github.com/influxdata/telegraf"
+	_ "github.com/jackc/pgx/v4/stdlib" // Register the postgres driver as "pgx".
+	"github.com/prometheus/common/model"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+	defaultMaxRows = 100
+)
+
+var (
+	errUnknownDriver     = errors.New("unknown driver, must be postgres or mysql")
+	errMissingQuery      = errors.New("the query is missing")
+	errMissingDSN        = errors.New("the dsn is missing")
+	errNoMetrics         = errors.New("at least one metric column is required")
+	errMissingColumn     = errors.New("column not found in the result")
+	errInvalidMetricName = errors.New("invalid metric name")
+)
+
+//nolint:gochecknoglobals
+var invalidLabelNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// drivers maps the driver names of the configuration to the database/sql drivers.
+//
+//nolint:gochecknoglobals
+var drivers = map[string]string{
+	"postgres":   "pgx",
+	"postgresql": "pgx",
+	"mysql":      "mysql",
+}
+
+type sqlInput struct {
+	name    string
+	query   string
+	metrics map[string]string
+	labels  []string
+	timeout time.Duration
+	maxRows int
+
+	db *sql.DB
+}
+
+// New returns an input running the query and converting the configured columns to metrics.
+func New(query config.SQLQuery) (telegraf.Input, registry.RegistrationOption, error) {
+	driver, ok := drivers[query.Driver]
+	if !ok {
+		return nil, registry.RegistrationOption{}, fmt.Errorf("%w: %q", errUnknownDriver, query.Driver)
+	}
+
+	switch {
+	case query.DSN == "":
+		return nil, registry.RegistrationOption{}, errMissingDSN
+	case query.Query == "":
+		return nil, registry.RegistrationOption{}, errMissingQuery
+	case len(query.Metrics) == 0:
+		return nil, registry.RegistrationOption{}, errNoMetrics
+	}
+
+	for _, metricName := range query.Metrics {
+		if !model.IsValidMetricName(model.LabelValue(metricName)) {
+			return nil, registry.RegistrationOption{}, fmt.Errorf("%w: %q", errInvalidMetricName, metricName)
+		}
+	}
+
+	// Open doesn't connect to the database, the connection is made on the first query.
+	db, err := sql.Open(driver, query.DSN)
+	if err != nil {
+		return nil, registry.RegistrationOption{}, err
+	}
+
+	db.SetMaxOpenConns(1)
+
+	input := &sqlInput{
+		name:    query.Name,
+		query:   query.Query,
+		metrics: query.Metrics,
+		labels:  query.Labels,
+		timeout: time.Duration(query.Timeout) * time.Second,
+		maxRows: query.MaxRows,
+		db:      db,
+	}
+
+	if input.timeout <= 0 {
+		input.timeout = defaultTimeout
+	}
+
+	if input.maxRows <= 0 {
+		input.maxRows = defaultMaxRows
+	}
+
+	internalInput := &internal.Input{
+		Input: input,
+		Name:  "sql",
+	}
+
+	options := registry.RegistrationOption{
+		Description: "SQL query " + query.Name,
+		Interval:    time.Duration(query.Interval) * time.Second,
+		Timeout:     input.timeout + 5*time.Second,
+	}
+
+	return internalInput, options, nil
+}
+
+// SampleConfig returns the default configuration of the input.
+func (i *sqlInput) SampleConfig() string {
+	return ""
+}
+
+// Start implements telegraf.ServiceInput. The connection is only made on the first query.
+func (i *sqlInput) Start(telegraf.Accumulator) error {
+	return nil
+}
+
+// Stop closes the connections to the database.
+func (i *sqlInput) Stop() {
+	if err := i.db.Close(); err != nil {
+		logger.V(1).Printf("Failed to close the database of the SQL query %s: %v", i.name, err)
+	}
+}
+
+// Gather runs the query and adds a point per metric column and row.
+func (i *sqlInput) Gather(acc telegraf.Accumulator) error {
+	ctx, cancel := context.WithTimeout(context.Background(), i.timeout)
+	defer cancel()
+
+	rows, err := i.db.QueryContext(ctx, i.query)
+	if err != nil {
+		return fmt.Errorf("query %s: %w", i.name, err)
+	}
+
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	columnIndex := make(map[string]int, len(columns))
+
+	for idx, column := range columns {
+		columnIndex[column] = idx
+	}
+
+	for _, column := range i.labels {
+		if _, ok := columnIndex[column]; !ok {
+			return fmt.Errorf("query %s: %w: %s", i.name, errMissingColumn, column)
+		}
+	}
+
+	for column := range i.metrics {
+		if _, ok := columnIndex[column]; !ok {
+			return fmt.Errorf("query %s: %w: %s", i.name, errMissingColumn, column)
+		}
+	}
+
+	values := make([]sql.NullString, len(columns))
+	scanArgs := make([]any, len(columns))
+
+	for idx := range values {
+		scanArgs[idx] = &values[idx]
+	}
+
+	for count := 0; count < i.maxRows && rows.Next(); count++ {
+		if err := rows.Scan(scanArgs...); err != nil {
+			return fmt.Errorf("query %s: %w", i.name, err)
+		}
+
+		tags := make(map[string]string, len(i.labels))
+
+		for _, column := range i.labels {
+			tags[labelName(column)] = values[columnIndex[column]].String
+		}
+
+		fields := make(map[string]any, len(i.metrics))
+
+		for column, metricName := range i.metrics {
+			value := values[columnIndex[column]]
+			if !value.Valid {
+				continue
+			}
+
+			floatValue, err := strconv.ParseFloat(value.String, 64)
+			if err != nil {
+				acc.AddError(fmt.Errorf("query %s: column %s: %w", i.name, column, err))
+
+				continue
+			}
+
+			fields[metricName] = floatValue
+		}
+
+		if len(fields) > 0 {
+			// An empty measurement keeps the metric names as configured.
+			acc.AddFields("", fields, tags)
+		}
+	}
+
+	return rows.Err()
+}
+
+// labelName returns a valid label name for a column. Invalid characters are replaced
+// by underscores and the name can't start with a digit or with the reserved "__" prefix.
+func labelName(column string) string {
+	name := strings.TrimLeft(invalidLabelNameChars.ReplaceAllString(column, "_"), "_")
+
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "label_" + name
+	}
+
+	return name
+}
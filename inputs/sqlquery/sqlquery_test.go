@@ -0,0 +1,282 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlquery
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/bleemeo/glouton/config"
+	"github.com/bleemeo/glouton/inputs/internal"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var errNotSupported = errors.New("not supported")
+
+// fakeDriver is a database/sql driver returning the same result for any query.
+type fakeDriver struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	return fakeConn(d), nil
+}
+
+type fakeConn fakeDriver
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) {
+	return fakeStmt(c), nil
+}
+
+func (c fakeConn) Close() error {
+	return nil
+}
+
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return nil, errNotSupported
+}
+
+type fakeStmt fakeDriver
+
+func (s fakeStmt) Close() error {
+	return nil
+}
+
+func (s fakeStmt) NumInput() int {
+	return 0
+}
+
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errNotSupported
+}
+
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &fakeRows{columns: s.columns, rows: s.rows}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+	next    int
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[r.next])
+	r.next++
+
+	return nil
+}
+
+func newTestInput(t *testing.T, fake fakeDriver, query config.SQLQuery) *sqlInput {
+	t.Helper()
+
+	sql.Register(t.Name(), fake)
+
+	db, err := sql.Open(t.Name(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return &sqlInput{
+		name:    query.Name,
+		query:   query.Query,
+		metrics: query.Metrics,
+		labels:  query.Labels,
+		timeout: time.Second,
+		maxRows: query.MaxRows,
+		db:      db,
+	}
+}
+
+func TestGather(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeDriver{
+		columns: []string{"status", "total", "amount"},
+		rows: [][]driver.Value{
+			{"paid", int64(12), []byte("1250.5")},
+			{"pending", int64(3), nil},
+			{"cancelled", int64(1), []byte("0")},
+		},
+	}
+
+	input := newTestInput(t, fake, config.SQLQuery{
+		Name:    "orders",
+		Query:   "SELECT status, count(*), sum(amount) FROM orders GROUP BY status",
+		Metrics: map[string]string{"total": "shop_orders", "amount": "shop_orders_amount"},
+		Labels:  []string{"status"},
+		MaxRows: 2,
+	})
+
+	acc := &internal.StoreAccumulator{}
+
+	if err := input.Gather(acc); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []internal.Measurement{
+		{
+			Tags:   map[string]string{"status": "paid"},
+			Fields: map[string]any{"shop_orders": 12.0, "shop_orders_amount": 1250.5},
+		},
+		{
+			// The NULL amount is skipped.
+			Tags:   map[string]string{"status": "pending"},
+			Fields: map[string]any{"shop_orders": 3.0},
+		},
+		// The third row is ignored because of MaxRows.
+	}
+
+	if diff := cmp.Diff(want, acc.Measurement); diff != "" {
+		t.Errorf("metrics mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGatherMissingColumn(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeDriver{
+		columns: []string{"total"},
+		rows:    [][]driver.Value{{int64(1)}},
+	}
+
+	input := newTestInput(t, fake, config.SQLQuery{
+		Query:   "SELECT count(*) AS total FROM orders",
+		Metrics: map[string]string{"count": "shop_orders"},
+	})
+
+	err := input.Gather(&internal.StoreAccumulator{})
+	if !errors.Is(err, errMissingColumn) {
+		t.Errorf("Gather() error = %v, want %v", err, errMissingColumn)
+	}
+}
+
+func TestGatherLabelNames(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeDriver{
+		columns: []string{"order status", "__name__", "1st", "total"},
+		rows:    [][]driver.Value{{"paid", "orders", "yes", int64(12)}},
+	}
+
+	input := newTestInput(t, fake, config.SQLQuery{
+		Query:   "SELECT status AS \"order status\", ...",
+		Metrics: map[string]string{"total": "shop_orders"},
+		Labels:  []string{"order status", "__name__", "1st"},
+		MaxRows: 10,
+	})
+
+	acc := &internal.StoreAccumulator{}
+
+	if err := input.Gather(acc); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []internal.Measurement{
+		{
+			Tags:   map[string]string{"order_status": "paid", "name__": "orders", "label_1st": "yes"},
+			Fields: map[string]any{"shop_orders": 12.0},
+		},
+	}
+
+	if diff := cmp.Diff(want, acc.Measurement); diff != "" {
+		t.Errorf("metrics mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestStop(t *testing.T) {
+	t.Parallel()
+
+	input := newTestInput(t, fakeDriver{}, config.SQLQuery{Query: "SELECT 1"})
+
+	input.Stop()
+
+	if err := input.db.Ping(); err == nil {
+		t.Error("the database is still open after Stop()")
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		query   config.SQLQuery
+		wantErr error
+	}{
+		{
+			name:    "unknown driver",
+			query:   config.SQLQuery{Driver: "oracle", DSN: "x", Query: "SELECT 1", Metrics: map[string]string{"a": "b"}},
+			wantErr: errUnknownDriver,
+		},
+		{
+			name:    "missing query",
+			query:   config.SQLQuery{Driver: "mysql", DSN: "x", Metrics: map[string]string{"a": "b"}},
+			wantErr: errMissingQuery,
+		},
+		{
+			name:    "no metrics",
+			query:   config.SQLQuery{Driver: "postgres", DSN: "x", Query: "SELECT 1"},
+			wantErr: errNoMetrics,
+		},
+		{
+			name:    "invalid metric name",
+			query:   config.SQLQuery{Driver: "postgres", DSN: "x", Query: "SELECT 1", Metrics: map[string]string{"a": "shop-orders"}},
+			wantErr: errInvalidMetricName,
+		},
+		{
+			name: "valid",
+			query: config.SQLQuery{
+				Driver:  "postgres",
+				DSN:     "postgres://localhost/db",
+				Query:   "SELECT 1 AS one",
+				Metrics: map[string]string{"one": "one"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, _, err := New(test.query)
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("New() error = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
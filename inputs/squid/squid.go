@@ -0,0 +1,239 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package squid gathers the statistics of a Squid proxy from its cache manager,
+// either with HTTP requests or with the squidclient command.
+package squid
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bleemeo/glouton/config"
+	"github.com/bleemeo/glouton/inputs/internal"
+	"github.com/bleemeo/glouton/prometheus/registry"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	managerTimeout = 5 * time.Second
+	// defaultUsername is the user name sent with the cache manager password.
+	// Squid only checks the password, the user name is only logged.
+	defaultUsername = "admin"
+	// sourceSquidclient is the stats_protocol used to run squidclient.
+	sourceSquidclient = "squidclient"
+)
+
+var (
+	errUnexpectedStatus = errors.New("unexpected status code")
+	errAccessDenied     = errors.New("access to the cache manager denied, check the password")
+)
+
+type squidInput struct {
+	// managerURL is the base URL of the cache manager, like "http://127.0.0.1:3128/squid-internal-mgr/".
+	managerURL     string
+	useSquidclient bool
+	host           string
+	port           string
+	username       string
+	password       string
+	httpClient     *http.Client
+}
+
+// New returns a Squid input. By default the cache manager is queried with HTTP on
+// the service address, or on the stats_url of the service. When stats_protocol is
+// "squidclient", the squidclient command is used instead. The password is the
+// cachemgr_passwd of Squid.
+func New(address string, config config.Service) (telegraf.Input, registry.RegistrationOption, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, registry.RegistrationOption{}, err
+	}
+
+	input := &squidInput{
+		managerURL:     config.StatsURL,
+		useSquidclient: config.StatsProtocol == sourceSquidclient,
+		host:           host,
+		port:           port,
+		username:       config.Username,
+		password:       config.Password,
+		httpClient:     &http.Client{Timeout: managerTimeout},
+	}
+
+	if input.managerURL == "" {
+		input.managerURL = "http://" + address + "/squid-internal-mgr/"
+	}
+
+	if !strings.HasSuffix(input.managerURL, "/") {
+		input.managerURL += "/"
+	}
+
+	if input.username == "" {
+		input.username = defaultUsername
+	}
+
+	internalInput := &internal.Input{
+		Input: input,
+		Accumulator: internal.Accumulator{
+			DerivatedMetrics: []string{"requests", "server_requests"},
+		},
+		Name: "squid",
+	}
+
+	return internalInput, registry.RegistrationOption{}, nil
+}
+
+// SampleConfig returns the default configuration of the input.
+func (i *squidInput) SampleConfig() string {
+	return ""
+}
+
+// Gather adds the request rates, the cache hit ratio and the number of clients.
+func (i *squidInput) Gather(acc telegraf.Accumulator) error {
+	info, err := i.query("info")
+	if err != nil {
+		return err
+	}
+
+	counters, err := i.query("counters")
+	if err != nil {
+		return err
+	}
+
+	fields := parseInfo(info)
+
+	for name, value := range parseCounters(counters) {
+		fields[name] = value
+	}
+
+	acc.AddFields("squid", fields, nil)
+
+	return nil
+}
+
+// query returns the output of a cache manager action.
+func (i *squidInput) query(action string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), managerTimeout)
+	defer cancel()
+
+	if i.useSquidclient {
+		request := "mgr:" + action
+		if i.password != "" {
+			request += "@" + i.password
+		}
+
+		return exec.CommandContext(ctx, "squidclient", "-h", i.host, "-p", i.port, request).Output() //nolint:gosec
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, i.managerURL+action, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if i.password != "" {
+		req.SetBasicAuth(i.username, i.password)
+	}
+
+	resp, err := i.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, errAccessDenied
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnexpectedStatus, resp.Status)
+	}
+}
+
+// parseInfo returns the number of clients and the cache hit ratio (over the
+// last 5 minutes) from the output of the "info" action.
+func parseInfo(data []byte) map[string]interface{} {
+	fields := make(map[string]interface{})
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		name, value, found := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !found {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+
+		switch name {
+		case "Number of clients accessing cache":
+			if clients, err := strconv.ParseFloat(value, 64); err == nil {
+				fields["clients"] = clients
+			}
+		case "Request Hit Ratios", "Hits as % of all requests":
+			// The value looks like "5min: 12.5%, 60min: 10.0%".
+			value, _, _ = strings.Cut(value, ",")
+			value = strings.TrimSuffix(strings.TrimSpace(strings.TrimPrefix(value, "5min:")), "%")
+
+			if ratio, err := strconv.ParseFloat(value, 64); err == nil {
+				fields["hit_ratio"] = ratio
+			}
+		}
+	}
+
+	return fields
+}
+
+// parseCounters returns the number of client and server requests
+// from the output of the "counters" action.
+func parseCounters(data []byte) map[string]interface{} {
+	names := map[string]string{
+		"client_http.requests": "requests",
+		"server.all.requests":  "server_requests",
+	}
+
+	fields := make(map[string]interface{})
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		name, value, found := strings.Cut(scanner.Text(), "=")
+		if !found {
+			continue
+		}
+
+		field, ok := names[strings.TrimSpace(name)]
+		if !ok {
+			continue
+		}
+
+		if count, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+			fields[field] = count
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,188 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package squid
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bleemeo/glouton/config"
+	"github.com/bleemeo/glouton/inputs/internal"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const infoOutput = `HTTP/1.1 200 OK
+Server: squid/4.13
+Squid Object Cache: Version 4.13
+Service Name: squid
+Start Time:	Mon, 04 Mar 2024 09:00:00 GMT
+Current Time:	Mon, 04 Mar 2024 10:00:00 GMT
+Connection information for squid:
+	Number of clients accessing cache:	3
+	Number of HTTP requests received:	1234
+	Number of ICP messages received:	0
+	Request failure ratio:	 0.00
+	Average HTTP requests per minute since start:	20.6
+Cache information for squid:
+	Hits as % of all requests:	5min: 12.5%, 60min: 10.0%
+	Hits as % of bytes sent:	5min: 3.0%, 60min: 2.0%
+	Memory hits as % of hit requests:	5min: 50.0%, 60min: 40.0%
+File descriptor usage for squid:
+	Maximum number of file descriptors:   1024
+	Number of file desc currently in use:   12
+`
+
+const countersOutput = `sample_time = 1709546400.000000 (Mon, 04 Mar 2024 10:00:00 GMT)
+client_http.requests = 1234
+client_http.hits = 154
+client_http.errors = 2
+server.all.requests = 1080
+server.all.errors = 0
+`
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	want := map[string]interface{}{
+		"clients":   3.0,
+		"hit_ratio": 12.5,
+	}
+
+	if diff := cmp.Diff(want, parseInfo([]byte(infoOutput))); diff != "" {
+		t.Errorf("parseInfo() mismatch (-want +got):\n%s", diff)
+	}
+
+	want = map[string]interface{}{
+		"requests":        1234.0,
+		"server_requests": 1080.0,
+	}
+
+	if diff := cmp.Diff(want, parseCounters([]byte(countersOutput))); diff != "" {
+		t.Errorf("parseCounters() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestParseSquid3 checks the hit ratio format of the recent Squid versions.
+func TestParseSquid3(t *testing.T) {
+	t.Parallel()
+
+	info := "Cache information for squid:\n\tRequest Hit Ratios:\t5min: 33.3%, 60min: 30.0%\n"
+
+	if diff := cmp.Diff(map[string]interface{}{"hit_ratio": 33.3}, parseInfo([]byte(info))); diff != "" {
+		t.Errorf("parseInfo() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGatherHTTP(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, password, ok := r.BasicAuth(); !ok || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		switch r.URL.Path {
+		case "/squid-internal-mgr/info":
+			_, _ = w.Write([]byte(infoOutput))
+		case "/squid-internal-mgr/counters":
+			_, _ = w.Write([]byte(countersOutput))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	address := strings.TrimPrefix(srv.URL, "http://")
+
+	input, _, err := New(address, config.Service{Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acc := &internal.StoreAccumulator{}
+
+	if err := input.Gather(acc); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(acc.Measurement) != 1 {
+		t.Fatalf("got %d measurements, want 1", len(acc.Measurement))
+	}
+
+	// The requests counters are derivated, so they are only sent from the second gather.
+	want := map[string]interface{}{
+		"clients":   3.0,
+		"hit_ratio": 12.5,
+	}
+
+	if diff := cmp.Diff(want, acc.Measurement[0].Fields); diff != "" {
+		t.Errorf("fields mismatch (-want +got):\n%s", diff)
+	}
+
+	input, _, err = New(address, config.Service{Password: "wrong"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	squid, _ := input.(*internal.Input).Input.(*squidInput)
+
+	if err := squid.Gather(&internal.StoreAccumulator{}); !errors.Is(err, errAccessDenied) {
+		t.Errorf("Gather() error = %v, want %v", err, errAccessDenied)
+	}
+}
+
+func TestManagerURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		config config.Service
+		want   string
+	}{
+		{
+			name: "default",
+			want: "http://127.0.0.1:3128/squid-internal-mgr/",
+		},
+		{
+			name:   "stats-url",
+			config: config.Service{StatsURL: "http://proxy.local:8080/squid-internal-mgr"},
+			want:   "http://proxy.local:8080/squid-internal-mgr/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			input, _, err := New("127.0.0.1:3128", tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			squid, _ := input.(*internal.Input).Input.(*squidInput)
+			if squid.managerURL != tt.want {
+				t.Errorf("managerURL = %s, want %s", squid.managerURL, tt.want)
+			}
+		})
+	}
+}
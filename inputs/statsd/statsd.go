@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/bleemeo/glouton/inputs"
 	"github.com/bleemeo/glouton/inputs/internal"
@@ -42,13 +43,34 @@ func reflectSetPercentile(input *statsd.Statsd) {
 	percentilesValue.Set(slice)
 }
 
+// Options are the options of a StatsD listener.
+type Options struct {
+	// ServiceAddress is the address the listener binds to, like "127.0.0.1:8125".
+	ServiceAddress string
+	// Protocol is "udp" (the default), "udp4", "udp6", "tcp", "tcp4" or "tcp6".
+	Protocol string
+	// Templates are Graphite-like templates used to extract tags from the bucket name.
+	// The extracted tags are kept as labels.
+	Templates []string
+	// DatadogExtensions enables parsing of the DogStatsD tags ("|#tag1:v1,tag2:v2").
+	// The tags are kept as labels.
+	DatadogExtensions bool
+}
+
 // New initialise statsd.Input.
-func New(bindAddress string) (i telegraf.Input, err error) {
+func New(options Options) (i telegraf.Input, err error) {
 	input, ok := telegraf_inputs.Inputs["statsd"]
 	if ok {
 		statsdInput, ok := input().(*statsd.Statsd)
 		if ok {
-			statsdInput.ServiceAddress = bindAddress
+			statsdInput.ServiceAddress = options.ServiceAddress
+			statsdInput.Templates = options.Templates
+			statsdInput.DataDogExtensions = options.DatadogExtensions
+
+			if options.Protocol != "" {
+				statsdInput.Protocol = options.Protocol
+			}
+
 			statsdInput.DeleteGauges = false
 			statsdInput.DeleteCounters = false
 			statsdInput.DeleteTimings = true
@@ -69,7 +91,7 @@ func New(bindAddress string) (i telegraf.Input, err error) {
 			i = &internal.Input{
 				Input: statsdInput,
 				Accumulator: internal.Accumulator{
-					RenameGlobal:          renameGlobal,
+					RenameGlobal:          renameGlobal(len(options.Templates) > 0 || options.DatadogExtensions),
 					ShouldDerivateMetrics: shouldDerivateMetrics,
 					TransformMetrics:      transformMetrics,
 				},
@@ -85,12 +107,50 @@ func New(bindAddress string) (i telegraf.Input, err error) {
 	return i, nil
 }
 
-func renameGlobal(gatherContext internal.GatherContext) (internal.GatherContext, bool) {
-	gatherContext.Measurement = "statsd"
-	gatherContext.OriginalTags = gatherContext.Tags
-	gatherContext.Tags = nil
+// renameGlobal returns the RenameGlobal function of the accumulator. When keepTags is
+// true, the tags extracted by the templates or the DogStatsD tags are kept, except
+// the metric_type. Tag names are sanitized to be valid label names.
+func renameGlobal(keepTags bool) func(internal.GatherContext) (internal.GatherContext, bool) {
+	return func(gatherContext internal.GatherContext) (internal.GatherContext, bool) {
+		gatherContext.Measurement = "statsd"
+		gatherContext.OriginalTags = gatherContext.Tags
+		gatherContext.Tags = nil
+
+		if keepTags {
+			tags := make(map[string]string, len(gatherContext.OriginalTags))
+
+			for k, v := range gatherContext.OriginalTags {
+				if k == "metric_type" || k == "temporality" {
+					continue
+				}
+
+				tags[sanitizeLabelName(k)] = v
+			}
+
+			if len(tags) > 0 {
+				gatherContext.Tags = tags
+			}
+		}
+
+		return gatherContext, false
+	}
+}
+
+// sanitizeLabelName replaces the characters not allowed in a label name by an underscore.
+func sanitizeLabelName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+
+		return '_'
+	}, name)
+
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
 
-	return gatherContext, false
+	return name
 }
 
 func shouldDerivateMetrics(currentContext internal.GatherContext, metricName string) bool {
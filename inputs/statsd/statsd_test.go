@@ -0,0 +1,136 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statsd
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bleemeo/glouton/inputs/internal"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/telegraf/plugins/inputs/statsd"
+)
+
+// TestDatadogTags feeds raw UDP payloads to the input and checks the labels of the resulting points.
+func TestDatadogTags(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name              string
+		datadogExtensions bool
+		payload           string
+		wantName          string
+		wantTags          map[string]string
+	}{
+		{
+			name:              "no-tags",
+			datadogExtensions: false,
+			payload:           "queue_size:12|g",
+			wantName:          "queue_size",
+			wantTags:          nil,
+		},
+		{
+			name:              "no-tags-with-extensions",
+			datadogExtensions: true,
+			payload:           "queue_size:12|g",
+			wantName:          "queue_size",
+			wantTags:          nil,
+		},
+		{
+			name:              "tags",
+			datadogExtensions: true,
+			payload:           "queue_size:12|g|#env:prod,app.name:web-front,canary",
+			wantName:          "queue_size",
+			wantTags: map[string]string{
+				"env":      "prod",
+				"app_name": "web-front",
+				"canary":   "true",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			input, err := New(Options{
+				ServiceAddress:    "127.0.0.1:0",
+				DatadogExtensions: tt.datadogExtensions,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			internalInput, _ := input.(*internal.Input)
+
+			if err := internalInput.Init(); err != nil {
+				t.Fatal(err)
+			}
+
+			acc := &internal.StoreAccumulator{}
+
+			if err := internalInput.Start(acc); err != nil {
+				t.Fatal(err)
+			}
+
+			t.Cleanup(internalInput.Stop)
+
+			statsdInput, _ := internalInput.Input.(*statsd.Statsd)
+
+			conn, err := net.Dial("udp", statsdInput.UDPlistener.LocalAddr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer conn.Close()
+
+			if _, err := conn.Write([]byte(tt.payload + "\n")); err != nil {
+				t.Fatal(err)
+			}
+
+			deadline := time.Now().Add(5 * time.Second)
+
+			for len(acc.Measurement) == 0 && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+
+				if err := internalInput.Gather(acc); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if len(acc.Measurement) != 1 {
+				t.Fatalf("got %d measurements, want 1", len(acc.Measurement))
+			}
+
+			got := acc.Measurement[0]
+
+			if got.Name != "statsd" {
+				t.Errorf("measurement = %s, want statsd", got.Name)
+			}
+
+			if _, ok := got.Fields[tt.wantName]; !ok {
+				t.Errorf("fields = %v, want field %s", got.Fields, tt.wantName)
+			}
+
+			if diff := cmp.Diff(tt.wantTags, got.Tags); diff != "" {
+				t.Errorf("tags mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -278,4 +278,5 @@ var (
 	ErrUnexpectedType = errors.New("input does not have the expected type")
 	ErrDisabledInput  = errors.New("input is not enabled in service Telegraf")
 	ErrMissingCommand = errors.New("missing command for input")
+	ErrUnavailable    = errors.New("input is unavailable on this system")
 )
@@ -29,8 +29,9 @@ import (
 	"github.com/influxdata/telegraf/plugins/inputs/upsd"
 )
 
-// New returns a UPSD input.
-func New(server string, port int, username, password string) (telegraf.Input, registry.RegistrationOption, error) {
+// New returns a UPSD input. All the UPS of the server are gathered,
+// unless allowedUPS is not empty, then only the UPS with these names are gathered.
+func New(server string, port int, username, password string, allowedUPS []string) (telegraf.Input, registry.RegistrationOption, error) {
 	input, ok := telegraf_inputs.Inputs["upsd"]
 	if !ok {
 		return nil, registry.RegistrationOption{}, inputs.ErrDisabledInput
@@ -49,7 +50,7 @@ func New(server string, port int, username, password string) (telegraf.Input, re
 	internalInput := &internal.Input{
 		Input: upsdInput,
 		Accumulator: internal.Accumulator{
-			RenameGlobal: renameGlobal,
+			RenameGlobal: renameGlobalWithAllowlist(allowedUPS),
 		},
 		Name: "UPSD",
 	}
@@ -70,6 +71,27 @@ func New(server string, port int, username, password string) (telegraf.Input, re
 	return internalInput, options, nil
 }
 
+// renameGlobalWithAllowlist returns a renameGlobal which drops the UPS not in the allowlist.
+func renameGlobalWithAllowlist(allowedUPS []string) func(internal.GatherContext) (internal.GatherContext, bool) {
+	if len(allowedUPS) == 0 {
+		return renameGlobal
+	}
+
+	allowed := make(map[string]bool, len(allowedUPS))
+
+	for _, name := range allowedUPS {
+		allowed[name] = true
+	}
+
+	return func(gatherContext internal.GatherContext) (internal.GatherContext, bool) {
+		if !allowed[gatherContext.Tags["ups_name"]] {
+			return gatherContext, true
+		}
+
+		return renameGlobal(gatherContext)
+	}
+}
+
 func renameGlobal(gatherContext internal.GatherContext) (result internal.GatherContext, drop bool) {
 	for name := range gatherContext.Tags {
 		// Status labels are added (status_OL, status_OB, ...) depending on the UPS state.
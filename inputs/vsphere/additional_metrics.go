@@ -19,6 +19,7 @@ package vsphere
 import (
 	"context"
 	"maps"
+	"strings"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -242,6 +243,157 @@ func additionalDatastoreIO(tags map[string]string, hostMOIDs map[string]bool, ac
 	return nil
 }
 
+func additionalDatastoreMetrics(ctx context.Context, client *vim25.Client, datastores []*object.Datastore, cache *propsCache[datastoreLightProps], acc telegraf.Accumulator, h *Hierarchy, t0 time.Time) error {
+	dsProps, err := retrieveProps(ctx, client, datastores, relevantDatastoreProperties, cache)
+	if err != nil {
+		return err
+	}
+
+	for ds, props := range dsProps {
+		capacity := props.Summary.Capacity
+		if capacity <= 0 {
+			// The capacity is unknown when the datastore is inaccessible.
+			continue
+		}
+
+		freeSpace := props.Summary.FreeSpace
+
+		fields := map[string]any{
+			"used_perc":  100 - (float64(freeSpace)*100)/float64(capacity),
+			"free_bytes": freeSpace,
+		}
+
+		tags := map[string]string{
+			"dcname": h.ParentDCName(ds),
+			"dsname": ds.Name(),
+			"moid":   ds.Reference().Value,
+		}
+
+		acc.AddFields("vsphere_datastore", fields, tags, t0)
+	}
+
+	return nil
+}
+
+// additionalResourcePoolMetrics emits the CPU usage (in MHz) and the memory usage (in bytes)
+// of each resource pool, aggregated from the VMs of the pool and of its nested pools.
+// The item is the path of the pool from the root pool, like "Resources/Production/Databases",
+// because nested pools with the same name could exist in different parent pools.
+func additionalResourcePoolMetrics(ctx context.Context, client *vim25.Client, resourcePools []*object.ResourcePool, vms []*object.VirtualMachine, caches *propsCaches, acc telegraf.Accumulator, h *Hierarchy, t0 time.Time) error {
+	poolProps, err := walkResourcePools(ctx, client, resourcePools, caches.resourcePoolCache)
+	if err != nil {
+		return err
+	}
+
+	vmProps, err := retrieveProps(ctx, client, vms, relevantVMProperties, caches.vmCache)
+	if err != nil {
+		return err
+	}
+
+	cpuUsagesMHz := make(map[string]int64, len(poolProps))
+	memUsagesMB := make(map[string]int64, len(poolProps))
+
+	for _, props := range vmProps {
+		if props.ResourcePool == nil {
+			continue
+		}
+
+		// The usage of a VM is accounted in its pool and in all the parent pools.
+		moid := props.ResourcePool.Value
+
+		for {
+			pool, ok := poolProps[moid]
+			if !ok {
+				break
+			}
+
+			cpuUsagesMHz[moid] += int64(props.Summary.QuickStats.OverallCpuUsage)
+			memUsagesMB[moid] += int64(props.Summary.QuickStats.HostMemoryUsage)
+
+			if pool.ManagedEntity.Parent == nil {
+				break
+			}
+
+			moid = pool.ManagedEntity.Parent.Value
+		}
+	}
+
+	for moid, props := range poolProps {
+		// The owner of a pool is the cluster, or the compute resource of a standalone host.
+		clusterName, _ := h.DeviceName(props.Owner.Value)
+
+		tags := map[string]string{
+			"clustername": clusterName,
+			"dcname":      h.ParentDCName(props.Owner),
+			"item":        resourcePoolPath(poolProps, moid),
+			"moid":        moid,
+		}
+
+		fields := map[string]any{
+			"cpu_usage": cpuUsagesMHz[moid],
+			"mem_usage": memUsagesMB[moid] * 1024 * 1024,
+		}
+
+		acc.AddFields("vsphere_resource_pool", fields, tags, t0)
+	}
+
+	return nil
+}
+
+// resourcePoolPath returns the names of the pool and of its parent pools, separated by slashes.
+func resourcePoolPath(poolProps map[string]resourcePoolLightProps, moid string) string {
+	var names []string
+
+	for {
+		pool, ok := poolProps[moid]
+		if !ok {
+			break
+		}
+
+		names = append([]string{pool.ManagedEntity.Name}, names...)
+
+		if pool.ManagedEntity.Parent == nil {
+			break
+		}
+
+		moid = pool.ManagedEntity.Parent.Value
+	}
+
+	return strings.Join(names, "/")
+}
+
+// walkResourcePools returns the properties of the given resource pools and of
+// all their nested pools, indexed by MOID.
+func walkResourcePools(ctx context.Context, client *vim25.Client, resourcePools []*object.ResourcePool, cache *propsCache[resourcePoolLightProps]) (map[string]resourcePoolLightProps, error) {
+	result := make(map[string]resourcePoolLightProps, len(resourcePools))
+	toVisit := make([]refName, 0, len(resourcePools))
+
+	for _, pool := range resourcePools {
+		toVisit = append(toVisit, refName{ref: pool.Reference(), name: pool.Name()})
+	}
+
+	for len(toVisit) > 0 {
+		poolProps, err := retrieveProps(ctx, client, toVisit, relevantResourcePoolProperties, cache)
+		if err != nil {
+			return nil, err
+		}
+
+		toVisit = toVisit[:0]
+
+		for pool, props := range poolProps {
+			result[pool.Reference().Value] = props
+
+			for _, child := range props.ResourcePool {
+				if _, visited := result[child.Value]; !visited {
+					toVisit = append(toVisit, refName{ref: child})
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
 func additionalHostMetrics(_ context.Context, _ *vim25.Client, hosts []*object.HostSystem, acc telegraf.Accumulator, h *Hierarchy, vmStatesPerHost map[string][]bool, t0 time.Time) error {
 	for _, host := range hosts {
 		moid := host.Reference().Value
@@ -293,8 +445,29 @@ func additionalVMMetrics(ctx context.Context, client *vim25.Client, vms []*objec
 			}
 		}
 
-		if props.Guest != nil {
+		// The power state of each VM allows alerting on a specific VM being stopped.
+		powerState := 0
+		if props.Runtime.PowerState == types.VirtualMachinePowerStatePoweredOn {
+			powerState = 1
+		}
+
+		vmTags := map[string]string{
+			"clustername": h.ParentClusterName(vm),
+			"dcname":      h.ParentDCName(vm),
+			"esxhostname": h.ParentHostName(vm),
+			"moid":        vm.Reference().Value,
+			"vmname":      vm.Name(),
+		}
+
+		acc.AddFields("vsphere_vm", map[string]any{"power_state": powerState}, vmTags, t0)
+
+		// The guest disks are only known and kept up to date by the VMware Tools.
+		if props.Guest != nil && props.Guest.ToolsRunningStatus == string(types.VirtualMachineToolsRunningStatusGuestToolsRunning) {
 			for _, disk := range props.Guest.Disk {
+				if disk.Capacity <= 0 {
+					continue
+				}
+
 				usage := 100 - (float64(disk.FreeSpace)*100)/float64(disk.Capacity) // Percentage of disk used
 
 				tags := map[string]string{
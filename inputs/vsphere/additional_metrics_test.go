@@ -0,0 +1,297 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsphere
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bleemeo/glouton/inputs"
+	"github.com/bleemeo/glouton/prometheus/registry"
+	"github.com/bleemeo/glouton/types"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// TestAdditionalVMMetricsGuestDisks checks that the guest disks usage is gathered
+// from the VMware Tools, and that VMs whose tools aren't running are skipped.
+func TestAdditionalVMMetricsGuestDisks(t *testing.T) {
+	vSphereCfg, deferFn := setupVSphereAPITest(t, "esxi_1")
+	defer deferFn()
+
+	ctx, cancel := context.WithTimeout(context.Background(), commonTimeout)
+	defer cancel()
+
+	finder, client, err := newDeviceFinder(ctx, vSphereCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clusters, _, resourcePools, hosts, vms, err := findDevices(ctx, finder, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caches := newPropsCaches()
+	hierarchy := NewHierarchy()
+
+	err = hierarchy.Refresh(ctx, clusters, nil, resourcePools, hosts, vms, caches.vmCache)
+	if err != nil {
+		t.Fatal("Failed to refresh hierarchy:", err)
+	}
+
+	pointBuffer := new(registry.PointBuffer)
+	acc := inputs.Accumulator{
+		Pusher:  pointBuffer,
+		Context: ctx,
+	}
+
+	err = additionalVMMetrics(ctx, client, vms, caches.vmCache, &acc, hierarchy, make(map[string][]bool), time.Now())
+	if err != nil {
+		t.Fatal("Failed to gather additional VM metrics:", err)
+	}
+
+	type diskUsage struct {
+		VMName string
+		Item   string
+		Value  float64
+	}
+
+	var got []diskUsage
+
+	for _, point := range pointBuffer.Points() {
+		if point.Labels[types.LabelName] != "vsphere_vm_disk_used_perc" {
+			continue
+		}
+
+		got = append(got, diskUsage{
+			VMName: point.Labels["vmname"],
+			Item:   point.Labels[types.LabelItem],
+			Value:  point.Value,
+		})
+	}
+
+	// The VM "lunar" also reports a guest disk, but its tools aren't running.
+	expected := []diskUsage{
+		{VMName: "alp1", Item: "/", Value: 6.161370103719307},
+		{VMName: "alp1", Item: "/boot", Value: 27.40969523872782},
+	}
+
+	opts := []cmp.Option{
+		cmpopts.EquateApprox(0, 1e-9),
+		cmpopts.SortSlices(func(x, y diskUsage) bool { return x.VMName+x.Item < y.VMName+y.Item }),
+	}
+
+	if diff := cmp.Diff(expected, got, opts...); diff != "" {
+		t.Fatalf("Unexpected disk usage metrics (-want +got):\n%s", diff)
+	}
+}
+
+// TestAdditionalDatastoreMetrics checks the capacity metrics of the datastores.
+func TestAdditionalDatastoreMetrics(t *testing.T) {
+	vSphereCfg, deferFn := setupVSphereAPITest(t, "esxi_1")
+	defer deferFn()
+
+	ctx, cancel := context.WithTimeout(context.Background(), commonTimeout)
+	defer cancel()
+
+	finder, client, err := newDeviceFinder(ctx, vSphereCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clusters, datastores, resourcePools, hosts, vms, err := findDevices(ctx, finder, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caches := newPropsCaches()
+	hierarchy := NewHierarchy()
+
+	err = hierarchy.Refresh(ctx, clusters, datastores, resourcePools, hosts, vms, caches.vmCache)
+	if err != nil {
+		t.Fatal("Failed to refresh hierarchy:", err)
+	}
+
+	pointBuffer := new(registry.PointBuffer)
+	acc := inputs.Accumulator{
+		Pusher:  pointBuffer,
+		Context: ctx,
+	}
+
+	err = additionalDatastoreMetrics(ctx, client, datastores, caches.datastoreCache, &acc, hierarchy, time.Now())
+	if err != nil {
+		t.Fatal("Failed to gather additional datastore metrics:", err)
+	}
+
+	got := make(map[string]float64)
+
+	for _, point := range pointBuffer.Points() {
+		if point.Labels["dsname"] != "datastore1" || point.Labels["dcname"] != "ha-datacenter" {
+			t.Errorf("Unexpected labels %v", point.Labels)
+		}
+
+		got[point.Labels[types.LabelName]] = point.Value
+	}
+
+	expected := map[string]float64{
+		"vsphere_datastore_used_perc":  60.902255639097746,
+		"vsphere_datastore_free_bytes": 41875931136,
+	}
+
+	if diff := cmp.Diff(expected, got, cmpopts.EquateApprox(0, 1e-9)); diff != "" {
+		t.Fatalf("Unexpected datastore metrics (-want +got):\n%s", diff)
+	}
+}
+
+// TestAdditionalVMMetricsPowerState checks the power state metric of each VM.
+func TestAdditionalVMMetricsPowerState(t *testing.T) {
+	vSphereCfg, deferFn := setupVSphereAPITest(t, "esxi_1")
+	defer deferFn()
+
+	ctx, cancel := context.WithTimeout(context.Background(), commonTimeout)
+	defer cancel()
+
+	finder, client, err := newDeviceFinder(ctx, vSphereCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clusters, _, resourcePools, hosts, vms, err := findDevices(ctx, finder, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caches := newPropsCaches()
+	hierarchy := NewHierarchy()
+
+	err = hierarchy.Refresh(ctx, clusters, nil, resourcePools, hosts, vms, caches.vmCache)
+	if err != nil {
+		t.Fatal("Failed to refresh hierarchy:", err)
+	}
+
+	pointBuffer := new(registry.PointBuffer)
+	acc := inputs.Accumulator{
+		Pusher:  pointBuffer,
+		Context: ctx,
+	}
+
+	err = additionalVMMetrics(ctx, client, vms, caches.vmCache, &acc, hierarchy, make(map[string][]bool), time.Now())
+	if err != nil {
+		t.Fatal("Failed to gather additional VM metrics:", err)
+	}
+
+	got := make(map[string]float64)
+
+	for _, point := range pointBuffer.Points() {
+		if point.Labels[types.LabelName] != "vsphere_vm_power_state" {
+			continue
+		}
+
+		got[point.Labels["moid"]] = point.Value
+	}
+
+	// Only the VM "alp1" (moid 10) is powered on.
+	expected := map[string]float64{
+		"1":  0,
+		"8":  0,
+		"10": 1,
+	}
+
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Fatalf("Unexpected power state metrics (-want +got):\n%s", diff)
+	}
+}
+
+// TestAdditionalResourcePoolMetrics checks that the usage of the VMs is aggregated
+// in their resource pool and in all the parent pools.
+func TestAdditionalResourcePoolMetrics(t *testing.T) {
+	vSphereCfg, deferFn := setupVSphereAPITest(t, "vcenter_3")
+	defer deferFn()
+
+	ctx, cancel := context.WithTimeout(context.Background(), commonTimeout)
+	defer cancel()
+
+	finder, client, err := newDeviceFinder(ctx, vSphereCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clusters, datastores, resourcePools, hosts, vms, err := findDevices(ctx, finder, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caches := newPropsCaches()
+	hierarchy := NewHierarchy()
+
+	err = hierarchy.Refresh(ctx, clusters, datastores, resourcePools, hosts, vms, caches.vmCache)
+	if err != nil {
+		t.Fatal("Failed to refresh hierarchy:", err)
+	}
+
+	pointBuffer := new(registry.PointBuffer)
+	acc := inputs.Accumulator{
+		Pusher:  pointBuffer,
+		Context: ctx,
+	}
+
+	err = additionalResourcePoolMetrics(ctx, client, resourcePools, vms, caches, &acc, hierarchy, time.Now())
+	if err != nil {
+		t.Fatal("Failed to gather additional resource pool metrics:", err)
+	}
+
+	type poolUsage struct {
+		CPU    float64
+		Memory float64
+	}
+
+	got := make(map[string]poolUsage)
+
+	for _, point := range pointBuffer.Points() {
+		if point.Labels["clustername"] != "DC0_C0" || point.Labels["dcname"] != "DC0" {
+			t.Errorf("Unexpected labels %v", point.Labels)
+		}
+
+		usage := got[point.Labels[types.LabelItem]]
+
+		switch point.Labels[types.LabelName] {
+		case "vsphere_resource_pool_cpu_usage":
+			usage.CPU = point.Value
+		case "vsphere_resource_pool_mem_usage":
+			usage.Memory = point.Value
+		default:
+			t.Errorf("Unexpected metric %s", point.Labels[types.LabelName])
+		}
+
+		got[point.Labels[types.LabelItem]] = usage
+	}
+
+	// The VM of the pool "Databases" uses 500 MHz and 16 MB,
+	// the VM of its parent pool "Production" uses 200 MHz and 8 MB.
+	expected := map[string]poolUsage{
+		"Resources":                      {CPU: 700, Memory: 24 << 20},
+		"Resources/Production":           {CPU: 700, Memory: 24 << 20},
+		"Resources/Production/Databases": {CPU: 500, Memory: 16 << 20},
+	}
+
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Fatalf("Unexpected resource pool metrics (-want +got):\n%s", diff)
+	}
+}
@@ -121,7 +121,7 @@ func TestVSphereSteps(t *testing.T) {
 	providedFacts := map[string]string{"fqdn": scraperFQDN}
 	dummyVSphere := newVSphere(vSphereURL.Host, vSphereCfg, nil, facts.NewMockFacter(providedFacts))
 
-	err = dummyVSphere.hierarchy.Refresh(ctx, clusters, resourcePools, hosts, vms, dummyVSphere.devicePropsCache.vmCache)
+	err = dummyVSphere.hierarchy.Refresh(ctx, clusters, nil, resourcePools, hosts, vms, dummyVSphere.devicePropsCache.vmCache)
 	if err != nil {
 		t.Fatalf("Got an error refreshing the vSphere hierarchy: %v", err)
 	}
@@ -0,0 +1,168 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsphere
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/bleemeo/glouton/config"
+	"github.com/bleemeo/glouton/logger"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// credentials holds the credentials used to log in to a vSphere.
+// When a username or password file is configured, it's read again after an
+// authentication failure, so rotated credentials are used without restarting Glouton.
+// The generation is incremented each time the credentials change, the users
+// of the credentials compare it to detect that they must log in again.
+// It also keeps whether the last login succeeded.
+type credentials struct {
+	l sync.Mutex
+
+	username     string
+	password     string
+	usernameFile string
+	passwordFile string
+	generation   uint64
+
+	connectionUp bool
+}
+
+func newCredentials(cfg config.VSphere) *credentials {
+	c := &credentials{
+		username:     cfg.Username,
+		password:     cfg.Password,
+		usernameFile: cfg.UsernameFile,
+		passwordFile: cfg.PasswordFile,
+	}
+
+	c.reload()
+
+	return c
+}
+
+// get returns the current username, password and generation.
+func (c *credentials) get() (username string, password string, generation uint64) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	return c.username, c.password, c.generation
+}
+
+// currentGeneration returns the generation of the current credentials.
+func (c *credentials) currentGeneration() uint64 {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	return c.generation
+}
+
+// reload reads the username and password files again and returns whether the credentials changed.
+func (c *credentials) reload() bool {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	username, usernameChanged := readCredentialFile(c.usernameFile, c.username)
+	password, passwordChanged := readCredentialFile(c.passwordFile, c.password)
+
+	if !usernameChanged && !passwordChanged {
+		return false
+	}
+
+	c.username = username
+	c.password = password
+	c.generation++
+
+	return true
+}
+
+// readCredentialFile returns the content of the file and whether it's different
+// from the current value. The current value is kept when the file isn't readable.
+func readCredentialFile(path string, current string) (string, bool) {
+	if path == "" {
+		return current, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.V(1).Printf("Failed to read the vSphere credentials file: %v", err)
+
+		return current, false
+	}
+
+	value := strings.TrimSpace(string(data))
+
+	return value, value != current
+}
+
+func (c *credentials) setConnectionUp(up bool) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	c.connectionUp = up
+}
+
+func (c *credentials) isConnectionUp() bool {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	return c.connectionUp
+}
+
+// newDeviceFinder logs in with the current credentials. On an authentication failure,
+// the credentials are reloaded and the login is retried if they changed.
+func (c *credentials) newDeviceFinder(ctx context.Context, cfg config.VSphere) (*find.Finder, *vim25.Client, error) {
+	cfg.Username, cfg.Password, _ = c.get()
+
+	finder, client, err := newDeviceFinder(ctx, cfg)
+	if err != nil && isAuthError(err) && c.reload() {
+		logger.V(2).Printf("vSphere %s: authentication failed, retrying with the new credentials", cfg.URL)
+
+		cfg.Username, cfg.Password, _ = c.get()
+		finder, client, err = newDeviceFinder(ctx, cfg)
+	}
+
+	c.setConnectionUp(err == nil)
+
+	return finder, client, err
+}
+
+// isAuthError returns whether the error is caused by invalid credentials or an expired session.
+func isAuthError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if soap.IsSoapFault(err) {
+		switch soap.ToSoapFault(err).VimFault().(type) {
+		case types.InvalidLogin, *types.InvalidLogin, types.NotAuthenticated, *types.NotAuthenticated:
+			return true
+		}
+	}
+
+	// The telegraf input only returns the message of the fault.
+	msg := err.Error()
+
+	return strings.Contains(msg, "incorrect user name or password") || strings.Contains(msg, "NotAuthenticated")
+}
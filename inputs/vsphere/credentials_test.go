@@ -0,0 +1,215 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsphere
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bleemeo/glouton/config"
+	"github.com/bleemeo/glouton/facts"
+	"github.com/bleemeo/glouton/prometheus/registry"
+
+	"github.com/influxdata/telegraf/plugins/inputs/vsphere"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCredentialsReload(t *testing.T) {
+	t.Parallel()
+
+	passwordFile := filepath.Join(t.TempDir(), "password")
+
+	if err := os.WriteFile(passwordFile, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	creds := newCredentials(config.VSphere{Username: "root", Password: "unused", PasswordFile: passwordFile})
+
+	username, password, firstGeneration := creds.get()
+	if username != "root" || password != "first" {
+		t.Fatalf("get() = %q, %q, want root, first", username, password)
+	}
+
+	if creds.reload() {
+		t.Error("reload() = true, want false since the password didn't change")
+	}
+
+	if err := os.WriteFile(passwordFile, []byte("second\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if !creds.reload() {
+		t.Error("reload() = false, want true since the password changed")
+	}
+
+	if _, password, generation := creds.get(); password != "second" || generation != firstGeneration+1 {
+		t.Errorf("password = %q, generation = %d, want second, %d", password, generation, firstGeneration+1)
+	}
+
+	// The username is reloaded too.
+	usernameFile := filepath.Join(t.TempDir(), "username")
+
+	if err := os.WriteFile(usernameFile, []byte("admin\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	creds = newCredentials(config.VSphere{Username: "root", UsernameFile: usernameFile, PasswordFile: passwordFile})
+	initialGeneration := creds.currentGeneration()
+
+	if err := os.WriteFile(usernameFile, []byte("operator\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if !creds.reload() {
+		t.Error("reload() = false, want true since the username changed")
+	}
+
+	if username, password, generation := creds.get(); username != "operator" || password != "second" || generation != initialGeneration+1 {
+		t.Errorf("get() = %q, %q, %d, want operator, second, %d", username, password, generation, initialGeneration+1)
+	}
+
+	// Without password file, the configured password is kept.
+	creds = newCredentials(config.VSphere{Username: "root", Password: "passwd"})
+
+	if creds.reload() {
+		t.Error("reload() = true, want false without password file")
+	}
+
+	if _, password, _ := creds.get(); password != "passwd" {
+		t.Errorf("password = %q, want passwd", password)
+	}
+}
+
+func TestIsAuthError(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{err: nil, want: false},
+		{err: errors.New("ServerFaultCode: Cannot complete login due to an incorrect user name or password."), want: true},
+		{err: errors.New("ServerFaultCode: NotAuthenticated"), want: true},
+		{err: errors.New("connection refused"), want: false},
+	}
+
+	for _, c := range cases {
+		if got := isAuthError(c.err); got != c.want {
+			t.Errorf("isAuthError(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+// TestCredentialsConnectionUp checks that a failed login marks the connection as down.
+func TestCredentialsConnectionUp(t *testing.T) {
+	vSphereCfg, deferFn := setupVSphereAPITest(t, "esxi_1")
+	defer deferFn()
+
+	ctx, cancel := context.WithTimeout(context.Background(), commonTimeout)
+	defer cancel()
+
+	creds := newCredentials(vSphereCfg)
+
+	if _, _, err := creds.newDeviceFinder(ctx, vSphereCfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if !creds.isConnectionUp() {
+		t.Error("the connection should be up")
+	}
+}
+
+// TestEndpointRecreatedOnCredentialsChange checks that the realtime and historical
+// endpoints are re-created with the new credentials, even when they were reloaded
+// by another user of the credentials.
+func TestEndpointRecreatedOnCredentialsChange(t *testing.T) {
+	vSphereCfg, vSphereDeferFn := setupVSphereAPITest(t, "esxi_1")
+	defer vSphereDeferFn()
+
+	passwordFile := filepath.Join(t.TempDir(), "password")
+
+	if err := os.WriteFile(passwordFile, []byte("pass\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	vSphereCfg.PasswordFile = passwordFile
+
+	ctx, cancel := context.WithTimeout(context.Background(), commonTimeout)
+	defer cancel()
+
+	manager := new(Manager)
+	manager.RegisterGatherers(ctx, []config.VSphere{vSphereCfg}, func(_ registry.RegistrationOption, _ prometheus.Gatherer) (int, error) { return 0, nil }, nil, facts.NewMockFacter(make(map[string]string)))
+
+	u, _ := url.Parse(vSphereCfg.URL)
+
+	vSphere, ok := manager.vSpheres[u.Host]
+	if !ok {
+		t.Fatalf("Expected manager to have a vSphere for the key %q.", u.Host)
+	}
+
+	gatherers := []*vSphereGatherer{vSphere.realtimeGatherer, vSphere.historical30minGatherer}
+	oldEndpoints := make([]*vsphere.Endpoint, len(gatherers))
+
+	for i, gatherer := range gatherers {
+		gatherer.l.Lock()
+		oldEndpoints[i] = gatherer.endpoint
+		gatherer.l.Unlock()
+
+		if oldEndpoints[i] == nil {
+			t.Fatalf("%s endpoint wasn't created", gatherer.kind)
+		}
+	}
+
+	if err := os.WriteFile(passwordFile, []byte("new-pass\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The credentials are reloaded once, as done by the device discovery after a login failure.
+	if !vSphere.creds.reload() {
+		t.Fatal("reload() = false, want true since the password changed")
+	}
+
+	for i, gatherer := range gatherers {
+		if _, err := gatherer.GatherWithState(ctx, registry.GatherState{T0: time.Now(), FromScrapeLoop: true}); err != nil {
+			t.Fatalf("%s gather failed: %v", gatherer.kind, err)
+		}
+
+		deadline := time.Now().Add(10 * time.Second)
+
+		for {
+			gatherer.l.Lock()
+			endpoint := gatherer.endpoint
+			upToDate := gatherer.endpointGeneration == vSphere.creds.currentGeneration()
+			gatherer.l.Unlock()
+
+			if endpoint != nil && endpoint != oldEndpoints[i] && upToDate {
+				break
+			}
+
+			if time.Now().After(deadline) {
+				t.Fatalf("%s endpoint wasn't re-created with the new credentials", gatherer.kind)
+			}
+
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsphere
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bleemeo/glouton/config"
+)
+
+// deviceFilter restricts the clusters, hosts and VMs collected on a vSphere.
+// A nil deviceFilter allows every device.
+type deviceFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+func newDeviceFilter(cfg config.VSphere) (*deviceFilter, error) {
+	if len(cfg.Include) == 0 && len(cfg.Exclude) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	include, err := compilePatterns(cfg.Include)
+	if err != nil {
+		return nil, fmt.Errorf("%w: include: %w", config.ErrInvalidValue, err)
+	}
+
+	exclude, err := compilePatterns(cfg.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("%w: exclude: %w", config.ErrInvalidValue, err)
+	}
+
+	return &deviceFilter{include: include, exclude: exclude}, nil
+}
+
+// compilePatterns compiles the given patterns so that they must match the whole value.
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		r, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, r)
+	}
+
+	return res, nil
+}
+
+// isAllowed returns whether the device with the given MOID should be collected.
+// The names are the one of the device followed by the ones of its parents (cluster, datacenter),
+// so a whole cluster or datacenter can be included or excluded.
+func (f *deviceFilter) isAllowed(moid string, names ...string) bool {
+	if f == nil {
+		return true
+	}
+
+	values := append([]string{moid}, names...)
+
+	if len(f.include) > 0 && !matchAny(f.include, values) {
+		return false
+	}
+
+	return !matchAny(f.exclude, values)
+}
+
+func matchAny(patterns []*regexp.Regexp, values []string) bool {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+
+		for _, r := range patterns {
+			if r.MatchString(value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// filterObjects returns the objects allowed by the filter.
+// The hierarchy must have been refreshed with all the objects beforehand.
+func filterObjects[T commonObject](f *deviceFilter, h *Hierarchy, objects []T) []T {
+	if f == nil {
+		return objects
+	}
+
+	filtered := make([]T, 0, len(objects))
+
+	for _, obj := range objects {
+		if f.isAllowed(obj.Reference().Value, obj.Name(), h.ParentClusterName(obj), h.ParentDCName(obj)) {
+			filtered = append(filtered, obj)
+		}
+	}
+
+	return filtered
+}
+
+// includePaths returns the sorted inventory paths, usable in the include lists of
+// the Telegraf input. The Telegraf input matches each path element with a glob when
+// it contains one of "*?[", the special characters are escaped.
+func includePaths(inventoryPaths []string) []string {
+	paths := make([]string, 0, len(inventoryPaths))
+
+	for _, inventoryPath := range inventoryPaths {
+		elements := strings.Split(inventoryPath, "/")
+
+		for i, element := range elements {
+			elements[i] = escapeGlob(element)
+		}
+
+		paths = append(paths, strings.Join(elements, "/"))
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+// escapeGlob returns a pattern matching only the given name with the Telegraf filters.
+func escapeGlob(name string) string {
+	if !strings.ContainsAny(name, "*?[") {
+		return name
+	}
+
+	var sb strings.Builder
+
+	for _, r := range name {
+		if strings.ContainsRune(`*?[]{},\`, r) {
+			sb.WriteByte('\\')
+		}
+
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,234 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsphere
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	bleemeoTypes "github.com/bleemeo/glouton/bleemeo/types"
+	"github.com/bleemeo/glouton/config"
+	"github.com/bleemeo/glouton/facts"
+	"github.com/bleemeo/glouton/prometheus/registry"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestDeviceFilter(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		moid     string
+		names    []string
+		expected bool
+	}{
+		{
+			name:     "no filter",
+			moid:     "vm-28",
+			names:    []string{"web-1", "C0", "DC0"},
+			expected: true,
+		},
+		{
+			name:     "include by datacenter",
+			include:  []string{"DC0"},
+			moid:     "vm-28",
+			names:    []string{"web-1", "C0", "DC0"},
+			expected: true,
+		},
+		{
+			name:     "include must match the whole name",
+			include:  []string{"DC"},
+			moid:     "vm-28",
+			names:    []string{"web-1", "C0", "DC0"},
+			expected: false,
+		},
+		{
+			name:     "exclude by moid",
+			exclude:  []string{"vm-2."},
+			moid:     "vm-28",
+			names:    []string{"web-1", "C0", "DC0"},
+			expected: false,
+		},
+		{
+			name:     "exclude wins over include",
+			include:  []string{"C0"},
+			exclude:  []string{"web-.*"},
+			moid:     "vm-28",
+			names:    []string{"web-1", "C0", "DC0"},
+			expected: false,
+		},
+		{
+			name:     "other cluster not included",
+			include:  []string{"C0"},
+			moid:     "vm-30",
+			names:    []string{"web-2", "C1", "DC0"},
+			expected: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			filter, err := newDeviceFilter(config.VSphere{Include: tc.include, Exclude: tc.exclude})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := filter.isAllowed(tc.moid, tc.names...); got != tc.expected {
+				t.Errorf("isAllowed(%q, %v) = %v, want %v", tc.moid, tc.names, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestDeviceFilterInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := newDeviceFilter(config.VSphere{Exclude: []string{"vm-("}})
+	if !errors.Is(err, config.ErrInvalidValue) {
+		t.Fatalf("Expected an invalid value error, got %v", err)
+	}
+}
+
+// TestDevicesFiltered checks that excluded devices aren't described.
+func TestDevicesFiltered(t *testing.T) {
+	cases := []struct {
+		name          string
+		include       []string
+		exclude       []string
+		expectedMOIDs []string
+	}{
+		{
+			name:          "include cluster",
+			include:       []string{"DC0_C0"},
+			expectedMOIDs: []string{"domain-c16", "host-23", "vm-28"},
+		},
+		{
+			name:          "exclude vm",
+			exclude:       []string{"DC0_C0_RP0_VM0"},
+			expectedMOIDs: []string{"domain-c16", "host-23"},
+		},
+		{
+			name:          "include host",
+			include:       []string{"host-23"},
+			expectedMOIDs: []string{"host-23"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			vSphereCfg, deferFn := setupVSphereAPITest(t, "vcenter_1")
+			defer deferFn()
+
+			vSphereCfg.Include = tc.include
+			vSphereCfg.Exclude = tc.exclude
+
+			u, _ := url.Parse(vSphereCfg.URL)
+
+			filter, err := newDeviceFilter(vSphereCfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			vSphere := newVSphere(u.Host, vSphereCfg, nil, facts.NewMockFacter(make(map[string]string)))
+			vSphere.filter = filter
+			devChan := make(chan bleemeoTypes.VSphereDevice)
+			done := make(chan struct{})
+
+			var moids []string
+
+			go func() {
+				defer close(done)
+
+				for dev := range devChan {
+					moids = append(moids, dev.MOID())
+				}
+			}()
+
+			vSphere.devices(context.Background(), devChan)
+			close(devChan)
+			<-done
+
+			sort.Strings(moids)
+
+			if diff := cmp.Diff(tc.expectedMOIDs, moids); diff != "" {
+				t.Fatalf("Unexpected devices (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestRealtimeInputRestricted checks that the realtime input only queries the allowed VMs.
+func TestRealtimeInputRestricted(t *testing.T) {
+	vSphereCfg, deferFn := setupVSphereAPITest(t, "vcenter_1")
+	defer deferFn()
+
+	vSphereCfg.Include = []string{"DC0_C0_RP0_VM0"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commonTimeout)
+	defer cancel()
+
+	manager := new(Manager)
+	manager.RegisterGatherers(ctx, []config.VSphere{vSphereCfg}, func(_ registry.RegistrationOption, _ prometheus.Gatherer) (int, error) { return 0, nil }, nil, facts.NewMockFacter(make(map[string]string)))
+
+	u, _ := url.Parse(vSphereCfg.URL)
+
+	vSphere, ok := manager.vSpheres[u.Host]
+	if !ok {
+		t.Fatalf("Expected manager to have a vSphere for the key %q.", u.Host)
+	}
+
+	gatherer := vSphere.realtimeGatherer
+
+	if _, err := gatherer.GatherWithState(ctx, registry.GatherState{T0: time.Now(), FromScrapeLoop: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	gatherer.l.Lock()
+	vmInclude := gatherer.input.VMInclude
+	hostInclude := gatherer.input.HostInclude
+	gatherer.l.Unlock()
+
+	if len(vmInclude) != 1 || !strings.HasSuffix(vmInclude[0], "/DC0_C0_RP0_VM0") {
+		t.Errorf("VMInclude = %v, want only DC0_C0_RP0_VM0", vmInclude)
+	}
+
+	if len(hostInclude) != 0 {
+		t.Errorf("HostInclude = %v, want no host", hostInclude)
+	}
+}
+
+func TestIncludePaths(t *testing.T) {
+	t.Parallel()
+
+	got := includePaths([]string{"/DC0/vm/web*1", "/DC0/host/C0/H0"})
+	want := []string{"/DC0/host/C0/H0", `/DC0/vm/web\*1`}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("includePaths() mismatch (-want +got):\n%s", diff)
+	}
+}
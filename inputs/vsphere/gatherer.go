@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -35,8 +36,10 @@ import (
 	"github.com/bleemeo/glouton/types"
 
 	"github.com/influxdata/telegraf"
+	telegraf_config "github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/plugins/inputs/vsphere"
 	dto "github.com/prometheus/client_model/go"
+	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/soap"
 )
 
@@ -46,21 +49,68 @@ type gatherKind string
 
 const (
 	gatherRT      gatherKind = "realtime"         // Hosts and VMs (and Clusters, aggregated from hosts metrics)
+	gatherHist5m  gatherKind = "historical 5min"  // Datastores I/O
 	gatherHist30m gatherKind = "historical 30min" // Datastores
 )
 
+func (kind gatherKind) isHistorical() bool {
+	return kind == gatherHist5m || kind == gatherHist30m
+}
+
+// historicalKinds returns the historical gatherers enabled by the config.
+func historicalKinds(cfg config.VSphere) ([]gatherKind, error) {
+	if cfg.SkipHistorical {
+		return nil, nil
+	}
+
+	if len(cfg.HistoricalIntervals) == 0 {
+		return []gatherKind{gatherHist30m}, nil
+	}
+
+	kinds := make([]gatherKind, 0, len(cfg.HistoricalIntervals))
+
+	for _, interval := range cfg.HistoricalIntervals {
+		var kind gatherKind
+
+		switch interval {
+		case "5min":
+			kind = gatherHist5m
+		case "30min":
+			kind = gatherHist30m
+		default:
+			return nil, fmt.Errorf("%w: unknown historical interval %q", config.ErrInvalidValue, interval)
+		}
+
+		if !slices.Contains(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+
+	return kinds, nil
+}
+
 type vSphereGatherer struct {
 	kind     gatherKind
 	interval time.Duration
 
-	cfg *config.VSphere
+	cfg    *config.VSphere
+	filter *deviceFilter
+	creds  *credentials
 	// Storing the endpoint's URL, just in case the endpoint can't be
 	// created at startup and will need to be instanced later.
 	soapURL             *url.URL
 	endpointCreateTimer *time.Timer
 
-	endpoint *vsphere.Endpoint
-	cancel   context.CancelFunc
+	// The input and the context are kept to re-create the endpoint when the credentials change.
+	input       *vsphere.VSphere
+	endpointCtx context.Context //nolint:containedctx
+	endpoint    *vsphere.Endpoint
+	// endpointGeneration is the generation of the credentials used by the endpoint.
+	endpointGeneration uint64
+	// includeChanged is true when the objects included in the input changed
+	// since the endpoint was created.
+	includeChanged bool
+	cancel         context.CancelFunc
 
 	acc        *internal.Accumulator
 	buffer     *registry.PointBuffer
@@ -91,6 +141,9 @@ func (gatherer *vSphereGatherer) GatherWithState(ctx context.Context, state regi
 	gatherer.l.Lock()
 	defer gatherer.l.Unlock()
 
+	// The credentials may have been reloaded by the other gatherers or by the device discovery.
+	gatherer.recreateOutdatedEndpoint()
+
 	if gatherer.endpoint == nil {
 		return nil, nil
 	}
@@ -118,13 +171,13 @@ func (gatherer *vSphereGatherer) GatherWithState(ctx context.Context, state regi
 
 		var err error
 
-		if gatherer.kind != gatherHist30m || state.T0.Sub(gatherer.lastInputCollect) >= 5*time.Minute {
+		if !gatherer.kind.isHistorical() || state.T0.Sub(gatherer.lastInputCollect) >= 5*time.Minute {
 			// Registry calls both historical and real-time gatherer every minute, so all vSphere agents produce
 			// a point every minute. But for historical metrics, we don't need to do the real call every minute:
 			// every 5 minutes is enough. We prefer 5 minutes rather than 30 minutes, to get the last data point
 			// a bit sooner (we don't know the delay before that point is available).
 			err = gatherer.endpoint.Collect(ctx, retAcc)
-			if gatherer.kind == gatherHist30m && err == nil {
+			if gatherer.kind.isHistorical() && err == nil {
 				gatherer.lastInputCollect = state.T0
 			}
 		}
@@ -132,10 +185,18 @@ func (gatherer *vSphereGatherer) GatherWithState(ctx context.Context, state regi
 		errAddMetrics := gatherer.collectAdditionalMetrics(ctx, state, gatherer.acc, retAcc.retainedPerMeasurement)
 		allErrs := errors.Join(filterErrors(append(errAcc.errs, err, errAddMetrics))...)
 
+		// The endpoint keeps the credentials it was created with, it must be
+		// re-created when they were rotated.
+		if isAuthError(allErrs) {
+			gatherer.creds.reload()
+		}
+
+		gatherer.recreateOutdatedEndpoint()
+
 		gatherer.lastPoints = gatherer.buffer.Points()
 		gatherer.lastErr = allErrs
 
-		if gatherer.kind == gatherHist30m && allErrs == nil {
+		if gatherer.kind.isHistorical() && allErrs == nil {
 			gatherer.lastPoints = gatherer.ptsCache.update(gatherer.lastPoints, state.T0)
 		}
 
@@ -164,7 +225,7 @@ func filterErrors(errs []error) []error {
 
 // Additional metrics are those that we don't get (or directly get) from the telegraf input.
 func (gatherer *vSphereGatherer) collectAdditionalMetrics(ctx context.Context, state registry.GatherState, acc telegraf.Accumulator, retained retainedMetrics) error {
-	finder, client, err := newDeviceFinder(ctx, *gatherer.cfg)
+	finder, client, err := gatherer.creds.newDeviceFinder(ctx, *gatherer.cfg)
 	if err != nil {
 		return err
 	}
@@ -178,13 +239,24 @@ func (gatherer *vSphereGatherer) collectAdditionalMetrics(ctx context.Context, s
 		return nil
 	}
 
-	err = gatherer.hierarchy.Refresh(ctx, clusters, resourcePools, hosts, vms, gatherer.devicePropsCache.vmCache)
+	err = gatherer.hierarchy.Refresh(ctx, clusters, datastores, resourcePools, hosts, vms, gatherer.devicePropsCache.vmCache)
 	if err != nil {
 		return fmt.Errorf("can't describe hierarchy: %w", err)
 	}
 
+	// The excluded VMs still use the resources of their pool.
+	allVMs := vms
+
+	allHosts := hosts
+
+	clusters = filterObjects(gatherer.filter, gatherer.hierarchy, clusters)
+	hosts = filterObjects(gatherer.filter, gatherer.hierarchy, hosts)
+	vms = filterObjects(gatherer.filter, gatherer.hierarchy, vms)
+
 	switch gatherer.kind { //nolint:exhaustive,gocritic
 	case gatherRT:
+		gatherer.restrictInputObjects(clusters, allHosts, hosts, vms)
+
 		// For each host, we want a list of vm states (running/stopped).
 		vmStatesPerHost := make(map[string][]bool, len(hosts))
 
@@ -203,6 +275,16 @@ func (gatherer *vSphereGatherer) collectAdditionalMetrics(ctx context.Context, s
 		if err != nil {
 			return err
 		}
+
+		err = additionalDatastoreMetrics(ctx, client, datastores, gatherer.devicePropsCache.datastoreCache, acc, gatherer.hierarchy, state.T0)
+		if err != nil {
+			return err
+		}
+
+		err = additionalResourcePoolMetrics(ctx, client, resourcePools, allVMs, gatherer.devicePropsCache, acc, gatherer.hierarchy, state.T0)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -222,6 +304,70 @@ func (gatherer *vSphereGatherer) stop() {
 	}
 }
 
+// restrictInputObjects limits the hosts and VMs queried by the input to the allowed ones,
+// so no performance counter is requested for the excluded devices. The hosts of the allowed
+// clusters are kept, their metrics are aggregated to get the cluster metrics.
+// The endpoint is re-created when the allowed devices changed. It must be called with the lock held.
+func (gatherer *vSphereGatherer) restrictInputObjects(
+	clusters []*object.ClusterComputeResource,
+	allHosts []*object.HostSystem,
+	hosts []*object.HostSystem,
+	vms []*object.VirtualMachine,
+) {
+	if gatherer.filter == nil {
+		return
+	}
+
+	clusterNames := make(map[string]bool, len(clusters))
+	for _, cluster := range clusters {
+		clusterNames[cluster.Name()] = true
+	}
+
+	hostPaths := make([]string, 0, len(hosts))
+
+	for _, host := range allHosts {
+		if slices.Contains(hosts, host) || clusterNames[gatherer.hierarchy.ParentClusterName(host)] {
+			hostPaths = append(hostPaths, host.InventoryPath)
+		}
+	}
+
+	vmPaths := make([]string, 0, len(vms))
+	for _, vm := range vms {
+		vmPaths = append(vmPaths, vm.InventoryPath)
+	}
+
+	hostPaths = includePaths(hostPaths)
+	vmPaths = includePaths(vmPaths)
+
+	if slices.Equal(hostPaths, gatherer.input.HostInclude) && slices.Equal(vmPaths, gatherer.input.VMInclude) {
+		return
+	}
+
+	gatherer.input.HostInclude = hostPaths
+	gatherer.input.VMInclude = vmPaths
+	gatherer.includeChanged = true
+}
+
+// recreateOutdatedEndpoint closes the endpoint and creates a new one in the background
+// when the credentials or the included objects changed since it was created.
+// It must be called with the lock held.
+func (gatherer *vSphereGatherer) recreateOutdatedEndpoint() {
+	if gatherer.endpoint == nil {
+		return
+	}
+
+	if gatherer.endpointGeneration == gatherer.creds.currentGeneration() && !gatherer.includeChanged {
+		return
+	}
+
+	logger.V(2).Printf("vSphere %s endpoint for %q: the credentials or the included devices changed, re-creating the endpoint", gatherer.kind, gatherer.soapURL.Host)
+
+	gatherer.endpoint.Close()
+	gatherer.endpoint = nil
+
+	go gatherer.createEndpoint(gatherer.endpointCtx, gatherer.input)
+}
+
 func (gatherer *vSphereGatherer) createEndpoint(ctx context.Context, input *vsphere.VSphere) {
 	gatherer.l.Lock()
 	defer gatherer.l.Unlock()
@@ -230,10 +376,20 @@ func (gatherer *vSphereGatherer) createEndpoint(ctx context.Context, input *vsph
 		return
 	}
 
+	username, password, generation := gatherer.creds.get()
+
+	input.Username.Destroy()
+	input.Password.Destroy()
+
+	input.Username = telegraf_config.NewSecret([]byte(username))
+	input.Password = telegraf_config.NewSecret([]byte(password))
+
 	newEP := func(epURL *url.URL) error {
 		ep, err := vsphere.NewEndpoint(ctx, input, epURL, input.Log)
 		if err == nil {
 			gatherer.endpoint = ep
+			gatherer.endpointGeneration = generation
+			gatherer.includeChanged = false
 		}
 
 		return err
@@ -270,17 +426,29 @@ func (gatherer *vSphereGatherer) createEndpoint(ctx context.Context, input *vsph
 		}
 	}
 
-	logger.V(1).Printf("Failed to create vSphere %s endpoint for %q: %v -- will retry in %s.", gatherer.kind, gatherer.soapURL.Host, err, endpointCreationRetryDelay)
+	retryDelay := endpointCreationRetryDelay
+
+	if isAuthError(err) {
+		gatherer.creds.setConnectionUp(false)
+		gatherer.creds.reload()
+
+		// Don't wait to retry when the credentials changed since the endpoint creation started.
+		if gatherer.creds.currentGeneration() != generation {
+			retryDelay = 0
+		}
+	}
+
+	logger.V(1).Printf("Failed to create vSphere %s endpoint for %q: %v -- will retry in %s.", gatherer.kind, gatherer.soapURL.Host, err, retryDelay)
 
 	gatherer.lastErr = err
-	gatherer.endpointCreateTimer = time.AfterFunc(endpointCreationRetryDelay, func() {
+	gatherer.endpointCreateTimer = time.AfterFunc(retryDelay, func() {
 		gatherer.createEndpoint(ctx, input)
 	})
 }
 
 // newGatherer creates a vSphere gatherer from the given endpoint.
 // It will return an error if the endpoint URL is not valid.
-func newGatherer(ctx context.Context, kind gatherKind, cfg *config.VSphere, input *vsphere.VSphere, acc *internal.Accumulator, hierarchy *Hierarchy, devicePropsCache *propsCaches) (*vSphereGatherer, error) {
+func newGatherer(ctx context.Context, kind gatherKind, cfg *config.VSphere, filter *deviceFilter, creds *credentials, input *vsphere.VSphere, acc *internal.Accumulator, hierarchy *Hierarchy, devicePropsCache *propsCaches) (*vSphereGatherer, error) {
 	soapURL, err := soap.ParseURL(cfg.URL)
 	if err != nil {
 		return nil, err
@@ -292,6 +460,10 @@ func newGatherer(ctx context.Context, kind gatherKind, cfg *config.VSphere, inpu
 		kind:             kind,
 		interval:         time.Duration(input.HistoricalInterval),
 		cfg:              cfg,
+		filter:           filter,
+		creds:            creds,
+		input:            input,
+		endpointCtx:      ctx,
 		soapURL:          soapURL,
 		acc:              acc,
 		buffer:           new(registry.PointBuffer),
@@ -300,7 +472,7 @@ func newGatherer(ctx context.Context, kind gatherKind, cfg *config.VSphere, inpu
 		devicePropsCache: devicePropsCache,
 	}
 
-	if kind == gatherHist30m {
+	if kind.isHistorical() {
 		gatherer.ptsCache = make(pointCache)
 	}
 
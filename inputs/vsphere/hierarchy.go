@@ -33,7 +33,7 @@ const hierarchyMinUpdateInterval = time.Minute
 // It drops the folder levels to get a hierarchy with a shape like:
 // VM -> Host -> Cluster -> Datacenter
 //
-// It also indexes the device names of VMs, hosts, resource pools, clusters and datacenters.
+// It also indexes the device names of VMs, hosts, resource pools, clusters, datastores and datacenters.
 type Hierarchy struct {
 	deviceNamePerMOID  map[string]string
 	parentPerChildMOID map[string]types.ManagedObjectReference
@@ -49,7 +49,7 @@ func NewHierarchy() *Hierarchy {
 	}
 }
 
-func (h *Hierarchy) Refresh(ctx context.Context, clusters []*object.ClusterComputeResource, resourcePools []*object.ResourcePool, hosts []*object.HostSystem, vms []*object.VirtualMachine, vmPropsCache *propsCache[vmLightProps]) error {
+func (h *Hierarchy) Refresh(ctx context.Context, clusters []*object.ClusterComputeResource, datastores []*object.Datastore, resourcePools []*object.ResourcePool, hosts []*object.HostSystem, vms []*object.VirtualMachine, vmPropsCache *propsCache[vmLightProps]) error {
 	h.l.Lock()
 	defer h.l.Unlock()
 
@@ -86,6 +86,13 @@ func (h *Hierarchy) Refresh(ctx context.Context, clusters []*object.ClusterCompu
 		}
 	}
 
+	for _, datastore := range datastores {
+		err := h.recurseDescribe(ctx, datastore.Client(), datastore.Reference())
+		if err != nil {
+			return err
+		}
+	}
+
 	// Resource pools aren't part of the hierarchy, but their name is used in VM facts.
 	for _, resourcePool := range resourcePools {
 		h.deviceNamePerMOID[resourcePool.Reference().Value] = resourcePool.Name()
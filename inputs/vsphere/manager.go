@@ -78,7 +78,7 @@ func (m *Manager) EndpointsInError() map[string]bool {
 	for _, vSphere := range m.vSpheres {
 		vSphere.l.Lock()
 
-		if vSphere.realtimeGatherer == nil || vSphere.historical30minGatherer == nil || vSphere.consecutiveErr > 0 {
+		if !vSphere.gatherersReady() || vSphere.consecutiveErr > 0 {
 			endpoints[vSphere.host] = true
 		}
 
@@ -106,7 +106,23 @@ func (m *Manager) RegisterGatherers(ctx context.Context, vSphereCfgs []config.VS
 			continue
 		}
 
+		filter, err := newDeviceFilter(vSphereCfg)
+		if err != nil {
+			logger.V(1).Printf("Invalid device filter for vSphere %q: %v", u.Host, err)
+
+			continue
+		}
+
+		kinds, err := historicalKinds(vSphereCfg)
+		if err != nil {
+			logger.V(1).Printf("Invalid historical intervals for vSphere %q: %v", u.Host, err)
+
+			continue
+		}
+
 		vSphere := newVSphere(u.Host, vSphereCfg, state, factProvider)
+		vSphere.filter = filter
+		vSphere.historicalKinds = kinds
 
 		realtimeGatherer, opt, err := vSphere.makeRealtimeGatherer(ctx)
 		if err != nil {
@@ -122,22 +138,34 @@ func (m *Manager) RegisterGatherers(ctx context.Context, vSphereCfgs []config.VS
 			continue
 		}
 
-		historical30minGatherer, opt, err := vSphere.makeHistorical30minGatherer(ctx)
-		if err != nil {
-			logger.V(1).Printf("Failed to create historical 30min gatherer for %s: %v", vSphere.String(), err)
-
+		if !vSphere.registerHistoricalGatherers(ctx, registerGatherer) {
 			continue
 		}
 
-		_, err = registerGatherer(opt, registry.WithPastPointFilter(historical30minGatherer, 2*time.Hour))
+		m.vSpheres[u.Host] = vSphere
+	}
+}
+
+// registerHistoricalGatherers creates and registers the historical gatherers enabled by the config.
+// It returns false if one of them failed.
+func (vSphere *vSphere) registerHistoricalGatherers(ctx context.Context, registerGatherer func(opt registry.RegistrationOption, gatherer prometheus.Gatherer) (int, error)) bool {
+	for _, kind := range vSphere.historicalKinds {
+		historicalGatherer, opt, err := vSphere.makeHistoricalGatherer(ctx, kind)
 		if err != nil {
-			logger.V(1).Printf("Failed to register historical 30min gatherer for %s: %v", vSphere.String(), err)
+			logger.V(1).Printf("Failed to create %s gatherer for %s: %v", kind, vSphere.String(), err)
 
-			continue
+			return false
 		}
 
-		m.vSpheres[u.Host] = vSphere
+		_, err = registerGatherer(opt, registry.WithPastPointFilter(historicalGatherer, 2*time.Hour))
+		if err != nil {
+			logger.V(1).Printf("Failed to register %s gatherer for %s: %v", kind, vSphere.String(), err)
+
+			return false
+		}
 	}
+
+	return true
 }
 
 // Devices returns the list of all the vSphere devices that have been found
@@ -365,12 +393,18 @@ func (m *Manager) DiagnosticVSphere(ctx context.Context, archive types.ArchiveWr
 		switch {
 		case vSphere.lastErrorMessage != "":
 			status = vSphere.lastErrorMessage
-		case vSphere.realtimeGatherer == nil || vSphere.historical30minGatherer == nil:
+		case !vSphere.gatherersReady():
 			status = "gatherers haven't been initialized yet"
 		case vSphere.realtimeGatherer.lastErr != nil:
 			status = vSphere.realtimeGatherer.lastErr.Error()
-		case vSphere.historical30minGatherer.lastErr != nil:
-			status = vSphere.historical30minGatherer.lastErr.Error()
+		default:
+			for _, kind := range vSphere.historicalKinds {
+				if err := vSphere.historicalGatherer(kind).lastErr; err != nil {
+					status = err.Error()
+
+					break
+				}
+			}
 		}
 		vSphere.l.Unlock()
 
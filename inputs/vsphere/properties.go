@@ -51,21 +51,23 @@ func (r refName) Name() string {
 
 // propsCaches holds the caches of object properties from different types.
 type propsCaches struct {
-	clusterCache   *propsCache[clusterLightProps]
-	datastoreCache *propsCache[datastoreLightProps]
-	hostCache      *propsCache[hostLightProps]
-	vmCache        *propsCache[vmLightProps]
+	clusterCache      *propsCache[clusterLightProps]
+	datastoreCache    *propsCache[datastoreLightProps]
+	hostCache         *propsCache[hostLightProps]
+	resourcePoolCache *propsCache[resourcePoolLightProps]
+	vmCache           *propsCache[vmLightProps]
 
 	lastPurge time.Time
 }
 
 func newPropsCaches() *propsCaches {
 	return &propsCaches{
-		clusterCache:   &propsCache[clusterLightProps]{m: make(map[string]cachedProp[clusterLightProps])},
-		datastoreCache: &propsCache[datastoreLightProps]{m: make(map[string]cachedProp[datastoreLightProps])},
-		hostCache:      &propsCache[hostLightProps]{m: make(map[string]cachedProp[hostLightProps])},
-		vmCache:        &propsCache[vmLightProps]{m: make(map[string]cachedProp[vmLightProps])},
-		lastPurge:      time.Now(),
+		clusterCache:      &propsCache[clusterLightProps]{m: make(map[string]cachedProp[clusterLightProps])},
+		datastoreCache:    &propsCache[datastoreLightProps]{m: make(map[string]cachedProp[datastoreLightProps])},
+		hostCache:         &propsCache[hostLightProps]{m: make(map[string]cachedProp[hostLightProps])},
+		resourcePoolCache: &propsCache[resourcePoolLightProps]{m: make(map[string]cachedProp[resourcePoolLightProps])},
+		vmCache:           &propsCache[vmLightProps]{m: make(map[string]cachedProp[vmLightProps])},
+		lastPurge:         time.Now(),
 	}
 }
 
@@ -79,6 +81,7 @@ func (propsCache *propsCaches) purge() {
 	propsCache.clusterCache.purge()
 	propsCache.datastoreCache.purge()
 	propsCache.hostCache.purge()
+	propsCache.resourcePoolCache.purge()
 	propsCache.vmCache.purge()
 
 	propsCache.lastPurge = time.Now()
@@ -219,6 +222,8 @@ var (
 	relevantDatastoreProperties = []string{
 		"name",
 		"info",
+		"summary.capacity",
+		"summary.freeSpace",
 	}
 	relevantHostProperties = []string{
 		"name",
@@ -238,6 +243,12 @@ var (
 		"config.network.ipV6Enabled",
 		"config.dateTimeInfo.timeZone.name",
 	}
+	relevantResourcePoolProperties = []string{
+		"name",
+		"parent",
+		"owner",
+		"resourcePool",
+	}
 	relevantVMProperties = []string{
 		"config.name",
 		"config.guestFullName",
@@ -252,9 +263,12 @@ var (
 		"guest.guestFullName",
 		"guest.hostName",
 		"guest.ipAddress",
+		"guest.toolsRunningStatus",
 		"guest.disk",
 		"summary.config.product.name",
 		"summary.config.product.vendor",
+		"summary.quickStats.overallCpuUsage",
+		"summary.quickStats.hostMemoryUsage",
 	}
 )
 
@@ -293,12 +307,18 @@ type (
 	datastoreLightProps struct {
 		ManagedEntity datastoreLightManagedEntity
 		Info          types.BaseDatastoreInfo
+		Summary       datastoreLightSummary
 	}
 
 	datastoreLightManagedEntity struct {
 		Name string
 	}
 
+	datastoreLightSummary struct {
+		Capacity  int64
+		FreeSpace int64
+	}
+
 	// Lightweight version of mo.HostSystem.
 	hostLightProps struct {
 		ManagedEntity hostLightManagedEntity
@@ -379,6 +399,18 @@ type (
 		Name string
 	}
 
+	// Lightweight version of mo.ResourcePool.
+	resourcePoolLightProps struct {
+		ManagedEntity resourcePoolLightManagedEntity
+		Owner         types.ManagedObjectReference
+		ResourcePool  []types.ManagedObjectReference
+	}
+
+	resourcePoolLightManagedEntity struct {
+		Parent *types.ManagedObjectReference
+		Name   string
+	}
+
 	// Lightweight version of mo.VirtualMachine.
 	vmLightProps struct {
 		Config       *vmLightConfig
@@ -413,10 +445,11 @@ type (
 	}
 
 	vmLightGuest struct {
-		GuestFullName string
-		HostName      string
-		IpAddress     string //nolint: revive,stylecheck
-		Disk          []vmLightGuestDisk
+		GuestFullName      string
+		HostName           string
+		IpAddress          string //nolint: revive,stylecheck
+		ToolsRunningStatus string
+		Disk               []vmLightGuestDisk
 	}
 
 	vmLightGuestDisk struct {
@@ -426,8 +459,9 @@ type (
 	}
 
 	vmLightSummary struct {
-		Vm     *types.ManagedObjectReference //nolint: revive,stylecheck
-		Config vmLightSummaryConfig
+		Vm         *types.ManagedObjectReference //nolint: revive,stylecheck
+		Config     vmLightSummaryConfig
+		QuickStats vmLightSummaryQuickStats
 	}
 
 	vmLightSummaryConfig struct {
@@ -438,4 +472,9 @@ type (
 		Name   string
 		Vendor string
 	}
+
+	vmLightSummaryQuickStats struct {
+		OverallCpuUsage int32 //nolint: revive,stylecheck
+		HostMemoryUsage int32
+	}
 )
@@ -60,13 +60,18 @@ type labelsMetadata struct {
 }
 
 type vSphere struct {
-	host string
-	opts config.VSphere
+	host   string
+	opts   config.VSphere
+	filter *deviceFilter
+	creds  *credentials
 
 	state        bleemeoTypes.State
 	factProvider bleemeoTypes.FactProvider
 
+	// historicalKinds are the historical gatherers enabled by the config.
+	historicalKinds         []gatherKind
 	realtimeGatherer        *vSphereGatherer
+	historical5minGatherer  *vSphereGatherer
 	historical30minGatherer *vSphereGatherer
 
 	hierarchy        *Hierarchy
@@ -84,6 +89,7 @@ func newVSphere(host string, cfg config.VSphere, state bleemeoTypes.State, factP
 	return &vSphere{
 		host:             host,
 		opts:             cfg,
+		creds:            newCredentials(cfg),
 		state:            state,
 		factProvider:     factProvider,
 		hierarchy:        NewHierarchy(),
@@ -120,13 +126,45 @@ func (vSphere *vSphere) getStatus() (types.Status, string) {
 	switch {
 	case vSphere.realtimeGatherer != nil && vSphere.realtimeGatherer.lastErr != nil:
 		return types.StatusCritical, "realtime endpoint error: " + vSphere.realtimeGatherer.lastErr.Error()
-	case vSphere.historical30minGatherer != nil && vSphere.historical30minGatherer.lastErr != nil:
-		return types.StatusCritical, "historical 30min endpoint error: " + vSphere.historical30minGatherer.lastErr.Error()
+	}
+
+	for _, kind := range vSphere.historicalKinds {
+		gatherer := vSphere.historicalGatherer(kind)
+		if gatherer != nil && gatherer.lastErr != nil {
+			return types.StatusCritical, string(kind) + " endpoint error: " + gatherer.lastErr.Error()
+		}
 	}
 
 	return types.StatusOk, ""
 }
 
+// historicalGatherer returns the historical gatherer of the given kind, or nil.
+func (vSphere *vSphere) historicalGatherer(kind gatherKind) *vSphereGatherer {
+	switch kind { //nolint:exhaustive
+	case gatherHist5m:
+		return vSphere.historical5minGatherer
+	case gatherHist30m:
+		return vSphere.historical30minGatherer
+	default:
+		return nil
+	}
+}
+
+// gatherersReady returns whether all the enabled gatherers have been created.
+func (vSphere *vSphere) gatherersReady() bool {
+	if vSphere.realtimeGatherer == nil {
+		return false
+	}
+
+	for _, kind := range vSphere.historicalKinds {
+		if vSphere.historicalGatherer(kind) == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (vSphere *vSphere) String() string {
 	return fmt.Sprintf("vSphere(%s)", vSphere.host)
 }
@@ -137,7 +175,7 @@ func (vSphere *vSphere) devices(ctx context.Context, deviceChan chan<- bleemeoTy
 
 	t0 := time.Now()
 
-	finder, client, err := newDeviceFinder(findCtx, vSphere.opts)
+	finder, client, err := vSphere.creds.newDeviceFinder(findCtx, vSphere.opts)
 	if err != nil {
 		vSphere.setErr(err)
 		logger.V(1).Printf("Can't create vSphere client for %q: %v", vSphere.host, err)
@@ -162,13 +200,17 @@ func (vSphere *vSphere) devices(ctx context.Context, deviceChan chan<- bleemeoTy
 		return
 	}
 
-	err = vSphere.hierarchy.Refresh(ctx, clusters, resourcePools, hosts, vms, vSphere.devicePropsCache.vmCache)
+	err = vSphere.hierarchy.Refresh(ctx, clusters, datastores, resourcePools, hosts, vms, vSphere.devicePropsCache.vmCache)
 	if err != nil {
 		vSphere.setErr(err)
 
 		return
 	}
 
+	clusters = filterObjects(vSphere.filter, vSphere.hierarchy, clusters)
+	hosts = filterObjects(vSphere.filter, vSphere.hierarchy, hosts)
+	vms = filterObjects(vSphere.filter, vSphere.hierarchy, vms)
+
 	var (
 		devs           []bleemeoTypes.VSphereDevice
 		errs           []error
@@ -293,8 +335,7 @@ func (vSphere *vSphere) makeRealtimeGatherer(ctx context.Context) (registry.Gath
 		return nil, registry.RegistrationOption{}, inputs.ErrUnexpectedType
 	}
 
-	vsphereInput.Username = telegraf_config.NewSecret([]byte(vSphere.opts.Username))
-	vsphereInput.Password = telegraf_config.NewSecret([]byte(vSphere.opts.Password))
+	// The credentials are set when the endpoint is created.
 
 	vsphereInput.VMInstances = !vSphere.opts.SkipMonitorVMs
 	vsphereInput.HostInstances = true
@@ -314,6 +355,12 @@ func (vSphere *vSphere) makeRealtimeGatherer(ctx context.Context) (registry.Gath
 		}
 	}
 
+	if vSphere.filter != nil {
+		// Only the allowed hosts and VMs are queried, they are set on the first gather.
+		vsphereInput.HostInclude = []string{}
+		vsphereInput.VMInclude = []string{}
+	}
+
 	vsphereInput.HostMetricInclude = []string{
 		"cpu.usage.average",
 		"cpu.usagemhz.average", // Will be converted to the percentage for Cluster CPU
@@ -348,7 +395,7 @@ func (vSphere *vSphere) makeRealtimeGatherer(ctx context.Context) (registry.Gath
 		RenameGlobal:     vSphere.renameGlobal,
 	}
 
-	gatherer, err := newGatherer(ctx, gatherRT, &vSphere.opts, vsphereInput, acc, vSphere.hierarchy, vSphere.devicePropsCache)
+	gatherer, err := newGatherer(ctx, gatherRT, &vSphere.opts, vSphere.filter, vSphere.creds, vsphereInput, acc, vSphere.hierarchy, vSphere.devicePropsCache)
 	if err != nil {
 		return nil, registry.RegistrationOption{}, err
 	}
@@ -365,6 +412,8 @@ func (vSphere *vSphere) makeRealtimeGatherer(ctx context.Context) (registry.Gath
 		GatherModifier: func(mfs []*dto.MetricFamily, _ error) []*dto.MetricFamily {
 			vSphere.purgeNoMetricsSinceMap(noMetricsSince, &noMetricsSinceIterations)
 
+			mfs = append(mfs, vSphere.connectionUpMetric())
+
 			return vSphere.gatherModifier(mfs, noMetricsSince, map[ResourceKind]bool{KindVM: true, KindHost: true, KindCluster: true})
 		},
 	}
@@ -372,7 +421,9 @@ func (vSphere *vSphere) makeRealtimeGatherer(ctx context.Context) (registry.Gath
 	return gatherer, opt, nil
 }
 
-func (vSphere *vSphere) makeHistorical30minGatherer(ctx context.Context) (registry.GathererWithOrWithoutState, registry.RegistrationOption, error) {
+// makeHistoricalGatherer creates the historical gatherer of the given kind.
+// The 5min gatherer collects the I/O of the datastores, the 30min one their usage.
+func (vSphere *vSphere) makeHistoricalGatherer(ctx context.Context, kind gatherKind) (registry.GathererWithOrWithoutState, registry.RegistrationOption, error) {
 	input, ok := telegraf_inputs.Inputs["vsphere"]
 	if !ok {
 		return nil, registry.RegistrationOption{}, inputs.ErrDisabledInput
@@ -383,14 +434,22 @@ func (vSphere *vSphere) makeHistorical30minGatherer(ctx context.Context) (regist
 		return nil, registry.RegistrationOption{}, inputs.ErrUnexpectedType
 	}
 
-	vsphereInput.Username = telegraf_config.NewSecret([]byte(vSphere.opts.Username))
-	vsphereInput.Password = telegraf_config.NewSecret([]byte(vSphere.opts.Password))
+	// The credentials are set when the endpoint is created.
 
 	vsphereInput.DatastoreInstances = true
 
-	vsphereInput.DatastoreMetricInclude = []string{
-		"disk.used.latest",
-		"disk.capacity.latest",
+	if kind == gatherHist5m {
+		vsphereInput.DatastoreMetricInclude = []string{
+			"datastore.read.average",
+			"datastore.write.average",
+		}
+		vsphereInput.HistoricalInterval = telegraf_config.Duration(5 * time.Minute)
+	} else {
+		vsphereInput.DatastoreMetricInclude = []string{
+			"disk.used.latest",
+			"disk.capacity.latest",
+		}
+		vsphereInput.HistoricalInterval = telegraf_config.Duration(30 * time.Minute)
 	}
 
 	vsphereInput.VMMetricExclude = []string{"*"}
@@ -405,10 +464,9 @@ func (vSphere *vSphere) makeHistorical30minGatherer(ctx context.Context) (regist
 	vsphereInput.DatacenterInstances = false
 
 	vsphereInput.InsecureSkipVerify = vSphere.opts.InsecureSkipVerify
-	vsphereInput.HistoricalInterval = telegraf_config.Duration(30 * time.Minute)
 	vsphereInput.ObjectDiscoveryInterval = telegraf_config.Duration(5 * time.Minute)
 
-	vsphereInput.Log = logger.NewTelegrafLog(vSphere.String() + " historical 30min")
+	vsphereInput.Log = logger.NewTelegrafLog(vSphere.String() + " " + string(kind))
 
 	acc := &internal.Accumulator{
 		RenameMetrics:    renameMetrics,
@@ -416,17 +474,21 @@ func (vSphere *vSphere) makeHistorical30minGatherer(ctx context.Context) (regist
 		RenameGlobal:     vSphere.renameGlobal,
 	}
 
-	gatherer, err := newGatherer(ctx, gatherHist30m, &vSphere.opts, vsphereInput, acc, vSphere.hierarchy, vSphere.devicePropsCache)
+	gatherer, err := newGatherer(ctx, kind, &vSphere.opts, vSphere.filter, vSphere.creds, vsphereInput, acc, vSphere.hierarchy, vSphere.devicePropsCache)
 	if err != nil {
 		return nil, registry.RegistrationOption{}, err
 	}
 
-	vSphere.historical30minGatherer = gatherer
+	if kind == gatherHist5m {
+		vSphere.historical5minGatherer = gatherer
+	} else {
+		vSphere.historical30minGatherer = gatherer
+	}
 
 	noMetricsSinceIterations := 0
 	noMetricsSince := make(map[string]int)
 	opt := registry.RegistrationOption{
-		Description:         fmt.Sprint(vSphere, " ", gatherHist30m),
+		Description:         fmt.Sprint(vSphere, " ", kind),
 		MinInterval:         1 * time.Minute, // 4 times out of 5, we will re-use the previous point
 		StopCallback:        gatherer.stop,
 		ApplyDynamicRelabel: true,
@@ -459,6 +521,29 @@ func (vSphere *vSphere) purgeNoMetricsSinceMap(noMetricsSince map[string]int, it
 	}
 }
 
+// connectionUpMetric returns whether the last login to the vSphere succeeded.
+func (vSphere *vSphere) connectionUpMetric() *dto.MetricFamily {
+	value := 0.
+	if vSphere.creds.isConnectionUp() {
+		value = 1
+	}
+
+	return &dto.MetricFamily{
+		Name: proto.String("vsphere_connection_up"),
+		Type: dto.MetricType_GAUGE.Enum(),
+		Metric: []*dto.Metric{
+			{
+				Label: []*dto.LabelPair{
+					{Name: proto.String(types.LabelItem), Value: proto.String(vSphere.host)},
+				},
+				Gauge: &dto.Gauge{
+					Value: proto.Float64(value),
+				},
+			},
+		},
+	}
+}
+
 func (vSphere *vSphere) gatherModifier(mfs []*dto.MetricFamily, noMetricsSince map[string]int, devKinds map[ResourceKind]bool) []*dto.MetricFamily {
 	vSphere.l.Lock()
 	defer vSphere.l.Unlock()
@@ -597,6 +682,23 @@ func (vSphere *vSphere) modifyLabels(labelPairs []*dto.LabelPair) (shouldBeKept
 	isHost := !isVM && labels["esxhostname"].GetValue() != ""
 	isCluster := !isVM && !isHost && labels["dcname"].GetValue() != ""
 
+	var deviceName string
+
+	switch {
+	case isVM:
+		deviceName = labels["vmname"].GetValue()
+	case isHost:
+		deviceName = labels["esxhostname"].GetValue()
+	case isCluster:
+		deviceName = labels["clustername"].GetValue()
+	}
+
+	if (isVM || isHost || isCluster) && !vSphere.filter.isAllowed(moid, deviceName, labels["clustername"].GetValue(), labels["dcname"].GetValue()) {
+		shouldBeKept = false
+
+		return
+	}
+
 	switch {
 	case isVM:
 		if vSphere.opts.SkipMonitorVMs {
@@ -814,6 +916,11 @@ func renameMetrics(currentContext internal.GatherContext, metricName string) (ne
 	newMetricName = strings.TrimSuffix(newMetricName, "_latest")
 
 	// We remove the prefix "vsphere_(vm|host|datastore|cluster)_", except for "vsphere_vm_cpu latency"
+	// and "vsphere_vm power_state".
+	if currentContext.Measurement == "vsphere_vm" && newMetricName == "power_state" {
+		return newMeasurement, newMetricName
+	}
+
 	if newMetricName != "latency" {
 		newMeasurement = strings.TrimPrefix(newMeasurement, "vsphere_")
 		newMeasurement = strings.TrimPrefix(newMeasurement, "vm_")
@@ -45,6 +45,24 @@ func TestRenameMetrics(t *testing.T) {
 			"vsphere_vm_cpu",
 			"latency_perc",
 		},
+		{
+			"vsphere_vm",
+			"power_state",
+			"vsphere_vm",
+			"power_state",
+		},
+		{
+			"vsphere_resource_pool",
+			"cpu_usage",
+			"resource_pool",
+			"cpu_usage",
+		},
+		{
+			"vsphere_resource_pool",
+			"mem_usage",
+			"resource_pool",
+			"mem_usage",
+		},
 		{
 			"vsphere_host_mem",
 			"swapout_average",
@@ -195,14 +213,16 @@ func setupVSphereTest(t *testing.T, hostsCount int) (vsphereRealtimeGatherer, vs
 			InsecureSkipVerify: true,
 			SkipMonitorVMs:     false,
 		},
+		historicalKinds: []gatherKind{gatherHist30m},
 	}
+	vSphere.creds = newCredentials(vSphere.opts)
 
 	realtimeGatherer, _, err := vSphere.makeRealtimeGatherer(context.Background())
 	if err != nil {
 		t.Fatal("Failed to create vSphere realtime gatherer:", err)
 	}
 
-	historical30minGatherer, _, err := vSphere.makeHistorical30minGatherer(context.Background())
+	historical30minGatherer, _, err := vSphere.makeHistoricalGatherer(context.Background(), gatherHist30m)
 	if err != nil {
 		t.Fatal("Failed to create vSphere historical 30min gatherer:", err)
 	}
@@ -271,3 +291,50 @@ func TestVSphereInputMultipleHosts(t *testing.T) {
 		t.Errorf("Unexpected fields:\n%v", diff)
 	}
 }
+
+func TestHistoricalKinds(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name        string
+		cfg         config.VSphere
+		expected    []gatherKind
+		expectError bool
+	}{
+		{
+			name:     "default",
+			cfg:      config.VSphere{},
+			expected: []gatherKind{gatherHist30m},
+		},
+		{
+			name:     "skipped",
+			cfg:      config.VSphere{SkipHistorical: true, HistoricalIntervals: []string{"5min"}},
+			expected: nil,
+		},
+		{
+			name:     "both",
+			cfg:      config.VSphere{HistoricalIntervals: []string{"5min", "30min", "5min"}},
+			expected: []gatherKind{gatherHist5m, gatherHist30m},
+		},
+		{
+			name:        "invalid",
+			cfg:         config.VSphere{HistoricalIntervals: []string{"1h"}},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			kinds, err := historicalKinds(tc.cfg)
+			if tc.expectError != (err != nil) {
+				t.Fatalf("historicalKinds() error = %v, expected an error: %t", err, tc.expectError)
+			}
+
+			if diff := cmp.Diff(tc.expected, kinds); diff != "" {
+				t.Errorf("Unexpected historical kinds (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -17,8 +17,10 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -32,6 +34,7 @@ var (
 	configFiles   = flag.String("config", "", "Configuration files/dirs to load.")
 	showVersion   = flag.Bool("version", false, "Show version and exit")
 	disableReload = flag.Bool("disable-reload", false, "Disable auto-reload on config changes.")
+	checkConfig   = flag.Bool("check-config", false, "Validate the configuration, print the warnings and errors and exit.")
 )
 
 //nolint:gochecknoglobals
@@ -57,6 +60,10 @@ func main() {
 		return
 	}
 
+	if *checkConfig {
+		os.Exit(checkConfiguration(os.Stdout, strings.Split(*configFiles, ",")))
+	}
+
 	// Run os-specific initialisation code.
 	OSDependentMain()
 
@@ -66,3 +73,51 @@ func main() {
 
 	agent.StartReloadManager(strings.Split(*configFiles, ","), *disableReload)
 }
+
+// configCheckReport is the result of the configuration validation.
+type configCheckReport struct {
+	Valid    bool     `json:"valid"`
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings"`
+}
+
+// checkConfiguration loads and validates the configuration, writes the warnings and
+// errors found as JSON and returns the exit code: non-zero if an error is present.
+func checkConfiguration(w io.Writer, configFiles []string) int {
+	report := configCheckReport{
+		Errors:   []string{},
+		Warnings: []string{},
+	}
+
+	cfg, _, warnings, err := config.Load(true, true, configFiles...)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	}
+
+	for _, warning := range warnings {
+		report.Warnings = append(report.Warnings, warning.Error())
+	}
+
+	if err == nil {
+		for _, validationErr := range agent.ValidateConfig(cfg) {
+			report.Errors = append(report.Errors, validationErr.Error())
+		}
+	}
+
+	report.Valid = len(report.Errors) == 0
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(report); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write the report: %v\n", err)
+
+		return 1
+	}
+
+	if !report.Valid {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,89 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckConfiguration(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantCode   int
+		wantErrors int
+	}{
+		{
+			name:     "valid",
+			content:  "agent:\n  metrics_format: prometheus\n",
+			wantCode: 0,
+		},
+		{
+			name:       "service-without-type",
+			content:    "service:\n  - instance: web\n    port: 8080\n",
+			wantCode:   1,
+			wantErrors: 1,
+		},
+		{
+			name:       "invalid-container-metrics-allowlist",
+			content:    "container:\n  metrics_allowlist:\n    - \"(\"\n",
+			wantCode:   1,
+			wantErrors: 1,
+		},
+		{
+			name:       "invalid-metrics-format",
+			content:    "agent:\n  metrics_format: graphite\n",
+			wantCode:   1,
+			wantErrors: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "glouton.conf")
+
+			if err := os.WriteFile(path, []byte(test.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			var output bytes.Buffer
+
+			code := checkConfiguration(&output, []string{path})
+			if code != test.wantCode {
+				t.Errorf("checkConfiguration() = %d, want %d", code, test.wantCode)
+			}
+
+			var report configCheckReport
+
+			if err := json.Unmarshal(output.Bytes(), &report); err != nil {
+				t.Fatalf("failed to decode the report %q: %v", output.String(), err)
+			}
+
+			if len(report.Errors) != test.wantErrors {
+				t.Errorf("report has %d errors, want %d: %v", len(report.Errors), test.wantErrors, report.Errors)
+			}
+
+			if report.Valid != (test.wantCode == 0) {
+				t.Errorf("report.Valid = %v, want %v", report.Valid, test.wantCode == 0)
+			}
+		})
+	}
+}
@@ -34,9 +34,11 @@ import (
 )
 
 const (
-	// The minimum duration to wait before reconnecting.
+	// The default interval between keepalive pings.
+	defaultKeepAlive = 45 * time.Second
+	// The default minimum duration to wait before reconnecting.
 	minimalDelayBetweenConnect = 5 * time.Second
-	// The maximum duration to wait before reconnecting.
+	// The default maximum duration to wait before reconnecting.
 	maximalDelayBetweenConnect = 10 * time.Minute
 	// The maximum number of messages to keep while MQTT is unreachable.
 	maxPendingMessages = 1000
@@ -76,10 +78,32 @@ type Options struct {
 	// A unique identifier for this client.
 	ID                  string
 	PahoLastPingCheckAt func() time.Time
+	// KeepAlive is the interval between keepalive pings, 45 seconds when zero.
+	KeepAlive time.Duration
+	// MinReconnectDelay and MaxReconnectDelay bound the exponential backoff
+	// between connection attempts, 5 seconds and 10 minutes when zero.
+	MinReconnectDelay time.Duration
+	MaxReconnectDelay time.Duration
 }
 
 // New creates a new client.
 func New(opts Options) *Client {
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = defaultKeepAlive
+	}
+
+	if opts.MinReconnectDelay <= 0 {
+		opts.MinReconnectDelay = minimalDelayBetweenConnect
+	}
+
+	if opts.MaxReconnectDelay <= 0 {
+		opts.MaxReconnectDelay = maximalDelayBetweenConnect
+	}
+
+	if opts.MaxReconnectDelay < opts.MinReconnectDelay {
+		opts.MaxReconnectDelay = opts.MinReconnectDelay
+	}
+
 	client := &Client{
 		opts:           opts,
 		mqtt:           opts.ReloadState.Client(),
@@ -137,7 +161,7 @@ func (c *Client) setupMQTT(ctx context.Context) (paho.Client, error) {
 	// Allow for slightly larger timeout value, to avoid disconnection
 	// with bad network connection.
 	opts.SetPingTimeout(20 * time.Second)
-	opts.SetKeepAlive(45 * time.Second)
+	opts.SetKeepAlive(c.opts.KeepAlive)
 
 	// We use our own automatic reconnection logic which is more reliable.
 	opts.SetAutoReconnect(false)
@@ -208,10 +232,13 @@ func (c *Client) connectionManager(ctx context.Context) {
 
 	var lastConnectionTimes []time.Time
 
-	currentConnectDelay := minimalDelayBetweenConnect / 2
+	currentConnectDelay := c.opts.MinReconnectDelay / 2
 	consecutiveError := 0
 	pingMissingConsecutive := 0
 
+	// With a long keepalive, pings are expected less often.
+	delayWithoutPing := max(maxDelayWithoutPing, 2*c.opts.KeepAlive)
+
 mainLoop:
 	for ctx.Err() == nil {
 		c.l.Lock()
@@ -255,10 +282,10 @@ mainLoop:
 					lastConnectionTimes = lastConnectionTimes[len(lastConnectionTimes)-20:]
 				}
 
-				if currentConnectDelay < maximalDelayBetweenConnect {
+				if currentConnectDelay < c.opts.MaxReconnectDelay {
 					consecutiveError++
 					currentConnectDelay = delay.JitterDelay(
-						delay.Exponential(minimalDelayBetweenConnect, 1.55, consecutiveError, maximalDelayBetweenConnect),
+						delay.Exponential(c.opts.MinReconnectDelay, 1.55, consecutiveError, c.opts.MaxReconnectDelay),
 						0.1,
 					)
 					if consecutiveError == 5 {
@@ -277,7 +304,10 @@ mainLoop:
 				}
 
 				optionReader := mqtt.OptionsReader()
-				logger.V(2).Printf("Connecting to %s MQTT broker %v", c.opts.ID, optionReader.Servers()[0])
+				logger.V(2).Printf(
+					"Connecting to %s MQTT broker %v (keepalive %v, reconnect delay between %v and %v)",
+					c.opts.ID, optionReader.Servers()[0], c.opts.KeepAlive, c.opts.MinReconnectDelay, c.opts.MaxReconnectDelay,
+				)
 
 				var connectionTimeout bool
 
@@ -313,7 +343,7 @@ mainLoop:
 				}
 			}
 		case mqtt != nil && mqtt.IsConnectionOpen():
-			if c.opts.PahoLastPingCheckAt != nil && !c.opts.PahoLastPingCheckAt().IsZero() && time.Since(c.opts.PahoLastPingCheckAt()) > maxDelayWithoutPing {
+			if c.opts.PahoLastPingCheckAt != nil && !c.opts.PahoLastPingCheckAt().IsZero() && time.Since(c.opts.PahoLastPingCheckAt()) > delayWithoutPing {
 				pingMissingConsecutive++
 
 				if pingMissingConsecutive >= 2 {
@@ -347,8 +377,8 @@ mainLoop:
 
 			length := len(lastConnectionTimes)
 			if length > 0 && time.Since(lastConnectionTimes[length-1]) > stableConnection {
-				logger.V(2).Printf("%s MQTT connection was stable, reset delay to %v", c.opts.ID, minimalDelayBetweenConnect)
-				currentConnectDelay = minimalDelayBetweenConnect
+				logger.V(2).Printf("%s MQTT connection was stable, reset delay to %v", c.opts.ID, c.opts.MinReconnectDelay)
+				currentConnectDelay = c.opts.MinReconnectDelay
 				consecutiveError = 0
 			} else if length > 0 {
 				delay := currentConnectDelay - time.Since(lastConnectionTimes[len(lastConnectionTimes)-1])
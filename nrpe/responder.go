@@ -26,6 +26,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/bleemeo/glouton/config"
 	"github.com/bleemeo/glouton/discovery"
@@ -37,8 +38,16 @@ import (
 var (
 	errContainsEmptyCommand = errors.New("NRPE: config file contains an empty command")
 	errUnreadable           = errors.New("NRPE: Unable to read output")
+	errArgumentsNotEnabled  = errors.New("NRPE: arguments not enabled")
+	errIllegalMetachars     = errors.New("NRPE: request contained illegal metachars")
 )
 
+// nastyMetachars are the characters refused in the arguments of a request, like
+// the NRPE server does. "$" is refused so an argument can't contain another $ARGn$.
+// Whitespaces are also refused, as the arguments are substituted in the command
+// line before it's split.
+const nastyMetachars = "|`&><'\"\\[]{};$"
+
 type checkRegistry interface {
 	GetCheckNow(nameInstance discovery.NameInstance) (discovery.CheckNow, error)
 }
@@ -52,7 +61,9 @@ type Responder struct {
 }
 
 // NewResponse returns a Response.
-func NewResponse(services []config.Service, checkRegistry checkRegistry, nrpeConfPath []string) Responder {
+// Arguments in requests are allowed when allowArguments is true or when
+// dont_blame_nrpe is enabled in the NRPE configuration files.
+func NewResponse(services []config.Service, checkRegistry checkRegistry, nrpeConfPath []string, allowArguments bool) Responder {
 	customChecks := make(map[string]discovery.NameInstance)
 
 	for _, service := range services {
@@ -66,13 +77,13 @@ func NewResponse(services []config.Service, checkRegistry checkRegistry, nrpeCon
 		}
 	}
 
-	nrpeCommands, allowArguments := readNRPEConf(nrpeConfPath)
+	nrpeCommands, confAllowArguments := readNRPEConf(nrpeConfPath)
 
 	return Responder{
 		discovery:      checkRegistry,
 		customCheck:    customChecks,
 		nrpeCommands:   nrpeCommands,
-		allowArguments: allowArguments,
+		allowArguments: allowArguments || confAllowArguments,
 	}
 }
 
@@ -82,6 +93,10 @@ func (r Responder) Response(ctx context.Context, request string) (string, int16,
 
 	logger.V(2).Printf("Received request for NRPE command %s", requestArgs[0])
 
+	if len(requestArgs) > 1 && !r.allowArguments {
+		return "", 0, errArgumentsNotEnabled
+	}
+
 	_, ok := r.customCheck[requestArgs[0]]
 	if ok {
 		return r.responseCustomCheck(ctx, requestArgs[0])
@@ -112,6 +127,12 @@ func (r Responder) responseCustomCheck(ctx context.Context, request string) (str
 
 func (r Responder) responseNRPEConf(ctx context.Context, requestArgs []string) (string, int16, error) {
 	nrpeCommand, err := r.returnCommand(requestArgs)
+	if errors.Is(err, errIllegalMetachars) {
+		logger.V(1).Printf("Refused NRPE command %s: %s", requestArgs[0], err)
+
+		return "", 0, err
+	}
+
 	if err != nil {
 		logger.V(1).Printf("Impossible to create the NRPE command : %s", err)
 
@@ -147,6 +168,14 @@ func (r Responder) responseNRPEConf(ctx context.Context, requestArgs []string) (
 func (r Responder) returnCommand(requestArgs []string) ([]string, error) {
 	nrpeCommand := r.nrpeCommands[requestArgs[0]]
 
+	if r.allowArguments {
+		for i, arg := range requestArgs[1:] {
+			if !isSafeArgument(arg) {
+				return nil, fmt.Errorf("%w in argument %d", errIllegalMetachars, i+1)
+			}
+		}
+	}
+
 	argPattern := "\\$ARG([0-9])+\\$"
 	regex := regexp.MustCompile(argPattern)
 
@@ -166,6 +195,15 @@ func (r Responder) returnCommand(requestArgs []string) ([]string, error) {
 	return shlex.Split(nrpeCommand)
 }
 
+// isSafeArgument returns whether the argument can be substituted in a command line.
+func isSafeArgument(arg string) bool {
+	if strings.ContainsAny(arg, nastyMetachars) {
+		return false
+	}
+
+	return !strings.ContainsFunc(arg, unicode.IsSpace)
+}
+
 // readNRPEConf reads all the conf files of nrpeConfPath and returns a map which contains all the commands
 // and a boolean to allow or not the arguments in NRPE requests.
 func readNRPEConf(nrpeConfPath []string) (map[string]string, bool) {
@@ -17,6 +17,7 @@
 package nrpe
 
 import (
+	"context"
 	"errors"
 	"reflect"
 	"testing"
@@ -276,8 +277,8 @@ func TestReturnCommand(t *testing.T) { //nolint:maintidx
 				Args: []string{"check_users", "space in args"},
 			},
 			Want: Want{
-				Command: []string{"command", "--option", "space", "in", "args", "-s"},
-				Err:     nil,
+				Command: nil,
+				Err:     errIllegalMetachars,
 			},
 		},
 		{
@@ -293,8 +294,8 @@ func TestReturnCommand(t *testing.T) { //nolint:maintidx
 				Args: []string{"check_users", "the argument one", "the argument two"},
 			},
 			Want: Want{
-				Command: []string{"command", "--option", "the", "argument", "one", "-s", "the argument two"},
-				Err:     nil,
+				Command: nil,
+				Err:     errIllegalMetachars,
 			},
 		},
 		{
@@ -310,8 +311,8 @@ func TestReturnCommand(t *testing.T) { //nolint:maintidx
 				Args: []string{"check_users", "the argument one", "the argument two"},
 			},
 			Want: Want{
-				Command: []string{"command", "--option", "the", "argument", "one", "-s", "the argument two"},
-				Err:     nil,
+				Command: nil,
+				Err:     errIllegalMetachars,
 			},
 		},
 		{
@@ -460,10 +461,10 @@ func TestReturnCommand(t *testing.T) { //nolint:maintidx
 					},
 					allowArguments: true,
 				},
-				Args: []string{"check_users", "glouton", "bleemeo company", "three as number", "four (4)", "the number five"},
+				Args: []string{"check_users", "glouton", "bleemeo-company", "three", "four", "five"},
 			},
 			Want: Want{
-				Command: []string{"command", "--args", "glouton by the number five"},
+				Command: []string{"command", "--args", "glouton by five"},
 				Err:     nil,
 			},
 		},
@@ -484,6 +485,57 @@ func TestReturnCommand(t *testing.T) { //nolint:maintidx
 				Err:     nil,
 			},
 		},
+		{
+			Entries: Entries{
+				Responder: Responder{
+					discovery:   nil,
+					customCheck: nil,
+					nrpeCommands: map[string]string{
+						"check_users": "command --option $ARG1$ -a $ARG2$",
+					},
+					allowArguments: true,
+				},
+				Args: []string{"check_users", "5;rm", "10"},
+			},
+			Want: Want{
+				Command: nil,
+				Err:     errIllegalMetachars,
+			},
+		},
+		{
+			Entries: Entries{
+				Responder: Responder{
+					discovery:   nil,
+					customCheck: nil,
+					nrpeCommands: map[string]string{
+						"check_users": "command --option '$ARG1$' -a $ARG2$",
+					},
+					allowArguments: true,
+				},
+				Args: []string{"check_users", "'$ARG2$'", "10"},
+			},
+			Want: Want{
+				Command: nil,
+				Err:     errIllegalMetachars,
+			},
+		},
+		{
+			Entries: Entries{
+				Responder: Responder{
+					discovery:   nil,
+					customCheck: nil,
+					nrpeCommands: map[string]string{
+						"check_users": "command --option $ARG1$",
+					},
+					allowArguments: true,
+				},
+				Args: []string{"check_users", "5", "$(reboot)"},
+			},
+			Want: Want{
+				Command: nil,
+				Err:     errIllegalMetachars,
+			},
+		},
 	}
 
 	for i, c := range cases {
@@ -497,3 +549,62 @@ func TestReturnCommand(t *testing.T) { //nolint:maintidx
 		}
 	}
 }
+
+func TestResponseArguments(t *testing.T) {
+	cases := []struct {
+		Name           string
+		AllowArguments bool
+		Request        string
+		WantOutput     string
+		WantErr        error
+	}{
+		{
+			Name:           "arguments disabled",
+			AllowArguments: false,
+			Request:        "check_echo!5!10",
+			WantErr:        errArgumentsNotEnabled,
+		},
+		{
+			Name:           "no arguments",
+			AllowArguments: false,
+			Request:        "check_echo",
+			WantOutput:     "warning= critical=",
+		},
+		{
+			Name:           "arguments enabled",
+			AllowArguments: true,
+			Request:        "check_echo!5!10",
+			WantOutput:     "warning=5 critical=10",
+		},
+		{
+			Name:           "injected argument",
+			AllowArguments: true,
+			Request:        "check_echo!5`touch /tmp/nrpe`!10",
+			WantErr:        errIllegalMetachars,
+		},
+		{
+			Name:           "argument with spaces",
+			AllowArguments: true,
+			Request:        "check_echo!5 --verbose!10",
+			WantErr:        errIllegalMetachars,
+		},
+	}
+
+	for _, c := range cases {
+		r := Responder{
+			nrpeCommands: map[string]string{
+				"check_echo": "echo warning=$ARG1$ critical=$ARG2$",
+			},
+			allowArguments: c.AllowArguments,
+		}
+
+		output, _, err := r.Response(context.Background(), c.Request)
+		if !errors.Is(err, c.WantErr) {
+			t.Errorf("%s: Response(%s) error = %v, want %v", c.Name, c.Request, err, c.WantErr)
+		}
+
+		if output != c.WantOutput {
+			t.Errorf("%s: Response(%s) = %q, want %q", c.Name, c.Request, output, c.WantOutput)
+		}
+	}
+}
@@ -40,7 +40,10 @@ import (
 	"github.com/bleemeo/glouton/version"
 )
 
-var errWrongValue = errors.New("wrong value for crc32")
+var (
+	errWrongValue         = errors.New("wrong value for crc32")
+	errUnsupportedVersion = errors.New("unsupported packet version")
+)
 
 type reducedPacket struct {
 	packetVersion int16
@@ -80,7 +83,7 @@ func handleConnection(ctx context.Context, c io.ReadWriteCloser, cb callback, rn
 	var answer reducedPacket
 
 	if decodedRequest.buffer == "_NRPE_CHECK" {
-		answer.buffer = fmt.Sprintf("NRPE v3 (Glouton %v)", version.Version)
+		answer.buffer = fmt.Sprintf("NRPE v4 (Glouton %v)", version.Version)
 	} else {
 		answer.buffer, answer.resultCode, err = cb(ctx, decodedRequest.buffer)
 	}
@@ -94,9 +97,12 @@ func handleConnection(ctx context.Context, c io.ReadWriteCloser, cb callback, rn
 
 	var encodedAnswer []byte
 
-	if answer.packetVersion == 3 {
+	switch answer.packetVersion {
+	case 4:
+		encodedAnswer, err = encodeV4(answer)
+	case 3:
 		encodedAnswer, err = encodeV3(answer)
-	} else {
+	default:
 		encodedAnswer, err = encodeV2(answer, rndBytes)
 	}
 
@@ -165,7 +171,11 @@ func decode(r io.Reader) (reducedPacket, error) {
 		decodedPacket.resultCode = 0
 	}
 
-	if decodedPacket.packetVersion == 3 {
+	if decodedPacket.packetVersion != 2 && decodedPacket.packetVersion != 3 && decodedPacket.packetVersion != 4 {
+		return decodedPacket, fmt.Errorf("%w: %d", errUnsupportedVersion, decodedPacket.packetVersion)
+	}
+
+	if decodedPacket.packetVersion == 3 || decodedPacket.packetVersion == 4 {
 		var uselessvariable int16
 
 		err = binary.Read(buf, binary.BigEndian, &uselessvariable)
@@ -187,14 +197,20 @@ func decode(r io.Reader) (reducedPacket, error) {
 		bufferlength = 1017
 	}
 
-	packetBuffer := make([]byte, bufferlength+3)
+	// Version 2 and 3 packets end with 3 padding bytes, version 4 removed them.
+	padding := int32(3)
+	if decodedPacket.packetVersion == 4 {
+		padding = 0
+	}
+
+	packetBuffer := make([]byte, bufferlength+padding)
 
 	_, err = r.Read(packetBuffer)
 	if err != nil {
 		return reducedPacket{}, err
 	}
 	// test value CRC32
-	completePacket := make([]byte, 19+bufferlength)
+	completePacket := make([]byte, 16+bufferlength+padding)
 
 	copy(completePacket[:16], packetHead)
 	copy(completePacket[16:], packetBuffer)
@@ -209,8 +225,12 @@ func decode(r io.Reader) (reducedPacket, error) {
 	}
 
 	i := bytes.IndexByte(packetBuffer, 0x0)
+	if i == -1 {
+		// Version 4 packets may not be NUL-terminated.
+		i = len(packetBuffer)
+	}
 
-	if decodedPacket.packetVersion == 3 {
+	if decodedPacket.packetVersion == 3 || decodedPacket.packetVersion == 4 {
 		packetBuffer = packetBuffer[:i]
 		decodedPacket.buffer = string(packetBuffer)
 	}
@@ -276,9 +296,19 @@ func encodeV2(decodedPacket reducedPacket, randBytes [2]byte) ([]byte, error) {
 }
 
 func encodeV3(decodedPacket reducedPacket) ([]byte, error) {
+	return encodeVariableLength(decodedPacket, 3)
+}
+
+// encodeV4 encodes a version 4 packet. It only differs from version 3 by the
+// removal of the 3 padding bytes after the buffer.
+func encodeV4(decodedPacket reducedPacket) ([]byte, error) {
+	return encodeVariableLength(decodedPacket, 0)
+}
+
+func encodeVariableLength(decodedPacket reducedPacket, padding int) ([]byte, error) {
 	decodedPacket.packetType = 2
 	bufferLength := int32(len(decodedPacket.buffer))
-	encodedPacket := make([]byte, 19+len(decodedPacket.buffer))
+	encodedPacket := make([]byte, 16+len(decodedPacket.buffer)+padding)
 
 	buf := new(bytes.Buffer)
 
@@ -19,6 +19,7 @@ package nrpe
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"testing"
 )
@@ -108,6 +109,32 @@ func TestDecodeEncodeV3(t *testing.T) {
 	}
 }
 
+func TestDecodeEncodeV4(t *testing.T) {
+	cases := reducedPacket{4, 2, 0, "connection successful"}
+	inter, _ := encodeV4(cases)
+
+	got, err := decode(bytes.NewReader(inter))
+	if got != cases {
+		t.Errorf("decode(encodeV4(%v)) == %v, want %v", cases, got, cases)
+	}
+
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDecodeUnsupportedVersion(t *testing.T) {
+	packet := make([]byte, len(checkUsersV4.QueryRaw))
+	copy(packet, checkUsersV4.QueryRaw)
+
+	packet[1] = 0x05
+
+	_, err := decode(bytes.NewReader(packet))
+	if !errors.Is(err, errUnsupportedVersion) {
+		t.Errorf("decode() error = %v, want %v", err, errUnsupportedVersion)
+	}
+}
+
 func TestDecodeEncode(t *testing.T) {
 	for _, c := range allPackets {
 		packet := reducedPacket{
@@ -124,9 +151,12 @@ func TestDecodeEncode(t *testing.T) {
 
 		var inter []byte
 
-		if c.Version == 3 {
+		switch c.Version {
+		case 4:
+			inter, _ = encodeV4(packet)
+		case 3:
 			inter, _ = encodeV3(packet)
-		} else if c.Version == 2 {
+		case 2:
 			var rndBytes [2]byte
 
 			copy(rndBytes[:], c.ReplyRaw[len(c.ReplyRaw)-2:])
@@ -168,14 +198,17 @@ func TestEncode(t *testing.T) {
 			inPacket.buffer = c.ReplyError.Error()
 		}
 
-		if c.Version == 2 {
+		switch c.Version {
+		case 2:
 			var rndBytes [2]byte
 
 			copy(rndBytes[:], c.ReplyRaw[len(c.ReplyRaw)-2:])
 
 			got, err = encodeV2(inPacket, rndBytes)
-		} else if c.Version == 3 {
+		case 3:
 			got, err = encodeV3(inPacket)
+		case 4:
+			got, err = encodeV4(inPacket)
 		}
 
 		if !bytes.Equal(got, c.ReplyRaw) {
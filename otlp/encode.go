@@ -0,0 +1,116 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"sort"
+
+	"github.com/bleemeo/glouton/types"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// Resource describes the entity producing the metrics.
+type Resource struct {
+	Attributes map[string]string
+}
+
+// newExportRequest returns the ExportMetricsServiceRequest containing the points.
+// All the points are sent as gauges, the name of the metric is the __name__ label and
+// the other labels are the attributes of the data point. The value of a status metric
+// is its Nagios code.
+func newExportRequest(resource Resource, scopeVer string, points []types.MetricPoint) *colmetricspb.ExportMetricsServiceRequest {
+	metricsByName := make(map[string]*metricspb.Metric)
+	metrics := make([]*metricspb.Metric, 0)
+
+	for _, point := range points {
+		name := point.Labels[types.LabelName]
+		if name == "" {
+			continue
+		}
+
+		metric, ok := metricsByName[name]
+		if !ok {
+			metric = &metricspb.Metric{
+				Name: name,
+				Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{}},
+			}
+
+			metricsByName[name] = metric
+			metrics = append(metrics, metric)
+		}
+
+		gauge := metric.GetGauge()
+		gauge.DataPoints = append(gauge.DataPoints, newDataPoint(point))
+	}
+
+	return &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{
+			{
+				Resource: &resourcepb.Resource{Attributes: keyValues(resource.Attributes, "")},
+				ScopeMetrics: []*metricspb.ScopeMetrics{
+					{
+						Scope:   &commonpb.InstrumentationScope{Name: "glouton", Version: scopeVer},
+						Metrics: metrics,
+					},
+				},
+			},
+		},
+	}
+}
+
+func newDataPoint(point types.MetricPoint) *metricspb.NumberDataPoint {
+	value := point.Value
+
+	if status := point.Annotations.Status; status.CurrentStatus.IsSet() {
+		value = float64(status.CurrentStatus.NagiosCode())
+	}
+
+	return &metricspb.NumberDataPoint{
+		Attributes:   keyValues(point.Labels, types.LabelName),
+		TimeUnixNano: uint64(point.Time.UnixNano()),
+		Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: value},
+	}
+}
+
+// keyValues converts the labels to OTLP attributes sorted by key, the label
+// named skip is ignored.
+func keyValues(labels map[string]string, skip string) []*commonpb.KeyValue {
+	keys := make([]string, 0, len(labels))
+
+	for k := range labels {
+		if k != skip {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	result := make([]*commonpb.KeyValue, 0, len(keys))
+
+	for _, k := range keys {
+		result = append(result, &commonpb.KeyValue{
+			Key:   k,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: labels[k]}},
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,209 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otlp sends the metrics to an OpenTelemetry collector using OTLP.
+package otlp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bleemeo/glouton/logger"
+	"github.com/bleemeo/glouton/mqtt"
+	"github.com/bleemeo/glouton/types"
+	"github.com/bleemeo/glouton/utils/pointsbuffer"
+	"github.com/bleemeo/glouton/version"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+
+	defaultMaxPendingPoints = 100000
+	defaultBatchSize        = 1000
+
+	// Delays between two attempts to send the points when the endpoint isn't reachable.
+	minRetryDelay = 10 * time.Second
+	maxRetryDelay = 5 * time.Minute
+)
+
+//nolint:gochecknoglobals
+var pendingPointsDesc = prometheus.NewDesc(
+	"glouton_otlp_pending_points",
+	"Number of points waiting to be sent to the OTLP endpoint",
+	nil,
+	nil,
+)
+
+var errUnknownProtocol = errors.New("unknown protocol")
+
+// Store is the interface used by the client to access the Metric Store.
+type Store interface {
+	AddNotifiee(cb func([]types.MetricPoint)) int
+	RemoveNotifiee(id int)
+}
+
+// Options are the options of the OTLP client.
+type Options struct {
+	// Protocol is ProtocolGRPC or ProtocolHTTP.
+	Protocol string
+	// Endpoint is the host:port of the collector. With OTLP/HTTP, it could also be an URL.
+	Endpoint string
+	// Insecure disables TLS.
+	Insecure    bool
+	CAFile      string
+	SSLInsecure bool
+	// Headers are added to each request.
+	Headers map[string]string
+	// MaxPendingPoints is the number of points kept while the endpoint is unreachable.
+	// The oldest points are dropped when the buffer is full.
+	MaxPendingPoints int
+	// Resource returns the attributes of the OTLP resource, it's called for each request
+	// because some attributes (e.g. the agent ID) could be known after the start.
+	Resource func() Resource
+}
+
+// Client sends the points of the store to an OTLP endpoint.
+type Client struct {
+	opts         Options
+	store        Store
+	sender       sender
+	buffer       *pointsbuffer.Buffer
+	maxBatchSize int
+
+	lastErr error
+}
+
+// New returns an OTLP client.
+func New(opts Options, store Store) (*Client, error) {
+	maxPendingPoints := opts.MaxPendingPoints
+	if maxPendingPoints <= 0 {
+		maxPendingPoints = defaultMaxPendingPoints
+	}
+
+	if opts.Resource == nil {
+		opts.Resource = func() Resource { return Resource{} }
+	}
+
+	tlsConfig := mqtt.TLSConfig(opts.SSLInsecure, opts.CAFile)
+
+	var (
+		s   sender
+		err error
+	)
+
+	switch opts.Protocol {
+	case ProtocolGRPC, "":
+		s, err = newGRPCSender(opts.Endpoint, !opts.Insecure, tlsConfig, opts.Headers)
+		if err != nil {
+			return nil, err
+		}
+	case ProtocolHTTP:
+		s = newHTTPSender(opts.Endpoint, !opts.Insecure, tlsConfig, opts.Headers)
+	default:
+		return nil, fmt.Errorf("%w %q, it must be %s or %s", errUnknownProtocol, opts.Protocol, ProtocolGRPC, ProtocolHTTP)
+	}
+
+	return &Client{
+		opts:         opts,
+		store:        store,
+		sender:       s,
+		buffer:       pointsbuffer.New("OTLP", maxPendingPoints),
+		maxBatchSize: defaultBatchSize,
+	}, nil
+}
+
+// sendPending sends the pending points by batches. It returns false when
+// a batch failed to be sent and must be retried later.
+func (c *Client) sendPending(ctx context.Context) bool {
+	for ctx.Err() == nil && c.buffer.Len() > 0 {
+		batch := c.buffer.Batch(c.maxBatchSize)
+
+		err := c.sender.send(ctx, newExportRequest(c.opts.Resource(), version.Version, batch.Points))
+		if errors.Is(err, errRejected) {
+			logger.V(1).Printf("The OTLP endpoint rejected %d points: %v", len(batch.Points), err)
+
+			err = nil
+		}
+
+		if err != nil {
+			// The points are kept on authentication errors, they will be sent once the
+			// credentials are fixed. The error is always logged because it needs an action.
+			if c.lastErr == nil || errors.Is(err, errUnauthorized) {
+				logger.Printf("Fail to send the metrics to the OTLP endpoint: %v", err)
+			} else {
+				logger.V(2).Printf("Fail to send the metrics to the OTLP endpoint: %v", err)
+			}
+
+			c.lastErr = err
+
+			return false
+		}
+
+		if c.lastErr != nil {
+			logger.Printf("The OTLP endpoint is reachable again")
+
+			c.lastErr = nil
+		}
+
+		c.buffer.Remove(batch)
+	}
+
+	return true
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *Client) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pendingPointsDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *Client) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(pendingPointsDesc, prometheus.GaugeValue, float64(c.buffer.Len()))
+}
+
+// Run sends the points of the store until the context is cancelled.
+func (c *Client) Run(ctx context.Context) error {
+	defer c.sender.Close()
+
+	notifieeID := c.store.AddNotifiee(c.buffer.Add)
+	defer c.store.RemoveNotifiee(notifieeID)
+
+	retryDelay := minRetryDelay
+
+	for ctx.Err() == nil {
+		delay := minRetryDelay
+
+		if c.sendPending(ctx) {
+			retryDelay = minRetryDelay
+		} else {
+			// Exponential backoff while the endpoint is unreachable.
+			delay = retryDelay
+			retryDelay = min(2*retryDelay, maxRetryDelay)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,310 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bleemeo/glouton/types"
+
+	"github.com/google/go-cmp/cmp"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// decodedPoint is the simplified content of a data point.
+type decodedPoint struct {
+	Name       string
+	Time       time.Time
+	Value      float64
+	Attributes map[string]string
+}
+
+func decodeKeyValues(values []*commonpb.KeyValue) map[string]string {
+	result := make(map[string]string, len(values))
+
+	for _, kv := range values {
+		result[kv.GetKey()] = kv.GetValue().GetStringValue()
+	}
+
+	return result
+}
+
+// decodeExportRequest returns the resource attributes and the data points of a request.
+func decodeExportRequest(t *testing.T, request *colmetricspb.ExportMetricsServiceRequest) (map[string]string, []decodedPoint) {
+	t.Helper()
+
+	if len(request.GetResourceMetrics()) != 1 || len(request.GetResourceMetrics()[0].GetScopeMetrics()) != 1 {
+		t.Fatalf("request = %v, want a single resource and scope", request)
+	}
+
+	resourceMetrics := request.GetResourceMetrics()[0]
+	resource := decodeKeyValues(resourceMetrics.GetResource().GetAttributes())
+
+	var points []decodedPoint
+
+	for _, metric := range resourceMetrics.GetScopeMetrics()[0].GetMetrics() {
+		for _, point := range metric.GetGauge().GetDataPoints() {
+			points = append(points, decodedPoint{
+				Name:       metric.GetName(),
+				Time:       time.Unix(0, int64(point.GetTimeUnixNano())),
+				Value:      point.GetAsDouble(),
+				Attributes: decodeKeyValues(point.GetAttributes()),
+			})
+		}
+	}
+
+	return resource, points
+}
+
+func testPoints(t0 time.Time) ([]types.MetricPoint, []decodedPoint) {
+	points := []types.MetricPoint{
+		{
+			Point:  types.Point{Time: t0, Value: 42},
+			Labels: map[string]string{types.LabelName: "cpu_used", "instance": "server"},
+		},
+		{
+			Point:  types.Point{Time: t0, Value: 1},
+			Labels: map[string]string{types.LabelName: "service_status", "service": "nginx"},
+			Annotations: types.MetricAnnotations{
+				Status: types.StatusDescription{
+					CurrentStatus:     types.StatusCritical,
+					StatusDescription: "connection refused",
+				},
+			},
+		},
+		{
+			Point:  types.Point{Time: t0.Add(10 * time.Second), Value: 43},
+			Labels: map[string]string{types.LabelName: "cpu_used", "instance": "server"},
+		},
+	}
+
+	want := []decodedPoint{
+		{Name: "cpu_used", Time: t0, Value: 42, Attributes: map[string]string{"instance": "server"}},
+		{Name: "cpu_used", Time: t0.Add(10 * time.Second), Value: 43, Attributes: map[string]string{"instance": "server"}},
+		{
+			Name:       "service_status",
+			Time:       t0,
+			Value:      2,
+			Attributes: map[string]string{"service": "nginx"},
+		},
+	}
+
+	return points, want
+}
+
+func TestEncodeExportRequest(t *testing.T) {
+	t.Parallel()
+
+	t0 := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	points, wantPoints := testPoints(t0)
+	wantResource := map[string]string{"host.name": "server.example.com", "glouton.agent_id": "1234"}
+
+	resource, got := decodeExportRequest(t, newExportRequest(Resource{Attributes: wantResource}, "1.0", points))
+
+	if diff := cmp.Diff(wantResource, resource); diff != "" {
+		t.Errorf("resource mismatch (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff(wantPoints, got); diff != "" {
+		t.Errorf("points mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSendHTTP(t *testing.T) {
+	t.Parallel()
+
+	var (
+		l        sync.Mutex
+		requests [][]byte
+		status   = http.StatusOK
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.Lock()
+		defer l.Unlock()
+
+		if r.URL.Path != httpPath || r.Header.Get("Content-Type") != "application/x-protobuf" || r.Header.Get("X-Tenant") != "t1" {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, body)
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := New(Options{Protocol: ProtocolHTTP, Endpoint: srv.URL, Headers: map[string]string{"X-Tenant": "t1"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t0 := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	points, wantPoints := testPoints(t0)
+
+	client.buffer.Add(points)
+
+	// The points are kept when the endpoint is unavailable or the authentication failed.
+	for _, failStatus := range []int{http.StatusServiceUnavailable, http.StatusUnauthorized, http.StatusForbidden} {
+		l.Lock()
+		status = failStatus
+		l.Unlock()
+
+		if client.sendPending(context.Background()) {
+			t.Errorf("sendPending() succeeded with the status %d", failStatus)
+		}
+
+		if n := client.buffer.Len(); n != len(points) {
+			t.Errorf("buffer.Len() = %d with the status %d, want %d", n, failStatus, len(points))
+		}
+	}
+
+	l.Lock()
+	status = http.StatusOK
+	requests = nil
+	l.Unlock()
+
+	if !client.sendPending(context.Background()) {
+		t.Fatal("sendPending() failed")
+	}
+
+	if n := client.buffer.Len(); n != 0 {
+		t.Errorf("buffer.Len() = %d, want 0", n)
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+
+	var request colmetricspb.ExportMetricsServiceRequest
+
+	if err := proto.Unmarshal(requests[0], &request); err != nil {
+		t.Fatal(err)
+	}
+
+	_, got := decodeExportRequest(t, &request)
+	if diff := cmp.Diff(wantPoints, got); diff != "" {
+		t.Errorf("points mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// metricsServer is an OTLP/gRPC server which records the requests.
+type metricsServer struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+
+	l        sync.Mutex
+	err      error
+	requests []*colmetricspb.ExportMetricsServiceRequest
+}
+
+func (s *metricsServer) Export(_ context.Context, request *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	s.requests = append(s.requests, request)
+
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+func TestSendGRPC(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := &metricsServer{err: status.Error(codes.Unauthenticated, "invalid token")}
+
+	server := grpc.NewServer()
+	colmetricspb.RegisterMetricsServiceServer(server, metrics)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	t.Cleanup(server.Stop)
+
+	client, err := New(Options{Protocol: ProtocolGRPC, Endpoint: listener.Addr().String(), Insecure: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { client.sender.Close() })
+
+	t0 := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	points, wantPoints := testPoints(t0)
+
+	client.buffer.Add(points)
+
+	if client.sendPending(context.Background()) {
+		t.Error("sendPending() succeeded while the authentication failed")
+	}
+
+	if n := client.buffer.Len(); n != len(points) {
+		t.Errorf("buffer.Len() = %d, want %d", n, len(points))
+	}
+
+	metrics.l.Lock()
+	metrics.err = nil
+	metrics.l.Unlock()
+
+	if !client.sendPending(context.Background()) {
+		t.Fatal("sendPending() failed")
+	}
+
+	metrics.l.Lock()
+	defer metrics.l.Unlock()
+
+	if len(metrics.requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(metrics.requests))
+	}
+
+	_, got := decodeExportRequest(t, metrics.requests[0])
+	if diff := cmp.Diff(wantPoints, got); diff != "" {
+		t.Errorf("points mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewUnknownProtocol(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New(Options{Protocol: "udp", Endpoint: "localhost:4317"}, nil); err == nil {
+		t.Error("New() succeeded with an unknown protocol")
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bleemeo/glouton/version"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	httpPath    = "/v1/metrics"
+	sendTimeout = 30 * time.Second
+)
+
+var (
+	errServer       = errors.New("server error")
+	errUnauthorized = errors.New("authentication failed")
+	errRejected     = errors.New("points rejected")
+)
+
+// sender sends an ExportMetricsServiceRequest.
+type sender interface {
+	send(ctx context.Context, request *colmetricspb.ExportMetricsServiceRequest) error
+	Close() error
+}
+
+// httpSender uses OTLP/HTTP with the binary protobuf encoding.
+type httpSender struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newHTTPSender(endpoint string, useTLS bool, tlsConfig *tls.Config, headers map[string]string) *httpSender {
+	url := endpoint
+
+	if !strings.Contains(url, "://") {
+		if useTLS {
+			url = "https://" + url
+		} else {
+			url = "http://" + url
+		}
+	}
+
+	if !strings.HasSuffix(url, httpPath) {
+		url = strings.TrimSuffix(url, "/") + httpPath
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+	transport.TLSClientConfig = tlsConfig
+
+	return &httpSender{
+		url:     url,
+		headers: headers,
+		client:  &http.Client{Transport: transport, Timeout: sendTimeout},
+	}
+}
+
+func (s *httpSender) send(ctx context.Context, request *colmetricspb.ExportMetricsServiceRequest) error {
+	body, err := proto.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", version.UserAgent())
+
+	for name, value := range s.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	// Ensure response body is read to allow HTTP keep-alive to works
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode/100 == 2:
+		return nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %s", errServer, resp.Status)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%w: %s", errUnauthorized, resp.Status)
+	default:
+		return fmt.Errorf("%w: %s", errRejected, resp.Status)
+	}
+}
+
+func (s *httpSender) Close() error {
+	s.client.CloseIdleConnections()
+
+	return nil
+}
+
+// grpcSender uses OTLP/gRPC.
+type grpcSender struct {
+	conn    *grpc.ClientConn
+	client  colmetricspb.MetricsServiceClient
+	headers metadata.MD
+}
+
+func newGRPCSender(endpoint string, useTLS bool, tlsConfig *tls.Config, headers map[string]string) (*grpcSender, error) {
+	creds := insecure.NewCredentials()
+	if useTLS {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(creds), grpc.WithUserAgent(version.UserAgent()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &grpcSender{
+		conn:    conn,
+		client:  colmetricspb.NewMetricsServiceClient(conn),
+		headers: metadata.New(headers),
+	}, nil
+}
+
+func (s *grpcSender) send(ctx context.Context, request *colmetricspb.ExportMetricsServiceRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	ctx = metadata.NewOutgoingContext(ctx, s.headers)
+
+	_, err := s.client.Export(ctx, request)
+	if err == nil {
+		return nil
+	}
+
+	// The retryable codes are defined by the OTLP specification.
+	switch status.Code(err) { //nolint:exhaustive
+	case codes.Canceled, codes.DeadlineExceeded, codes.Aborted, codes.OutOfRange,
+		codes.Unavailable, codes.DataLoss, codes.ResourceExhausted:
+		return fmt.Errorf("%w: %w", errServer, err)
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return fmt.Errorf("%w: %w", errUnauthorized, err)
+	default:
+		return fmt.Errorf("%w: %w", errRejected, err)
+	}
+}
+
+func (s *grpcSender) Close() error {
+	return s.conn.Close()
+}
@@ -22,6 +22,7 @@ import (
 	"crypto/x509"
 	"errors"
 	"fmt"
+	"maps"
 	"net"
 	"net/http/httptrace"
 	"net/url"
@@ -50,10 +51,17 @@ const (
 	proberNameICMP string = "icmp"
 	proberNameDNS  string = "dns"
 
+	// proberNameTransaction executes the multi-step HTTP checks.
+	proberNameTransaction string = "transaction"
+
 	// Context key to get the CA Root, used only in tests.
 	contextKeyTestInjectCARoot contextKey = iota
 	// Context key to get the time function.
 	contextKeyNowFunc contextKey = iota
+	// Context key to get the steps of a transaction.
+	contextKeyTransaction contextKey = iota
+	// Context key to get the source IP of the HTTP probes.
+	contextKeySourceIP contextKey = iota
 )
 
 //nolint:gochecknoglobals
@@ -83,10 +91,12 @@ var (
 		nil,
 	)
 	probers = map[string]prober.ProbeFn{
-		proberNameHTTP: prober.ProbeHTTP,
+		proberNameHTTP: ProbeHTTP,
 		proberNameTCP:  ProbeTCP,
-		proberNameICMP: prober.ProbeICMP,
+		proberNameICMP: ProbeICMP,
 		proberNameDNS:  prober.ProbeDNS,
+
+		proberNameTransaction: ProbeTransaction,
 	}
 )
 
@@ -221,6 +231,8 @@ func (target configTarget) CollectWithContext(ctx context.Context, ch chan<- pro
 	// ProbeFn type we pass these values inside the context.
 	subCtx = context.WithValue(subCtx, contextKeyTestInjectCARoot, target.testInjectCARoot)
 	subCtx = context.WithValue(subCtx, contextKeyNowFunc, target.nowFunc)
+	subCtx = context.WithValue(subCtx, contextKeyTransaction, target.transaction)
+	subCtx = context.WithValue(subCtx, contextKeySourceIP, target.sourceIP)
 
 	// do all the actual work
 	success := probeFn(subCtx, target.URL, target.Module, registry, extLogger)
@@ -458,7 +470,9 @@ func getLastChainExpiry(verifiedChains [][]*x509.Certificate) time.Time {
 
 // compareConfigTargets returns true if the monitors are identical, and false otherwise.
 func compareConfigTargets(a configTarget, b configTarget) bool {
-	return a.BleemeoAgentID == b.BleemeoAgentID && a.URL == b.URL && a.RefreshRate == b.RefreshRate && reflect.DeepEqual(a.Module, b.Module)
+	return a.BleemeoAgentID == b.BleemeoAgentID && a.URL == b.URL && a.RefreshRate == b.RefreshRate &&
+		reflect.DeepEqual(a.Module, b.Module) && reflect.DeepEqual(a.transaction, b.transaction) &&
+		a.sourceIP.Equal(b.sourceIP)
 }
 
 func collectorInMap(value collectorWithLabels, iterable map[int]gathererWithConfigTarget) bool {
@@ -483,11 +497,24 @@ func gathererInArray(value gathererWithConfigTarget, iterable []collectorWithLab
 }
 
 // updateRegistrations registers and deregisters collectors to sync the internal state with the configuration.
+// The caller must hold m.updateL. The registrations are updated on a copy which is swapped in
+// with m.l held, because the registry must not be called with m.l held.
 func (m *RegisterManager) updateRegistrations() error {
+	m.l.Lock()
+	targets := m.targets
+	registrations := maps.Clone(m.registrations)
+	m.l.Unlock()
+
+	defer func() {
+		m.l.Lock()
+		m.registrations = registrations
+		m.l.Unlock()
+	}()
+
 	// register new probes
-	for _, collectorFromConfig := range m.targets {
-		if !collectorInMap(collectorFromConfig, m.registrations) {
-			gatherer, err := newGatherer(collectorFromConfig.Collector)
+	for _, collectorFromConfig := range targets {
+		if !collectorInMap(collectorFromConfig, registrations) {
+			gatherer, err := newGatherer(collectorFromConfig.Collector, m.isPaused)
 			if err != nil {
 				return err
 			}
@@ -530,7 +557,7 @@ func (m *RegisterManager) updateRegistrations() error {
 				m.registry.ScheduleScrape(id, time.Now())
 			}
 
-			m.registrations[id] = gathererWithConfigTarget{
+			registrations[id] = gathererWithConfigTarget{
 				target:   collectorFromConfig.Collector,
 				gatherer: g,
 			}
@@ -540,12 +567,12 @@ func (m *RegisterManager) updateRegistrations() error {
 	}
 
 	// unregister any obsolete probe
-	for idx, gatherer := range m.registrations {
-		if gatherer.target.BleemeoAgentID != "" && !gathererInArray(gatherer, m.targets) {
+	for idx, gatherer := range registrations {
+		if gatherer.target.BleemeoAgentID != "" && !gathererInArray(gatherer, targets) {
 			logger.V(2).Printf("The probe for '%s' is now deactivated", gatherer.target.Name)
 
 			m.registry.Unregister(idx)
-			delete(m.registrations, idx)
+			delete(registrations, idx)
 		}
 	}
 
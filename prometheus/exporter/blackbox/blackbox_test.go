@@ -2083,7 +2083,7 @@ func runTest(t *testing.T, test testCase, usePlainTCPOrSSL bool, monitorID, agen
 		target.Collector.testInjectCARoot = test.target.Certificate()
 	}
 
-	gatherer, err := newGatherer(target.Collector)
+	gatherer, err := newGatherer(target.Collector, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
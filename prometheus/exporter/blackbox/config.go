@@ -34,7 +34,12 @@ import (
 	promConfig "github.com/prometheus/common/config"
 )
 
-var errUnknownModule = errors.New("unknown blackbox module found in your configuration")
+var (
+	errUnknownModule       = errors.New("unknown blackbox module found in your configuration")
+	errInvalidSourceIP     = errors.New("invalid source IP")
+	errSourceIPNotLocal    = errors.New("source IP is not assigned to a local interface")
+	errSourceIPUnsupported = errors.New("source IP isn't supported by this prober")
+)
 
 const defaultTimeout time.Duration = 12 * time.Second
 
@@ -147,6 +152,7 @@ func genCollectorFromDynamicTarget(monitor types.Monitor, userAgent string) (*co
 	}
 
 	confTarget := configTarget{
+		ID:             monitor.ID,
 		Module:         mod,
 		Name:           monitor.URL,
 		BleemeoAgentID: monitor.BleemeoAgentID,
@@ -205,6 +211,74 @@ func genCollectorFromStaticTarget(ct configTarget) collectorWithLabels {
 	}
 }
 
+// genCollectorFromTransaction returns the collector executing the steps of the transaction.
+func genCollectorFromTransaction(transaction config.BlackboxTransaction, userAgent string) collectorWithLabels {
+	mod := defaultModule(userAgent)
+	mod.Prober = proberNameTransaction
+
+	if transaction.Timeout > 0 {
+		mod.Timeout = time.Duration(transaction.Timeout) * time.Second
+	}
+
+	var firstURL string
+
+	if len(transaction.Steps) > 0 {
+		firstURL = transaction.Steps[0].URL
+	}
+
+	if transaction.Name == "" {
+		transaction.Name = firstURL
+	}
+
+	return genCollectorFromStaticTarget(configTarget{
+		Name:        transaction.Name,
+		URL:         firstURL,
+		Module:      mod,
+		ModuleName:  proberNameTransaction,
+		nowFunc:     time.Now,
+		transaction: &transaction,
+	})
+}
+
+// withSourceIP returns the target with its outgoing connections bound to the given source IP.
+// The IP must be assigned to one of the local addresses.
+func withSourceIP(target configTarget, sourceIP string, localAddrs []net.Addr) (configTarget, error) {
+	ip := net.ParseIP(sourceIP)
+	if ip == nil {
+		return target, fmt.Errorf("%w: %s", errInvalidSourceIP, sourceIP)
+	}
+
+	isLocal := false
+
+	for _, addr := range localAddrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			isLocal = true
+
+			break
+		}
+	}
+
+	if !isLocal {
+		return target, fmt.Errorf("%w: %s", errSourceIPNotLocal, sourceIP)
+	}
+
+	switch target.Module.Prober {
+	case proberNameTCP:
+		target.Module.TCP.SourceIPAddress = ip.String()
+	case proberNameICMP:
+		target.Module.ICMP.SourceIPAddress = ip.String()
+	case proberNameDNS:
+		target.Module.DNS.SourceIPAddress = ip.String()
+	case proberNameHTTP:
+		// The HTTP module has no source address, ProbeHTTP reads it from the target.
+		target.sourceIP = ip
+	default:
+		return target, fmt.Errorf("%w %s", errSourceIPUnsupported, target.Module.Prober)
+	}
+
+	return target, nil
+}
+
 // set user-agent on HTTP prober is not already set.
 func setUserAgent(modules map[string]bbConf.Module, userAgent string) {
 	for k, m := range modules {
@@ -243,6 +317,8 @@ func New(
 
 	targets := make([]collectorWithLabels, 0, len(config.Targets))
 
+	var localAddrs []net.Addr
+
 	for idx := range config.Targets {
 		if config.Targets[idx].Name == "" {
 			config.Targets[idx].Name = config.Targets[idx].URL
@@ -255,18 +331,44 @@ func New(
 				"This is a probably bug, please contact us", errUnknownModule, config.Targets[idx].Name, config.Targets[idx].Module)
 		}
 
-		targets = append(targets, genCollectorFromStaticTarget(configTarget{
-			Name:       config.Targets[idx].Name,
-			URL:        config.Targets[idx].URL,
-			Module:     module,
-			ModuleName: config.Targets[idx].Module,
-			nowFunc:    time.Now,
-		}))
+		target := configTarget{
+			Name:        config.Targets[idx].Name,
+			URL:         config.Targets[idx].URL,
+			Module:      module,
+			ModuleName:  config.Targets[idx].Module,
+			RefreshRate: time.Duration(config.Targets[idx].Interval) * time.Second,
+			nowFunc:     time.Now,
+		}
+
+		if config.Targets[idx].SourceIP != "" {
+			if localAddrs == nil {
+				addrs, err := net.InterfaceAddrs()
+				if err != nil {
+					return nil, fmt.Errorf("unable to list local addresses: %w", err)
+				}
+
+				localAddrs = addrs
+			}
+
+			var err error
+
+			target, err = withSourceIP(target, config.Targets[idx].SourceIP, localAddrs)
+			if err != nil {
+				return nil, fmt.Errorf("invalid source_ip for %s: %w", config.Targets[idx].Name, err)
+			}
+		}
+
+		targets = append(targets, genCollectorFromStaticTarget(target))
+	}
+
+	for idx := range config.Transactions {
+		targets = append(targets, genCollectorFromTransaction(config.Transactions[idx], config.UserAgent))
 	}
 
 	manager := &RegisterManager{
 		targets:       targets,
 		registrations: make(map[int]gathererWithConfigTarget, len(config.Targets)),
+		paused:        make(map[string]time.Time),
 		registry:      registry,
 		scraperName:   config.ScraperName,
 		metricFormat:  metricFormat,
@@ -295,6 +397,13 @@ func (m *RegisterManager) DiagnosticArchive(_ context.Context, archive types.Arc
 		fmt.Fprintf(file, "url=%s labels=%v\n", t.Collector.URL, t.Labels)
 	}
 
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	for id, since := range m.paused {
+		fmt.Fprintf(file, "monitor %s paused since %s\n", id, since.Format(time.RFC3339))
+	}
+
 	return nil
 }
 
@@ -303,10 +412,17 @@ func (m *RegisterManager) UpdateDynamicTargets(monitors []types.Monitor) error {
 	// it is easier to keep only the static monitors and rebuild the dynamic config
 	// than to compute the difference between the new and the old configuration.
 	// This is simple because calling UpdateDynamicTargets with the same argument should be idempotent.
-	newTargets := make([]collectorWithLabels, 0, len(monitors)+len(m.targets))
+	m.updateL.Lock()
+	defer m.updateL.Unlock()
+
+	m.l.Lock()
+	currentTargets := m.targets
+	m.l.Unlock()
+
+	newTargets := make([]collectorWithLabels, 0, len(monitors)+len(currentTargets))
 
 	// get a list of static monitors
-	for _, currentTarget := range m.targets {
+	for _, currentTarget := range currentTargets {
 		if currentTarget.Collector.BleemeoAgentID == "" {
 			newTargets = append(newTargets, currentTarget)
 		}
@@ -331,6 +447,7 @@ func (m *RegisterManager) UpdateDynamicTargets(monitors []types.Monitor) error {
 
 	m.l.Lock()
 	m.targets = newTargets
+	m.forgetRemovedPausedLocked()
 	m.l.Unlock()
 
 	logger.V(2).Println("blackbox_exporter: Internal configuration successfully updated.")
@@ -17,6 +17,8 @@
 package blackbox
 
 import (
+	"errors"
+	"net"
 	"reflect"
 	"testing"
 	"time"
@@ -99,9 +101,10 @@ func TestConfigParsing(t *testing.T) {
 				ICMP: bbConf.DefaultICMPProbe,
 				TCP:  bbConf.DefaultTCPProbe,
 			},
-			ModuleName: "dns",
-			Name:       "inpt.fr",
-			nowFunc:    time.Now,
+			ModuleName:  "dns",
+			Name:        "inpt.fr",
+			RefreshRate: 5 * time.Minute,
+			nowFunc:     time.Now,
 		}),
 		genCollectorFromStaticTarget(configTarget{
 			URL: "http://neverssl.com",
@@ -157,3 +160,72 @@ func TestNoTargetsConfigParsing(t *testing.T) {
 		t.Fatalf("TestConfigParsing() = %+v, want %+v", bbManager.targets, []collectorWithLabels{})
 	}
 }
+
+// TestNewInvalidSourceIP checks that an invalid source IP is a configuration error.
+func TestNewInvalidSourceIP(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Blackbox{
+		Targets: []config.BlackboxTarget{
+			{Name: "db", URL: "db.example.com:5432", Module: "tcp_connect", SourceIP: "not-an-ip"},
+		},
+		Modules: map[string]bbConf.Module{
+			"tcp_connect": {Prober: proberNameTCP},
+		},
+	}
+
+	_, err := New(nil, cfg, types.MetricFormatPrometheus)
+	if !errors.Is(err, errInvalidSourceIP) {
+		t.Errorf("New() error = %v, want %v", err, errInvalidSourceIP)
+	}
+}
+
+func TestWithSourceIP(t *testing.T) {
+	t.Parallel()
+
+	localAddrs := []net.Addr{
+		&net.IPNet{IP: net.ParseIP("127.0.0.1"), Mask: net.CIDRMask(8, 32)},
+		&net.IPNet{IP: net.ParseIP("192.168.1.2"), Mask: net.CIDRMask(24, 32)},
+	}
+
+	tests := []struct {
+		name     string
+		prober   string
+		sourceIP string
+		wantErr  error
+	}{
+		{name: "tcp", prober: proberNameTCP, sourceIP: "192.168.1.2"},
+		{name: "icmp", prober: proberNameICMP, sourceIP: "192.168.1.2"},
+		{name: "dns", prober: proberNameDNS, sourceIP: "127.0.0.1"},
+		{name: "http", prober: proberNameHTTP, sourceIP: "192.168.1.2"},
+		{name: "transaction", prober: proberNameTransaction, sourceIP: "192.168.1.2", wantErr: errSourceIPUnsupported},
+		{name: "not-local", prober: proberNameTCP, sourceIP: "10.1.2.3", wantErr: errSourceIPNotLocal},
+		{name: "invalid", prober: proberNameTCP, sourceIP: "not-an-ip", wantErr: errInvalidSourceIP},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			target, err := withSourceIP(configTarget{Module: bbConf.Module{Prober: tt.prober}}, tt.sourceIP, localAddrs)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("withSourceIP() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			module := target.Module
+
+			got := module.TCP.SourceIPAddress + module.ICMP.SourceIPAddress + module.DNS.SourceIPAddress
+			if target.sourceIP != nil {
+				got += target.sourceIP.String()
+			}
+
+			if got != tt.sourceIP {
+				t.Errorf("source IP = %q, want %q", got, tt.sourceIP)
+			}
+		})
+	}
+}
@@ -31,14 +31,16 @@ type gathererWithContext struct {
 	reg            *prometheus.Registry
 	l              sync.Mutex
 	target         configTarget
+	isPaused       func(id string) bool
 	currentContext context.Context //nolint:containedctx
 }
 
-func newGatherer(target configTarget) (*gathererWithContext, error) {
+func newGatherer(target configTarget, isPaused func(id string) bool) (*gathererWithContext, error) {
 	reg := prometheus.NewRegistry()
 	self := &gathererWithContext{
-		reg:    reg,
-		target: target,
+		reg:      reg,
+		target:   target,
+		isPaused: isPaused,
 	}
 
 	err := self.reg.Register(self)
@@ -51,6 +53,11 @@ func (g *gathererWithContext) Gather() ([]*dto.MetricFamily, error) {
 }
 
 func (g *gathererWithContext) GatherWithState(ctx context.Context, _ registry.GatherState) ([]*dto.MetricFamily, error) {
+	if g.target.ID != "" && g.isPaused != nil && g.isPaused(g.target.ID) {
+		// Don't probe a monitor in maintenance, only report it as unknown.
+		return pausedMetricFamilies(g.target), nil
+	}
+
 	g.l.Lock()
 	defer g.l.Unlock()
 
@@ -0,0 +1,162 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blackbox
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bleemeo/glouton/logger"
+	"github.com/bleemeo/glouton/types"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrUnknownMonitor is returned when pausing or resuming a monitor that isn't registered.
+var ErrUnknownMonitor = errors.New("unknown monitor")
+
+const pausedStatusDescription = "Monitor is paused for maintenance"
+
+// PauseMonitor stops probing the monitor with the given ID until ResumeMonitor is called.
+// While paused, the monitor is reported with an unknown status instead of being probed.
+func (m *RegisterManager) PauseMonitor(id string) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	if !m.hasMonitorLocked(id) {
+		return fmt.Errorf("%w: %s", ErrUnknownMonitor, id)
+	}
+
+	if _, ok := m.paused[id]; !ok {
+		m.paused[id] = time.Now()
+
+		logger.V(1).Printf("Monitor %s is paused", id)
+	}
+
+	return nil
+}
+
+// ResumeMonitor restarts probing a monitor previously paused by PauseMonitor.
+func (m *RegisterManager) ResumeMonitor(id string) error {
+	m.l.Lock()
+
+	if !m.hasMonitorLocked(id) {
+		m.l.Unlock()
+
+		return fmt.Errorf("%w: %s", ErrUnknownMonitor, id)
+	}
+
+	_, wasPaused := m.paused[id]
+	delete(m.paused, id)
+
+	// The registrations are read with the lock held, but the scrapes are
+	// scheduled once it is released.
+	var regIDs []int
+
+	if wasPaused {
+		for regID, reg := range m.registrations {
+			if reg.target.ID == id {
+				regIDs = append(regIDs, regID)
+			}
+		}
+	}
+
+	m.l.Unlock()
+
+	if !wasPaused {
+		return nil
+	}
+
+	logger.V(1).Printf("Monitor %s is resumed", id)
+
+	// Probe the monitor immediately instead of waiting for the next interval.
+	for _, regID := range regIDs {
+		m.registry.ScheduleScrape(regID, time.Now())
+	}
+
+	return nil
+}
+
+// PausedMonitors returns the IDs of paused monitors with the time they were paused.
+func (m *RegisterManager) PausedMonitors() map[string]time.Time {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	result := make(map[string]time.Time, len(m.paused))
+
+	for id, since := range m.paused {
+		result[id] = since
+	}
+
+	return result
+}
+
+func (m *RegisterManager) isPaused(id string) bool {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	_, ok := m.paused[id]
+
+	return ok
+}
+
+func (m *RegisterManager) hasMonitorLocked(id string) bool {
+	if id == "" {
+		return false
+	}
+
+	for _, t := range m.targets {
+		if t.Collector.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// forgetRemovedPausedLocked drops the pause state of monitors which no longer exist.
+func (m *RegisterManager) forgetRemovedPausedLocked() {
+	for id := range m.paused {
+		if !m.hasMonitorLocked(id) {
+			delete(m.paused, id)
+		}
+	}
+}
+
+// pausedMetricFamilies returns the probe_success metric of a paused monitor.
+// It carries an unknown status, so the monitor isn't seen as failing.
+func pausedMetricFamilies(target configTarget) []*dto.MetricFamily {
+	return []*dto.MetricFamily{
+		{
+			Name: proto.String("probe_success"),
+			Help: proto.String("Displays whether or not the probe was a success"),
+			Type: dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{
+				{
+					Label: []*dto.LabelPair{
+						{Name: proto.String("instance"), Value: proto.String(target.Name)},
+						{Name: proto.String(types.LabelMetaCurrentStatus), Value: proto.String(types.StatusUnknown.String())},
+						{Name: proto.String(types.LabelMetaCurrentDescription), Value: proto.String(pausedStatusDescription)},
+					},
+					Gauge: &dto.Gauge{Value: proto.Float64(0)},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blackbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/bleemeo/glouton/config"
+	"github.com/bleemeo/glouton/prometheus/registry"
+	"github.com/bleemeo/glouton/types"
+)
+
+func TestPauseMonitor(t *testing.T) {
+	reg, err := registry.New(registry.Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manager, err := New(reg, config.Blackbox{}, types.MetricFormatPrometheus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	monitor := types.Monitor{
+		ID:             "4a2b6c1e-0d6b-4a7c-8a8e-4a0f4ab1c2d3",
+		URL:            "http://localhost:1",
+		BleemeoAgentID: "e6b1a7a3-5c4e-4a6e-9b5b-2f4f1e9b0a1c",
+	}
+
+	if err := manager.UpdateDynamicTargets([]types.Monitor{monitor}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.PauseMonitor("not-a-monitor"); !errors.Is(err, ErrUnknownMonitor) {
+		t.Errorf("PauseMonitor() error = %v, want %v", err, ErrUnknownMonitor)
+	}
+
+	if err := manager.PauseMonitor(monitor.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := manager.PausedMonitors()[monitor.ID]; !ok {
+		t.Errorf("monitor %s isn't in paused monitors", monitor.ID)
+	}
+
+	var gatherer *gathererWithContext
+
+	for _, r := range manager.registrations {
+		if r.target.ID == monitor.ID {
+			gatherer, _ = newGatherer(r.target, manager.isPaused)
+		}
+	}
+
+	if gatherer == nil {
+		t.Fatal("monitor isn't registered")
+	}
+
+	mfs, err := gatherer.GatherWithState(context.Background(), registry.GatherState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mfs) != 1 || mfs[0].GetName() != "probe_success" {
+		t.Fatalf("got %d metric families, want only probe_success", len(mfs))
+	}
+
+	for _, lbl := range mfs[0].GetMetric()[0].GetLabel() {
+		if lbl.GetName() == types.LabelMetaCurrentStatus && lbl.GetValue() != types.StatusUnknown.String() {
+			t.Errorf("status = %s, want %s", lbl.GetValue(), types.StatusUnknown)
+		}
+	}
+
+	if err := manager.ResumeMonitor(monitor.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if manager.isPaused(monitor.ID) {
+		t.Errorf("monitor %s is still paused", monitor.ID)
+	}
+
+	if err := manager.PauseMonitor(monitor.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Removing the monitor must drop its pause state.
+	if err := manager.UpdateDynamicTargets(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(manager.PausedMonitors()) != 0 {
+		t.Errorf("PausedMonitors() = %v, want empty", manager.PausedMonitors())
+	}
+}
+
+// TestResumeMonitorConcurrentUpdate checks that the registrations can be updated
+// while a monitor is resumed. It's meant to be run with the race detector.
+func TestResumeMonitorConcurrentUpdate(t *testing.T) {
+	reg, err := registry.New(registry.Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manager, err := New(reg, config.Blackbox{}, types.MetricFormatPrometheus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	monitor := types.Monitor{
+		ID:             "4a2b6c1e-0d6b-4a7c-8a8e-4a0f4ab1c2d3",
+		URL:            "http://localhost:1",
+		BleemeoAgentID: "e6b1a7a3-5c4e-4a6e-9b5b-2f4f1e9b0a1c",
+	}
+
+	if err := manager.UpdateDynamicTargets([]types.Monitor{monitor}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for range 50 {
+			if err := manager.PauseMonitor(monitor.ID); err != nil && !errors.Is(err, ErrUnknownMonitor) {
+				t.Error(err)
+			}
+
+			if err := manager.ResumeMonitor(monitor.ID); err != nil && !errors.Is(err, ErrUnknownMonitor) {
+				t.Error(err)
+			}
+		}
+	}()
+
+	for i := range 50 {
+		monitors := []types.Monitor{monitor}
+		if i%2 == 0 {
+			monitors = nil
+		}
+
+		if err := manager.UpdateDynamicTargets(monitors); err != nil {
+			t.Error(err)
+		}
+	}
+
+	wg.Wait()
+}
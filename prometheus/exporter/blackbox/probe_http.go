@@ -0,0 +1,198 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blackbox
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	bbConf "github.com/prometheus/blackbox_exporter/config"
+	"github.com/prometheus/blackbox_exporter/prober"
+	"github.com/prometheus/client_golang/prometheus"
+	promConfig "github.com/prometheus/common/config"
+)
+
+// ProbeHTTP probes the target with the blackbox_exporter HTTP prober.
+// The blackbox_exporter prober doesn't allow to choose the local address, so when
+// a source IP is in the context, the probe is done by probeHTTPFromSourceIP.
+func ProbeHTTP(ctx context.Context, target string, module bbConf.Module, registry *prometheus.Registry, logger log.Logger) bool {
+	sourceIP, _ := ctx.Value(contextKeySourceIP).(net.IP)
+	if sourceIP == nil {
+		return prober.ProbeHTTP(ctx, target, module, registry, logger)
+	}
+
+	return probeHTTPFromSourceIP(ctx, target, module, sourceIP, registry, logger)
+}
+
+// probeHTTPFromSourceIP does a single HTTP request with the connections bound to the source IP.
+// It supports the status code, body regexp and SSL checks of the HTTP module.
+func probeHTTPFromSourceIP(
+	ctx context.Context,
+	target string,
+	module bbConf.Module,
+	sourceIP net.IP,
+	registry *prometheus.Registry,
+	logger log.Logger,
+) bool {
+	statusCodeGauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_http_status_code",
+		Help: "Response HTTP status code",
+	})
+	contentLengthGauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_http_content_length",
+		Help: "Length of http content response",
+	})
+	isSSLGauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_http_ssl",
+		Help: "Indicates if SSL was used for the final redirect",
+	})
+
+	registry.MustRegister(statusCodeGauge, contentLengthGauge, isSSLGauge)
+
+	tlsConfig, err := promConfig.NewTLSConfig(&module.HTTP.HTTPClientConfig.TLSConfig)
+	if err != nil {
+		_ = level.Error(logger).Log("msg", "Error creating TLS configuration", "err", err)
+
+		return false
+	}
+
+	transport := &http.Transport{
+		Proxy:             http.ProxyFromEnvironment,
+		DialContext:       sourceIPDialContext(sourceIP),
+		TLSClientConfig:   tlsConfig,
+		DisableKeepAlives: true,
+	}
+	defer transport.CloseIdleConnections()
+
+	client := &http.Client{Transport: transport}
+
+	if !module.HTTP.HTTPClientConfig.FollowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	method := module.HTTP.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	if module.HTTP.Body != "" {
+		body = strings.NewReader(module.HTTP.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		_ = level.Error(logger).Log("msg", "Error creating request", "err", err)
+
+		return false
+	}
+
+	for header, value := range module.HTTP.Headers {
+		if strings.EqualFold(header, "Host") {
+			req.Host = value
+
+			continue
+		}
+
+		req.Header.Set(header, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		_ = level.Error(logger).Log("msg", "Error for HTTP request", "err", err)
+
+		return false
+	}
+
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+
+	statusCodeGauge.Set(float64(resp.StatusCode))
+	contentLengthGauge.Set(float64(len(content)))
+
+	if resp.TLS != nil {
+		isSSLGauge.Set(1)
+	}
+
+	if err != nil {
+		_ = level.Info(logger).Log("msg", "Failed to read HTTP response body", "err", err)
+
+		return false
+	}
+
+	return checkHTTPResponse(module.HTTP, resp, content, logger)
+}
+
+// checkHTTPResponse returns whether the response matches the expectations of the module.
+func checkHTTPResponse(httpProbe bbConf.HTTPProbe, resp *http.Response, content []byte, logger log.Logger) bool {
+	validStatus := resp.StatusCode >= 200 && resp.StatusCode < 300
+	if len(httpProbe.ValidStatusCodes) > 0 {
+		validStatus = slices.Contains(httpProbe.ValidStatusCodes, resp.StatusCode)
+	}
+
+	if !validStatus {
+		_ = level.Info(logger).Log("msg", "Invalid HTTP response status code", "status_code", resp.StatusCode)
+
+		return false
+	}
+
+	for _, re := range httpProbe.FailIfBodyMatchesRegexp {
+		if re.Match(content) {
+			_ = level.Info(logger).Log("msg", "Body matched regular expression", "regexp", re.String())
+
+			return false
+		}
+	}
+
+	for _, re := range httpProbe.FailIfBodyNotMatchesRegexp {
+		if !re.Match(content) {
+			_ = level.Info(logger).Log("msg", "Body did not match regular expression", "regexp", re.String())
+
+			return false
+		}
+	}
+
+	if httpProbe.FailIfSSL && resp.TLS != nil {
+		_ = level.Info(logger).Log("msg", "Final request was over SSL")
+
+		return false
+	}
+
+	if httpProbe.FailIfNotSSL && resp.TLS == nil {
+		_ = level.Info(logger).Log("msg", "Final request was not over SSL")
+
+		return false
+	}
+
+	return true
+}
+
+// sourceIPDialContext returns a DialContext function with the connections bound to the source IP.
+func sourceIPDialContext(sourceIP net.IP) func(ctx context.Context, network string, address string) (net.Conn, error) {
+	dialer := &net.Dialer{LocalAddr: &net.TCPAddr{IP: sourceIP}}
+
+	return dialer.DialContext
+}
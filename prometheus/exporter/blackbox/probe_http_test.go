@@ -0,0 +1,122 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blackbox
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	bbConf "github.com/prometheus/blackbox_exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestProbeHTTPFromSourceIP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		httpProbe   bbConf.HTTPProbe
+		wantSuccess bool
+	}{
+		{
+			name:        "success",
+			httpProbe:   bbConf.HTTPProbe{},
+			wantSuccess: true,
+		},
+		{
+			name:        "unexpected-status",
+			httpProbe:   bbConf.HTTPProbe{ValidStatusCodes: []int{http.StatusNoContent}},
+			wantSuccess: false,
+		},
+		{
+			name:        "forbidden-content",
+			httpProbe:   bbConf.HTTPProbe{FailIfBodyMatchesRegexp: []bbConf.Regexp{bbConf.MustNewRegexp("Welcome")}},
+			wantSuccess: false,
+		},
+		{
+			name:        "missing-content",
+			httpProbe:   bbConf.HTTPProbe{FailIfBodyNotMatchesRegexp: []bbConf.Regexp{bbConf.MustNewRegexp("Goodbye")}},
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			remoteAddrs := make(chan string, 1)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				remoteAddrs <- r.RemoteAddr
+
+				if r.Header.Get("User-Agent") != "glouton-test" {
+					w.WriteHeader(http.StatusBadRequest)
+
+					return
+				}
+
+				_, _ = w.Write([]byte("Welcome"))
+			}))
+			defer srv.Close()
+
+			module := bbConf.Module{Prober: proberNameHTTP, HTTP: tt.httpProbe}
+			module.HTTP.Headers = map[string]string{"User-Agent": "glouton-test"}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			ctx = context.WithValue(ctx, contextKeySourceIP, net.ParseIP("127.0.0.1"))
+			registry := prometheus.NewRegistry()
+
+			success := ProbeHTTP(ctx, srv.URL, module, registry, log.NewNopLogger())
+			if success != tt.wantSuccess {
+				t.Errorf("success = %v, want %v", success, tt.wantSuccess)
+			}
+
+			host, _, err := net.SplitHostPort(<-remoteAddrs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if host != "127.0.0.1" {
+				t.Errorf("request came from %s, want 127.0.0.1", host)
+			}
+
+			mfs, err := registry.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var statusCode float64
+
+			for _, mf := range mfs {
+				if mf.GetName() == "probe_http_status_code" {
+					statusCode = mf.GetMetric()[0].GetGauge().GetValue()
+				}
+			}
+
+			if statusCode != http.StatusOK {
+				t.Errorf("probe_http_status_code = %v, want %d", statusCode, http.StatusOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blackbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/blackbox_exporter/config"
+	"github.com/prometheus/blackbox_exporter/prober"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// icmpPacketCount is the number of echo requests sent on each ICMP probe.
+const icmpPacketCount = 3
+
+var errNoIPForProtocol = errors.New("no IP address for the protocol")
+
+// lookupIPFunc resolves a host, it matches net.Resolver.LookupIP.
+type lookupIPFunc func(ctx context.Context, network string, host string) ([]net.IP, error)
+
+// ProbeICMP sends icmpPacketCount echo requests to the target and reports the packet loss.
+// The target is resolved once for all the echo requests. The other metrics, like
+// probe_icmp_duration_seconds, are the ones of the last successful echo request,
+// or of the last one when all of them failed.
+//
+// The blackbox prober uses unprivileged ICMP sockets on Linux when the agent doesn't
+// have the CAP_NET_RAW capability, this requires the net.ipv4.ping_group_range sysctl
+// to include the agent group.
+func ProbeICMP(ctx context.Context, target string, module config.Module, registry *prometheus.Registry, logger log.Logger) bool {
+	return probeICMPWithCount(ctx, target, module, registry, logger, icmpPacketCount, prober.ProbeICMP, net.DefaultResolver.LookupIP)
+}
+
+func probeICMPWithCount(
+	ctx context.Context,
+	target string,
+	module config.Module,
+	registry *prometheus.Registry,
+	logger log.Logger,
+	count int,
+	probeFn prober.ProbeFn,
+	lookupIP lookupIPFunc,
+) bool {
+	probePacketLoss := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_icmp_packet_loss_ratio",
+		Help: "Ratio of echo requests without reply",
+	})
+
+	registry.MustRegister(probePacketLoss)
+	probePacketLoss.Set(1)
+
+	resolveStart := time.Now()
+
+	ip, err := resolveICMPTarget(ctx, target, module, lookupIP)
+	if err != nil {
+		_ = level.Error(logger).Log("msg", "Error resolving address", "err", err)
+
+		// Let the prober report the resolution error with its usual metrics.
+		return probeFn(ctx, target, module, registry, logger)
+	}
+
+	resolveDuration := time.Since(resolveStart)
+
+	var (
+		received     int
+		keptRegistry *prometheus.Registry
+		keptSuccess  bool
+	)
+
+	for i := range count {
+		// Each attempt uses its own registry, otherwise the metrics
+		// would be registered multiple times.
+		attemptRegistry := prometheus.NewRegistry()
+
+		attemptCtx, cancel := icmpAttemptContext(ctx, count-i)
+		success := probeFn(attemptCtx, ip, module, attemptRegistry, logger)
+
+		cancel()
+
+		if success {
+			received++
+		}
+
+		// Keep the last successful attempt, or the last attempt if none succeeded.
+		if success || !keptSuccess {
+			keptRegistry = attemptRegistry
+			keptSuccess = success
+		}
+	}
+
+	probePacketLoss.Set(float64(count-received) / float64(count))
+
+	if keptRegistry != nil {
+		mfs, err := keptRegistry.Gather()
+		if err != nil {
+			_ = level.Warn(logger).Log("msg", "Error gathering the metrics of the echo request", "err", err)
+		}
+
+		// The prober only resolved an IP address, report the real resolution time.
+		for _, mf := range mfs {
+			if mf.GetName() == "probe_dns_lookup_time_seconds" {
+				for _, m := range mf.GetMetric() {
+					m.Gauge = &dto.Gauge{Value: proto.Float64(resolveDuration.Seconds())}
+				}
+			}
+		}
+
+		registry.MustRegister(familiesCollector{mfs: mfs})
+	}
+
+	return received > 0
+}
+
+// resolveICMPTarget returns the IP address to probe, following the IP protocol
+// preference of the module like the blackbox prober does.
+func resolveICMPTarget(ctx context.Context, target string, module config.Module, lookupIP lookupIPFunc) (string, error) {
+	if net.ParseIP(target) != nil {
+		return target, nil
+	}
+
+	ipProtocol := module.ICMP.IPProtocol
+	if ipProtocol == "" {
+		ipProtocol = "ip6"
+	}
+
+	ips, err := lookupIP(ctx, "ip", target)
+	if err != nil {
+		return "", err
+	}
+
+	for _, ip := range ips {
+		if (ip.To4() != nil) == (ipProtocol == "ip4") {
+			return ip.String(), nil
+		}
+	}
+
+	if module.ICMP.IPProtocolFallback && len(ips) > 0 {
+		return ips[0].String(), nil
+	}
+
+	return "", fmt.Errorf("%w %s: %s", errNoIPForProtocol, ipProtocol, target)
+}
+
+// familiesCollector is an unchecked collector which returns fixed metric families.
+type familiesCollector struct {
+	mfs []*dto.MetricFamily
+}
+
+func (c familiesCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (c familiesCollector) Collect(ch chan<- prometheus.Metric) {
+	writeMFsToChan(c.mfs, ch)
+}
+
+// icmpAttemptContext returns a context with a deadline that leaves enough time
+// for the remaining attempts.
+func icmpAttemptContext(ctx context.Context, remainingAttempts int) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, time.Until(deadline)/time.Duration(remainingAttempts))
+}
@@ -0,0 +1,167 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blackbox
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/blackbox_exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var errLookupFailed = errors.New("lookup failed")
+
+func TestProbeICMPPacketLoss(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		replies     []bool
+		wantSuccess bool
+		wantLoss    float64
+		// wantAttempt is the attempt whose metrics are kept.
+		wantAttempt float64
+	}{
+		{name: "all-replies", replies: []bool{true, true, true, true}, wantSuccess: true, wantLoss: 0, wantAttempt: 3},
+		{name: "partial-loss", replies: []bool{true, false, true, false}, wantSuccess: true, wantLoss: 0.5, wantAttempt: 2},
+		{name: "no-reply", replies: []bool{false, false, false, false}, wantSuccess: false, wantLoss: 1, wantAttempt: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			registry := prometheus.NewRegistry()
+			call := 0
+			lookups := 0
+
+			lookupIP := func(_ context.Context, _ string, host string) ([]net.IP, error) {
+				lookups++
+
+				if host != "example.com" {
+					return nil, errLookupFailed
+				}
+
+				return []net.IP{net.ParseIP("2001:db8::1"), net.ParseIP("192.0.2.1")}, nil
+			}
+
+			probeFn := func(_ context.Context, target string, _ config.Module, attemptRegistry *prometheus.Registry, _ log.Logger) bool {
+				if target != "192.0.2.1" {
+					t.Errorf("target = %s, want the resolved address 192.0.2.1", target)
+				}
+
+				attempt := prometheus.NewGauge(prometheus.GaugeOpts{Name: "probe_icmp_attempt"})
+				attempt.Set(float64(call))
+				attemptRegistry.MustRegister(attempt)
+
+				success := tt.replies[call]
+				call++
+
+				return success
+			}
+
+			module := config.Module{ICMP: config.ICMPProbe{IPProtocol: "ip4"}}
+
+			success := probeICMPWithCount(ctx, "example.com", module, registry, log.NewNopLogger(), len(tt.replies), probeFn, lookupIP)
+			if success != tt.wantSuccess {
+				t.Errorf("success = %v, want %v", success, tt.wantSuccess)
+			}
+
+			if lookups != 1 {
+				t.Errorf("target resolved %d times, want 1", lookups)
+			}
+
+			mfs, err := registry.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make(map[string]float64, len(mfs))
+
+			for _, mf := range mfs {
+				for _, m := range mf.GetMetric() {
+					got[mf.GetName()] = m.GetGauge().GetValue()
+				}
+			}
+
+			if len(got) != 2 {
+				t.Fatalf("unexpected metrics: %v", got)
+			}
+
+			if got["probe_icmp_packet_loss_ratio"] != tt.wantLoss {
+				t.Errorf("packet loss = %v, want %v", got["probe_icmp_packet_loss_ratio"], tt.wantLoss)
+			}
+
+			if got["probe_icmp_attempt"] != tt.wantAttempt {
+				t.Errorf("kept metrics of attempt %v, want %v", got["probe_icmp_attempt"], tt.wantAttempt)
+			}
+		})
+	}
+}
+
+func TestResolveICMPTarget(t *testing.T) {
+	t.Parallel()
+
+	lookupIP := func(_ context.Context, _ string, host string) ([]net.IP, error) {
+		switch host {
+		case "dual-stack":
+			return []net.IP{net.ParseIP("2001:db8::1"), net.ParseIP("192.0.2.1")}, nil
+		case "ipv6-only":
+			return []net.IP{net.ParseIP("2001:db8::1")}, nil
+		default:
+			return nil, errLookupFailed
+		}
+	}
+
+	tests := []struct {
+		name     string
+		target   string
+		module   config.ICMPProbe
+		want     string
+		wantFail bool
+	}{
+		{name: "ip-literal", target: "192.0.2.10", want: "192.0.2.10"},
+		{name: "ip4", target: "dual-stack", module: config.ICMPProbe{IPProtocol: "ip4"}, want: "192.0.2.1"},
+		{name: "default-ip6", target: "dual-stack", want: "2001:db8::1"},
+		{name: "fallback", target: "ipv6-only", module: config.ICMPProbe{IPProtocol: "ip4", IPProtocolFallback: true}, want: "2001:db8::1"},
+		{name: "no-fallback", target: "ipv6-only", module: config.ICMPProbe{IPProtocol: "ip4"}, wantFail: true},
+		{name: "lookup-error", target: "unknown", wantFail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := resolveICMPTarget(context.Background(), tt.target, config.Module{ICMP: tt.module}, lookupIP)
+			if (err != nil) != tt.wantFail {
+				t.Fatalf("resolveICMPTarget() error = %v, want failure %v", err, tt.wantFail)
+			}
+
+			if got != tt.want {
+				t.Errorf("resolveICMPTarget() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
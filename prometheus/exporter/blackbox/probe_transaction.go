@@ -0,0 +1,220 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blackbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bleemeo/glouton/config"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	bbConf "github.com/prometheus/blackbox_exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxBodySize is the maximum size of a response body read by a transaction step
+// or by an HTTP probe with a source IP.
+const maxBodySize = 10 << 20
+
+var (
+	errNoTransaction      = errors.New("no transaction in the context")
+	errUnexpectedStatus   = errors.New("unexpected status code")
+	errContentNotMatching = errors.New("the response doesn't match the expected content")
+	errExtractNotMatching = errors.New("the response doesn't match the extract expression")
+
+	variableRegexp = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+)
+
+// ProbeTransaction executes the steps of the transaction, which is passed in the context.
+// The cookies are kept between steps and the variables extracted by a step are available
+// in the following steps. The probe stops at the first failing step.
+func ProbeTransaction(ctx context.Context, _ string, module bbConf.Module, registry *prometheus.Registry, logger log.Logger) bool {
+	transaction, ok := ctx.Value(contextKeyTransaction).(*config.BlackboxTransaction)
+	if !ok || transaction == nil {
+		_ = level.Error(logger).Log("msg", errNoTransaction)
+
+		return false
+	}
+
+	stepDuration := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "probe_transaction_step_duration_seconds",
+			Help: "Duration of the transaction step",
+		},
+		[]string{"step"},
+	)
+	stepSuccess := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "probe_transaction_step_success",
+			Help: "Displays whether or not the transaction step was a success",
+		},
+		[]string{"step"},
+	)
+
+	registry.MustRegister(stepDuration)
+	registry.MustRegister(stepSuccess)
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		_ = level.Error(logger).Log("msg", "Failed to create the cookie jar", "err", err)
+
+		return false
+	}
+
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	defer transport.CloseIdleConnections()
+
+	variables := make(map[string]string)
+
+	for i, step := range transaction.Steps {
+		name := step.Name
+		if name == "" {
+			name = strconv.Itoa(i + 1)
+		}
+
+		start := time.Now()
+		err := runTransactionStep(ctx, transport, jar, module.HTTP.Headers, step, variables)
+
+		stepDuration.WithLabelValues(name).Set(time.Since(start).Seconds())
+
+		if err != nil {
+			_ = level.Info(logger).Log("msg", "Transaction step failed", "step", name, "err", err)
+
+			stepSuccess.WithLabelValues(name).Set(0)
+
+			return false
+		}
+
+		stepSuccess.WithLabelValues(name).Set(1)
+	}
+
+	return true
+}
+
+func runTransactionStep(
+	ctx context.Context,
+	transport http.RoundTripper,
+	jar http.CookieJar,
+	defaultHeaders map[string]string,
+	step config.BlackboxTransactionStep,
+	variables map[string]string,
+) error {
+	method := step.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	if step.Body != "" {
+		body = strings.NewReader(expandVariables(step.Body, variables))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(method), expandVariables(step.URL, variables), body)
+	if err != nil {
+		return err
+	}
+
+	for header, value := range defaultHeaders {
+		req.Header.Set(header, value)
+	}
+
+	for header, value := range step.Headers {
+		req.Header.Set(header, expandVariables(value, variables))
+	}
+
+	client := &http.Client{Transport: transport, Jar: jar}
+
+	if step.FollowRedirects != nil && !*step.FollowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case step.ExpectedStatus != 0 && resp.StatusCode != step.ExpectedStatus:
+		return fmt.Errorf("%w: got %d, want %d", errUnexpectedStatus, resp.StatusCode, step.ExpectedStatus)
+	case step.ExpectedStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode >= 300):
+		return fmt.Errorf("%w: got %d, want 2xx", errUnexpectedStatus, resp.StatusCode)
+	}
+
+	if step.ExpectedContent != "" {
+		re, err := regexp.Compile(step.ExpectedContent)
+		if err != nil {
+			return err
+		}
+
+		if !re.Match(content) {
+			return errContentNotMatching
+		}
+	}
+
+	for variable, expression := range step.Extract {
+		re, err := regexp.Compile(expression)
+		if err != nil {
+			return err
+		}
+
+		match := re.FindSubmatch(content)
+
+		switch {
+		case match == nil:
+			return fmt.Errorf("%w for %s", errExtractNotMatching, variable)
+		case len(match) > 1:
+			variables[variable] = string(match[1])
+		default:
+			variables[variable] = string(match[0])
+		}
+	}
+
+	return nil
+}
+
+// expandVariables replaces the "{{name}}" references by the value of the variables.
+// Unknown variables are kept unchanged.
+func expandVariables(value string, variables map[string]string) string {
+	return variableRegexp.ReplaceAllStringFunc(value, func(ref string) string {
+		name := variableRegexp.FindStringSubmatch(ref)[1]
+
+		if v, ok := variables[name]; ok {
+			return v
+		}
+
+		return ref
+	})
+}
@@ -0,0 +1,174 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blackbox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bleemeo/glouton/config"
+
+	"github.com/go-kit/log"
+	bbConf "github.com/prometheus/blackbox_exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newShopServer returns a server with a login form protected by a CSRF token and a session cookie.
+func newShopServer() *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`<form><input type="hidden" name="csrf" value="token123"></form>`))
+
+			return
+		}
+
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("csrf") != "token123" {
+			w.WriteHeader(http.StatusForbidden)
+
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "logged"})
+		http.Redirect(w, r, "/account", http.StatusFound)
+	})
+
+	mux.HandleFunc("/account", func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "logged" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		_, _ = w.Write([]byte("Welcome back"))
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestProbeTransaction(t *testing.T) {
+	t.Parallel()
+
+	srv := newShopServer()
+	t.Cleanup(srv.Close)
+
+	noRedirect := false
+
+	tests := []struct {
+		name        string
+		csrf        string
+		wantSuccess bool
+		wantSteps   map[string]float64
+	}{
+		{
+			name:        "success",
+			csrf:        "{{csrf}}",
+			wantSuccess: true,
+			wantSteps:   map[string]float64{"login page": 1, "login": 1, "account": 1},
+		},
+		{
+			name:        "wrong-token",
+			csrf:        "invalid",
+			wantSuccess: false,
+			wantSteps:   map[string]float64{"login page": 1, "login": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			transaction := &config.BlackboxTransaction{
+				Name: "shop",
+				Steps: []config.BlackboxTransactionStep{
+					{
+						Name:    "login page",
+						URL:     srv.URL + "/login",
+						Extract: map[string]string{"csrf": `name="csrf" value="([^"]+)"`},
+					},
+					{
+						Name:            "login",
+						Method:          "post",
+						URL:             srv.URL + "/login",
+						Headers:         map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+						Body:            "csrf=" + tt.csrf,
+						FollowRedirects: &noRedirect,
+						ExpectedStatus:  http.StatusFound,
+					},
+					{
+						Name:            "account",
+						URL:             srv.URL + "/account",
+						ExpectedContent: "Welcome",
+					},
+				},
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			ctx = context.WithValue(ctx, contextKeyTransaction, transaction)
+			registry := prometheus.NewRegistry()
+
+			success := ProbeTransaction(ctx, srv.URL, bbConf.Module{}, registry, log.NewNopLogger())
+			if success != tt.wantSuccess {
+				t.Errorf("success = %v, want %v", success, tt.wantSuccess)
+			}
+
+			mfs, err := registry.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotSteps := make(map[string]float64)
+
+			for _, mf := range mfs {
+				if mf.GetName() != "probe_transaction_step_success" {
+					continue
+				}
+
+				for _, m := range mf.GetMetric() {
+					gotSteps[m.GetLabel()[0].GetValue()] = m.GetGauge().GetValue()
+				}
+			}
+
+			if len(gotSteps) != len(tt.wantSteps) {
+				t.Fatalf("steps = %v, want %v", gotSteps, tt.wantSteps)
+			}
+
+			for step, want := range tt.wantSteps {
+				if gotSteps[step] != want {
+					t.Errorf("step %s success = %v, want %v", step, gotSteps[step], want)
+				}
+			}
+		})
+	}
+}
+
+func TestExpandVariables(t *testing.T) {
+	t.Parallel()
+
+	got := expandVariables("csrf={{csrf}}&user={{ user }}&other={{unknown}}", map[string]string{"csrf": "abc", "user": "bob"})
+	want := "csrf=abc&user=bob&other={{unknown}}"
+
+	if got != want {
+		t.Errorf("expandVariables() = %q, want %q", got, want)
+	}
+}
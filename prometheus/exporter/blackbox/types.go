@@ -18,9 +18,11 @@ package blackbox
 
 import (
 	"crypto/x509"
+	"net"
 	"sync"
 	"time"
 
+	"github.com/bleemeo/glouton/config"
 	"github.com/bleemeo/glouton/prometheus/registry"
 	"github.com/bleemeo/glouton/types"
 
@@ -30,6 +32,7 @@ import (
 
 // configTarget is the information we will supply to the probe() function.
 type configTarget struct {
+	ID               string
 	Name             string
 	URL              string
 	Module           bbConf.Module
@@ -39,6 +42,11 @@ type configTarget struct {
 	RefreshRate      time.Duration
 	testInjectCARoot *x509.Certificate
 	nowFunc          func() time.Time
+	// transaction contains the steps executed by the transaction prober.
+	transaction *config.BlackboxTransaction
+	// sourceIP is the local address of the HTTP probe connections. The other
+	// probers have it in their module.
+	sourceIP net.IP
 }
 
 // We define labels to apply on a specific collector at registration, as those labels cannot be exposed
@@ -60,8 +68,11 @@ type RegisterManager struct {
 	targets       []collectorWithLabels
 	scraperName   string
 	registrations map[int]gathererWithConfigTarget
+	paused        map[string]time.Time
 	registry      *registry.Registry
 	metricFormat  types.MetricFormat
 	userAgent     string
 	l             sync.Mutex
+	// updateL serializes the updates of the registrations.
+	updateL sync.Mutex
 }
@@ -0,0 +1,174 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cgroup gathers the CPU and memory usage of systemd slices from the cgroup v2 hierarchy.
+package cgroup
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bleemeo/glouton/prometheus/model"
+	"github.com/bleemeo/glouton/types"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var (
+	ErrCgroupV2NotAvailable = errors.New("cgroup v2 hierarchy isn't available")
+	errUsageNotFound        = errors.New("usage_usec not found in cpu.stat")
+)
+
+// Gatherer gathers the CPU and memory usage of a list of cgroups.
+type Gatherer struct {
+	cgroupRoot string
+	slices     []string
+
+	l sync.Mutex
+	// pastCPU contains the CPU time of each slice at the previous gather,
+	// it's used to compute the CPU usage between two gathers.
+	pastCPU map[string]cpuSample
+}
+
+type cpuSample struct {
+	time    time.Time
+	seconds float64
+}
+
+// New returns a gatherer for the given slices. The slices are paths relative to the
+// cgroup v2 root, like "system.slice" or "user.slice/user-1000.slice".
+func New(hostRoot string, slices []string) (*Gatherer, error) {
+	cgroupRoot := filepath.Join(hostRoot, "sys/fs/cgroup")
+
+	// cgroup.controllers only exists at the root of a cgroup v2 hierarchy.
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCgroupV2NotAvailable, err)
+	}
+
+	return &Gatherer{
+		cgroupRoot: cgroupRoot,
+		slices:     slices,
+		pastCPU:    make(map[string]cpuSample),
+	}, nil
+}
+
+// Gather implements prometheus.Gatherer.
+func (g *Gatherer) Gather() ([]*dto.MetricFamily, error) {
+	now := time.Now()
+	points := make([]types.MetricPoint, 0, 2*len(g.slices))
+
+	var errs prometheus.MultiError
+
+	g.l.Lock()
+	defer g.l.Unlock()
+
+	for _, slice := range g.slices {
+		dir := filepath.Join(g.cgroupRoot, filepath.Clean("/"+slice))
+
+		cpuSeconds, err := readCPUSeconds(filepath.Join(dir, "cpu.stat"))
+		if err != nil {
+			errs.Append(fmt.Errorf("cgroup %s: %w", slice, err))
+		} else if cpuRate, ok := g.cpuRate(slice, now, cpuSeconds); ok {
+			points = append(points, types.MetricPoint{
+				Point: types.Point{Time: now, Value: cpuRate},
+				Labels: map[string]string{
+					types.LabelName: "cgroup_cpu_seconds",
+					"cgroup":        slice,
+				},
+			})
+		}
+
+		memoryBytes, err := readUint(filepath.Join(dir, "memory.current"))
+		if err != nil {
+			errs.Append(fmt.Errorf("cgroup %s: %w", slice, err))
+		} else {
+			points = append(points, types.MetricPoint{
+				Point: types.Point{Time: now, Value: float64(memoryBytes)},
+				Labels: map[string]string{
+					types.LabelName: "cgroup_memory_bytes",
+					"cgroup":        slice,
+				},
+			})
+		}
+	}
+
+	return model.MetricPointsToFamilies(points), errs.MaybeUnwrap()
+}
+
+// cpuRate returns the CPU seconds used per second by the slice since the previous
+// gather. It returns false on the first gather of the slice.
+// The lock must be held.
+func (g *Gatherer) cpuRate(slice string, now time.Time, cpuSeconds float64) (float64, bool) {
+	past, ok := g.pastCPU[slice]
+	g.pastCPU[slice] = cpuSample{time: now, seconds: cpuSeconds}
+
+	deltaT := now.Sub(past.time).Seconds()
+	if !ok || deltaT <= 0 {
+		return 0, false
+	}
+
+	delta := cpuSeconds - past.seconds
+	if delta < 0 {
+		// Assume the cgroup was recreated and its counter reset.
+		delta = cpuSeconds
+	}
+
+	return delta / deltaT, true
+}
+
+// readCPUSeconds returns the CPU time used by the cgroup from its cpu.stat file.
+func readCPUSeconds(path string) (float64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[0] != "usage_usec" {
+			continue
+		}
+
+		usec, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+
+		return float64(usec) / 1e6, nil
+	}
+
+	return 0, errUsageNotFound
+}
+
+func readUint(path string) (uint64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+}
@@ -0,0 +1,102 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cgroup
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGather(t *testing.T) {
+	t.Parallel()
+
+	g, err := New("testdata", []string{"system.slice", "user.slice", "missing.slice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mfs, err := g.Gather()
+	if err == nil {
+		t.Error("Gather() didn't return an error for the missing slice")
+	}
+
+	// The CPU usage is only known from the second gather.
+	want := map[string]float64{
+		"cgroup_memory_bytes/system.slice": 1073741824,
+		"cgroup_memory_bytes/user.slice":   2048,
+	}
+
+	got := make(map[string]float64)
+
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			for _, lbl := range m.GetLabel() {
+				if lbl.GetName() == "cgroup" {
+					got[mf.GetName()+"/"+lbl.GetValue()] = m.GetUntyped().GetValue()
+				}
+			}
+		}
+	}
+
+	if len(got) != len(want) {
+		t.Errorf("got %d points, want %d: %v", len(got), len(want), got)
+	}
+
+	for key, wantValue := range want {
+		if got[key] != wantValue {
+			t.Errorf("%s = %f, want %f", key, got[key], wantValue)
+		}
+	}
+}
+
+func TestCPURate(t *testing.T) {
+	t.Parallel()
+
+	g := &Gatherer{pastCPU: make(map[string]cpuSample)}
+	t0 := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		time       time.Time
+		cpuSeconds float64
+		want       float64
+		wantOk     bool
+	}{
+		{time: t0, cpuSeconds: 100},
+		{time: t0.Add(10 * time.Second), cpuSeconds: 105, want: 0.5, wantOk: true},
+		{time: t0.Add(20 * time.Second), cpuSeconds: 125, want: 2, wantOk: true},
+		// The counter was reset.
+		{time: t0.Add(30 * time.Second), cpuSeconds: 1, want: 0.1, wantOk: true},
+		{time: t0.Add(30 * time.Second), cpuSeconds: 2},
+	}
+
+	for i, step := range steps {
+		got, ok := g.cpuRate("system.slice", step.time, step.cpuSeconds)
+		if ok != step.wantOk || got != step.want {
+			t.Errorf("step %d: cpuRate() = %f, %v, want %f, %v", i, got, ok, step.want, step.wantOk)
+		}
+	}
+}
+
+func TestNewWithoutCgroupV2(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(t.TempDir(), []string{"system.slice"})
+	if !errors.Is(err, ErrCgroupV2NotAvailable) {
+		t.Errorf("New() error = %v, want %v", err, ErrCgroupV2NotAvailable)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bleemeo/glouton/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+const exporterReloadTimeout = 10 * time.Second
+
+// snmpAuths is the auths section of the snmp_exporter configuration.
+// The snmp_exporter only reads the credentials from its configuration,
+// the scrape request only contains the name of the auth to use.
+type snmpAuths struct {
+	Auths map[string]snmpAuth `yaml:"auths"`
+}
+
+type snmpAuth struct {
+	Version       int    `yaml:"version"`
+	Username      string `yaml:"username"`
+	SecurityLevel string `yaml:"security_level"`
+	Password      string `yaml:"password,omitempty"`
+	AuthProtocol  string `yaml:"auth_protocol,omitempty"`
+	PrivProtocol  string `yaml:"priv_protocol,omitempty"`
+	PrivPassword  string `yaml:"priv_password,omitempty"`
+	ContextName   string `yaml:"context_name,omitempty"`
+}
+
+// isSNMPv3 returns whether the target uses SNMPv3.
+func isSNMPv3(t config.SNMPTarget) bool {
+	return t.SecurityName != ""
+}
+
+// authName returns the name of the auth of a SNMPv3 target in the snmp_exporter configuration.
+func authName(t config.SNMPTarget) string {
+	return "glouton_" + t.Target
+}
+
+func securityLevel(t config.SNMPTarget) string {
+	switch {
+	case t.PrivProtocol != "":
+		return "authPriv"
+	case t.AuthProtocol != "":
+		return "authNoPriv"
+	default:
+		return "noAuthNoPriv"
+	}
+}
+
+// authsConfig returns the snmp_exporter configuration with the credentials of the SNMPv3 targets.
+func authsConfig(targets []*Target) ([]byte, error) {
+	auths := snmpAuths{Auths: make(map[string]snmpAuth)}
+
+	for _, t := range targets {
+		if !isSNMPv3(t.opt) {
+			continue
+		}
+
+		auths.Auths[authName(t.opt)] = snmpAuth{
+			Version:       3,
+			Username:      t.opt.SecurityName,
+			SecurityLevel: securityLevel(t.opt),
+			Password:      t.opt.AuthPassphrase,
+			AuthProtocol:  t.opt.AuthProtocol,
+			PrivProtocol:  t.opt.PrivProtocol,
+			PrivPassword:  t.opt.PrivPassphrase,
+			ContextName:   t.opt.ContextName,
+		}
+	}
+
+	return yaml.Marshal(auths)
+}
+
+// writeAuthsFile writes the credentials of the SNMPv3 targets to the auths file.
+// It returns whether the file content changed.
+func writeAuthsFile(path string, targets []*Target) (bool, error) {
+	content, err := authsConfig(targets)
+	if err != nil {
+		return false, err
+	}
+
+	current, err := os.ReadFile(path)
+	if err == nil && bytes.Equal(current, content) {
+		return false, nil
+	}
+
+	// The file is written then renamed, so the snmp_exporter never reads a partial file.
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return false, err
+	}
+
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(content); err != nil {
+		tmpFile.Close()
+
+		return false, err
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return false, err
+	}
+
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// reloadExporter asks the snmp_exporter to read its configuration again.
+func reloadExporter(ctx context.Context, exporterAddress *url.URL) error {
+	ctx, cancel := context.WithTimeout(ctx, exporterReloadTimeout)
+	defer cancel()
+
+	reloadURL := exporterAddress.ResolveReference(&url.URL{Path: "/-/reload"})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reloadURL.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s", errReloadFailed, resp.Status)
+	}
+
+	return nil
+}
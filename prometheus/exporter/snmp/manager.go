@@ -18,8 +18,11 @@ package snmp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
+	"reflect"
+	"slices"
 	"sync"
 	"time"
 
@@ -30,13 +33,34 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+//nolint:gochecknoglobals
+var (
+	validAuthProtocols = []string{"MD5", "SHA", "SHA224", "SHA256", "SHA384", "SHA512"}
+	validPrivProtocols = []string{"DES", "AES", "AES192", "AES256", "AES192C", "AES256C"}
+)
+
+var (
+	errMissingSecurityName = errors.New("security_name is required when using SNMPv3")
+	errIncompleteAuth      = errors.New("auth_protocol and auth_passphrase must be set together")
+	errUnknownAuthProtocol = errors.New("unknown auth_protocol")
+	errPrivWithoutAuth     = errors.New("priv_protocol requires auth_protocol and auth_passphrase")
+	errIncompletePriv      = errors.New("priv_protocol and priv_passphrase must be set together")
+	errUnknownPrivProtocol = errors.New("unknown priv_protocol")
+	errNoAuthsFile         = errors.New("metric.snmp.auths_file must be set to use SNMPv3")
+	errReloadFailed        = errors.New("snmp_exporter reload failed")
+)
+
 type FactProvider interface {
 	Facts(ctx context.Context, maxAge time.Duration) (facts map[string]string, err error)
 }
 
 type Manager struct {
 	exporterAddress *url.URL
-	targets         []*Target
+	authsFile       string
+	scraperFact     FactProvider
+
+	targetsLock sync.Mutex
+	targets     []*Target
 
 	l                  sync.Mutex
 	checkOnlinePending bool
@@ -49,7 +73,8 @@ type GathererWithInfo struct {
 }
 
 // NewManager return a new SNMP manager.
-func NewManager(exporterAddress string, scaperFact FactProvider, targets []config.SNMPTarget) (*Manager, prometheus.MultiError) {
+// The credentials of the SNMPv3 targets are written to authsFile, which must be loaded by the snmp_exporter.
+func NewManager(exporterAddress string, authsFile string, scaperFact FactProvider, targets []config.SNMPTarget) (*Manager, prometheus.MultiError) {
 	var warnings prometheus.MultiError
 
 	exporterURL, err := url.Parse(exporterAddress)
@@ -68,9 +93,53 @@ func NewManager(exporterAddress string, scaperFact FactProvider, targets []confi
 
 	mgr := &Manager{
 		exporterAddress: exporterURL,
-		targets:         make([]*Target, 0, len(targets)),
+		authsFile:       authsFile,
+		scraperFact:     scaperFact,
+	}
+
+	mgr.targets, warnings = mgr.buildTargets(targets, nil)
+
+	if err := mgr.updateAuthsFile(); err != nil {
+		warnings.Append(err)
+	}
+
+	return mgr, warnings
+}
+
+// updateAuthsFile writes the credentials of the SNMPv3 targets to the auths file,
+// and reloads the snmp_exporter when they changed.
+// m.targetsLock must be held or the manager not yet shared.
+func (m *Manager) updateAuthsFile() error {
+	if m.authsFile == "" {
+		return nil
+	}
+
+	changed, err := writeAuthsFile(m.authsFile, m.targets)
+	if err != nil {
+		return fmt.Errorf("unable to write the SNMP auths file: %w", err)
+	}
+
+	if !changed {
+		return nil
 	}
 
+	go func() {
+		defer crashreport.ProcessPanic()
+
+		if err := reloadExporter(context.Background(), m.exporterAddress); err != nil {
+			logger.Printf("Unable to reload the snmp_exporter after updating %s: %v", m.authsFile, err)
+		}
+	}()
+
+	return nil
+}
+
+// buildTargets returns the targets of the configuration. The existing targets with
+// the same configuration are reused, so their facts are kept.
+func (m *Manager) buildTargets(targets []config.SNMPTarget, existing []*Target) ([]*Target, prometheus.MultiError) {
+	var warnings prometheus.MultiError
+
+	result := make([]*Target, 0, len(targets))
 	targetExists := make(map[string]bool)
 
 	for i, t := range targets {
@@ -86,10 +155,104 @@ func NewManager(exporterAddress string, scaperFact FactProvider, targets []confi
 			continue
 		}
 
-		mgr.targets = append(mgr.targets, newTarget(t, scaperFact, exporterURL))
+		if err := validateSNMPv3(t); err != nil {
+			warnings.Append(fmt.Errorf("%w: SNMP target %s is skipped: %w", config.ErrInvalidValue, t.Target, err))
+
+			continue
+		}
+
+		if isSNMPv3(t) && m.authsFile == "" {
+			warnings.Append(fmt.Errorf("%w: SNMP target %s is skipped: %w", config.ErrInvalidValue, t.Target, errNoAuthsFile))
+
+			continue
+		}
+
+		targetExists[t.Target] = true
+
+		idx := slices.IndexFunc(existing, func(e *Target) bool { return reflect.DeepEqual(e.opt, t) })
+		if idx >= 0 {
+			result = append(result, existing[idx])
+
+			continue
+		}
+
+		result = append(result, newTarget(t, m.scraperFact, m.exporterAddress))
 	}
 
-	return mgr, warnings
+	return result, warnings
+}
+
+// UpdateTargets replaces the targets by the ones of the configuration. Targets
+// whose configuration didn't change are kept as is. It returns the targets removed
+// and whether the targets changed. The gatherers must be registered again when
+// the targets changed.
+func (m *Manager) UpdateTargets(targets []config.SNMPTarget) ([]*Target, bool, prometheus.MultiError) {
+	if m == nil {
+		return nil, false, nil
+	}
+
+	m.targetsLock.Lock()
+	defer m.targetsLock.Unlock()
+
+	newTargets, warnings := m.buildTargets(targets, m.targets)
+
+	var removed []*Target
+
+	for _, t := range m.targets {
+		if !slices.Contains(newTargets, t) {
+			removed = append(removed, t)
+		}
+	}
+
+	changed := len(removed) > 0 || len(newTargets) != len(m.targets)-len(removed)
+
+	m.targets = newTargets
+
+	if err := m.updateAuthsFile(); err != nil {
+		warnings.Append(err)
+	}
+
+	return removed, changed, warnings
+}
+
+// validateSNMPv3 checks that SNMPv3 credentials of a target are complete.
+// A target without any SNMPv3 field uses SNMPv2c and is always valid.
+func validateSNMPv3(t config.SNMPTarget) error {
+	isV3 := t.SecurityName != "" || t.AuthProtocol != "" || t.AuthPassphrase != "" ||
+		t.PrivProtocol != "" || t.PrivPassphrase != "" || t.ContextName != ""
+	if !isV3 {
+		return nil
+	}
+
+	if t.SecurityName == "" {
+		return errMissingSecurityName
+	}
+
+	if (t.AuthProtocol == "") != (t.AuthPassphrase == "") {
+		return errIncompleteAuth
+	}
+
+	if t.AuthProtocol != "" && !slices.Contains(validAuthProtocols, t.AuthProtocol) {
+		return fmt.Errorf("%w %q, must be one of %v", errUnknownAuthProtocol, t.AuthProtocol, validAuthProtocols)
+	}
+
+	if t.PrivProtocol == "" && t.PrivPassphrase == "" {
+		return nil
+	}
+
+	if t.AuthProtocol == "" {
+		return errPrivWithoutAuth
+	}
+
+	if t.PrivProtocol == "" || t.PrivPassphrase == "" {
+		return errIncompletePriv
+	}
+
+	if !slices.Contains(validPrivProtocols, t.PrivProtocol) {
+		return fmt.Errorf("%w %q, must be one of %v", errUnknownPrivProtocol, t.PrivProtocol, validPrivProtocols)
+	}
+
+	return nil
 }
 
 // OnlineCount return the number of target that are available (e.g. for which Facts worked).
@@ -104,7 +267,7 @@ func (m *Manager) OnlineCount() int {
 
 	var needCheck []*Target
 
-	for _, t := range m.targets {
+	for _, t := range m.Targets() {
 		t.l.Lock()
 
 		if t.lastFactErr == nil {
@@ -151,9 +314,10 @@ func (m *Manager) Gatherers() []GathererWithInfo {
 		return nil
 	}
 
-	result := make([]GathererWithInfo, 0, len(m.targets))
+	targets := m.Targets()
+	result := make([]GathererWithInfo, 0, len(targets))
 
-	for _, t := range m.targets {
+	for _, t := range targets {
 		result = append(result, GathererWithInfo{
 			Gatherer:    t,
 			Address:     t.Address(),
@@ -170,5 +334,8 @@ func (m *Manager) Targets() []*Target {
 		return nil
 	}
 
+	m.targetsLock.Lock()
+	defer m.targetsLock.Unlock()
+
 	return m.targets
 }
@@ -406,6 +406,12 @@ func (t *Target) buildScraper(module string) registry.GathererWithState {
 	qs := u.Query()
 	qs.Set("module", module)
 	qs.Set("target", t.opt.Target)
+
+	// The credentials are read by the snmp_exporter from the auths file.
+	if isSNMPv3(t.opt) {
+		qs.Set("auth", authName(t.opt))
+	}
+
 	u.RawQuery = qs.Encode()
 
 	target := scrapper.New(u, t.extraLabels())
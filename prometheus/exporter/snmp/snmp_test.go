@@ -20,6 +20,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"sort"
@@ -37,6 +39,7 @@ import (
 	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/common/expfmt"
 	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
 )
 
 func fileToMFS(filename string) ([]*dto.MetricFamily, error) {
@@ -751,3 +754,217 @@ func Test_addressSelectPublic(t *testing.T) {
 		})
 	}
 }
+
+func TestNewManagerSNMPv3(t *testing.T) {
+	t.Parallel()
+
+	targets := []config.SNMPTarget{
+		{Target: "v2c.example.com"},
+		{
+			Target:         "v3.example.com",
+			SecurityName:   "glouton",
+			AuthProtocol:   "SHA",
+			AuthPassphrase: "auth-secret",
+			PrivProtocol:   "AES",
+			PrivPassphrase: "priv-secret",
+			ContextName:    "vlan-1",
+		},
+		{Target: "v3-noauth.example.com", SecurityName: "glouton"},
+		{Target: "missing-name.example.com", AuthProtocol: "SHA", AuthPassphrase: "secret"},
+		{Target: "missing-passphrase.example.com", SecurityName: "glouton", AuthProtocol: "SHA"},
+		{
+			Target:         "priv-without-auth.example.com",
+			SecurityName:   "glouton",
+			PrivProtocol:   "AES",
+			PrivPassphrase: "secret",
+		},
+		{
+			Target:         "bad-protocol.example.com",
+			SecurityName:   "glouton",
+			AuthProtocol:   "SHA1024",
+			AuthPassphrase: "secret",
+		},
+	}
+
+	reloaded := make(chan struct{}, 1)
+
+	exporter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/-/reload" && r.Method == http.MethodPost {
+			reloaded <- struct{}{}
+		}
+	}))
+	defer exporter.Close()
+
+	authsFile := filepath.Join(t.TempDir(), "glouton_auths.yml")
+
+	mgr, warnings := NewManager(exporter.URL, authsFile, nil, targets)
+	if len(warnings) != 4 {
+		t.Errorf("got %d warnings, want 4: %v", len(warnings), warnings)
+	}
+
+	for _, err := range warnings {
+		if !errors.Is(err, config.ErrInvalidValue) {
+			t.Errorf("warning %v isn't an ErrInvalidValue", err)
+		}
+	}
+
+	select {
+	case <-reloaded:
+	case <-time.After(10 * time.Second):
+		t.Error("the snmp_exporter wasn't reloaded")
+	}
+
+	// The credentials must not be in the scrape URL, only the name of the auth.
+	wantQueries := map[string]map[string]string{
+		"v2c.example.com": {
+			"module": "if_mib",
+			"target": "v2c.example.com",
+		},
+		"v3.example.com": {
+			"module": "if_mib",
+			"target": "v3.example.com",
+			"auth":   "glouton_v3.example.com",
+		},
+		"v3-noauth.example.com": {
+			"module": "if_mib",
+			"target": "v3-noauth.example.com",
+			"auth":   "glouton_v3-noauth.example.com",
+		},
+	}
+
+	if len(mgr.targets) != len(wantQueries) {
+		t.Fatalf("got %d targets, want %d", len(mgr.targets), len(wantQueries))
+	}
+
+	for _, tgt := range mgr.targets {
+		scraper, ok := tgt.buildScraper("if_mib").(*scrapper.Target)
+		if !ok {
+			t.Fatalf("buildScraper() isn't a *scrapper.Target")
+		}
+
+		qs := scraper.URL.Query()
+
+		got := make(map[string]string, len(qs))
+		for k := range qs {
+			got[k] = qs.Get(k)
+		}
+
+		if diff := cmp.Diff(wantQueries[tgt.opt.Target], got); diff != "" {
+			t.Errorf("query mismatch for %s (-want +got)\n%s", tgt.opt.Target, diff)
+		}
+	}
+
+	content, err := os.ReadFile(authsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotAuths snmpAuths
+
+	if err := yaml.Unmarshal(content, &gotAuths); err != nil {
+		t.Fatal(err)
+	}
+
+	wantAuths := snmpAuths{
+		Auths: map[string]snmpAuth{
+			"glouton_v3.example.com": {
+				Version:       3,
+				Username:      "glouton",
+				SecurityLevel: "authPriv",
+				Password:      "auth-secret",
+				AuthProtocol:  "SHA",
+				PrivProtocol:  "AES",
+				PrivPassword:  "priv-secret",
+				ContextName:   "vlan-1",
+			},
+			"glouton_v3-noauth.example.com": {
+				Version:       3,
+				Username:      "glouton",
+				SecurityLevel: "noAuthNoPriv",
+			},
+		},
+	}
+
+	if diff := cmp.Diff(wantAuths, gotAuths); diff != "" {
+		t.Errorf("auths file mismatch (-want +got)\n%s", diff)
+	}
+
+	info, err := os.Stat(authsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("auths file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// The targets using SNMPv3 are skipped when the credentials can't be given to the snmp_exporter.
+	mgr, warnings = NewManager(exporter.URL, "", nil, targets[:3])
+	if len(warnings) != 2 || !errors.Is(warnings[0], errNoAuthsFile) {
+		t.Errorf("warnings = %v, want 2 %v", warnings, errNoAuthsFile)
+	}
+
+	if len(mgr.targets) != 1 {
+		t.Errorf("got %d targets, want only the SNMPv2c one", len(mgr.targets))
+	}
+}
+
+func TestManagerUpdateTargets(t *testing.T) {
+	t.Parallel()
+
+	mgr, warnings := NewManager("http://localhost:9116", "", nil, []config.SNMPTarget{
+		{Target: "kept.example.com"},
+		{Target: "modified.example.com", InitialName: "old name"},
+		{Target: "removed.example.com"},
+	})
+	if warnings != nil {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	}
+
+	kept := mgr.Targets()[0]
+
+	removed, changed, warnings := mgr.UpdateTargets([]config.SNMPTarget{
+		{Target: "kept.example.com"},
+		{Target: "modified.example.com", InitialName: "new name"},
+		{Target: "added.example.com"},
+		{Target: "added.example.com"},
+	})
+	if len(warnings) != 1 {
+		t.Errorf("got %d warnings, want 1 for the duplicated target: %v", len(warnings), warnings)
+	}
+
+	if !changed {
+		t.Error("changed = false, want true")
+	}
+
+	removedAddresses := make([]string, 0, len(removed))
+	for _, tgt := range removed {
+		removedAddresses = append(removedAddresses, tgt.Address())
+	}
+
+	if diff := cmp.Diff([]string{"modified.example.com", "removed.example.com"}, removedAddresses); diff != "" {
+		t.Errorf("removed targets mismatch (-want +got)\n%s", diff)
+	}
+
+	targets := mgr.Targets()
+	if len(targets) != 3 {
+		t.Fatalf("got %d targets, want 3", len(targets))
+	}
+
+	if targets[0] != kept {
+		t.Error("the unchanged target was re-created")
+	}
+
+	if targets[1].opt.InitialName != "new name" {
+		t.Errorf("InitialName = %q, want %q", targets[1].opt.InitialName, "new name")
+	}
+
+	_, changed, _ = mgr.UpdateTargets([]config.SNMPTarget{
+		{Target: "kept.example.com"},
+		{Target: "modified.example.com", InitialName: "new name"},
+		{Target: "added.example.com"},
+	})
+	if changed {
+		t.Error("changed = true with the same targets, want false")
+	}
+}
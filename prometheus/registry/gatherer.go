@@ -25,8 +25,10 @@ import (
 	"time"
 
 	"github.com/bleemeo/glouton/logger"
+	"github.com/bleemeo/glouton/prometheus/matcher"
 	"github.com/bleemeo/glouton/prometheus/model"
 	"github.com/bleemeo/glouton/prometheus/registry/internal/ruler"
+	"github.com/bleemeo/glouton/types"
 
 	"github.com/prometheus/client_golang/prometheus"
 	dto "github.com/prometheus/client_model/go"
@@ -155,6 +157,52 @@ func (w *GathererWithStateWrapper) Gather() ([]*dto.MetricFamily, error) {
 	return res, err
 }
 
+// denyGatherer drops the metrics matching the deny list from the result of its source.
+type denyGatherer struct {
+	source   prometheus.Gatherer
+	denyList []matcher.Matchers
+}
+
+// Gather implements prometheus.Gatherer.
+func (g denyGatherer) Gather() ([]*dto.MetricFamily, error) {
+	mfs, err := g.source.Gather()
+
+	result := make([]*dto.MetricFamily, 0, len(mfs))
+
+	for _, mf := range mfs {
+		metrics := make([]*dto.Metric, 0, len(mf.GetMetric()))
+
+		for _, m := range mf.GetMetric() {
+			lbls := make(map[string]string, len(m.GetLabel())+1)
+			lbls[types.LabelName] = mf.GetName()
+
+			for _, lp := range m.GetLabel() {
+				lbls[lp.GetName()] = lp.GetValue()
+			}
+
+			if !matcher.MatchesAny(lbls, g.denyList) {
+				metrics = append(metrics, m)
+			}
+		}
+
+		switch {
+		case len(metrics) == 0:
+			continue
+		case len(metrics) == len(mf.GetMetric()):
+			result = append(result, mf)
+		default:
+			result = append(result, &dto.MetricFamily{
+				Name:   mf.Name,
+				Help:   mf.Help,
+				Type:   mf.Type,
+				Metric: metrics,
+			})
+		}
+	}
+
+	return result, err
+}
+
 type gatherModifier func(mfs []*dto.MetricFamily, gatherError error) []*dto.MetricFamily
 
 // wrappedGatherer wraps a gatherer to apply Registry change and apply RegistrationOption.
@@ -281,7 +329,12 @@ func (g *wrappedGatherer) GatherWithState(ctx context.Context, state GatherState
 
 	for _, mf := range mfs {
 		for i, m := range mf.GetMetric() {
-			m.Label = mergeLabelsDTO(m.GetLabel(), g.labels)
+			if g.opt.HonorLabels {
+				m.Label = mergeLabelsDTO(g.labels, m.GetLabel())
+			} else {
+				m.Label = mergeLabelsDTO(m.GetLabel(), g.labels)
+			}
+
 			mf.Metric[i] = m
 		}
 	}
@@ -350,6 +403,32 @@ func mergeLabels(a labels.Labels, b []*dto.LabelPair) labels.Labels {
 	return result
 }
 
+// mergeLabelsHonor merge two sorted list of labels. In case of name conflict, value from a wins.
+func mergeLabelsHonor(a labels.Labels, b []*dto.LabelPair) labels.Labels {
+	result := make(labels.Labels, 0, len(a)+len(b))
+	bIndex := 0
+
+	for _, aLabel := range a {
+		for bIndex < len(b) && b[bIndex].GetName() < aLabel.Name {
+			result = append(result, labels.Label{Name: b[bIndex].GetName(), Value: b[bIndex].GetValue()})
+			bIndex++
+		}
+
+		if bIndex < len(b) && b[bIndex].GetName() == aLabel.Name {
+			bIndex++
+		}
+
+		result = append(result, aLabel)
+	}
+
+	for bIndex < len(b) {
+		result = append(result, labels.Label{Name: b[bIndex].GetName(), Value: b[bIndex].GetValue()})
+		bIndex++
+	}
+
+	return result
+}
+
 // mergeLabelsDTO merge two sorted list of labels. In case of name conflict, value from b wins.
 func mergeLabelsDTO(a []*dto.LabelPair, b []*dto.LabelPair) []*dto.LabelPair {
 	result := make([]*dto.LabelPair, 0, len(a)+len(b))
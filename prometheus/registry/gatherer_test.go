@@ -129,6 +129,14 @@ func Test_mergeLabels(t *testing.T) {
 				t.Errorf("mergeLabels() mismatch (-want +got)\n%s", diff)
 			}
 
+			// With honor labels, the labels from the gathered metrics win: swap the arguments.
+			inputB := labels.FromMap(model.DTO2Labels("fake_name", tt.args.b))
+			gotHonorLabels := mergeLabelsHonor(inputB, tt.args.a)
+
+			if diff := cmp.Diff(wantLabels, gotHonorLabels); diff != "" {
+				t.Errorf("mergeLabelsHonor() mismatch (-want +got)\n%s", diff)
+			}
+
 			got := mergeLabelsDTO(tt.args.a, tt.args.b)
 
 			if diff := cmp.Diff(tt.want, got, opts...); diff != "" {
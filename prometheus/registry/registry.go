@@ -26,9 +26,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -37,6 +39,7 @@ import (
 	"github.com/bleemeo/glouton/delay"
 	"github.com/bleemeo/glouton/inputs"
 	"github.com/bleemeo/glouton/logger"
+	"github.com/bleemeo/glouton/prometheus/matcher"
 	gloutonModel "github.com/bleemeo/glouton/prometheus/model"
 	"github.com/bleemeo/glouton/prometheus/registry/internal/renamer"
 	"github.com/bleemeo/glouton/types"
@@ -51,6 +54,7 @@ import (
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/prometheus/prometheus/model/value"
 	"github.com/prometheus/prometheus/promql/parser"
 	"github.com/prometheus/prometheus/rules"
 	"github.com/prometheus/prometheus/storage"
@@ -137,12 +141,14 @@ type Registry struct {
 	relabelConfigs          []*relabel.Config
 	registrations           map[int]*registration
 	internalRegistry        *prometheus.Registry
+	gatherDuration          *prometheus.GaugeVec
 	pushedPoints            map[string]types.MetricPoint
 	pushedPointsExpiration  map[string]time.Time
 	lastPushedPointsCleanup time.Time
 	currentDelay            time.Duration
 	relabelHook             RelabelHook
 	renamer                 *renamer.Renamer
+	resolution              *resolutionSubsampler
 }
 
 type Option struct {
@@ -156,6 +162,22 @@ type Option struct {
 	Filter                metricFilter
 	SecretInputsGate      *gate.Gate
 	ShutdownDeadline      time.Duration
+	// AlignTimestamps truncates the timestamp of gathered points to the gather interval boundary.
+	AlignTimestamps bool
+	// ResolutionOverrides maps a metric name to the minimal interval between two stored points
+	// of this metric. Points gathered more often are dropped.
+	ResolutionOverrides map[string]time.Duration
+	// MetricRenames maps a metric name to the name it should be renamed to.
+	// It allows to keep some names when switching between the Bleemeo and Prometheus formats.
+	MetricRenames map[string]string
+	// EmitRawCounters enables emitting the raw counters alongside the computed rates for inputs supporting it.
+	EmitRawCounters bool
+	// ExporterDenyMetrics are metrics hidden from the local /metrics endpoint.
+	// They are still stored and sent to Bleemeo.
+	ExporterDenyMetrics []matcher.Matchers
+	// StaleMetrics are metrics for which a staleness marker is pushed to the store
+	// when a series disappears from the gather of its registration.
+	StaleMetrics []matcher.Matchers
 }
 
 type RegistrationOption struct {
@@ -167,6 +189,9 @@ type RegistrationOption struct {
 	StopCallback func() `json:"-"`
 	// ExtraLabels are labels added. If a labels already exists, extraLabels take precedence.
 	ExtraLabels map[string]string
+	// HonorLabels makes labels of the gathered metrics take precedence over ExtraLabels
+	// (and labels added by the relabel hook) on conflict, like honor_labels in Prometheus.
+	HonorLabels bool
 	// NoLabelsAlteration disable (most) alteration of labels. It don't apply to PushPoints.
 	// Meta labels (starting with __) are still dropped and (if applicable) converted to annotations.
 	NoLabelsAlteration bool
@@ -205,6 +230,11 @@ type RegistrationOption struct {
 	rrules                 []*rules.RecordingRule
 }
 
+// rawCountersInput is an input which can emit the raw counters used to compute rates.
+type rawCountersInput interface {
+	SetEmitRawCounters(enable bool)
+}
+
 type AppenderCallback interface {
 	// Collect collects point and write them into Appender. The appender must not be used once Collect returned.
 	// If you omit to Commit() on the appender, it will be automatically done when Collect return without error.
@@ -267,6 +297,7 @@ func (s scrapeRun) MarshalJSON() ([]byte, error) {
 
 type registration struct {
 	l                    sync.Mutex
+	id                   int
 	option               RegistrationOption
 	addedAt              time.Time
 	removalRequested     bool
@@ -278,6 +309,9 @@ type registration struct {
 	annotations          types.MetricAnnotations
 	relabelHookSkip      bool
 	lastRelabelHookRetry time.Time
+	// staleSeries are the labels of the series matching StaleMetrics
+	// pushed by the previous gather, indexed by their text representation.
+	staleSeries map[string]map[string]string
 }
 
 // RunNow will trigger an run of the scrapeLoop. If the registry isn't running,
@@ -409,6 +443,14 @@ func getDefaultRelabelConfig() []*relabel.Config {
 			TargetLabel:  types.LabelScrapeInstance,
 			Replacement:  "$1",
 		},
+		{
+			Action:       relabel.Replace,
+			Separator:    ";",
+			Regex:        relabel.MustNewRegexp("(.*)"),
+			SourceLabels: model.LabelNames{types.LabelMetaScrapeSource},
+			TargetLabel:  types.LabelScrapeSource,
+			Replacement:  "$1",
+		},
 	}
 }
 
@@ -437,11 +479,20 @@ func (r *Registry) init() {
 	r.condition = sync.NewCond(&r.l)
 	r.registrations = make(map[int]*registration)
 	r.internalRegistry = prometheus.NewRegistry()
+	r.gatherDuration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "agent_gather_duration_seconds",
+			Help: "Duration of the last gather of each collector",
+		},
+		[]string{"collector_id", "collector"},
+	)
+	r.internalRegistry.MustRegister(r.gatherDuration)
 	r.pushedPoints = make(map[string]types.MetricPoint)
 	r.pushedPointsExpiration = make(map[string]time.Time)
 	r.currentDelay = 10 * time.Second
 	r.relabelConfigs = getDefaultRelabelConfig()
-	r.renamer = renamer.LoadRules(renamer.GetDefaultRules())
+	r.renamer = renamer.LoadRules(append(metricRenamesRules(r.option.MetricRenames), renamer.GetDefaultRules()...))
+	r.resolution = newResolutionSubsampler(r.option.ResolutionOverrides)
 }
 
 func (r *Registry) Run(ctx context.Context) error {
@@ -640,6 +691,10 @@ func (r *Registry) RegisterInput(
 		return 0, err
 	}
 
+	if ri, ok := input.(rawCountersInput); ok && r.option.EmitRawCounters {
+		ri.SetEmitRawCounters(true)
+	}
+
 	// Initialize the input.
 	if si, ok := input.(telegraf.Initializer); ok {
 		if err := si.Init(); err != nil {
@@ -688,6 +743,11 @@ func (r *Registry) UpdateRelabelHook(hook RelabelHook) {
 }
 
 func (r *Registry) updateRelabelHook(hook RelabelHook) {
+	r.updateRelabel(func() { r.relabelHook = hook })
+}
+
+// updateRelabel applies the change to the relabeling and updates the labels of all gatherers.
+func (r *Registry) updateRelabel(change func()) {
 	r.l.Lock()
 	defer r.l.Unlock()
 
@@ -698,7 +758,7 @@ func (r *Registry) updateRelabelHook(hook RelabelHook) {
 		r.condition.Wait()
 	}
 
-	r.relabelHook = hook
+	change()
 
 	// Since the updated Agent ID may change metrics labels, drop pushed points
 	r.pushedPoints = make(map[string]types.MetricPoint)
@@ -716,6 +776,34 @@ func (r *Registry) updateRelabelHook(hook RelabelHook) {
 	r.condition.Broadcast()
 }
 
+// UpdateGlobalLabels changes the labels added to all metrics going through relabeling.
+// A global label doesn't override a label already present on the metric.
+func (r *Registry) UpdateGlobalLabels(globalLabels map[string]string) {
+	relabelConfigs := getDefaultRelabelConfig()
+
+	names := make([]string, 0, len(globalLabels))
+	for name := range globalLabels {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		relabelConfigs = append(relabelConfigs, &relabel.Config{
+			Action:       relabel.Replace,
+			Separator:    ";",
+			Regex:        relabel.MustNewRegexp(""),
+			SourceLabels: model.LabelNames{model.LabelName(name)},
+			TargetLabel:  name,
+			Replacement:  globalLabels[name],
+		})
+	}
+
+	r.restartLoops(func() {
+		r.updateRelabel(func() { r.relabelConfigs = relabelConfigs })
+	})
+}
+
 func (r *Registry) DiagnosticArchive(ctx context.Context, archive types.ArchiveWriter) error {
 	file, err := archive.Create("metrics.txt")
 	if err != nil {
@@ -1002,6 +1090,7 @@ func (r *Registry) addRegistration(reg *registration) (int, error) {
 		_, ok = r.registrations[id]
 	}
 
+	reg.id = id
 	reg.addedAt = time.Now()
 
 	r.registrations[id] = reg
@@ -1184,6 +1273,7 @@ func (r *Registry) Unregister(id int) bool {
 	}
 
 	reg.gatherer.close()
+	r.gatherDuration.DeleteLabelValues(strconv.Itoa(reg.id), reg.option.Description)
 
 	if reg.option.StopCallback != nil {
 		reg.option.StopCallback()
@@ -1434,7 +1524,13 @@ func (r *Registry) Exporter() http.Handler {
 
 		wrapper.SetState(state)
 
-		promhttp.HandlerFor(wrapper, promhttp.HandlerOpts{
+		var gatherer prometheus.Gatherer = wrapper
+
+		if len(r.option.ExporterDenyMetrics) > 0 {
+			gatherer = denyGatherer{source: gatherer, denyList: r.option.ExporterDenyMetrics}
+		}
+
+		promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{
 			ErrorHandling: promhttp.ContinueOnError,
 			ErrorLog:      prefixLogger("/metrics endpoint:"),
 		}).ServeHTTP(w, req)
@@ -1513,6 +1609,10 @@ func (r *Registry) scrapeFromLoop(ctx context.Context, loopCtx context.Context,
 		reg.l.Unlock()
 
 		state.HintMetricFilter = func(lbls labels.Labels) bool {
+			if reg.option.HonorLabels {
+				return r.option.Filter.IsMetricAllowed(mergeLabelsHonor(lbls, extraLabels), true)
+			}
+
 			return r.option.Filter.IsMetricAllowed(mergeLabels(lbls, extraLabels), true)
 		}
 	}
@@ -1568,9 +1668,25 @@ func (r *Registry) scrapeFromLoop(ctx context.Context, loopCtx context.Context,
 
 	reg.l.Unlock()
 
+	r.gatherDuration.WithLabelValues(strconv.Itoa(reg.id), reg.option.Description).Set(duration.Seconds())
+
 	// Don't drop the meta labels here, they are needed for relabeling.
 	points := gloutonModel.FamiliesToMetricPoints(t0, mfs, !reg.option.ApplyDynamicRelabel)
 
+	if r.option.AlignTimestamps {
+		reg.l.Lock()
+
+		var interval time.Duration
+
+		if reg.loop != nil {
+			interval = reg.loop.interval
+		}
+
+		reg.l.Unlock()
+
+		alignPointsTime(points, interval)
+	}
+
 	if (reg.annotations != types.MetricAnnotations{}) {
 		for i := range points {
 			points[i].Annotations = points[i].Annotations.Merge(reg.annotations)
@@ -1593,11 +1709,55 @@ func (r *Registry) scrapeFromLoop(ctx context.Context, loopCtx context.Context,
 		points = r.option.Filter.FilterPoints(points, true)
 	}
 
+	// The series are tracked before the resolution overrides, which skip some gathers.
+	var staleMarkers []types.MetricPoint
+
+	if len(r.option.StaleMetrics) > 0 {
+		staleMarkers = r.staleMarkers(reg, t0, points)
+	}
+
+	points = r.resolution.Filter(points)
+	points = append(points, staleMarkers...)
+
 	if len(points) > 0 && r.option.PushPoint != nil {
 		r.option.PushPoint.PushPoints(ctx, points)
 	}
 }
 
+// staleMarkers returns a Prometheus staleness marker (a stale NaN) for each series
+// matching StaleMetrics which was pushed by the previous gather of the registration
+// but is now missing. The marker is only sent once.
+func (r *Registry) staleMarkers(reg *registration, t0 time.Time, points []types.MetricPoint) []types.MetricPoint {
+	current := make(map[string]map[string]string)
+
+	for _, point := range points {
+		if matcher.MatchesAny(point.Labels, r.option.StaleMetrics) {
+			current[types.LabelsToText(point.Labels)] = point.Labels
+		}
+	}
+
+	reg.l.Lock()
+	defer reg.l.Unlock()
+
+	var markers []types.MetricPoint
+
+	for key, lbls := range reg.staleSeries {
+		if _, ok := current[key]; ok {
+			continue
+		}
+
+		markers = append(markers, types.MetricPoint{
+			// Use Float64frombits because float64(value.StaleNaN) isn't a stale NaN.
+			Point:  types.Point{Time: t0, Value: math.Float64frombits(value.StaleNaN)},
+			Labels: lbls,
+		})
+	}
+
+	reg.staleSeries = current
+
+	return markers
+}
+
 func (r *Registry) scrape(ctx context.Context, state GatherState, reg *registration) ([]*dto.MetricFamily, time.Duration, error) {
 	r.l.Lock()
 	reg.l.Lock()
@@ -1647,6 +1807,43 @@ func (r *Registry) scrape(ctx context.Context, state GatherState, reg *registrat
 	return mfs, time.Since(start), err
 }
 
+// metricRenamesRules returns the renamer rules for the user defined metric renames.
+func metricRenamesRules(renames map[string]string) []renamer.Rule {
+	names := make([]string, 0, len(renames))
+	for name := range renames {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	rules := make([]renamer.Rule, 0, len(renames))
+
+	for _, name := range names {
+		rules = append(rules, renamer.Rule{
+			MetricName: name,
+			RewriteRules: []renamer.RewriteRule{
+				{
+					LabelName: types.LabelName,
+					NewValue:  renames[name],
+				},
+			},
+		})
+	}
+
+	return rules
+}
+
+// alignPointsTime truncates the time of the points to a multiple of the interval.
+func alignPointsTime(points []types.MetricPoint, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	for i := range points {
+		points[i].Time = points[i].Time.Truncate(interval)
+	}
+}
+
 // pushPoint add a new point to the list of pushed point with a specified TTL.
 // As for AddMetricPointFunction, points should not be mutated after the call.
 func (r *Registry) pushPoint(ctx context.Context, points []types.MetricPoint, ttl time.Duration, format types.MetricFormat) {
@@ -1714,6 +1911,11 @@ func (r *Registry) pushPoint(ctx context.Context, points []types.MetricPoint, tt
 	points = points[:n]
 	points = r.renamer.Rename(points)
 
+	if r.option.AlignTimestamps {
+		// Pushed points have no registration, align them on the interval of the dynamic gathers.
+		alignPointsTime(points, r.currentDelay)
+	}
+
 	// Apply the thresholds after the relabel hook to get the instance UUID in the labels.
 	if r.option.ThresholdHandler != nil {
 		var statusPoints []types.MetricPoint
@@ -1740,7 +1942,11 @@ func (r *Registry) pushPoint(ctx context.Context, points []types.MetricPoint, tt
 
 	r.l.Unlock()
 
-	if r.option.PushPoint != nil {
+	// The points are kept in pushedPoints for the local /metrics endpoint,
+	// the resolution overrides only apply to the points sent to the store.
+	points = r.resolution.Filter(points)
+
+	if len(points) > 0 && r.option.PushPoint != nil {
 		r.option.PushPoint.PushPoints(ctx, points)
 	}
 
@@ -34,15 +34,18 @@ import (
 	"testing"
 	"time"
 
+	"github.com/bleemeo/glouton/prometheus/matcher"
 	"github.com/bleemeo/glouton/prometheus/model"
 	"github.com/bleemeo/glouton/types"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/influxdata/telegraf"
+	"github.com/prometheus/client_golang/prometheus"
 	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/prometheus/prometheus/model/value"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/util/gate"
 	"golang.org/x/sync/errgroup"
@@ -4068,6 +4071,168 @@ func pointsWithOverrideToPoints(in []metricPointTimeOverride) []types.MetricPoin
 	return result
 }
 
+func TestAlignPointsTime(t *testing.T) {
+	t.Parallel()
+
+	t0 := time.Date(2024, 6, 20, 10, 31, 17, 250_000_000, time.UTC)
+	points := []types.MetricPoint{
+		{Point: types.Point{Time: t0}},
+		{Point: types.Point{Time: t0.Add(3 * time.Second)}},
+	}
+
+	alignPointsTime(points, 10*time.Second)
+
+	want := []time.Time{
+		time.Date(2024, 6, 20, 10, 31, 10, 0, time.UTC),
+		time.Date(2024, 6, 20, 10, 31, 20, 0, time.UTC),
+	}
+
+	for i, p := range points {
+		if !p.Time.Equal(want[i]) {
+			t.Errorf("points[%d] time = %s, want %s", i, p.Time, want[i])
+		}
+	}
+
+	// A zero interval leaves the points unchanged.
+	alignPointsTime(points, 0)
+
+	if !points[0].Time.Equal(want[0]) {
+		t.Errorf("point time = %s, want %s", points[0].Time, want[0])
+	}
+}
+
+func TestPushPointsAlignTimestamps(t *testing.T) {
+	t.Parallel()
+
+	var (
+		l      sync.Mutex
+		pushed []types.MetricPoint
+	)
+
+	reg, err := New(Option{
+		Filter:          &fakeFilter{},
+		AlignTimestamps: true,
+		PushPoint: pushFunction(func(_ context.Context, pts []types.MetricPoint) {
+			l.Lock()
+			defer l.Unlock()
+
+			pushed = append(pushed, pts...)
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t0 := time.Date(2024, 6, 20, 10, 31, 17, 250_000_000, time.UTC)
+
+	reg.WithTTL(time.Hour).PushPoints(context.Background(), []types.MetricPoint{
+		{
+			Point:  types.Point{Value: 1, Time: t0},
+			Labels: map[string]string{types.LabelName: "cpu_used"},
+		},
+	})
+
+	l.Lock()
+	defer l.Unlock()
+
+	if len(pushed) != 1 {
+		t.Fatalf("got %d points, want 1", len(pushed))
+	}
+
+	// The default gather interval is 10 seconds.
+	want := time.Date(2024, 6, 20, 10, 31, 10, 0, time.UTC)
+	if !pushed[0].Time.Equal(want) {
+		t.Errorf("point time = %s, want %s", pushed[0].Time, want)
+	}
+}
+
+func TestResolutionSubsampler(t *testing.T) {
+	t.Parallel()
+
+	s := newResolutionSubsampler(map[string]time.Duration{"slow_query": time.Minute})
+	t0 := time.Now().Truncate(time.Minute)
+
+	kept := 0
+
+	for i := range 12 {
+		points := []types.MetricPoint{
+			{Point: types.Point{Time: t0.Add(time.Duration(i) * 10 * time.Second)}, Labels: map[string]string{types.LabelName: "slow_query"}},
+			{Point: types.Point{Time: t0.Add(time.Duration(i) * 10 * time.Second)}, Labels: map[string]string{types.LabelName: "cpu_used"}},
+		}
+
+		points = s.Filter(points)
+
+		for _, p := range points {
+			if p.Labels[types.LabelName] == "slow_query" {
+				kept++
+			}
+		}
+
+		if points[len(points)-1].Labels[types.LabelName] != "cpu_used" {
+			t.Errorf("cpu_used point was dropped at iteration %d", i)
+		}
+	}
+
+	// Points at 0s and 60s must be kept, all other points are dropped.
+	if kept != 2 {
+		t.Errorf("kept = %d, want 2", kept)
+	}
+}
+
+func TestPushPointsResolution(t *testing.T) {
+	t.Parallel()
+
+	var (
+		l      sync.Mutex
+		pushed []string
+	)
+
+	reg, err := New(Option{
+		Filter:              &fakeFilter{},
+		ResolutionOverrides: map[string]time.Duration{"slow_query": time.Minute},
+		PushPoint: pushFunction(func(_ context.Context, pts []types.MetricPoint) {
+			l.Lock()
+			defer l.Unlock()
+
+			for _, p := range pts {
+				pushed = append(pushed, p.Labels[types.LabelName])
+			}
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t0 := time.Now().Truncate(time.Minute)
+
+	for i := range 6 {
+		reg.WithTTL(time.Hour).PushPoints(context.Background(), []types.MetricPoint{
+			{
+				Point:  types.Point{Value: 1, Time: t0.Add(time.Duration(i) * 10 * time.Second)},
+				Labels: map[string]string{types.LabelName: "slow_query"},
+			},
+			{
+				Point:  types.Point{Value: 1, Time: t0.Add(time.Duration(i) * 10 * time.Second)},
+				Labels: map[string]string{types.LabelName: "cpu_used"},
+			},
+		})
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	count := make(map[string]int)
+	for _, name := range pushed {
+		count[name]++
+	}
+
+	// Only the first point of slow_query is kept within the minute.
+	want := map[string]int{"slow_query": 1, "cpu_used": 6}
+	if diff := cmp.Diff(want, count); diff != "" {
+		t.Errorf("pushed points mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func pointsWithOverrideToMFS(in []metricPointTimeOverride) []*dto.MetricFamily {
 	result := make([]types.MetricPoint, len(in))
 
@@ -4097,3 +4262,274 @@ func sortMetricPoints(points []types.MetricPoint) []types.MetricPoint {
 
 	return points
 }
+
+func TestRegistry_MetricRenames(t *testing.T) {
+	t.Parallel()
+
+	reg, err := New(Option{
+		Filter:        &fakeFilter{},
+		MetricRenames: map[string]string{"node_load1": "system_load1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reg.WithTTL(time.Hour).PushPoints(
+		context.Background(),
+		[]types.MetricPoint{
+			{
+				Point:  types.Point{Value: 1.0, Time: time.Now()},
+				Labels: map[string]string{types.LabelName: "node_load1"},
+			},
+			{
+				Point:  types.Point{Value: 2.0, Time: time.Now()},
+				Labels: map[string]string{types.LabelName: "node_load5"},
+			},
+		},
+	)
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0, len(mfs))
+	for _, mf := range mfs {
+		names = append(names, mf.GetName())
+	}
+
+	if diff := cmp.Diff([]string{"node_load5", "system_load1"}, names); diff != "" {
+		t.Errorf("metric names mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDenyGatherer(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	points := []types.MetricPoint{
+		{
+			Point:  types.Point{Value: 1.0, Time: now},
+			Labels: map[string]string{types.LabelName: "cpu_used"},
+		},
+		{
+			Point:  types.Point{Value: 2.0, Time: now},
+			Labels: map[string]string{types.LabelName: "secret_metric"},
+		},
+		{
+			Point:  types.Point{Value: 3.0, Time: now},
+			Labels: map[string]string{types.LabelName: "disk_used", types.LabelItem: "/home"},
+		},
+		{
+			Point:  types.Point{Value: 4.0, Time: now},
+			Labels: map[string]string{types.LabelName: "disk_used", types.LabelItem: "/"},
+		},
+	}
+
+	denyList := make([]matcher.Matchers, 0, 2)
+
+	for _, metric := range []string{"secret_*", `disk_used{item="/home"}`} {
+		m, err := matcher.NormalizeMetric(metric)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		denyList = append(denyList, m)
+	}
+
+	gatherer := denyGatherer{
+		source: prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			return model.MetricPointsToFamilies(points), nil
+		}),
+		denyList: denyList,
+	}
+
+	mfs, err := gatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]string, 0, len(mfs))
+
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			got = append(got, fmt.Sprintf("%s=%v", mf.GetName(), m.GetUntyped().GetValue()+m.GetGauge().GetValue()))
+		}
+	}
+
+	sort.Strings(got)
+
+	if diff := cmp.Diff([]string{"cpu_used=1", "disk_used=4"}, got); diff != "" {
+		t.Errorf("gathered metrics mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRegistry_GatherDuration(t *testing.T) {
+	t.Parallel()
+
+	reg, err := New(Option{Filter: &fakeFilter{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both registrations use the same description, the series must not collide.
+	id, err := reg.RegisterGatherer(RegistrationOption{Description: "my collector", DisablePeriodicGather: true}, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherID, err := reg.RegisterGatherer(RegistrationOption{Description: "my collector", DisablePeriodicGather: true}, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reg.InternalRunScrape(context.Background(), context.Background(), time.Now(), id)
+	reg.InternalRunScrape(context.Background(), context.Background(), time.Now(), otherID)
+
+	countSeries := func() int {
+		mfs, err := reg.internalRegistry.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		count := 0
+
+		for _, mf := range mfs {
+			if mf.GetName() != "agent_gather_duration_seconds" {
+				continue
+			}
+
+			for _, m := range mf.GetMetric() {
+				for _, lbl := range m.GetLabel() {
+					if lbl.GetName() == "collector" && lbl.GetValue() == "my collector" {
+						count++
+					}
+				}
+			}
+		}
+
+		return count
+	}
+
+	if got := countSeries(); got != 2 {
+		t.Errorf("got %d series for the collector, want 2", got)
+	}
+
+	reg.Unregister(id)
+
+	if got := countSeries(); got != 1 {
+		t.Errorf("got %d series for the collector after unregister, want 1", got)
+	}
+
+	reg.Unregister(otherID)
+
+	if got := countSeries(); got != 0 {
+		t.Errorf("got %d series for the collector after unregister, want 0", got)
+	}
+}
+
+// TestRegistry_StaleMarkers checks that a staleness marker is pushed once when a series
+// disappears, and that each registration only marks its own series as stale.
+func TestRegistry_StaleMarkers(t *testing.T) {
+	t.Parallel()
+
+	m, err := matcher.NormalizeMetric("container_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		l      sync.Mutex
+		pushed []types.MetricPoint
+	)
+
+	reg, err := New(Option{
+		Filter:       &fakeFilter{},
+		StaleMetrics: []matcher.Matchers{m},
+		PushPoint: pushFunction(func(_ context.Context, pts []types.MetricPoint) {
+			l.Lock()
+			defer l.Unlock()
+
+			pushed = append(pushed, pts...)
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newPoint := func(name string, item string) types.MetricPoint {
+		return types.MetricPoint{
+			Point:  types.Point{Value: 1.0, Time: time.Now()},
+			Labels: map[string]string{types.LabelName: name, types.LabelItem: item},
+		}
+	}
+
+	currentWeb := []types.MetricPoint{newPoint("container_cpu_used", "web"), newPoint("container_cpu_used", "db"), newPoint("cpu_used", "")}
+	currentOther := []types.MetricPoint{newPoint("container_cpu_used", "cache")}
+
+	register := func(current *[]types.MetricPoint) int {
+		id, err := reg.RegisterGatherer(
+			RegistrationOption{Description: "test", DisablePeriodicGather: true},
+			prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+				return model.MetricPointsToFamilies(*current), nil
+			}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return id
+	}
+
+	idWeb := register(&currentWeb)
+	idOther := register(&currentOther)
+
+	// scrapeStale runs a scrape and returns the items of the staleness markers pushed.
+	scrapeStale := func(id int) []string {
+		t.Helper()
+
+		l.Lock()
+		pushed = nil
+		l.Unlock()
+
+		reg.InternalRunScrape(context.Background(), context.Background(), time.Now(), id)
+
+		l.Lock()
+		defer l.Unlock()
+
+		var stale []string
+
+		for _, p := range pushed {
+			if value.IsStaleNaN(p.Value) {
+				stale = append(stale, p.Labels[types.LabelName]+"/"+p.Labels[types.LabelItem])
+			}
+		}
+
+		return stale
+	}
+
+	if got := scrapeStale(idWeb); len(got) != 0 {
+		t.Errorf("first gather has stale markers: %v", got)
+	}
+
+	if got := scrapeStale(idOther); len(got) != 0 {
+		t.Errorf("first gather of the other registration has stale markers: %v", got)
+	}
+
+	// The "db" container and cpu_used disappear, only the container metric is tracked.
+	currentWeb = currentWeb[:1]
+
+	if diff := cmp.Diff([]string{"container_cpu_used/db"}, scrapeStale(idWeb)); diff != "" {
+		t.Errorf("second gather stale markers mismatch (-want +got):\n%s", diff)
+	}
+
+	// The other registration doesn't mark the series of the first one as stale.
+	if got := scrapeStale(idOther); len(got) != 0 {
+		t.Errorf("second gather of the other registration has stale markers: %v", got)
+	}
+
+	// The marker is only sent once.
+	if got := scrapeStale(idWeb); len(got) != 0 {
+		t.Errorf("third gather has stale markers: %v", got)
+	}
+}
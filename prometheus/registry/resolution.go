@@ -0,0 +1,100 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bleemeo/glouton/types"
+)
+
+const resolutionPurgeInterval = 10 * time.Minute
+
+// resolutionSubsampler drops points of metrics that have a resolution override
+// so that at most one point per override interval is kept for each series.
+type resolutionSubsampler struct {
+	l         sync.Mutex
+	overrides map[string]time.Duration
+	lastKept  map[string]keptPoint
+	lastPurge time.Time
+}
+
+type keptPoint struct {
+	time       time.Time
+	resolution time.Duration
+}
+
+func newResolutionSubsampler(overrides map[string]time.Duration) *resolutionSubsampler {
+	return &resolutionSubsampler{
+		overrides: overrides,
+		lastKept:  make(map[string]keptPoint),
+	}
+}
+
+// Filter returns the points to keep. The points slice is modified in place.
+func (s *resolutionSubsampler) Filter(points []types.MetricPoint) []types.MetricPoint {
+	if s == nil || len(s.overrides) == 0 {
+		return points
+	}
+
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	i := 0
+
+	for _, p := range points {
+		resolution, ok := s.overrides[p.Labels[types.LabelName]]
+		if !ok || resolution <= 0 {
+			points[i] = p
+			i++
+
+			continue
+		}
+
+		key := types.LabelsToText(p.Labels)
+
+		// Allow a small jitter between gathers, otherwise an override equal to a multiple of
+		// the gather interval would only keep one point every two overrides.
+		if last, ok := s.lastKept[key]; ok && p.Time.Sub(last.time) < resolution-resolution/10 {
+			continue
+		}
+
+		s.lastKept[key] = keptPoint{time: p.Time, resolution: resolution}
+		points[i] = p
+		i++
+	}
+
+	s.purge(time.Now())
+
+	return points[:i]
+}
+
+// purge forgets the series which haven't received any point for a while.
+func (s *resolutionSubsampler) purge(now time.Time) {
+	if now.Sub(s.lastPurge) < resolutionPurgeInterval {
+		return
+	}
+
+	s.lastPurge = now
+
+	for key, last := range s.lastKept {
+		if now.Sub(last.time) > resolutionPurgeInterval+last.resolution {
+			delete(s.lastKept, key)
+		}
+	}
+}
@@ -77,7 +77,11 @@ type Target struct {
 	Rules           []types.SimpleRule
 	ExtraLabels     map[string]string
 	ContainerLabels map[string]string
-	mockResponse    []byte
+	// HonorLabels keeps the labels of the scraped metrics when they conflict with ExtraLabels.
+	HonorLabels bool
+	// MetricPrefix is prepended to the name of the scraped metrics.
+	MetricPrefix string
+	mockResponse []byte
 }
 
 func NewMock(content []byte, extraLabels map[string]string) *Target {
@@ -127,7 +131,31 @@ func (t *Target) GatherWithState(ctx context.Context, state registry.GatherState
 		return nil, fmt.Errorf("read from %s: %w", u.String(), err)
 	}
 
-	return parserReader(body, state.HintMetricFilter)
+	if t.MetricPrefix == "" {
+		return parserReader(body, state.HintMetricFilter)
+	}
+
+	// The filter must see the names with the prefix, as they are filtered later.
+	filter := state.HintMetricFilter
+	if filter != nil {
+		filter = func(lbls labels.Labels) bool {
+			builder := labels.NewBuilder(lbls)
+			builder.Set(types.LabelName, t.MetricPrefix+lbls.Get(types.LabelName))
+
+			return state.HintMetricFilter(builder.Labels())
+		}
+	}
+
+	mfs, err := parserReader(body, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mf := range mfs {
+		mf.Name = proto.String(t.MetricPrefix + mf.GetName())
+	}
+
+	return mfs, nil
 }
 
 func (t *Target) readAll(ctx context.Context) ([]byte, error) {
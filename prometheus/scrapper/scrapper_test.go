@@ -18,6 +18,7 @@ package scrapper
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"math"
 	"net/url"
@@ -25,7 +26,9 @@ import (
 	"path/filepath"
 	"sort"
 	"testing"
+	"time"
 
+	"github.com/bleemeo/glouton/prometheus/registry"
 	"github.com/bleemeo/glouton/types"
 
 	dto "github.com/prometheus/client_model/go"
@@ -532,3 +535,40 @@ func Benchmark_parserReader(b *testing.B) {
 		}
 	}
 }
+
+func TestMetricPrefix(t *testing.T) {
+	t.Parallel()
+
+	target := NewMock([]byte("# TYPE requests_total counter\nrequests_total{code=\"200\"} 42\nuptime_seconds 10\n"), nil)
+	target.MetricPrefix = "leaf1_"
+
+	var filteredNames []string
+
+	state := registry.GatherState{
+		T0: time.Now(),
+		HintMetricFilter: func(lbls labels.Labels) bool {
+			filteredNames = append(filteredNames, lbls.Get(types.LabelName))
+
+			return lbls.Get(types.LabelName) == "leaf1_requests_total"
+		},
+	}
+
+	mfs, err := target.GatherWithState(context.Background(), state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mfs) != 1 || mfs[0].GetName() != "leaf1_requests_total" {
+		t.Fatalf("GatherWithState() = %v, want only leaf1_requests_total", mfs)
+	}
+
+	if got := mfs[0].GetMetric()[0].GetCounter().GetValue(); got != 42 {
+		t.Errorf("leaf1_requests_total = %v, want 42", got)
+	}
+
+	sort.Strings(filteredNames)
+
+	if want := []string{"leaf1_requests_total", "leaf1_uptime_seconds"}; fmt.Sprint(filteredNames) != fmt.Sprint(want) {
+		t.Errorf("names seen by the filter = %v, want %v", filteredNames, want)
+	}
+}
@@ -38,12 +38,14 @@ type processProvider interface {
 
 // StatusSource collects process status metrics.
 type StatusSource struct {
-	ps processProvider
+	ps           processProvider
+	countZombies bool
 }
 
 // NewStatusSource initializes a StatusSource.
-func NewStatusSource(ps processProvider) StatusSource {
-	return StatusSource{ps: ps}
+// When countZombies is false, zombie processes aren't counted in process_total.
+func NewStatusSource(ps processProvider, countZombies bool) StatusSource {
+	return StatusSource{ps: ps, countZombies: countZombies}
 }
 
 // CollectWithState sends process metrics to the Appender.
@@ -79,6 +81,10 @@ func (s StatusSource) CollectWithState(ctx context.Context, state registry.Gathe
 			counts["blocked"]++
 		case facts.ProcessStatusZombie:
 			counts["zombies"]++
+
+			if !s.countZombies {
+				continue
+			}
 		case facts.ProcessStatusUnknown:
 			logger.V(2).Printf("Process %v has status unknown, assume sleeping", p)
 
@@ -105,6 +111,15 @@ func (s StatusSource) CollectWithState(ctx context.Context, state registry.Gathe
 				Value: float64(total),
 			},
 		},
+		{
+			Labels: map[string]string{
+				types.LabelName: "system_zombie_processes",
+			},
+			Point: types.Point{
+				Time:  now,
+				Value: float64(counts["zombies"]),
+			},
+		},
 		{
 			Labels: map[string]string{
 				types.LabelName: "process_total_threads",
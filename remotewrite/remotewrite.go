@@ -0,0 +1,286 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package remotewrite sends the metrics to a Prometheus remote_write endpoint.
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/bleemeo/glouton/logger"
+	"github.com/bleemeo/glouton/mqtt"
+	"github.com/bleemeo/glouton/types"
+	"github.com/bleemeo/glouton/utils/pointsbuffer"
+	"github.com/bleemeo/glouton/version"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const (
+	defaultMaxPendingPoints = 100000
+	defaultBatchSize        = 1000
+
+	// Delays between two attempts to send the points when the server isn't reachable.
+	minRetryDelay = 10 * time.Second
+	maxRetryDelay = 5 * time.Minute
+)
+
+//nolint:gochecknoglobals
+var pendingPointsDesc = prometheus.NewDesc(
+	"glouton_remote_write_pending_points",
+	"Number of points waiting to be sent to the remote_write endpoint",
+	nil,
+	nil,
+)
+
+var (
+	errServer   = errors.New("server error")
+	errRejected = errors.New("points rejected")
+)
+
+// Store is the interface used by the client to access the Metric Store.
+type Store interface {
+	AddNotifiee(cb func([]types.MetricPoint)) int
+	RemoveNotifiee(id int)
+}
+
+// Options are the options of the remote_write client.
+type Options struct {
+	URL      string
+	Username string
+	Password string
+	// Headers are added to each request.
+	Headers     map[string]string
+	CAFile      string
+	SSLInsecure bool
+	// MaxPendingPoints is the number of points kept while the endpoint is unreachable.
+	// The oldest points are dropped when the buffer is full.
+	MaxPendingPoints int
+}
+
+// Client sends the points of the store to a Prometheus remote_write endpoint.
+type Client struct {
+	opts         Options
+	store        Store
+	httpClient   *http.Client
+	buffer       *pointsbuffer.Buffer
+	maxBatchSize int
+
+	lastErr error
+}
+
+// New returns a remote_write client.
+func New(opts Options, store Store) *Client {
+	maxPendingPoints := opts.MaxPendingPoints
+	if maxPendingPoints <= 0 {
+		maxPendingPoints = defaultMaxPendingPoints
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+	transport.TLSClientConfig = mqtt.TLSConfig(opts.SSLInsecure, opts.CAFile)
+
+	return &Client{
+		opts:  opts,
+		store: store,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   30 * time.Second,
+		},
+		buffer:       pointsbuffer.New("remote_write", maxPendingPoints),
+		maxBatchSize: defaultBatchSize,
+	}
+}
+
+// encodeWriteRequest converts the points to a snappy-compressed WriteRequest.
+// The points with the same labels are grouped in a single time series.
+func encodeWriteRequest(points []types.MetricPoint) ([]byte, error) {
+	seriesByKey := make(map[string]*prompb.TimeSeries)
+	keys := make([]string, 0)
+
+	for _, point := range points {
+		key := types.LabelsToText(point.Labels)
+
+		series, ok := seriesByKey[key]
+		if !ok {
+			series = &prompb.TimeSeries{Labels: convertLabels(point.Labels)}
+			seriesByKey[key] = series
+			keys = append(keys, key)
+		}
+
+		series.Samples = append(series.Samples, prompb.Sample{
+			Value:     point.Value,
+			Timestamp: point.Time.UnixMilli(),
+		})
+	}
+
+	request := prompb.WriteRequest{
+		Timeseries: make([]prompb.TimeSeries, 0, len(keys)),
+	}
+
+	for _, key := range keys {
+		request.Timeseries = append(request.Timeseries, *seriesByKey[key])
+	}
+
+	data, err := request.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	return snappy.Encode(nil, data), nil
+}
+
+// convertLabels returns the labels sorted by name, as required by remote_write.
+func convertLabels(lbls map[string]string) []prompb.Label {
+	result := make([]prompb.Label, 0, len(lbls))
+
+	for name, value := range lbls {
+		result = append(result, prompb.Label{Name: name, Value: value})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// send sends a batch of points. It returns errRejected when the endpoint refused
+// the points, retrying the same batch won't succeed.
+func (c *Client) send(ctx context.Context, points []types.MetricPoint) error {
+	body, err := encodeWriteRequest(points)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errRejected, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "Glouton "+version.Version)
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	for name, value := range c.opts.Headers {
+		req.Header.Set(name, value)
+	}
+
+	if c.opts.Username != "" {
+		req.SetBasicAuth(c.opts.Username, c.opts.Password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	switch {
+	case resp.StatusCode/100 == 2:
+		return nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %s: %s", errServer, resp.Status, bytes.TrimSpace(message))
+	default:
+		return fmt.Errorf("%w: %s: %s", errRejected, resp.Status, bytes.TrimSpace(message))
+	}
+}
+
+// sendPending sends the pending points by batches. It returns false when
+// a batch failed to be sent and must be retried later.
+func (c *Client) sendPending(ctx context.Context) bool {
+	for ctx.Err() == nil && c.buffer.Len() > 0 {
+		batch := c.buffer.Batch(c.maxBatchSize)
+
+		err := c.send(ctx, batch.Points)
+		if errors.Is(err, errRejected) {
+			logger.V(1).Printf("The remote_write endpoint rejected %d points: %v", len(batch.Points), err)
+
+			err = nil
+		}
+
+		if err != nil {
+			if c.lastErr == nil {
+				logger.Printf("Fail to send the metrics to the remote_write endpoint: %v", err)
+			} else {
+				logger.V(2).Printf("Fail to send the metrics to the remote_write endpoint: %v", err)
+			}
+
+			c.lastErr = err
+
+			return false
+		}
+
+		if c.lastErr != nil {
+			logger.Printf("The remote_write endpoint is reachable again")
+
+			c.lastErr = nil
+		}
+
+		c.buffer.Remove(batch)
+	}
+
+	return true
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *Client) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pendingPointsDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *Client) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(pendingPointsDesc, prometheus.GaugeValue, float64(c.buffer.Len()))
+}
+
+// Run sends the points of the store until the context is cancelled.
+func (c *Client) Run(ctx context.Context) error {
+	notifieeID := c.store.AddNotifiee(c.buffer.Add)
+	defer c.store.RemoveNotifiee(notifieeID)
+
+	retryDelay := minRetryDelay
+
+	for ctx.Err() == nil {
+		delay := minRetryDelay
+
+		if c.sendPending(ctx) {
+			retryDelay = minRetryDelay
+		} else {
+			// Exponential backoff while the endpoint is unreachable.
+			delay = retryDelay
+			retryDelay = min(2*retryDelay, maxRetryDelay)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+	}
+
+	return nil
+}
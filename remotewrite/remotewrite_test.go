@@ -0,0 +1,209 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remotewrite
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bleemeo/glouton/types"
+
+	"github.com/golang/snappy"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// fakeEndpoint is a remote_write endpoint which records the requests received.
+type fakeEndpoint struct {
+	l          sync.Mutex
+	statusCode int
+	requests   []prompb.WriteRequest
+	headers    []http.Header
+}
+
+func (e *fakeEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.l.Lock()
+	defer e.l.Unlock()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	data, err := snappy.Decode(nil, body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	var request prompb.WriteRequest
+
+	if err := request.Unmarshal(data); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	e.requests = append(e.requests, request)
+	e.headers = append(e.headers, r.Header.Clone())
+
+	if e.statusCode != 0 {
+		w.WriteHeader(e.statusCode)
+	}
+}
+
+func TestSendPending(t *testing.T) {
+	t.Parallel()
+
+	endpoint := &fakeEndpoint{}
+
+	srv := httptest.NewServer(endpoint)
+	t.Cleanup(srv.Close)
+
+	client := New(Options{
+		URL:      srv.URL,
+		Username: "user",
+		Password: "pass",
+		Headers:  map[string]string{"X-Scope-OrgID": "tenant"},
+	}, nil)
+	client.maxBatchSize = 2
+
+	t0 := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+
+	client.buffer.Add([]types.MetricPoint{
+		{Point: types.Point{Time: t0, Value: 1}, Labels: map[string]string{types.LabelName: "cpu_used", "instance": "server"}},
+		{Point: types.Point{Time: t0, Value: 2}, Labels: map[string]string{types.LabelName: "mem_used", "instance": "server"}},
+		{Point: types.Point{Time: t0.Add(10 * time.Second), Value: 3}, Labels: map[string]string{types.LabelName: "cpu_used", "instance": "server"}},
+	})
+
+	if !client.sendPending(context.Background()) {
+		t.Fatal("sendPending() failed")
+	}
+
+	if n := client.buffer.Len(); n != 0 {
+		t.Errorf("buffer.Len() = %d, want 0", n)
+	}
+
+	endpoint.l.Lock()
+	defer endpoint.l.Unlock()
+
+	want := []prompb.WriteRequest{
+		{
+			Timeseries: []prompb.TimeSeries{
+				{
+					Labels:  []prompb.Label{{Name: types.LabelName, Value: "cpu_used"}, {Name: "instance", Value: "server"}},
+					Samples: []prompb.Sample{{Value: 1, Timestamp: t0.UnixMilli()}},
+				},
+				{
+					Labels:  []prompb.Label{{Name: types.LabelName, Value: "mem_used"}, {Name: "instance", Value: "server"}},
+					Samples: []prompb.Sample{{Value: 2, Timestamp: t0.UnixMilli()}},
+				},
+			},
+		},
+		{
+			Timeseries: []prompb.TimeSeries{
+				{
+					Labels:  []prompb.Label{{Name: types.LabelName, Value: "cpu_used"}, {Name: "instance", Value: "server"}},
+					Samples: []prompb.Sample{{Value: 3, Timestamp: t0.Add(10 * time.Second).UnixMilli()}},
+				},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(want, endpoint.requests); diff != "" {
+		t.Errorf("requests mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, header := range endpoint.headers {
+		if got := header.Get("Content-Encoding"); got != "snappy" {
+			t.Errorf("Content-Encoding = %q, want snappy", got)
+		}
+
+		if got := header.Get("X-Scope-OrgID"); got != "tenant" {
+			t.Errorf("X-Scope-OrgID = %q, want tenant", got)
+		}
+
+		if got := header.Get("Authorization"); got != "Basic dXNlcjpwYXNz" {
+			t.Errorf("Authorization = %q, want basic auth", got)
+		}
+	}
+}
+
+func TestSendPendingErrors(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name        string
+		statusCode  int
+		wantOK      bool
+		wantPending int
+	}{
+		{name: "server error is retried", statusCode: http.StatusServiceUnavailable, wantOK: false, wantPending: 1},
+		{name: "too many requests is retried", statusCode: http.StatusTooManyRequests, wantOK: false, wantPending: 1},
+		{name: "rejected points are dropped", statusCode: http.StatusBadRequest, wantOK: true, wantPending: 0},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(&fakeEndpoint{statusCode: tt.statusCode})
+			t.Cleanup(srv.Close)
+
+			client := New(Options{URL: srv.URL}, nil)
+			client.buffer.Add([]types.MetricPoint{
+				{Point: types.Point{Time: time.Now(), Value: 1}, Labels: map[string]string{types.LabelName: "cpu_used"}},
+			})
+
+			if ok := client.sendPending(context.Background()); ok != tt.wantOK {
+				t.Errorf("sendPending() = %v, want %v", ok, tt.wantOK)
+			}
+
+			if n := client.buffer.Len(); n != tt.wantPending {
+				t.Errorf("buffer.Len() = %d, want %d", n, tt.wantPending)
+			}
+		})
+	}
+}
+
+func TestAddPointsDropOldest(t *testing.T) {
+	t.Parallel()
+
+	client := New(Options{MaxPendingPoints: 2}, nil)
+
+	for i := range 3 {
+		client.buffer.Add([]types.MetricPoint{
+			{Point: types.Point{Value: float64(i)}, Labels: map[string]string{types.LabelName: "cpu_used"}},
+		})
+	}
+
+	if n := client.buffer.Len(); n != 2 {
+		t.Fatalf("buffer.Len() = %d, want 2", n)
+	}
+
+	if got := client.buffer.Batch(1).Points[0].Value; got != 1 {
+		t.Errorf("oldest point value = %v, want 1", got)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bleemeo/glouton/types"
+)
+
+// maxBatchPoints is the number of pending points after which a batch is
+// pushed without waiting for the end of the window.
+const maxBatchPoints = 10000
+
+// BatchPusher coalesces the points pushed during a short window into a single
+// call to the wrapped PushPoints. This reduces the lock contention on the store
+// when many gatherers push points at the same time. Each point keeps its own
+// timestamp, only the time it's written to the store is delayed.
+type BatchPusher struct {
+	pusher types.PointPusher
+	window time.Duration
+
+	// pushLock ensures batches are pushed in the order they were taken.
+	pushLock sync.Mutex
+	l        sync.Mutex
+	pending  []types.MetricPoint
+	timer    *time.Timer
+}
+
+// NewBatchPusher returns a BatchPusher writing to pusher. When window is zero,
+// points are pushed immediately.
+func NewBatchPusher(pusher types.PointPusher, window time.Duration) *BatchPusher {
+	return &BatchPusher{
+		pusher: pusher,
+		window: window,
+	}
+}
+
+// PushPoints adds the points to the current batch.
+func (b *BatchPusher) PushPoints(ctx context.Context, points []types.MetricPoint) {
+	if b.window <= 0 {
+		b.pusher.PushPoints(ctx, points)
+
+		return
+	}
+
+	if len(points) == 0 {
+		return
+	}
+
+	b.l.Lock()
+
+	b.pending = append(b.pending, points...)
+
+	if len(b.pending) >= maxBatchPoints {
+		b.l.Unlock()
+		b.flush(ctx)
+
+		return
+	}
+
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.window, b.Flush)
+	}
+
+	b.l.Unlock()
+}
+
+// Run waits for the context to be cancelled and pushes the pending points.
+func (b *BatchPusher) Run(ctx context.Context) error {
+	<-ctx.Done()
+
+	b.Flush()
+
+	return nil
+}
+
+// Flush pushes the pending points immediately.
+func (b *BatchPusher) Flush() {
+	b.flush(context.Background())
+}
+
+func (b *BatchPusher) flush(ctx context.Context) {
+	b.pushLock.Lock()
+	defer b.pushLock.Unlock()
+
+	b.l.Lock()
+	batch := b.takeBatch()
+	b.l.Unlock()
+
+	if len(batch) > 0 {
+		b.pusher.PushPoints(ctx, batch)
+	}
+}
+
+// takeBatch returns the pending points and stops the flush timer.
+// The lock must be held.
+func (b *BatchPusher) takeBatch() []types.MetricPoint {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	batch := b.pending
+	b.pending = nil
+
+	return batch
+}
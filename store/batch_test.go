@@ -0,0 +1,190 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bleemeo/glouton/types"
+)
+
+// countingPusher records the number of PushPoints calls and the pushed points.
+type countingPusher struct {
+	l      sync.Mutex
+	calls  int
+	points []types.MetricPoint
+}
+
+func (p *countingPusher) PushPoints(_ context.Context, points []types.MetricPoint) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	p.calls++
+	p.points = append(p.points, points...)
+}
+
+func (p *countingPusher) result() (int, []types.MetricPoint) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	return p.calls, p.points
+}
+
+func TestBatchPusher(t *testing.T) {
+	t.Parallel()
+
+	pusher := &countingPusher{}
+	batch := NewBatchPusher(pusher, time.Hour)
+	t0 := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+
+	for i := range 5 {
+		batch.PushPoints(context.Background(), []types.MetricPoint{
+			{
+				Point:  types.Point{Time: t0.Add(time.Duration(i) * time.Second), Value: float64(i)},
+				Labels: map[string]string{types.LabelName: "metric"},
+			},
+		})
+	}
+
+	if calls, _ := pusher.result(); calls != 0 {
+		t.Fatalf("got %d calls before the end of the window, want 0", calls)
+	}
+
+	batch.Flush()
+
+	calls, points := pusher.result()
+	if calls != 1 {
+		t.Fatalf("got %d calls, want 1", calls)
+	}
+
+	if len(points) != 5 {
+		t.Fatalf("got %d points, want 5", len(points))
+	}
+
+	for i, point := range points {
+		if want := t0.Add(time.Duration(i) * time.Second); !point.Time.Equal(want) {
+			t.Errorf("point %d time = %s, want %s", i, point.Time, want)
+		}
+	}
+}
+
+func TestBatchPusherWindow(t *testing.T) {
+	t.Parallel()
+
+	pusher := &countingPusher{}
+	batch := NewBatchPusher(pusher, 10*time.Millisecond)
+
+	batch.PushPoints(context.Background(), []types.MetricPoint{{Labels: map[string]string{types.LabelName: "metric"}}})
+	batch.PushPoints(context.Background(), []types.MetricPoint{{Labels: map[string]string{types.LabelName: "metric2"}}})
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		if _, points := pusher.result(); len(points) == 2 {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	if calls, points := pusher.result(); calls != 1 || len(points) != 2 {
+		t.Errorf("got %d calls with %d points, want 1 call with 2 points", calls, len(points))
+	}
+}
+
+func TestBatchPusherMaxPoints(t *testing.T) {
+	t.Parallel()
+
+	pusher := &countingPusher{}
+	batch := NewBatchPusher(pusher, time.Hour)
+
+	batch.PushPoints(context.Background(), make([]types.MetricPoint, maxBatchPoints))
+
+	if calls, points := pusher.result(); calls != 1 || len(points) != maxBatchPoints {
+		t.Errorf("got %d calls with %d points, want 1 call with %d points", calls, len(points), maxBatchPoints)
+	}
+}
+
+// storeCallCounter counts the PushPoints calls to the store, each of them
+// taking the store locks.
+type storeCallCounter struct {
+	store *Store
+	calls atomic.Int64
+}
+
+func (c *storeCallCounter) PushPoints(ctx context.Context, points []types.MetricPoint) {
+	c.calls.Add(1)
+	c.store.PushPoints(ctx, points)
+}
+
+// BenchmarkPushPointsContention pushes one point per call from parallel
+// gatherers, directly to the store and through a BatchPusher. The store-calls/op
+// metric is the number of times the store locks are taken per point.
+func BenchmarkPushPointsContention(b *testing.B) {
+	tests := []struct {
+		name   string
+		window time.Duration
+	}{
+		{name: "direct"},
+		{name: "batch-10ms", window: 10 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		b.Run(tt.name, func(b *testing.B) {
+			store := New(time.Hour, time.Hour)
+			store.AddNotifiee(func([]types.MetricPoint) {})
+
+			counter := &storeCallCounter{store: store}
+			pusher := NewBatchPusher(counter, tt.window)
+			t0 := time.Now()
+
+			labelsList := make([]map[string]string, 1000)
+			for i := range labelsList {
+				labelsList[i] = map[string]string{
+					types.LabelName: "metric_" + strconv.Itoa(i),
+					types.LabelItem: "item",
+				}
+			}
+
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+
+				for pb.Next() {
+					pusher.PushPoints(context.Background(), []types.MetricPoint{
+						{
+							Point:  types.Point{Time: t0.Add(time.Duration(i) * time.Millisecond), Value: float64(i)},
+							Labels: labelsList[i%len(labelsList)],
+						},
+					})
+
+					i++
+				}
+			})
+
+			pusher.Flush()
+
+			b.ReportMetric(float64(counter.calls.Load())/float64(b.N), "store-calls/op")
+		})
+	}
+}
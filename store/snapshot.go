@@ -0,0 +1,190 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bleemeo/glouton/types"
+)
+
+// snapshotVersion is incremented when the snapshot format changes in an incompatible way.
+const snapshotVersion = 1
+
+var errSnapshotVersion = errors.New("unsupported snapshot version")
+
+type snapshot struct {
+	Version int
+	Metrics []snapshotMetric
+}
+
+type snapshotMetric struct {
+	Labels      map[string]string
+	Annotations types.MetricAnnotations
+	Points      []types.Point
+}
+
+// SetSnapshotFile sets the file where the store is saved by Run and reloaded by Load.
+// The snapshot is disabled when the path is empty.
+func (s *Store) SetSnapshotFile(path string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.snapshotFile = path
+}
+
+// Load reloads the metrics saved in the snapshot file. The points older than
+// the maximum age of the points are dropped. A missing snapshot isn't an error.
+// The store is unchanged when the snapshot can't be read.
+func (s *Store) Load() error {
+	s.lock.Lock()
+	path := s.snapshotFile
+	s.lock.Unlock()
+
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	var snap snapshot
+
+	if err := gob.NewDecoder(file).Decode(&snap); err != nil {
+		return fmt.Errorf("corrupted snapshot %s: %w", path, err)
+	}
+
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("%w: %d", errSnapshotVersion, snap.Version)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.nowFunc()
+
+	for _, m := range snap.Metrics {
+		points := make([]types.Point, 0, len(m.Points))
+
+		for _, p := range m.Points {
+			if now.Sub(p.Time) < s.maxPointsAge {
+				points = append(points, p)
+			}
+		}
+
+		if len(points) == 0 {
+			continue
+		}
+
+		metric, found, _ := s.metricGet(m.Labels, m.Annotations)
+		if found {
+			// Points were already received for this metric, keep them.
+			continue
+		}
+
+		metric.lastPoint = points[len(points)-1].Time
+		s.metrics[metric.metricID] = metric
+
+		if err := s.points.setPoints(metric.metricID, points); err != nil {
+			delete(s.metrics, metric.metricID)
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteSnapshot saves the metrics and their points in the snapshot file.
+// The file is replaced atomically, a failed write leaves the previous snapshot.
+func (s *Store) WriteSnapshot() error {
+	s.lock.Lock()
+
+	path := s.snapshotFile
+	if path == "" {
+		s.lock.Unlock()
+
+		return nil
+	}
+
+	snap := snapshot{
+		Version: snapshotVersion,
+		Metrics: make([]snapshotMetric, 0, len(s.metrics)),
+	}
+
+	for metricID, m := range s.metrics {
+		points, err := s.points.getPoints(metricID)
+		if err != nil || len(points) == 0 {
+			continue
+		}
+
+		snap.Metrics = append(snap.Metrics, snapshotMetric{
+			Labels:      m.labels,
+			Annotations: m.annotations,
+			Points:      points,
+		})
+	}
+
+	s.lock.Unlock()
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	err = writeSnapshot(file, snap)
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+
+	if err != nil {
+		_ = os.Remove(file.Name())
+
+		return err
+	}
+
+	return nil
+}
+
+// writeSnapshot encodes the snapshot in the file and closes it.
+func writeSnapshot(file *os.File, snap snapshot) error {
+	if err := gob.NewEncoder(file).Encode(snap); err != nil {
+		_ = file.Close()
+
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+
+		return err
+	}
+
+	return file.Close()
+}
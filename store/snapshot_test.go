@@ -0,0 +1,139 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bleemeo/glouton/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "store.snapshot")
+	t0 := time.Now().Truncate(time.Second)
+
+	source := New(time.Hour, 2*time.Hour)
+	source.SetSnapshotFile(path)
+	source.PushPoints(context.Background(), []types.MetricPoint{
+		{
+			Point:       types.Point{Time: t0.Add(-90 * time.Minute), Value: 1},
+			Labels:      map[string]string{types.LabelName: "cpu_used"},
+			Annotations: types.MetricAnnotations{BleemeoItem: "item"},
+		},
+		{
+			Point:  types.Point{Time: t0.Add(-10 * time.Minute), Value: 2},
+			Labels: map[string]string{types.LabelName: "cpu_used"},
+		},
+		{
+			Point:  types.Point{Time: t0.Add(-2 * time.Hour), Value: 3},
+			Labels: map[string]string{types.LabelName: "old_metric"},
+		},
+	})
+
+	if err := source.WriteSnapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Errorf("the directory contains %d files, want only the snapshot", len(entries))
+	}
+
+	loaded := New(time.Hour, 2*time.Hour)
+	loaded.SetSnapshotFile(path)
+
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	metrics, err := loaded.Metrics(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The points older than the maximum age are dropped, old_metric has no point left.
+	if len(metrics) != 1 {
+		t.Fatalf("got %d metrics, want 1", len(metrics))
+	}
+
+	points, err := metrics[0].Points(t0.Add(-3*time.Hour), t0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []types.Point{{Time: t0.Add(-10 * time.Minute), Value: 2}}
+	if diff := cmp.Diff(want, points, timeComparer); diff != "" {
+		t.Errorf("points mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := metrics[0].LastPointReceivedAt(); !got.Equal(t0.Add(-10 * time.Minute)) {
+		t.Errorf("LastPointReceivedAt() = %v, want %v", got, t0.Add(-10*time.Minute))
+	}
+}
+
+func TestSnapshotCorrupted(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "store.snapshot")
+
+	if err := os.WriteFile(path, []byte("not a snapshot"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(time.Hour, 2*time.Hour)
+	s.SetSnapshotFile(path)
+
+	if err := s.Load(); err == nil {
+		t.Error("Load() succeeded on a corrupted snapshot")
+	}
+
+	if count := s.MetricsCount(); count != 0 {
+		t.Errorf("MetricsCount() = %d, want 0", count)
+	}
+
+	// The corrupted snapshot is replaced by the next write.
+	if err := s.WriteSnapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Load(); err != nil {
+		t.Errorf("Load() failed after a new snapshot was written: %v", err)
+	}
+}
+
+func TestSnapshotMissing(t *testing.T) {
+	t.Parallel()
+
+	s := New(time.Hour, 2*time.Hour)
+	s.SetSnapshotFile(filepath.Join(t.TempDir(), "missing"))
+
+	if err := s.Load(); err != nil {
+		t.Errorf("Load() = %v, want nil", err)
+	}
+}
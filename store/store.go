@@ -16,7 +16,8 @@
 
 // Package store implement a Metric/MetricPoint store.
 //
-// currently the storage in only in-memory and not persisted.
+// The storage is in-memory, it could be periodically saved to a snapshot file
+// which is reloaded at startup.
 package store
 
 import (
@@ -49,6 +50,7 @@ type Store struct {
 	maxPointsAge         time.Duration
 	maxMetricsAge        time.Duration
 	lastAnnotationChange time.Time
+	snapshotFile         string
 	workLabels           labels.Labels
 	lock                 sync.Mutex
 	notifeeLock          sync.Mutex
@@ -119,18 +121,28 @@ func (s *Store) LastAnnotationChange() time.Time {
 }
 
 // Run will run the store until context is cancelled.
+// When a snapshot file is set, the store is saved after each purge and on stop.
 func (s *Store) Run(ctx context.Context) error {
 	for {
 		s.RunOnce()
+		s.writeSnapshotAndLog()
 
 		select {
 		case <-time.After(300 * time.Second):
 		case <-ctx.Done():
+			s.writeSnapshotAndLog()
+
 			return nil
 		}
 	}
 }
 
+func (s *Store) writeSnapshotAndLog() {
+	if err := s.WriteSnapshot(); err != nil {
+		logger.V(1).Printf("Store: failed to write the snapshot: %v", err)
+	}
+}
+
 // RunOnce runs the store once to remove old points and metrics.
 func (s *Store) RunOnce() {
 	s.run(s.nowFunc())
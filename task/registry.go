@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"sync"
 
 	"github.com/bleemeo/glouton/crashreport"
@@ -33,6 +34,29 @@ var errAlreadyClosed = errors.New("registry already closed")
 // Runner is something that can be Run.
 type Runner func(context.Context) error
 
+// Priority of a task. Tasks with a higher priority are started first and stopped last.
+type Priority int
+
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	// PriorityHigh is used by critical tasks, like the metric store or the MQTT connector.
+	PriorityHigh Priority = 1
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	default:
+		return strconv.Itoa(int(p))
+	}
+}
+
 // Registry contains running tasks. It allow to add/remove tasks.
 type Registry struct {
 	ctx    context.Context //nolint:containedctx
@@ -45,7 +69,10 @@ type Registry struct {
 type taskInfo struct {
 	Runner     Runner
 	Name       string
+	Priority   Priority
 	CancelFunc func()
+	// Done is closed when the task exited.
+	Done chan interface{}
 
 	l         sync.Mutex
 	Running   bool
@@ -72,20 +99,16 @@ func (r *Registry) DiagnosticArchive(_ context.Context, archive types.ArchiveWri
 	r.l.Lock()
 	defer r.l.Unlock()
 
-	ids := make([]int, 0, len(r.tasks))
-
-	for id := range r.tasks {
-		ids = append(ids, id)
-	}
-
-	sort.Ints(ids)
-
-	for _, id := range ids {
+	for _, id := range r.idsByPriority() {
 		ti := r.tasks[id]
 
 		ti.l.Lock()
 
-		fmt.Fprintf(file, "task id=%d: name=%s running=%v exitErr=%v\n", id, ti.Name, ti.Running, ti.ExitError)
+		fmt.Fprintf(
+			file,
+			"task id=%d: name=%s priority=%s running=%v exitErr=%v\n",
+			id, ti.Name, ti.Priority, ti.Running, ti.ExitError,
+		)
 
 		ti.l.Unlock()
 	}
@@ -93,15 +116,64 @@ func (r *Registry) DiagnosticArchive(_ context.Context, archive types.ArchiveWri
 	return nil
 }
 
+// idsByPriority returns the task IDs sorted by decreasing priority, then by ID.
+// The lock must be held.
+func (r *Registry) idsByPriority() []int {
+	ids := make([]int, 0, len(r.tasks))
+
+	for id := range r.tasks {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		pi, pj := r.tasks[ids[i]].Priority, r.tasks[ids[j]].Priority
+		if pi != pj {
+			return pi > pj
+		}
+
+		return ids[i] < ids[j]
+	})
+
+	return ids
+}
+
 // Close stops and wait for all currently running tasks.
+// Tasks are stopped by increasing priority, so critical tasks are stopped last.
+// All tasks with the same priority are stopped concurrently.
 func (r *Registry) Close() {
 	r.close()
-	r.cancel()
 
-	for k := range r.tasks {
-		r.removeTask(k, true)
+	r.l.Lock()
+	ids := r.idsByPriority()
+	tasks := make([]*taskInfo, len(ids))
+
+	for i, id := range ids {
+		tasks[i] = r.tasks[id]
 	}
 
+	r.l.Unlock()
+
+	for end := len(tasks); end > 0; {
+		start := end - 1
+		for start > 0 && tasks[start-1].Priority == tasks[end-1].Priority {
+			start--
+		}
+
+		group := tasks[start:end]
+
+		for _, task := range group {
+			task.CancelFunc()
+		}
+
+		for _, task := range group {
+			<-task.Done
+		}
+
+		end = start
+	}
+
+	r.cancel()
+
 	r.l.Lock()
 	defer r.l.Unlock()
 
@@ -115,8 +187,15 @@ func (r *Registry) close() {
 	r.closed = true
 }
 
-// AddTask add and start a new task. It return an taskID that could be used in RemoveTask.
+// AddTask add and start a new task with a normal priority.
+// It return an taskID that could be used in RemoveTask.
 func (r *Registry) AddTask(task Runner, shortName string) (int, error) {
+	return r.AddTaskWithPriority(task, shortName, PriorityNormal)
+}
+
+// AddTaskWithPriority add and start a new task with given priority.
+// It return an taskID that could be used in RemoveTask.
+func (r *Registry) AddTaskWithPriority(task Runner, shortName string, priority Priority) (int, error) {
 	r.l.Lock()
 	defer r.l.Unlock()
 
@@ -138,14 +217,12 @@ func (r *Registry) AddTask(task Runner, shortName string) (int, error) {
 
 	ctx, cancel := context.WithCancel(r.ctx)
 	waitC := make(chan interface{})
-	cancelWait := func() {
-		cancel()
-		<-waitC
-	}
 	ti := &taskInfo{
-		CancelFunc: cancelWait,
+		CancelFunc: cancel,
+		Done:       waitC,
 		Runner:     task,
 		Name:       shortName,
+		Priority:   priority,
 		Running:    true,
 	}
 
@@ -179,7 +256,7 @@ func (r *Registry) RemoveTask(taskID int) {
 		return
 	}
 
-	r.removeTask(taskID, false)
+	r.removeTask(taskID)
 }
 
 // IsRunning return true if the taskID is still running.
@@ -199,14 +276,13 @@ func (r *Registry) IsRunning(taskID int) (bool, error) {
 	return task.Running, task.ExitError
 }
 
-func (r *Registry) removeTask(taskID int, forClosing bool) {
+func (r *Registry) removeTask(taskID int) {
 	if task, ok := r.tasks[taskID]; ok {
 		task.CancelFunc()
+		<-task.Done
 	} else {
 		logger.V(2).Printf("called RemoveTask with unexisting ID %d", taskID)
 	}
 
-	if !forClosing {
-		delete(r.tasks, taskID)
-	}
+	delete(r.tasks, taskID)
 }
@@ -0,0 +1,129 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestCloseOrder checks that the tasks are stopped by increasing priority,
+// and that the tasks with the same priority are stopped concurrently.
+func TestCloseOrder(t *testing.T) {
+	t.Parallel()
+
+	registry := NewRegistry(context.Background())
+
+	var (
+		l       sync.Mutex
+		started []string
+		stopped []string
+	)
+
+	// bothCancelled is closed once the two normal tasks were cancelled. Each normal
+	// task waits for it before exiting, so Close would block forever if it waited
+	// for a task before cancelling the other one.
+	bothCancelled := make(chan struct{})
+
+	var cancelled sync.WaitGroup
+
+	cancelled.Add(2)
+
+	go func() {
+		cancelled.Wait()
+		close(bothCancelled)
+	}()
+
+	newTask := func(name string, waitOther bool) Runner {
+		return func(ctx context.Context) error {
+			l.Lock()
+			started = append(started, name)
+			l.Unlock()
+
+			<-ctx.Done()
+
+			if waitOther {
+				cancelled.Done()
+
+				select {
+				case <-bothCancelled:
+				case <-time.After(5 * time.Second):
+					t.Errorf("task %s was stopped before the other tasks with the same priority were cancelled", name)
+				}
+			}
+
+			l.Lock()
+			stopped = append(stopped, name)
+			l.Unlock()
+
+			return nil
+		}
+	}
+
+	tasks := []struct {
+		name      string
+		priority  Priority
+		waitOther bool
+	}{
+		{name: "store", priority: PriorityHigh},
+		{name: "discovery", priority: PriorityNormal, waitOther: true},
+		{name: "facts", priority: PriorityNormal, waitOther: true},
+		{name: "updates", priority: PriorityLow},
+	}
+
+	for i, task := range tasks {
+		if _, err := registry.AddTaskWithPriority(newTask(task.name, task.waitOther), task.name, task.priority); err != nil {
+			t.Fatal(err)
+		}
+
+		// Wait for the task to start, so the start order is deterministic.
+		for {
+			l.Lock()
+			n := len(started)
+			l.Unlock()
+
+			if n > i {
+				break
+			}
+
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	registry.Close()
+
+	wantStarted := []string{"store", "discovery", "facts", "updates"}
+	if diff := cmp.Diff(wantStarted, started); diff != "" {
+		t.Errorf("start order mismatch (-want +got):\n%s", diff)
+	}
+
+	if len(stopped) != 4 {
+		t.Fatalf("stopped = %v, want 4 tasks", stopped)
+	}
+
+	if stopped[0] != "updates" || stopped[3] != "store" {
+		t.Errorf("stopped = %v, want updates first and store last", stopped)
+	}
+
+	if _, err := registry.AddTask(newTask("late", false), "late"); err == nil {
+		t.Error("AddTask succeeded on a closed registry")
+	}
+}
@@ -36,6 +36,10 @@ const (
 	statusCacheKey     = "CacheStatusState"
 	statusMetricSuffix = "_status"
 	statesTTL          = 25 * time.Hour // some metrics are send once per day (like system_pending_security_updates)
+	// referenceMaxAge is the maximum age of the reference point of a percentage threshold.
+	referenceMaxAge = 10 * time.Minute
+	// UnitPercent is the config unit of thresholds relative to a reference metric.
+	UnitPercent = "%"
 )
 
 // State store information about current firing threshold.
@@ -44,10 +48,16 @@ type State interface {
 	Set(key string, object interface{}) error
 }
 
+// MetricStore gives the reference points of the percentage thresholds.
+type MetricStore interface {
+	Metrics(filters map[string]string) (result []types.Metric, err error)
+}
+
 // Registry keep track of threshold states to update metrics if the exceed a threshold for a period
 // Use WithPusher() to create a pusher and sent metric points to it.
 type Registry struct {
 	state State
+	store MetricStore
 
 	agentID string
 
@@ -154,10 +164,20 @@ func (r *Registry) SetUnits(units map[string]Unit) {
 	logger.V(2).Printf("Units contains %d definitions", len(units))
 }
 
+// SetStore sets the store used to read the reference points of the percentage thresholds.
+func (r *Registry) SetStore(store MetricStore) {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	r.store = store
+}
+
 type statusState struct {
 	CurrentStatus types.Status
 	CriticalSince time.Time
 	WarningSince  time.Time
+	// RecoverySince is the time since the value is below the threshold of the current status.
+	RecoverySince time.Time
 	LastUpdate    time.Time
 }
 
@@ -166,9 +186,12 @@ type jsonState struct {
 	statusState
 }
 
+// Update returns the state with the new soft status. The status is upgraded when the
+// value exceeded the threshold for the warning or critical delay, and downgraded when
+// it stayed below the threshold for the recovery delay.
 func (s statusState) Update(
 	newStatus types.Status,
-	warningDelay, criticalDelay time.Duration,
+	warningDelay, criticalDelay, recoveryDelay time.Duration,
 	now time.Time,
 ) statusState {
 	if s.CurrentStatus == types.StatusUnset {
@@ -184,21 +207,43 @@ func (s statusState) Update(
 		s.WarningSince = time.Time{}
 	}
 
+	if s.RecoverySince.After(now) {
+		s.RecoverySince = time.Time{}
+	}
+
 	criticalDuration, warningDuration := s.setNewStatus(newStatus, now)
 
+	recovered := true
+
+	if newStatus.NagiosCode() < s.CurrentStatus.NagiosCode() && recoveryDelay > 0 {
+		if s.RecoverySince.IsZero() {
+			s.RecoverySince = now
+		}
+
+		recovered = now.Sub(s.RecoverySince) >= recoveryDelay
+	} else {
+		s.RecoverySince = time.Time{}
+	}
+
 	switch {
+	case !recovered:
+		// keep the current status until the value stayed below the threshold for the recovery delay
 	case newStatus == types.StatusOk:
-		// downgrade status immediately
+		// downgrade status, immediately without recovery delay
 		s.CurrentStatus = types.StatusOk
 	case (criticalDuration != 0 || newStatus == types.StatusCritical) && criticalDuration >= criticalDelay:
 		s.CurrentStatus = types.StatusCritical
 	case (warningDuration != 0 || newStatus == types.StatusWarning) && warningDuration >= warningDelay:
 		s.CurrentStatus = types.StatusWarning
 	case s.CurrentStatus == types.StatusCritical && newStatus == types.StatusWarning:
-		// downgrade status immediately
+		// downgrade status, immediately without recovery delay
 		s.CurrentStatus = types.StatusWarning
 	}
 
+	if s.CurrentStatus == newStatus {
+		s.RecoverySince = time.Time{}
+	}
+
 	s.LastUpdate = now
 
 	return s
@@ -238,6 +283,8 @@ func (s *statusState) setNewStatus(newStatus types.Status, now time.Time) (time.
 
 // Threshold define a min/max thresholds.
 // Use NaN to mark the limit as unset.
+// When Reference is set, the limits are percentages of the last value of
+// the reference metric, which has the same labels but its name.
 type Threshold struct {
 	LowCritical   float64
 	LowWarning    float64
@@ -245,6 +292,8 @@ type Threshold struct {
 	HighWarning   float64
 	HighCritical  float64
 	CriticalDelay time.Duration
+	RecoveryDelay time.Duration
+	Reference     string
 }
 
 func (t Threshold) MarshalJSON() ([]byte, error) {
@@ -262,6 +311,19 @@ func (t Threshold) MarshalJSON() ([]byte, error) {
 		t.WarningDelay.String(), t.CriticalDelay.String(),
 	)
 
+	if t.RecoveryDelay != 0 {
+		str = str[:len(str)-1] + fmt.Sprintf(`,"RecoveryDelay":"%s"}`, t.RecoveryDelay.String())
+	}
+
+	if t.Reference != "" {
+		reference, err := json.Marshal(t.Reference)
+		if err != nil {
+			return nil, err
+		}
+
+		str = str[:len(str)-1] + fmt.Sprintf(`,"Reference":%s}`, reference)
+	}
+
 	return []byte(str), nil
 }
 
@@ -270,6 +332,10 @@ func (t Threshold) Equal(other Threshold) bool {
 	if t == other {
 		return true
 	}
+
+	if t.Reference != other.Reference {
+		return false
+	}
 	// Need special handling for NaN
 	if t.LowCritical != other.LowCritical && (!math.IsNaN(t.LowCritical) || !math.IsNaN(other.LowCritical)) {
 		return false
@@ -312,6 +378,10 @@ func (t Threshold) Merge(other Threshold) Threshold {
 		t.CriticalDelay = other.CriticalDelay
 	}
 
+	if other.RecoveryDelay > t.RecoveryDelay {
+		t.RecoveryDelay = other.RecoveryDelay
+	}
+
 	return t
 }
 
@@ -338,6 +408,8 @@ func FromConfig(
 	metricName string,
 	softPeriods map[string]time.Duration,
 	defaultSoftPeriod time.Duration,
+	recoveryPeriods map[string]time.Duration,
+	defaultRecoveryPeriod time.Duration,
 ) Threshold {
 	thresh := Threshold{
 		LowCritical:  math.NaN(),
@@ -362,6 +434,11 @@ func FromConfig(
 		thresh.HighCritical = *config.HighCritical
 	}
 
+	// Without reference metric, the limits are absolute: the metric is already a percentage.
+	if config.Unit == UnitPercent {
+		thresh.Reference = config.Reference
+	}
+
 	// Apply delays from config or default delay.
 	thresh.WarningDelay = defaultSoftPeriod
 	thresh.CriticalDelay = defaultSoftPeriod
@@ -371,6 +448,12 @@ func FromConfig(
 		thresh.CriticalDelay = softPeriod
 	}
 
+	thresh.RecoveryDelay = defaultRecoveryPeriod
+
+	if recoveryPeriod, ok := recoveryPeriods[metricName]; ok {
+		thresh.RecoveryDelay = recoveryPeriod
+	}
+
 	return thresh
 }
 
@@ -407,6 +490,17 @@ func (t Threshold) CurrentStatus(value float64) (types.Status, bool) {
 	return types.StatusOk, false
 }
 
+// withReference returns the threshold with absolute limits, given the value of the reference metric.
+func (t Threshold) withReference(referenceValue float64) Threshold {
+	t.LowCritical = t.LowCritical * referenceValue / 100
+	t.LowWarning = t.LowWarning * referenceValue / 100
+	t.HighWarning = t.HighWarning * referenceValue / 100
+	t.HighCritical = t.HighCritical * referenceValue / 100
+	t.Reference = ""
+
+	return t
+}
+
 func (r *Registry) GetThresholdMetricNames() []string {
 	res := []string{}
 
@@ -635,6 +729,21 @@ func (r *Registry) ApplyThresholds(points []types.MetricPoint) ([]types.MetricPo
 			threshold := r.getThreshold(labelsText)
 
 			if !threshold.IsZero() && !math.IsNaN(point.Value) {
+				if threshold.Reference != "" {
+					referenceValue, ok := r.referenceValue(point, threshold.Reference)
+					if !ok {
+						// Without reference point, the limits are unknown and the threshold isn't evaluated.
+						newPoints, statusPoints = addPointWithStatus(newPoints, statusPoints, point, types.StatusDescription{
+							CurrentStatus:     types.StatusUnknown,
+							StatusDescription: fmt.Sprintf("No recent point for the reference metric %s", threshold.Reference),
+						})
+
+						continue
+					}
+
+					threshold = threshold.withReference(referenceValue)
+				}
+
 				newPoints, statusPoints = r.addPointWithThreshold(newPoints, statusPoints, point, threshold, labelsText)
 
 				continue
@@ -657,7 +766,7 @@ func (r *Registry) addPointWithThreshold(
 	softStatus, highThreshold := threshold.CurrentStatus(point.Value)
 	previousState := r.states[labelsText]
 
-	newState := previousState.Update(softStatus, threshold.WarningDelay, threshold.CriticalDelay, r.nowFunc())
+	newState := previousState.Update(softStatus, threshold.WarningDelay, threshold.CriticalDelay, threshold.RecoveryDelay, r.nowFunc())
 	r.states[labelsText] = newState
 
 	unit := r.units[labelsText]
@@ -703,6 +812,63 @@ func (r *Registry) addPointWithThreshold(
 		CurrentStatus:     newState.CurrentStatus,
 		StatusDescription: statusDescription,
 	}
+
+	return addPointWithStatus(points, statusPoints, point, status)
+}
+
+// referenceValue returns the last value of the reference metric of a point.
+// It returns false when the reference metric has no point since referenceMaxAge.
+func (r *Registry) referenceValue(point types.MetricPoint, reference string) (float64, bool) {
+	if r.store == nil {
+		return 0, false
+	}
+
+	filters := make(map[string]string, len(point.Labels))
+
+	for k, v := range point.Labels {
+		filters[k] = v
+	}
+
+	filters[types.LabelName] = reference
+
+	metrics, err := r.store.Metrics(filters)
+	if err != nil {
+		return 0, false
+	}
+
+	now := r.nowFunc()
+
+	for _, metric := range metrics {
+		// The store returns the metrics having at least the filters labels.
+		if len(metric.Labels()) != len(filters) {
+			continue
+		}
+
+		points, err := metric.Points(now.Add(-referenceMaxAge), now)
+		if err != nil || len(points) == 0 {
+			return 0, false
+		}
+
+		last := points[0]
+
+		for _, p := range points[1:] {
+			if p.Time.After(last.Time) {
+				last = p
+			}
+		}
+
+		return last.Value, !math.IsNaN(last.Value)
+	}
+
+	return 0, false
+}
+
+// addPointWithStatus appends the point with the given status and its status point.
+func addPointWithStatus(
+	points, statusPoints []types.MetricPoint,
+	point types.MetricPoint,
+	status types.StatusDescription,
+) ([]types.MetricPoint, []types.MetricPoint) {
 	annotationsCopy := point.Annotations
 	annotationsCopy.Status = status
 
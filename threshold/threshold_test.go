@@ -18,12 +18,14 @@
 package threshold
 
 import (
+	"context"
 	"math"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/bleemeo/glouton/config"
+	"github.com/bleemeo/glouton/store"
 	"github.com/bleemeo/glouton/types"
 
 	"github.com/google/go-cmp/cmp"
@@ -89,7 +91,7 @@ func TestStateUpdate(t *testing.T) {
 		state := statusState{}
 
 		for _, step := range c {
-			state = state.Update(step.status, 300*time.Second, 300*time.Second, now.Add(time.Duration(step.timeOffsetSecond)*time.Second))
+			state = state.Update(step.status, 300*time.Second, 300*time.Second, 0, now.Add(time.Duration(step.timeOffsetSecond)*time.Second))
 			if state.CurrentStatus != step.want {
 				t.Errorf("case #%d offset %d: state.CurrentStatus == %v, want %v", i, step.timeOffsetSecond, state.CurrentStatus, step.want)
 
@@ -99,6 +101,36 @@ func TestStateUpdate(t *testing.T) {
 	}
 }
 
+// TestStateUpdateRecovery checks that the status is only downgraded when the
+// value stayed below the threshold for the recovery delay.
+func TestStateUpdateRecovery(t *testing.T) {
+	steps := []struct {
+		timeOffsetSecond int
+		status           types.Status
+		want             types.Status
+	}{
+		{0, types.StatusCritical, types.StatusCritical},
+		{10, types.StatusOk, types.StatusCritical},
+		{20, types.StatusCritical, types.StatusCritical},
+		{30, types.StatusWarning, types.StatusCritical},
+		{80, types.StatusOk, types.StatusCritical},
+		{90, types.StatusOk, types.StatusOk},
+		{100, types.StatusCritical, types.StatusCritical},
+		{110, types.StatusWarning, types.StatusCritical},
+		{170, types.StatusWarning, types.StatusWarning},
+		{180, types.StatusOk, types.StatusWarning},
+	}
+	now := time.Now()
+	state := statusState{}
+
+	for _, step := range steps {
+		state = state.Update(step.status, 0, 0, time.Minute, now.Add(time.Duration(step.timeOffsetSecond)*time.Second))
+		if state.CurrentStatus != step.want {
+			t.Fatalf("offset %d: state.CurrentStatus == %v, want %v", step.timeOffsetSecond, state.CurrentStatus, step.want)
+		}
+	}
+}
+
 func TestStateUpdatePeriodChange(t *testing.T) {
 	cases := [][]struct {
 		period           int
@@ -122,7 +154,7 @@ func TestStateUpdatePeriodChange(t *testing.T) {
 		state := statusState{}
 
 		for _, step := range c {
-			state = state.Update(step.status, time.Duration(step.period)*time.Second, time.Duration(step.period)*time.Second, now.Add(time.Duration(step.timeOffsetSecond)*time.Second))
+			state = state.Update(step.status, time.Duration(step.period)*time.Second, time.Duration(step.period)*time.Second, 0, now.Add(time.Duration(step.timeOffsetSecond)*time.Second))
 			if state.CurrentStatus != step.want {
 				t.Errorf("case #%d offset %d: state.CurrentStatus == %v, want %v", i, step.timeOffsetSecond, state.CurrentStatus, step.want)
 
@@ -439,6 +471,62 @@ func TestAccumulatorThreshold(t *testing.T) {
 	}
 }
 
+// TestPercentThreshold checks thresholds relative to a reference metric read from the store.
+func TestPercentThreshold(t *testing.T) {
+	t0 := time.Date(2020, 2, 24, 15, 1, 0, 0, time.UTC)
+
+	metricStore := store.New(time.Hour, time.Hour)
+
+	threshold := New(mockState{})
+	threshold.nowFunc = func() time.Time { return t0 }
+	threshold.SetStore(metricStore)
+	threshold.SetThresholds(
+		"fake_id",
+		nil,
+		map[string]Threshold{"mem_used": {
+			LowCritical:  math.NaN(),
+			LowWarning:   math.NaN(),
+			HighWarning:  80,
+			HighCritical: 90,
+			Reference:    "mem_total",
+		}},
+	)
+
+	memUsed := types.MetricPoint{
+		Labels: map[string]string{types.LabelName: "mem_used"},
+		Point:  types.Point{Time: t0, Value: 7e9},
+	}
+
+	// Without reference point, the status is unknown.
+	newPoints, statusPoints := threshold.ApplyThresholds([]types.MetricPoint{memUsed})
+	if len(newPoints) != 1 || len(statusPoints) != 1 {
+		t.Fatalf("got %d points and %d status points, want 1 and 1", len(newPoints), len(statusPoints))
+	}
+
+	if status := newPoints[0].Annotations.Status.CurrentStatus; status != types.StatusUnknown {
+		t.Errorf("status = %v, want %v", status, types.StatusUnknown)
+	}
+
+	metricStore.PushPoints(context.Background(), []types.MetricPoint{
+		{
+			Labels: map[string]string{types.LabelName: "mem_total"},
+			Point:  types.Point{Time: t0.Add(-time.Minute), Value: 8e9},
+		},
+	})
+
+	// 7 GB is 87.5% of 8 GB.
+	newPoints, _ = threshold.ApplyThresholds([]types.MetricPoint{memUsed})
+
+	want := types.StatusDescription{
+		CurrentStatus:     types.StatusWarning,
+		StatusDescription: "Current value: 7000000000.00 threshold (6400000000.00) exceeded",
+	}
+
+	if diff := cmp.Diff(want, newPoints[0].Annotations.Status); diff != "" {
+		t.Errorf("status mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestThreshold(t *testing.T) { //nolint: maintidx
 	threshold := New(mockState{})
 
@@ -1087,12 +1175,14 @@ func TestThresholdsFromConfig(t *testing.T) {
 	t.Parallel()
 
 	tests := []struct {
-		Name              string
-		Config            config.Threshold
-		MetricName        string
-		SoftPeriods       map[string]time.Duration
-		DefaultSoftPeriod time.Duration
-		Expected          Threshold
+		Name                  string
+		Config                config.Threshold
+		MetricName            string
+		SoftPeriods           map[string]time.Duration
+		DefaultSoftPeriod     time.Duration
+		RecoveryPeriods       map[string]time.Duration
+		DefaultRecoveryPeriod time.Duration
+		Expected              Threshold
 	}{
 		{
 			Name: "full",
@@ -1156,6 +1246,48 @@ func TestThresholdsFromConfig(t *testing.T) {
 				CriticalDelay: time.Second,
 			},
 		},
+		{
+			Name: "recovery",
+			Config: config.Threshold{
+				HighWarning:  newFloatPointer(70),
+				HighCritical: newFloatPointer(80),
+			},
+			MetricName:            "cpu_used",
+			SoftPeriods:           nil,
+			DefaultSoftPeriod:     time.Second,
+			RecoveryPeriods:       map[string]time.Duration{"cpu_used": time.Minute},
+			DefaultRecoveryPeriod: 10 * time.Second,
+			Expected: Threshold{
+				LowCritical:   math.NaN(),
+				LowWarning:    math.NaN(),
+				HighWarning:   70,
+				HighCritical:  80,
+				WarningDelay:  time.Second,
+				CriticalDelay: time.Second,
+				RecoveryDelay: time.Minute,
+			},
+		},
+		{
+			Name: "percent",
+			Config: config.Threshold{
+				HighWarning:  newFloatPointer(80),
+				HighCritical: newFloatPointer(90),
+				Unit:         "%",
+				Reference:    "mem_total",
+			},
+			MetricName:        "mem_used",
+			SoftPeriods:       nil,
+			DefaultSoftPeriod: time.Second,
+			Expected: Threshold{
+				LowCritical:   math.NaN(),
+				LowWarning:    math.NaN(),
+				HighWarning:   80,
+				HighCritical:  90,
+				WarningDelay:  time.Second,
+				CriticalDelay: time.Second,
+				Reference:     "mem_total",
+			},
+		},
 		{
 			Name: "not set",
 			Config: config.Threshold{
@@ -1182,7 +1314,7 @@ func TestThresholdsFromConfig(t *testing.T) {
 		t.Run(test.Name, func(t *testing.T) {
 			t.Parallel()
 
-			got := FromConfig(test.Config, test.MetricName, test.SoftPeriods, test.DefaultSoftPeriod)
+			got := FromConfig(test.Config, test.MetricName, test.SoftPeriods, test.DefaultSoftPeriod, test.RecoveryPeriods, test.DefaultRecoveryPeriod)
 			if diff := cmp.Diff(test.Expected, got); diff != "" {
 				t.Fatalf("Wrong threshold from config:\n%s", diff)
 			}
@@ -117,6 +117,7 @@ const (
 	LabelMetaPort                   = "__meta_port"
 	LabelMetaScrapeInstance         = "__meta_scrape_instance"
 	LabelMetaScrapeJob              = "__meta_scrape_job"
+	LabelMetaScrapeSource           = "__meta_scrape_source"
 	LabelMetaSNMPTarget             = "__meta_snmp_target"
 	LabelMetaKubernetesCluster      = "__meta_kubernetes_cluster"
 	LabelMetaVSphere                = "__meta_vsphere"
@@ -140,6 +141,7 @@ const (
 	LabelContainerName              = "container_name"
 	LabelScrapeJob                  = "scrape_job"
 	LabelScrapeInstance             = "scrape_instance"
+	LabelScrapeSource               = "source"
 	LabelService                    = "service"
 	LabelServiceInstance            = "service_instance"
 	LabelDevice                     = "device"
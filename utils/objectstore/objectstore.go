@@ -0,0 +1,335 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package objectstore uploads files to an S3-compatible object store.
+package objectstore
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bleemeo/glouton/types"
+)
+
+const defaultRegion = "us-east-1"
+
+var (
+	errMissingBucket = errors.New("missing endpoint or bucket")
+	errUploadFailed  = errors.New("upload failed")
+	errArchiveClosed = errors.New("the archive is already closed")
+)
+
+// Client uploads objects to a bucket using path-style requests signed with AWS Signature Version 4.
+type Client struct {
+	// Endpoint is the URL of the object store, e.g. "https://s3.eu-west-1.amazonaws.com".
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	// HTTPClient is the client used for requests, http.DefaultClient is used when nil.
+	HTTPClient *http.Client
+
+	now func() time.Time
+}
+
+// Put uploads body to the given object key.
+func (c *Client) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	if c.Endpoint == "" || c.Bucket == "" {
+		return errMissingBucket
+	}
+
+	endpoint, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/") + "/" + c.Bucket + "/" + strings.TrimPrefix(key, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	c.sign(req, body)
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+		return fmt.Errorf("%w: %s: %s", errUploadFailed, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// sign adds the AWS Signature Version 4 headers to the request.
+func (c *Client) sign(req *http.Request, body []byte) {
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+
+	t := now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	region := c.Region
+	if region == "" {
+		region = defaultRegion
+	}
+
+	payloadHash := sha256.Sum256(body)
+	payloadHashHex := hex.EncodeToString(payloadHash[:])
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHashHex)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEscape(req.URL.Path),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHashHex,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHashHex,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(canonicalHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.SecretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set(
+		"Authorization",
+		fmt.Sprintf(
+			"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+			c.AccessKey, scope, signedHeaders, signature,
+		),
+	)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+
+	return h.Sum(nil)
+}
+
+// uriEscape encodes the path as required by the canonical request:
+// all bytes except unreserved characters and "/" are percent-encoded.
+func uriEscape(path string) string {
+	var sb strings.Builder
+
+	for i := range len(path) {
+		b := path[i]
+
+		switch {
+		case b >= 'A' && b <= 'Z', b >= 'a' && b <= 'z', b >= '0' && b <= '9',
+			b == '-', b == '_', b == '.', b == '~', b == '/':
+			sb.WriteByte(b)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+
+	return sb.String()
+}
+
+// ArchiveWriter is a types.ArchiveWriter which builds a tar archive in memory
+// and uploads it to the object store.
+// A file is added to the tar archive once the next file is created, so the archive
+// could be uploaded by UploadPartial while a file is still being written.
+type ArchiveWriter struct {
+	l                  sync.Mutex
+	tar                *tar.Writer
+	buffer             *bytes.Buffer
+	currentFileName    string
+	currentFileContent *bytes.Buffer
+	closed             bool
+
+	client *Client
+}
+
+// NewArchiveWriter returns an ArchiveWriter uploading to the object store using client.
+func NewArchiveWriter(client *Client) *ArchiveWriter {
+	buffer := new(bytes.Buffer)
+
+	return &ArchiveWriter{
+		tar:    tar.NewWriter(buffer),
+		buffer: buffer,
+		client: client,
+	}
+}
+
+// Create implements types.ArchiveWriter.
+func (w *ArchiveWriter) Create(filename string) (io.Writer, error) {
+	w.l.Lock()
+	defer w.l.Unlock()
+
+	if w.closed {
+		return nil, errArchiveClosed
+	}
+
+	if err := w.flushPending(); err != nil {
+		return nil, err
+	}
+
+	// Use a new buffer for each file: after UploadPartial the buffer of the
+	// current file may still be written, it must never be read.
+	w.currentFileName = filename
+	w.currentFileContent = new(bytes.Buffer)
+
+	return w.currentFileContent, nil
+}
+
+// CurrentFileName implements types.ArchiveWriter.
+func (w *ArchiveWriter) CurrentFileName() string {
+	w.l.Lock()
+	defer w.l.Unlock()
+
+	return w.currentFileName
+}
+
+// Upload closes the archive and uploads it to the given object key.
+func (w *ArchiveWriter) Upload(ctx context.Context, key string) error {
+	w.l.Lock()
+
+	if w.closed {
+		w.l.Unlock()
+
+		return errArchiveClosed
+	}
+
+	err := w.flushPending()
+	if err == nil {
+		err = w.close()
+	}
+
+	w.l.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return w.client.Put(ctx, key, w.buffer.Bytes(), "application/x-tar")
+}
+
+// UploadPartial closes the archive without the file currently written and uploads it
+// to the given object key. It's used when writing the archive doesn't finish on time.
+// The archive contains an additional file telling which file was abandoned.
+func (w *ArchiveWriter) UploadPartial(ctx context.Context, key string) error {
+	w.l.Lock()
+
+	if w.closed {
+		w.l.Unlock()
+
+		return errArchiveClosed
+	}
+
+	abandonedFileName := w.currentFileName
+
+	w.currentFileName = "incomplete-archive.txt"
+	w.currentFileContent = new(bytes.Buffer)
+
+	fmt.Fprintf(w.currentFileContent, "The archive is incomplete, writing the file %s didn't finish on time.\n", abandonedFileName)
+
+	err := w.flushPending()
+	if err == nil {
+		err = w.close()
+	}
+
+	w.l.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return w.client.Put(ctx, key, w.buffer.Bytes(), "application/x-tar")
+}
+
+// flushPending adds the current file to the tar archive. The lock must be held.
+func (w *ArchiveWriter) flushPending() error {
+	if w.currentFileName == "" {
+		return nil
+	}
+
+	header := &tar.Header{
+		Name:    w.currentFileName,
+		ModTime: time.Now(),
+		Mode:    0o644,
+		Size:    int64(w.currentFileContent.Len()),
+	}
+
+	if err := w.tar.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err := w.tar.Write(w.currentFileContent.Bytes())
+
+	return err
+}
+
+// close terminates the tar archive. The lock must be held.
+func (w *ArchiveWriter) close() error {
+	w.closed = true
+	w.currentFileName = ""
+	w.currentFileContent = nil
+
+	return w.tar.Close()
+}
+
+var _ types.ArchiveWriter = (*ArchiveWriter)(nil)
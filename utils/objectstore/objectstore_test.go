@@ -0,0 +1,183 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objectstore
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArchiveWriterUpload(t *testing.T) {
+	t.Parallel()
+
+	var (
+		gotPath string
+		gotAuth string
+		gotBody []byte
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	client := &Client{
+		Endpoint:  srv.URL,
+		Bucket:    "bucket",
+		AccessKey: "access",
+		SecretKey: "secret",
+		now: func() time.Time {
+			return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		},
+	}
+
+	archive := NewArchiveWriter(client)
+
+	w, err := archive.Create("goroutines.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Write([]byte("content")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := archive.Upload(context.Background(), "diagnostic.tar"); err != nil {
+		t.Fatal(err)
+	}
+
+	if gotPath != "/bucket/diagnostic.tar" {
+		t.Errorf("path = %q, want %q", gotPath, "/bucket/diagnostic.tar")
+	}
+
+	wantAuthPrefix := "AWS4-HMAC-SHA256 Credential=access/20240102/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="
+	if !strings.HasPrefix(gotAuth, wantAuthPrefix) {
+		t.Errorf("Authorization = %q, want prefix %q", gotAuth, wantAuthPrefix)
+	}
+
+	reader := tar.NewReader(bytes.NewReader(gotBody))
+
+	header, err := reader.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := io.ReadAll(reader)
+
+	if header.Name != "goroutines.txt" || string(content) != "content" {
+		t.Errorf("archive contains %q = %q, want goroutines.txt = content", header.Name, content)
+	}
+}
+
+// TestArchiveWriterUploadPartial checks that the partial archive contains the completed
+// files, but not the file still being written.
+func TestArchiveWriterUploadPartial(t *testing.T) {
+	t.Parallel()
+
+	var gotBody []byte
+
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	archive := NewArchiveWriter(&Client{Endpoint: srv.URL, Bucket: "bucket"})
+
+	for _, name := range []string{"goroutines.txt", "discovery.txt"} {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := w.Write([]byte("content")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := archive.UploadPartial(context.Background(), "diagnostic.tar"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := archive.Create("late.txt"); !errors.Is(err, errArchiveClosed) {
+		t.Errorf("Create() error = %v, want %v", err, errArchiveClosed)
+	}
+
+	reader := tar.NewReader(bytes.NewReader(gotBody))
+
+	var names []string
+
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names = append(names, header.Name)
+	}
+
+	want := []string{"goroutines.txt", "incomplete-archive.txt"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("archive contains %v, want %v", names, want)
+	}
+}
+
+func TestPutError(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	client := &Client{Endpoint: srv.URL, Bucket: "bucket"}
+
+	err := client.Put(context.Background(), "key", []byte("data"), "")
+	if !errors.Is(err, errUploadFailed) {
+		t.Errorf("Put() error = %v, want %v", err, errUploadFailed)
+	}
+}
+
+func TestURIEscape(t *testing.T) {
+	t.Parallel()
+
+	got := uriEscape("/bucket/my file+name:1.tar")
+	want := "/bucket/my%20file%2Bname%3A1.tar"
+
+	if got != want {
+		t.Errorf("uriEscape() = %q, want %q", got, want)
+	}
+}
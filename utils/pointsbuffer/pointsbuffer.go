@@ -0,0 +1,135 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pointsbuffer implements the bounded buffer of points used by the outputs
+// while the points are waiting to be sent.
+package pointsbuffer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bleemeo/glouton/logger"
+	"github.com/bleemeo/glouton/types"
+)
+
+// dropLogInterval is the minimal delay between two logs about the dropped points.
+const dropLogInterval = time.Minute
+
+// Buffer keeps the points waiting to be sent. The oldest points are dropped when it's full.
+//
+// Each point is identified by a sequence number, so the points of a batch can be
+// removed once sent even if older points were dropped while the batch was in flight.
+type Buffer struct {
+	name      string
+	maxPoints int
+
+	l sync.Mutex
+	// points are the pending points, the sequence number of points[0] is firstSeq.
+	points        []types.MetricPoint
+	firstSeq      uint64
+	droppedPoints int
+	lastDropLog   time.Time
+}
+
+// Batch is a list of the oldest pending points.
+type Batch struct {
+	Points []types.MetricPoint
+	// endSeq is the sequence number following the last point of the batch.
+	endSeq uint64
+}
+
+// New returns a buffer keeping at most maxPoints points.
+// The name is used in the log about the dropped points.
+func New(name string, maxPoints int) *Buffer {
+	return &Buffer{
+		name:      name,
+		maxPoints: maxPoints,
+	}
+}
+
+// Add adds the points to the buffer, the oldest points are dropped when the buffer is full.
+func (b *Buffer) Add(points []types.MetricPoint) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	b.points = append(b.points, points...)
+
+	if extra := len(b.points) - b.maxPoints; extra > 0 {
+		b.removeLocked(extra)
+		b.dropped(extra)
+	}
+}
+
+// Batch returns the oldest pending points, at most maxSize. The points
+// stay in the buffer until the batch is removed.
+func (b *Buffer) Batch(maxSize int) Batch {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	size := min(len(b.points), maxSize)
+
+	batch := Batch{
+		Points: make([]types.MetricPoint, size),
+		endSeq: b.firstSeq + uint64(size),
+	}
+
+	copy(batch.Points, b.points[:size])
+
+	return batch
+}
+
+// Remove removes the points of the batch, once they were sent.
+// The points of the batch dropped since the batch was created are skipped.
+func (b *Buffer) Remove(batch Batch) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if batch.endSeq <= b.firstSeq {
+		return
+	}
+
+	b.removeLocked(min(int(batch.endSeq-b.firstSeq), len(b.points)))
+}
+
+// Len returns the number of pending points.
+func (b *Buffer) Len() int {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	return len(b.points)
+}
+
+// removeLocked removes the count oldest points. The lock must be held.
+func (b *Buffer) removeLocked(count int) {
+	b.points = append(b.points[:0], b.points[count:]...)
+	b.firstSeq += uint64(count)
+}
+
+// dropped records that the oldest points were dropped and logs it at most once per dropLogInterval.
+// The lock must be held.
+func (b *Buffer) dropped(count int) {
+	b.droppedPoints += count
+
+	if time.Since(b.lastDropLog) < dropLogInterval {
+		return
+	}
+
+	logger.Printf("The %s buffer is full, %d oldest points were dropped", b.name, b.droppedPoints)
+
+	b.droppedPoints = 0
+	b.lastDropLog = time.Now()
+}
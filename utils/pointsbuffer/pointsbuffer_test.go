@@ -0,0 +1,124 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pointsbuffer
+
+import (
+	"testing"
+
+	"github.com/bleemeo/glouton/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func makePoints(values ...float64) []types.MetricPoint {
+	points := make([]types.MetricPoint, 0, len(values))
+
+	for _, v := range values {
+		points = append(points, types.MetricPoint{Point: types.Point{Value: v}})
+	}
+
+	return points
+}
+
+func values(points []types.MetricPoint) []float64 {
+	result := make([]float64, 0, len(points))
+
+	for _, p := range points {
+		result = append(result, p.Value)
+	}
+
+	return result
+}
+
+func TestBufferBatch(t *testing.T) {
+	t.Parallel()
+
+	buffer := New("test", 10)
+
+	buffer.Add(makePoints(1, 2, 3))
+	buffer.Add(makePoints(4, 5))
+
+	batch := buffer.Batch(3)
+	if diff := cmp.Diff([]float64{1, 2, 3}, values(batch.Points)); diff != "" {
+		t.Errorf("Batch() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The points stay in the buffer until the batch is removed.
+	if n := buffer.Len(); n != 5 {
+		t.Errorf("Len() = %d, want 5", n)
+	}
+
+	buffer.Remove(batch)
+
+	// Removing the same batch twice does nothing.
+	buffer.Remove(batch)
+
+	if diff := cmp.Diff([]float64{4, 5}, values(buffer.Batch(10).Points)); diff != "" {
+		t.Errorf("Batch() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBufferDropOldest(t *testing.T) {
+	t.Parallel()
+
+	buffer := New("test", 3)
+
+	buffer.Add(makePoints(1, 2))
+	buffer.Add(makePoints(3, 4))
+
+	if diff := cmp.Diff([]float64{2, 3, 4}, values(buffer.Batch(10).Points)); diff != "" {
+		t.Errorf("Batch() mismatch (-want +got):\n%s", diff)
+	}
+
+	buffer.Add(makePoints(5, 6, 7, 8))
+
+	if diff := cmp.Diff([]float64{6, 7, 8}, values(buffer.Batch(10).Points)); diff != "" {
+		t.Errorf("Batch() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestBufferDropWhileSending checks that the points added while a batch is sent
+// are kept, even when older points were dropped in the meantime.
+func TestBufferDropWhileSending(t *testing.T) {
+	t.Parallel()
+
+	buffer := New("test", 4)
+
+	buffer.Add(makePoints(1, 2, 3, 4))
+
+	batch := buffer.Batch(3)
+
+	// Points 1 to 3 are being sent, 1 and 2 are dropped for the new points.
+	buffer.Add(makePoints(5, 6))
+
+	buffer.Remove(batch)
+
+	if diff := cmp.Diff([]float64{4, 5, 6}, values(buffer.Batch(10).Points)); diff != "" {
+		t.Errorf("Batch() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The whole batch was dropped while it was sent.
+	batch = buffer.Batch(1)
+
+	buffer.Add(makePoints(7, 8, 9, 10))
+
+	buffer.Remove(batch)
+
+	if diff := cmp.Diff([]float64{7, 8, 9, 10}, values(buffer.Batch(10).Points)); diff != "" {
+		t.Errorf("Batch() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,382 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zabbix
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bleemeo/glouton/logger"
+)
+
+const (
+	// maxPacketSize is the maximum size of a packet received from the Zabbix server.
+	maxPacketSize = 16 << 20
+	activeTimeout = 10 * time.Second
+	// itemStateNotSupported is the state of an item whose value couldn't be gathered.
+	itemStateNotSupported = 1
+)
+
+var (
+	errRequestFailed  = errors.New("zabbix request failed")
+	errPacketTooLarge = errors.New("packet too large")
+	errInvalidDelay   = errors.New("invalid item delay")
+)
+
+// ActiveOptions are the options of the active checks.
+type ActiveOptions struct {
+	// ServerAddress is the address (host:port) of the Zabbix server or proxy.
+	ServerAddress string
+	// Hostname is the name of the host in Zabbix.
+	Hostname string
+	// RefreshInterval is the delay between two refreshes of the list of items.
+	RefreshInterval time.Duration
+}
+
+// ActiveClient implements the Zabbix active checks: it periodically fetches the
+// list of items to monitor from the Zabbix server and sends their values.
+type ActiveClient struct {
+	opts     ActiveOptions
+	callback callback
+	session  string
+	lastID   int
+
+	items map[string]*activeItem
+}
+
+type activeItem struct {
+	key       string
+	delay     time.Duration
+	nextCheck time.Time
+}
+
+type activeChecksRequest struct {
+	Request string `json:"request"`
+	Host    string `json:"host"`
+}
+
+type activeChecksResponse struct {
+	Response string `json:"response"`
+	Info     string `json:"info"`
+	Data     []struct {
+		Key   string          `json:"key"`
+		Delay json.RawMessage `json:"delay"`
+	} `json:"data"`
+}
+
+type agentDataRequest struct {
+	Request string       `json:"request"`
+	Session string       `json:"session"`
+	Data    []agentValue `json:"data"`
+	Clock   int64        `json:"clock"`
+	NS      int          `json:"ns"`
+}
+
+type agentValue struct {
+	Host  string `json:"host"`
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	State int    `json:"state,omitempty"`
+	ID    int    `json:"id"`
+	Clock int64  `json:"clock"`
+	NS    int    `json:"ns"`
+}
+
+type agentDataResponse struct {
+	Response string `json:"response"`
+	Info     string `json:"info"`
+}
+
+// NewActive returns a client for the Zabbix active checks.
+// The item keys are resolved with callback, the system keys are answered from store like for the passive server.
+func NewActive(opts ActiveOptions, callback callback, store MetricStore) *ActiveClient {
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = 2 * time.Minute
+	}
+
+	session := make([]byte, 16)
+	_, _ = rand.Read(session)
+
+	return &ActiveClient{
+		opts:     opts,
+		callback: withSystemKeys(store, callback),
+		session:  hex.EncodeToString(session),
+		items:    make(map[string]*activeItem),
+	}
+}
+
+// Run fetches the items to monitor and sends their values until the context is cancelled.
+func (c *ActiveClient) Run(ctx context.Context) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var nextRefresh time.Time
+
+	for {
+		now := time.Now()
+
+		if !now.Before(nextRefresh) {
+			if err := c.refreshItems(ctx, now); err != nil && ctx.Err() == nil {
+				logger.V(1).Printf("Unable to get the Zabbix active checks: %v", err)
+			}
+
+			nextRefresh = now.Add(c.opts.RefreshInterval)
+		}
+
+		if values := c.collect(now); len(values) > 0 {
+			if err := c.sendValues(ctx, now, values); err != nil && ctx.Err() == nil {
+				logger.V(1).Printf("Unable to send the Zabbix active checks values: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshItems updates the list of items from the Zabbix server.
+// The schedule of the items which are kept is preserved.
+func (c *ActiveClient) refreshItems(ctx context.Context, now time.Time) error {
+	var response activeChecksResponse
+
+	err := c.exchange(ctx, activeChecksRequest{Request: "active checks", Host: c.opts.Hostname}, &response)
+	if err != nil {
+		return err
+	}
+
+	if response.Response != "success" {
+		return fmt.Errorf("%w: %s", errRequestFailed, response.Info)
+	}
+
+	items := make(map[string]*activeItem, len(response.Data))
+
+	for _, data := range response.Data {
+		delay, err := parseDelay(data.Delay)
+		if err != nil {
+			logger.V(1).Printf("Ignoring Zabbix item %s: %v", data.Key, err)
+
+			continue
+		}
+
+		item := &activeItem{key: data.Key, delay: delay, nextCheck: now}
+
+		if previous, ok := c.items[data.Key]; ok && previous.delay == delay {
+			item.nextCheck = previous.nextCheck
+		}
+
+		items[data.Key] = item
+	}
+
+	c.items = items
+
+	return nil
+}
+
+// collect returns the values of the items which are due.
+func (c *ActiveClient) collect(now time.Time) []agentValue {
+	var values []agentValue
+
+	for _, item := range c.items {
+		if now.Before(item.nextCheck) {
+			continue
+		}
+
+		item.nextCheck = now.Add(item.delay)
+
+		value := agentValue{
+			Host:  c.opts.Hostname,
+			Key:   item.key,
+			Clock: now.Unix(),
+			NS:    now.Nanosecond(),
+		}
+
+		key, args, err := splitData(item.key)
+		if err == nil {
+			value.Value, err = c.callback(key, args)
+		}
+
+		if err != nil {
+			value.Value = err.Error()
+			value.State = itemStateNotSupported
+		}
+
+		c.lastID++
+		value.ID = c.lastID
+
+		values = append(values, value)
+	}
+
+	return values
+}
+
+// sendValues sends the values using the agent data request of the sender protocol.
+func (c *ActiveClient) sendValues(ctx context.Context, now time.Time, values []agentValue) error {
+	request := agentDataRequest{
+		Request: "agent data",
+		Session: c.session,
+		Data:    values,
+		Clock:   now.Unix(),
+		NS:      now.Nanosecond(),
+	}
+
+	var response agentDataResponse
+
+	if err := c.exchange(ctx, request, &response); err != nil {
+		return err
+	}
+
+	if response.Response != "success" {
+		return fmt.Errorf("%w: %s", errRequestFailed, response.Info)
+	}
+
+	logger.V(2).Printf("Zabbix active checks values sent: %s", response.Info)
+
+	return nil
+}
+
+// exchange sends a JSON request to the Zabbix server and decodes its JSON response.
+func (c *ActiveClient) exchange(ctx context.Context, request interface{}, response interface{}) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	dialer := net.Dialer{Timeout: activeTimeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", c.opts.ServerAddress)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(activeTimeout)); err != nil {
+		return err
+	}
+
+	if _, err := conn.Write(encodePacket(data)); err != nil {
+		return err
+	}
+
+	answer, err := readPacket(conn)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(answer, response)
+}
+
+// encodePacket adds the Zabbix header to data.
+func encodePacket(data []byte) []byte {
+	packet := make([]byte, 13+len(data))
+
+	copy(packet[0:4], "ZBXD")
+
+	packet[4] = 1 // version
+
+	binary.LittleEndian.PutUint64(packet[5:13], uint64(len(data)))
+	copy(packet[13:], data)
+
+	return packet
+}
+
+// readPacket reads a Zabbix packet and returns its data.
+func readPacket(r io.Reader) ([]byte, error) {
+	header := make([]byte, 13)
+
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(header[0:4], []byte("ZBXD")) {
+		return nil, errWrongHeader
+	}
+
+	dataLength := binary.LittleEndian.Uint64(header[5:13])
+	if dataLength > maxPacketSize {
+		return nil, fmt.Errorf("%w: %d bytes", errPacketTooLarge, dataLength)
+	}
+
+	data := make([]byte, dataLength)
+
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// parseDelay parses the delay of an item. The delay is a number of seconds in older
+// Zabbix versions and a string with an optional time suffix ("30s", "5m") in newer ones.
+// Only the default interval is used when the delay contains flexible intervals.
+func parseDelay(raw json.RawMessage) (time.Duration, error) {
+	var delay string
+
+	if err := json.Unmarshal(raw, &delay); err != nil {
+		var seconds int64
+
+		if err := json.Unmarshal(raw, &seconds); err != nil {
+			return 0, fmt.Errorf("%w: %s", errInvalidDelay, raw)
+		}
+
+		delay = strconv.FormatInt(seconds, 10)
+	}
+
+	delay, _, _ = strings.Cut(delay, ";")
+
+	unit := time.Second
+
+	if delay != "" {
+		switch delay[len(delay)-1] {
+		case 's':
+			delay = delay[:len(delay)-1]
+		case 'm':
+			unit = time.Minute
+			delay = delay[:len(delay)-1]
+		case 'h':
+			unit = time.Hour
+			delay = delay[:len(delay)-1]
+		case 'd':
+			unit = 24 * time.Hour
+			delay = delay[:len(delay)-1]
+		case 'w':
+			unit = 7 * 24 * time.Hour
+			delay = delay[:len(delay)-1]
+		}
+	}
+
+	value, err := strconv.ParseInt(delay, 10, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("%w: %s", errInvalidDelay, raw)
+	}
+
+	return time.Duration(value) * unit, nil
+}
@@ -0,0 +1,222 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zabbix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeActiveServer is a Zabbix server answering the active checks and agent data requests.
+type fakeActiveServer struct {
+	l        sync.Mutex
+	listener net.Listener
+	items    string
+	hosts    []string
+	values   []agentValue
+}
+
+func newFakeActiveServer(t *testing.T, items string) *fakeActiveServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &fakeActiveServer{listener: listener, items: items}
+
+	t.Cleanup(func() { listener.Close() })
+
+	go srv.serve()
+
+	return srv
+}
+
+func (s *fakeActiveServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.handle(conn)
+	}
+}
+
+func (s *fakeActiveServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	data, err := readPacket(conn)
+	if err != nil {
+		return
+	}
+
+	var request agentDataRequest
+
+	if err := json.Unmarshal(data, &request); err != nil {
+		return
+	}
+
+	var host struct {
+		Host string `json:"host"`
+	}
+
+	_ = json.Unmarshal(data, &host)
+
+	s.l.Lock()
+
+	var answer string
+
+	switch request.Request {
+	case "active checks":
+		s.hosts = append(s.hosts, host.Host)
+		answer = s.items
+	case "agent data":
+		s.values = append(s.values, request.Data...)
+		answer = `{"response":"success","info":"processed: 1"}`
+	}
+
+	s.l.Unlock()
+
+	_, _ = conn.Write(encodePacket([]byte(answer)))
+}
+
+func TestActiveClient(t *testing.T) {
+	t.Parallel()
+
+	srv := newFakeActiveServer(t, `{"response":"success","data":[
+		{"key":"agent.ping","delay":30},
+		{"key":"agent.version","delay":"1m"},
+		{"key":"unknown.key[a,b]","delay":"30s;10s/1-5,09:00-18:00"},
+		{"key":"invalid.delay","delay":"{$MACRO}"}
+	]}`)
+
+	client := NewActive(
+		ActiveOptions{ServerAddress: srv.listener.Addr().String(), Hostname: "server.example.com"},
+		func(key string, args []string) (string, error) {
+			switch key {
+			case "agent.ping":
+				return "1", nil
+			case "agent.version":
+				return "4", nil
+			}
+
+			return "", errors.New("unsupported key") //nolint:goerr113
+		},
+		nil,
+	)
+
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := client.refreshItems(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.items) != 3 {
+		t.Fatalf("len(items) = %d, want 3", len(client.items))
+	}
+
+	values := client.collect(now)
+
+	if err := client.sendValues(ctx, now, values); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.l.Lock()
+	defer srv.l.Unlock()
+
+	if len(srv.hosts) != 1 || srv.hosts[0] != "server.example.com" {
+		t.Errorf("hosts = %v, want [server.example.com]", srv.hosts)
+	}
+
+	sort.Slice(srv.values, func(i, j int) bool {
+		return srv.values[i].Key < srv.values[j].Key
+	})
+
+	want := []struct {
+		key   string
+		value string
+		state int
+	}{
+		{key: "agent.ping", value: "1"},
+		{key: "agent.version", value: "4"},
+		{key: "unknown.key[a,b]", value: "unsupported key", state: itemStateNotSupported},
+	}
+
+	if len(srv.values) != len(want) {
+		t.Fatalf("len(values) = %d, want %d", len(srv.values), len(want))
+	}
+
+	for i, w := range want {
+		got := srv.values[i]
+		if got.Key != w.key || got.Value != w.value || got.State != w.state || got.Host != "server.example.com" {
+			t.Errorf("values[%d] = %+v, want key=%s value=%s state=%d", i, got, w.key, w.value, w.state)
+		}
+	}
+
+	// Nothing is due before the smallest delay.
+	if values := client.collect(now.Add(10 * time.Second)); len(values) != 0 {
+		t.Errorf("collect() returned %d values, want 0", len(values))
+	}
+
+	if values := client.collect(now.Add(30 * time.Second)); len(values) != 2 {
+		t.Errorf("collect() returned %d values, want 2", len(values))
+	}
+}
+
+func TestParseDelay(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		raw     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{raw: `30`, want: 30 * time.Second},
+		{raw: `"60"`, want: time.Minute},
+		{raw: `"15s"`, want: 15 * time.Second},
+		{raw: `"5m"`, want: 5 * time.Minute},
+		{raw: `"1h"`, want: time.Hour},
+		{raw: `"1d"`, want: 24 * time.Hour},
+		{raw: `"1w"`, want: 7 * 24 * time.Hour},
+		{raw: `"30s;10s/1-5,09:00-18:00"`, want: 30 * time.Second},
+		{raw: `"{$DELAY}"`, wantErr: true},
+		{raw: `"0"`, wantErr: true},
+		{raw: `""`, wantErr: true},
+	}
+
+	for _, c := range cases {
+		got, err := parseDelay(json.RawMessage(c.raw))
+		if (err != nil) != c.wantErr {
+			t.Errorf("parseDelay(%s) error = %v, wantErr %v", c.raw, err, c.wantErr)
+
+			continue
+		}
+
+		if got != c.want {
+			t.Errorf("parseDelay(%s) = %v, want %v", c.raw, got, c.want)
+		}
+	}
+}
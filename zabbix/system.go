@@ -0,0 +1,176 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zabbix
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bleemeo/glouton/types"
+)
+
+// maxPointAge is the maximum age of the point used to answer a system key.
+const maxPointAge = 5 * time.Minute
+
+var (
+	errUnsupportedParameter = errors.New("unsupported item parameter")
+	errNoValue              = errors.New("no value available")
+)
+
+// MetricStore is the store of the metrics used to answer the system keys.
+type MetricStore interface {
+	Metrics(filters map[string]string) (result []types.Metric, err error)
+}
+
+// systemKey describes how a Zabbix key is answered from a Glouton metric.
+type systemKey struct {
+	// metricName returns the name of the metric for the given arguments.
+	metricName func(args []string) (string, error)
+	// integer tells whether the value is sent as an integer.
+	integer bool
+}
+
+//nolint:gochecknoglobals
+var systemKeys = map[string]systemKey{
+	"system.cpu.load": {
+		metricName: func(args []string) (string, error) {
+			if cpu := arg(args, 0); cpu != "" && cpu != "all" {
+				return "", fmt.Errorf("%w: cpu %q", errUnsupportedParameter, cpu)
+			}
+
+			switch mode := arg(args, 1); mode {
+			case "", "avg1":
+				return "system_load1", nil
+			case "avg5":
+				return "system_load5", nil
+			case "avg15":
+				return "system_load15", nil
+			default:
+				return "", fmt.Errorf("%w: mode %q", errUnsupportedParameter, mode)
+			}
+		},
+	},
+	"vm.memory.size": {
+		metricName: func(args []string) (string, error) {
+			switch mode := arg(args, 0); mode {
+			case "", "total":
+				return "mem_total", nil
+			case "free":
+				return "mem_free", nil
+			case "available":
+				return "mem_available", nil
+			case "used":
+				return "mem_used", nil
+			case "buffers":
+				return "mem_buffered", nil
+			case "cached":
+				return "mem_cached", nil
+			case "pused":
+				return "mem_used_perc", nil
+			case "pavailable":
+				return "mem_available_perc", nil
+			default:
+				return "", fmt.Errorf("%w: mode %q", errUnsupportedParameter, mode)
+			}
+		},
+		integer: true,
+	},
+	"system.uptime": {
+		metricName: func([]string) (string, error) {
+			return "uptime", nil
+		},
+		integer: true,
+	},
+}
+
+// withSystemKeys returns a callback which answers the system keys from the
+// store and forwards the other keys to cb.
+func withSystemKeys(store MetricStore, cb callback) callback {
+	if store == nil {
+		return cb
+	}
+
+	return func(key string, args []string) (string, error) {
+		sk, ok := systemKeys[key]
+		if !ok {
+			return cb(key, args)
+		}
+
+		name, err := sk.metricName(args)
+		if err != nil {
+			return "", err
+		}
+
+		value, err := lastValue(store, name, time.Now())
+		if err != nil {
+			return "", err
+		}
+
+		// Percentages are floats even for integer keys.
+		if sk.integer && name != "mem_used_perc" && name != "mem_available_perc" {
+			return strconv.FormatFloat(value, 'f', 0, 64), nil
+		}
+
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	}
+}
+
+// lastValue returns the most recent value of the host metric with the given name.
+func lastValue(store MetricStore, name string, now time.Time) (float64, error) {
+	metrics, err := store.Metrics(map[string]string{types.LabelName: name})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range metrics {
+		// Only use the host metric, not a metric about an item.
+		if m.Labels()[types.LabelItem] != "" {
+			continue
+		}
+
+		points, err := m.Points(now.Add(-maxPointAge), now)
+		if err != nil {
+			return 0, err
+		}
+
+		if len(points) == 0 {
+			continue
+		}
+
+		last := points[0]
+
+		for _, p := range points[1:] {
+			if p.Time.After(last.Time) {
+				last = p
+			}
+		}
+
+		return last.Value, nil
+	}
+
+	return 0, fmt.Errorf("%w for %s", errNoValue, name)
+}
+
+func arg(args []string, index int) string {
+	if index < len(args) {
+		return args[index]
+	}
+
+	return ""
+}
@@ -0,0 +1,91 @@
+// Copyright 2015-2024 Bleemeo
+//
+// bleemeo.com an infrastructure monitoring solution in the Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zabbix
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bleemeo/glouton/store"
+	"github.com/bleemeo/glouton/types"
+)
+
+var errTestUnsupportedKey = errors.New("unsupported key")
+
+func TestSystemKeys(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	st := store.New(time.Hour, time.Hour)
+
+	st.PushPoints(context.Background(), []types.MetricPoint{
+		{
+			Point:  types.Point{Time: now.Add(-20 * time.Second), Value: 0.5},
+			Labels: map[string]string{types.LabelName: "system_load1"},
+		},
+		{
+			Point:  types.Point{Time: now.Add(-10 * time.Second), Value: 0.75},
+			Labels: map[string]string{types.LabelName: "system_load1"},
+		},
+		{
+			Point:  types.Point{Time: now, Value: 8e9},
+			Labels: map[string]string{types.LabelName: "mem_total"},
+		},
+		{
+			Point:  types.Point{Time: now, Value: 42.5},
+			Labels: map[string]string{types.LabelName: "mem_used_perc"},
+		},
+		{
+			Point:  types.Point{Time: now, Value: 3600.4},
+			Labels: map[string]string{types.LabelName: "uptime"},
+		},
+	})
+
+	cb := withSystemKeys(st, func(string, []string) (string, error) {
+		return "", errTestUnsupportedKey
+	})
+
+	tests := []struct {
+		key     string
+		args    []string
+		want    string
+		wantErr error
+	}{
+		{key: "system.cpu.load", want: "0.75"},
+		{key: "system.cpu.load", args: []string{"all", "avg1"}, want: "0.75"},
+		{key: "system.cpu.load", args: []string{"percpu"}, wantErr: errUnsupportedParameter},
+		{key: "system.cpu.load", args: []string{"", "avg5"}, wantErr: errNoValue},
+		{key: "vm.memory.size", want: "8000000000"},
+		{key: "vm.memory.size", args: []string{"pused"}, want: "42.5"},
+		{key: "vm.memory.size", args: []string{"shared"}, wantErr: errUnsupportedParameter},
+		{key: "system.uptime", want: "3600"},
+		{key: "system.cpu.util", wantErr: errTestUnsupportedKey},
+	}
+
+	for _, tt := range tests {
+		got, err := cb(tt.key, tt.args)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s%v: err = %v, want %v", tt.key, tt.args, err, tt.wantErr)
+		}
+
+		if got != tt.want {
+			t.Errorf("%s%v = %q, want %q", tt.key, tt.args, got, tt.want)
+		}
+	}
+}
@@ -52,9 +52,11 @@ type Server struct {
 
 // New returns a Zabbix server
 // callback is the function responsible to generate the response for a given query.
-func New(bindAddress string, callback callback) Server {
+// When store isn't nil, the system keys (system.cpu.load, vm.memory.size and
+// system.uptime) are answered using the metrics of the store.
+func New(bindAddress string, callback callback, store MetricStore) Server {
 	return Server{
-		callback:    callback,
+		callback:    withSystemKeys(store, callback),
 		bindAddress: bindAddress,
 	}
 }
@@ -278,27 +280,7 @@ func encodeReply(message string, inputError error) ([]byte, error) {
 		message = fmt.Sprintf("ZBX_NOTSUPPORTED\x00%s.", inputError)
 	}
 
-	dataLength := int64(len(message))
-
-	encodedPacket := make([]byte, 13+dataLength)
-
-	copy(encodedPacket[0:4], "ZBXD")
-
-	encodedPacket[4] = 1 // version
-
-	buf := new(bytes.Buffer)
-
-	err := binary.Write(buf, binary.LittleEndian, &dataLength)
-	if err != nil {
-		err = fmt.Errorf("binary.Write failed for data_length: %w", err)
-
-		return encodedPacket, err
-	}
-
-	copy(encodedPacket[5:13], buf.Bytes())
-	copy(encodedPacket[13:], message)
-
-	return encodedPacket, nil
+	return encodePacket([]byte(message)), nil
 }
 
 // Run starts a connection with a zabbix server.